@@ -0,0 +1,35 @@
+/**
+ * Unit tests for Checker helpers that need no database.
+ */
+
+import { describe, test, expect } from 'vitest';
+import { parseUsersetPointer } from './checker.js';
+
+describe('parseUsersetPointer', () => {
+  test('splits type, id and relation', () => {
+    expect(parseUsersetPointer({ userset: 'group:eng#member' })).toEqual({
+      obj: { type: 'group', id: 'eng' },
+      rel: 'member',
+    });
+  });
+
+  test('keeps colons in the id', () => {
+    expect(parseUsersetPointer({ userset: 'group:team:eng#member' })).toEqual({
+      obj: { type: 'group', id: 'team:eng' },
+      rel: 'member',
+    });
+  });
+
+  test('keeps hashes in the id', () => {
+    expect(parseUsersetPointer({ userset: 'group:team#eng#member' })).toEqual({
+      obj: { type: 'group', id: 'team#eng' },
+      rel: 'member',
+    });
+  });
+
+  test('rejects malformed pointers', () => {
+    for (const userset of ['', 'group:eng', 'group:eng#', '#member', ':eng#member', 'group:#member', 'groupeng#member']) {
+      expect(parseUsersetPointer({ userset })).toBeNull();
+    }
+  });
+});
@@ -13,16 +13,19 @@ import type {
   ContextualTuple,
   PageOptions,
   ListResult,
+  ObjectRef,
 } from './types.js';
 import type { Trace, ExplainOptions } from './trace.js';
 import type { UsersetTree, ExpandOptions, Computed } from './expand.js';
 import { flattenUsers } from './expand.js';
 import type { Queryable } from './database.js';
+import { runQuery } from './database.js';
 import { Cache, NoopCache } from './cache.js';
 import { validateObject, validateRelation } from './validator.js';
 import { MelangeError } from './errors.js';
 import { BulkCheckBuilder } from './bulk-check.js';
 import { prefixIdent } from './identifier.js';
+import { toObjectRefs } from './object-ref.js';
 
 /**
  * CheckerOptions configures a Checker instance.
@@ -59,6 +62,15 @@ export interface CheckerOptions {
    * Sets the database schema where melange objects live.
    */
   databaseSchema?: string;
+
+  /**
+   * Schema hash check() passes to check_permission as p_expected_schema_hash.
+   * When the installed functions were generated from a schema with a
+   * different hash, check() rejects with SchemaHashMismatchError instead of
+   * answering. Use the hash melange_healthcheck() reports for the schema the
+   * application was built against. Only check() is guarded.
+   */
+  expectedSchemaHash?: string;
 }
 
 /**
@@ -96,6 +108,7 @@ export class Checker {
   private readonly validateRequest: boolean;
   private readonly validateUserset: boolean;
   private readonly databaseSchema: string;
+  private readonly expectedSchemaHash?: string;
 
   /**
    * Creates a new Checker instance.
@@ -110,6 +123,7 @@ export class Checker {
     this.validateRequest = options.validateRequest ?? true;
     this.validateUserset = options.validateUserset ?? true;
     this.databaseSchema = options.databaseSchema ?? '';
+    this.expectedSchemaHash = options.expectedSchemaHash || undefined;
   }
 
   /**
@@ -170,10 +184,18 @@ export class Checker {
 
     // Execute check_permission
     // TODO: Support contextual tuples when implemented in PostgreSQL functions
-    const result = await this.db.query<{ allowed: number }>(
-      `SELECT ${func}($1, $2, $3, $4, $5) as allowed`,
-      [subject.type, subject.id, relation, object.type, object.id]
-    );
+    const args = [subject.type, subject.id, relation, object.type, object.id];
+    const result = this.expectedSchemaHash
+      ? await runQuery<{ allowed: number }>(
+          this.db,
+          `SELECT ${func}($1, $2, $3, $4, $5, $6) as allowed`,
+          [...args, this.expectedSchemaHash]
+        )
+      : await runQuery<{ allowed: number }>(
+          this.db,
+          `SELECT ${func}($1, $2, $3, $4, $5) as allowed`,
+          args
+        );
 
     if (!result.rows || result.rows.length === 0) {
       throw new MelangeError('check_permission returned no rows');
@@ -250,7 +272,8 @@ export class Checker {
 
     const func = prefixIdent('list_accessible_objects', this.databaseSchema);
 
-    const result = await this.db.query<{ object_id: string; next_cursor: string }>(
+    const result = await runQuery<{ object_id: string; next_cursor: string }>(
+      this.db,
       `SELECT * FROM ${func}($1, $2, $3, $4, $5, $6)`,
       [subject.type, subject.id, relation, objectType, limit, after ?? null]
     );
@@ -263,6 +286,26 @@ export class Checker {
     return { items, nextCursor };
   }
 
+  /**
+   * ListObjectRefs is listObjects with each object ID paired with its type,
+   * so the items are typed by objectType.
+   *
+   * @example
+   * ```typescript
+   * const result = await checker.listObjectRefs(user, 'can_read', 'repository');
+   * // result.items: ObjectRef<'repository'>[]
+   * ```
+   */
+  async listObjectRefs<T extends ObjectType>(
+    subject: MelangeObject,
+    relation: Relation,
+    objectType: T,
+    options?: PageOptions
+  ): Promise<ListResult<ObjectRef<T>>> {
+    const result = await this.listObjects(subject, relation, objectType, options);
+    return { items: toObjectRefs(objectType, result.items), nextCursor: result.nextCursor };
+  }
+
   /**
    * ListSubjects returns all subjects of the given type that have the relation to the object.
    *
@@ -312,7 +355,8 @@ export class Checker {
 
     const func = prefixIdent('list_accessible_subjects', this.databaseSchema);
 
-    const result = await this.db.query<{ subject_id: string; next_cursor: string }>(
+    const result = await runQuery<{ subject_id: string; next_cursor: string }>(
+      this.db,
       `SELECT * FROM ${func}($1, $2, $3, $4, $5, $6)`,
       [object.type, object.id, relation, subjectType, limit, after ?? null]
     );
@@ -403,7 +447,8 @@ export class Checker {
     // parse — pg's default JSONB handling returns it already-parsed but the
     // cast keeps behaviour deterministic across pg-versions and driver
     // configurations.
-    const result = await this.db.query<{ trace: string | Trace }>(
+    const result = await runQuery<{ trace: string | Trace }>(
+      this.db,
       `SELECT ${func}($1, $2, $3, $4, $5, $6)::text AS trace`,
       [subject.type, subject.id, relation, object.type, object.id, maxNodes]
     );
@@ -453,7 +498,8 @@ export class Checker {
         ? options.maxLeaf
         : null;
 
-    const result = await this.db.query<{ tree: string | UsersetTree }>(
+    const result = await runQuery<{ tree: string | UsersetTree }>(
+      this.db,
       `SELECT ${func}($1, $2, $3, $4, $5)::text AS tree`,
       [object.type, object.id, relation, subjectType, maxLeaf]
     );
@@ -587,11 +633,14 @@ function collectPointers(
 }
 
 // parseUsersetPointer splits a `<type>:<id>#<relation>` string into a
-// MelangeObject + Relation. Returns null on malformed input so a
+// MelangeObject + Relation. The type ends at the first ':' and the
+// relation starts after the last '#', so IDs may contain either
+// ("group:team#eng#member"). Returns null on malformed input so a
 // degenerate tree response stops the walker rather than crashing.
-function parseUsersetPointer(c: Computed): { obj: MelangeObject; rel: Relation } | null {
+// Exported for tests only; not part of the package index.
+export function parseUsersetPointer(c: Computed): { obj: MelangeObject; rel: Relation } | null {
   const u = c.userset;
-  const hash = u.indexOf('#');
+  const hash = u.lastIndexOf('#');
   if (hash < 1 || hash === u.length - 1) return null;
   const colon = u.indexOf(':');
   if (colon < 1 || colon >= hash - 1) return null;
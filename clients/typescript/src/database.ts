@@ -5,6 +5,8 @@
  * allowing the Checker to work with any PostgreSQL client library.
  */
 
+import { translateError } from './errors.js';
+
 /**
  * QueryResult represents the result of a database query.
  */
@@ -44,3 +46,15 @@ export interface Queryable {
    */
   query<T = any>(text: string, params?: any[]): Promise<QueryResult<T>>;
 }
+
+/**
+ * runQuery runs a query on db, rethrowing any error through translateError so
+ * the SQLSTATEs the generated functions raise surface as melange errors.
+ */
+export async function runQuery<T = any>(db: Queryable, text: string, params?: any[]): Promise<QueryResult<T>> {
+  try {
+    return await db.query<T>(text, params);
+  } catch (err) {
+    throw translateError(err);
+  }
+}
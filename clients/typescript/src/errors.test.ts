@@ -0,0 +1,85 @@
+/**
+ * Unit tests for translating SQLSTATEs raised by the generated functions.
+ */
+
+import { describe, test, expect } from 'vitest';
+import {
+  InvalidArgumentError,
+  MelangeError,
+  RelationNotExpandableError,
+  RelationNotListableError,
+  ResolutionTooComplexError,
+  SchemaHashMismatchError,
+  translateError,
+} from './errors.js';
+import { Checker } from './checker.js';
+
+// pgError mimics a node-postgres DatabaseError, which carries the SQLSTATE in code.
+function pgError(code: string, message: string): Error {
+  return Object.assign(new Error(message), { code });
+}
+
+describe('translateError', () => {
+  test('maps each melange SQLSTATE to its class', () => {
+    const cases: [string, new (...args: any[]) => Error][] = [
+      ['M2002', ResolutionTooComplexError],
+      ['M2003', RelationNotListableError],
+      ['M2004', RelationNotExpandableError],
+      ['M2005', SchemaHashMismatchError],
+      ['22004', InvalidArgumentError],
+      ['22023', InvalidArgumentError],
+    ];
+    for (const [code, cls] of cases) {
+      const cause = pgError(code, `raised ${code}`);
+      const err = translateError(cause);
+      expect(err).toBeInstanceOf(cls);
+      expect(err).toBeInstanceOf(MelangeError);
+      expect((err as Error).cause).toBe(cause);
+      expect((err as { sqlState: string }).sqlState).toBe(code);
+    }
+  });
+
+  test('keeps the driver message', () => {
+    const err = translateError(pgError('M2003', 'relation not listable: document.viewer'));
+    expect((err as Error).message).toBe('relation not listable: document.viewer');
+  });
+
+  test('passes other errors through unchanged', () => {
+    for (const err of [pgError('22P02', 'bad input'), new Error('no code'), 'text', null, undefined]) {
+      expect(translateError(err)).toBe(err);
+    }
+  });
+});
+
+describe('Checker', () => {
+  test('rejects with the translated error', async () => {
+    const db = {
+      query: async () => {
+        throw pgError('M2003', 'relation not listable: document.viewer');
+      },
+    };
+    const checker = new Checker(db);
+    await expect(
+      checker.listObjects({ type: 'user', id: '1' }, 'viewer', 'document'),
+    ).rejects.toBeInstanceOf(RelationNotListableError);
+  });
+
+  test('passes expectedSchemaHash to check_permission', async () => {
+    const calls: { text: string; params?: any[] }[] = [];
+    const db = {
+      query: async (text: string, params?: any[]) => {
+        calls.push({ text, params });
+        return { rows: [{ allowed: 1 }] };
+      },
+    };
+    const user = { type: 'user', id: '1' };
+    const doc = { type: 'document', id: '1' };
+
+    await new Checker(db as any).check(user, 'viewer', doc);
+    await new Checker(db as any, { expectedSchemaHash: 'abc' }).check(user, 'viewer', doc);
+
+    expect(calls[0].text).toContain('check_permission($1, $2, $3, $4, $5)');
+    expect(calls[1].text).toContain('check_permission($1, $2, $3, $4, $5, $6)');
+    expect(calls[1].params).toEqual(['user', '1', 'viewer', 'document', '1', 'abc']);
+  });
+});
@@ -98,3 +98,126 @@ export class BulkCheckDeniedError extends MelangeError {
 export function isBulkCheckDeniedError(err: unknown): err is BulkCheckDeniedError {
   return err instanceof BulkCheckDeniedError;
 }
+
+/**
+ * SqlStateError is the base class for errors raised by melange's generated
+ * SQL functions. It keeps the SQLSTATE the function raised and the driver
+ * error as `cause`.
+ */
+export class SqlStateError extends MelangeError {
+  readonly sqlState: string;
+
+  constructor(message: string, sqlState: string, cause?: unknown) {
+    super(message);
+    this.name = 'SqlStateError';
+    this.sqlState = sqlState;
+    this.cause = cause;
+    Object.setPrototypeOf(this, SqlStateError.prototype);
+  }
+}
+
+/**
+ * ResolutionTooComplexError indicates a check or list hit the resolution
+ * depth limit (SQLSTATE M2002). It corresponds to OpenFGA error code 2002.
+ */
+export class ResolutionTooComplexError extends SqlStateError {
+  constructor(cause?: unknown) {
+    super('resolution too complex: depth limit exceeded', 'M2002', cause);
+    this.name = 'ResolutionTooComplexError';
+    Object.setPrototypeOf(this, ResolutionTooComplexError.prototype);
+  }
+}
+
+/**
+ * RelationNotListableError indicates a list call reached a relation melange
+ * generated no list function for (SQLSTATE M2003). Checks on the relation
+ * still work.
+ */
+export class RelationNotListableError extends SqlStateError {
+  constructor(message: string, cause?: unknown) {
+    super(message, 'M2003', cause);
+    this.name = 'RelationNotListableError';
+    Object.setPrototypeOf(this, RelationNotListableError.prototype);
+  }
+}
+
+/**
+ * RelationNotExpandableError indicates expand reached a relation melange
+ * generated no expand function for (SQLSTATE M2004). Checks on the relation
+ * still work.
+ */
+export class RelationNotExpandableError extends SqlStateError {
+  constructor(message: string, cause?: unknown) {
+    super(message, 'M2004', cause);
+    this.name = 'RelationNotExpandableError';
+    Object.setPrototypeOf(this, RelationNotExpandableError.prototype);
+  }
+}
+
+/**
+ * SchemaHashMismatchError indicates check_permission was given an expected
+ * schema hash that differs from the hash the installed functions were
+ * generated with (SQLSTATE M2005), typically because the schema changed and
+ * `melange migrate` has not run yet.
+ */
+export class SchemaHashMismatchError extends SqlStateError {
+  constructor(message: string, cause?: unknown) {
+    super(message, 'M2005', cause);
+    this.name = 'SchemaHashMismatchError';
+    Object.setPrototypeOf(this, SchemaHashMismatchError.prototype);
+  }
+}
+
+/**
+ * InvalidArgumentError indicates a generated function rejected an argument:
+ * a NULL where a value is required (SQLSTATE 22004), or a malformed
+ * "type:id" string (SQLSTATE 22023).
+ */
+export class InvalidArgumentError extends SqlStateError {
+  constructor(message: string, sqlState: string, cause?: unknown) {
+    super(message, sqlState, cause);
+    this.name = 'InvalidArgumentError';
+    Object.setPrototypeOf(this, InvalidArgumentError.prototype);
+  }
+}
+
+/**
+ * translateError maps an error raised by a generated SQL function to the
+ * melange error class for its SQLSTATE, read from the driver error's `code`
+ * property as pg and postgres.js report it. Any other error is returned
+ * unchanged.
+ *
+ * Checker applies it to every query. Code that calls the generated functions
+ * directly, such as the generated clients, uses it to get the same errors.
+ *
+ * @example
+ * ```typescript
+ * try {
+ *   await pool.query('SELECT * FROM list_accessible_objects($1, $2, $3, $4)', args);
+ * } catch (err) {
+ *   throw translateError(err);
+ * }
+ * ```
+ */
+export function translateError(err: unknown): unknown {
+  const code = (err as { code?: unknown } | null)?.code;
+  if (typeof code !== 'string') {
+    return err;
+  }
+  const message = err instanceof Error ? err.message : String(err);
+  switch (code) {
+    case 'M2002':
+      return new ResolutionTooComplexError(err);
+    case 'M2003':
+      return new RelationNotListableError(message, err);
+    case 'M2004':
+      return new RelationNotExpandableError(message, err);
+    case 'M2005':
+      return new SchemaHashMismatchError(message, err);
+    case '22004':
+    case '22023':
+      return new InvalidArgumentError(message, code, err);
+    default:
+      return err;
+  }
+}
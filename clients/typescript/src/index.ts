@@ -11,10 +11,24 @@
 export { Checker, DecisionAllow, DecisionDeny } from './checker.js';
 export type { CheckerOptions } from './checker.js';
 export { Cache, NoopCache, MemoryCache } from './cache.js';
-export { MelangeError, NotFoundError, ValidationError, BulkCheckDeniedError, isBulkCheckDeniedError } from './errors.js';
+export {
+  MelangeError,
+  NotFoundError,
+  ValidationError,
+  BulkCheckDeniedError,
+  isBulkCheckDeniedError,
+  SqlStateError,
+  ResolutionTooComplexError,
+  RelationNotListableError,
+  RelationNotExpandableError,
+  SchemaHashMismatchError,
+  InvalidArgumentError,
+  translateError,
+} from './errors.js';
 export { BulkCheckBuilder, BulkCheckResult, BulkCheckResults, MAX_BULK_CHECK_SIZE } from './bulk-check.js';
 export type { Queryable, QueryResult } from './database.js';
 export { validateObject, validateRelation } from './validator.js';
+export { parseObjectRef, toObjectRefs } from './object-ref.js';
 export type {
   ObjectType,
   Relation,
@@ -24,6 +38,7 @@ export type {
   ContextualTuple,
   PageOptions,
   ListResult,
+  ObjectRef,
 } from './types.js';
 export type {
   NodeType,
@@ -0,0 +1,33 @@
+/**
+ * Unit tests for object references.
+ */
+
+import { describe, test, expect } from 'vitest';
+import { parseObjectRef, toObjectRefs } from './object-ref.js';
+import type { ObjectRef } from './types.js';
+
+describe('parseObjectRef', () => {
+  test('splits type and id', () => {
+    expect(parseObjectRef('document:123')).toEqual({ type: 'document', id: '123' });
+  });
+
+  test('keeps colons in the id', () => {
+    expect(parseObjectRef('file:a:b')).toEqual({ type: 'file', id: 'a:b' });
+  });
+
+  test('rejects a missing type or id', () => {
+    for (const value of ['document', ':123', 'document:', '']) {
+      expect(() => parseObjectRef(value)).toThrow('is not a type:id string');
+    }
+  });
+});
+
+describe('toObjectRefs', () => {
+  test('types each id', () => {
+    const refs: ObjectRef<'document'>[] = toObjectRefs('document', ['1', '2']);
+    expect(refs).toEqual([
+      { type: 'document', id: '1' },
+      { type: 'document', id: '2' },
+    ]);
+  });
+});
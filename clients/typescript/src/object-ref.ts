@@ -0,0 +1,37 @@
+/**
+ * Typed object references for list results.
+ */
+
+import type { ObjectRef, ObjectType } from './types.js';
+import { ValidationError } from './errors.js';
+
+/**
+ * Parse a `type:id` string into an ObjectRef.
+ *
+ * The type ends at the first colon, so the ID may itself contain colons.
+ *
+ * @param value - The string to parse (e.g., "document:123")
+ * @throws {ValidationError} If the type or ID is missing
+ *
+ * @example
+ * ```typescript
+ * parseObjectRef('document:123'); // { type: 'document', id: '123' }
+ * ```
+ */
+export function parseObjectRef(value: string): ObjectRef {
+  const colon = value.indexOf(':');
+  if (colon < 1 || colon === value.length - 1) {
+    throw new ValidationError(`"${value}" is not a type:id string`);
+  }
+  return { type: value.slice(0, colon), id: value.slice(colon + 1) };
+}
+
+/**
+ * Pair each object ID listed for objectType with its type.
+ *
+ * @param objectType - The type the IDs were listed for
+ * @param ids - Object IDs, as returned by a list operation
+ */
+export function toObjectRefs<T extends ObjectType>(objectType: T, ids: readonly string[]): ObjectRef<T>[] {
+  return ids.map((id) => ({ type: objectType, id }));
+}
@@ -25,6 +25,16 @@ export interface MelangeObject {
   readonly relation?: Relation;
 }
 
+/**
+ * ObjectRef is an object returned by a list operation, typed by its object
+ * type. With a generated union of object types, an array of ObjectRef can be
+ * narrowed on its type field.
+ */
+export interface ObjectRef<T extends ObjectType = ObjectType> {
+  readonly type: T;
+  readonly id: string;
+}
+
 /**
  * Decision represents the result of a permission check.
  */
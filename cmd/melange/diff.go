@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "github.com/lib/pq"
+	"github.com/spf13/cobra"
+
+	"github.com/pthm/melange/lib/cli"
+	"github.com/pthm/melange/pkg/migrator"
+)
+
+var (
+	diffDB       string
+	diffDBSchema string
+	diffTuples   string
+	diffSchema   string
+	diffForce    bool
+	diffFormat   string
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show what migrate would change",
+	Long: `Compare the functions the schema generates with the ones deployed in the
+database and list the functions migrate would add, remove, or change.
+
+When the last recorded migration has the same schema checksum and codegen
+version, migrate would skip, so diff reports no changes without reading the
+deployed functions. Use --force to compare them anyway, for example to catch
+functions edited outside melange. With --verbose, each changed function's
+body is shown as a line diff.
+
+The command exits non-zero when there are differences.`,
+	Example: `  # List what migrate would change
+  melange diff --db postgres://localhost/mydb
+
+  # Show a line diff for each changed function
+  melange diff --db postgres://localhost/mydb --verbose
+
+  # Compare deployed functions even when the last migration matches
+  melange diff --db postgres://localhost/mydb --force
+
+  # Emit the diff as JSON for CI
+  melange diff --db postgres://localhost/mydb --format json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		databaseSchema := resolveString(diffDBSchema, cfg.Database.Schema)
+		tuplesTable := resolveString(diffTuples, cfg.Database.TuplesTable)
+		schemaPath := resolveString(diffSchema, cfg.Schema)
+
+		dsn, err := resolveDSN(diffDB)
+		if err != nil {
+			return err
+		}
+
+		return runDiff(dsn, databaseSchema, tuplesTable, schemaPath, diffForce, verbose > 0, diffFormat)
+	},
+}
+
+func init() {
+	f := diffCmd.Flags()
+	f.StringVar(&diffDB, "db", "", "database URL")
+	f.StringVar(&diffDBSchema, "db-schema", "public", "database schema")
+	f.StringVar(&diffTuples, "tuples-table", "", "tuples relation, optionally schema-qualified (default \"melange_tuples\")")
+	f.StringVar(&diffSchema, "schema", "", "path to schema.fga, fga.mod, or a directory of .fga files")
+	f.BoolVar(&diffForce, "force", false, "compare deployed functions even when the last migration matches the schema")
+	f.StringVar(&diffFormat, "format", "text", "output format: text (default) or json")
+}
+
+func runDiff(dsn, databaseSchema, tuplesTable, schemaPath string, force, showBodies bool, format string) error {
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return cli.DBConnectError("connecting to database", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	m := migrator.NewMigrator(db, schemaPath)
+	m.SetDatabaseSchema(databaseSchema)
+	m.SetTuplesTable(tuplesTable)
+
+	d, err := m.Diff(context.Background(), migrator.DiffOptions{Force: force})
+	if err != nil {
+		return cli.GeneralError("comparing functions", err)
+	}
+
+	if format == "json" {
+		if err := writeDiffJSON(d, schemaPath); err != nil {
+			return cli.GeneralError("writing diff", err)
+		}
+	} else if !quiet {
+		printDiff(d, showBodies)
+	}
+
+	if d.HasChanges() {
+		return cli.GeneralError(fmt.Sprintf("%d added, %d removed, %d changed functions",
+			len(d.Added), len(d.Removed), len(d.Changed)), nil)
+	}
+	return nil
+}
+
+// printDiff prints d as text. With showBodies, each changed function is
+// followed by a line diff of its deployed and generated bodies.
+func printDiff(d *migrator.SchemaDiff, showBodies bool) {
+	if d.Unchanged {
+		fmt.Println("No changes: the last migration matches this schema and melange version.")
+		fmt.Println("Use --force to compare the deployed functions anyway.")
+		return
+	}
+	if !d.HasChanges() {
+		fmt.Println("No changes: the deployed functions match the schema.")
+		return
+	}
+
+	fmt.Printf("Functions:    %d added, %d removed, %d changed\n", len(d.Added), len(d.Removed), len(d.Changed))
+	changed := make([]string, len(d.Changed))
+	for i, c := range d.Changed {
+		changed[i] = c.Name
+	}
+	for _, list := range []struct {
+		label string
+		names []string
+	}{
+		{"Added", d.Added},
+		{"Removed", d.Removed},
+		{"Changed", changed},
+	} {
+		if len(list.names) == 0 {
+			continue
+		}
+		fmt.Printf("  %s:\n", list.label)
+		for _, name := range list.names {
+			fmt.Printf("    %s\n", name)
+		}
+	}
+
+	if !showBodies {
+		return
+	}
+	for _, c := range d.Changed {
+		fmt.Printf("\n--- %s (deployed)\n+++ %s (generated)\n", c.Name, c.Name)
+		for _, line := range lineDiff(c.Deployed, c.Generated) {
+			fmt.Println(line)
+		}
+	}
+}
+
+// diffJSON is the JSON form of melange diff: the migrator.SchemaDiff plus
+// the schema path it was generated from.
+type diffJSON struct {
+	SchemaPath string `json:"schema_path"`
+	*migrator.SchemaDiff
+}
+
+// writeDiffJSON writes d to stdout as indented JSON. Empty lists are written
+// as [] rather than null.
+func writeDiffJSON(d *migrator.SchemaDiff, schemaPath string) error {
+	for _, list := range []*[]string{&d.Added, &d.Removed} {
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+	if d.Changed == nil {
+		d.Changed = []migrator.FunctionChange{}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diffJSON{SchemaPath: schemaPath, SchemaDiff: d})
+}
+
+// lineDiffContext is how many unchanged lines lineDiff keeps around each
+// change.
+const lineDiffContext = 3
+
+// lineDiffMaxCells caps the size of the table lineDiff builds to align the
+// two bodies. Past it, the differing region is shown as removed and then
+// added in full.
+const lineDiffMaxCells = 1 << 20
+
+// lineDiff returns a line diff of from and to: removed lines prefixed with
+// "-", added lines with "+", and up to lineDiffContext unchanged lines
+// around each change prefixed with a space. Lines that differ only in
+// whitespace count as unchanged, as they do when comparing bodies, and are
+// shown as in to. Skipped runs of unchanged lines are shown as "...".
+func lineDiff(from, to string) []string {
+	a := strings.Split(strings.Trim(from, "\n"), "\n")
+	b := strings.Split(strings.Trim(to, "\n"), "\n")
+	same := func(i, j int) bool {
+		return strings.Join(strings.Fields(a[i]), " ") == strings.Join(strings.Fields(b[j]), " ")
+	}
+
+	// Unchanged lines at either end need no alignment.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && same(prefix, prefix) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && same(len(a)-1-suffix, len(b)-1-suffix) {
+		suffix++
+	}
+
+	// ops holds one entry per output line: ' ', '-' or '+', with the line.
+	type op struct {
+		kind byte
+		text string
+	}
+	var ops []op
+	for _, line := range b[:prefix] {
+		ops = append(ops, op{' ', line})
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(midA)+1)*(len(midB)+1) > lineDiffMaxCells {
+		for _, line := range midA {
+			ops = append(ops, op{'-', line})
+		}
+		for _, line := range midB {
+			ops = append(ops, op{'+', line})
+		}
+	} else {
+		// lcs[i][j] is the longest common subsequence of midA[i:] and midB[j:].
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if same(prefix+i, prefix+j) {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && same(prefix+i, prefix+j):
+				ops = append(ops, op{' ', midB[j]})
+				i++
+				j++
+			case i < len(midA) && (j == len(midB) || lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, op{'-', midA[i]})
+				i++
+			default:
+				ops = append(ops, op{'+', midB[j]})
+				j++
+			}
+		}
+	}
+	for _, line := range b[len(b)-suffix:] {
+		ops = append(ops, op{' ', line})
+	}
+
+	// Keep changes and the unchanged lines within lineDiffContext of one.
+	keep := make([]bool, len(ops))
+	for k, o := range ops {
+		if o.kind == ' ' {
+			continue
+		}
+		for c := max(k-lineDiffContext, 0); c <= min(k+lineDiffContext, len(ops)-1); c++ {
+			keep[c] = true
+		}
+	}
+	var out []string
+	skipped := false
+	for k, o := range ops {
+		if !keep[k] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			out = append(out, "...")
+		}
+		skipped = false
+		out = append(out, string(o.kind)+o.text)
+	}
+	return out
+}
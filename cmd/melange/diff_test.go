@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineDiff(t *testing.T) {
+	from := "\nBEGIN\n    IF p_x THEN\n        RETURN 1;\n    END IF;\n    RETURN 0;\nEND;\n"
+	to := "\nBEGIN\n  IF p_x THEN\n        RETURN 2;\n    END IF;\n    RETURN 0;\nEND;\n"
+
+	assert.Equal(t, []string{
+		" BEGIN",
+		"   IF p_x THEN",
+		"-        RETURN 1;",
+		"+        RETURN 2;",
+		"     END IF;",
+		"     RETURN 0;",
+		" END;",
+	}, lineDiff(from, to))
+}
+
+func TestLineDiff_ElidesDistantContext(t *testing.T) {
+	var lines []string
+	for i := range 20 {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	from := strings.Join(lines, "\n")
+	lines[10] = "changed"
+	lines = append(lines, "added")
+	to := strings.Join(lines, "\n")
+
+	assert.Equal(t, []string{
+		"...",
+		" line 7",
+		" line 8",
+		" line 9",
+		"-line 10",
+		"+changed",
+		" line 11",
+		" line 12",
+		" line 13",
+		"...",
+		" line 17",
+		" line 18",
+		" line 19",
+		"+added",
+	}, lineDiff(from, to))
+}
@@ -16,9 +16,12 @@ import (
 var (
 	doctorDB              string
 	doctorDBSchema        string
+	doctorTuples          string
 	doctorSchema          string
 	doctorVerbose         bool
 	doctorSkipPerformance bool
+	doctorBaseline        string
+	doctorFormat          string
 )
 
 var doctorCmd = &cobra.Command{
@@ -32,19 +35,27 @@ var doctorCmd = &cobra.Command{
   melange doctor --db postgres://localhost/mydb --db-schema myschema
 
   # Run with verbose output
-  melange doctor --db postgres://localhost/mydb --verbose`,
+  melange doctor --db postgres://localhost/mydb --verbose
+
+  # Fail unless the installed functions match a committed manifest
+  melange doctor --db postgres://localhost/mydb --baseline schemas/manifest.json
+
+  # Emit the report as JSON for CI
+  melange doctor --db postgres://localhost/mydb --format json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		databaseSchema := resolveString(doctorDBSchema, cfg.Database.Schema)
+		tuplesTable := resolveString(doctorTuples, cfg.Database.TuplesTable)
 		schemaPath := resolveString(doctorSchema, cfg.Schema)
 		verboseFlag := resolveBool(doctorVerbose, cfg.Doctor.Verbose)
 		skipPerf := resolveBool(doctorSkipPerformance, cfg.Doctor.SkipPerformance)
+		baseline := resolveString(doctorBaseline, cfg.Doctor.Baseline)
 
 		dsn, err := resolveDSN(doctorDB)
 		if err != nil {
 			return err
 		}
 
-		return runDoctor(dsn, databaseSchema, schemaPath, verboseFlag, skipPerf)
+		return runDoctor(dsn, databaseSchema, tuplesTable, schemaPath, verboseFlag, skipPerf, baseline, doctorFormat)
 	},
 }
 
@@ -52,12 +63,19 @@ func init() {
 	f := doctorCmd.Flags()
 	f.StringVar(&doctorDB, "db", "", "database URL")
 	f.StringVar(&doctorDBSchema, "db-schema", "public", "database schema")
-	f.StringVar(&doctorSchema, "schema", "", "path to schema.fga or fga.mod file")
+	f.StringVar(&doctorTuples, "tuples-table", "", "tuples relation, optionally schema-qualified (default \"melange_tuples\")")
+	f.StringVar(&doctorSchema, "schema", "", "path to schema.fga, fga.mod, or a directory of .fga files")
 	f.BoolVar(&doctorVerbose, "verbose", false, "show detailed output")
 	f.BoolVar(&doctorSkipPerformance, "skip-performance", false, "skip performance checks")
+	f.StringVar(&doctorBaseline, "baseline", "", "manifest from 'melange generate manifest' that installed functions must match")
+	f.StringVar(&doctorFormat, "format", "text", "output format: text (default) or json")
 }
 
-func runDoctor(dsn, databaseSchema, schemaPath string, verboseFlag, skipPerformance bool) error {
+func runDoctor(dsn, databaseSchema, tuplesTable, schemaPath string, verboseFlag, skipPerformance bool, baseline, format string) error {
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
+
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return cli.DBConnectError("connecting to database", err)
@@ -66,18 +84,28 @@ func runDoctor(dsn, databaseSchema, schemaPath string, verboseFlag, skipPerforma
 
 	ctx := context.Background()
 
-	if !quiet {
+	if !quiet && format != "json" {
 		fmt.Println("melange doctor - Health Check")
 	}
 
-	d := doctor.New(db, schemaPath, doctor.Options{SkipPerformance: skipPerformance})
+	d := doctor.New(db, schemaPath, doctor.Options{
+		SkipPerformance: skipPerformance,
+		BaselinePath:    baseline,
+	})
 	d.SetDatabaseSchema(databaseSchema)
+	d.SetTuplesTable(tuplesTable)
 	report, err := d.Run(ctx)
 	if err != nil {
 		return cli.GeneralError("running doctor", err)
 	}
 
-	report.Print(os.Stdout, verboseFlag)
+	if format == "json" {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			return cli.GeneralError("writing report", err)
+		}
+	} else {
+		report.Print(os.Stdout, verboseFlag)
+	}
 
 	if report.HasErrors() {
 		return cli.GeneralError("health checks failed", nil)
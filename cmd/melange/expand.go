@@ -104,7 +104,7 @@ func runExpand(dsn, databaseSchema string, object melange.Object, relation melan
 	}
 	defer func() { _ = db.Close() }()
 
-	checker := melange.NewChecker(db, melange.WithDatabaseSchema(databaseSchema))
+	checker := melange.NewChecker(db, melange.WithDatabaseSchema(databaseSchema), melange.WithTuplesTable(cfg.Database.TuplesTable))
 
 	var opts []melange.ExpandOption
 	if subjectType != "" {
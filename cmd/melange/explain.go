@@ -126,7 +126,7 @@ func runExplain(dsn, databaseSchema string, subject melange.Object, relation mel
 	}
 	defer func() { _ = db.Close() }()
 
-	checker := melange.NewChecker(db, melange.WithDatabaseSchema(databaseSchema))
+	checker := melange.NewChecker(db, melange.WithDatabaseSchema(databaseSchema), melange.WithTuplesTable(cfg.Database.TuplesTable))
 
 	ctx := context.Background()
 	var opts []melange.ExplainOption
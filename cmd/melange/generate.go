@@ -11,4 +11,6 @@ var generateCmd = &cobra.Command{
 func init() {
 	generateCmd.AddCommand(generateClientCmd)
 	generateCmd.AddCommand(generateMigrationCmd)
+	generateCmd.AddCommand(generateManifestCmd)
+	generateCmd.AddCommand(generateSQLCmd)
 }
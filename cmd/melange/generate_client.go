@@ -15,12 +15,16 @@ import (
 )
 
 var (
-	genClientRuntime string
-	genClientSchema  string
-	genClientOutput  string
-	genClientPackage string
-	genClientFilter  string
-	genClientIDType  string
+	genClientRuntime     string
+	genClientSchema      string
+	genClientOutput      string
+	genClientPackage     string
+	genClientFilter      string
+	genClientIDType      string
+	genClientSplit       bool
+	genClientDBSchema    string
+	genClientConcurrency int
+	genClientCheckMemo   bool
 )
 
 var generateClientCmd = &cobra.Command{
@@ -38,6 +42,18 @@ Supported runtimes: ` + strings.Join(clientgen.ListRuntimes(), ", "),
   # Generate only permission relations (can_*)
   melange generate client --runtime go --schema schemas/schema.fga --output . --filter can_
 
+  # One file per object type plus a shared client.go
+  melange generate client --runtime go --schema schemas/schema.fga --output internal/authz/ --split-by-type
+
+  # Run up to 16 checks at once in the Go FilterAccessible functions
+  melange generate client --runtime go --schema schemas/schema.fga --output . --concurrency 16
+
+  # Route FilterAccessible checks through the check_permission memo
+  melange generate client --runtime go --schema schemas/schema.fga --output . --check-memo
+
+  # Call check_permission in the authz schema, whatever the search_path
+  melange generate client --runtime go --schema schemas/schema.fga --output . --db-schema authz
+
   # Output to stdout
   melange generate client --runtime go --schema schemas/schema.fga`,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -48,6 +64,10 @@ Supported runtimes: ` + strings.Join(clientgen.ListRuntimes(), ", "),
 		pkg := resolveString(genClientPackage, cfg.Generate.Client.Package, "authz")
 		filter := resolveString(genClientFilter, cfg.Generate.Client.Filter)
 		idType := resolveString(genClientIDType, cfg.Generate.Client.IDType, "string")
+		split := resolveBool(genClientSplit, cfg.Generate.Client.SplitByType)
+		databaseSchema := resolveString(genClientDBSchema, cfg.Database.Schema)
+		concurrency := resolveInt(genClientConcurrency, cfg.Generate.Client.Concurrency)
+		checkMemo := resolveBool(genClientCheckMemo, cfg.Migrate.CheckMemo)
 
 		// Validate required fields
 		if runtime == "" {
@@ -56,6 +76,9 @@ Supported runtimes: ` + strings.Join(clientgen.ListRuntimes(), ", "),
 		if schema == "" {
 			return cli.ConfigError("--schema is required", nil)
 		}
+		if concurrency < 0 {
+			return cli.ConfigError("--concurrency must not be negative", nil)
+		}
 
 		// Validate runtime
 		if !clientgen.Registered(runtime) {
@@ -80,6 +103,10 @@ Supported runtimes: ` + strings.Join(clientgen.ListRuntimes(), ", "),
 			Package:        pkg,
 			RelationFilter: filter,
 			IDType:         idType,
+			SplitByType:    split,
+			SchemaName:     databaseSchema,
+			Concurrency:    concurrency,
+			CheckMemo:      checkMemo,
 			Version:        version.Version,
 			SourcePath:     schema,
 		}
@@ -120,9 +147,13 @@ Supported runtimes: ` + strings.Join(clientgen.ListRuntimes(), ", "),
 func init() {
 	f := generateClientCmd.Flags()
 	f.StringVar(&genClientRuntime, "runtime", "", "target runtime: "+strings.Join(clientgen.ListRuntimes(), ", "))
-	f.StringVar(&genClientSchema, "schema", "", "path to schema.fga or fga.mod file")
+	f.StringVar(&genClientSchema, "schema", "", "path to schema.fga, fga.mod, or a directory of .fga files")
 	f.StringVar(&genClientOutput, "output", "", "output directory or file path (default: stdout)")
 	f.StringVar(&genClientPackage, "package", "", "package/module name (default: authz)")
 	f.StringVar(&genClientFilter, "filter", "", "relation prefix filter (e.g., can_)")
 	f.StringVar(&genClientIDType, "id-type", "", "ID type for constructors (default: string)")
+	f.BoolVar(&genClientSplit, "split-by-type", false, "emit one file per object type plus a shared file")
+	f.StringVar(&genClientDBSchema, "db-schema", "", "database schema the functions are installed in; generated calls are qualified with it")
+	f.IntVar(&genClientConcurrency, "concurrency", 0, "checks the Go FilterAccessible functions run at once (default 8)")
+	f.BoolVar(&genClientCheckMemo, "check-memo", false, "have the Go FilterAccessible functions call check_permission, for a database migrated with --check-memo (default: migrate.check_memo)")
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pthm/melange/lib/cli"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+var (
+	genManifestSchema   string
+	genManifestDBSchema string
+	genManifestOutput   string
+)
+
+var generateManifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Generate a function manifest for drift detection",
+	Long: `Generate a JSON manifest of the functions the schema compiles to: names,
+argument types, and normalized body checksums.
+
+Commit the manifest next to the schema and run 'melange doctor --baseline' in
+CI to fail when a database does not hold exactly these functions. The manifest
+reflects default code generation; it does not include opt-in migrate options
+such as --pooler-safe.`,
+	Example: `  # Write the manifest next to the schema
+  melange generate manifest --schema schemas/schema.fga --output schemas/manifest.json
+
+  # For functions installed in a custom database schema
+  melange generate manifest --schema schemas/schema.fga --db-schema authz --output schemas/manifest.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		databaseSchema := resolveString(genManifestDBSchema, cfg.Database.Schema)
+		schemaPath := resolveString(genManifestSchema, cfg.Schema)
+
+		if schemaPath == "" {
+			return cli.ConfigError("--schema is required", nil)
+		}
+		if _, err := os.Stat(schemaPath); err != nil {
+			return cli.SchemaParseError(fmt.Sprintf("schema not found: %s", schemaPath), nil)
+		}
+
+		types, err := parser.ParseSchema(schemaPath)
+		if err != nil {
+			return cli.SchemaParseError("parsing schema", err)
+		}
+		if err := schema.DetectCycles(types); err != nil {
+			return cli.SchemaParseError("schema has cycles", err)
+		}
+		if err := schema.ValidateTupleToUsersets(types); err != nil {
+			return cli.SchemaParseError("invalid tuple-to-userset", err)
+		}
+
+		m := migrator.NewMigrator(nil, schemaPath)
+		m.SetDatabaseSchema(databaseSchema)
+		m.SetTuplesTable(cfg.Database.TuplesTable)
+		manifest, err := m.GenerateManifest(types)
+		if err != nil {
+			return cli.GeneralError("generating manifest", err)
+		}
+
+		if genManifestOutput == "" {
+			return manifest.Write(os.Stdout)
+		}
+
+		f, err := os.Create(genManifestOutput)
+		if err != nil {
+			return cli.GeneralError("creating output file", err)
+		}
+		if err := manifest.Write(f); err != nil {
+			_ = f.Close()
+			return cli.GeneralError("writing manifest", err)
+		}
+		if err := f.Close(); err != nil {
+			return cli.GeneralError("writing manifest", err)
+		}
+
+		if !quiet {
+			fmt.Printf("Generated %s (%d functions)\n", genManifestOutput, len(manifest.Functions))
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := generateManifestCmd.Flags()
+	f.StringVar(&genManifestSchema, "schema", "", "path to .fga file, fga.mod manifest, or directory of .fga files")
+	f.StringVar(&genManifestDBSchema, "db-schema", "public", "database schema")
+	f.StringVar(&genManifestOutput, "output", "", "output file (default: stdout)")
+}
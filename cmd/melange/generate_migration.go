@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -94,11 +96,6 @@ Three comparison modes determine orphaned functions to drop:
 			return cli.SchemaParseError(fmt.Sprintf("schema not found: %s", schemaPath), nil)
 		}
 
-		schemaContent, err := parser.ReadSchemaContent(schemaPath)
-		if err != nil {
-			return cli.GeneralError("reading schema", err)
-		}
-
 		types, err := parser.ParseSchema(schemaPath)
 		if err != nil {
 			return cli.SchemaParseError("parsing schema", err)
@@ -108,13 +105,16 @@ Three comparison modes determine orphaned functions to drop:
 		if err := schema.DetectCycles(types); err != nil {
 			return cli.SchemaParseError("schema has cycles", err)
 		}
+		if err := schema.ValidateTupleToUsersets(types); err != nil {
+			return cli.SchemaParseError("invalid tuple-to-userset", err)
+		}
 
 		closureRows := schema.ComputeRelationClosure(types)
 		analyses := compiler.AnalyzeRelations(types, closureRows)
 		analyses = compiler.ComputeCanGenerate(analyses)
 		inlineData := compiler.BuildInlineSQLData(closureRows, analyses)
 
-		generatedSQL, err := compiler.GenerateSQL(analyses, inlineData, databaseSchema)
+		generatedSQL, err := compiler.GenerateSQLWithOptions(analyses, inlineData, databaseSchema, compiler.GenerateSQLOptions{SchemaHash: migrator.SchemaHash(types)})
 		if err != nil {
 			return cli.GeneralError("generating check SQL", err)
 		}
@@ -125,13 +125,17 @@ Three comparison modes determine orphaned functions to drop:
 		}
 
 		expectedFunctions := compiler.CollectFunctionNames(analyses)
+		if generatedSQL.ContextualTuplesFunction != "" {
+			expectedFunctions = append(expectedFunctions, compiler.ContextualTuplesFunctionName)
+		}
+		generatedSQL.HealthcheckFunction = compiler.GenerateHealthcheckFunction(databaseSchema, "", migrator.SchemaHash(types), expectedFunctions, "")
 		namedFunctions := compiler.CollectNamedFunctions(generatedSQL, listSQL, analyses)
 
 		// Resolve previous state
 		opts := compiler.MigrationOptions{
 			DatabaseSchema: databaseSchema,
 			Version:        version.Version,
-			SchemaChecksum: migrator.ComputeSchemaChecksum(string(schemaContent)),
+			SchemaChecksum: migrator.SchemaHash(types),
 			CodegenVersion: migrator.CodegenVersion(),
 			NamedFunctions: namedFunctions,
 		}
@@ -180,7 +184,7 @@ Three comparison modes determine orphaned functions to drop:
 
 func init() {
 	f := generateMigrationCmd.Flags()
-	f.StringVar(&genMigrationSchema, "schema", "", "path to .fga file or fga.mod manifest")
+	f.StringVar(&genMigrationSchema, "schema", "", "path to .fga file, fga.mod manifest, or directory of .fga files")
 	f.StringVar(&genMigrationOutput, "output", "", "output directory (default: stdout)")
 	f.StringVar(&genMigrationName, "name", "", "migration name suffix (default: melange)")
 	f.StringVar(&genMigrationFormat, "format", "", `"split" (.up.sql/.down.sql) or "single" (default: split)`)
@@ -189,7 +193,7 @@ func init() {
 	f.StringVar(&genMigrationDB, "db", "", "database URL for comparison (reads previous state)")
 	f.StringVar(&genMigrationDBSchema, "db-schema", "public", "database schema")
 	f.StringVar(&genMigrationGitRef, "git-ref", "", "git ref for comparison (reads previous schema)")
-	f.StringVar(&genMigrationPreviousSchema, "previous-schema", "", "path to previous .fga file for comparison (modular schemas not supported)")
+	f.StringVar(&genMigrationPreviousSchema, "previous-schema", "", "path to previous .fga file or directory of .fga files for comparison (modular schemas not supported)")
 }
 
 func writeStdout(result compiler.MigrationSQL) error {
@@ -327,6 +331,9 @@ func previousStateFromSchema(pathOrRef, schemaPath, databaseSchema string, isGit
 	}
 
 	names := compiler.CollectFunctionNames(analyses)
+	if genSQL.ContextualTuplesFunction != "" {
+		names = append(names, compiler.ContextualTuplesFunctionName)
+	}
 	namedFns := compiler.CollectNamedFunctions(genSQL, listSQL, analyses)
 	checksums := migrator.ComputeFunctionChecksums(namedFns)
 
@@ -337,12 +344,23 @@ func previousStateFromSchema(pathOrRef, schemaPath, databaseSchema string, isGit
 }
 
 // parsePreviousSchema reads and parses a previous schema from either a git ref
-// or a local file path. Supports both single .fga files and fga.mod manifests
-// (manifests only via git-ref; --previous-schema rejects them earlier).
+// or a local file path. Supports single .fga files, fga.mod manifests
+// (manifests only via git-ref; --previous-schema rejects them earlier) and
+// directories of .fga files.
 func parsePreviousSchema(pathOrRef, schemaPath string, isGitRef bool) ([]schema.TypeDefinition, error) {
 	if isGitRef && parser.IsModularSchema(schemaPath) {
 		return parseModularSchemaFromGit(pathOrRef, schemaPath)
 	}
+	if isGitRef && isDirectory(schemaPath) {
+		return parseSchemaDirFromGit(pathOrRef, schemaPath)
+	}
+	if !isGitRef && isDirectory(pathOrRef) {
+		types, err := parser.ParseSchemaDir(pathOrRef)
+		if err != nil {
+			return nil, cli.SchemaParseError("parsing previous schema", err)
+		}
+		return types, nil
+	}
 
 	var content string
 	if isGitRef {
@@ -407,6 +425,54 @@ func parseModularSchemaFromGit(gitRef, manifestPath string) ([]schema.TypeDefini
 	return types, nil
 }
 
+// parseSchemaDirFromGit reads a schema directory from a git ref: the
+// directory's fga.mod manifest when the ref has one, and otherwise the .fga
+// files directly inside it, merged as parser.ParseSchemaDir merges them.
+func parseSchemaDirFromGit(gitRef, dir string) ([]schema.TypeDefinition, error) {
+	cmd := exec.Command("git", "ls-tree", "--name-only", gitRef, "--", filepath.ToSlash(dir)+"/") //nolint:gosec // ref and path are from trusted CLI flags
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, cli.GeneralError(
+			fmt.Sprintf("listing schema directory from git ref %q (path: %s)", gitRef, dir),
+			fmt.Errorf("%w — ensure the ref exists and the schema path is relative to the repo root", err),
+		)
+	}
+
+	var paths []string
+	for _, p := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if path.Base(p) == "fga.mod" {
+			return parseModularSchemaFromGit(gitRef, p)
+		}
+		if path.Ext(p) == ".fga" {
+			paths = append(paths, p)
+		}
+	}
+
+	files := make(map[string]string, len(paths))
+	for _, p := range paths {
+		content, err := gitShowFile(gitRef, p)
+		if err != nil {
+			return nil, cli.GeneralError(fmt.Sprintf("reading %s from git ref %q", p, gitRef), err)
+		}
+		files[path.Base(p)] = content
+	}
+	if len(files) == 0 {
+		return nil, cli.SchemaParseError(fmt.Sprintf("no .fga files in %s at git ref %q", dir, gitRef), nil)
+	}
+
+	types, err := parser.ParseSchemaFiles(files)
+	if err != nil {
+		return nil, cli.SchemaParseError("parsing schema directory from git ref", err)
+	}
+	return types, nil
+}
+
+// isDirectory reports whether path names a directory.
+func isDirectory(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && info.IsDir()
+}
+
 // gitShowFile reads a file from a git ref using "git show ref:path".
 func gitShowFile(ref, path string) (string, error) {
 	cmd := exec.Command("git", "show", ref+":"+path) //nolint:gosec // ref and path are from trusted CLI flags
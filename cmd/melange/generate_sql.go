@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pthm/melange/lib/cli"
+	"github.com/pthm/melange/lib/version"
+	"github.com/pthm/melange/pkg/compiler"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+var (
+	genSQLSchema   string
+	genSQLDBSchema string
+	genSQLOutput   string
+	genSQLFilter   []string
+	genSQLGen      generationFlags
+)
+
+var generateSQLCmd = &cobra.Command{
+	Use:   "sql",
+	Short: "Generate the SQL for a schema without a database",
+	Long: `Generate every function and dispatcher the schema compiles to as one SQL
+script wrapped in a transaction, in the order a migration creates them.
+
+Unlike 'melange migrate --dry-run' it needs no database connection, and unlike
+'melange generate migration' it writes a single script with no DOWN half and
+no migration record. Use it to review generated SQL or to hand it to another
+migration tool.
+
+It takes the flags and migrate config that change the generated SQL
+(--tuples-table, --max-depth, --dialect, --pooler-safe and so on), so the
+script matches what 'melange migrate' installs with the same settings.
+
+--filter limits the script to the functions of matching relations, given as
+type.relation patterns with * wildcards. Dispatchers route every relation, so
+a filtered script leaves them out and is meant for review.`,
+	Example: `  # Write the SQL for a schema to a file
+  melange generate sql --schema schema.fga --output melange.sql
+
+  # Generate what 'melange migrate --dialect cockroach --max-depth 40' installs
+  melange generate sql --schema schema.fga --dialect cockroach --max-depth 40
+
+  # Print the functions of document.viewer
+  melange generate sql --schema schema.fga --filter document.viewer
+
+  # Print the functions of every document relation and of folder.viewer
+  melange generate sql --schema schema.fga --filter 'document.*' --filter folder.viewer`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		databaseSchema := resolveString(genSQLDBSchema, cfg.Database.Schema)
+		schemaPath := resolveString(genSQLSchema, cfg.Schema)
+
+		if schemaPath == "" {
+			return cli.ConfigError("--schema is required", nil)
+		}
+		if _, err := os.Stat(schemaPath); err != nil {
+			return cli.SchemaParseError(fmt.Sprintf("schema not found: %s", schemaPath), nil)
+		}
+
+		types, err := parser.ParseSchema(schemaPath)
+		if err != nil {
+			return cli.SchemaParseError("parsing schema", err)
+		}
+		if err := schema.DetectCycles(types); err != nil {
+			return cli.SchemaParseError("schema has cycles", err)
+		}
+		if err := schema.ValidateTupleToUsersets(types); err != nil {
+			return cli.SchemaParseError("invalid tuple-to-userset", err)
+		}
+
+		opts := migrator.MigrateOptions{DatabaseSchema: databaseSchema}
+		genSQLGen.apply(&opts)
+		generatedSQL, listSQL, analyses, err := migrator.GenerateWithOptions(types, opts)
+		if err != nil {
+			return cli.GeneralError("generating SQL", err)
+		}
+
+		script, err := compiler.GenerateScriptSQL(generatedSQL, listSQL, analyses, compiler.ScriptOptions{
+			Version:        version.Version,
+			SchemaChecksum: migrator.SchemaHash(types),
+			CodegenVersion: migrator.CodegenVersion(),
+			Relations:      genSQLFilter,
+		})
+		if err != nil {
+			return cli.ConfigError("--filter", err)
+		}
+
+		if genSQLOutput == "" {
+			if _, err := fmt.Fprint(os.Stdout, script); err != nil {
+				return cli.GeneralError("writing to stdout", err)
+			}
+			return nil
+		}
+		if err := os.WriteFile(genSQLOutput, []byte(script), 0o644); err != nil {
+			return cli.GeneralError(fmt.Sprintf("writing %s", genSQLOutput), err)
+		}
+		if !quiet {
+			fmt.Printf("Generated %s\n", genSQLOutput)
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := generateSQLCmd.Flags()
+	f.StringVar(&genSQLSchema, "schema", "", "path to .fga file, fga.mod manifest, or directory of .fga files")
+	f.StringVar(&genSQLDBSchema, "db-schema", "public", "database schema")
+	f.StringVar(&genSQLOutput, "output", "", "output file (default: stdout)")
+	genSQLGen.register(generateSQLCmd)
+	f.StringSliceVar(&genSQLFilter, "filter", nil, "only output the functions of relations matching these type.relation patterns (repeatable)")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/pthm/melange/pkg/migrator"
+)
+
+// generationFlags are the flags that change the generated SQL. 'melange
+// migrate' and 'melange generate sql' both register them, so a script from
+// generate sql is the SQL migrate would install with the same flags and
+// config.
+type generationFlags struct {
+	tuplesTable     string
+	effectiveAccess bool
+	checkEvidence   bool
+	strictCheck     bool
+	poolerSafe      bool
+	checkMemo       bool
+	tableRouted     bool
+	anytime         bool
+	closureFunction bool
+	listCursor      bool
+	expandSubjects  bool
+	objectDelimiter string
+	maxDepth        int
+	noNullGuards    bool
+	dialect         string
+}
+
+// register adds the generation flags to cmd.
+func (g *generationFlags) register(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringVar(&g.tuplesTable, "tuples-table", "", "relation the generated functions read tuples from, optionally schema-qualified (default \"melange_tuples\")")
+	f.BoolVar(&g.effectiveAccess, "effective-access", false, "also install the effective_access audit function")
+	f.BoolVar(&g.checkEvidence, "check-evidence", false, "also install check_with_evidence_* functions that return the granting tuple")
+	f.BoolVar(&g.strictCheck, "strict-check", false, "also install check_permission_strict, which resolves every userset grant through a full recursive check")
+	f.BoolVar(&g.poolerSafe, "pooler-safe", false, "generate functions that read no session-level settings (for PgBouncer transaction pooling)")
+	f.BoolVar(&g.checkMemo, "check-memo", false, "memoize repeated sub-checks within each check_permission call (disables parallel plans for it)")
+	f.BoolVar(&g.tableRouted, "table-routed-dispatcher", false, "route check_permission through the melange_routes table instead of a per-relation IF-chain")
+	f.BoolVar(&g.anytime, "anytime-list-objects", false, "return base-level grants before recursively found objects from unpaged list_objects calls")
+	f.BoolVar(&g.closureFunction, "closure-function", false, "have check and list functions call the melange_closure_rows and melange_userset_rows functions instead of inlining the relation closure and userset rows")
+	f.BoolVar(&g.listCursor, "list-objects-cursor", false, "also install list_accessible_objects_cursor and list_*_objects_cursor, which return a refcursor to FETCH in batches")
+	f.BoolVar(&g.expandSubjects, "expand-wildcard-subjects", false, "have list_subjects calls with p_expand_wildcard read the subjects a wildcard covers from the melange_subjects view")
+	f.StringVar(&g.objectDelimiter, "object-delimiter", "", `separator between type and id in the "type:id" string overloads (default ":")`)
+	f.IntVar(&g.maxDepth, "max-depth", 0, "levels of recursion generated functions follow before raising M2002 (default 25)")
+	f.BoolVar(&g.noNullGuards, "disable-null-guards", false, "leave out the checks that make generated check and list functions raise on NULL required arguments")
+	f.StringVar(&g.dialect, "dialect", "", "database the generated functions target: postgres or cockroach (default \"postgres\")")
+}
+
+// apply resolves each flag against its config value (flag > config) and
+// sets the result on opts.
+func (g *generationFlags) apply(opts *migrator.MigrateOptions) {
+	opts.TuplesTable = resolveString(g.tuplesTable, cfg.Database.TuplesTable)
+	opts.EnableEffectiveAccess = resolveBool(g.effectiveAccess, cfg.Migrate.EffectiveAccess)
+	opts.EnableCheckEvidence = resolveBool(g.checkEvidence, cfg.Migrate.CheckEvidence)
+	opts.EnableStrictCheck = resolveBool(g.strictCheck, cfg.Migrate.StrictCheck)
+	opts.PoolerSafe = resolveBool(g.poolerSafe, cfg.Migrate.PoolerSafe)
+	opts.EnableCheckMemo = resolveBool(g.checkMemo, cfg.Migrate.CheckMemo)
+	opts.TableRoutedDispatcher = resolveBool(g.tableRouted, cfg.Migrate.TableRoutedDispatcher)
+	opts.AnytimeListObjects = resolveBool(g.anytime, cfg.Migrate.AnytimeListObjects)
+	opts.ClosureFunction = resolveBool(g.closureFunction, cfg.Migrate.ClosureFunction)
+	opts.EnableListObjectsCursor = resolveBool(g.listCursor, cfg.Migrate.ListObjectsCursor)
+	opts.ExpandWildcardSubjects = resolveBool(g.expandSubjects, cfg.Migrate.ExpandWildcardSubjects)
+	opts.ObjectDelimiter = resolveString(g.objectDelimiter, cfg.Migrate.ObjectDelimiter)
+	opts.MaxDepth = resolveInt(g.maxDepth, cfg.Migrate.MaxDepth)
+	opts.DisableNullGuards = resolveBool(g.noNullGuards, cfg.Migrate.DisableNullGuards)
+	opts.Dialect = resolveString(g.dialect, cfg.Migrate.Dialect)
+}
@@ -5,7 +5,9 @@
 //   - generate client: Produce type-safe client code for Go or TypeScript
 //   - migrate: Load schema into PostgreSQL (creates tables and functions)
 //   - status: Check current migration state
+//   - diff: Show which functions migrate would add, remove, or change
 //   - doctor: Run health checks on authorization infrastructure
+//   - test: Run the assertions of an OpenFGA store file against the database
 //   - version: Print version information
 //   - license: Print license and third-party notices
 //   - config show: Display effective configuration
@@ -23,7 +25,7 @@
 //
 //	melange [flags] <command>
 //
-// Commands that require database access (migrate, status) need -db or MELANGE_DATABASE_URL.
+// Commands that require database access (migrate, status, diff, test) need -db or MELANGE_DATABASE_URL.
 // Commands that only work with files (validate, generate) do not need database access.
 package main
 
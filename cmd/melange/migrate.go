@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/pthm/melange/lib/cli"
 	"github.com/pthm/melange/lib/version"
 	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
 )
 
 var (
@@ -20,7 +22,16 @@ var (
 	migrateDBSchema string
 	migrateSchema   string
 	migrateDryRun   bool
+	migrateNoTx     bool
 	migrateForce    bool
+	migrateMaxFns   int
+	migrateGen      generationFlags
+	migrateShadow   string
+	migratePromote  string
+	migrateDropShdw string
+	migrateUninst   bool
+	migrateDropTups bool
+	migrateDown     bool
 )
 
 var migrateCmd = &cobra.Command{
@@ -36,8 +47,77 @@ var migrateCmd = &cobra.Command{
   # Preview migration without applying
   melange migrate --db postgres://localhost/mydb --dry-run
 
+  # Write a script for a migration tool that manages its own transactions
+  melange migrate --db postgres://localhost/mydb --dry-run --no-transaction > V42__authz.sql
+
   # Force re-apply even if schema unchanged
-  melange migrate --db postgres://localhost/mydb --force`,
+  melange migrate --db postgres://localhost/mydb --force
+
+  # Also install the effective_access audit function
+  melange migrate --db postgres://localhost/mydb --effective-access
+
+  # Also install check_with_evidence_* functions for audit logging
+  melange migrate --db postgres://localhost/mydb --check-evidence
+
+  # Also install check_permission_strict, which re-checks every userset grant
+  melange migrate --db postgres://localhost/mydb --strict-check
+
+  # Generate functions safe behind a transaction-mode pooler (PgBouncer)
+  melange migrate --db postgres://localhost/mydb --pooler-safe
+
+  # Memoize repeated sub-checks within each check_permission call
+  melange migrate --db postgres://localhost/mydb --check-memo
+
+  # Route check_permission through the melange_routes table (very large schemas)
+  melange migrate --db postgres://localhost/mydb --table-routed-dispatcher
+
+  # Return direct grants before recursively found objects from list_objects
+  melange migrate --db postgres://localhost/mydb --anytime-list-objects
+
+  # Look the relation closure and userset rows up through shared functions
+  melange migrate --db postgres://localhost/mydb --closure-function
+
+  # Also install refcursor list_objects functions for batched FETCHes
+  melange migrate --db postgres://localhost/mydb --list-objects-cursor
+
+  # Expand wildcard grants in list_subjects from the melange_subjects view
+  melange migrate --db postgres://localhost/mydb --expand-wildcard-subjects
+
+  # Read tuples from tenant_42.tuples instead of melange_tuples
+  melange migrate --db postgres://localhost/mydb --tuples-table tenant_42.tuples
+
+  # Split type:id string arguments on "/" instead of ":"
+  melange migrate --db postgres://localhost/mydb --object-delimiter /
+
+  # Follow recursive relations up to 40 levels before raising M2002
+  melange migrate --db postgres://localhost/mydb --max-depth 40
+
+  # Skip the NULL argument checks in generated functions
+  melange migrate --db postgres://localhost/mydb --disable-null-guards
+
+  # Generate functions CockroachDB accepts
+  melange migrate --db postgres://localhost:26257/mydb --dialect cockroach
+
+  # Refuse to install more than 2000 functions
+  melange migrate --db postgres://localhost/mydb --max-functions 2000
+
+  # Install the schema into a shadow schema to compare against the live one
+  melange migrate --db postgres://localhost/mydb --shadow melange_shadow
+
+  # Make the shadowed schema live and drop the shadow
+  melange migrate --db postgres://localhost/mydb --promote-shadow melange_shadow
+
+  # Abandon the shadow
+  melange migrate --db postgres://localhost/mydb --drop-shadow melange_shadow
+
+  # List what uninstalling melange would drop
+  melange migrate --db postgres://localhost/mydb --uninstall --dry-run
+
+  # Drop every function and table melange created (melange_tuples is kept)
+  melange migrate --db postgres://localhost/mydb --uninstall
+
+  # Roll back: drop the functions previous migrations recorded
+  melange migrate --db postgres://localhost/mydb --down`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Warn if generate.migration.output is configured
 		if cfg.Generate.Migration.Output != "" && !quiet {
@@ -52,7 +132,14 @@ var migrateCmd = &cobra.Command{
 		databaseSchema := resolveString(migrateDBSchema, cfg.Database.Schema)
 		schemaPath := resolveString(migrateSchema, cfg.Schema)
 		dryRun := resolveBool(migrateDryRun, cfg.Migrate.DryRun)
-		force := resolveBool(migrateForce, cfg.Migrate.Force)
+		noTransaction := resolveBool(migrateNoTx, cfg.Migrate.NoTransaction)
+		opts := migrator.MigrateOptions{
+			Force:          resolveBool(migrateForce, cfg.Migrate.Force),
+			MaxFunctions:   resolveInt(migrateMaxFns, cfg.Migrate.MaxFunctions),
+			Version:        version.Version,
+			DatabaseSchema: databaseSchema,
+		}
+		migrateGen.apply(&opts)
 
 		// Get DSN
 		dsn, err := resolveDSN(migrateDB)
@@ -60,7 +147,27 @@ var migrateCmd = &cobra.Command{
 			return err
 		}
 
-		return runMigrate(dsn, schemaPath, dryRun, force, databaseSchema)
+		if migrateDropTups && !migrateUninst {
+			return cli.ConfigError("--drop-tuples requires --uninstall", nil)
+		}
+		if migrateNoTx && !dryRun {
+			return cli.ConfigError("--no-transaction requires --dry-run", nil)
+		}
+		if migrateUninst {
+			return runUninstall(dsn, databaseSchema, opts.TuplesTable, dryRun)
+		}
+		if migrateDown {
+			return runRollback(dsn, databaseSchema, dryRun)
+		}
+
+		if migrateShadow != "" || migratePromote != "" || migrateDropShdw != "" {
+			if dryRun {
+				return cli.ConfigError("--dry-run cannot be combined with --shadow, --promote-shadow or --drop-shadow", nil)
+			}
+			return runShadow(dsn, schemaPath, opts)
+		}
+
+		return runMigrate(dsn, schemaPath, dryRun, noTransaction, opts)
 	},
 }
 
@@ -68,9 +175,19 @@ func init() {
 	f := migrateCmd.Flags()
 	f.StringVar(&migrateDB, "db", "", "database URL")
 	f.StringVar(&migrateDBSchema, "db-schema", "public", "database schema")
-	f.StringVar(&migrateSchema, "schema", "", "path to schema.fga or fga.mod file")
+	f.StringVar(&migrateSchema, "schema", "", "path to schema.fga, fga.mod, or a directory of .fga files")
 	f.BoolVar(&migrateDryRun, "dry-run", false, "output migration SQL without applying")
+	f.BoolVar(&migrateNoTx, "no-transaction", false, "with --dry-run, leave BEGIN and COMMIT out of the script")
 	f.BoolVar(&migrateForce, "force", false, "force migration even if schema unchanged")
+	migrateGen.register(migrateCmd)
+	f.IntVar(&migrateMaxFns, "max-functions", 0, "fail before applying anything if the schema compiles to more functions than this (0 = no limit)")
+	f.StringVar(&migrateShadow, "shadow", "", "install into this throwaway schema instead, leaving the live functions untouched")
+	f.StringVar(&migratePromote, "promote-shadow", "", "apply the schema installed in this shadow schema to the database schema, then drop the shadow")
+	f.StringVar(&migrateDropShdw, "drop-shadow", "", "drop this shadow schema without promoting it")
+	f.BoolVar(&migrateUninst, "uninstall", false, "drop every function and table melange created instead of migrating (keeps the tuples relation)")
+	f.BoolVar(&migrateDropTups, "drop-tuples", false, "with --uninstall, also drop the tuples relation")
+	f.BoolVar(&migrateDown, "down", false, "drop the functions recorded by previous migrations and clear the migration history instead of migrating")
+	migrateCmd.MarkFlagsMutuallyExclusive("shadow", "promote-shadow", "drop-shadow", "uninstall", "down")
 }
 
 // resolveDSN gets the database DSN from flag or config.
@@ -89,7 +206,7 @@ func resolveDSN(flagDSN string) (string, error) {
 	return dsn, nil
 }
 
-func runMigrate(dsn, schemaPath string, dryRun, force bool, databaseSchema string) error {
+func runMigrate(dsn, schemaPath string, dryRun, noTransaction bool, opts migrator.MigrateOptions) error {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return cli.DBConnectError("connecting to database", err)
@@ -98,14 +215,9 @@ func runMigrate(dsn, schemaPath string, dryRun, force bool, databaseSchema strin
 
 	ctx := context.Background()
 
-	opts := migrator.MigrateOptions{
-		Force:          force,
-		Version:        version.Version,
-		DatabaseSchema: databaseSchema,
-	}
-
 	if dryRun {
 		opts.DryRun = os.Stdout
+		opts.NoTransaction = noTransaction
 		if !quiet {
 			fmt.Fprintln(os.Stderr, "-- Dry-run mode: SQL will be output but not applied")
 			fmt.Fprintln(os.Stderr, "")
@@ -137,16 +249,146 @@ func runMigrate(dsn, schemaPath string, dryRun, force bool, databaseSchema strin
 		}
 	}
 
-	// Check for melange_tuples warning
+	// Check for tuples relation warning
 	m := migrator.NewMigrator(db, schemaPath)
-	m.SetDatabaseSchema(databaseSchema)
+	m.SetDatabaseSchema(opts.DatabaseSchema)
+	m.SetTuplesTable(opts.TuplesTable)
+
+	if types, err := parser.ParseSchema(schemaPath); err == nil && !quiet {
+		for _, warning := range nameCollisionWarnings(types) {
+			fmt.Println()
+			fmt.Println(warning)
+		}
+	}
 
 	status, err := m.GetStatus(ctx)
 	if err == nil && !status.TuplesExists && !quiet {
 		fmt.Println()
-		fmt.Println("WARNING: melange_tuples view/table does not exist.")
+		fmt.Printf("WARNING: %s view/table does not exist.\n", m.TuplesTable())
 		fmt.Println("         Permission checks will fail until you create it.")
 	}
 
 	return nil
 }
+
+// runShadow runs whichever of --shadow, --promote-shadow and --drop-shadow was
+// given.
+func runShadow(dsn, schemaPath string, opts migrator.MigrateOptions) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return cli.DBConnectError("connecting to database", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+
+	classify := func(err error) error {
+		if strings.Contains(err.Error(), "parsing schema") {
+			return cli.SchemaParseError("schema error", err)
+		}
+		return cli.GeneralError("shadow migration failed", err)
+	}
+
+	switch {
+	case migrateShadow != "":
+		if err := migrator.MigrateShadow(ctx, db, schemaPath, migrateShadow, opts); err != nil {
+			return classify(err)
+		}
+		if !quiet {
+			fmt.Printf("Authz schema installed in shadow schema %q.\n", migrateShadow)
+			fmt.Printf("Compare it with %q, then run with --promote-shadow %s.\n", opts.DatabaseSchema, migrateShadow)
+		}
+	case migratePromote != "":
+		skipped, err := migrator.PromoteShadow(ctx, db, schemaPath, migratePromote, opts)
+		if err != nil {
+			return classify(err)
+		}
+		if !quiet {
+			if skipped {
+				fmt.Println("Schema already live, shadow dropped.")
+			} else {
+				fmt.Printf("Shadow schema %q promoted and dropped.\n", migratePromote)
+			}
+		}
+	default:
+		if err := migrator.DropShadow(ctx, db, migrateDropShdw); err != nil {
+			return classify(err)
+		}
+		if !quiet {
+			fmt.Printf("Shadow schema %q dropped.\n", migrateDropShdw)
+		}
+	}
+	return nil
+}
+
+// runRollback drops the functions recorded in melange_migrations, or prints
+// the statements that would do so with dryRun.
+func runRollback(dsn, databaseSchema string, dryRun bool) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return cli.DBConnectError("connecting to database", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	m := migrator.NewMigrator(db, "")
+	m.SetDatabaseSchema(databaseSchema)
+
+	var w io.Writer
+	if dryRun {
+		w = os.Stdout
+		if !quiet {
+			fmt.Fprintln(os.Stderr, "-- Dry-run mode: SQL will be output but not applied")
+			fmt.Fprintln(os.Stderr, "")
+		}
+	}
+
+	stmts, err := m.Rollback(context.Background(), w)
+	if err != nil {
+		return cli.GeneralError("rollback failed", err)
+	}
+	if !dryRun && !quiet {
+		if len(stmts) == 0 {
+			fmt.Println("No recorded migration to roll back.")
+		} else {
+			fmt.Println("Rolled back the recorded migration.")
+		}
+	}
+	return nil
+}
+
+// runUninstall drops everything melange created in databaseSchema, or prints
+// the statements that would do so with dryRun. tuplesTable names the relation
+// --drop-tuples drops.
+func runUninstall(dsn, databaseSchema, tuplesTable string, dryRun bool) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return cli.DBConnectError("connecting to database", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	opts := migrator.UninstallOptions{
+		DatabaseSchema: databaseSchema,
+		DropTuples:     migrateDropTups,
+		TuplesTable:    tuplesTable,
+	}
+	if dryRun {
+		opts.DryRun = os.Stdout
+		if !quiet {
+			fmt.Fprintln(os.Stderr, "-- Dry-run mode: SQL will be output but not applied")
+			fmt.Fprintln(os.Stderr, "")
+		}
+	}
+
+	dropped, err := migrator.Uninstall(context.Background(), db, opts)
+	if err != nil {
+		return cli.GeneralError("uninstall failed", err)
+	}
+	if !dryRun && !quiet {
+		if len(dropped) == 0 {
+			fmt.Println("Nothing to uninstall.")
+		} else {
+			fmt.Printf("Dropped %d melange objects.\n", len(dropped))
+		}
+	}
+	return nil
+}
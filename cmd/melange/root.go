@@ -88,15 +88,19 @@ func init() {
 	validateCmd.GroupID = groupSchema
 	migrateCmd.GroupID = groupSchema
 	statusCmd.GroupID = groupSchema
+	diffCmd.GroupID = groupSchema
 	doctorCmd.GroupID = groupSchema
 	explainCmd.GroupID = groupSchema
 	expandCmd.GroupID = groupSchema
+	testCmd.GroupID = groupSchema
 	rootCmd.AddCommand(validateCmd)
 	rootCmd.AddCommand(migrateCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(diffCmd)
 	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(explainCmd)
 	rootCmd.AddCommand(expandCmd)
+	rootCmd.AddCommand(testCmd)
 
 	// Client commands
 	generateCmd.GroupID = groupClient
@@ -142,6 +146,17 @@ func resolveBool(values ...bool) bool {
 	return false
 }
 
+// resolveInt returns the first non-zero int from the provided values.
+// Used to implement precedence: flag > config > default.
+func resolveInt(values ...int) int {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
+
 // boolCount returns the number of true values.
 func boolCount(values ...bool) int {
 	n := 0
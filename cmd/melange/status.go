@@ -3,7 +3,10 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"os"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/spf13/cobra"
@@ -15,7 +18,10 @@ import (
 var (
 	statusDB       string
 	statusDBSchema string
+	statusTuples   string
 	statusSchema   string
+	statusHistory  int
+	statusFormat   string
 )
 
 var statusCmd = &cobra.Command{
@@ -26,9 +32,16 @@ var statusCmd = &cobra.Command{
   melange status --db postgres://localhost/mydb
 
   # Use a different database schema
-  melange status --db postgres://localhost/mydb --db-schema myschema`,
+  melange status --db postgres://localhost/mydb --db-schema myschema
+
+  # Show the last 20 applied migrations
+  melange status --db postgres://localhost/mydb --history 20
+
+  # Emit the status as JSON for CI
+  melange status --db postgres://localhost/mydb --format json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		databaseSchema := resolveString(statusDBSchema, cfg.Database.Schema)
+		tuplesTable := resolveString(statusTuples, cfg.Database.TuplesTable)
 		schemaPath := resolveString(statusSchema, cfg.Schema)
 
 		dsn, err := resolveDSN(statusDB)
@@ -36,7 +49,7 @@ var statusCmd = &cobra.Command{
 			return err
 		}
 
-		return runStatus(dsn, databaseSchema, schemaPath)
+		return runStatus(dsn, databaseSchema, tuplesTable, schemaPath, statusHistory, statusFormat)
 	},
 }
 
@@ -44,10 +57,17 @@ func init() {
 	f := statusCmd.Flags()
 	f.StringVar(&statusDB, "db", "", "database URL")
 	f.StringVar(&statusDBSchema, "db-schema", "public", "database schema")
-	f.StringVar(&statusSchema, "schema", "", "path to schema.fga or fga.mod file")
+	f.StringVar(&statusTuples, "tuples-table", "", "tuples relation, optionally schema-qualified (default \"melange_tuples\")")
+	f.StringVar(&statusSchema, "schema", "", "path to schema.fga, fga.mod, or a directory of .fga files")
+	f.IntVar(&statusHistory, "history", 5, "number of applied migrations to show (0 to hide)")
+	f.StringVar(&statusFormat, "format", "text", "output format: text (default) or json")
 }
 
-func runStatus(dsn, databaseSchema, schemaPath string) error {
+func runStatus(dsn, databaseSchema, tuplesTable, schemaPath string, historyLimit int, format string) error {
+	if err := checkOutputFormat(format); err != nil {
+		return err
+	}
+
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return cli.DBConnectError("connecting to database", err)
@@ -57,12 +77,32 @@ func runStatus(dsn, databaseSchema, schemaPath string) error {
 	ctx := context.Background()
 	m := migrator.NewMigrator(db, schemaPath)
 	m.SetDatabaseSchema(databaseSchema)
+	m.SetTuplesTable(tuplesTable)
 
 	s, err := m.GetStatus(ctx)
 	if err != nil {
 		return cli.GeneralError("getting status", err)
 	}
 
+	history := s.History
+	if historyLimit > migrator.StatusHistoryLimit {
+		history, err = m.GetHistory(ctx, historyLimit)
+		if err != nil {
+			return cli.GeneralError("getting migration history", err)
+		}
+	}
+	if historyLimit < len(history) {
+		history = history[:max(historyLimit, 0)]
+	}
+
+	if format == "json" {
+		s.History = history
+		if err := writeStatusJSON(s, schemaPath, m.TuplesTable()); err != nil {
+			return cli.GeneralError("writing status", err)
+		}
+		return nil
+	}
+
 	if s.SchemaExists {
 		fmt.Println("Schema file:  present")
 	} else {
@@ -74,12 +114,106 @@ func runStatus(dsn, databaseSchema, schemaPath string) error {
 		fmt.Println("Tuples view:  missing")
 	}
 
+	if s.SchemaExists {
+		fmt.Printf("Functions:    %d expected, %d installed, %d missing, %d stale\n",
+			s.FunctionsExpected, s.FunctionsInstalled, len(s.FunctionsMissing), len(s.FunctionsStale))
+		printFunctionList("Missing", s.FunctionsMissing)
+		printFunctionList("Stale", s.FunctionsStale)
+	}
+
+	printHistory(history)
+
 	if !s.SchemaExists {
 		fmt.Printf("\nNo schema found at %s\n", schemaPath)
 	} else if !s.TuplesExists {
 		fmt.Println("\nTuples view not found.")
-		fmt.Println("Create melange_tuples before running checks.")
+		fmt.Printf("Create %s before running checks.\n", m.TuplesTable())
+	}
+	if len(s.FunctionsMissing) > 0 || len(s.FunctionsStale) > 0 {
+		fmt.Println("\nGenerated functions are out of date. Run 'melange migrate' to update them.")
 	}
 
 	return nil
 }
+
+// statusJSON is the JSON form of melange status: the migrator.Status, with
+// every missing and stale function rather than the first statusListLimit,
+// plus the paths it was read from.
+type statusJSON struct {
+	SchemaPath  string `json:"schema_path"`
+	TuplesTable string `json:"tuples_table"`
+	*migrator.Status
+}
+
+// writeStatusJSON writes s to stdout as indented JSON. Empty lists are
+// written as [] rather than null.
+func writeStatusJSON(s *migrator.Status, schemaPath, tuplesTable string) error {
+	for _, list := range []*[]string{&s.FunctionsMissing, &s.FunctionsStale} {
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+	if s.History == nil {
+		s.History = []migrator.MigrationHistoryEntry{}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(statusJSON{SchemaPath: schemaPath, TuplesTable: tuplesTable, Status: s})
+}
+
+// checkOutputFormat rejects --format values other than text and json.
+func checkOutputFormat(format string) error {
+	switch format {
+	case "text", "json", "":
+		return nil
+	default:
+		return cli.GeneralError("output format", fmt.Errorf("unknown format %q (want text|json)", format))
+	}
+}
+
+// statusListLimit caps how many function names printFunctionList shows.
+const statusListLimit = 10
+
+// printFunctionList prints up to statusListLimit names under label.
+func printFunctionList(label string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Printf("  %s:\n", label)
+	for i, name := range names {
+		if i == statusListLimit {
+			fmt.Printf("    ... and %d more\n", len(names)-statusListLimit)
+			break
+		}
+		fmt.Printf("    %s\n", name)
+	}
+}
+
+// printHistory prints applied migrations, most recent first, marking those
+// a rollback has undone.
+func printHistory(history []migrator.MigrationHistoryEntry) {
+	if len(history) == 0 {
+		return
+	}
+	fmt.Println("Migrations:")
+	for _, e := range history {
+		source := e.SchemaPath
+		if source == "" {
+			source = "(inline schema)"
+		}
+		line := fmt.Sprintf("  %s  %s  %s  %s",
+			e.MigratedAt.UTC().Format(time.RFC3339), shortChecksum(e.SchemaChecksum), e.MelangeVersion, source)
+		if e.RolledBackAt != nil {
+			line += "  (rolled back " + e.RolledBackAt.UTC().Format(time.RFC3339) + ")"
+		}
+		fmt.Println(line)
+	}
+}
+
+// shortChecksum abbreviates a schema checksum for display.
+func shortChecksum(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/lib/pq"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/pthm/melange/lib/cli"
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+	"github.com/pthm/melange/lib/version"
+	"github.com/pthm/melange/melange"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+var (
+	testDB     string
+	testFilter int
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test <file.fga.yaml>",
+	Short: "Run model tests against the generated SQL",
+	Long: `Test runs the assertions of an OpenFGA store file (the .fga.yaml format
+used by "fga model test") against the functions melange generates for its
+model.
+
+The model is installed into a throwaway schema inside a transaction that is
+rolled back when the run ends, so nothing is left in the database. The
+store's tuples are loaded into a melange_tuples table in that schema, and
+each test's own tuples are added for that test only.
+
+Every check and list_objects expectation counts as one assertion, numbered
+from 1 in file order; --filter runs a single one. list_users blocks are not
+run. The command exits non-zero when any assertion fails.`,
+	Example: `  # Run every assertion
+  melange test model.fga.yaml --db postgres://localhost/mydb
+
+  # Run only assertion 3
+  melange test model.fga.yaml --db postgres://localhost/mydb --filter 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dsn, err := resolveDSN(testDB)
+		if err != nil {
+			return err
+		}
+
+		store, err := loadStoreFile(args[0])
+		if err != nil {
+			return err
+		}
+		assertions := store.assertions()
+		if testFilter != 0 && (testFilter < 1 || testFilter > len(assertions)) {
+			return cli.ConfigError(fmt.Sprintf("--filter %d is out of range (file has %d assertions)", testFilter, len(assertions)), nil)
+		}
+
+		return runStoreTests(dsn, store, assertions, testFilter)
+	},
+}
+
+func init() {
+	f := testCmd.Flags()
+	f.StringVar(&testDB, "db", "", "database URL")
+	f.IntVar(&testFilter, "filter", 0, "run only the assertion with this 1-based index (0 = all)")
+}
+
+// storeFile is an OpenFGA store file. The model comes from Model or
+// ModelFile; tuples from any of Tuples, TupleFile and TupleFiles.
+type storeFile struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModelFile  string       `json:"model_file"`
+	Tuples     []storeTuple `json:"tuples"`
+	TupleFile  string       `json:"tuple_file"`
+	TupleFiles []string     `json:"tuple_files"`
+	Tests      []storeTest  `json:"tests"`
+
+	types []schema.TypeDefinition
+}
+
+// storeTest is one entry of a store file's tests. Its tuples are added to
+// the store's for the duration of the test.
+type storeTest struct {
+	Name        string            `json:"name"`
+	Tuples      []storeTuple      `json:"tuples"`
+	TupleFile   string            `json:"tuple_file"`
+	TupleFiles  []string          `json:"tuple_files"`
+	Check       []storeCheck      `json:"check"`
+	ListObjects []storeListObject `json:"list_objects"`
+}
+
+type storeTuple struct {
+	User      string          `json:"user"`
+	Relation  string          `json:"relation"`
+	Object    string          `json:"object"`
+	Condition *storeCondition `json:"condition"`
+}
+
+type storeCondition struct {
+	Name    string         `json:"name"`
+	Context map[string]any `json:"context"`
+}
+
+// storeCheck expects, per relation, whether User has it on Object.
+type storeCheck struct {
+	User       string          `json:"user"`
+	Object     string          `json:"object"`
+	Context    map[string]any  `json:"context"`
+	Assertions map[string]bool `json:"assertions"`
+}
+
+// storeListObject expects, per relation, the objects of Type User has it on.
+type storeListObject struct {
+	User       string              `json:"user"`
+	Type       string              `json:"type"`
+	Context    map[string]any      `json:"context"`
+	Assertions map[string][]string `json:"assertions"`
+}
+
+// loadStoreFile reads a store file, resolving model and tuple files
+// relative to its directory, and parses its model.
+func loadStoreFile(path string) (*storeFile, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is from trusted source
+	if err != nil {
+		return nil, cli.GeneralError("reading store file", err)
+	}
+	var store storeFile
+	if err := yaml.Unmarshal(data, &store); err != nil {
+		return nil, cli.GeneralError("parsing store file", err)
+	}
+	dir := filepath.Dir(path)
+
+	switch {
+	case store.Model != "" && store.ModelFile != "":
+		return nil, cli.GeneralError("store file sets both model and model_file", nil)
+	case store.ModelFile != "":
+		store.types, err = parser.ParseSchema(filepath.Join(dir, store.ModelFile))
+	case store.Model != "":
+		store.types, err = parser.ParseSchemaString(store.Model)
+	default:
+		return nil, cli.GeneralError("store file has no model or model_file", nil)
+	}
+	if err != nil {
+		return nil, cli.SchemaParseError("parsing model", err)
+	}
+
+	if store.Tuples, err = appendTupleFiles(store.Tuples, dir, store.TupleFile, store.TupleFiles); err != nil {
+		return nil, err
+	}
+	for i := range store.Tests {
+		test := &store.Tests[i]
+		if test.Tuples, err = appendTupleFiles(test.Tuples, dir, test.TupleFile, test.TupleFiles); err != nil {
+			return nil, err
+		}
+	}
+	return &store, nil
+}
+
+// appendTupleFiles appends the tuples of file and files, which hold a YAML
+// or JSON list of tuples.
+func appendTupleFiles(tuples []storeTuple, dir, file string, files []string) ([]storeTuple, error) {
+	if file != "" {
+		files = append([]string{file}, files...)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(dir, f)) //nolint:gosec // path is from trusted source
+		if err != nil {
+			return nil, cli.GeneralError("reading tuple file", err)
+		}
+		var more []storeTuple
+		if err := yaml.Unmarshal(data, &more); err != nil {
+			return nil, cli.GeneralError(fmt.Sprintf("parsing tuple file %s", f), err)
+		}
+		tuples = append(tuples, more...)
+	}
+	return tuples, nil
+}
+
+// testAssertion is a single expectation of a store file, numbered from 1 in
+// file order.
+type testAssertion struct {
+	Index    int
+	Test     int // index into storeFile.Tests
+	User     string
+	Relation string
+	Context  map[string]any
+
+	// Check assertions set Object and Allowed; list_objects assertions set
+	// Type and Objects.
+	Object  string
+	Allowed bool
+	Type    string
+	Objects []string
+}
+
+func (a testAssertion) String() string {
+	if a.Type != "" {
+		return fmt.Sprintf("list_objects %s %s %s", a.User, a.Relation, a.Type)
+	}
+	return fmt.Sprintf("check %s %s %s", a.User, a.Relation, a.Object)
+}
+
+// assertions flattens the store's tests. Relations within one check or
+// list_objects entry are taken in name order, as the file maps them.
+func (s *storeFile) assertions() []testAssertion {
+	var out []testAssertion
+	add := func(a testAssertion) {
+		a.Index = len(out) + 1
+		out = append(out, a)
+	}
+	for i, test := range s.Tests {
+		for _, c := range test.Check {
+			for _, rel := range sortedKeys(c.Assertions) {
+				add(testAssertion{Test: i, User: c.User, Relation: rel, Context: c.Context, Object: c.Object, Allowed: c.Assertions[rel]})
+			}
+		}
+		for _, l := range test.ListObjects {
+			for _, rel := range sortedKeys(l.Assertions) {
+				add(testAssertion{Test: i, User: l.User, Relation: rel, Context: l.Context, Type: l.Type, Objects: l.Assertions[rel]})
+			}
+		}
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runStoreTests installs the store's model into a throwaway schema and runs
+// assertions against it, or only the one numbered filter when it is set.
+func runStoreTests(dsn string, store *storeFile, assertions []testAssertion, filter int) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return cli.DBConnectError("connecting to database", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return cli.DBConnectError("starting transaction", err)
+	}
+	// Nothing the run creates outlives it.
+	defer func() { _ = tx.Rollback() }()
+
+	databaseSchema, err := createTestSchema(ctx, tx)
+	if err != nil {
+		return cli.GeneralError("creating test schema", err)
+	}
+	if _, err := migrator.ApplyTx(ctx, tx, store.types, migrator.ApplyTxOptions{
+		Version:        version.Version,
+		DatabaseSchema: databaseSchema,
+	}); err != nil {
+		return cli.GeneralError("installing model", err)
+	}
+	if err := insertTestTuples(ctx, tx, databaseSchema, store.Tuples); err != nil {
+		return cli.GeneralError("loading tuples", err)
+	}
+
+	checker := melange.NewChecker(tx, melange.WithDatabaseSchema(databaseSchema))
+	var passed, failed int
+	for test := range store.Tests {
+		var selected []testAssertion
+		for _, a := range assertions {
+			if a.Test == test && (filter == 0 || a.Index == filter) {
+				selected = append(selected, a)
+			}
+		}
+		if len(selected) == 0 {
+			continue
+		}
+
+		// Scope the test's tuples to its assertions.
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT melange_test"); err != nil {
+			return cli.GeneralError("creating savepoint", err)
+		}
+		if err := insertTestTuples(ctx, tx, databaseSchema, store.Tests[test].Tuples); err != nil {
+			return cli.GeneralError(fmt.Sprintf("loading tuples of test %q", store.Tests[test].Name), err)
+		}
+		for _, a := range selected {
+			if problem := runAssertion(ctx, tx, checker, databaseSchema, a); problem != "" {
+				failed++
+				fmt.Printf("FAIL %d %s (%s): %s\n", a.Index, a, store.Tests[test].Name, problem)
+			} else {
+				passed++
+				if !quiet {
+					fmt.Printf("PASS %d %s\n", a.Index, a)
+				}
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT melange_test"); err != nil {
+			return cli.GeneralError("rolling back test tuples", err)
+		}
+	}
+
+	if !quiet {
+		fmt.Printf("\n%d passed, %d failed\n", passed, failed)
+	}
+	if failed > 0 {
+		return cli.GeneralError(fmt.Sprintf("%d of %d assertions failed", failed, passed+failed), nil)
+	}
+	return nil
+}
+
+// createTestSchema creates a uniquely named schema holding an empty
+// melange_tuples table and returns its name.
+func createTestSchema(ctx context.Context, tx *sql.Tx) (string, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	name := "melange_test_" + hex.EncodeToString(suffix)
+	stmts := []string{
+		"CREATE SCHEMA " + pq.QuoteIdentifier(name),
+		`CREATE TABLE ` + sqldsl.PrefixIdent(sqldsl.DefaultTuplesTable, name) + ` (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// insertTestTuples writes tuples to the test schema's melange_tuples. A
+// tuple's condition name needs no storing, since melange reads every tuple
+// of a conditioned subject type as conditional, but condition context
+// stored on a tuple cannot be represented.
+func insertTestTuples(ctx context.Context, tx *sql.Tx, databaseSchema string, tuples []storeTuple) error {
+	query := "INSERT INTO " + sqldsl.PrefixIdent(sqldsl.DefaultTuplesTable, databaseSchema) +
+		" (subject_type, subject_id, relation, object_type, object_id) VALUES ($1, $2, $3, $4, $5)"
+	for _, t := range tuples {
+		if t.Condition != nil && len(t.Condition.Context) > 0 {
+			return fmt.Errorf("tuple %s %s %s: condition context on tuples is not supported", t.User, t.Relation, t.Object)
+		}
+		subject, err := parseTypedIdent(t.User, "tuple user")
+		if err != nil {
+			return err
+		}
+		object, err := parseTypedIdent(t.Object, "tuple object")
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, subject.Type, subject.ID, t.Relation, object.Type, object.ID); err != nil {
+			return fmt.Errorf("tuple %s %s %s: %w", t.User, t.Relation, t.Object, err)
+		}
+	}
+	return nil
+}
+
+// runAssertion evaluates a, returning a description of the mismatch or
+// error, or "" when it holds.
+func runAssertion(ctx context.Context, tx *sql.Tx, checker *melange.Checker, databaseSchema string, a testAssertion) string {
+	subject, err := parseTypedIdent(a.User, "user")
+	if err != nil {
+		return err.Error()
+	}
+
+	if a.Type != "" {
+		if len(a.Context) > 0 {
+			return "list_objects with context is not supported"
+		}
+		ids, err := checker.ListObjectsAll(ctx, subject, melange.Relation(a.Relation), melange.ObjectType(a.Type))
+		if err != nil {
+			return err.Error()
+		}
+		got := make([]string, len(ids))
+		for i, id := range ids {
+			got[i] = a.Type + ":" + id
+		}
+		want := slices.Clone(a.Objects)
+		sort.Strings(got)
+		sort.Strings(want)
+		if !slices.Equal(got, want) {
+			return fmt.Sprintf("want %v, got %v", want, got)
+		}
+		return ""
+	}
+
+	object, err := parseTypedIdent(a.Object, "object")
+	if err != nil {
+		return err.Error()
+	}
+	var allowed bool
+	if len(a.Context) > 0 {
+		allowed, err = checkWithContext(ctx, tx, databaseSchema, subject, a.Relation, object, a.Context)
+	} else {
+		allowed, err = checker.Check(ctx, subject, melange.Relation(a.Relation), object)
+	}
+	if err != nil {
+		return err.Error()
+	}
+	if allowed != a.Allowed {
+		return fmt.Sprintf("want %t, got %t", a.Allowed, allowed)
+	}
+	return ""
+}
+
+// checkWithContext calls check_permission_with_context, which evaluates the
+// model's conditions against values.
+func checkWithContext(ctx context.Context, tx *sql.Tx, databaseSchema string, subject melange.Object, relation string, object melange.Object, values map[string]any) (bool, error) {
+	payload, err := json.Marshal(values)
+	if err != nil {
+		return false, fmt.Errorf("encoding context: %w", err)
+	}
+	var result int
+	query := "SELECT " + sqldsl.PrefixIdent("check_permission_with_context", databaseSchema) + "($1, $2, $3, $4, $5, $6::JSONB)"
+	if err := tx.QueryRowContext(ctx, query, subject.Type, subject.ID, relation, object.Type, object.ID, string(payload)).Scan(&result); err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestLoadStoreFile pins how a store file is read: model and tuple files
+// resolve relative to the store file, and assertions are numbered from 1 in
+// file order, with relations of one entry in name order.
+func TestLoadStoreFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("model.fga", `model
+  schema 1.1
+type user
+type document
+  relations
+    define owner: [user]
+    define viewer: [user] or owner
+`)
+	write("tuples.yaml", `
+- user: user:anne
+  relation: owner
+  object: document:1
+`)
+	write("store.fga.yaml", `
+name: documents
+model_file: ./model.fga
+tuple_file: ./tuples.yaml
+tuples:
+  - user: user:bob
+    relation: viewer
+    object: document:2
+tests:
+  - name: owners view
+    check:
+      - user: user:anne
+        object: document:1
+        assertions:
+          viewer: true
+          owner: true
+    list_objects:
+      - user: user:anne
+        type: document
+        assertions:
+          viewer: [document:1]
+  - name: extra viewer
+    tuples:
+      - user: user:carl
+        relation: viewer
+        object: document:1
+    check:
+      - user: user:carl
+        object: document:1
+        context:
+          region: eu
+        assertions:
+          viewer: true
+`)
+
+	store, err := loadStoreFile(filepath.Join(dir, "store.fga.yaml"))
+	if err != nil {
+		t.Fatalf("loadStoreFile: %v", err)
+	}
+	if len(store.types) != 2 {
+		t.Errorf("model has %d types, want 2", len(store.types))
+	}
+	var users []string
+	for _, tuple := range store.Tuples {
+		users = append(users, tuple.User)
+	}
+	if !slices.Equal(users, []string{"user:bob", "user:anne"}) {
+		t.Errorf("store tuples = %v, want inline then file tuples", users)
+	}
+	if len(store.Tests[1].Tuples) != 1 {
+		t.Errorf("second test has %d tuples, want 1", len(store.Tests[1].Tuples))
+	}
+
+	want := []string{
+		"check user:anne owner document:1",
+		"check user:anne viewer document:1",
+		"list_objects user:anne viewer document",
+		"check user:carl viewer document:1",
+	}
+	assertions := store.assertions()
+	if len(assertions) != len(want) {
+		t.Fatalf("got %d assertions, want %d", len(assertions), len(want))
+	}
+	for i, a := range assertions {
+		if a.Index != i+1 || a.String() != want[i] {
+			t.Errorf("assertion %d = %d %q, want %d %q", i, a.Index, a, i+1, want[i])
+		}
+	}
+	if assertions[3].Test != 1 || assertions[3].Context["region"] != "eu" {
+		t.Errorf("last assertion = %+v, want test 1 with context", assertions[3])
+	}
+}
+
+func TestLoadStoreFile_RequiresModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.fga.yaml")
+	if err := os.WriteFile(path, []byte("name: empty\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadStoreFile(path); err == nil {
+		t.Error("loadStoreFile accepted a store file without a model")
+	}
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/pthm/melange/lib/cli"
+	"github.com/pthm/melange/pkg/compiler"
 	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/pkg/schema"
 )
 
 var validateSchema string
@@ -15,7 +19,12 @@ var validateSchema string
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate schema syntax",
-	Long:  `Validate schema syntax using the OpenFGA parser.`,
+	Long: `Validate schema syntax using the OpenFGA parser, check that every type and
+relation the schema references is defined, and check that every
+tuple-to-userset ("viewer from parent") names a linking relation defined on
+the type whose linked types define the inherited relation.
+
+Each undefined reference is printed with the type and relation that makes it.`,
 	Example: `  # Validate a single-file schema
   melange validate --schema schemas/schema.fga
 
@@ -34,10 +43,31 @@ var validateCmd = &cobra.Command{
 
 		types, err := parser.ParseSchema(schemaPath)
 		if err != nil {
+			var parseErrs parser.ParseErrors
+			if errors.As(err, &parseErrs) {
+				if source, readErr := os.ReadFile(schemaPath); readErr == nil { //nolint:gosec // path is from trusted source
+					fmt.Fprint(os.Stderr, parseErrs.Format(schemaPath, string(source)))
+				}
+			}
 			return cli.SchemaParseError("parsing schema", err)
 		}
 
+		if err := parser.Validate(types); err != nil {
+			var refErrs parser.ReferenceErrors
+			if errors.As(err, &refErrs) {
+				fmt.Fprint(os.Stderr, refErrs.Format(schemaPath))
+			}
+			return cli.SchemaParseError("undefined references", err)
+		}
+
+		if err := schema.ValidateTupleToUsersets(types); err != nil {
+			return cli.SchemaParseError("invalid tuple-to-userset", err)
+		}
+
 		if !quiet {
+			for _, warning := range nameCollisionWarnings(types) {
+				fmt.Fprintln(os.Stderr, warning)
+			}
 			fmt.Printf("Schema is valid. Found %d types:\n", len(types))
 			for _, t := range types {
 				fmt.Printf("  - %s (%d relations)\n", t.Name, len(t.Relations))
@@ -53,5 +83,23 @@ var validateCmd = &cobra.Command{
 }
 
 func init() {
-	validateCmd.Flags().StringVar(&validateSchema, "schema", "", "path to schema.fga or fga.mod file")
+	validateCmd.Flags().StringVar(&validateSchema, "schema", "", "path to schema.fga, fga.mod, or a directory of .fga files")
+}
+
+// nameCollisionWarnings returns a warning for each set of relations whose
+// generated function names fold to the same identifier. Generation keeps
+// them apart with a hash suffix, but names differing only by case are
+// usually a typo.
+func nameCollisionWarnings(types []schema.TypeDefinition) []string {
+	var warnings []string
+	for _, c := range compiler.FindNameCollisions(types) {
+		warning := fmt.Sprintf("WARNING: relations %s fold to the same function name %q;", strings.Join(c.Relations, ", "), c.Identifier)
+		if c.Kept != "" {
+			warning += fmt.Sprintf(" functions other than %s's get a hash suffix.", c.Kept)
+		} else {
+			warning += " their functions get a hash suffix."
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
 }
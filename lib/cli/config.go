@@ -38,6 +38,9 @@ type DatabaseConfig struct {
 	Password string `mapstructure:"password"`
 	SSLMode  string `mapstructure:"sslmode"`
 	Schema   string `mapstructure:"schema"`
+	// TuplesTable is the relation the generated functions read tuples from
+	// (empty = melange_tuples). It may be schema-qualified.
+	TuplesTable string `mapstructure:"tuples_table"`
 }
 
 // GenerateConfig holds code generation settings.
@@ -48,11 +51,13 @@ type GenerateConfig struct {
 
 // ClientConfig holds client code generation settings.
 type ClientConfig struct {
-	Runtime string `mapstructure:"runtime"`
-	Output  string `mapstructure:"output"`
-	Package string `mapstructure:"package"`
-	Filter  string `mapstructure:"filter"`
-	IDType  string `mapstructure:"id_type"`
+	Runtime     string `mapstructure:"runtime"`
+	Output      string `mapstructure:"output"`
+	Package     string `mapstructure:"package"`
+	Filter      string `mapstructure:"filter"`
+	IDType      string `mapstructure:"id_type"`
+	SplitByType bool   `mapstructure:"split_by_type"`
+	Concurrency int    `mapstructure:"concurrency"`
 }
 
 // MigrationGenConfig holds settings for `melange generate migration`, which
@@ -69,13 +74,47 @@ type MigrationGenConfig struct {
 // MigrateConfig holds settings for `melange migrate` (builtin migration).
 type MigrateConfig struct {
 	DryRun bool `mapstructure:"dry_run"`
-	Force  bool `mapstructure:"force"`
+	// NoTransaction leaves BEGIN and COMMIT out of the dry-run script.
+	NoTransaction bool `mapstructure:"no_transaction"`
+	Force         bool `mapstructure:"force"`
+	// EffectiveAccess installs the opt-in effective_access audit function.
+	EffectiveAccess bool `mapstructure:"effective_access"`
+	// CheckEvidence installs the opt-in check_with_evidence_* audit functions.
+	CheckEvidence bool `mapstructure:"check_evidence"`
+	// StrictCheck installs the opt-in check_permission_strict entrypoint.
+	StrictCheck bool `mapstructure:"strict_check"`
+	// PoolerSafe generates functions that read no session-level settings.
+	PoolerSafe bool `mapstructure:"pooler_safe"`
+	// CheckMemo memoizes repeated sub-checks within each check_permission call.
+	CheckMemo bool `mapstructure:"check_memo"`
+	// TableRoutedDispatcher routes check_permission through the melange_routes table.
+	TableRoutedDispatcher bool `mapstructure:"table_routed_dispatcher"`
+	// AnytimeListObjects returns base-level grants first from unpaged recursive list_objects calls.
+	AnytimeListObjects bool `mapstructure:"anytime_list_objects"`
+	// ClosureFunction has check and list functions call melange_closure_rows and melange_userset_rows instead of inlining those rows.
+	ClosureFunction bool `mapstructure:"closure_function"`
+	// ListObjectsCursor installs the refcursor list_objects functions.
+	ListObjectsCursor bool `mapstructure:"list_objects_cursor"`
+	// ExpandWildcardSubjects expands wildcards in list_subjects from the melange_subjects view.
+	ExpandWildcardSubjects bool `mapstructure:"expand_wildcard_subjects"`
+	// ObjectDelimiter separates type from id in "type:id" strings (empty = ":").
+	ObjectDelimiter string `mapstructure:"object_delimiter"`
+	// MaxFunctions fails the migration when the schema compiles to more functions (0 = no limit).
+	MaxFunctions int `mapstructure:"max_functions"`
+	// MaxDepth bounds recursion in generated functions before M2002 (0 = 25).
+	MaxDepth int `mapstructure:"max_depth"`
+	// DisableNullGuards leaves the NULL argument checks out of generated functions.
+	DisableNullGuards bool `mapstructure:"disable_null_guards"`
+	// Dialect is the database generated functions target (empty = "postgres").
+	Dialect string `mapstructure:"dialect"`
 }
 
 // DoctorConfig holds doctor command settings.
 type DoctorConfig struct {
 	Verbose         bool `mapstructure:"verbose"`
 	SkipPerformance bool `mapstructure:"skip_performance"`
+	// Baseline is a function manifest the installed functions must match.
+	Baseline string `mapstructure:"baseline"`
 }
 
 // LoadConfig discovers and loads configuration with proper precedence:
@@ -133,6 +172,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("database.password", "")
 	v.SetDefault("database.sslmode", "prefer")
 	v.SetDefault("database.schema", "")
+	v.SetDefault("database.tuples_table", "")
 
 	// Generate client defaults
 	v.SetDefault("generate.client.runtime", "")
@@ -140,6 +180,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("generate.client.package", "authz")
 	v.SetDefault("generate.client.filter", "")
 	v.SetDefault("generate.client.id_type", "string")
+	v.SetDefault("generate.client.split_by_type", false)
+	v.SetDefault("generate.client.concurrency", 0)
 
 	// Generate migration defaults
 	v.SetDefault("generate.migration.output", "")
@@ -148,11 +190,28 @@ func setDefaults(v *viper.Viper) {
 
 	// Migrate defaults
 	v.SetDefault("migrate.dry_run", false)
+	v.SetDefault("migrate.no_transaction", false)
 	v.SetDefault("migrate.force", false)
+	v.SetDefault("migrate.effective_access", false)
+	v.SetDefault("migrate.check_evidence", false)
+	v.SetDefault("migrate.strict_check", false)
+	v.SetDefault("migrate.pooler_safe", false)
+	v.SetDefault("migrate.check_memo", false)
+	v.SetDefault("migrate.table_routed_dispatcher", false)
+	v.SetDefault("migrate.anytime_list_objects", false)
+	v.SetDefault("migrate.closure_function", false)
+	v.SetDefault("migrate.list_objects_cursor", false)
+	v.SetDefault("migrate.expand_wildcard_subjects", false)
+	v.SetDefault("migrate.object_delimiter", "")
+	v.SetDefault("migrate.max_functions", 0)
+	v.SetDefault("migrate.max_depth", 0)
+	v.SetDefault("migrate.disable_null_guards", false)
+	v.SetDefault("migrate.dialect", "")
 
 	// Doctor defaults
 	v.SetDefault("doctor.verbose", false)
 	v.SetDefault("doctor.skip_performance", false)
+	v.SetDefault("doctor.baseline", "")
 }
 
 // findConfigFile locates the config file to load. When explicitPath is given,
@@ -360,6 +360,7 @@ func TestLoadConfig_MigrateAndGenerateMigrationDefaults(t *testing.T) {
 
 	// melange migrate defaults
 	assert.False(t, cfg.Migrate.DryRun)
+	assert.False(t, cfg.Migrate.NoTransaction)
 	assert.False(t, cfg.Migrate.Force)
 
 	// melange generate migration defaults
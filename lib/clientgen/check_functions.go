@@ -0,0 +1,42 @@
+package clientgen
+
+import (
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+// CheckFunctions maps object type -> relation -> the name of the specialized
+// check function melange migrate installs for it, such as
+// check_document_viewer. Relations without access paths have no such
+// function and are absent. Names are computed as the migration computes
+// them, so relations that differ only by case or exceed the identifier limit
+// get the same hashed names.
+//
+// A client that knows the type and relation at generation time can call the
+// function directly with (subject_type, subject_id, object_id). The call
+// answers as check_permission does without going through the dispatcher's
+// routing. Calls made this way skip the check_permission memo (melange
+// migrate --check-memo) and its p_expected_schema_hash guard; see
+// Config.CheckMemo.
+func CheckFunctions(types []schema.TypeDefinition) map[string]map[string]string {
+	closure := schema.ComputeRelationClosure(types)
+	analyses := sqlgen.ComputeCanGenerate(sqlgen.AnalyzeRelations(types, closure))
+	names := make(map[string]map[string]string)
+	for _, a := range analyses {
+		if !a.Capabilities.CheckAllowed {
+			continue
+		}
+		if names[a.ObjectType] == nil {
+			names[a.ObjectType] = make(map[string]string)
+		}
+		names[a.ObjectType][a.Relation] = sqlgen.CheckFunctionName(a)
+	}
+	return names
+}
+
+// CheckFunctionSQL returns how generated SQL names the specialized check
+// function name, qualified as CheckPermissionSQL is.
+func (c *Config) CheckFunctionSQL(name string) string {
+	return sqldsl.PrefixIdent(name, c.SchemaName)
+}
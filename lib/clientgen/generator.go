@@ -12,6 +12,7 @@ package clientgen
 import (
 	"fmt"
 
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
 	"github.com/pthm/melange/pkg/schema"
 )
 
@@ -68,11 +69,52 @@ type Config struct {
 	// Example: "schemas/schema.fga"
 	SourcePath string
 
+	// SplitByType emits one file per object type, holding its type constant
+	// and constructors, plus a shared file for everything else. Large schemas
+	// then produce small per-type diffs instead of rewriting one file. Each
+	// generator documents its file names.
+	SplitByType bool
+
+	// SchemaName is the PostgreSQL schema the melange functions are
+	// installed in (melange migrate --db-schema). When set, generated code
+	// calls them schema-qualified, so calls work whatever the connection's
+	// search_path. Empty leaves calls unqualified.
+	SchemaName string
+
+	// Concurrency is how many checks the generated filter helpers run at
+	// once. Zero uses the generator's default. Only the Go generator emits
+	// filter helpers.
+	Concurrency int
+
+	// CheckMemo makes the generated filter helpers call check_permission
+	// instead of each relation's check function, so their checks share the
+	// memo of a database migrated with melange migrate --check-memo. Only
+	// the Go generator emits filter helpers.
+	CheckMemo bool
+
 	// Options holds language-specific configuration.
 	// Each generator documents its supported options.
 	Options map[string]any
 }
 
+// CheckPermissionSQL returns how generated SQL names check_permission:
+// quoted and qualified with SchemaName when it is set, bare otherwise.
+func (c *Config) CheckPermissionSQL() string {
+	return sqldsl.PrefixIdent("check_permission", c.SchemaName)
+}
+
+// ListObjectsSQL returns how generated SQL names list_accessible_objects,
+// qualified as CheckPermissionSQL is.
+func (c *Config) ListObjectsSQL() string {
+	return sqldsl.PrefixIdent("list_accessible_objects", c.SchemaName)
+}
+
+// ListObjectsCursorSQL returns how generated SQL names
+// list_accessible_objects_cursor, qualified as CheckPermissionSQL is.
+func (c *Config) ListObjectsCursorSQL() string {
+	return sqldsl.PrefixIdent("list_accessible_objects_cursor", c.SchemaName)
+}
+
 // registry maps runtime names to generators.
 var registry = make(map[string]Generator)
 
@@ -10,10 +10,14 @@ package gogen
 import (
 	"bytes"
 	"fmt"
+	"go/build"
+	"io"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/pthm/melange/lib/clientgen"
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
 	"github.com/pthm/melange/pkg/schema"
 )
 
@@ -39,13 +43,28 @@ func (g *Generator) DefaultConfig() *clientgen.Config {
 
 // Generate produces Go client code from the given type definitions.
 //
-// Returns a single file map entry with key "schema_gen.go".
+// Returns a single file map entry with key "schema_gen.go", or with
+// cfg.SplitByType one file per object type plus a shared "client.go" (see
+// generateSplit).
 //
 // Generated code includes:
 //   - ObjectType constants (TypeUser, TypeRepository, etc.)
 //   - Relation constants (RelCanRead, RelOwner, etc.)
 //   - Constructor functions (User(id), Repository(id), etc.)
 //   - Wildcard constructors (AnyUser(), AnyRepository(), etc.)
+//   - BatchCheck, which runs many checks in one query (see batchCheckSource)
+//   - Hooks and SetHooks, which report each check to optional callbacks
+//     (see hooksSource)
+//   - ListObjectsCursor, which iterates over a list_objects cursor (see
+//     listObjectsCursorSource)
+//   - FilterAccessible<Type><Relation> for each object type and relation,
+//     which checks many IDs concurrently, calling the relation's check
+//     function directly unless cfg.CheckMemo routes it through
+//     check_permission (see filterAccessibleSource and checkQuery)
+//
+// Returns an error when a type's constructor would redeclare a BatchCheck,
+// Hooks or ListObjectsCursor identifier, or when two FilterAccessible
+// functions, or one and a constructor, would share a name.
 func (g *Generator) Generate(types []schema.TypeDefinition, cfg *clientgen.Config) (map[string][]byte, error) {
 	// Validate schema before generating code
 	if err := schema.DetectCycles(types); err != nil {
@@ -72,6 +91,11 @@ func (g *Generator) Generate(types []schema.TypeDefinition, cfg *clientgen.Confi
 		objectTypes = append(objectTypes, t.Name)
 	}
 	sort.Strings(objectTypes)
+	for _, t := range objectTypes {
+		if clientHelperNames[pascalCase(t)] {
+			return nil, fmt.Errorf("go: constructor for type %q collides with the generated %s", t, pascalCase(t))
+		}
+	}
 
 	// Collect unique relations (with optional prefix filter)
 	relSet := make(map[string]bool)
@@ -89,11 +113,142 @@ func (g *Generator) Generate(types []schema.TypeDefinition, cfg *clientgen.Confi
 	}
 	sort.Strings(relations)
 
+	filters, err := filterFuncs(types, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SplitByType {
+		return generateSplit(objectTypes, relations, filters, cfg, pkg, idType)
+	}
+
 	// Generate code into buffer
 	var buf bytes.Buffer
 	ew := &errWriter{w: &buf}
 
-	// Write header
+	writeHeader(ew, cfg, pkg, idType != "string", true, true)
+
+	// Write ObjectType constants
+	ew.writeln("// ObjectType constants from schema.")
+	ew.writeln("const (")
+	for _, t := range objectTypes {
+		constName := "Type" + pascalCase(t)
+		ew.writef("\t%s melange.ObjectType = %q\n", constName, t)
+	}
+	ew.writeln(")")
+	ew.writeln("")
+
+	writeRelations(ew, relations)
+
+	// Write constructor functions
+	ew.writeln("// Object constructors.")
+	ew.writeln("")
+	for _, t := range objectTypes {
+		writeConstructor(ew, t, idType)
+	}
+
+	// Write wildcard constructors
+	ew.writeln("// Wildcard constructors for public access patterns.")
+	ew.writeln("")
+	for _, t := range objectTypes {
+		writeWildcardConstructor(ew, t)
+	}
+
+	ew.writeln("// Filter functions, checking many objects of one type at once.")
+	ew.writeln("")
+	for _, t := range objectTypes {
+		writeFilterFuncs(ew, filters[t], idType)
+	}
+
+	ew.writeln(hooksSource)
+	writeBatchCheck(ew, cfg)
+	writeListObjectsCursor(ew, cfg)
+	writeFilterAccessible(ew, cfg)
+
+	if ew.err != nil {
+		return nil, ew.err
+	}
+
+	return map[string][]byte{
+		"schema_gen.go": buf.Bytes(),
+	}, nil
+}
+
+// generateSplit renders the SplitByType layout: "client.go" holds the
+// relation constants, Hooks, BatchCheck, ListObjectsCursor and the
+// filterAccessible worker pool, shared by every type, and each object type
+// gets a file named after it (see typeFileName) holding its ObjectType
+// constant, constructors and FilterAccessible functions. Every declaration
+// lives in exactly one file, so the files compile together as one package.
+func generateSplit(objectTypes, relations []string, filters map[string][]filterFunc, cfg *clientgen.Config, pkg, idType string) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(objectTypes)+1)
+
+	var buf bytes.Buffer
+	ew := &errWriter{w: &buf}
+	writeHeader(ew, cfg, pkg, false, true, true)
+	if len(relations) > 0 {
+		writeRelations(ew, relations)
+	}
+	ew.writeln(hooksSource)
+	writeBatchCheck(ew, cfg)
+	writeListObjectsCursor(ew, cfg)
+	writeFilterAccessible(ew, cfg)
+	if ew.err != nil {
+		return nil, ew.err
+	}
+	files["client.go"] = buf.Bytes()
+
+	for _, t := range objectTypes {
+		var buf bytes.Buffer
+		ew := &errWriter{w: &buf}
+		writeHeader(ew, cfg, pkg, idType != "string", len(filters[t]) > 0, false)
+		ew.writef("// Type%s is the %s object type.\n", pascalCase(t), t)
+		ew.writef("const Type%s melange.ObjectType = %q\n", pascalCase(t), t)
+		ew.writeln("")
+		writeConstructor(ew, t, idType)
+		writeWildcardConstructor(ew, t)
+		writeFilterFuncs(ew, filters[t], idType)
+		if ew.err != nil {
+			return nil, ew.err
+		}
+		files[typeFileName(t)] = buf.Bytes()
+	}
+
+	return files, nil
+}
+
+// typeFileName returns the SplitByType file for an object type: the type name
+// with a .go extension, unless the go tool would treat that file specially or
+// it would replace client.go. A _test suffix makes it a test file, a GOOS or
+// GOARCH suffix restricts it to one platform, and a leading _ or . hides it;
+// those types get "type_<name>_gen.go" instead, which none of the rules match.
+func typeFileName(objectType string) string {
+	name := objectType + ".go"
+	if objectType == "client" || strings.HasSuffix(objectType, "_test") || !builtEverywhere(name) {
+		return "type_" + objectType + "_gen.go"
+	}
+	return name
+}
+
+// builtEverywhere reports whether the go tool includes a file named name on
+// every platform. Two platforms sharing neither GOOS nor GOARCH are enough to
+// catch any GOOS or GOARCH file name suffix.
+func builtEverywhere(name string) bool {
+	for _, platform := range [][2]string{{"linux", "amd64"}, {"windows", "arm64"}} {
+		ctxt := build.Default
+		ctxt.GOOS, ctxt.GOARCH = platform[0], platform[1]
+		ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("package p\n")), nil
+		}
+		if ok, err := ctxt.MatchFile(".", name); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// writePackageClause writes the generated-code header and package clause.
+func writePackageClause(ew *errWriter, cfg *clientgen.Config, pkg string) {
 	ew.writeln("// Code generated by melange. DO NOT EDIT.")
 	ew.writeln("//")
 	if cfg.Version != "" {
@@ -105,30 +260,45 @@ func (g *Generator) Generate(types []schema.TypeDefinition, cfg *clientgen.Confi
 	ew.writeln("")
 	ew.writef("package %s\n", pkg)
 	ew.writeln("")
+}
 
-	// Only import fmt if IDType is not string (need fmt.Sprint for conversion)
-	if idType == "string" {
+// writeHeader writes the package clause and imports. fmt is imported only
+// when constructors convert a non-string ID with fmt.Sprint, context only
+// when the file declares FilterAccessible functions or the shared helpers,
+// and the other packages Hooks, BatchCheck, ListObjectsCursor and
+// filterAccessible use only in the file that declares them.
+func writeHeader(ew *errWriter, cfg *clientgen.Config, pkg string, needsFmt, needsContext, batch bool) {
+	writePackageClause(ew, cfg, pkg)
+	var std []string
+	if needsContext {
+		std = append(std, "context")
+	}
+	if batch {
+		std = append(std, "database/sql")
+	}
+	if needsFmt {
+		std = append(std, "fmt")
+	}
+	if batch {
+		std = append(std, "iter", "strconv", "strings", "sync", "sync/atomic", "time")
+	}
+	if len(std) == 0 {
 		ew.writeln("import \"github.com/pthm/melange/melange\"")
-	} else {
-		ew.writeln("import (")
-		ew.writeln("\t\"fmt\"")
 		ew.writeln("")
-		ew.writeln("\t\"github.com/pthm/melange/melange\"")
-		ew.writeln(")")
+		return
 	}
-	ew.writeln("")
-
-	// Write ObjectType constants
-	ew.writeln("// ObjectType constants from schema.")
-	ew.writeln("const (")
-	for _, t := range objectTypes {
-		constName := "Type" + pascalCase(t)
-		ew.writef("\t%s melange.ObjectType = %q\n", constName, t)
+	ew.writeln("import (")
+	for _, p := range std {
+		ew.writef("\t%q\n", p)
 	}
+	ew.writeln("")
+	ew.writeln("\t\"github.com/pthm/melange/melange\"")
 	ew.writeln(")")
 	ew.writeln("")
+}
 
-	// Write Relation constants
+// writeRelations writes the Relation constants.
+func writeRelations(ew *errWriter, relations []string) {
 	ew.writeln("// Relations from schema.")
 	ew.writeln("// ALL relations are generated, not just \"can_*\" permissions.")
 	ew.writeln("const (")
@@ -138,38 +308,500 @@ func (g *Generator) Generate(types []schema.TypeDefinition, cfg *clientgen.Confi
 	}
 	ew.writeln(")")
 	ew.writeln("")
+}
 
-	// Write constructor functions
-	ew.writeln("// Object constructors.")
-	ew.writeln("")
-	for _, t := range objectTypes {
-		funcName := pascalCase(t)
-		constName := "Type" + funcName
-		ew.writef("// %s creates a %s object for relation checks.\n", funcName, t)
-		if idType == "string" {
-			ew.writef("func %s(id string) melange.Object { return melange.Object{Type: %s, ID: id} }\n\n", funcName, constName)
-		} else {
-			ew.writef("func %s(id %s) melange.Object { return melange.Object{Type: %s, ID: fmt.Sprint(id)} }\n\n", funcName, idType, constName)
+// writeConstructor writes the object constructor for one type.
+func writeConstructor(ew *errWriter, objectType, idType string) {
+	funcName := pascalCase(objectType)
+	constName := "Type" + funcName
+	ew.writef("// %s creates a %s object for relation checks.\n", funcName, objectType)
+	if idType == "string" {
+		ew.writef("func %s(id string) melange.Object { return melange.Object{Type: %s, ID: id} }\n\n", funcName, constName)
+	} else {
+		ew.writef("func %s(id %s) melange.Object { return melange.Object{Type: %s, ID: fmt.Sprint(id)} }\n\n", funcName, idType, constName)
+	}
+}
+
+// writeWildcardConstructor writes the wildcard constructor for one type.
+func writeWildcardConstructor(ew *errWriter, objectType string) {
+	funcName := "Any" + pascalCase(objectType)
+	constName := "Type" + pascalCase(objectType)
+	ew.writef("// %s returns a wildcard %s that matches type:* tuples.\n", funcName, objectType)
+	ew.writef("func %s() melange.Object { return melange.Object{Type: %s, ID: \"*\"} }\n\n", funcName, constName)
+}
+
+// filterFunc is one generated FilterAccessible<Type><Relation> function.
+type filterFunc struct {
+	name       string
+	objectType string
+	relation   string
+	// query checks one object, binding the subject type, subject ID and
+	// object ID as $1, $2 and $3 (see checkQuery).
+	query string
+	// checkFunc is the check function query calls directly, or empty when
+	// it calls check_permission.
+	checkFunc string
+}
+
+// filterFuncs returns the FilterAccessible functions of each object type, one
+// per relation passing cfg.RelationFilter, sorted by relation. It returns an
+// error when two would share a name, as for types "a_b" and "a" with
+// relations "c" and "b_c", or one would redeclare a constructor.
+func filterFuncs(types []schema.TypeDefinition, cfg *clientgen.Config) (map[string][]filterFunc, error) {
+	taken := make(map[string]string)
+	for _, t := range types {
+		taken[pascalCase(t.Name)] = fmt.Sprintf("constructor for type %q", t.Name)
+	}
+	checkFuncs := clientgen.CheckFunctions(types)
+	funcs := make(map[string][]filterFunc)
+	for _, t := range types {
+		for _, r := range t.Relations {
+			if cfg.RelationFilter == "" || strings.HasPrefix(r.Name, cfg.RelationFilter) {
+				checkFunc := checkFuncs[t.Name][r.Name]
+				if cfg.CheckMemo {
+					checkFunc = ""
+				}
+				funcs[t.Name] = append(funcs[t.Name], filterFunc{
+					name:       "FilterAccessible" + pascalCase(t.Name) + pascalCase(r.Name),
+					objectType: t.Name,
+					relation:   r.Name,
+					query:      checkQuery(cfg, checkFunc, t.Name, r.Name),
+					checkFunc:  checkFunc,
+				})
+			}
+		}
+		sort.Slice(funcs[t.Name], func(i, j int) bool { return funcs[t.Name][i].relation < funcs[t.Name][j].relation })
+		for _, f := range funcs[t.Name] {
+			if other, ok := taken[f.name]; ok {
+				return nil, fmt.Errorf("go: %s for %s.%s collides with the %s", f.name, f.objectType, f.relation, other)
+			}
+			taken[f.name] = fmt.Sprintf("%s for %s.%s", f.name, f.objectType, f.relation)
 		}
 	}
+	return funcs, nil
+}
 
-	// Write wildcard constructors
-	ew.writeln("// Wildcard constructors for public access patterns.")
-	ew.writeln("")
-	for _, t := range objectTypes {
-		funcName := "Any" + pascalCase(t)
-		constName := "Type" + pascalCase(t)
-		ew.writef("// %s returns a wildcard %s that matches type:* tuples.\n", funcName, t)
-		ew.writef("func %s() melange.Object { return melange.Object{Type: %s, ID: \"*\"} }\n\n", funcName, constName)
+// checkQuery returns the query a FilterAccessible function checks one object
+// with. It calls the relation's specialized check function by name, skipping
+// the routing in check_permission along with what check_permission adds on
+// top: the --check-memo memo and the p_expected_schema_hash guard. The check
+// function raises on NULL arguments itself. With checkFunc empty, either
+// because the relation has no access paths or because cfg.CheckMemo asks for
+// the dispatcher, it calls check_permission with the type and relation
+// spliced in as literals, which keeps the three parameters of the direct
+// call.
+func checkQuery(cfg *clientgen.Config, checkFunc, objectType, relation string) string {
+	if checkFunc != "" {
+		return "SELECT " + cfg.CheckFunctionSQL(checkFunc) + "($1, $2, $3)"
 	}
+	return "SELECT " + cfg.CheckPermissionSQL() + "($1, $2, " + sqldsl.QuoteLiteral(relation) + ", " + sqldsl.QuoteLiteral(objectType) + ", $3)"
+}
 
-	if ew.err != nil {
-		return nil, ew.err
+// writeFilterFuncs writes the FilterAccessible functions of one type, each a
+// typed wrapper around filterAccessible.
+func writeFilterFuncs(ew *errWriter, funcs []filterFunc, idType string) {
+	objectID := "id"
+	if idType != "string" {
+		objectID = "fmt.Sprint(id)"
+	}
+	for _, f := range funcs {
+		ew.writef("// %s returns the IDs in ids of the %s objects subject has %s on,\n", f.name, f.objectType, f.relation)
+		ew.writeln("// in the order given, running the checks concurrently (see filterAccessible).")
+		if f.checkFunc != "" {
+			ew.writef("// Each check calls %s directly rather than check_permission, so it\n", f.checkFunc)
+			ew.writeln("// skips the --check-memo memo; regenerate with --check-memo to route it")
+			ew.writeln("// through check_permission.")
+		}
+		ew.writef("func %s(ctx context.Context, q melange.Querier, subject melange.Object, ids []%s) ([]%s, error) {\n", f.name, idType, idType)
+		ew.writef("\treturn filterAccessible(ctx, q, %q, subject, %q, ids, func(id %s) string { return %s })\n", f.query, f.relation, idType, objectID)
+		ew.writeln("}")
+		ew.writeln("")
 	}
+}
 
-	return map[string][]byte{
-		"schema_gen.go": buf.Bytes(),
-	}, nil
+// clientHelperNames are the exported identifiers hooksSource,
+// batchCheckSource and listObjectsCursorSource declare, which no constructor
+// may reuse.
+var clientHelperNames = map[string]bool{
+	"Hooks": true, "SetHooks": true,
+	"BatchCheck": true, "BatchCheckRequest": true, "BatchCheckResult": true,
+	"ListObjectsCursor": true,
+}
+
+// hooksSource declares Hooks and SetHooks. The hooks live in an
+// atomic.Pointer so SetHooks can race with checks in flight, and checkHook
+// returns nil when none are installed, so an unhooked client never reads the
+// clock.
+const hooksSource = `// Hooks holds callbacks the generated functions invoke, for example to record
+// metrics. Every field is optional.
+type Hooks struct {
+	// OnCheck is called once per check with its relation, its result, how
+	// long it took and its error. Checks BatchCheck answers in a single query
+	// each report the duration of that query.
+	OnCheck func(relation string, allowed bool, dur time.Duration, err error)
+}
+
+var hooks atomic.Pointer[Hooks]
+
+// SetHooks installs h for every later call of the generated functions,
+// replacing the hooks installed before. A nil h removes them. SetHooks is
+// safe to call while checks are running.
+func SetHooks(h *Hooks) { hooks.Store(h) }
+
+// checkHook returns the installed OnCheck callback, or nil.
+func checkHook() func(string, bool, time.Duration, error) {
+	if h := hooks.Load(); h != nil {
+		return h.OnCheck
+	}
+	return nil
+}
+`
+
+// batchCheckSource declares BatchCheck. It is the same for every schema: the
+// relation is a value in each request, so one query serves every pair.
+// unnest expands the five request arrays into rows, WITH ORDINALITY numbers
+// them so results can be put back in request order, and the lateral
+// subquery calls check_permission once per row. The arrays are bound as
+// text[] literals, so no request value is spliced into the SQL text.
+const batchCheckSource = `// BatchCheckRequest is one permission check passed to BatchCheck.
+type BatchCheckRequest struct {
+	Subject  melange.Object
+	Relation melange.Relation
+	Object   melange.Object
+}
+
+// BatchCheckResult is the outcome of one BatchCheckRequest. Err holds the
+// error for that check alone, in which case Allowed is false.
+type BatchCheckResult struct {
+	Allowed bool
+	Err     error
+}
+
+const batchCheckQuery = "SELECT t.idx::integer, c.allowed " +
+	"FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[]) " +
+	"WITH ORDINALITY AS t(subject_type, subject_id, relation, object_type, object_id, idx) " +
+	"CROSS JOIN LATERAL (SELECT check_permission(t.subject_type, t.subject_id, t.relation, t.object_type, t.object_id) AS allowed) c"
+
+// BatchCheck runs every request in a single query and returns one result per
+// request, in the same order. An empty reqs returns an empty slice without
+// querying. check_permission must be on the connection's search_path.
+//
+// If the query fails, each request is checked on its own so that an error,
+// such as a resolution depth limit, lands on the request that caused it
+// rather than failing the batch. Errors raised by check_permission are
+// translated by melange.TranslateError. When q is a *sql.Tx, the query and
+// each check run under a savepoint that is rolled back on error, so an error
+// neither aborts the transaction nor fails the later requests. The returned
+// error is non-nil only when ctx is done.
+//
+// Each request is reported to Hooks.OnCheck once it has a result.
+func BatchCheck(ctx context.Context, q melange.Querier, reqs []BatchCheckRequest) ([]BatchCheckResult, error) {
+	results := make([]BatchCheckResult, len(reqs))
+	if len(reqs) == 0 {
+		return results, nil
+	}
+	onCheck := checkHook()
+	var start time.Time
+	if onCheck != nil {
+		start = time.Now()
+	}
+
+	cols := make([][]string, 5)
+	for _, r := range reqs {
+		cols[0] = append(cols[0], string(r.Subject.Type))
+		cols[1] = append(cols[1], r.Subject.ID)
+		cols[2] = append(cols[2], string(r.Relation))
+		cols[3] = append(cols[3], string(r.Object.Type))
+		cols[4] = append(cols[4], r.Object.ID)
+	}
+	// A failed statement aborts a transaction until it rolls back to a
+	// savepoint taken before the statement. Errors from the savepoint
+	// statements themselves surface through the checks that follow.
+	tx, inTx := q.(*sql.Tx)
+	savepoint := func(stmt string) {
+		if inTx {
+			_, _ = tx.ExecContext(ctx, stmt+" melange_batch_check")
+		}
+	}
+	savepoint("SAVEPOINT")
+	defer savepoint("RELEASE SAVEPOINT")
+
+	rows, err := q.QueryContext(ctx, batchCheckQuery,
+		batchTextArray(cols[0]), batchTextArray(cols[1]), batchTextArray(cols[2]),
+		batchTextArray(cols[3]), batchTextArray(cols[4]))
+	if err == nil {
+		err = scanBatchCheck(rows, results)
+	}
+	if err == nil {
+		if onCheck != nil {
+			dur := time.Since(start)
+			for i, r := range reqs {
+				onCheck(string(r.Relation), results[i].Allowed, dur, nil)
+			}
+		}
+		return results, nil
+	}
+	savepoint("ROLLBACK TO SAVEPOINT")
+
+	for i, r := range reqs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if onCheck != nil {
+			start = time.Now()
+		}
+		var allowed int
+		err := melange.TranslateError(q.QueryRowContext(ctx, "SELECT check_permission($1, $2, $3, $4, $5)",
+			string(r.Subject.Type), r.Subject.ID, string(r.Relation), string(r.Object.Type), r.Object.ID).Scan(&allowed))
+		results[i] = BatchCheckResult{Allowed: err == nil && allowed == 1, Err: err}
+		if err != nil {
+			savepoint("ROLLBACK TO SAVEPOINT")
+		}
+		if onCheck != nil {
+			onCheck(string(r.Relation), results[i].Allowed, time.Since(start), err)
+		}
+	}
+	return results, nil
+}
+
+// scanBatchCheck records each row of batchCheckQuery in results.
+func scanBatchCheck(rows *sql.Rows, results []BatchCheckResult) error {
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var idx, allowed int
+		if err := rows.Scan(&idx, &allowed); err != nil {
+			return err
+		}
+		// idx comes from WITH ORDINALITY, so it is 1-based.
+		if idx >= 1 && idx <= len(results) {
+			results[idx-1].Allowed = allowed == 1
+		}
+	}
+	return rows.Err()
+}
+
+// batchTextArray encodes vals as a PostgreSQL text[] literal.
+func batchTextArray(vals []string) string {
+	quote := strings.NewReplacer("\\", "\\\\", "\"", "\\\"")
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, v := range vals {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('"')
+		b.WriteString(quote.Replace(v))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+`
+
+// writeBatchCheck writes BatchCheck and its request and result types, with
+// check_permission named as cfg.CheckPermissionSQL and, when that name is
+// schema-qualified, documented as such.
+func writeBatchCheck(ew *errWriter, cfg *clientgen.Config) {
+	fn := strconv.Quote(cfg.CheckPermissionSQL())
+	fn = fn[1 : len(fn)-1] // spliced into Go string literals
+	src := strings.ReplaceAll(batchCheckSource, "SELECT check_permission(", "SELECT "+fn+"(")
+	if cfg.SchemaName != "" {
+		src = strings.Replace(src, "querying. check_permission must be on the connection's search_path.",
+			"querying. It calls check_permission in the "+strconv.Quote(cfg.SchemaName)+" schema.", 1)
+	}
+	ew.writeln(src)
+}
+
+// listObjectsCursorSource declares ListObjectsCursor, which wraps the
+// refcursor that list_accessible_objects_cursor returns in an iterator. Each
+// FETCH is read in full before its IDs are yielded, because the transaction's
+// connection cannot run another statement while rows are pending.
+const listObjectsCursorSource = `// ListObjectsCursor iterates over the IDs of the objectType objects that
+// subject has relation on, each once and ordered by ID. It fetches batchSize
+// IDs at a time from the cursor list_accessible_objects_cursor opens, so the
+// client holds one batch however many objects there are. A batchSize below 1
+// fetches 1000 at a time. list_accessible_objects_cursor is installed by
+// melange migrate --list-objects-cursor.
+//
+// The consistency on ctx (melange.WithConsistency) is passed as
+// p_consistency, which does not change the result: tx reads the database it
+// was begun on, so begin it on the primary for melange.ConsistencyStrong.
+//
+// The cursor belongs to tx, so iterate before tx ends. The loop body may run
+// other queries on tx between IDs. Iteration ends after yielding the first
+// error, with an empty ID; stopping early closes the cursor. Errors raised
+// by the list functions, such as melange.ErrRelationNotListable, are
+// translated by melange.TranslateError.
+func ListObjectsCursor(ctx context.Context, tx *sql.Tx, subject melange.Object, relation melange.Relation, objectType melange.ObjectType, batchSize int) iter.Seq2[string, error] {
+	if batchSize < 1 {
+		batchSize = 1000
+	}
+	return func(yield func(string, error) bool) {
+		var consistency any
+		if c := melange.GetConsistency(ctx); c != melange.ConsistencyUnspecified {
+			consistency = c.String()
+		}
+		var cursor string
+		err := tx.QueryRowContext(ctx, "SELECT list_accessible_objects_cursor($1, $2, $3, $4, p_consistency => $5)",
+			string(subject.Type), subject.ID, string(relation), string(objectType), consistency).Scan(&cursor)
+		if err != nil {
+			yield("", melange.TranslateError(err))
+			return
+		}
+		// Cursor names such as "<unnamed portal 1>" need quoting.
+		name := "\"" + strings.ReplaceAll(cursor, "\"", "\"\"") + "\""
+		defer func() { _, _ = tx.ExecContext(ctx, "CLOSE "+name) }()
+
+		fetch := "FETCH " + strconv.Itoa(batchSize) + " FROM " + name
+		for {
+			ids, err := fetchObjectIDs(ctx, tx, fetch)
+			if err != nil {
+				yield("", melange.TranslateError(err))
+				return
+			}
+			for _, id := range ids {
+				if !yield(id, nil) {
+					return
+				}
+			}
+			if len(ids) < batchSize {
+				return
+			}
+		}
+	}
+}
+
+// fetchObjectIDs runs fetch and returns the object IDs it read.
+func fetchObjectIDs(ctx context.Context, tx *sql.Tx, fetch string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+`
+
+// writeListObjectsCursor writes ListObjectsCursor, with
+// list_accessible_objects_cursor named as cfg.ListObjectsCursorSQL.
+func writeListObjectsCursor(ew *errWriter, cfg *clientgen.Config) {
+	fn := strconv.Quote(cfg.ListObjectsCursorSQL())
+	fn = fn[1 : len(fn)-1] // spliced into Go string literals
+	ew.writeln(strings.ReplaceAll(listObjectsCursorSource, "SELECT list_accessible_objects_cursor(", "SELECT "+fn+"("))
+}
+
+// defaultFilterConcurrency is how many checks filterAccessible runs at once
+// when Config.Concurrency is unset.
+const defaultFilterConcurrency = 8
+
+// filterAccessibleSource declares filterAccessible, the worker pool behind
+// every FilterAccessible function. Workers take indexes from an unbuffered
+// channel, so no more than filterConcurrency checks are in flight, and the
+// first error cancels the context the others query with.
+const filterAccessibleSource = `// filterConcurrency is how many checks filterAccessible runs at once.
+const filterConcurrency = 8
+
+// filterAccessible returns the ids of the objects subject has relation on, in
+// the order given. It runs query, which takes the subject type, subject ID
+// and object ID, once per id. With a *sql.DB it runs up to filterConcurrency
+// checks at once, spread over the connection pool. Any other q, such as a
+// *sql.Tx or *sql.Conn, holds a single connection that cannot run statements
+// concurrently, so its checks run one at a time. The first failing check
+// cancels the others and its error is returned with no IDs, as is ctx's error
+// once ctx is done.
+//
+// Each check is reported to Hooks.OnCheck.
+func filterAccessible[ID any](ctx context.Context, q melange.Querier, query string, subject melange.Object, relation melange.Relation, ids []ID, objectID func(ID) string) ([]ID, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	allowed := make([]bool, len(ids))
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		failErr  error
+	)
+	workers := 1
+	if _, ok := q.(*sql.DB); ok {
+		workers = filterConcurrency
+	}
+	next := make(chan int)
+	for range min(workers, len(ids)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				ok, err := filterCheck(ctx, q, query, subject, relation, objectID(ids[i]))
+				if err != nil {
+					failOnce.Do(func() {
+						failErr = err
+						cancel()
+					})
+					continue
+				}
+				allowed[i] = ok
+			}
+		}()
+	}
+feed:
+	for i := range ids {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if failErr != nil {
+		return nil, failErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]ID, 0, len(ids))
+	for i, id := range ids {
+		if allowed[i] {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// filterCheck runs one check for filterAccessible.
+func filterCheck(ctx context.Context, q melange.Querier, query string, subject melange.Object, relation melange.Relation, objectID string) (bool, error) {
+	onCheck := checkHook()
+	var start time.Time
+	if onCheck != nil {
+		start = time.Now()
+	}
+	var allowed int
+	err := melange.TranslateError(q.QueryRowContext(ctx, query, string(subject.Type), subject.ID, objectID).Scan(&allowed))
+	ok := err == nil && allowed == 1
+	if onCheck != nil {
+		onCheck(string(relation), ok, time.Since(start), err)
+	}
+	return ok, err
+}
+`
+
+// writeFilterAccessible writes filterAccessible, running cfg.Concurrency
+// checks at once (defaultFilterConcurrency when unset).
+func writeFilterAccessible(ew *errWriter, cfg *clientgen.Config) {
+	concurrency := cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultFilterConcurrency
+	}
+	ew.writeln(strings.Replace(filterAccessibleSource, "const filterConcurrency = 8", "const filterConcurrency = "+strconv.Itoa(concurrency), 1))
 }
 
 // errWriter wraps a bytes.Buffer and captures the first error.
@@ -1,6 +1,12 @@
 package gogen_test
 
 import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
 	"strings"
 	"testing"
 
@@ -265,6 +271,541 @@ func TestGenerator_VersionHeader(t *testing.T) {
 	})
 }
 
+// melangeStub declares the melange types generated code refers to, so the
+// generated files can be type-checked without loading the real package.
+const melangeStub = `package melange
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+type ObjectType string
+type Relation string
+type Object struct {
+	Type ObjectType
+	ID   string
+}
+
+func TranslateError(err error) error { return err }
+
+type Consistency int
+
+const ConsistencyUnspecified Consistency = 0
+
+func (c Consistency) String() string { return "" }
+
+func GetConsistency(ctx context.Context) Consistency { return ConsistencyUnspecified }
+`
+
+// typeCheck type-checks files as one package, returning the first error.
+func typeCheck(t *testing.T, files map[string][]byte) error {
+	t.Helper()
+	fset := token.NewFileSet()
+	stubFile, err := parser.ParseFile(fset, "melange.go", melangeStub, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	std := importer.ForCompiler(fset, "source", nil)
+	stub, err := (&types.Config{Importer: std}).Check("github.com/pthm/melange/melange", fset, []*ast.File{stubFile}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed []*ast.File
+	for name, content := range files {
+		f, err := parser.ParseFile(fset, name, content, 0)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, f)
+	}
+	conf := types.Config{Importer: importerFunc(func(path string) (*types.Package, error) {
+		if path == stub.Path() {
+			return stub, nil
+		}
+		return std.Import(path)
+	})}
+	_, err = conf.Check("authz", fset, parsed, nil)
+	return err
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+func TestGenerator_SplitByType(t *testing.T) {
+	typeDefs := []schema.TypeDefinition{
+		{Name: "user"},
+		{
+			Name: "repository",
+			Relations: []schema.RelationDefinition{
+				{Name: "owner", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}},
+				{Name: "can_read", ImpliedBy: []string{"owner"}},
+			},
+		},
+		{
+			Name: "pull_request",
+			Relations: []schema.RelationDefinition{
+				{Name: "owner", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}},
+			},
+		},
+	}
+
+	gen := &gogen.Generator{}
+
+	t.Run("one file per type plus client.go", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{SplitByType: true})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+
+		var names []string
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if got, want := strings.Join(names, ","), "client.go,pull_request.go,repository.go,user.go"; got != want {
+			t.Errorf("files = %s, want %s", got, want)
+		}
+
+		// Relations shared by several types are declared once, in client.go.
+		client := string(files["client.go"])
+		if !strings.Contains(client, `RelOwner melange.Relation = "owner"`) {
+			t.Error("client.go should declare RelOwner")
+		}
+		repo := string(files["repository.go"])
+		for _, want := range []string{
+			`const TypeRepository melange.ObjectType = "repository"`,
+			"func Repository(id string) melange.Object",
+			"func AnyRepository() melange.Object",
+		} {
+			if !strings.Contains(repo, want) {
+				t.Errorf("repository.go missing %q", want)
+			}
+		}
+		if strings.Contains(repo, "RelOwner") || strings.Contains(repo, "TypeUser") {
+			t.Error("repository.go should only declare repository")
+		}
+
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("split files do not compile as one package: %v", err)
+		}
+	})
+
+	t.Run("compiles with non-string IDs and no relations", func(t *testing.T) {
+		files, err := gen.Generate([]schema.TypeDefinition{{Name: "user"}}, &clientgen.Config{SplitByType: true, IDType: "int64"})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("split files do not compile as one package: %v", err)
+		}
+	})
+
+	t.Run("renames files the go tool treats specially", func(t *testing.T) {
+		special := []schema.TypeDefinition{{Name: "client"}, {Name: "audit_test"}, {Name: "host_linux"}, {Name: "board_arm64"}}
+		files, err := gen.Generate(special, &clientgen.Config{SplitByType: true})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		for _, name := range []string{"client.go", "type_client_gen.go", "type_audit_test_gen.go", "type_host_linux_gen.go", "type_board_arm64_gen.go"} {
+			if _, ok := files[name]; !ok {
+				t.Errorf("missing %s", name)
+			}
+		}
+		if len(files) != 5 {
+			t.Errorf("Generate returned %d files, want 5", len(files))
+		}
+	})
+}
+
+func TestGenerator_BatchCheck(t *testing.T) {
+	typeDefs := []schema.TypeDefinition{
+		{Name: "user"},
+		{Name: "repository", Relations: []schema.RelationDefinition{{Name: "can_read"}}},
+	}
+	gen := &gogen.Generator{}
+
+	t.Run("runs every check in one unnest query", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, nil)
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		code := string(files["schema_gen.go"])
+		for _, want := range []string{
+			"type BatchCheckRequest struct {",
+			"type BatchCheckResult struct {",
+			"func BatchCheck(ctx context.Context, q melange.Querier, reqs []BatchCheckRequest) ([]BatchCheckResult, error) {",
+			"FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[]) ",
+			"WITH ORDINALITY AS t(",
+			"CROSS JOIN LATERAL (SELECT check_permission(",
+			"tx, inTx := q.(*sql.Tx)",
+			`savepoint("ROLLBACK TO SAVEPOINT")`,
+			"check_permission must be on the connection's search_path.",
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("schema_gen.go missing %q", want)
+			}
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("generated code does not compile: %v", err)
+		}
+	})
+
+	t.Run("qualifies check_permission with SchemaName", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{SchemaName: "authz"})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		code := string(files["schema_gen.go"])
+		// BatchCheck's batch query and per-request retry, and the filter
+		// function of can_read, which has no check function of its own.
+		if n := strings.Count(code, `SELECT \"authz\".\"check_permission\"(`); n != 3 {
+			t.Errorf("found %d qualified check_permission calls, want 3", n)
+		}
+		if strings.Contains(code, "SELECT check_permission(") {
+			t.Error("schema_gen.go still calls check_permission unqualified")
+		}
+		if !strings.Contains(code, `It calls check_permission in the "authz" schema.`) || strings.Contains(code, "search_path") {
+			t.Error("BatchCheck's doc comment should name the schema instead of the search_path")
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("generated code does not compile: %v", err)
+		}
+	})
+
+	t.Run("split layout declares it once in client.go", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{SplitByType: true})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		for name, content := range files {
+			has := strings.Contains(string(content), "func BatchCheck(")
+			if has != (name == "client.go") {
+				t.Errorf("%s declares BatchCheck: %v", name, has)
+			}
+		}
+	})
+
+	t.Run("rejects types whose constructor collides", func(t *testing.T) {
+		if _, err := gen.Generate([]schema.TypeDefinition{{Name: "batch_check_result"}}, nil); err == nil {
+			t.Error("Generate should reject a type whose constructor redeclares BatchCheckResult")
+		}
+	})
+}
+
+// Every query the generated code runs passes its error through
+// melange.TranslateError, so callers get the same typed errors as Checker.
+func TestGenerator_TranslatesErrors(t *testing.T) {
+	typeDefs := []schema.TypeDefinition{
+		{Name: "user"},
+		{Name: "repository", Relations: []schema.RelationDefinition{{Name: "can_read"}}},
+	}
+	files, err := (&gogen.Generator{}).Generate(typeDefs, nil)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	code := string(files["schema_gen.go"])
+	for _, want := range []string{
+		// BatchCheck's per-request retry.
+		`err := melange.TranslateError(q.QueryRowContext(ctx, "SELECT check_permission(`,
+		// ListObjectsCursor, on opening the cursor and on each FETCH.
+		`yield("", melange.TranslateError(err))`,
+		// filterCheck.
+		"err := melange.TranslateError(q.QueryRowContext(ctx, query,",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("schema_gen.go missing %q", want)
+		}
+	}
+	if n := strings.Count(code, `yield("", melange.TranslateError(err))`); n != 2 {
+		t.Errorf("ListObjectsCursor translates %d errors, want 2", n)
+	}
+	if err := typeCheck(t, files); err != nil {
+		t.Errorf("generated code does not compile: %v", err)
+	}
+}
+
+func TestGenerator_Hooks(t *testing.T) {
+	typeDefs := []schema.TypeDefinition{
+		{Name: "user"},
+		{Name: "repository", Relations: []schema.RelationDefinition{{Name: "can_read"}}},
+	}
+	gen := &gogen.Generator{}
+
+	t.Run("BatchCheck reports each check to OnCheck", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, nil)
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		code := string(files["schema_gen.go"])
+		for _, want := range []string{
+			"type Hooks struct {",
+			"OnCheck func(relation string, allowed bool, dur time.Duration, err error)",
+			"func SetHooks(h *Hooks) { hooks.Store(h) }",
+			"onCheck(string(r.Relation), results[i].Allowed, dur, nil)",
+			"onCheck(string(r.Relation), results[i].Allowed, time.Since(start), err)",
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("schema_gen.go missing %q", want)
+			}
+		}
+		// Without hooks, BatchCheck must not read the clock.
+		for _, call := range []string{"start = time.Now()", "dur := time.Since(start)"} {
+			i := strings.Index(code, call)
+			if i < 0 || !strings.HasSuffix(strings.TrimSpace(code[:i]), "if onCheck != nil {") {
+				t.Errorf("%q should only run when OnCheck is set", call)
+			}
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("generated code does not compile: %v", err)
+		}
+	})
+
+	t.Run("split layout declares it once in client.go", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{SplitByType: true})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		for name, content := range files {
+			has := strings.Contains(string(content), "type Hooks struct {")
+			if has != (name == "client.go") {
+				t.Errorf("%s declares Hooks: %v", name, has)
+			}
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("split files do not compile as one package: %v", err)
+		}
+	})
+
+	t.Run("rejects types whose constructor collides", func(t *testing.T) {
+		if _, err := gen.Generate([]schema.TypeDefinition{{Name: "hooks"}}, nil); err == nil {
+			t.Error("Generate should reject a type whose constructor redeclares Hooks")
+		}
+	})
+}
+
+func TestGenerator_ListObjectsCursor(t *testing.T) {
+	typeDefs := []schema.TypeDefinition{
+		{Name: "user"},
+		{Name: "repository", Relations: []schema.RelationDefinition{{Name: "can_read"}}},
+	}
+	gen := &gogen.Generator{}
+
+	t.Run("iterates over the cursor in batches", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, nil)
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		code := string(files["schema_gen.go"])
+		for _, want := range []string{
+			"func ListObjectsCursor(ctx context.Context, tx *sql.Tx, subject melange.Object, relation melange.Relation, objectType melange.ObjectType, batchSize int) iter.Seq2[string, error] {",
+			`"SELECT list_accessible_objects_cursor($1, $2, $3, $4, p_consistency => $5)"`,
+			"consistency = c.String()",
+			`"FETCH " + strconv.Itoa(batchSize) + " FROM " + name`,
+			`"CLOSE "+name`,
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("schema_gen.go missing %q", want)
+			}
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("generated code does not compile: %v", err)
+		}
+	})
+
+	t.Run("qualifies the cursor function with SchemaName", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{SchemaName: "authz"})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		code := string(files["schema_gen.go"])
+		if !strings.Contains(code, `SELECT \"authz\".\"list_accessible_objects_cursor\"($1, $2, $3, $4, p_consistency => $5)`) {
+			t.Error("schema_gen.go should call list_accessible_objects_cursor schema-qualified")
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("generated code does not compile: %v", err)
+		}
+	})
+
+	t.Run("split layout declares it once in client.go", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{SplitByType: true, IDType: "int64"})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		for name, content := range files {
+			has := strings.Contains(string(content), "func ListObjectsCursor(")
+			if has != (name == "client.go") {
+				t.Errorf("%s declares ListObjectsCursor: %v", name, has)
+			}
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("split files do not compile as one package: %v", err)
+		}
+	})
+
+	t.Run("rejects types whose constructor collides", func(t *testing.T) {
+		if _, err := gen.Generate([]schema.TypeDefinition{{Name: "list_objects_cursor"}}, nil); err == nil {
+			t.Error("Generate should reject a type whose constructor redeclares ListObjectsCursor")
+		}
+	})
+}
+
+func TestGenerator_FilterAccessible(t *testing.T) {
+	typeDefs := []schema.TypeDefinition{
+		{Name: "user"},
+		{Name: "repository", Relations: []schema.RelationDefinition{
+			{Name: "owner", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}},
+			{Name: "can_read", ImpliedBy: []string{"owner"}},
+			{Name: "archived"},
+		}},
+	}
+	gen := &gogen.Generator{}
+
+	t.Run("one typed function per type and relation", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{IDType: "int64", RelationFilter: "can_"})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		code := string(files["schema_gen.go"])
+		for _, want := range []string{
+			"func FilterAccessibleRepositoryCanRead(ctx context.Context, q melange.Querier, subject melange.Object, ids []int64) ([]int64, error) {",
+			`return filterAccessible(ctx, q, "SELECT check_repository_can_read($1, $2, $3)", subject, "can_read", ids, func(id int64) string { return fmt.Sprint(id) })`,
+			"const filterConcurrency = 8",
+			"if _, ok := q.(*sql.DB); ok {",
+			"func filterAccessible[ID any](",
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("schema_gen.go missing %q", want)
+			}
+		}
+		if strings.Contains(code, "FilterAccessibleRepositoryOwner") {
+			t.Error("RelationFilter should leave out FilterAccessibleRepositoryOwner")
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("generated code does not compile: %v", err)
+		}
+	})
+
+	t.Run("calls each relation's check function directly", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{SchemaName: "authz"})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		code := string(files["schema_gen.go"])
+		for _, want := range []string{
+			`return filterAccessible(ctx, q, "SELECT \"authz\".\"check_repository_owner\"($1, $2, $3)", subject, "owner",`,
+			`return filterAccessible(ctx, q, "SELECT \"authz\".\"check_repository_can_read\"($1, $2, $3)", subject, "can_read",`,
+			// archived has no access paths and so no check function.
+			`return filterAccessible(ctx, q, "SELECT \"authz\".\"check_permission\"($1, $2, 'archived', 'repository', $3)", subject, "archived",`,
+		} {
+			if !strings.Contains(code, want) {
+				t.Errorf("schema_gen.go missing %q", want)
+			}
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("generated code does not compile: %v", err)
+		}
+	})
+
+	t.Run("documents the bypass of check_permission", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, nil)
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		code := string(files["schema_gen.go"])
+		if !strings.Contains(code, "// Each check calls check_repository_can_read directly rather than check_permission, so it\n// skips the --check-memo memo;") {
+			t.Error("FilterAccessibleRepositoryCanRead should say it bypasses check_permission")
+		}
+	})
+
+	t.Run("CheckMemo keeps the dispatcher", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{CheckMemo: true})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		code := string(files["schema_gen.go"])
+		if !strings.Contains(code, `return filterAccessible(ctx, q, "SELECT check_permission($1, $2, 'can_read', 'repository', $3)", subject, "can_read",`) {
+			t.Error("CheckMemo should route FilterAccessibleRepositoryCanRead through check_permission")
+		}
+		if strings.Contains(code, "check_repository_") || strings.Contains(code, "directly rather than check_permission") {
+			t.Error("CheckMemo should leave no direct check function calls")
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("generated code does not compile: %v", err)
+		}
+	})
+
+	t.Run("uses the hashed names of relations differing by case", func(t *testing.T) {
+		user := []schema.SubjectTypeRef{{Type: "user"}}
+		files, err := gen.Generate([]schema.TypeDefinition{
+			{Name: "user"},
+			{Name: "doc", Relations: []schema.RelationDefinition{
+				{Name: "viewer", SubjectTypeRefs: user},
+				{Name: "Viewer", SubjectTypeRefs: user},
+			}},
+		}, &clientgen.Config{RelationFilter: "V"})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		code := string(files["schema_gen.go"])
+		if !strings.Contains(code, `return filterAccessible(ctx, q, "SELECT check_doc_viewer_`) {
+			t.Error("FilterAccessibleDocViewer should call the hashed check function of doc.Viewer")
+		}
+		if strings.Contains(code, `"SELECT check_doc_viewer($1, $2, $3)"`) {
+			t.Error("FilterAccessibleDocViewer calls check_doc_viewer, which checks doc.viewer")
+		}
+	})
+
+	t.Run("Concurrency sets the worker count", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{Concurrency: 32})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		if !strings.Contains(string(files["schema_gen.go"]), "const filterConcurrency = 32") {
+			t.Error("schema_gen.go should run 32 checks at once")
+		}
+	})
+
+	t.Run("split layout puts each function in its type's file", func(t *testing.T) {
+		files, err := gen.Generate(typeDefs, &clientgen.Config{SplitByType: true})
+		if err != nil {
+			t.Fatalf("Generate error: %v", err)
+		}
+		for name, content := range files {
+			code := string(content)
+			if has := strings.Contains(code, "func filterAccessible["); has != (name == "client.go") {
+				t.Errorf("%s declares filterAccessible: %v", name, has)
+			}
+			if has := strings.Contains(code, "func FilterAccessibleRepositoryCanRead("); has != (name == "repository.go") {
+				t.Errorf("%s declares FilterAccessibleRepositoryCanRead: %v", name, has)
+			}
+		}
+		if strings.Contains(string(files["user.go"]), `"context"`) {
+			t.Error("user.go has no filter functions and should not import context")
+		}
+		if err := typeCheck(t, files); err != nil {
+			t.Errorf("split files do not compile as one package: %v", err)
+		}
+	})
+
+	t.Run("rejects functions sharing a name", func(t *testing.T) {
+		clash := []schema.TypeDefinition{
+			{Name: "a_b", Relations: []schema.RelationDefinition{{Name: "c"}}},
+			{Name: "a", Relations: []schema.RelationDefinition{{Name: "b_c"}}},
+		}
+		if _, err := gen.Generate(clash, nil); err == nil {
+			t.Error("Generate should reject two types whose FilterAccessible functions are both FilterAccessibleABC")
+		}
+	})
+}
+
 func TestRegistry_GoGeneratorRegistered(t *testing.T) {
 	gen := clientgen.Get("go")
 	if gen == nil {
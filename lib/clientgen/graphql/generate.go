@@ -0,0 +1,247 @@
+// Package graphql implements the GraphQL schema generator for melange.
+//
+// This generator emits GraphQL SDL describing the permission model: enums of
+// object types and relations, and a Query type with a check and a list field
+// per relation. It produces only the schema contract; resolvers, which call
+// melange's check_permission and list_accessible_objects, are left to the
+// application.
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pthm/melange/lib/clientgen"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+func init() {
+	clientgen.Register(&Generator{})
+}
+
+// Generator implements clientgen.Generator for GraphQL SDL.
+type Generator struct{}
+
+// Name returns "graphql" as the runtime identifier.
+func (g *Generator) Name() string { return "graphql" }
+
+// DefaultConfig returns default configuration for GraphQL schema generation.
+func (g *Generator) DefaultConfig() *clientgen.Config {
+	return &clientgen.Config{
+		Package:        "", // Not used for GraphQL
+		RelationFilter: "",
+		IDType:         "ID", // GraphQL always uses ID
+		Options:        make(map[string]any),
+	}
+}
+
+// Generate produces GraphQL SDL from the given type definitions.
+//
+// Returns a single file map entry with key "schema.graphql". cfg.SplitByType
+// is ignored, as the Query type cannot be split without extensions that not
+// every GraphQL server supports.
+//
+// Generated SDL includes:
+//   - ObjectType and Relation enums (USER, CAN_READ, etc.)
+//   - A Query field checkTypeRelation(subjectId, objectId): Boolean! per relation
+//   - A Query field listTypeRelationObjects(subjectId, first, after): [ID!]!
+//     per relation
+//
+// Returns an error when a type or relation name cannot be spelled as a
+// GraphQL name, when two fields would share a name, or when cfg.RelationFilter
+// leaves no relations, since GraphQL rejects an empty enum or Query type.
+func (g *Generator) Generate(types []schema.TypeDefinition, cfg *clientgen.Config) (map[string][]byte, error) {
+	// Validate schema before generating code
+	if err := schema.DetectCycles(types); err != nil {
+		return nil, err
+	}
+
+	if cfg == nil {
+		cfg = g.DefaultConfig()
+	}
+
+	sorted := make([]schema.TypeDefinition, len(types))
+	copy(sorted, types)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	objectTypes := make([]string, 0, len(sorted))
+	relSet := make(map[string]bool)
+	var fields []queryField
+	for _, t := range sorted {
+		objectTypes = append(objectTypes, t.Name)
+
+		var relations []string
+		for _, r := range t.Relations {
+			if cfg.RelationFilter == "" || strings.HasPrefix(r.Name, cfg.RelationFilter) {
+				relations = append(relations, r.Name)
+				relSet[r.Name] = true
+			}
+		}
+		sort.Strings(relations)
+		for _, r := range relations {
+			fields = append(fields, queryField{objectType: t.Name, relation: r})
+		}
+	}
+
+	relations := make([]string, 0, len(relSet))
+	for r := range relSet {
+		relations = append(relations, r)
+	}
+	sort.Strings(relations)
+
+	if len(objectTypes) == 0 {
+		return nil, fmt.Errorf("graphql: schema has no types")
+	}
+	if len(relations) == 0 {
+		if cfg.RelationFilter != "" {
+			return nil, fmt.Errorf("graphql: no relations match filter %q", cfg.RelationFilter)
+		}
+		return nil, fmt.Errorf("graphql: schema has no relations")
+	}
+	if err := validateNames(objectTypes, relations, fields); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	ew := &errWriter{w: &buf}
+
+	ew.writeln("# Generated by melange. DO NOT EDIT.")
+	ew.writeln("#")
+	if cfg.Version != "" {
+		ew.writef("# melange version: %s\n", cfg.Version)
+	}
+	if cfg.SourcePath != "" {
+		ew.writef("# source: %s\n", cfg.SourcePath)
+	}
+	ew.writeln("")
+
+	ew.writeln("\"Object types from the authorization schema.\"")
+	writeEnum(ew, "ObjectType", objectTypes)
+	ew.writeln("\"Relations from the authorization schema.\"")
+	writeEnum(ew, "Relation", relations)
+
+	ew.writeln("type Query {")
+	for i, f := range fields {
+		if i > 0 {
+			ew.writeln("")
+		}
+		ew.writef("  \"Whether the subject has %s on the %s.\"\n", f.relation, f.objectType)
+		ew.writef("  %s(subjectId: ID!, objectId: ID!): Boolean!\n", f.checkName())
+		ew.writeln("")
+		ew.writef("  \"IDs of the %s objects the subject has %s on, after the cursor.\"\n", f.objectType, f.relation)
+		ew.writef("  %s(subjectId: ID!, first: Int, after: String): [ID!]!\n", f.listName())
+	}
+	ew.writeln("}")
+
+	if ew.err != nil {
+		return nil, ew.err
+	}
+
+	return map[string][]byte{
+		"schema.graphql": buf.Bytes(),
+	}, nil
+}
+
+// queryField is one relation exposed on the Query type.
+type queryField struct {
+	objectType string
+	relation   string
+}
+
+// checkName returns the check field name, e.g. checkDocumentViewer.
+func (f queryField) checkName() string {
+	return "check" + pascalCase(f.objectType) + pascalCase(f.relation)
+}
+
+// listName returns the list field name, e.g. listDocumentViewerObjects.
+func (f queryField) listName() string {
+	return "list" + pascalCase(f.objectType) + pascalCase(f.relation) + "Objects"
+}
+
+// graphqlName matches the GraphQL Name production.
+var graphqlName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// validateNames rejects schemas whose names cannot be spelled in SDL. OpenFGA
+// allows names such as "team-member" that GraphQL does not, and snake_case
+// names like "doc_a"/"b" and "doc"/"a_b" collapse to the same field name.
+func validateNames(objectTypes, relations []string, fields []queryField) error {
+	for _, values := range [][]string{objectTypes, relations} {
+		seen := make(map[string]string, len(values))
+		for _, v := range values {
+			value := enumValue(v)
+			if !graphqlName.MatchString(value) || value == "TRUE" || value == "FALSE" || value == "NULL" {
+				return fmt.Errorf("graphql: %q is not a valid GraphQL enum value", v)
+			}
+			if prev, ok := seen[value]; ok {
+				return fmt.Errorf("graphql: %q and %q both map to enum value %s", prev, v, value)
+			}
+			seen[value] = v
+		}
+	}
+
+	seen := make(map[string]queryField, len(fields))
+	for _, f := range fields {
+		name := f.checkName()
+		if !graphqlName.MatchString(name) {
+			return fmt.Errorf("graphql: %s#%s is not a valid GraphQL field name", f.objectType, f.relation)
+		}
+		if prev, ok := seen[name]; ok {
+			return fmt.Errorf("graphql: %s#%s and %s#%s both map to field %s",
+				prev.objectType, prev.relation, f.objectType, f.relation, name)
+		}
+		seen[name] = f
+	}
+	return nil
+}
+
+// writeEnum writes an enum whose values are the SCREAMING_SNAKE_CASE form of
+// names.
+func writeEnum(ew *errWriter, name string, names []string) {
+	ew.writef("enum %s {\n", name)
+	for _, n := range names {
+		ew.writef("  %s\n", enumValue(n))
+	}
+	ew.writeln("}")
+	ew.writeln("")
+}
+
+// errWriter wraps a bytes.Buffer and captures the first error.
+type errWriter struct {
+	w   *bytes.Buffer
+	err error
+}
+
+func (ew *errWriter) writeln(s string) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintln(ew.w, s)
+}
+
+func (ew *errWriter) writef(format string, args ...any) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
+
+// enumValue converts a schema name to an enum value.
+// Examples: "user" -> "USER", "can_read" -> "CAN_READ"
+func enumValue(s string) string {
+	return strings.ToUpper(s)
+}
+
+// pascalCase converts snake_case to PascalCase.
+// Examples: "user" -> "User", "pull_request" -> "PullRequest"
+func pascalCase(s string) string {
+	parts := strings.Split(s, "_")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
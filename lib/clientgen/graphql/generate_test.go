@@ -0,0 +1,144 @@
+package graphql_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pthm/melange/lib/clientgen"
+	"github.com/pthm/melange/lib/clientgen/graphql"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+func TestGenerator_Interface(t *testing.T) {
+	gen := &graphql.Generator{}
+
+	if got := gen.Name(); got != "graphql" {
+		t.Errorf("Name() = %q, want %q", got, "graphql")
+	}
+	if !clientgen.Registered("graphql") {
+		t.Error("graphql generator should register itself")
+	}
+}
+
+func testTypes() []schema.TypeDefinition {
+	return []schema.TypeDefinition{
+		{Name: "user"},
+		{
+			Name: "pull_request",
+			Relations: []schema.RelationDefinition{
+				{Name: "author", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}},
+				{Name: "can_review", ImpliedBy: []string{"author"}},
+			},
+		},
+		{
+			Name: "document",
+			Relations: []schema.RelationDefinition{
+				{Name: "viewer", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}},
+			},
+		},
+	}
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	gen := &graphql.Generator{}
+	files, err := gen.Generate(testTypes(), &clientgen.Config{Version: "v1.2.3", SourcePath: "schema.fga"})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Generate returned %d files, want 1", len(files))
+	}
+	sdl := string(files["schema.graphql"])
+
+	for _, want := range []string{
+		"# Generated by melange. DO NOT EDIT.",
+		"# melange version: v1.2.3",
+		"# source: schema.fga",
+		"enum ObjectType {\n  DOCUMENT\n  PULL_REQUEST\n  USER\n}",
+		"enum Relation {\n  AUTHOR\n  CAN_REVIEW\n  VIEWER\n}",
+		"checkDocumentViewer(subjectId: ID!, objectId: ID!): Boolean!",
+		"listDocumentViewerObjects(subjectId: ID!, first: Int, after: String): [ID!]!",
+		"checkPullRequestCanReview(subjectId: ID!, objectId: ID!): Boolean!",
+		"listPullRequestAuthorObjects(subjectId: ID!, first: Int, after: String): [ID!]!",
+	} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("schema.graphql missing %q\n%s", want, sdl)
+		}
+	}
+
+	// Fields follow type, then relation, order.
+	if strings.Index(sdl, "checkDocumentViewer") > strings.Index(sdl, "checkPullRequestAuthor") ||
+		strings.Index(sdl, "checkPullRequestAuthor") > strings.Index(sdl, "checkPullRequestCanReview") {
+		t.Errorf("Query fields are not sorted:\n%s", sdl)
+	}
+	if strings.Count(sdl, "{") != strings.Count(sdl, "}") {
+		t.Errorf("unbalanced braces:\n%s", sdl)
+	}
+}
+
+func TestGenerator_RelationFilter(t *testing.T) {
+	gen := &graphql.Generator{}
+	files, err := gen.Generate(testTypes(), &clientgen.Config{RelationFilter: "can_"})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	sdl := string(files["schema.graphql"])
+
+	if !strings.Contains(sdl, "enum Relation {\n  CAN_REVIEW\n}") {
+		t.Errorf("Relation enum should hold only filtered relations:\n%s", sdl)
+	}
+	if !strings.Contains(sdl, "checkPullRequestCanReview") {
+		t.Error("filtered relation should have a check field")
+	}
+	for _, excluded := range []string{"checkDocumentViewer", "listPullRequestAuthorObjects"} {
+		if strings.Contains(sdl, excluded) {
+			t.Errorf("filtered-out relation produced %s", excluded)
+		}
+	}
+	// Types stay in the enum even when none of their relations pass.
+	if !strings.Contains(sdl, "  DOCUMENT\n") {
+		t.Error("ObjectType enum should keep every type")
+	}
+
+	if _, err := gen.Generate(testTypes(), &clientgen.Config{RelationFilter: "nothing_"}); err == nil {
+		t.Error("a filter matching no relations should fail, as GraphQL rejects an empty Query type")
+	}
+}
+
+func TestGenerator_InvalidNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		types []schema.TypeDefinition
+	}{
+		{
+			name: "hyphenated type",
+			types: []schema.TypeDefinition{{
+				Name:      "team-space",
+				Relations: []schema.RelationDefinition{{Name: "member"}},
+			}},
+		},
+		{
+			name: "colliding field names",
+			types: []schema.TypeDefinition{
+				{Name: "doc_a", Relations: []schema.RelationDefinition{{Name: "b"}}},
+				{Name: "doc", Relations: []schema.RelationDefinition{{Name: "a_b"}}},
+			},
+		},
+		{
+			name: "reserved enum value",
+			types: []schema.TypeDefinition{{
+				Name:      "document",
+				Relations: []schema.RelationDefinition{{Name: "null"}},
+			}},
+		},
+	}
+
+	gen := &graphql.Generator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := gen.Generate(tt.types, nil); err == nil {
+				t.Error("Generate should reject names GraphQL cannot express")
+			}
+		})
+	}
+}
@@ -0,0 +1,495 @@
+// Package rust implements the Rust client code generator for melange.
+//
+// This generator produces a Rust module from authorization schemas: object
+// type and relation enums, object constructors, and a check helper per
+// relation that calls check_permission through sqlx.
+//
+// Generated code depends on the sqlx crate with the postgres feature.
+package rust
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pthm/melange/lib/clientgen"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+func init() {
+	clientgen.Register(&Generator{})
+}
+
+// Generator implements clientgen.Generator for Rust.
+type Generator struct{}
+
+// Name returns "rust" as the runtime identifier.
+func (g *Generator) Name() string { return "rust" }
+
+// DefaultConfig returns default configuration for Rust code generation.
+func (g *Generator) DefaultConfig() *clientgen.Config {
+	return &clientgen.Config{
+		Package:        "authz",
+		RelationFilter: "",
+		IDType:         "string",
+		Options:        make(map[string]any),
+	}
+}
+
+// Generate produces Rust client code from the given type definitions.
+//
+// Returns a single file map entry with key "mod.rs", to be placed in the
+// directory of the module named by cfg.Package (src/authz/mod.rs for the
+// default "authz"). cfg.SplitByType is ignored.
+//
+// Generated code includes:
+//   - ObjectType and Relation enums with as_str()
+//   - An Object struct with constructors (user(id), repository(id), etc.)
+//   - Wildcard constructors (any_user(), any_repository(), etc.)
+//   - A check helper per relation (check_repository_can_read(executor,
+//     subject, object)) that binds every argument as a query parameter
+//   - batch_check, which runs many checks in one query
+//
+// cfg.IDType is the Rust type constructors take. "string" (the default)
+// accepts anything convertible into a String; any other type, such as "i64"
+// or "uuid::Uuid", must implement ToString.
+//
+// Returns an error when a type, relation or cfg.Package cannot be spelled as
+// a Rust identifier.
+func (g *Generator) Generate(types []schema.TypeDefinition, cfg *clientgen.Config) (map[string][]byte, error) {
+	// Validate schema before generating code
+	if err := schema.DetectCycles(types); err != nil {
+		return nil, err
+	}
+
+	if cfg == nil {
+		cfg = g.DefaultConfig()
+	}
+
+	// Apply defaults for empty fields
+	idType := cfg.IDType
+	if idType == "" {
+		idType = "string"
+	}
+	pkg := cfg.Package
+	if pkg == "" {
+		pkg = "authz"
+	}
+	if !rustIdent.MatchString(pkg) || rustKeywords[pkg] {
+		return nil, fmt.Errorf("rust: package %q is not a valid module name", pkg)
+	}
+
+	sorted := make([]schema.TypeDefinition, len(types))
+	copy(sorted, types)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	// Collect object types, relations (with optional prefix filter), and the
+	// relations each type gets a check helper for
+	objectTypes := make([]string, 0, len(sorted))
+	relSet := make(map[string]bool)
+	var checks []checkHelper
+	for _, t := range sorted {
+		objectTypes = append(objectTypes, t.Name)
+
+		var relations []string
+		for _, r := range t.Relations {
+			if cfg.RelationFilter == "" || strings.HasPrefix(r.Name, cfg.RelationFilter) {
+				relations = append(relations, r.Name)
+				relSet[r.Name] = true
+			}
+		}
+		sort.Strings(relations)
+		for _, r := range relations {
+			checks = append(checks, checkHelper{objectType: t.Name, relation: r})
+		}
+	}
+
+	relations := make([]string, 0, len(relSet))
+	for r := range relSet {
+		relations = append(relations, r)
+	}
+	sort.Strings(relations)
+
+	if err := validateNames(objectTypes, relations, checks); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	ew := &errWriter{w: &buf}
+
+	writeHeader(ew, cfg, pkg)
+	writeEnum(ew, "ObjectType", "An object type from the authorization schema.", objectTypes)
+	writeEnum(ew, "Relation", "A relation from the authorization schema.", relations)
+	writeObject(ew)
+
+	ew.writeln("// Object constructors.")
+	ew.writeln("")
+	for _, t := range objectTypes {
+		writeConstructor(ew, t, idType)
+	}
+
+	ew.writeln("// Wildcard constructors for public access patterns.")
+	ew.writeln("")
+	for _, t := range objectTypes {
+		writeWildcardConstructor(ew, t)
+	}
+
+	if len(checks) > 0 {
+		writeCheckPermission(ew, cfg)
+		ew.writeln("// Relation checks.")
+		ew.writeln("")
+		for _, c := range checks {
+			writeCheckHelper(ew, c)
+		}
+		writeBatchCheck(ew, cfg)
+	}
+
+	if ew.err != nil {
+		return nil, ew.err
+	}
+
+	return map[string][]byte{
+		"mod.rs": buf.Bytes(),
+	}, nil
+}
+
+// checkHelper is one relation that gets a check_<type>_<relation> helper.
+type checkHelper struct {
+	objectType string
+	relation   string
+}
+
+// name returns the helper's function name, e.g. check_document_viewer.
+func (c checkHelper) name() string {
+	return "check_" + c.objectType + "_" + c.relation
+}
+
+// rustIdent matches names usable as Rust identifiers once keywords are
+// escaped with r#.
+var rustIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// unrawable are the keywords that cannot be raw identifiers either.
+var unrawable = map[string]bool{"self": true, "Self": true, "super": true, "crate": true}
+
+// rustKeywords are the strict and reserved keywords of the 2021 edition. A
+// type named after one gets a raw identifier (r#match) as its constructor.
+var rustKeywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true,
+	"continue": true, "crate": true, "dyn": true, "else": true, "enum": true,
+	"extern": true, "false": true, "fn": true, "for": true, "if": true,
+	"impl": true, "in": true, "let": true, "loop": true, "match": true,
+	"mod": true, "move": true, "mut": true, "pub": true, "ref": true,
+	"return": true, "self": true, "Self": true, "static": true, "struct": true,
+	"super": true, "trait": true, "true": true, "type": true, "unsafe": true,
+	"use": true, "where": true, "while": true, "abstract": true, "become": true,
+	"box": true, "do": true, "final": true, "macro": true, "override": true,
+	"priv": true, "try": true, "typeof": true, "unsized": true, "virtual": true,
+	"yield": true,
+}
+
+// validateNames rejects schemas whose names cannot be spelled in Rust or
+// would collide once converted: OpenFGA allows names such as "team-member",
+// and "doc_a"/"b" and "doc"/"a_b" both give check_doc_a_b.
+func validateNames(objectTypes, relations []string, checks []checkHelper) error {
+	for i, names := range [][]string{objectTypes, relations} {
+		variants := make(map[string]string, len(names))
+		for _, n := range names {
+			v := pascalCase(n)
+			// Types also name their constructor function.
+			if !rustIdent.MatchString(n) || v == "Self" || (i == 0 && unrawable[n]) {
+				return fmt.Errorf("rust: %q is not a valid Rust identifier", n)
+			}
+			if prev, ok := variants[v]; ok {
+				return fmt.Errorf("rust: %q and %q both map to variant %s", prev, n, v)
+			}
+			variants[v] = n
+		}
+	}
+
+	fns := map[string]string{
+		"check_permission": "the check_permission helper",
+		"batch_check":      "the batch_check helper",
+	}
+	add := func(name, from string) error {
+		if prev, ok := fns[name]; ok {
+			return fmt.Errorf("rust: %s and %s both map to function %s", prev, from, name)
+		}
+		fns[name] = from
+		return nil
+	}
+	for _, t := range objectTypes {
+		if err := add(t, "type "+t); err != nil {
+			return err
+		}
+		if err := add("any_"+t, "type "+t); err != nil {
+			return err
+		}
+	}
+	for _, c := range checks {
+		if err := add(c.name(), c.objectType+"#"+c.relation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader writes the generated-code header, module docs and imports.
+func writeHeader(ew *errWriter, cfg *clientgen.Config, pkg string) {
+	ew.writeln("// Code generated by melange. DO NOT EDIT.")
+	ew.writeln("//")
+	if cfg.Version != "" {
+		ew.writef("// melange version: %s\n", cfg.Version)
+	}
+	if cfg.SourcePath != "" {
+		ew.writef("// source: %s\n", cfg.SourcePath)
+	}
+	ew.writeln("")
+	ew.writef("//! Authorization types for the `%s` module, generated from the schema.\n", pkg)
+	ew.writeln("//!")
+	ew.writef("//! Place this file at `src/%s/mod.rs` and declare `mod %s;`.\n", pkg, pkg)
+	if cfg.SchemaName != "" {
+		ew.writef("//! Check helpers call `check_permission` in the `%s` schema.\n", cfg.SchemaName)
+	} else {
+		ew.writeln("//! Check helpers call `check_permission`, which must be on the connection's")
+		ew.writeln("//! search_path.")
+	}
+	ew.writeln("")
+	ew.writeln("#![allow(dead_code)]")
+	ew.writeln("")
+}
+
+// writeEnum writes a string-backed enum with one variant per name.
+func writeEnum(ew *errWriter, name, doc string, names []string) {
+	ew.writef("/// %s\n", doc)
+	ew.writeln("#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]")
+	ew.writef("pub enum %s {\n", name)
+	for _, n := range names {
+		ew.writef("    %s,\n", pascalCase(n))
+	}
+	ew.writeln("}")
+	ew.writeln("")
+	ew.writef("impl %s {\n", name)
+	ew.writeln("    /// Returns the name used in the schema and in melange_tuples.")
+	ew.writeln("    pub const fn as_str(&self) -> &'static str {")
+	if len(names) == 0 {
+		ew.writeln("        match *self {}")
+	} else {
+		ew.writeln("        match self {")
+		for _, n := range names {
+			ew.writef("            %s::%s => %q,\n", name, pascalCase(n), n)
+		}
+		ew.writeln("        }")
+	}
+	ew.writeln("    }")
+	ew.writeln("}")
+	ew.writeln("")
+}
+
+// writeObject writes the Object struct that constructors return.
+func writeObject(ew *errWriter) {
+	ew.writeln("/// An object or subject: a type and an id. The id \"*\" is a wildcard.")
+	ew.writeln("#[derive(Debug, Clone, PartialEq, Eq, Hash)]")
+	ew.writeln("pub struct Object {")
+	ew.writeln("    pub object_type: ObjectType,")
+	ew.writeln("    pub id: String,")
+	ew.writeln("}")
+	ew.writeln("")
+}
+
+// writeConstructor writes the object constructor for one type.
+func writeConstructor(ew *errWriter, objectType, idType string) {
+	ew.writef("/// Creates a %s object for relation checks.\n", objectType)
+	if idType == "string" || idType == "String" {
+		ew.writef("pub fn %s(id: impl Into<String>) -> Object {\n", fnName(objectType))
+		ew.writef("    Object { object_type: ObjectType::%s, id: id.into() }\n", pascalCase(objectType))
+	} else {
+		ew.writef("pub fn %s(id: %s) -> Object {\n", fnName(objectType), idType)
+		ew.writef("    Object { object_type: ObjectType::%s, id: id.to_string() }\n", pascalCase(objectType))
+	}
+	ew.writeln("}")
+	ew.writeln("")
+}
+
+// writeWildcardConstructor writes the wildcard constructor for one type.
+func writeWildcardConstructor(ew *errWriter, objectType string) {
+	ew.writef("/// Returns a wildcard %s that matches type:* tuples.\n", objectType)
+	ew.writef("pub fn any_%s() -> Object {\n", objectType)
+	ew.writef("    Object { object_type: ObjectType::%s, id: \"*\".to_string() }\n", pascalCase(objectType))
+	ew.writeln("}")
+	ew.writeln("")
+}
+
+// writeCheckPermission writes the private helper every check helper calls.
+// All five arguments are bound parameters, so no schema or caller value is
+// ever spliced into the SQL text.
+func writeCheckPermission(ew *errWriter, cfg *clientgen.Config) {
+	ew.writeln("async fn check_permission<'e, E>(")
+	ew.writeln("    executor: E,")
+	ew.writeln("    subject: &Object,")
+	ew.writeln("    relation: Relation,")
+	ew.writeln("    object_type: ObjectType,")
+	ew.writeln("    object: &Object,")
+	ew.writeln(") -> Result<bool, sqlx::Error>")
+	ew.writeln("where")
+	ew.writeln("    E: sqlx::PgExecutor<'e>,")
+	ew.writeln("{")
+	ew.writeln("    debug_assert_eq!(object.object_type, object_type, \"object has the wrong type\");")
+	ew.writef("    sqlx::query_scalar(\"SELECT %s($1, $2, $3, $4, $5) = 1\")\n", rustStringContent(cfg.CheckPermissionSQL()))
+	ew.writeln("        .bind(subject.object_type.as_str())")
+	ew.writeln("        .bind(&subject.id)")
+	ew.writeln("        .bind(relation.as_str())")
+	ew.writeln("        .bind(object_type.as_str())")
+	ew.writeln("        .bind(&object.id)")
+	ew.writeln("        .fetch_one(executor)")
+	ew.writeln("        .await")
+	ew.writeln("}")
+	ew.writeln("")
+}
+
+// writeCheckHelper writes check_<type>_<relation> for one relation.
+func writeCheckHelper(ew *errWriter, c checkHelper) {
+	ew.writef("/// Reports whether subject has %s on the %s object.\n", c.relation, c.objectType)
+	ew.writef("pub async fn %s<'e, E>(executor: E, subject: &Object, object: &Object) -> Result<bool, sqlx::Error>\n", c.name())
+	ew.writeln("where")
+	ew.writeln("    E: sqlx::PgExecutor<'e>,")
+	ew.writeln("{")
+	ew.writef("    check_permission(executor, subject, Relation::%s, ObjectType::%s, object).await\n", pascalCase(c.relation), pascalCase(c.objectType))
+	ew.writeln("}")
+	ew.writeln("")
+}
+
+// batchCheckSource declares batch_check. unnest expands the five request
+// arrays into rows, WITH ORDINALITY numbers them so results can be put back
+// in request order, and the lateral subquery calls check_permission once per
+// row. The arrays are bound as text[] parameters.
+const batchCheckSource = `/// One permission check passed to [` + "`batch_check`" + `].
+#[derive(Debug, Clone, PartialEq, Eq, Hash)]
+pub struct BatchCheckRequest {
+    pub subject: Object,
+    pub relation: Relation,
+    pub object: Object,
+}
+
+/// The outcome of one [` + "`BatchCheckRequest`" + `]. ` + "`error`" + ` holds the error for that
+/// check alone, in which case ` + "`allowed`" + ` is false.
+#[derive(Debug)]
+pub struct BatchCheckResult {
+    pub allowed: bool,
+    pub error: Option<sqlx::Error>,
+}
+
+/// Runs every request in a single query and returns one result per request,
+/// in the same order. An empty ` + "`requests`" + ` returns an empty ` + "`Vec`" + ` without
+/// querying.
+///
+/// If the query fails, each request is checked on its own so that an error,
+/// such as a resolution depth limit, lands on the request that caused it
+/// rather than failing the batch. That reuses the executor, hence the ` + "`Copy`" + `
+/// bound: pass a ` + "`&PgPool`" + `. In a transaction the first error would abort
+/// every later check anyway.
+pub async fn batch_check<'e, E>(executor: E, requests: &[BatchCheckRequest]) -> Vec<BatchCheckResult>
+where
+    E: sqlx::PgExecutor<'e> + Copy,
+{
+    if requests.is_empty() {
+        return Vec::new();
+    }
+
+    let rows: Result<Vec<(i32, i32)>, sqlx::Error> = sqlx::query_as(
+        "SELECT t.idx::integer, c.allowed \
+         FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[]) \
+         WITH ORDINALITY AS t(subject_type, subject_id, relation, object_type, object_id, idx) \
+         CROSS JOIN LATERAL (SELECT check_permission(t.subject_type, t.subject_id, t.relation, t.object_type, t.object_id) AS allowed) c",
+    )
+    .bind(requests.iter().map(|r| r.subject.object_type.as_str()).collect::<Vec<_>>())
+    .bind(requests.iter().map(|r| r.subject.id.as_str()).collect::<Vec<_>>())
+    .bind(requests.iter().map(|r| r.relation.as_str()).collect::<Vec<_>>())
+    .bind(requests.iter().map(|r| r.object.object_type.as_str()).collect::<Vec<_>>())
+    .bind(requests.iter().map(|r| r.object.id.as_str()).collect::<Vec<_>>())
+    .fetch_all(executor)
+    .await;
+
+    if let Ok(rows) = rows {
+        let mut results: Vec<BatchCheckResult> = requests
+            .iter()
+            .map(|_| BatchCheckResult { allowed: false, error: None })
+            .collect();
+        for (idx, allowed) in rows {
+            // idx comes from WITH ORDINALITY, so it is 1-based.
+            let slot = usize::try_from(idx).ok().and_then(|i| i.checked_sub(1));
+            if let Some(result) = slot.and_then(|i| results.get_mut(i)) {
+                result.allowed = allowed == 1;
+            }
+        }
+        return results;
+    }
+
+    let mut results = Vec::with_capacity(requests.len());
+    for r in requests {
+        results.push(
+            match check_permission(executor, &r.subject, r.relation, r.object.object_type, &r.object).await {
+                Ok(allowed) => BatchCheckResult { allowed, error: None },
+                Err(error) => BatchCheckResult { allowed: false, error: Some(error) },
+            },
+        );
+    }
+    results
+}
+`
+
+// writeBatchCheck writes batch_check and its request and result types.
+func writeBatchCheck(ew *errWriter, cfg *clientgen.Config) {
+	ew.writeln("// Batch checks.")
+	ew.writeln("")
+	fn := rustStringContent(cfg.CheckPermissionSQL())
+	ew.writeln(strings.ReplaceAll(batchCheckSource, "SELECT check_permission(", "SELECT "+fn+"("))
+}
+
+// rustStringContent escapes s for use inside a Rust string literal.
+func rustStringContent(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// errWriter wraps a bytes.Buffer and captures the first error.
+type errWriter struct {
+	w   *bytes.Buffer
+	err error
+}
+
+func (ew *errWriter) writeln(s string) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintln(ew.w, s)
+}
+
+func (ew *errWriter) writef(format string, args ...any) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
+
+// fnName returns the constructor name for an object type: the type name,
+// escaped as a raw identifier when it is a keyword.
+// Examples: "user" -> "user", "match" -> "r#match"
+func fnName(objectType string) string {
+	if rustKeywords[objectType] {
+		return "r#" + objectType
+	}
+	return objectType
+}
+
+// pascalCase converts snake_case to PascalCase.
+// Examples: "user" -> "User", "pull_request" -> "PullRequest"
+func pascalCase(s string) string {
+	parts := strings.Split(s, "_")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
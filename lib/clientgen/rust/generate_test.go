@@ -0,0 +1,204 @@
+package rust_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pthm/melange/lib/clientgen"
+	"github.com/pthm/melange/lib/clientgen/rust"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+func TestGenerator_Interface(t *testing.T) {
+	gen := &rust.Generator{}
+
+	t.Run("name returns rust", func(t *testing.T) {
+		if got := gen.Name(); got != "rust" {
+			t.Errorf("Name() = %q, want %q", got, "rust")
+		}
+		if !clientgen.Registered("rust") {
+			t.Error("rust generator should register itself")
+		}
+	})
+
+	t.Run("default config has sensible values", func(t *testing.T) {
+		cfg := gen.DefaultConfig()
+		if cfg.Package != "authz" {
+			t.Errorf("Package = %q, want %q", cfg.Package, "authz")
+		}
+		if cfg.IDType != "string" {
+			t.Errorf("IDType = %q, want %q", cfg.IDType, "string")
+		}
+	})
+}
+
+func testTypes() []schema.TypeDefinition {
+	return []schema.TypeDefinition{
+		{Name: "user"},
+		{
+			Name: "pull_request",
+			Relations: []schema.RelationDefinition{
+				{Name: "author", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}},
+				{Name: "can_review", ImpliedBy: []string{"author"}},
+			},
+		},
+	}
+}
+
+func generate(t *testing.T, types []schema.TypeDefinition, cfg *clientgen.Config) string {
+	t.Helper()
+	files, err := (&rust.Generator{}).Generate(types, cfg)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Generate returned %d files, want 1", len(files))
+	}
+	code, ok := files["mod.rs"]
+	if !ok {
+		t.Fatal("Generate should return mod.rs")
+	}
+	return string(code)
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	code := generate(t, testTypes(), &clientgen.Config{Package: "perms", Version: "v1.2.3", SourcePath: "schema.fga"})
+
+	for _, want := range []string{
+		"// Code generated by melange. DO NOT EDIT.",
+		"// melange version: v1.2.3",
+		"// source: schema.fga",
+		"//! Place this file at `src/perms/mod.rs` and declare `mod perms;`.",
+		"pub enum ObjectType {\n    PullRequest,\n    User,\n}",
+		`ObjectType::PullRequest => "pull_request",`,
+		"pub enum Relation {\n    Author,\n    CanReview,\n}",
+		`Relation::CanReview => "can_review",`,
+		"pub fn pull_request(id: impl Into<String>) -> Object {",
+		"pub fn any_user() -> Object {",
+		"pub async fn check_pull_request_can_review<'e, E>(executor: E, subject: &Object, object: &Object) -> Result<bool, sqlx::Error>",
+		"check_permission(executor, subject, Relation::CanReview, ObjectType::PullRequest, object).await",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("mod.rs missing %q\n%s", want, code)
+		}
+	}
+
+	// Every value reaches Postgres as a bound parameter.
+	if !strings.Contains(code, `sqlx::query_scalar("SELECT check_permission($1, $2, $3, $4, $5) = 1")`) {
+		t.Error("check_permission should be called with placeholders only")
+	}
+	helper := code[strings.Index(code, "async fn check_permission<"):]
+	helper = helper[:strings.Index(helper, "\n}\n")]
+	if strings.Count(helper, ".bind(") != 5 {
+		t.Errorf("want 5 bound parameters, got %d", strings.Count(helper, ".bind("))
+	}
+	if strings.Contains(code, "format!") {
+		t.Error("generated code must not build SQL with format!")
+	}
+}
+
+func TestGenerator_RelationFilter(t *testing.T) {
+	code := generate(t, testTypes(), &clientgen.Config{RelationFilter: "can_"})
+
+	if !strings.Contains(code, "check_pull_request_can_review") {
+		t.Error("filtered relation should have a check helper")
+	}
+	if strings.Contains(code, "check_pull_request_author") || strings.Contains(code, "Relation::Author") {
+		t.Error("relations outside the filter should be omitted")
+	}
+
+	// No relation passes: the module still builds, with no check helpers.
+	code = generate(t, testTypes(), &clientgen.Config{RelationFilter: "nothing_"})
+	if strings.Contains(code, "async fn") {
+		t.Error("no check helpers should be generated when the filter matches nothing")
+	}
+	if !strings.Contains(code, "match *self {}") {
+		t.Error("an empty Relation enum needs an empty match")
+	}
+}
+
+func TestGenerator_BatchCheck(t *testing.T) {
+	code := generate(t, testTypes(), nil)
+
+	for _, want := range []string{
+		"pub struct BatchCheckRequest {\n    pub subject: Object,\n    pub relation: Relation,\n    pub object: Object,\n}",
+		"pub error: Option<sqlx::Error>,",
+		"pub async fn batch_check<'e, E>(executor: E, requests: &[BatchCheckRequest]) -> Vec<BatchCheckResult>",
+		"E: sqlx::PgExecutor<'e> + Copy,",
+		"if requests.is_empty() {\n        return Vec::new();",
+		"FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[])",
+		"CROSS JOIN LATERAL (SELECT check_permission(",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("mod.rs missing %q", want)
+		}
+	}
+
+	code = generate(t, testTypes(), &clientgen.Config{SchemaName: "authz"})
+	for _, want := range []string{
+		`sqlx::query_scalar("SELECT \"authz\".\"check_permission\"($1, $2, $3, $4, $5) = 1")`,
+		`CROSS JOIN LATERAL (SELECT \"authz\".\"check_permission\"(`,
+		"//! Check helpers call `check_permission` in the `authz` schema.",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("mod.rs with SchemaName missing %q", want)
+		}
+	}
+
+	// Without relations no request can be built, so there is no batch_check.
+	code = generate(t, testTypes(), &clientgen.Config{RelationFilter: "nothing_"})
+	if strings.Contains(code, "batch_check") {
+		t.Error("batch_check should be omitted when no relation passes the filter")
+	}
+
+	if _, err := (&rust.Generator{}).Generate([]schema.TypeDefinition{{Name: "batch_check"}}, nil); err == nil {
+		t.Error("Generate should reject a type whose constructor would be named batch_check")
+	}
+}
+
+func TestGenerator_IDType(t *testing.T) {
+	code := generate(t, testTypes(), &clientgen.Config{IDType: "i64"})
+
+	if !strings.Contains(code, "pub fn user(id: i64) -> Object {\n    Object { object_type: ObjectType::User, id: id.to_string() }") {
+		t.Errorf("constructors should take the configured IDType\n%s", code)
+	}
+	if !strings.Contains(code, `id: "*".to_string()`) {
+		t.Error("wildcard constructors keep a string id")
+	}
+}
+
+func TestGenerator_Keywords(t *testing.T) {
+	code := generate(t, []schema.TypeDefinition{{Name: "match"}}, nil)
+	if !strings.Contains(code, "pub fn r#match(id: impl Into<String>) -> Object {") {
+		t.Error("a keyword type name should become a raw identifier")
+	}
+	if !strings.Contains(code, "pub fn any_match() -> Object {") {
+		t.Error("wildcard constructor needs no escaping")
+	}
+}
+
+func TestGenerator_InvalidNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		types []schema.TypeDefinition
+		cfg   *clientgen.Config
+	}{
+		{name: "hyphenated type", types: []schema.TypeDefinition{{Name: "team-space"}}},
+		{name: "unrawable keyword", types: []schema.TypeDefinition{{Name: "crate"}}},
+		{name: "self relation", types: []schema.TypeDefinition{{Name: "doc", Relations: []schema.RelationDefinition{{Name: "self"}}}}},
+		{name: "colliding helpers", types: []schema.TypeDefinition{
+			{Name: "doc_a", Relations: []schema.RelationDefinition{{Name: "b"}}},
+			{Name: "doc", Relations: []schema.RelationDefinition{{Name: "a_b"}}},
+		}},
+		{name: "colliding constructors", types: []schema.TypeDefinition{{Name: "user"}, {Name: "any_user"}}},
+		{name: "invalid package", types: testTypes(), cfg: &clientgen.Config{Package: "my-authz"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (&rust.Generator{}).Generate(tt.types, tt.cfg); err == nil {
+				t.Error("Generate should reject names Rust cannot express")
+			}
+		})
+	}
+}
@@ -1,7 +1,8 @@
 // Package typescript implements the TypeScript client code generator for melange.
 //
 // This generator produces type-safe TypeScript code from authorization schemas,
-// including object type constants, relation constants, and factory functions.
+// including object type constants, relation constants, factory functions, and
+// list functions returning typed object references.
 //
 // Generated code uses the @pthm/melange runtime package for type definitions.
 package typescript
@@ -38,12 +39,22 @@ func (g *Generator) DefaultConfig() *clientgen.Config {
 
 // Generate produces TypeScript client code from the given type definitions.
 //
-// Returns a multi-file map with keys: "types.ts", "schema.ts", "index.ts".
+// Returns a multi-file map with keys: "types.ts", "schema.ts", "batch.ts",
+// "list.ts", "index.ts". With cfg.SplitByType, schema.ts is replaced by one
+// file per object type (see typeFileName).
 //
 // Generated code includes:
-//   - types.ts: ObjectType/Relation constants and union types
-//   - schema.ts: Factory functions and wildcard constructors
+//   - types.ts: ObjectType/Relation constants and union types, and a ref
+//     type per object type (DocumentRef) with their union ObjectRef
+//   - schema.ts: Factory functions, wildcard constructors, and one list
+//     function per relation (listDocumentViewerObjects)
+//   - batch.ts: batchCheck, which runs many checks in one query
+//   - list.ts: listObjectRefs, which the list functions call
 //   - index.ts: Re-exports for clean imports
+//
+// Relations outside cfg.RelationFilter get neither a constant nor a list
+// function. Returns an error when two exported names would be the same (see
+// checkExportedNames).
 func (g *Generator) Generate(types []schema.TypeDefinition, cfg *clientgen.Config) (map[string][]byte, error) {
 	// Validate schema before generating code
 	if err := schema.DetectCycles(types); err != nil {
@@ -61,15 +72,21 @@ func (g *Generator) Generate(types []schema.TypeDefinition, cfg *clientgen.Confi
 	}
 	sort.Strings(objectTypes)
 
-	// Collect unique relations (with optional prefix filter)
+	// Collect unique relations (with optional prefix filter), and each
+	// type's own for its list functions
 	relSet := make(map[string]bool)
+	typeRelations := make(map[string][]string, len(types))
 	for _, t := range types {
 		for _, r := range t.Relations {
 			if cfg.RelationFilter == "" || strings.HasPrefix(r.Name, cfg.RelationFilter) {
 				relSet[r.Name] = true
+				typeRelations[t.Name] = append(typeRelations[t.Name], r.Name)
 			}
 		}
 	}
+	if err := checkExportedNames(objectTypes, typeRelations); err != nil {
+		return nil, err
+	}
 
 	relations := make([]string, 0, len(relSet))
 	for r := range relSet {
@@ -86,13 +103,41 @@ func (g *Generator) Generate(types []schema.TypeDefinition, cfg *clientgen.Confi
 	}
 	files["types.ts"] = typesContent
 
-	schemaContent, err := g.generateSchema(objectTypes, cfg)
+	var modules []string
+	if cfg.SplitByType {
+		for _, t := range objectTypes {
+			content, err := g.generateSchema([]string{t}, typeRelations, cfg)
+			if err != nil {
+				return nil, err
+			}
+			name := typeFileName(t)
+			files[name] = content
+			modules = append(modules, strings.TrimSuffix(name, ".ts"))
+		}
+	} else {
+		schemaContent, err := g.generateSchema(objectTypes, typeRelations, cfg)
+		if err != nil {
+			return nil, err
+		}
+		files["schema.ts"] = schemaContent
+		modules = []string{"schema"}
+	}
+
+	batchContent, err := g.generateBatch(cfg)
+	if err != nil {
+		return nil, err
+	}
+	files["batch.ts"] = batchContent
+	modules = append(modules, "batch")
+
+	listContent, err := g.generateList(cfg)
 	if err != nil {
 		return nil, err
 	}
-	files["schema.ts"] = schemaContent
+	files["list.ts"] = listContent
+	modules = append(modules, "list")
 
-	indexContent, err := g.generateIndex(cfg)
+	indexContent, err := g.generateIndex(objectTypes, modules, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -159,6 +204,27 @@ func (g *Generator) generateTypes(objectTypes, relations []string, cfg *clientge
 	ew.writeln("export type Relation = (typeof Relations)[keyof typeof Relations];")
 	ew.writeln("")
 
+	// Write a ref type per object type and their union
+	refs := make([]string, len(objectTypes))
+	for i, t := range objectTypes {
+		refs[i] = refTypeName(t)
+		ew.writeln("/**")
+		ew.writef(" * %s is a %s object returned by a list operation.\n", refs[i], t)
+		ew.writeln(" */")
+		ew.writef("export type %s = { readonly type: typeof ObjectTypes.%s; readonly id: string };\n", refs[i], pascalCase(t))
+		ew.writeln("")
+	}
+	ew.writeln("/**")
+	ew.writeln(" * ObjectRef is a union of the ref types of all object types, discriminated")
+	ew.writeln(" * by type.")
+	ew.writeln(" */")
+	if len(refs) == 0 {
+		ew.writeln("export type ObjectRef = never;")
+	} else {
+		ew.writef("export type ObjectRef = %s;\n", strings.Join(refs, " | "))
+	}
+	ew.writeln("")
+
 	if ew.err != nil {
 		return nil, ew.err
 	}
@@ -166,18 +232,47 @@ func (g *Generator) generateTypes(objectTypes, relations []string, cfg *clientge
 	return buf.Bytes(), nil
 }
 
-// generateSchema creates the schema.ts file with factory functions.
-func (g *Generator) generateSchema(objectTypes []string, _ *clientgen.Config) ([]byte, error) {
+// typeFileName returns the SplitByType file for an object type: the type name
+// with a .ts extension, or "type_<name>.ts" when that would replace types.ts,
+// batch.ts, list.ts or index.ts.
+func typeFileName(objectType string) string {
+	switch objectType {
+	case "types", "batch", "list", "index":
+		return "type_" + objectType + ".ts"
+	}
+	return objectType + ".ts"
+}
+
+// generateSchema creates the factory and list functions for objectTypes:
+// schema.ts, or with SplitByType one type's file. typeRelations holds the
+// relations of each type that pass the RelationFilter.
+func (g *Generator) generateSchema(objectTypes []string, typeRelations map[string][]string, _ *clientgen.Config) ([]byte, error) {
 	var buf bytes.Buffer
 	ew := &errWriter{w: &buf}
 
+	// Only files with list functions import what they use
+	var refs []string
+	for _, t := range objectTypes {
+		if len(typeRelations[t]) > 0 {
+			refs = append(refs, refTypeName(t))
+		}
+	}
+
 	// Write header
 	ew.writeln("/**")
 	ew.writeln(" * Generated by melange. DO NOT EDIT.")
 	ew.writeln(" */")
 	ew.writeln("")
-	ew.writeln("import type { MelangeObject } from '@pthm/melange';")
-	ew.writeln("import { ObjectTypes } from './types.js';")
+	if len(refs) == 0 {
+		ew.writeln("import type { MelangeObject } from '@pthm/melange';")
+		ew.writeln("import { ObjectTypes } from './types.js';")
+	} else {
+		ew.writeln("import type { MelangeObject, Queryable } from '@pthm/melange';")
+		ew.writeln("import { ObjectTypes, Relations } from './types.js';")
+		ew.writef("import type { %s } from './types.js';\n", strings.Join(refs, ", "))
+		ew.writeln("import { listObjectRefs } from './list.js';")
+		ew.writeln("import type { Consistency } from './list.js';")
+	}
 	ew.writeln("")
 
 	// Write factory functions
@@ -202,8 +297,196 @@ func (g *Generator) generateSchema(objectTypes []string, _ *clientgen.Config) ([
 		ew.writef("  return { type: ObjectTypes.%s, id: '*' };\n", constName)
 		ew.writef("}\n")
 		ew.writeln("")
+
+		// Write list functions
+		for _, r := range typeRelations[t] {
+			listName := listFunctionName(t, r)
+			ew.writef("/**\n")
+			ew.writef(" * %s lists the %s objects subject has %s on.\n", listName, t, r)
+			ew.writef(" */\n")
+			ew.writef("export function %s(db: Queryable, subject: MelangeObject, consistency?: Consistency): Promise<%s[]> {\n", listName, refTypeName(t))
+			ew.writef("  return listObjectRefs<%s>(db, subject, Relations.%s, ObjectTypes.%s, consistency);\n", refTypeName(t), pascalCase(r), constName)
+			ew.writef("}\n")
+			ew.writeln("")
+		}
+	}
+
+	if ew.err != nil {
+		return nil, ew.err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// batchCheckSource is the body of batch.ts. unnest expands the five request
+// arrays into rows, WITH ORDINALITY numbers them so results can be put back
+// in request order, and the lateral subquery calls check_permission once per
+// row. The arrays are bound as parameters, so no request value is spliced
+// into the SQL text.
+const batchCheckSource = `import type { MelangeObject, Queryable } from '@pthm/melange';
+import { translateError } from '@pthm/melange';
+import type { Relation } from './types.js';
+
+/**
+ * BatchCheckRequest is one permission check passed to batchCheck.
+ */
+export interface BatchCheckRequest {
+  subject: MelangeObject;
+  relation: Relation;
+  object: MelangeObject;
+}
+
+/**
+ * BatchCheckResult is the outcome of one BatchCheckRequest. error holds the
+ * error for that check alone, in which case allowed is false.
+ */
+export interface BatchCheckResult {
+  allowed: boolean;
+  error?: Error;
+}
+
+const BATCH_CHECK_QUERY =
+  'SELECT t.idx::integer AS idx, c.allowed ' +
+  'FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[]) ' +
+  'WITH ORDINALITY AS t(subject_type, subject_id, relation, object_type, object_id, idx) ' +
+  'CROSS JOIN LATERAL (SELECT check_permission(t.subject_type, t.subject_id, t.relation, t.object_type, t.object_id) AS allowed) c';
+
+/**
+ * batchCheck runs every request in a single query and resolves to one result
+ * per request, in the same order. An empty requests resolves to an empty
+ * array without querying. check_permission must be on the connection's
+ * search_path.
+ *
+ * If the query fails, each request is checked on its own so that an error,
+ * such as a resolution depth limit, lands on the request that caused it
+ * rather than rejecting the batch. Inside a transaction the first error
+ * aborts the transaction, so every later request reports an error too.
+ * Errors raised by check_permission are translated by translateError.
+ */
+export async function batchCheck(
+  db: Queryable,
+  requests: readonly BatchCheckRequest[],
+): Promise<BatchCheckResult[]> {
+  if (requests.length === 0) {
+    return [];
+  }
+
+  try {
+    const result = await db.query<{ idx: number; allowed: number }>(BATCH_CHECK_QUERY, [
+      requests.map((r) => r.subject.type),
+      requests.map((r) => r.subject.id),
+      requests.map((r) => r.relation),
+      requests.map((r) => r.object.type),
+      requests.map((r) => r.object.id),
+    ]);
+    const results: BatchCheckResult[] = requests.map(() => ({ allowed: false }));
+    for (const row of result.rows) {
+      // idx comes from WITH ORDINALITY, so it is 1-based.
+      const r = results[Number(row.idx) - 1];
+      if (r) {
+        r.allowed = row.allowed === 1;
+      }
+    }
+    return results;
+  } catch {
+    const results: BatchCheckResult[] = [];
+    for (const r of requests) {
+      try {
+        const result = await db.query<{ allowed: number }>(
+          'SELECT check_permission($1, $2, $3, $4, $5) AS allowed',
+          [r.subject.type, r.subject.id, r.relation, r.object.type, r.object.id],
+        );
+        results.push({ allowed: result.rows[0]?.allowed === 1 });
+      } catch (err) {
+        const error = translateError(err);
+        results.push({ allowed: false, error: error instanceof Error ? error : new Error(String(error)) });
+      }
+    }
+    return results;
+  }
+}
+`
+
+// generateBatch creates the batch.ts file with batchCheck and its request
+// and result types.
+func (g *Generator) generateBatch(cfg *clientgen.Config) ([]byte, error) {
+	var buf bytes.Buffer
+	ew := &errWriter{w: &buf}
+
+	// Write header
+	ew.writeln("/**")
+	ew.writeln(" * Generated by melange. DO NOT EDIT.")
+	ew.writeln(" */")
+	ew.writeln("")
+	// check_permission is spliced into single-quoted string literals.
+	fn := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(cfg.CheckPermissionSQL())
+	ew.writeln(strings.ReplaceAll(batchCheckSource, "SELECT check_permission(", "SELECT "+fn+"("))
+
+	if ew.err != nil {
+		return nil, ew.err
 	}
 
+	return buf.Bytes(), nil
+}
+
+// listObjectRefsSource is the body of list.ts. The ref type is a type
+// parameter rather than derived from objectType, which TypeScript cannot
+// narrow ObjectRef on, so the list functions name it explicitly.
+const listObjectRefsSource = `import type { MelangeObject, Queryable } from '@pthm/melange';
+import { translateError } from '@pthm/melange';
+import type { ObjectRef, Relation } from './types.js';
+
+const LIST_OBJECTS_QUERY = 'SELECT object_id FROM list_accessible_objects($1, $2, $3, $4, p_consistency => $5)';
+
+/**
+ * Consistency is how fresh the tuples behind a read must be, as in OpenFGA:
+ * 'strong' reads must see every write made before them, 'eventual' reads
+ * may lag behind.
+ */
+export type Consistency = 'strong' | 'eventual';
+
+/**
+ * listObjectRefs resolves to every objectType object that subject has
+ * relation on, ordered by ID, each typed as R, the ref type of objectType.
+ * list_accessible_objects must be on the connection's search_path. Errors
+ * raised by list_accessible_objects, such as RelationNotListableError, are
+ * translated by translateError.
+ *
+ * consistency is passed to list_accessible_objects as p_consistency, which
+ * does not change the result: the database db is connected to decides how
+ * fresh it is. Pass a connection to the primary with 'strong', and one that
+ * may reach a replica only with 'eventual'.
+ */
+export async function listObjectRefs<R extends ObjectRef>(
+  db: Queryable,
+  subject: MelangeObject,
+  relation: Relation,
+  objectType: R['type'],
+  consistency?: Consistency,
+): Promise<R[]> {
+  const result = await db
+    .query<{ object_id: string }>(LIST_OBJECTS_QUERY, [subject.type, subject.id, relation, objectType, consistency ?? null])
+    .catch((err: unknown) => {
+      throw translateError(err);
+    });
+  return result.rows.map((row) => ({ type: objectType, id: row.object_id }) as R);
+}
+`
+
+// generateList creates the list.ts file with listObjectRefs.
+func (g *Generator) generateList(cfg *clientgen.Config) ([]byte, error) {
+	var buf bytes.Buffer
+	ew := &errWriter{w: &buf}
+
+	// Write header
+	ew.writeln("/**")
+	ew.writeln(" * Generated by melange. DO NOT EDIT.")
+	ew.writeln(" */")
+	ew.writeln("")
+	// list_accessible_objects is spliced into a single-quoted string literal.
+	fn := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(cfg.ListObjectsSQL())
+	ew.writeln(strings.ReplaceAll(listObjectRefsSource, "FROM list_accessible_objects(", "FROM "+fn+"("))
+
 	if ew.err != nil {
 		return nil, ew.err
 	}
@@ -211,8 +494,9 @@ func (g *Generator) generateSchema(objectTypes []string, _ *clientgen.Config) ([
 	return buf.Bytes(), nil
 }
 
-// generateIndex creates the index.ts file with re-exports.
-func (g *Generator) generateIndex(_ *clientgen.Config) ([]byte, error) {
+// generateIndex creates the index.ts file with re-exports of types.ts and
+// each module holding factory functions, batchCheck or listObjectRefs.
+func (g *Generator) generateIndex(objectTypes, modules []string, _ *clientgen.Config) ([]byte, error) {
 	var buf bytes.Buffer
 	ew := &errWriter{w: &buf}
 
@@ -223,7 +507,14 @@ func (g *Generator) generateIndex(_ *clientgen.Config) ([]byte, error) {
 	ew.writeln("")
 	ew.writeln("export { ObjectTypes, Relations } from './types.js';")
 	ew.writeln("export type { ObjectType, Relation } from './types.js';")
-	ew.writeln("export * from './schema.js';")
+	refs := []string{"ObjectRef"}
+	for _, t := range objectTypes {
+		refs = append(refs, refTypeName(t))
+	}
+	ew.writef("export type { %s } from './types.js';\n", strings.Join(refs, ", "))
+	for _, m := range modules {
+		ew.writef("export * from './%s.js';\n", m)
+	}
 	ew.writeln("")
 
 	if ew.err != nil {
@@ -233,6 +524,47 @@ func (g *Generator) generateIndex(_ *clientgen.Config) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// refTypeName returns the name of an object type's ref type: "document" ->
+// "DocumentRef".
+func refTypeName(objectType string) string {
+	return pascalCase(objectType) + "Ref"
+}
+
+// listFunctionName returns the name of a relation's list function:
+// "document", "viewer" -> "listDocumentViewerObjects".
+func listFunctionName(objectType, relation string) string {
+	return "list" + pascalCase(objectType) + pascalCase(relation) + "Objects"
+}
+
+// checkExportedNames returns an error when two names index.ts re-exports
+// would be the same: factories, wildcard constructors, ref types and list
+// functions derived from the schema, and the fixed batchCheck,
+// listObjectRefs and ObjectRef.
+func checkExportedNames(objectTypes []string, typeRelations map[string][]string) error {
+	owners := map[string]string{
+		"batchCheck":     "the generated batchCheck",
+		"listObjectRefs": "the generated listObjectRefs",
+		"ObjectRef":      "the generated ObjectRef",
+	}
+	for _, t := range objectTypes {
+		names := [][2]string{
+			{camelCase(t), fmt.Sprintf("factory for type %q", t)},
+			{"any" + pascalCase(t), fmt.Sprintf("wildcard constructor for type %q", t)},
+			{refTypeName(t), fmt.Sprintf("ref type for type %q", t)},
+		}
+		for _, r := range typeRelations[t] {
+			names = append(names, [2]string{listFunctionName(t, r), fmt.Sprintf("list function for %s.%s", t, r)})
+		}
+		for _, n := range names {
+			if owner, ok := owners[n[0]]; ok {
+				return fmt.Errorf("typescript: %s collides with %s", n[1], owner)
+			}
+			owners[n[0]] = n[1]
+		}
+	}
+	return nil
+}
+
 // errWriter wraps a bytes.Buffer and captures the first error.
 type errWriter struct {
 	w   *bytes.Buffer
@@ -57,7 +57,7 @@ func TestGenerator_Generate(t *testing.T) {
 			t.Fatalf("Generate error: %v", err)
 		}
 
-		expectedFiles := []string{"types.ts", "schema.ts", "index.ts"}
+		expectedFiles := []string{"types.ts", "schema.ts", "batch.ts", "list.ts", "index.ts"}
 		if len(files) != len(expectedFiles) {
 			t.Errorf("Generate returned %d files, want %d", len(files), len(expectedFiles))
 		}
@@ -203,12 +203,12 @@ func TestGenerator_Generate(t *testing.T) {
 
 		code := string(files["schema.ts"])
 
-		if !strings.Contains(code, "import type { MelangeObject } from '@pthm/melange';") {
-			t.Error("schema.ts should import MelangeObject from @pthm/melange")
+		if !strings.Contains(code, "import type { MelangeObject, Queryable } from '@pthm/melange';") {
+			t.Error("schema.ts should import MelangeObject and Queryable from @pthm/melange")
 		}
 
-		if !strings.Contains(code, "import { ObjectTypes } from './types.js';") {
-			t.Error("schema.ts should import ObjectTypes from types.ts")
+		if !strings.Contains(code, "import { ObjectTypes, Relations } from './types.js';") {
+			t.Error("schema.ts should import ObjectTypes and Relations from types.ts")
 		}
 	})
 
@@ -386,8 +386,8 @@ func TestGenerator_EmptySchema(t *testing.T) {
 			t.Fatalf("Generate error: %v", err)
 		}
 
-		if len(files) != 3 {
-			t.Errorf("should generate 3 files even for empty schema, got %d", len(files))
+		if len(files) != 5 {
+			t.Errorf("should generate 5 files even for empty schema, got %d", len(files))
 		}
 
 		typesCode := string(files["types.ts"])
@@ -402,6 +402,168 @@ func TestGenerator_EmptySchema(t *testing.T) {
 	})
 }
 
+func TestGenerator_SplitByType(t *testing.T) {
+	gen := &typescript.Generator{}
+	typeDefs := []schema.TypeDefinition{{Name: "user"}, {Name: "pull_request"}, {Name: "index"}, {Name: "batch"}}
+
+	files, err := gen.Generate(typeDefs, &clientgen.Config{SplitByType: true})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+
+	expectedFiles := []string{"types.ts", "batch.ts", "list.ts", "index.ts", "user.ts", "pull_request.ts", "type_index.ts", "type_batch.ts"}
+	if len(files) != len(expectedFiles) {
+		t.Errorf("Generate returned %d files, want %d", len(files), len(expectedFiles))
+	}
+	for _, filename := range expectedFiles {
+		if _, ok := files[filename]; !ok {
+			t.Errorf("Generate should return %s file", filename)
+		}
+	}
+
+	pr := string(files["pull_request.ts"])
+	if !strings.Contains(pr, "export function pullRequest(id: string): MelangeObject {") {
+		t.Error("pull_request.ts should hold the pullRequest factory")
+	}
+	if strings.Contains(pr, "function user(") {
+		t.Error("pull_request.ts should only hold pull_request factories")
+	}
+
+	indexCode := string(files["index.ts"])
+	for _, want := range []string{"export * from './pull_request.js';", "export * from './type_index.js';", "export * from './user.js';", "export * from './batch.js';"} {
+		if !strings.Contains(indexCode, want) {
+			t.Errorf("index.ts missing %q", want)
+		}
+	}
+	if strings.Contains(indexCode, "./schema.js") {
+		t.Error("index.ts should not re-export schema.ts when split by type")
+	}
+}
+
+func TestGenerator_BatchCheck(t *testing.T) {
+	gen := &typescript.Generator{}
+	typeDefs := []schema.TypeDefinition{
+		{Name: "user"},
+		{Name: "repository", Relations: []schema.RelationDefinition{{Name: "can_read"}}},
+	}
+
+	files, err := gen.Generate(typeDefs, nil)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	batch := string(files["batch.ts"])
+	for _, want := range []string{
+		"import type { MelangeObject, Queryable } from '@pthm/melange';",
+		"import { translateError } from '@pthm/melange';",
+		"import type { Relation } from './types.js';",
+		"export interface BatchCheckRequest {",
+		"const error = translateError(err);",
+		"export interface BatchCheckResult {",
+		"export async function batchCheck(",
+		"'FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[]) ' +",
+		"'CROSS JOIN LATERAL (SELECT check_permission(",
+		"if (requests.length === 0) {\n    return [];",
+	} {
+		if !strings.Contains(batch, want) {
+			t.Errorf("batch.ts missing %q", want)
+		}
+	}
+	if !strings.Contains(string(files["index.ts"]), "export * from './batch.js';") {
+		t.Error("index.ts should re-export batch.ts")
+	}
+
+	files, err = gen.Generate(typeDefs, &clientgen.Config{SchemaName: "authz"})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	batch = string(files["batch.ts"])
+	if n := strings.Count(batch, `SELECT "authz"."check_permission"(`); n != 2 {
+		t.Errorf("batch.ts has %d qualified check_permission calls, want 2", n)
+	}
+
+	if _, err := gen.Generate([]schema.TypeDefinition{{Name: "batch_check"}}, nil); err == nil {
+		t.Error("Generate should reject a type whose factory would be named batchCheck")
+	}
+}
+
+func TestGenerator_ListObjects(t *testing.T) {
+	gen := &typescript.Generator{}
+	typeDefs := []schema.TypeDefinition{
+		{Name: "user"},
+		{Name: "document", Relations: []schema.RelationDefinition{{Name: "owner"}, {Name: "can_view"}}},
+	}
+
+	files, err := gen.Generate(typeDefs, &clientgen.Config{RelationFilter: "can_"})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	types := string(files["types.ts"])
+	for _, want := range []string{
+		"export type DocumentRef = { readonly type: typeof ObjectTypes.Document; readonly id: string };",
+		"export type UserRef = { readonly type: typeof ObjectTypes.User; readonly id: string };",
+		"export type ObjectRef = DocumentRef | UserRef;",
+	} {
+		if !strings.Contains(types, want) {
+			t.Errorf("types.ts missing %q", want)
+		}
+	}
+
+	schemaCode := string(files["schema.ts"])
+	for _, want := range []string{
+		"import type { DocumentRef } from './types.js';",
+		"import { listObjectRefs } from './list.js';",
+		"import type { Consistency } from './list.js';",
+		"export function listDocumentCanViewObjects(db: Queryable, subject: MelangeObject, consistency?: Consistency): Promise<DocumentRef[]> {\n" +
+			"  return listObjectRefs<DocumentRef>(db, subject, Relations.CanView, ObjectTypes.Document, consistency);\n}",
+	} {
+		if !strings.Contains(schemaCode, want) {
+			t.Errorf("schema.ts missing %q", want)
+		}
+	}
+	if strings.Contains(schemaCode, "listDocumentOwnerObjects") {
+		t.Error("schema.ts should not list relations outside the RelationFilter")
+	}
+
+	list := string(files["list.ts"])
+	for _, want := range []string{
+		"export async function listObjectRefs<R extends ObjectRef>(",
+		"): Promise<R[]> {",
+		"'SELECT object_id FROM list_accessible_objects($1, $2, $3, $4, p_consistency => $5)'",
+		"export type Consistency = 'strong' | 'eventual';",
+		"  consistency?: Consistency,\n): Promise<R[]> {",
+		"import { translateError } from '@pthm/melange';",
+		"throw translateError(err);",
+	} {
+		if !strings.Contains(list, want) {
+			t.Errorf("list.ts missing %q", want)
+		}
+	}
+	index := string(files["index.ts"])
+	for _, want := range []string{"export type { ObjectRef, DocumentRef, UserRef } from './types.js';", "export * from './list.js';"} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.ts missing %q", want)
+		}
+	}
+
+	files, err = gen.Generate(typeDefs, &clientgen.Config{SchemaName: "authz"})
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if !strings.Contains(string(files["list.ts"]), `FROM "authz"."list_accessible_objects"(`) {
+		t.Error("list.ts should call list_accessible_objects schema-qualified")
+	}
+
+	for _, defs := range [][]schema.TypeDefinition{
+		{{Name: "object"}},
+		{{Name: "list_object_refs"}},
+		{{Name: "pull", Relations: []schema.RelationDefinition{{Name: "request_viewer"}}}, {Name: "pull_request", Relations: []schema.RelationDefinition{{Name: "viewer"}}}},
+	} {
+		if _, err := gen.Generate(defs, nil); err == nil {
+			t.Errorf("Generate(%v) should reject colliding exported names", defs)
+		}
+	}
+}
+
 func TestRegistry_TypeScriptGeneratorRegistered(t *testing.T) {
 	gen := clientgen.Get("typescript")
 	if gen == nil {
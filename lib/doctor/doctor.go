@@ -16,6 +16,7 @@ package doctor
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -55,35 +56,55 @@ func (s Status) Symbol() string {
 	}
 }
 
+// String returns the status name used in JSON output: "pass", "warn" or
+// "fail".
+func (s Status) String() string {
+	switch s {
+	case StatusPass:
+		return "pass"
+	case StatusWarn:
+		return "warn"
+	case StatusFail:
+		return "fail"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the status as its String form.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 // CheckResult represents the outcome of a single health check.
 type CheckResult struct {
 	// Category groups related checks (e.g., "schema", "functions", "tuples").
-	Category string
+	Category string `json:"category"`
 
 	// Name is a short identifier for the check.
-	Name string
+	Name string `json:"name"`
 
 	// Status is the check outcome.
-	Status Status
+	Status Status `json:"severity"`
 
 	// Message is a human-readable description of the result.
-	Message string
+	Message string `json:"message"`
 
 	// Details provides additional information for verbose output.
-	Details string
+	Details string `json:"details,omitempty"`
 
 	// FixHint suggests how to resolve issues.
-	FixHint string
+	FixHint string `json:"fix_hint,omitempty"`
 }
 
 // Report contains all health check results.
 type Report struct {
-	Checks []CheckResult
+	Checks []CheckResult `json:"checks"`
 
 	// Summary counts.
-	Passed   int
-	Warnings int
-	Errors   int
+	Passed   int `json:"passed"`
+	Warnings int `json:"warnings"`
+	Errors   int `json:"errors"`
 }
 
 // AddCheck adds a check result and updates summary counts.
@@ -133,6 +154,18 @@ func (r *Report) Print(w io.Writer, verbose bool) {
 		r.Passed, r.Warnings, r.Errors)
 }
 
+// WriteJSON writes the report to w as an indented JSON object holding every
+// check, with its details, and the summary counts.
+func (r *Report) WriteJSON(w io.Writer) error {
+	out := *r
+	if out.Checks == nil {
+		out.Checks = []CheckResult{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
 // HasErrors returns true if any check failed.
 func (r *Report) HasErrors() bool {
 	return r.Errors > 0
@@ -141,12 +174,18 @@ func (r *Report) HasErrors() bool {
 // Options configures doctor behavior.
 type Options struct {
 	SkipPerformance bool
+
+	// BaselinePath names a manifest written by `melange generate manifest`.
+	// When set, installed functions must match it exactly: any added, removed,
+	// or changed function fails the Baseline check.
+	BaselinePath string
 }
 
 // Doctor performs health checks on the melange authorization infrastructure.
 type Doctor struct {
 	db             *sql.DB
 	databaseSchema string
+	tuplesTable    string
 	schemaPath     string
 	opts           Options
 
@@ -182,13 +221,15 @@ func (d *Doctor) getAnalyses() []sqlgen.RelationAnalysis {
 	return analyses
 }
 
-// TuplesInfo contains information about the melange_tuples relation.
+// TuplesInfo contains information about the tuples relation.
 type TuplesInfo struct {
 	Exists     bool
 	RelKind    string // 'r' = table, 'v' = view, 'm' = materialized view
 	RelKindStr string // human-readable
 	Columns    []string
-	RowCount   int64 // -1 if unknown/expensive to compute
+	// ColumnTypes maps each column to its type as rendered by format_type.
+	ColumnTypes map[string]string
+	RowCount    int64 // -1 if unknown/expensive to compute
 }
 
 // New creates a new Doctor instance.
@@ -214,6 +255,17 @@ func (d *Doctor) DatabaseSchema() string {
 	return d.databaseSchema
 }
 
+// SetTuplesTable sets the tuples relation the checks inspect, as in
+// migrator.MigrateOptions.TuplesTable. Empty means melange_tuples.
+func (d *Doctor) SetTuplesTable(tuplesTable string) {
+	d.tuplesTable = tuplesTable
+}
+
+// TuplesTable returns the tuples relation the checks inspect.
+func (d *Doctor) TuplesTable() string {
+	return sqlgen.TuplesTableName(d.tuplesTable)
+}
+
 // Run executes all health checks and returns a report.
 func (d *Doctor) Run(ctx context.Context) (*Report, error) {
 	report := &Report{}
@@ -226,6 +278,11 @@ func (d *Doctor) Run(ctx context.Context) (*Report, error) {
 	if err := d.checkGeneratedFunctions(ctx, report); err != nil {
 		return nil, fmt.Errorf("checking generated functions: %w", err)
 	}
+	if d.opts.BaselinePath != "" {
+		if err := d.checkBaseline(ctx, report); err != nil {
+			return nil, fmt.Errorf("checking baseline: %w", err)
+		}
+	}
 	if err := d.checkTuplesSource(ctx, report); err != nil {
 		return nil, fmt.Errorf("checking tuples source: %w", err)
 	}
@@ -263,6 +320,7 @@ func (d *Doctor) Run(ctx context.Context) (*Report, error) {
 func (d *Doctor) checkSchemaFile(report *Report) {
 	m := migrator.NewMigrator(d.db, d.schemaPath)
 	m.SetDatabaseSchema(d.databaseSchema)
+	m.SetTuplesTable(d.tuplesTable)
 
 	schemaPath := m.SchemaPath()
 
@@ -333,12 +391,46 @@ func (d *Doctor) checkSchemaFile(report *Report) {
 		Status:   StatusPass,
 		Message:  "No cyclic dependencies detected",
 	})
+
+	// Check tuple-to-userset linking relations
+	if err := schema.ValidateTupleToUsersets(types); err != nil {
+		report.AddCheck(CheckResult{
+			Category: "Schema File",
+			Name:     "tuple-to-userset",
+			Status:   StatusFail,
+			Message:  "Schema has unresolvable tuple-to-userset relations",
+			Details:  err.Error(),
+			FixHint:  "Check the linking relation in each `X from Y` for typos",
+		})
+		return
+	}
+
+	report.AddCheck(CheckResult{
+		Category: "Schema File",
+		Name:     "tuple-to-userset",
+		Status:   StatusPass,
+		Message:  "All tuple-to-userset linking relations resolve",
+	})
+
+	// Relations whose names differ only by case generate correctly, but are
+	// usually a typo.
+	for _, c := range sqlgen.FindNameCollisions(types) {
+		report.AddCheck(CheckResult{
+			Category: "Schema File",
+			Name:     "function-names",
+			Status:   StatusWarn,
+			Message:  fmt.Sprintf("Relations %s fold to the same function name", strings.Join(c.Relations, ", ")),
+			Details:  fmt.Sprintf("Their functions are told apart by a hash suffix; %q is the shared name.", c.Identifier),
+			FixHint:  "Rename one of the relations unless they are meant to differ only by case",
+		})
+	}
 }
 
 // checkMigrationState validates the migration tracking table and state.
 func (d *Doctor) checkMigrationState(ctx context.Context, report *Report) error {
 	m := migrator.NewMigrator(d.db, d.schemaPath)
 	m.SetDatabaseSchema(d.databaseSchema)
+	m.SetTuplesTable(d.tuplesTable)
 
 	// Check if migrations table exists
 	var tableExists bool
@@ -404,12 +496,13 @@ func (d *Doctor) checkMigrationState(ctx context.Context, report *Report) error
 
 	// Check if schema has changed since last migration
 	if d.parsedTypes != nil {
-		// Read schema content for checksum
+		// The checksum is computed from the parsed types, so formatting-only
+		// edits do not report the schema as changed.
 		schemaPath := m.SchemaPath()
-		content, err := readFileContent(schemaPath)
+		content, err := parser.ReadSchemaContent(schemaPath)
 		if err == nil {
-			d.schemaContent = content
-			currentChecksum := migrator.ComputeSchemaChecksum(content)
+			d.schemaContent = string(content)
+			currentChecksum := migrator.SchemaHash(d.parsedTypes)
 
 			switch {
 			case currentChecksum != lastMigration.SchemaChecksum:
@@ -418,7 +511,7 @@ func (d *Doctor) checkMigrationState(ctx context.Context, report *Report) error
 					Name:     "schema_sync",
 					Status:   StatusWarn,
 					Message:  "Schema file has changed since last migration",
-					Details:  fmt.Sprintf("File checksum: %s...\nDB checksum:   %s...", currentChecksum[:16], lastMigration.SchemaChecksum[:16]),
+					Details:  fmt.Sprintf("Schema hash: %s...\nDB checksum: %s...", currentChecksum[:16], lastMigration.SchemaChecksum[:16]),
 					FixHint:  "Run 'melange migrate' to apply changes",
 				})
 			case lastMigration.CodegenVersion != migrator.CodegenVersion():
@@ -511,10 +604,24 @@ func (d *Doctor) checkGeneratedFunctions(ctx context.Context, report *Report) er
 			}
 		}
 
+		// Opt-in audit, strict check and cursor functions are installed only when
+		// enabled at migrate time, and the contextual tuples function only with an
+		// unqualified tuples table, so they are neither missing nor orphaned.
+		optionalSet := map[string]bool{sqlgen.ContextualTuplesFunctionName: true}
+		for _, fn := range sqlgen.CollectEvidenceFunctionNames(analyses) {
+			optionalSet[fn] = true
+		}
+		for _, fn := range sqlgen.CollectStrictFunctionNames(analyses) {
+			optionalSet[fn] = true
+		}
+		for _, fn := range sqlgen.CollectListObjectsCursorFunctionNames(analyses) {
+			optionalSet[fn] = true
+		}
+
 		// Find orphan functions (in DB but not expected)
 		var orphans []string
 		for _, fn := range currentFuncs {
-			if !expectedSet[fn] {
+			if !expectedSet[fn] && !optionalSet[fn] {
 				orphans = append(orphans, fn)
 			}
 		}
@@ -549,6 +656,12 @@ func (d *Doctor) checkGeneratedFunctions(ctx context.Context, report *Report) er
 				FixHint:  "Run 'melange migrate' to clean up orphans",
 			})
 		}
+
+		comments, err := d.getFunctionComments(ctx)
+		if err != nil {
+			return fmt.Errorf("getting function comments: %w", err)
+		}
+		emitMetadataDrift(report, sqlgen.ExpectedFunctionMetadata(analyses), comments)
 	} else {
 		// No schema to compare against, just report function count
 		checkFuncs := 0
@@ -571,7 +684,7 @@ func (d *Doctor) checkGeneratedFunctions(ctx context.Context, report *Report) er
 	return nil
 }
 
-// checkTuplesSource validates the melange_tuples view/table.
+// checkTuplesSource validates the tuples view/table.
 func (d *Doctor) checkTuplesSource(ctx context.Context, report *Report) error {
 	info, err := d.getTuplesInfo(ctx)
 	if err != nil {
@@ -584,8 +697,8 @@ func (d *Doctor) checkTuplesSource(ctx context.Context, report *Report) error {
 			Category: "Tuples Source",
 			Name:     "exists",
 			Status:   StatusFail,
-			Message:  "melange_tuples does not exist",
-			FixHint:  "Create a view/table named melange_tuples over your domain tables",
+			Message:  fmt.Sprintf("%s does not exist", d.TuplesTable()),
+			FixHint:  fmt.Sprintf("Create a view/table named %s over your domain tables", d.TuplesTable()),
 		})
 		return nil
 	}
@@ -594,7 +707,7 @@ func (d *Doctor) checkTuplesSource(ctx context.Context, report *Report) error {
 		Category: "Tuples Source",
 		Name:     "exists",
 		Status:   StatusPass,
-		Message:  fmt.Sprintf("melange_tuples exists (%s)", info.RelKindStr),
+		Message:  fmt.Sprintf("%s exists (%s)", d.TuplesTable(), info.RelKindStr),
 	})
 
 	// Check required columns
@@ -618,7 +731,7 @@ func (d *Doctor) checkTuplesSource(ctx context.Context, report *Report) error {
 			Status:   StatusFail,
 			Message:  fmt.Sprintf("Missing required columns: %s", strings.Join(missingCols, ", ")),
 			Details:  fmt.Sprintf("Found columns: %s", strings.Join(info.Columns, ", ")),
-			FixHint:  "Update melange_tuples to include all required columns",
+			FixHint:  fmt.Sprintf("Update %s to include all required columns", d.TuplesTable()),
 		})
 	} else {
 		report.AddCheck(CheckResult{
@@ -629,13 +742,15 @@ func (d *Doctor) checkTuplesSource(ctx context.Context, report *Report) error {
 		})
 	}
 
+	checkTuplesColumnTypes(report, info)
+
 	// For materialized views, suggest refresh consideration
 	if info.RelKind == "m" {
 		report.AddCheck(CheckResult{
 			Category: "Tuples Source",
 			Name:     "refresh",
 			Status:   StatusWarn,
-			Message:  "melange_tuples is a materialized view",
+			Message:  fmt.Sprintf("%s is a materialized view", d.TuplesTable()),
 			Details:  "Materialized views require manual refresh to see data changes",
 			FixHint:  "Ensure you have a refresh strategy (e.g., REFRESH MATERIALIZED VIEW CONCURRENTLY)",
 		})
@@ -644,7 +759,82 @@ func (d *Doctor) checkTuplesSource(ctx context.Context, report *Report) error {
 	return nil
 }
 
-// checkDataHealth validates the data in melange_tuples.
+// tuplesColumnTypeClass classifies a melange_tuples column type by how the
+// generated functions' TEXT comparisons treat it.
+type tuplesColumnTypeClass int
+
+const (
+	// columnTypeText covers text and varchar: varchar is binary-coercible to
+	// text, so comparisons and text-opclass indexes behave as for text.
+	columnTypeText tuplesColumnTypeClass = iota
+	// columnTypeCoerced covers string types whose own semantics are lost
+	// against TEXT parameters (citext, char(n)): comparisons resolve as text
+	// and indexes built with the column's own operator class go unused.
+	columnTypeCoerced
+	// columnTypeIncompatible covers non-string types (uuid, integer, ...),
+	// which have no comparison with TEXT parameters at all.
+	columnTypeIncompatible
+)
+
+// classifyTuplesColumnType classifies a format_type rendering.
+func classifyTuplesColumnType(typ string) tuplesColumnTypeClass {
+	base, _, _ := strings.Cut(typ, "(")
+	switch strings.TrimSpace(base) {
+	case "text", "character varying":
+		return columnTypeText
+	case "citext", "character":
+		return columnTypeCoerced
+	default:
+		return columnTypeIncompatible
+	}
+}
+
+// checkTuplesColumnTypes reports melange_tuples columns whose type is not text.
+func checkTuplesColumnTypes(report *Report, info *TuplesInfo) {
+	var coerced, incompatible []string
+	for _, col := range []string{"object_type", "object_id", "relation", "subject_type", "subject_id"} {
+		typ, ok := info.ColumnTypes[col]
+		if !ok {
+			continue // Reported by the columns check
+		}
+		switch classifyTuplesColumnType(typ) {
+		case columnTypeCoerced:
+			coerced = append(coerced, fmt.Sprintf("%s: %s", col, typ))
+		case columnTypeIncompatible:
+			incompatible = append(incompatible, fmt.Sprintf("%s: %s", col, typ))
+		}
+	}
+
+	switch {
+	case len(incompatible) > 0:
+		report.AddCheck(CheckResult{
+			Category: "Tuples Source",
+			Name:     "column_types",
+			Status:   StatusFail,
+			Message:  fmt.Sprintf("%d column(s) are not a string type", len(incompatible)),
+			Details:  strings.Join(append(incompatible, coerced...), "\n"),
+			FixHint:  "Cast to text in the view (e.g. id::text AS object_id) and add matching expression indexes",
+		})
+	case len(coerced) > 0:
+		report.AddCheck(CheckResult{
+			Category: "Tuples Source",
+			Name:     "column_types",
+			Status:   StatusWarn,
+			Message:  fmt.Sprintf("%d column(s) compare as text against generated function parameters", len(coerced)),
+			Details:  strings.Join(coerced, "\n") + "\nComparisons are case- and padding-sensitive, and indexes on these columns are not used",
+			FixHint:  "Expose these columns as text (e.g. email::text AS subject_id) and index that expression",
+		})
+	default:
+		report.AddCheck(CheckResult{
+			Category: "Tuples Source",
+			Name:     "column_types",
+			Status:   StatusPass,
+			Message:  "Required columns are text or varchar",
+		})
+	}
+}
+
+// checkDataHealth validates the data in the tuples relation.
 func (d *Doctor) checkDataHealth(ctx context.Context, report *Report) error {
 	if d.tuplesInfo == nil || !d.tuplesInfo.Exists {
 		return nil // Already reported in tuples check
@@ -652,14 +842,15 @@ func (d *Doctor) checkDataHealth(ctx context.Context, report *Report) error {
 
 	// Check if there's any data
 	var count int64
-	err := d.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, d.prefixIdent("melange_tuples"))).Scan(&count)
+	_, _, tuplesRef := d.tuplesRelation()
+	err := d.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, tuplesRef)).Scan(&count)
 	if err != nil {
 		// May fail if columns are wrong - just skip
 		report.AddCheck(CheckResult{
 			Category: "Data Health",
 			Name:     "query",
 			Status:   StatusWarn,
-			Message:  "Could not query melange_tuples",
+			Message:  fmt.Sprintf("Could not query %s", d.TuplesTable()),
 			Details:  err.Error(),
 		})
 		return nil
@@ -670,7 +861,7 @@ func (d *Doctor) checkDataHealth(ctx context.Context, report *Report) error {
 			Category: "Data Health",
 			Name:     "data",
 			Status:   StatusWarn,
-			Message:  "melange_tuples is empty",
+			Message:  fmt.Sprintf("%s is empty", d.TuplesTable()),
 			Details:  "No authorization data to evaluate permissions against",
 		})
 	} else {
@@ -678,7 +869,7 @@ func (d *Doctor) checkDataHealth(ctx context.Context, report *Report) error {
 			Category: "Data Health",
 			Name:     "data",
 			Status:   StatusPass,
-			Message:  fmt.Sprintf("melange_tuples contains %d tuples", count),
+			Message:  fmt.Sprintf("%s contains %d tuples", d.TuplesTable(), count),
 		})
 	}
 
@@ -692,25 +883,56 @@ func (d *Doctor) checkDataHealth(ctx context.Context, report *Report) error {
 	return nil
 }
 
-// tupleSignature holds an aggregated (object_type, relation, subject_type) group
-// with its tuple count, used for classifying orphaned tuples.
+// tupleSignature holds an aggregated (object_type, relation, subject_type,
+// subject form) group with its tuple count and one of its tuples, used for
+// classifying orphaned tuples.
 type tupleSignature struct {
 	objectType  string
 	relation    string
 	subjectType string
 	count       int64
+	// subjectRelation is the relation of a userset subject
+	// (group:eng#member), empty for other subjects.
+	subjectRelation string
+	// wildcard is set for type:* subjects.
+	wildcard bool
+	// example is one tuple of the group, as object#relation@subject.
+	example string
+}
+
+// subjectRef returns the type restriction that accepts the group's subjects:
+// "user", "user:*" or "group#member".
+func (s tupleSignature) subjectRef() string {
+	switch {
+	case s.wildcard:
+		return s.subjectType + ":*"
+	case s.subjectRelation != "":
+		return s.subjectType + "#" + s.subjectRelation
+	}
+	return s.subjectType
+}
+
+// describe formats the group's tuple count and example for report details.
+func (s tupleSignature) describe(count int64) string {
+	if s.example == "" {
+		return fmt.Sprintf("%d tuples", count)
+	}
+	return fmt.Sprintf("%d tuples, e.g. %s", count, s.example)
 }
 
 // validateTupleData checks all tuples against the schema model and reports
-// unknown object types, relations, subject types, and invalid subject type
-// assignments. This replaces the earlier sampling-based check with a
-// comprehensive GROUP BY aggregation.
+// unknown object types, relations, subject types, invalid subject type
+// assignments, and subjects in a form their relation does not accept. This
+// replaces the earlier sampling-based check with a comprehensive GROUP BY
+// aggregation.
 func (d *Doctor) validateTupleData(ctx context.Context, report *Report) error {
 	// Build validity maps from parsed schema.
 	validTypes := make(map[string]bool)
 	validRelations := make(map[string]map[string]bool)
-	// allowedSubjects: objectType -> relation -> set of allowed subject types.
-	// Only populated for relations with explicit SubjectTypeRefs (direct assignments).
+	// allowedSubjects: objectType -> relation -> set of allowed subject refs,
+	// written as "user", "user:*" or "group#member" (see
+	// tupleSignature.subjectRef). Only populated for relations with explicit
+	// SubjectTypeRefs (direct assignments).
 	allowedSubjects := make(map[string]map[string]map[string]bool)
 
 	for _, t := range d.parsedTypes {
@@ -721,7 +943,8 @@ func (d *Doctor) validateTupleData(ctx context.Context, report *Report) error {
 			if len(r.SubjectTypeRefs) > 0 {
 				allowed := make(map[string]bool, len(r.SubjectTypeRefs))
 				for _, ref := range r.SubjectTypeRefs {
-					allowed[ref.Type] = true
+					sig := tupleSignature{subjectType: ref.Type, subjectRelation: ref.Relation, wildcard: ref.Wildcard}
+					allowed[sig.subjectRef()] = true
 				}
 				if allowedSubjects[t.Name] == nil {
 					allowedSubjects[t.Name] = make(map[string]map[string]bool)
@@ -732,14 +955,23 @@ func (d *Doctor) validateTupleData(ctx context.Context, report *Report) error {
 		validRelations[t.Name] = rels
 	}
 
-	// Single aggregation query returns all distinct tuple signatures with counts.
+	// Single aggregation query returns all distinct tuple signatures with
+	// counts and an example tuple. A userset's relation follows the last '#'
+	// of subject_id, as in the generated functions.
+	_, _, tuplesRef := d.tuplesRelation()
 	rows, err := d.db.QueryContext(ctx, fmt.Sprintf(
 		`
-			SELECT object_type, relation, subject_type, COUNT(*) AS cnt
+			SELECT object_type, relation, subject_type,
+				CASE WHEN position('#' in subject_id) > 0
+					THEN substring(subject_id from length(subject_id) - strpos(reverse(subject_id), '#') + 2)
+					ELSE '' END AS subject_relation,
+				COALESCE(subject_id = '*', FALSE) AS wildcard,
+				COUNT(*) AS cnt,
+				COALESCE(MIN(object_type || ':' || object_id || '#' || relation || '@' || subject_type || ':' || subject_id), '') AS example
 			FROM %s
-			GROUP BY object_type, relation, subject_type
+			GROUP BY 1, 2, 3, 4, 5
 		`,
-		d.prefixIdent("melange_tuples"),
+		tuplesRef,
 	))
 	if err != nil {
 		return err
@@ -749,7 +981,7 @@ func (d *Doctor) validateTupleData(ctx context.Context, report *Report) error {
 	var sigs []tupleSignature
 	for rows.Next() {
 		var s tupleSignature
-		if err := rows.Scan(&s.objectType, &s.relation, &s.subjectType, &s.count); err != nil {
+		if err := rows.Scan(&s.objectType, &s.relation, &s.subjectType, &s.subjectRelation, &s.wildcard, &s.count, &s.example); err != nil {
 			return err
 		}
 		sigs = append(sigs, s)
@@ -759,7 +991,7 @@ func (d *Doctor) validateTupleData(ctx context.Context, report *Report) error {
 	}
 
 	findings := classifyTupleSignatures(sigs, validTypes, validRelations, allowedSubjects)
-	emitTupleFindings(report, findings)
+	emitTupleFindings(report, d.TuplesTable(), findings)
 	return nil
 }
 
@@ -769,11 +1001,13 @@ type tupleFindings struct {
 	unknownRelations   []string // "document:publish (42 tuples)"
 	unknownSubjects    []string // "device (30 tuples)"
 	invalidSubjects    []string // "organization:member subject_type=team (5 tuples)"
+	unmatchedSubjects  []string // "organization:member subject=user:* (2 tuples)"
 
 	unknownObjectTypeCount int64
 	unknownRelationCount   int64
 	unknownSubjectCount    int64
 	invalidSubjectCount    int64
+	unmatchedSubjectCount  int64
 }
 
 // classifyTupleSignatures categorizes aggregated tuple signatures against the
@@ -787,60 +1021,97 @@ func classifyTupleSignatures(
 	var f tupleFindings
 
 	// Accumulate counts per unique key. Multiple signatures can share the same
-	// unknown value (e.g., same unknown object_type with different relations).
+	// unknown value (e.g., same unknown object_type with different relations);
+	// the first signature seen supplies the example.
 	objTypeCounts := make(map[string]int64)
 	relationCounts := make(map[string]int64)
 	subjectCounts := make(map[string]int64)
+	invalidCounts := make(map[string]int64)
+	examples := make(map[string]tupleSignature)
+	add := func(counts map[string]int64, key string, s tupleSignature) {
+		if _, ok := counts[key]; !ok {
+			examples[key] = s
+		}
+		counts[key] += s.count
+	}
 
 	for _, s := range sigs {
 		// Unknown object type.
 		if !validTypes[s.objectType] {
-			objTypeCounts[s.objectType] += s.count
+			add(objTypeCounts, s.objectType, s)
 			f.unknownObjectTypeCount += s.count
 			continue // Skip further checks — relation/subject are meaningless.
 		}
 
 		// Unknown relation for this object type.
 		if !validRelations[s.objectType][s.relation] {
-			key := s.objectType + ":" + s.relation
-			relationCounts[key] += s.count
+			add(relationCounts, s.objectType+":"+s.relation, s)
 			f.unknownRelationCount += s.count
 			continue // Skip subject check — relation is already invalid.
 		}
 
 		// Unknown subject type.
 		if !validTypes[s.subjectType] {
-			subjectCounts[s.subjectType] += s.count
+			add(subjectCounts, s.subjectType, s)
 			f.unknownSubjectCount += s.count
 			continue
 		}
 
-		// Invalid subject type for this relation (only when relation has explicit constraints).
-		if allowed := allowedSubjects[s.objectType][s.relation]; allowed != nil {
-			if !allowed[s.subjectType] {
-				key := fmt.Sprintf("%s:%s subject_type=%s", s.objectType, s.relation, s.subjectType)
-				f.invalidSubjects = append(f.invalidSubjects, fmt.Sprintf("%s (%d tuples)", key, s.count))
-				f.invalidSubjectCount += s.count
-			}
+		// Only relations with explicit constraints restrict their subjects.
+		allowed := allowedSubjects[s.objectType][s.relation]
+		if allowed == nil {
+			continue
+		}
+
+		// Invalid subject type for this relation.
+		if !refsAllowType(allowed, s.subjectType) {
+			add(invalidCounts, fmt.Sprintf("%s:%s subject_type=%s", s.objectType, s.relation, s.subjectType), s)
+			f.invalidSubjectCount += s.count
+			continue
+		}
+
+		// The type is allowed, but not in this form: a user:* tuple on a
+		// [user] relation, or a group:eng#admin tuple on a [group#member]
+		// relation. Generated functions never match these tuples.
+		if !allowed[s.subjectRef()] {
+			key := fmt.Sprintf("%s:%s subject=%s", s.objectType, s.relation, s.subjectRef())
+			f.unmatchedSubjects = append(f.unmatchedSubjects, fmt.Sprintf("%s (%s)", key, s.describe(s.count)))
+			f.unmatchedSubjectCount += s.count
 		}
 	}
 
 	// Build display strings from accumulated counts.
-	for val, count := range objTypeCounts {
-		f.unknownObjectTypes = append(f.unknownObjectTypes, fmt.Sprintf("%s (%d tuples)", val, count))
-	}
-	for val, count := range relationCounts {
-		f.unknownRelations = append(f.unknownRelations, fmt.Sprintf("%s (%d tuples)", val, count))
-	}
-	for val, count := range subjectCounts {
-		f.unknownSubjects = append(f.unknownSubjects, fmt.Sprintf("%s (%d tuples)", val, count))
+	display := func(counts map[string]int64) []string {
+		out := make([]string, 0, len(counts))
+		for key, count := range counts {
+			out = append(out, fmt.Sprintf("%s (%s)", key, examples[key].describe(count)))
+		}
+		sort.Strings(out)
+		return out
 	}
+	f.unknownObjectTypes = display(objTypeCounts)
+	f.unknownRelations = display(relationCounts)
+	f.unknownSubjects = display(subjectCounts)
+	f.invalidSubjects = display(invalidCounts)
+	sort.Strings(f.unmatchedSubjects)
 
 	return f
 }
 
-// emitTupleFindings adds check results to the report based on classified findings.
-func emitTupleFindings(report *Report, f tupleFindings) {
+// refsAllowType reports whether any subject ref in allowed, as built by
+// validateTupleData, is of subjectType.
+func refsAllowType(allowed map[string]bool, subjectType string) bool {
+	for ref := range allowed {
+		if ref == subjectType || strings.HasPrefix(ref, subjectType+":") || strings.HasPrefix(ref, subjectType+"#") {
+			return true
+		}
+	}
+	return false
+}
+
+// emitTupleFindings adds check results to the report based on classified
+// findings in tuplesTable.
+func emitTupleFindings(report *Report, tuplesTable string, f tupleFindings) {
 	anyIssue := false
 
 	if len(f.unknownObjectTypes) > 0 {
@@ -851,7 +1122,7 @@ func emitTupleFindings(report *Report, f tupleFindings) {
 			Status:   StatusWarn,
 			Message:  fmt.Sprintf("Found %d unknown object types affecting %d tuples", len(f.unknownObjectTypes), f.unknownObjectTypeCount),
 			Details:  truncatedJoin(f.unknownObjectTypes, 10),
-			FixHint:  "Update melange_tuples to exclude rows with unknown object types, or add missing types to schema.fga",
+			FixHint:  fmt.Sprintf("Update %s to exclude rows with unknown object types, or add missing types to schema.fga", tuplesTable),
 		})
 	}
 
@@ -863,7 +1134,7 @@ func emitTupleFindings(report *Report, f tupleFindings) {
 			Status:   StatusWarn,
 			Message:  fmt.Sprintf("Found %d unknown relations affecting %d tuples", len(f.unknownRelations), f.unknownRelationCount),
 			Details:  truncatedJoin(f.unknownRelations, 10),
-			FixHint:  "Update melange_tuples to exclude rows with unknown relations, or add missing relations to schema.fga",
+			FixHint:  fmt.Sprintf("Update %s to exclude rows with unknown relations, or add missing relations to schema.fga", tuplesTable),
 		})
 	}
 
@@ -875,7 +1146,7 @@ func emitTupleFindings(report *Report, f tupleFindings) {
 			Status:   StatusWarn,
 			Message:  fmt.Sprintf("Found %d unknown subject types affecting %d tuples", len(f.unknownSubjects), f.unknownSubjectCount),
 			Details:  truncatedJoin(f.unknownSubjects, 10),
-			FixHint:  "Update melange_tuples to exclude rows with unknown subject types, or add missing types to schema.fga",
+			FixHint:  fmt.Sprintf("Update %s to exclude rows with unknown subject types, or add missing types to schema.fga", tuplesTable),
 		})
 	}
 
@@ -891,6 +1162,18 @@ func emitTupleFindings(report *Report, f tupleFindings) {
 		})
 	}
 
+	if len(f.unmatchedSubjects) > 0 {
+		anyIssue = true
+		report.AddCheck(CheckResult{
+			Category: "Data Health",
+			Name:     "unmatched_subjects",
+			Status:   StatusWarn,
+			Message:  fmt.Sprintf("Found %d subject forms their relation does not accept, affecting %d tuples", len(f.unmatchedSubjects), f.unmatchedSubjectCount),
+			Details:  truncatedJoin(f.unmatchedSubjects, 10),
+			FixHint:  "No generated function matches these tuples: a type:* subject needs [type:*] and a type:id#rel subject needs [type#rel] in the relation definition in schema.fga",
+		})
+	}
+
 	if !anyIssue {
 		report.AddCheck(CheckResult{
 			Category: "Data Health",
@@ -901,6 +1184,184 @@ func emitTupleFindings(report *Report, f tupleFindings) {
 	}
 }
 
+// checkBaseline compares the installed functions against the committed
+// manifest at opts.BaselinePath.
+func (d *Doctor) checkBaseline(ctx context.Context, report *Report) error {
+	f, err := os.Open(d.opts.BaselinePath)
+	if err != nil {
+		report.AddCheck(CheckResult{
+			Category: "Baseline",
+			Name:     "manifest",
+			Status:   StatusFail,
+			Message:  fmt.Sprintf("Cannot read baseline manifest %s", d.opts.BaselinePath),
+			Details:  err.Error(),
+			FixHint:  "Run 'melange generate manifest --output " + d.opts.BaselinePath + "' and commit the result",
+		})
+		return nil
+	}
+	baseline, err := migrator.ReadManifest(f)
+	_ = f.Close()
+	if err != nil {
+		report.AddCheck(CheckResult{
+			Category: "Baseline",
+			Name:     "manifest",
+			Status:   StatusFail,
+			Message:  fmt.Sprintf("Baseline manifest %s is invalid", d.opts.BaselinePath),
+			Details:  err.Error(),
+			FixHint:  "Run 'melange generate manifest --output " + d.opts.BaselinePath + "' and commit the result",
+		})
+		return nil
+	}
+
+	m := migrator.NewMigrator(d.db, d.schemaPath)
+	m.SetDatabaseSchema(d.databaseSchema)
+	m.SetTuplesTable(d.tuplesTable)
+	installed, err := m.InstalledManifest(ctx)
+	if err != nil {
+		return err
+	}
+
+	emitBaselineDrift(report, d.opts.BaselinePath, len(baseline.Functions), migrator.CompareManifests(baseline, installed))
+	return nil
+}
+
+// emitBaselineDrift reports the drift between the database and a baseline
+// manifest of total functions.
+func emitBaselineDrift(report *Report, path string, total int, drift migrator.ManifestDrift) {
+	if drift.Clean() {
+		report.AddCheck(CheckResult{
+			Category: "Baseline",
+			Name:     "drift",
+			Status:   StatusPass,
+			Message:  fmt.Sprintf("Installed functions match %s (%d functions)", path, total),
+		})
+		return
+	}
+
+	var details []string
+	for _, group := range []struct {
+		label string
+		sigs  []string
+	}{
+		{"Added (not in baseline)", drift.Added},
+		{"Removed (not installed)", drift.Removed},
+		{"Changed (different body)", drift.Changed},
+	} {
+		if len(group.sigs) > 0 {
+			details = append(details, group.label+":\n  "+strings.Join(group.sigs, "\n  "))
+		}
+	}
+	report.AddCheck(CheckResult{
+		Category: "Baseline",
+		Name:     "drift",
+		Status:   StatusFail,
+		Message: fmt.Sprintf("Installed functions differ from %s: %d added, %d removed, %d changed",
+			path, len(drift.Added), len(drift.Removed), len(drift.Changed)),
+		Details: strings.Join(details, "\n"),
+		FixHint: "Run 'melange migrate' from this commit, or regenerate the manifest if the change is intended",
+	})
+}
+
+// emitMetadataDrift compares the metadata comment on each installed function
+// against the metadata the current schema analysis expects. A mismatch means
+// the function was generated from a different schema or melange version
+// (a stale deployment); a missing comment means it predates metadata
+// comments. Expected functions that are not installed are reported by the
+// missing check, not here.
+func emitMetadataDrift(report *Report, expected map[string]sqlgen.FunctionMetadata, comments map[string]string) {
+	var stale, unlabeled []string
+	for name, want := range expected {
+		comment, installed := comments[name]
+		if !installed {
+			continue
+		}
+		got, ok := sqlgen.ParseFunctionMetadata(comment)
+		switch {
+		case !ok:
+			unlabeled = append(unlabeled, name)
+		case !got.Equal(want):
+			stale = append(stale, fmt.Sprintf("%s: installed %s, expected %s",
+				name, describeMetadata(got), describeMetadata(want)))
+		}
+	}
+	sort.Strings(stale)
+	sort.Strings(unlabeled)
+
+	switch {
+	case len(stale) > 0:
+		report.AddCheck(CheckResult{
+			Category: "Generated Functions",
+			Name:     "metadata",
+			Status:   StatusWarn,
+			Message:  fmt.Sprintf("%d functions were generated from a different schema analysis", len(stale)),
+			Details:  truncatedJoin(stale, 10),
+			FixHint:  "Run 'melange migrate' to regenerate them",
+		})
+	case len(unlabeled) > 0:
+		report.AddCheck(CheckResult{
+			Category: "Generated Functions",
+			Name:     "metadata",
+			Status:   StatusWarn,
+			Message:  fmt.Sprintf("%d functions have no generation metadata", len(unlabeled)),
+			Details:  truncatedJoin(unlabeled, 10),
+			FixHint:  "Run 'melange migrate' to regenerate them with metadata comments",
+		})
+	default:
+		report.AddCheck(CheckResult{
+			Category: "Generated Functions",
+			Name:     "metadata",
+			Status:   StatusPass,
+			Message:  "Function metadata matches the schema analysis",
+		})
+	}
+}
+
+// describeMetadata summarizes md for a drift report.
+func describeMetadata(md sqlgen.FunctionMetadata) string {
+	desc := md.Features
+	if md.ListStrategy != "" {
+		desc += " (list " + md.ListStrategy + ")"
+	}
+	if len(md.ClosureRelations) > 0 {
+		desc += " via " + strings.Join(md.ClosureRelations, ",")
+	}
+	return desc
+}
+
+// getFunctionComments returns the comment on each melange-generated function,
+// keyed by name. Functions without a comment map to "".
+func (d *Doctor) getFunctionComments(ctx context.Context) (map[string]string, error) {
+	rows, err := d.db.QueryContext(ctx, fmt.Sprintf(
+		`
+			SELECT p.proname, COALESCE(obj_description(p.oid, 'pg_proc'), '')
+			FROM pg_proc p
+			JOIN pg_namespace n ON p.pronamespace = n.oid
+			WHERE n.nspname = %s
+			AND (
+				p.proname LIKE 'check_%%'
+				OR p.proname LIKE 'list_%%'
+				OR p.proname LIKE 'explain_%%'
+				OR p.proname LIKE 'expand_%%'
+			)
+		`,
+		d.postgresSchema(),
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	comments := make(map[string]string)
+	for rows.Next() {
+		var name, comment string
+		if err := rows.Scan(&name, &comment); err != nil {
+			return nil, err
+		}
+		comments[name] = comment
+	}
+	return comments, rows.Err()
+}
+
 // getCurrentFunctions returns all melange-generated function names.
 func (d *Doctor) getCurrentFunctions(ctx context.Context) ([]string, error) {
 	rows, err := d.db.QueryContext(ctx, fmt.Sprintf(
@@ -914,6 +1375,9 @@ func (d *Doctor) getCurrentFunctions(ctx context.Context) ([]string, error) {
 				OR p.proname LIKE 'list_%%'
 				OR p.proname LIKE 'explain_%%'
 				OR p.proname LIKE 'expand_%%'
+				OR p.proname = 'melange_model_relations'
+				OR p.proname = 'melange_depth_exceeded'
+				OR p.proname = 'melange_healthcheck'
 			)
 		`,
 		d.postgresSchema(),
@@ -934,9 +1398,10 @@ func (d *Doctor) getCurrentFunctions(ctx context.Context) ([]string, error) {
 	return functions, rows.Err()
 }
 
-// getTuplesInfo retrieves information about the melange_tuples relation.
+// getTuplesInfo retrieves information about the tuples relation.
 func (d *Doctor) getTuplesInfo(ctx context.Context) (*TuplesInfo, error) {
 	info := &TuplesInfo{RowCount: -1}
+	tuplesSchema, tuplesName, _ := d.tuplesRelation()
 
 	// Check if relation exists and get type
 	var relKind string
@@ -945,12 +1410,12 @@ func (d *Doctor) getTuplesInfo(ctx context.Context) (*TuplesInfo, error) {
 			SELECT c.relkind
 			FROM pg_class c
 			JOIN pg_namespace n ON n.oid = c.relnamespace
-			WHERE c.relname = 'melange_tuples'
+			WHERE c.relname = $1
 			AND n.nspname = %s
 			AND c.relkind IN ('r', 'v', 'm')
 		`,
-		d.postgresSchema(),
-	)).Scan(&relKind)
+		tuplesSchema,
+	), tuplesName).Scan(&relKind)
 
 	if err == sql.ErrNoRows {
 		return info, nil
@@ -973,29 +1438,31 @@ func (d *Doctor) getTuplesInfo(ctx context.Context) (*TuplesInfo, error) {
 	// Get columns
 	rows, err := d.db.QueryContext(ctx, fmt.Sprintf(
 		`
-			SELECT a.attname
+			SELECT a.attname, format_type(a.atttypid, a.atttypmod)
 			FROM pg_attribute a
 			JOIN pg_class c ON a.attrelid = c.oid
 			JOIN pg_namespace n ON c.relnamespace = n.oid
-			WHERE c.relname = 'melange_tuples'
+			WHERE c.relname = $1
 			AND n.nspname = %s
 			AND a.attnum > 0
 			AND NOT a.attisdropped
 			ORDER BY a.attnum
 		`,
-		d.postgresSchema(),
-	))
+		tuplesSchema,
+	), tuplesName)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = rows.Close() }()
 
+	info.ColumnTypes = make(map[string]string)
 	for rows.Next() {
-		var col string
-		if err := rows.Scan(&col); err != nil {
+		var col, typ string
+		if err := rows.Scan(&col, &typ); err != nil {
 			return nil, err
 		}
 		info.Columns = append(info.Columns, col)
+		info.ColumnTypes[col] = typ
 	}
 
 	return info, rows.Err()
@@ -1010,15 +1477,6 @@ func truncatedJoin(items []string, maxItems int) string {
 	return strings.Join(items[:maxItems], "\n") + fmt.Sprintf("\n... and %d more", len(items)-maxItems)
 }
 
-// readFileContent reads file content as string.
-func readFileContent(path string) (string, error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
-	}
-	return string(content), nil
-}
-
 func (d *Doctor) prefixIdent(identifier string) string {
 	return sqldsl.PrefixIdent(identifier, d.databaseSchema)
 }
@@ -1026,3 +1484,14 @@ func (d *Doctor) prefixIdent(identifier string) string {
 func (d *Doctor) postgresSchema() string {
 	return sqldsl.PostgresSchemaExpr(d.databaseSchema)
 }
+
+// tuplesRelation resolves the tuples relation to the SQL expression for its
+// schema name, its unqualified name, and the reference to use in queries. An
+// unqualified tuples table lives in the database schema.
+func (d *Doctor) tuplesRelation() (schemaExpr, name, ref string) {
+	schema, name := sqlgen.SplitTuplesTable(d.tuplesTable)
+	if schema == "" {
+		return d.postgresSchema(), name, d.prefixIdent(name)
+	}
+	return sqldsl.QuoteLiteral(schema), name, sqldsl.PrefixIdent(name, schema)
+}
@@ -1,10 +1,14 @@
 package doctor
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/pkg/migrator"
 )
 
 // TestGetAnalyses_CachesAndHandlesNilParsedTypes covers both the nil-input
@@ -39,9 +43,9 @@ func TestClassifyTupleSignatures(t *testing.T) {
 
 	t.Run("all valid", func(t *testing.T) {
 		sigs := []tupleSignature{
-			{"organization", "member", "user", 100},
-			{"repository", "admin", "user", 50},
-			{"repository", "org", "organization", 30},
+			{objectType: "organization", relation: "member", subjectType: "user", count: 100},
+			{objectType: "repository", relation: "admin", subjectType: "user", count: 50},
+			{objectType: "repository", relation: "org", subjectType: "organization", count: 30},
 		}
 		f := classifyTupleSignatures(sigs, validTypes, validRelations, allowedSubjects)
 		assert.Empty(t, f.unknownObjectTypes)
@@ -52,8 +56,8 @@ func TestClassifyTupleSignatures(t *testing.T) {
 
 	t.Run("unknown object type", func(t *testing.T) {
 		sigs := []tupleSignature{
-			{"organization", "member", "user", 100},
-			{"widget", "viewer", "user", 42},
+			{objectType: "organization", relation: "member", subjectType: "user", count: 100},
+			{objectType: "widget", relation: "viewer", subjectType: "user", count: 42},
 		}
 		f := classifyTupleSignatures(sigs, validTypes, validRelations, allowedSubjects)
 		assert.Len(t, f.unknownObjectTypes, 1)
@@ -64,8 +68,8 @@ func TestClassifyTupleSignatures(t *testing.T) {
 
 	t.Run("unknown relation", func(t *testing.T) {
 		sigs := []tupleSignature{
-			{"organization", "member", "user", 100},
-			{"organization", "billing", "user", 15},
+			{objectType: "organization", relation: "member", subjectType: "user", count: 100},
+			{objectType: "organization", relation: "billing", subjectType: "user", count: 15},
 		}
 		f := classifyTupleSignatures(sigs, validTypes, validRelations, allowedSubjects)
 		assert.Empty(t, f.unknownObjectTypes)
@@ -76,7 +80,7 @@ func TestClassifyTupleSignatures(t *testing.T) {
 
 	t.Run("unknown subject type", func(t *testing.T) {
 		sigs := []tupleSignature{
-			{"organization", "member", "device", 30},
+			{objectType: "organization", relation: "member", subjectType: "device", count: 30},
 		}
 		f := classifyTupleSignatures(sigs, validTypes, validRelations, allowedSubjects)
 		assert.Empty(t, f.unknownObjectTypes)
@@ -88,7 +92,7 @@ func TestClassifyTupleSignatures(t *testing.T) {
 
 	t.Run("invalid subject type for relation", func(t *testing.T) {
 		sigs := []tupleSignature{
-			{"repository", "admin", "organization", 5},
+			{objectType: "repository", relation: "admin", subjectType: "organization", count: 5},
 		}
 		f := classifyTupleSignatures(sigs, validTypes, validRelations, allowedSubjects)
 		assert.Empty(t, f.unknownObjectTypes)
@@ -102,8 +106,8 @@ func TestClassifyTupleSignatures(t *testing.T) {
 
 	t.Run("counts aggregate across signatures", func(t *testing.T) {
 		sigs := []tupleSignature{
-			{"widget", "viewer", "user", 10},
-			{"widget", "editor", "user", 20},
+			{objectType: "widget", relation: "viewer", subjectType: "user", count: 10},
+			{objectType: "widget", relation: "editor", subjectType: "user", count: 20},
 		}
 		f := classifyTupleSignatures(sigs, validTypes, validRelations, allowedSubjects)
 		assert.Len(t, f.unknownObjectTypes, 1)
@@ -111,12 +115,42 @@ func TestClassifyTupleSignatures(t *testing.T) {
 		assert.Contains(t, f.unknownObjectTypes[0], "30")
 	})
 
+	t.Run("subject forms the relation does not accept", func(t *testing.T) {
+		allowed := map[string]map[string]map[string]bool{
+			"repository": {"reader": {"user": true, "organization#member": true}},
+		}
+		sigs := []tupleSignature{
+			{objectType: "repository", relation: "reader", subjectType: "user", count: 100},
+			{objectType: "repository", relation: "reader", subjectType: "organization", subjectRelation: "member", count: 20},
+			{objectType: "repository", relation: "reader", subjectType: "user", wildcard: true, count: 2, example: "repository:1#reader@user:*"},
+			{objectType: "repository", relation: "reader", subjectType: "organization", subjectRelation: "owner", count: 3},
+			{objectType: "repository", relation: "reader", subjectType: "organization", count: 4},
+		}
+		f := classifyTupleSignatures(sigs, validTypes, validRelations, allowed)
+		assert.Empty(t, f.invalidSubjects)
+		assert.Equal(t, []string{
+			"repository:reader subject=organization (4 tuples)",
+			"repository:reader subject=organization#owner (3 tuples)",
+			"repository:reader subject=user:* (2 tuples, e.g. repository:1#reader@user:*)",
+		}, f.unmatchedSubjects)
+		assert.Equal(t, int64(9), f.unmatchedSubjectCount)
+	})
+
+	t.Run("details carry an example tuple", func(t *testing.T) {
+		sigs := []tupleSignature{
+			{objectType: "widget", relation: "viewer", subjectType: "user", count: 10, example: "widget:7#viewer@user:alice"},
+			{objectType: "widget", relation: "editor", subjectType: "user", count: 5, example: "widget:8#editor@user:bob"},
+		}
+		f := classifyTupleSignatures(sigs, validTypes, validRelations, allowedSubjects)
+		assert.Equal(t, []string{"widget (15 tuples, e.g. widget:7#viewer@user:alice)"}, f.unknownObjectTypes)
+	})
+
 	t.Run("multiple categories at once", func(t *testing.T) {
 		sigs := []tupleSignature{
-			{"gadget", "view", "user", 5},              // unknown object type
-			{"organization", "billing", "user", 10},    // unknown relation
-			{"organization", "member", "device", 3},    // unknown subject type
-			{"repository", "admin", "organization", 2}, // invalid subject type
+			{objectType: "gadget", relation: "view", subjectType: "user", count: 5},              // unknown object type
+			{objectType: "organization", relation: "billing", subjectType: "user", count: 10},    // unknown relation
+			{objectType: "organization", relation: "member", subjectType: "device", count: 3},    // unknown subject type
+			{objectType: "repository", relation: "admin", subjectType: "organization", count: 2}, // invalid subject type
 		}
 		f := classifyTupleSignatures(sigs, validTypes, validRelations, allowedSubjects)
 		assert.Len(t, f.unknownObjectTypes, 1)
@@ -129,7 +163,7 @@ func TestClassifyTupleSignatures(t *testing.T) {
 func TestEmitTupleFindings(t *testing.T) {
 	t.Run("all clean", func(t *testing.T) {
 		report := &Report{}
-		emitTupleFindings(report, tupleFindings{})
+		emitTupleFindings(report, "melange_tuples", tupleFindings{})
 		require.Len(t, report.Checks, 1)
 		assert.Equal(t, StatusPass, report.Checks[0].Status)
 		assert.Equal(t, "valid", report.Checks[0].Name)
@@ -137,7 +171,7 @@ func TestEmitTupleFindings(t *testing.T) {
 
 	t.Run("unknown object types only", func(t *testing.T) {
 		report := &Report{}
-		emitTupleFindings(report, tupleFindings{
+		emitTupleFindings(report, "melange_tuples", tupleFindings{
 			unknownObjectTypes:     []string{"widget (10 tuples)"},
 			unknownObjectTypeCount: 10,
 		})
@@ -150,7 +184,7 @@ func TestEmitTupleFindings(t *testing.T) {
 
 	t.Run("unknown relations only", func(t *testing.T) {
 		report := &Report{}
-		emitTupleFindings(report, tupleFindings{
+		emitTupleFindings(report, "melange_tuples", tupleFindings{
 			unknownRelations:     []string{"org:billing (5 tuples)"},
 			unknownRelationCount: 5,
 		})
@@ -160,7 +194,7 @@ func TestEmitTupleFindings(t *testing.T) {
 
 	t.Run("unknown subject types only", func(t *testing.T) {
 		report := &Report{}
-		emitTupleFindings(report, tupleFindings{
+		emitTupleFindings(report, "melange_tuples", tupleFindings{
 			unknownSubjects:     []string{"device (3 tuples)"},
 			unknownSubjectCount: 3,
 		})
@@ -170,7 +204,7 @@ func TestEmitTupleFindings(t *testing.T) {
 
 	t.Run("invalid subject types only", func(t *testing.T) {
 		report := &Report{}
-		emitTupleFindings(report, tupleFindings{
+		emitTupleFindings(report, "melange_tuples", tupleFindings{
 			invalidSubjects:     []string{"repo:admin subject_type=org (2 tuples)"},
 			invalidSubjectCount: 2,
 		})
@@ -178,9 +212,20 @@ func TestEmitTupleFindings(t *testing.T) {
 		assert.Equal(t, "invalid_subject_types", report.Checks[0].Name)
 	})
 
+	t.Run("unmatched subjects only", func(t *testing.T) {
+		report := &Report{}
+		emitTupleFindings(report, "melange_tuples", tupleFindings{
+			unmatchedSubjects:     []string{"repo:reader subject=user:* (2 tuples)"},
+			unmatchedSubjectCount: 2,
+		})
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, "unmatched_subjects", report.Checks[0].Name)
+		assert.Equal(t, StatusWarn, report.Checks[0].Status)
+	})
+
 	t.Run("all categories at once", func(t *testing.T) {
 		report := &Report{}
-		emitTupleFindings(report, tupleFindings{
+		emitTupleFindings(report, "melange_tuples", tupleFindings{
 			unknownObjectTypes:     []string{"a (1 tuples)"},
 			unknownObjectTypeCount: 1,
 			unknownRelations:       []string{"b:c (2 tuples)"},
@@ -189,10 +234,123 @@ func TestEmitTupleFindings(t *testing.T) {
 			unknownSubjectCount:    3,
 			invalidSubjects:        []string{"e:f subject_type=g (4 tuples)"},
 			invalidSubjectCount:    4,
+			unmatchedSubjects:      []string{"h:i subject=j:* (5 tuples)"},
+			unmatchedSubjectCount:  5,
 		})
-		assert.Len(t, report.Checks, 4, "should emit one check per category")
+		assert.Len(t, report.Checks, 5, "should emit one check per category")
 		assert.Equal(t, 0, report.Errors)
-		assert.Equal(t, 4, report.Warnings)
+		assert.Equal(t, 5, report.Warnings)
+	})
+}
+
+func TestEmitBaselineDrift(t *testing.T) {
+	t.Run("clean", func(t *testing.T) {
+		report := &Report{}
+		emitBaselineDrift(report, "manifest.json", 12, migrator.ManifestDrift{})
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, StatusPass, report.Checks[0].Status)
+		assert.Contains(t, report.Checks[0].Message, "(12 functions)")
+	})
+
+	t.Run("drift fails", func(t *testing.T) {
+		report := &Report{}
+		emitBaselineDrift(report, "manifest.json", 12, migrator.ManifestDrift{
+			Added:   []string{"check_doc_legacy(text, text, text, text[])"},
+			Changed: []string{"check_doc_owner(text, text, text, text[])", "check_permission(text, text, text, text, text)"},
+		})
+		require.Len(t, report.Checks, 1)
+		check := report.Checks[0]
+		assert.Equal(t, StatusFail, check.Status)
+		assert.True(t, report.HasErrors())
+		assert.Contains(t, check.Message, "1 added, 0 removed, 2 changed")
+		assert.Contains(t, check.Details, "Added (not in baseline):\n  check_doc_legacy(text, text, text, text[])")
+		assert.Contains(t, check.Details, "check_doc_owner(text, text, text, text[])\n  check_permission(")
+		assert.NotContains(t, check.Details, "Removed")
+	})
+}
+
+func TestClassifyTuplesColumnType(t *testing.T) {
+	cases := map[string]tuplesColumnTypeClass{
+		"text":                   columnTypeText,
+		"character varying":      columnTypeText,
+		"character varying(255)": columnTypeText,
+		"citext":                 columnTypeCoerced,
+		"character(36)":          columnTypeCoerced,
+		"uuid":                   columnTypeIncompatible,
+		"bigint":                 columnTypeIncompatible,
+	}
+	for typ, want := range cases {
+		assert.Equal(t, want, classifyTuplesColumnType(typ), typ)
+	}
+}
+
+func TestCheckTuplesColumnTypes(t *testing.T) {
+	textCols := func() map[string]string {
+		return map[string]string{
+			"object_type":  "text",
+			"object_id":    "character varying(64)",
+			"relation":     "text",
+			"subject_type": "text",
+			"subject_id":   "text",
+		}
+	}
+
+	t.Run("text and varchar pass", func(t *testing.T) {
+		report := &Report{}
+		checkTuplesColumnTypes(report, &TuplesInfo{ColumnTypes: textCols()})
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, StatusPass, report.Checks[0].Status)
+	})
+
+	t.Run("citext warns", func(t *testing.T) {
+		cols := textCols()
+		cols["subject_id"] = "citext"
+		report := &Report{}
+		checkTuplesColumnTypes(report, &TuplesInfo{ColumnTypes: cols})
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, StatusWarn, report.Checks[0].Status)
+		assert.Contains(t, report.Checks[0].Details, "subject_id: citext")
+	})
+
+	t.Run("uuid fails", func(t *testing.T) {
+		cols := textCols()
+		cols["object_id"] = "uuid"
+		cols["subject_id"] = "citext"
+		report := &Report{}
+		checkTuplesColumnTypes(report, &TuplesInfo{ColumnTypes: cols})
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, StatusFail, report.Checks[0].Status)
+		assert.Contains(t, report.Checks[0].Details, "object_id: uuid")
+		assert.Contains(t, report.Checks[0].Details, "subject_id: citext")
+	})
+}
+
+func TestReportWriteJSON(t *testing.T) {
+	t.Run("checks and summary", func(t *testing.T) {
+		var report Report
+		report.AddCheck(CheckResult{Category: "Schema", Name: "schema_valid", Status: StatusPass, Message: "Schema is valid"})
+		report.AddCheck(CheckResult{
+			Category: "Functions", Name: "functions_current", Status: StatusFail,
+			Message: "2 functions missing", Details: "check_doc_viewer", FixHint: "Run 'melange migrate'",
+		})
+
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteJSON(&buf))
+		assert.JSONEq(t, `{
+			"checks": [
+				{"category": "Schema", "name": "schema_valid", "severity": "pass", "message": "Schema is valid"},
+				{"category": "Functions", "name": "functions_current", "severity": "fail", "message": "2 functions missing",
+				 "details": "check_doc_viewer", "fix_hint": "Run 'melange migrate'"}
+			],
+			"passed": 1, "warnings": 0, "errors": 1
+		}`, buf.String())
+		assert.True(t, report.HasErrors())
+	})
+
+	t.Run("empty report lists no checks", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, (&Report{}).WriteJSON(&buf))
+		assert.JSONEq(t, `{"checks": [], "passed": 0, "warnings": 0, "errors": 0}`, buf.String())
 	})
 }
 
@@ -204,3 +362,46 @@ func TestTruncatedJoin(t *testing.T) {
 	assert.Equal(t, "a\nb\nc\nd\ne", truncatedJoin(items, 10), "over count returns all")
 	assert.Equal(t, "a\nb\n... and 3 more", truncatedJoin(items, 2), "truncates with summary")
 }
+
+func TestEmitMetadataDrift(t *testing.T) {
+	viewer := sqlgen.FunctionMetadata{
+		Kind: sqlgen.FunctionKindCheck, ObjectType: "doc", Relation: "viewer",
+		Features: "Direct+Implied", ListStrategy: "Direct", ClosureRelations: []string{"viewer", "owner"},
+	}
+	owner := sqlgen.FunctionMetadata{Kind: sqlgen.FunctionKindCheck, ObjectType: "doc", Relation: "owner", Features: "Direct"}
+	expected := map[string]sqlgen.FunctionMetadata{"check_doc_viewer": viewer, "check_doc_owner": owner}
+
+	t.Run("matching", func(t *testing.T) {
+		report := &Report{}
+		emitMetadataDrift(report, expected, map[string]string{
+			"check_doc_viewer": viewer.Comment(),
+			"check_doc_owner":  owner.Comment(),
+		})
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, StatusPass, report.Checks[0].Status)
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		old := viewer
+		old.Features = "Direct"
+		old.ClosureRelations = []string{"viewer"}
+		report := &Report{}
+		emitMetadataDrift(report, expected, map[string]string{
+			"check_doc_viewer": old.Comment(),
+			"check_doc_owner":  owner.Comment(),
+		})
+		require.Len(t, report.Checks, 1)
+		check := report.Checks[0]
+		assert.Equal(t, StatusWarn, check.Status)
+		assert.Contains(t, check.Message, "1 functions")
+		assert.Equal(t, "check_doc_viewer: installed Direct (list Direct) via viewer, expected Direct+Implied (list Direct) via viewer,owner", check.Details)
+	})
+
+	t.Run("missing comments and functions", func(t *testing.T) {
+		report := &Report{}
+		emitMetadataDrift(report, expected, map[string]string{"check_doc_viewer": ""})
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, StatusWarn, report.Checks[0].Status)
+		assert.Equal(t, "check_doc_viewer", report.Checks[0].Details, "uninstalled check_doc_owner is left to the missing check")
+	})
+}
@@ -10,12 +10,13 @@ import (
 	"github.com/pthm/melange/lib/sqlgen/sqldsl"
 )
 
-// checkViewDefinition parses the melange_tuples view and emits checks for
+// checkViewDefinition parses the tuples view and emits checks for
 // view structure, UNION ALL usage, and discovered source tables.
 func (d *Doctor) checkViewDefinition(ctx context.Context, report *Report) error { //nolint:unparam // error return kept for consistent checker interface
 	var viewSQL string
+	_, _, tuplesRef := d.tuplesRelation()
 	err := d.db.QueryRowContext(ctx,
-		fmt.Sprintf(`SELECT pg_get_viewdef(%s::regclass, true)`, sqldsl.QuoteLiteral(d.prefixIdent("melange_tuples"))),
+		fmt.Sprintf(`SELECT pg_get_viewdef(%s::regclass, true)`, sqldsl.QuoteLiteral(tuplesRef)),
 	).Scan(&viewSQL)
 	if err != nil {
 		report.AddCheck(CheckResult{
@@ -127,13 +128,13 @@ func (d *Doctor) checkExpressionIndexes(ctx context.Context, report *Report) err
 
 	for table, info := range tableCasts {
 		// Get all index definitions for this table
-		indexDefs, err := d.getTableIndexDefs(ctx, table)
+		indexDefs, err := d.getTableIndexDefs(ctx, d.postgresSchema(), table)
 		if err != nil {
 			return fmt.Errorf("getting indexes for %s: %w", table, err)
 		}
 
 		// Get row count for severity
-		rowCount, err := d.getTableRowCount(ctx, table)
+		rowCount, err := d.getTableRowCount(ctx, d.postgresSchema(), table)
 		if err != nil {
 			// Non-fatal: default to warning severity
 			rowCount = 0
@@ -184,14 +185,15 @@ func (d *Doctor) checkExpressionIndexes(ctx context.Context, report *Report) err
 	return nil
 }
 
-// getTableIndexDefs returns all index definitions for a table.
-func (d *Doctor) getTableIndexDefs(ctx context.Context, table string) ([]string, error) {
+// getTableIndexDefs returns all index definitions for a table in the schema
+// schemaExpr evaluates to.
+func (d *Doctor) getTableIndexDefs(ctx context.Context, schemaExpr, table string) ([]string, error) {
 	rows, err := d.db.QueryContext(ctx, fmt.Sprintf(`
 		SELECT indexdef
 		FROM pg_indexes
 		WHERE schemaname = %s
 		  AND tablename = $1
-	`, d.postgresSchema()), table)
+	`, schemaExpr), table)
 	if err != nil {
 		return nil, err
 	}
@@ -208,15 +210,16 @@ func (d *Doctor) getTableIndexDefs(ctx context.Context, table string) ([]string,
 	return defs, rows.Err()
 }
 
-// getTableRowCount returns the approximate row count from pg_class.reltuples.
-func (d *Doctor) getTableRowCount(ctx context.Context, table string) (int64, error) {
+// getTableRowCount returns the approximate row count from pg_class.reltuples
+// for a table in the schema schemaExpr evaluates to.
+func (d *Doctor) getTableRowCount(ctx context.Context, schemaExpr, table string) (int64, error) {
 	var count float64
 	err := d.db.QueryRowContext(ctx, fmt.Sprintf(`
 		SELECT COALESCE(reltuples, 0)::float8
 		FROM pg_class
 		WHERE relname = $1
 		  AND relnamespace = (SELECT oid FROM pg_namespace WHERE nspname = %s)
-	`, d.postgresSchema()), table).Scan(&count)
+	`, schemaExpr), table).Scan(&count)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return 0, nil
@@ -260,10 +263,10 @@ type existingIndex struct {
 	predicate string // empty if not a partial index
 }
 
-// checkTableIndexes verifies that melange_tuples has indexes covering the
+// checkTableIndexes verifies that the tuples table has indexes covering the
 // access patterns generated by melange. The recommendations come from
-// sqlgen.RecommendIndexes() so they always match the user's actual schema
-// rather than a hardcoded subset.
+// sqlgen.RecommendIndexesWithTable() so they always match the user's actual
+// schema rather than a hardcoded subset.
 func (d *Doctor) checkTableIndexes(ctx context.Context, report *Report) error { //nolint:unparam // error return kept for consistent checker interface
 	analyses := d.getAnalyses()
 	if analyses == nil {
@@ -271,25 +274,26 @@ func (d *Doctor) checkTableIndexes(ctx context.Context, report *Report) error {
 		// file check has already reported the underlying problem.
 		return nil
 	}
-	recs := sqlgen.RecommendIndexes(analyses)
+	recs := sqlgen.RecommendIndexesWithTable(analyses, d.tuplesTable)
 	if len(recs) == 0 {
 		return nil
 	}
 
-	indexDefs, err := d.getTableIndexDefs(ctx, "melange_tuples")
+	tuplesSchema, tuplesName, _ := d.tuplesRelation()
+	indexDefs, err := d.getTableIndexDefs(ctx, tuplesSchema, tuplesName)
 	if err != nil {
 		report.AddCheck(CheckResult{
 			Category: "Performance",
 			Name:     "table_indexes",
 			Status:   StatusWarn,
-			Message:  "Could not retrieve index information for melange_tuples",
+			Message:  fmt.Sprintf("Could not retrieve index information for %s", d.TuplesTable()),
 			Details:  err.Error(),
 		})
 		return nil
 	}
 
 	existing := parseExistingIndexes(indexDefs)
-	rowCount, _ := d.getTableRowCount(ctx, "melange_tuples")
+	rowCount, _ := d.getTableRowCount(ctx, tuplesSchema, tuplesName)
 
 	allCovered := true
 	for _, rec := range recs {
@@ -314,7 +318,7 @@ func (d *Doctor) checkTableIndexes(ctx context.Context, report *Report) error {
 			Category: "Performance",
 			Name:     "table_indexes",
 			Status:   StatusPass,
-			Message:  fmt.Sprintf("melange_tuples has all %d recommended indexes", len(recs)),
+			Message:  fmt.Sprintf("%s has all %d recommended indexes", d.TuplesTable(), len(recs)),
 		})
 	}
 
@@ -390,7 +394,7 @@ func (d *Doctor) checkExpandFanoutAdvisory(_ context.Context, report *Report) er
 }
 
 // checkSourceTableIndexAdvisory emits the schema-derived index recommendations
-// as advisory output when melange_tuples is a view. PostgreSQL won't allow
+// as advisory output when the tuples relation is a view. PostgreSQL won't allow
 // CREATE INDEX directly on a view, so the user must translate the DDL to the
 // source tables that back the view's UNION ALL branches — but they need to
 // know which indexes to create, and this is the most discoverable place.
@@ -403,18 +407,19 @@ func (d *Doctor) checkSourceTableIndexAdvisory(_ context.Context, report *Report
 	if analyses == nil {
 		return nil
 	}
-	recs := sqlgen.RecommendIndexes(analyses)
+	recs := sqlgen.RecommendIndexesWithTable(analyses, d.tuplesTable)
 	if len(recs) == 0 {
 		return nil
 	}
 
 	var details strings.Builder
-	details.WriteString("Apply these to whichever source table(s) back the melange_tuples view. ")
-	details.WriteString("Replace 'melange_tuples' with the actual table name:\n\n")
+	fmt.Fprintf(&details, "Apply these to whichever source table(s) back the %s view. ", d.TuplesTable())
+	fmt.Fprintf(&details, "Replace '%s' with the actual table name:\n\n", d.TuplesTable())
 	for i, rec := range recs {
 		if i > 0 {
 			details.WriteByte('\n')
 		}
+		fmt.Fprintf(&details, "-- Benefits: %s\n", strings.Join(rec.BenefitsFunctions, ", "))
 		details.WriteString(rec.DDL)
 	}
 
@@ -245,6 +245,9 @@ func TestPredicatesEquivalent(t *testing.T) {
 	assert.True(t, predicatesEquivalent("SUBJECT_ID = '*'", "subject_id = '*'"))
 	assert.False(t, predicatesEquivalent("subject_id = 'x'", "subject_id = '*'"))
 	assert.False(t, predicatesEquivalent("", "subject_id = '*'"))
+
+	// The userset predicate round-trips with PG's extra parens and casts.
+	assert.True(t, predicatesEquivalent("(POSITION(('#'::text) IN (subject_id)) > 0)", "position('#' in subject_id) > 0"))
 }
 
 func TestParseExistingIndexes(t *testing.T) {
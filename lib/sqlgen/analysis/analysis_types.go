@@ -4,6 +4,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
 	"github.com/pthm/melange/pkg/schema"
 )
 
@@ -33,6 +34,7 @@ type RelationFeatures struct {
 	HasRecursive    bool // viewer from parent - grants inherited through parent/child relationships (TTU)
 	HasExclusion    bool // but not blocked - denies access based on negative conditions
 	HasIntersection bool // writer and editor - requires AND of all parts
+	HasCondition    bool // [user with non_expired] - direct grants that hold only when a condition does
 }
 
 // CanGenerate returns true if we can generate specialized SQL for this feature set.
@@ -49,8 +51,9 @@ func (f RelationFeatures) CanGenerate() bool {
 	// Note: All patterns are now supported. Complex cases (cross-type TTU, deep usersets)
 	// use check_permission_internal to delegate to the appropriate handler.
 
-	// Must have at least one access path
-	return f.HasDirect || f.HasImplied || f.HasUserset || f.HasRecursive || f.HasIntersection
+	// Must have at least one access path. A conditional grant is one, though
+	// only check_permission_with_context evaluates it.
+	return f.HasDirect || f.HasImplied || f.HasUserset || f.HasRecursive || f.HasIntersection || f.HasCondition
 }
 
 // IsSimplyResolvable returns true if this relation can be fully resolved
@@ -117,6 +120,9 @@ func (f RelationFeatures) String() string {
 	if f.HasIntersection {
 		parts = append(parts, "Intersection")
 	}
+	if f.HasCondition {
+		parts = append(parts, "Condition")
+	}
 	if len(parts) == 0 {
 		return "None"
 	}
@@ -303,14 +309,59 @@ type RelationAnalysis struct {
 	// function needs PL/pgSQL with cycle detection.
 	HasComplexUsersetPatterns bool
 
-	// Direct subject types (for generating direct tuple checks)
+	// Direct subject types (for generating direct tuple checks). Subject types
+	// granted under a condition are excluded; see Conditions.
 	DirectSubjectTypes []string // e.g., ["user", "org"]
 
+	// WildcardSubjectTypes lists the direct subject types that allow a
+	// wildcard grant: "user" for [user:*, service]. Features.HasWildcard is a
+	// single flag for the whole relation; this keeps which types it covers.
+	WildcardSubjectTypes []string
+
+	// Conditions lists the conditional direct grants, one per subject type,
+	// as in "viewer: [user with non_expired]". The plain check function
+	// ignores these grants; the check_{type}_{relation}_ctx variant allows
+	// them when the condition holds against the request context.
+	Conditions []ConditionInfo
+
+	// conditionReason explains why the relation's conditions cannot be
+	// evaluated, if they cannot. ComputeCanGenerate turns it into
+	// Capabilities.CheckReason.
+	conditionReason string
+
+	// cycleReason names the loop of purely implied relations that leaves
+	// this relation without a base, if one does. ComputeCanGenerate turns it
+	// into the capability reasons. See markBaselessImpliedCycles.
+	cycleReason string
+
+	// linkingReason explains why a computed linking relation of one of the
+	// relation's tuple-to-usersets cannot be resolved to the relations its
+	// tuples are stored under, if it cannot. ComputeCanGenerate turns it
+	// into the capability reasons. See resolveComputedLinkingRelations.
+	linkingReason string
+
+	// rewriteReason is the parser's CannotGenerateReason: the rewrite has a
+	// shape RelationDefinition cannot represent faithfully.
+	// ComputeCanGenerate turns it into the capability reasons.
+	rewriteReason string
+
+	// listIneligibility is every check computeCanGenerateList found failing,
+	// in the order it runs them; the first is Capabilities.ListReason. See
+	// ExplainListEligibility.
+	listIneligibility []string
+
 	// AllowedSubjectTypes is the union of all subject types from satisfying relations.
 	// This is used to enforce type restrictions in generated SQL.
 	// Computed by ComputeCanGenerate.
 	AllowedSubjectTypes []string
 
+	// ClosureWildcardSubjectTypes is the union of WildcardSubjectTypes over
+	// the satisfying relations: the subject types whose '*' tuples grant this
+	// relation through a direct lookup. Other allowed types match only their
+	// exact subject_id.
+	// Computed by ComputeCanGenerate.
+	ClosureWildcardSubjectTypes []string
+
 	// SimpleClosureRelations contains relations in the closure that can use tuple lookup.
 	// These are relations without exclusions, usersets, recursion, or intersections.
 	// Computed by ComputeCanGenerate.
@@ -357,11 +408,12 @@ type RelationAnalysis struct {
 
 	// MaxUsersetDepth is the maximum userset chain depth reachable from this relation.
 	// -1 means infinite (self-referential userset cycle), 0 means no userset patterns.
-	// Values >= 25 indicate the relation will always exceed the depth limit.
+	// Values >= the depth limit (DefaultMaxDepth unless overridden) indicate
+	// the relation will always exceed it.
 	// Computed by ComputeCanGenerate via computeMaxUsersetDepth.
 	MaxUsersetDepth int
 
-	// ExceedsDepthLimit is true if MaxUsersetDepth >= 25.
+	// ExceedsDepthLimit is true if MaxUsersetDepth reaches the depth limit.
 	// These relations generate functions that immediately raise M2002.
 	ExceedsDepthLimit bool
 
@@ -375,6 +427,13 @@ type RelationAnalysis struct {
 	// HasSelfReferentialUserset is true if len(SelfReferentialUsersets) > 0.
 	// When true, the list templates use recursive CTEs to expand the userset chain.
 	HasSelfReferentialUserset bool
+
+	// NameCollisions lists the relations of the schema whose function names
+	// carry a hash because they fold to the same identifier as another
+	// relation's (see FindNameCollisions). Every analysis of a schema shares
+	// it, since a function calls other relations' functions by name. Nil when
+	// no names collide. Set by AnalyzeRelations.
+	NameCollisions sqldsl.NameCollisions
 }
 
 // AnalyzeRelations classifies all relations and gathers data needed for SQL generation.
@@ -389,13 +448,17 @@ func AnalyzeRelations(types []TypeDefinition, closure []ClosureRow) []RelationAn
 		totalRelations += len(t.Relations)
 	}
 
+	collisions := nameCollisionIndex(types)
 	results := make([]RelationAnalysis, 0, totalRelations)
 	for _, t := range types {
 		for _, r := range t.Relations {
 			analysis := analyzeRelation(t, r, closureLookup)
+			analysis.NameCollisions = collisions
 			results = append(results, analysis)
 		}
 	}
+	markBaselessImpliedCycles(results)
+	resolveComputedLinkingRelations(results)
 	return results
 }
 
@@ -422,8 +485,9 @@ func analyzeRelation(
 	closureLookup map[string]map[string][]string,
 ) RelationAnalysis {
 	analysis := RelationAnalysis{
-		ObjectType: t.Name,
-		Relation:   r.Name,
+		ObjectType:    t.Name,
+		Relation:      r.Name,
+		rewriteReason: r.CannotGenerateReason,
 	}
 
 	// Gather satisfying relations from closure
@@ -462,6 +526,10 @@ func analyzeRelation(
 
 	// Collect direct subject types
 	analysis.DirectSubjectTypes = collectDirectSubjectTypes(r)
+	analysis.WildcardSubjectTypes = collectWildcardSubjectTypes(r)
+
+	// Collect conditional direct grants
+	analysis.Conditions, analysis.conditionReason = collectConditions(r)
 
 	// Build feature flags
 	analysis.Features = detectFeatures(r, analysis)
@@ -480,6 +548,7 @@ func detectFeatures(r RelationDefinition, analysis RelationAnalysis) RelationFea
 		HasRecursive:    hasTTUPatterns(analysis),
 		HasExclusion:    len(analysis.ExcludedRelations) > 0 || len(r.ExcludedParentRelations) > 0 || len(r.ExcludedIntersectionGroups) > 0,
 		HasIntersection: len(r.IntersectionGroups) > 0,
+		HasCondition:    len(analysis.Conditions) > 0,
 	}
 }
 
@@ -506,7 +575,7 @@ func collectDirectSubjectTypes(r RelationDefinition) []string {
 
 	// From SubjectTypeRefs (preferred)
 	for _, ref := range r.SubjectTypeRefs {
-		if ref.Relation == "" && !seen[ref.Type] {
+		if ref.Relation == "" && ref.Condition == nil && !seen[ref.Type] {
 			// Direct reference (not a userset like group#member)
 			types = append(types, ref.Type)
 			seen[ref.Type] = true
@@ -516,6 +585,20 @@ func collectDirectSubjectTypes(r RelationDefinition) []string {
 	return types
 }
 
+// collectWildcardSubjectTypes extracts the subject types referenced with a
+// wildcard ([user:*]).
+func collectWildcardSubjectTypes(r RelationDefinition) []string {
+	var types []string
+	seen := make(map[string]bool)
+	for _, ref := range r.SubjectTypeRefs {
+		if ref.Wildcard && ref.Relation == "" && !seen[ref.Type] {
+			types = append(types, ref.Type)
+			seen[ref.Type] = true
+		}
+	}
+	return types
+}
+
 // hasWildcardRefs checks if any subject type reference allows wildcards.
 func hasWildcardRefs(r RelationDefinition) bool {
 	for _, ref := range r.SubjectTypeRefs {
@@ -679,20 +762,9 @@ func BuildAnalysisLookup(analyses []RelationAnalysis) map[string]map[string]*Rel
 	return lookup
 }
 
-// sortByDependency performs topological sort on analyses based on all dependencies.
-// Returns sorted analyses where each relation is processed after its dependencies.
-// This ensures that when we check if a relation CanGenerate, all relations it depends on
-// have already been evaluated.
-//
-// Dependencies include:
-// - SatisfyingRelations (closure): implied-by relationships
-// - IntersectionGroups: relations referenced in AND groups
-// - ExcludedRelations: relations in "but not" clauses
-func sortByDependency(analyses []RelationAnalysis) []RelationAnalysis {
-	// Build lookup map for finding analyses during dependency tracking
-	lookup := BuildAnalysisLookup(analyses)
-
-	// Build dependency graph: relation -> relations it depends on
+// dependencyGraph maps each relation, keyed "type.relation", to the relations
+// it depends on. See sortByDependency for which references count.
+func dependencyGraph(analyses []RelationAnalysis, lookup map[string]map[string]*RelationAnalysis) map[string][]string {
 	deps := make(map[string][]string)        // key: "type.relation"
 	seen := make(map[string]map[string]bool) // Deduplicate dependencies
 
@@ -765,6 +837,62 @@ func sortByDependency(analyses []RelationAnalysis) []RelationAnalysis {
 		// that resulted in can_read being processed before reader (its closure dependency).
 	}
 
+	return deps
+}
+
+// DependentRelations returns the relations that depend on
+// objectType.relation, directly or transitively, as "type.relation" keys in
+// sorted order. The relation itself is not included. Generated SQL for a
+// dependent may call the relation's functions, so a relation dropped from
+// generation must take its dependents with it.
+func DependentRelations(analyses []RelationAnalysis, objectType, relation string) []string {
+	deps := dependencyGraph(analyses, BuildAnalysisLookup(analyses))
+	dependents := make(map[string][]string)
+	for key, keyDeps := range deps {
+		for _, dep := range keyDeps {
+			dependents[dep] = append(dependents[dep], key)
+		}
+	}
+
+	root := objectType + "." + relation
+	found := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, dependent := range dependents[key] {
+			if !found[dependent] {
+				found[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	delete(found, root)
+
+	result := make([]string, 0, len(found))
+	for key := range found {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// sortByDependency performs topological sort on analyses based on all dependencies.
+// Returns sorted analyses where each relation is processed after its dependencies.
+// This ensures that when we check if a relation CanGenerate, all relations it depends on
+// have already been evaluated.
+//
+// Dependencies include:
+// - SatisfyingRelations (closure): implied-by relationships
+// - IntersectionGroups: relations referenced in AND groups
+// - ExcludedRelations: relations in "but not" clauses
+func sortByDependency(analyses []RelationAnalysis) []RelationAnalysis {
+	// Build lookup map for finding analyses during dependency tracking
+	lookup := BuildAnalysisLookup(analyses)
+
+	// Build dependency graph: relation -> relations it depends on
+	deps := dependencyGraph(analyses, lookup)
+
 	// Build reverse mapping: for each relation, which relations depend on it.
 	// We iterate over deps in sorted order for deterministic results.
 	dependents := make(map[string][]string)
@@ -990,7 +1118,21 @@ func classifyIntersectionParts(objectType string, groups []IntersectionGroupInfo
 // - Partitions closure relations into SimpleClosureRelations and ComplexClosureRelations
 // - Partitions excluded relations into SimpleExcludedRelations and ComplexExcludedRelations
 // - Computes ListStrategy for selecting the appropriate list code generation path
+//
+// Userset chains are measured against DefaultMaxDepth; see
+// ComputeCanGenerateWithMaxDepth.
 func ComputeCanGenerate(analyses []RelationAnalysis) []RelationAnalysis {
+	return ComputeCanGenerateWithMaxDepth(analyses, DefaultMaxDepth)
+}
+
+// ComputeCanGenerateWithMaxDepth is ComputeCanGenerate with the recursion
+// depth limit the generated SQL will enforce. Relations whose userset chain
+// reaches maxDepth are marked ExceedsDepthLimit and take
+// ListStrategyDepthExceeded. A maxDepth of zero or less means DefaultMaxDepth.
+func ComputeCanGenerateWithMaxDepth(analyses []RelationAnalysis, maxDepth int) []RelationAnalysis {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
 	// Sort by dependency order first - ensures relations are processed after their dependencies
 	sorted := sortByDependency(analyses)
 
@@ -1004,6 +1146,7 @@ func ComputeCanGenerate(analyses []RelationAnalysis) []RelationAnalysis {
 		// Collect allowed subject types from all satisfying relations and their userset patterns.
 		// This ensures type restrictions are enforced correctly.
 		collector := newSubjectTypeCollector(&a.AllowedSubjectTypes)
+		wildcards := newSubjectTypeCollector(&a.ClosureWildcardSubjectTypes)
 		for _, rel := range a.SatisfyingRelations {
 			relAnalysis, ok := lookup[a.ObjectType][rel]
 			if !ok {
@@ -1013,6 +1156,9 @@ func ComputeCanGenerate(analyses []RelationAnalysis) []RelationAnalysis {
 			if relAnalysis.Features.HasWildcard {
 				a.Features.HasWildcard = true
 			}
+			for _, t := range relAnalysis.WildcardSubjectTypes {
+				wildcards.add(t)
+			}
 			// Include subject types from userset patterns in closure relations
 			for _, pattern := range relAnalysis.UsersetPatterns {
 				if subjectAnalysis, ok := lookup[pattern.SubjectType][pattern.SubjectRelation]; ok {
@@ -1107,6 +1253,39 @@ func ComputeCanGenerate(analyses []RelationAnalysis) []RelationAnalysis {
 			addTypesFromTTU(collector, lookup, a.ObjectType, &parent)
 		}
 
+		// Generated functions would evaluate a different rewrite than the
+		// model's.
+		if a.rewriteReason != "" {
+			a.Capabilities = GenerationCapabilities{
+				CheckReason: a.rewriteReason,
+				ListReason:  a.rewriteReason,
+			}
+			a.ListStrategy = ListStrategyDirect
+			continue
+		}
+
+		// A relation implied only through a loop without a base can never be
+		// satisfied; its functions would recurse until the depth limit.
+		if a.cycleReason != "" {
+			a.Capabilities = GenerationCapabilities{
+				CheckReason: a.cycleReason,
+				ListReason:  a.cycleReason,
+			}
+			a.ListStrategy = ListStrategyDirect
+			continue
+		}
+
+		// A tuple-to-userset whose computed linking relation cannot be
+		// resolved would look its parents up under the wrong relation.
+		if a.linkingReason != "" {
+			a.Capabilities = GenerationCapabilities{
+				CheckReason: a.linkingReason,
+				ListReason:  a.linkingReason,
+			}
+			a.ListStrategy = ListStrategyDirect
+			continue
+		}
+
 		// First check: does this relation's features allow generation?
 		if !a.Features.CanGenerate() {
 			a.Capabilities = GenerationCapabilities{
@@ -1389,9 +1568,9 @@ func ComputeCanGenerate(analyses []RelationAnalysis) []RelationAnalysis {
 
 		// Compute maximum userset depth for this relation.
 		// This enables generating functions that immediately raise M2002 for
-		// relations with userset chains exceeding the 25-level depth limit.
+		// relations with userset chains exceeding the depth limit.
 		a.MaxUsersetDepth = computeMaxUsersetDepth(a, lookup)
-		a.ExceedsDepthLimit = a.MaxUsersetDepth >= 25
+		a.ExceedsDepthLimit = a.MaxUsersetDepth >= maxDepth
 
 		// Detect self-referential userset patterns for list function generation.
 		// These are patterns like [group#member] on group.member where the userset
@@ -1480,7 +1659,8 @@ func ComputeCanGenerate(analyses []RelationAnalysis) []RelationAnalysis {
 				}
 			}
 
-			if a.Capabilities.ListAllowed {
+			// A relation refused for its linking relation stays refused.
+			if a.Capabilities.ListAllowed || a.linkingReason != "" {
 				continue
 			}
 
@@ -1499,6 +1679,10 @@ func ComputeCanGenerate(analyses []RelationAnalysis) []RelationAnalysis {
 		}
 	}
 
+	// Finally, disable relations whose conditions cannot be evaluated and
+	// those that would read conditional grants without evaluating them.
+	applyConditionSupport(sorted, lookup)
+
 	return sorted
 }
 
@@ -1558,10 +1742,13 @@ func computeCanGenerateList(a *RelationAnalysis, lookup map[string]map[string]*R
 		}
 	}
 
+	// Every failing check is recorded for ExplainListEligibility; the first
+	// is the reason returned.
+	var failed []string
+
 	// First check: does this relation's features allow list generation?
-	canGenerate, reason = canGenerateListFeatures(a.Features, hasIndirectAnchor)
-	if !canGenerate {
-		return canGenerate, reason
+	if ok, r := canGenerateListFeatures(a.Features, hasIndirectAnchor); !ok {
+		failed = append(failed, r)
 	}
 
 	// Phase 9B: Self-referential userset patterns (e.g., group#member on group.member)
@@ -1572,7 +1759,8 @@ func computeCanGenerateList(a *RelationAnalysis, lookup map[string]map[string]*R
 	// Phase 9A: If the relation exceeds the depth limit, we can still generate a specialized
 	// function that immediately raises M2002. This is more efficient than falling back to
 	// the generic handler and provides clearer error semantics.
-	if a.ExceedsDepthLimit {
+	if a.ExceedsDepthLimit && len(failed) == 0 {
+		a.listIneligibility = nil
 		return true, "" // Will use depth-exceeded template
 	}
 
@@ -1584,7 +1772,7 @@ func computeCanGenerateList(a *RelationAnalysis, lookup map[string]map[string]*R
 	hasAllowedSubjects := len(a.AllowedSubjectTypes) > 0
 
 	if hasUsersetAccess && !hasDirectSubjects && !hasAllowedSubjects {
-		return false, "userset chain too deep - no reachable subject types (depth limit protection)"
+		failed = append(failed, "userset chain too deep - no reachable subject types (depth limit protection)")
 	}
 
 	// Second check: ensure closure relations are valid.
@@ -1596,7 +1784,8 @@ func computeCanGenerateList(a *RelationAnalysis, lookup map[string]map[string]*R
 
 		relAnalysis, ok := lookup[a.ObjectType][rel]
 		if !ok {
-			return false, "unknown relation in closure: " + rel
+			failed = append(failed, "unknown relation in closure: "+rel)
+			continue
 		}
 
 		// Phase 5: Closure relations with recursive patterns (TTU) are now supported.
@@ -1607,14 +1796,15 @@ func computeCanGenerateList(a *RelationAnalysis, lookup map[string]map[string]*R
 		// This handles cases like "can_view: viewer" where viewer is pure TTU (no direct/implied access).
 		// The closure relation was already computed (dependency order), so check its result.
 		if !relAnalysis.Capabilities.ListAllowed {
-			return false, "closure relation " + rel + " is not list-generatable: " + relAnalysis.Capabilities.ListReason
+			failed = append(failed, "closure relation "+rel+" is not list-generatable: "+relAnalysis.Capabilities.ListReason)
+			continue
 		}
 
 		// Phase 9C: Closure relations with intersection are now supported.
 		// These are handled by composing with their list function (they are list-generatable).
 		// We track them in IntersectionClosureRelations so templates can compose correctly.
-		// Note: The check at line 1553 already ensures the closure relation IS list-generatable.
-		if relAnalysis.Features.HasIntersection {
+		// Note: The check above already ensures the closure relation IS list-generatable.
+		if relAnalysis.Features.HasIntersection && len(failed) == 0 {
 			a.IntersectionClosureRelations = append(a.IntersectionClosureRelations, rel)
 		}
 
@@ -1625,6 +1815,10 @@ func computeCanGenerateList(a *RelationAnalysis, lookup map[string]map[string]*R
 		// Userset, exclusion, and recursive in closure are OK - handled via check_permission_internal
 	}
 
+	a.listIneligibility = failed
+	if len(failed) > 0 {
+		return false, failed[0]
+	}
 	return true, ""
 }
 
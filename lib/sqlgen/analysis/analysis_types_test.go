@@ -269,6 +269,22 @@ func TestDetectFeatures_Wildcard(t *testing.T) {
 	}
 }
 
+func TestCollectWildcardSubjectTypes(t *testing.T) {
+	// define viewer: [user:*, service, group#member]
+	r := RelationDefinition{
+		Name: "viewer",
+		SubjectTypeRefs: []SubjectTypeRef{
+			{Type: "user", Wildcard: true},
+			{Type: "service"},
+			{Type: "group", Relation: "member"},
+		},
+	}
+	got := collectWildcardSubjectTypes(r)
+	if len(got) != 1 || got[0] != "user" {
+		t.Errorf("collectWildcardSubjectTypes() = %v, want [user]", got)
+	}
+}
+
 func TestDetectFeatures_Userset(t *testing.T) {
 	// define viewer: [user, group#member]
 	r := RelationDefinition{
@@ -24,6 +24,12 @@ type GenerationCapabilities struct {
 	ListReason string
 }
 
+// DefaultMaxDepth is the recursion depth limit generated SQL enforces unless
+// GenerateSQLOptions.MaxDepth overrides it: check resolution raises M2002
+// once it nests this deep, and recursive list functions raise M2002 rather
+// than return results truncated at this depth.
+const DefaultMaxDepth = 25
+
 // ListStrategy determines which list generation approach to use.
 // Each strategy corresponds to a different code generation path optimized
 // for specific authorization patterns.
@@ -47,7 +53,7 @@ const (
 	ListStrategyIntersection
 
 	// ListStrategyDepthExceeded generates a function that immediately raises M2002.
-	// Used for relations with userset chains >= 25 levels deep.
+	// Used for relations with userset chains that reach the depth limit.
 	ListStrategyDepthExceeded
 
 	// ListStrategySelfRefUserset handles self-referential userset patterns.
@@ -108,7 +114,8 @@ func hasSelfReferentialParent(a RelationAnalysis) bool {
 //  1. DepthExceeded - relation exceeds userset depth limit
 //  2. SelfRefUserset - has self-referential userset patterns
 //  3. Composed - has indirect anchor (pure TTU reaching direct grants), but only
-//     when there is no self-referential recursive parent — see below.
+//     when there is no self-referential recursive parent and no intersection —
+//     see below.
 //  4. Intersection - has intersection patterns (AND groups)
 //  5. Recursive - has TTU patterns or closure TTU patterns
 //  6. Userset - has userset patterns or closure userset patterns
@@ -127,7 +134,12 @@ func DetermineListStrategy(a RelationAnalysis) ListStrategy {
 	// so it under-reports every object reached only through the parent chain.
 	// Route these to Recursive, whose CTE covers both the cross-type anchor base
 	// and the self-referential parent expansion.
-	if a.IndirectAnchor != nil && !hasSelfReferentialParent(a) {
+	//
+	// Composed also has no blocks for intersection groups, so a relation like
+	// "can_manage: admin from org or (editor and can_manage from parent)" goes
+	// to Intersection, which renders both the groups and the standalone TTU
+	// arm.
+	if a.IndirectAnchor != nil && !hasSelfReferentialParent(a) && !a.Features.HasIntersection {
 		return ListStrategyComposed
 	}
 	if a.Features.HasIntersection {
@@ -141,3 +153,23 @@ func DetermineListStrategy(a RelationAnalysis) ListStrategy {
 	}
 	return ListStrategyDirect
 }
+
+// ExplainListEligibility returns why the relation gets no list function:
+// every check ComputeCanGenerate found failing, in the order it runs them,
+// rather than only the first. A schema author can then see everything to
+// restructure at once, such as each closure relation that is not
+// list-generatable itself. The first entry is always Capabilities.ListReason.
+//
+// A relation refused outside those checks (a loop of implied relations
+// without a base, an unresolvable linking relation, no access path at all,
+// or conditions) gets that single reason. Returns nil when ListAllowed or
+// no reason was recorded.
+func (a RelationAnalysis) ExplainListEligibility() []string {
+	if a.Capabilities.ListAllowed || a.Capabilities.ListReason == "" {
+		return nil
+	}
+	if len(a.listIneligibility) > 0 && a.listIneligibility[0] == a.Capabilities.ListReason {
+		return slices.Clone(a.listIneligibility)
+	}
+	return []string{a.Capabilities.ListReason}
+}
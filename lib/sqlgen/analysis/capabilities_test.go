@@ -1,6 +1,9 @@
 package analysis
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestListStrategy_String(t *testing.T) {
 	tests := []struct {
@@ -44,6 +47,14 @@ func TestDetermineListStrategy(t *testing.T) {
 			a:    RelationAnalysis{IndirectAnchor: &IndirectAnchorInfo{}},
 			want: ListStrategyComposed,
 		},
+		{
+			name: "indirect anchor with intersection groups",
+			a: RelationAnalysis{
+				IndirectAnchor: &IndirectAnchorInfo{},
+				Features:       RelationFeatures{HasIntersection: true, HasRecursive: true},
+			},
+			want: ListStrategyIntersection,
+		},
 		{
 			name: "intersection",
 			a:    RelationAnalysis{Features: RelationFeatures{HasIntersection: true}},
@@ -88,3 +99,49 @@ func TestDetermineListStrategy(t *testing.T) {
 		})
 	}
 }
+
+func TestExplainListEligibility(t *testing.T) {
+	types := []TypeDefinition{
+		{Name: "user"},
+		{Name: "doc", Relations: []RelationDefinition{
+			{Name: "owner", SubjectTypeRefs: []SubjectTypeRef{{Type: "user"}}},
+			// a/c and b/d imply each other with no base, so neither loop is listable.
+			{Name: "a", ImpliedBy: []string{"c"}},
+			{Name: "c", ImpliedBy: []string{"a"}},
+			{Name: "b", ImpliedBy: []string{"d"}},
+			{Name: "d", ImpliedBy: []string{"b"}},
+			{Name: "viewer", SubjectTypeRefs: []SubjectTypeRef{{Type: "user"}}, ImpliedBy: []string{"owner", "a", "b"}},
+		}},
+	}
+
+	analyses := ComputeCanGenerate(AnalyzeRelations(types, ComputeRelationClosure(types)))
+	lookup := make(map[string]*RelationAnalysis)
+	for i := range analyses {
+		lookup[analyses[i].ObjectType+"."+analyses[i].Relation] = &analyses[i]
+	}
+
+	if got := lookup["doc.owner"].ExplainListEligibility(); got != nil {
+		t.Errorf("doc.owner: ExplainListEligibility() = %v, want nil", got)
+	}
+
+	viewer := lookup["doc.viewer"]
+	got := viewer.ExplainListEligibility()
+	want := []string{
+		"closure relation a is not list-generatable: cyclic implication without base: a -> c -> a",
+		"closure relation b is not list-generatable: cyclic implication without base: b -> d -> b",
+		"closure relation c is not list-generatable: cyclic implication without base: c -> a -> c",
+		"closure relation d is not list-generatable: cyclic implication without base: d -> b -> d",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("doc.viewer: ExplainListEligibility() = %q, want %q", got, want)
+	}
+	if len(got) > 0 && got[0] != viewer.Capabilities.ListReason {
+		t.Errorf("doc.viewer: first entry %q, want ListReason %q", got[0], viewer.Capabilities.ListReason)
+	}
+
+	// Refused outside the closure checks: the single reason is reported.
+	a := lookup["doc.a"]
+	if got := a.ExplainListEligibility(); !reflect.DeepEqual(got, []string{a.Capabilities.ListReason}) {
+		t.Errorf("doc.a: ExplainListEligibility() = %q, want [%q]", got, a.Capabilities.ListReason)
+	}
+}
@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// resolveComputedLinkingRelations rewrites tuple-to-usersets whose linking
+// relation is computed, as in "define viewer: viewer from container" with
+// "define container: parent", so that each names a relation tuples are
+// actually stored under. OpenFGA follows the tupleset through its rewrite;
+// the generated SQL matches tuples by relation name, so "viewer from
+// container" becomes one "viewer from R" per relation R in container's
+// closure that has direct type restrictions. The list and check builders
+// then scan every such R for the linking tuple, the self-referential walk
+// included.
+//
+// A linking relation is unresolvable when some relation in its closure
+// grants through anything but direct object types or implication (a
+// userset, wildcard, parent, exclusion, intersection or condition), or when
+// a TTU inside an intersection resolves to more than one relation. Those
+// relations get linkingReason and are left to the generic check.
+func resolveComputedLinkingRelations(analyses []RelationAnalysis) {
+	lookup := make(map[string]map[string]*RelationAnalysis)
+	for i := range analyses {
+		a := &analyses[i]
+		if lookup[a.ObjectType] == nil {
+			lookup[a.ObjectType] = make(map[string]*RelationAnalysis)
+		}
+		lookup[a.ObjectType][a.Relation] = a
+	}
+
+	resolved := make(map[string]map[string][]string)
+	reasons := make(map[string]map[string]string)
+	resolve := func(objectType, linking string) ([]string, string) {
+		if rels, ok := resolved[objectType][linking]; ok {
+			return rels, reasons[objectType][linking]
+		}
+		rels, reason := resolveLinkingRelation(lookup[objectType], linking)
+		if resolved[objectType] == nil {
+			resolved[objectType] = make(map[string][]string)
+			reasons[objectType] = make(map[string]string)
+		}
+		resolved[objectType][linking] = rels
+		reasons[objectType][linking] = reason
+		return rels, reason
+	}
+
+	for i := range analyses {
+		a := &analyses[i]
+		var reason string
+		expand := func(parents []ParentRelationInfo) []ParentRelationInfo {
+			out := make([]ParentRelationInfo, 0, len(parents))
+			seen := make(map[string]bool)
+			for _, p := range parents {
+				rels, r := resolve(a.ObjectType, p.LinkingRelation)
+				if r != "" && reason == "" {
+					reason = r
+				}
+				if rels == nil {
+					rels = []string{p.LinkingRelation}
+				}
+				for _, rel := range rels {
+					key := p.Relation + ":" + rel
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					p.LinkingRelation = rel
+					out = append(out, p)
+				}
+			}
+			return out
+		}
+		a.ParentRelations = expand(a.ParentRelations)
+		a.ExcludedParentRelations = expand(a.ExcludedParentRelations)
+
+		for _, groups := range [][]IntersectionGroupInfo{a.IntersectionGroups, a.ExcludedIntersectionGroups} {
+			for gi := range groups {
+				for pi := range groups[gi].Parts {
+					pr := groups[gi].Parts[pi].ParentRelation
+					if pr == nil {
+						continue
+					}
+					rels, r := resolve(a.ObjectType, pr.LinkingRelation)
+					switch {
+					case r != "":
+					case len(rels) > 1:
+						r = fmt.Sprintf("linking relation %q of %q inside an intersection resolves to several relations (%s)",
+							pr.LinkingRelation, pr.Relation+" from "+pr.LinkingRelation, strings.Join(rels, ", "))
+					case len(rels) == 1:
+						pr.LinkingRelation = rels[0]
+					}
+					if r != "" && reason == "" {
+						reason = r
+					}
+				}
+			}
+		}
+		a.linkingReason = reason
+	}
+}
+
+// resolveLinkingRelation returns the relations that store the tuples linking
+// through linking, given the analyses of its type. It returns nil and no
+// reason when linking is not computed and needs no rewrite.
+func resolveLinkingRelation(relations map[string]*RelationAnalysis, linking string) ([]string, string) {
+	la, ok := relations[linking]
+	if !ok || len(la.SatisfyingRelations) <= 1 {
+		return nil, ""
+	}
+
+	var rels []string
+	for _, rel := range la.SatisfyingRelations {
+		ra, ok := relations[rel]
+		if !ok {
+			continue
+		}
+		f := ra.Features
+		if f.HasUserset || f.HasWildcard || f.HasRecursive || f.HasExclusion || f.HasIntersection || f.HasCondition {
+			return nil, fmt.Sprintf("linking relation %q resolves through %q, which does not link by direct object types", linking, rel)
+		}
+		if f.HasDirect {
+			rels = append(rels, rel)
+		}
+	}
+	if len(rels) == 0 {
+		return nil, fmt.Sprintf("linking relation %q resolves to no relation with direct type restrictions", linking)
+	}
+	return rels, ""
+}
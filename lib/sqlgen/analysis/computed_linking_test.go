@@ -0,0 +1,80 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveComputedLinkingRelations(t *testing.T) {
+	types := []TypeDefinition{
+		{Name: "user"},
+		{Name: "folder", Relations: []RelationDefinition{
+			{Name: "viewer", SubjectTypeRefs: []SubjectTypeRef{{Type: "user"}}},
+		}},
+		{Name: "doc", Relations: []RelationDefinition{
+			{Name: "parent", SubjectTypeRefs: []SubjectTypeRef{{Type: "folder"}}},
+			{Name: "shared_in", SubjectTypeRefs: []SubjectTypeRef{{Type: "folder"}}},
+			// container is computed: its tuples are stored under parent and shared_in.
+			{Name: "container", ImpliedBy: []string{"parent", "shared_in"}},
+			{Name: "primary", ImpliedBy: []string{"parent"}},
+			{Name: "grouped", SubjectTypeRefs: []SubjectTypeRef{{Type: "folder", Relation: "viewer"}}},
+			{Name: "via_group", ImpliedBy: []string{"grouped"}},
+
+			{Name: "viewer", ParentRelations: []ParentRelationCheck{
+				{Relation: "viewer", LinkingRelation: "container"},
+				{Relation: "viewer", LinkingRelation: "parent"},
+			}},
+			{Name: "editor", IntersectionGroups: []IntersectionGroup{{
+				Relations:       []string{"viewer"},
+				ParentRelations: []ParentRelationCheck{{Relation: "viewer", LinkingRelation: "primary"}},
+			}}},
+			{Name: "ambiguous", IntersectionGroups: []IntersectionGroup{{
+				Relations:       []string{"viewer"},
+				ParentRelations: []ParentRelationCheck{{Relation: "viewer", LinkingRelation: "container"}},
+			}}},
+			{Name: "unresolvable", ParentRelations: []ParentRelationCheck{{Relation: "viewer", LinkingRelation: "via_group"}}},
+		}},
+	}
+
+	analyses := ComputeCanGenerate(AnalyzeRelations(types, ComputeRelationClosure(types)))
+	lookup := make(map[string]*RelationAnalysis)
+	for i := range analyses {
+		lookup[analyses[i].ObjectType+"."+analyses[i].Relation] = &analyses[i]
+	}
+
+	viewer := lookup["doc.viewer"]
+	var linking []string
+	for _, p := range viewer.ParentRelations {
+		linking = append(linking, p.LinkingRelation)
+		if !reflect.DeepEqual(p.AllowedLinkingTypes, []string{"folder"}) {
+			t.Errorf("viewer from %s: AllowedLinkingTypes = %v, want [folder]", p.LinkingRelation, p.AllowedLinkingTypes)
+		}
+	}
+	if want := []string{"parent", "shared_in"}; !reflect.DeepEqual(linking, want) {
+		t.Errorf("doc.viewer linking relations = %v, want %v", linking, want)
+	}
+	if !viewer.Capabilities.CheckAllowed {
+		t.Errorf("doc.viewer: check refused: %s", viewer.Capabilities.CheckReason)
+	}
+
+	editor := lookup["doc.editor"]
+	if got := editor.IntersectionGroups[0].Parts[1].ParentRelation.LinkingRelation; got != "parent" {
+		t.Errorf("doc.editor intersection TTU links through %q, want parent", got)
+	}
+	if !editor.Capabilities.CheckAllowed {
+		t.Errorf("doc.editor: check refused: %s", editor.Capabilities.CheckReason)
+	}
+
+	for rel, reason := range map[string]string{
+		"doc.ambiguous":    `linking relation "container" of "viewer from container" inside an intersection resolves to several relations (parent, shared_in)`,
+		"doc.unresolvable": `linking relation "via_group" resolves through "grouped", which does not link by direct object types`,
+	} {
+		caps := lookup[rel].Capabilities
+		if caps.CheckAllowed || caps.ListAllowed {
+			t.Errorf("%s: generation allowed, want it refused", rel)
+		}
+		if caps.CheckReason != reason || caps.ListReason != reason {
+			t.Errorf("%s reasons = %q / %q, want %q", rel, caps.CheckReason, caps.ListReason, reason)
+		}
+	}
+}
@@ -0,0 +1,584 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ConditionInfo is an OpenFGA condition guarding the direct grants of one
+// subject type, as in "viewer: [user with non_expired]". Tuples carry no
+// condition, so every tuple of a conditioned subject type is treated as
+// written with the condition, and grants it only when Expr holds against the
+// request context.
+type ConditionInfo struct {
+	SubjectType string            // Subject type the condition applies to (e.g., "user")
+	Name        string            // Condition name (e.g., "non_expired")
+	Expression  string            // CEL source of the condition
+	Parameters  map[string]string // Parameter name -> DSL type (e.g., "timestamp")
+	Expr        ConditionExpr     // Parsed Expression
+}
+
+// ConditionExpr is a node of a parsed condition expression.
+//
+// Op names the node:
+//   - "string", "number", "bool", "timestamp": a literal; Value holds it
+//   - "param": a context parameter; Value holds its name
+//   - "list": a list literal; Args holds its string elements
+//   - "==", "!=", "<", "<=", ">", ">=", "in", "&&", "||", "!": an operator
+//     applied to Args
+//
+// Type is the node's value type: "string", "number", "bool", "timestamp" or
+// "list<string>". Every operator yields "bool".
+type ConditionExpr struct {
+	Op    string
+	Value string
+	Type  string
+	Args  []ConditionExpr
+}
+
+// conditionParamTypes maps the supported DSL parameter types to the value
+// types of the expression language. int, uint and double all compare as
+// numbers.
+var conditionParamTypes = map[string]string{
+	"string":       "string",
+	"int":          "number",
+	"uint":         "number",
+	"double":       "number",
+	"bool":         "bool",
+	"timestamp":    "timestamp",
+	"list<string>": "list<string>",
+}
+
+// ParseCondition parses the subset of CEL that generated check functions can
+// evaluate: comparisons (==, !=, <, <=, >, >=) between parameters and
+// literals of the same type, "in" over a string list literal or a
+// list<string> parameter, the logical operators &&, || and !, parentheses,
+// and timestamp("...") literals. Anything else, such as arithmetic, field
+// access, macros or duration and ipaddress values, is rejected with an error
+// naming the unsupported construct.
+func ParseCondition(expression string, params map[string]string) (ConditionExpr, error) {
+	tokens, err := lexCondition(expression)
+	if err != nil {
+		return ConditionExpr{}, err
+	}
+	p := &conditionParser{tokens: tokens, params: params}
+	expr, err := p.parseOr()
+	if err != nil {
+		return ConditionExpr{}, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return ConditionExpr{}, fmt.Errorf("unsupported token %q", tok.text)
+	}
+	if expr.Type != "bool" {
+		return ConditionExpr{}, fmt.Errorf("expression is %s, not bool", expr.Type)
+	}
+	return expr, nil
+}
+
+type conditionTokenKind int
+
+const (
+	tokEOF conditionTokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokPunct
+)
+
+type conditionToken struct {
+	kind conditionTokenKind
+	text string // identifier, punctuation, number, or unquoted string value
+}
+
+// conditionPuncts lists the operators the lexer accepts, longest first so
+// "<=" is not read as "<".
+var conditionPuncts = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", "-"}
+
+func lexCondition(src string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			value, n, err := lexConditionString(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, conditionToken{kind: tokString, text: value})
+			i += n
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			num := src[i:j]
+			if j < len(src) && src[j] == 'u' {
+				j++ // uint literal, e.g. 10u
+			}
+			if j < len(src) && (isIdentByte(src[j]) || src[j] == '.') || strings.Count(num, ".") > 1 || strings.HasSuffix(num, ".") {
+				return nil, fmt.Errorf("unsupported number literal near %q", src[i:])
+			}
+			tokens = append(tokens, conditionToken{kind: tokNumber, text: num})
+			i = j
+		case isIdentByte(c):
+			j := i
+			for j < len(src) && (isIdentByte(src[j]) || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, conditionToken{kind: tokIdent, text: src[i:j]})
+			i = j
+		default:
+			matched := false
+			for _, p := range conditionPuncts {
+				if strings.HasPrefix(src[i:], p) {
+					tokens = append(tokens, conditionToken{kind: tokPunct, text: p})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				r := []rune(src[i:])[0]
+				if unicode.IsPrint(r) {
+					return nil, fmt.Errorf("unsupported operator %q", string(r))
+				}
+				return nil, fmt.Errorf("unsupported character %U", r)
+			}
+		}
+	}
+	return append(tokens, conditionToken{kind: tokEOF}), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// lexConditionString reads a single- or double-quoted string literal at the
+// start of src, returning its value and the number of bytes consumed. Only
+// the escapes \\, \", \', \n and \t are supported.
+func lexConditionString(src string) (string, int, error) {
+	quote := src[0]
+	if strings.HasPrefix(src, strings.Repeat(string(quote), 3)) {
+		return "", 0, fmt.Errorf("triple-quoted strings are not supported")
+	}
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\':
+			if i+1 >= len(src) {
+				break
+			}
+			i++
+			switch src[i] {
+			case '\\', '"', '\'':
+				b.WriteByte(src[i])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				return "", 0, fmt.Errorf("unsupported escape \\%c in string literal", src[i])
+			}
+		case c == '\n':
+			return "", 0, fmt.Errorf("unterminated string literal")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string literal")
+}
+
+// conditionParser is a recursive descent parser over CEL's precedence levels:
+// || binds loosest, then &&, then the relations, then unary !.
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+	params map[string]string
+}
+
+func (p *conditionParser) peek() conditionToken { return p.tokens[p.pos] }
+
+func (p *conditionParser) next() conditionToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is the punctuation or keyword text.
+func (p *conditionParser) accept(text string) bool {
+	tok := p.peek()
+	if (tok.kind == tokPunct || tok.kind == tokIdent) && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) expect(text string) error {
+	if !p.accept(text) {
+		return fmt.Errorf("expected %q, found %s", text, describeToken(p.peek()))
+	}
+	return nil
+}
+
+func describeToken(tok conditionToken) string {
+	switch tok.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return fmt.Sprintf("string %q", tok.text)
+	}
+	return fmt.Sprintf("%q", tok.text)
+}
+
+func (p *conditionParser) parseOr() (ConditionExpr, error) {
+	return p.parseLogical("||", p.parseAnd)
+}
+
+func (p *conditionParser) parseAnd() (ConditionExpr, error) {
+	return p.parseLogical("&&", p.parseRelation)
+}
+
+// parseLogical parses operand (op operand)* with bool operands.
+func (p *conditionParser) parseLogical(op string, operand func() (ConditionExpr, error)) (ConditionExpr, error) {
+	left, err := operand()
+	if err != nil {
+		return ConditionExpr{}, err
+	}
+	for p.accept(op) {
+		right, err := operand()
+		if err != nil {
+			return ConditionExpr{}, err
+		}
+		if left.Type != "bool" || right.Type != "bool" {
+			return ConditionExpr{}, fmt.Errorf("%s needs bool operands, not %s and %s", op, left.Type, right.Type)
+		}
+		left = ConditionExpr{Op: op, Type: "bool", Args: []ConditionExpr{left, right}}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseRelation() (ConditionExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return ConditionExpr{}, err
+	}
+	tok := p.peek()
+	op := tok.text
+	switch {
+	case tok.kind == tokPunct && (op == "==" || op == "!=" || op == "<" || op == "<=" || op == ">" || op == ">="):
+	case tok.kind == tokIdent && op == "in":
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseUnary()
+	if err != nil {
+		return ConditionExpr{}, err
+	}
+	if err := checkRelationTypes(op, left, right); err != nil {
+		return ConditionExpr{}, err
+	}
+	return ConditionExpr{Op: op, Type: "bool", Args: []ConditionExpr{left, right}}, nil
+}
+
+// checkRelationTypes rejects comparisons the generated SQL cannot express
+// faithfully, such as mixing types or ordering bools.
+func checkRelationTypes(op string, left, right ConditionExpr) error {
+	if op == "in" {
+		if left.Type != "string" || right.Type != "list<string>" {
+			return fmt.Errorf("in needs a string and a list of strings, not %s and %s", left.Type, right.Type)
+		}
+		return nil
+	}
+	if left.Type != right.Type {
+		return fmt.Errorf("cannot compare %s with %s", left.Type, right.Type)
+	}
+	switch left.Type {
+	case "list<string>":
+		return fmt.Errorf("%s is not supported on lists", op)
+	case "bool":
+		if op != "==" && op != "!=" {
+			return fmt.Errorf("%s is not supported on bools", op)
+		}
+	}
+	return nil
+}
+
+func (p *conditionParser) parseUnary() (ConditionExpr, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return ConditionExpr{}, err
+		}
+		if operand.Type != "bool" {
+			return ConditionExpr{}, fmt.Errorf("! needs a bool operand, not %s", operand.Type)
+		}
+		return ConditionExpr{Op: "!", Type: "bool", Args: []ConditionExpr{operand}}, nil
+	}
+	if p.accept("-") {
+		tok := p.next()
+		if tok.kind != tokNumber {
+			return ConditionExpr{}, fmt.Errorf("unsupported operator %q", "-")
+		}
+		return ConditionExpr{Op: "number", Type: "number", Value: "-" + tok.text}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parsePrimary() (ConditionExpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		return ConditionExpr{Op: "string", Type: "string", Value: tok.text}, nil
+	case tokNumber:
+		return ConditionExpr{Op: "number", Type: "number", Value: tok.text}, nil
+	case tokIdent:
+		return p.parseIdent(tok.text)
+	case tokPunct:
+		switch tok.text {
+		case "(":
+			expr, err := p.parseOr()
+			if err != nil {
+				return ConditionExpr{}, err
+			}
+			return expr, p.expect(")")
+		case "[":
+			return p.parseList()
+		}
+	}
+	return ConditionExpr{}, fmt.Errorf("unexpected %s", describeToken(tok))
+}
+
+func (p *conditionParser) parseIdent(name string) (ConditionExpr, error) {
+	switch name {
+	case "true", "false":
+		return ConditionExpr{Op: "bool", Type: "bool", Value: name}, nil
+	case "null":
+		return ConditionExpr{}, fmt.Errorf("null is not supported")
+	case "in":
+		return ConditionExpr{}, fmt.Errorf("unexpected %q", name)
+	}
+	if p.accept("(") {
+		if name != "timestamp" {
+			return ConditionExpr{}, fmt.Errorf("unsupported function %s()", name)
+		}
+		arg := p.next()
+		if arg.kind != tokString {
+			return ConditionExpr{}, fmt.Errorf("timestamp() needs a string literal")
+		}
+		if _, err := time.Parse(time.RFC3339, arg.text); err != nil {
+			return ConditionExpr{}, fmt.Errorf("timestamp(%q) is not an RFC 3339 time", arg.text)
+		}
+		return ConditionExpr{Op: "timestamp", Type: "timestamp", Value: arg.text}, p.expect(")")
+	}
+	declared, ok := p.params[name]
+	if !ok {
+		return ConditionExpr{}, fmt.Errorf("unknown parameter %q", name)
+	}
+	typ, ok := conditionParamTypes[declared]
+	if !ok {
+		return ConditionExpr{}, fmt.Errorf("parameter %q has unsupported type %s", name, declared)
+	}
+	return ConditionExpr{Op: "param", Type: typ, Value: name}, nil
+}
+
+// parseList parses a list literal after its opening bracket. Only lists of
+// string literals are supported.
+func (p *conditionParser) parseList() (ConditionExpr, error) {
+	list := ConditionExpr{Op: "list", Type: "list<string>"}
+	if p.accept("]") {
+		return list, nil
+	}
+	for {
+		tok := p.next()
+		if tok.kind != tokString {
+			return ConditionExpr{}, fmt.Errorf("list literals may only hold strings, found %s", describeToken(tok))
+		}
+		list.Args = append(list.Args, ConditionExpr{Op: "string", Type: "string", Value: tok.text})
+		if p.accept("]") {
+			return list, nil
+		}
+		if err := p.expect(","); err != nil {
+			return ConditionExpr{}, err
+		}
+	}
+}
+
+// collectConditions extracts the conditional direct grants of a relation.
+// The returned reason is non-empty when a condition cannot be evaluated by
+// generated SQL; the relation is then not generated at all, so the condition
+// is never silently dropped.
+func collectConditions(r RelationDefinition) ([]ConditionInfo, string) {
+	var conditions []ConditionInfo
+	conditioned := make(map[string]string) // subject type -> condition name
+	unconditioned := make(map[string]bool)
+	for _, ref := range r.SubjectTypeRefs {
+		if ref.Condition == nil {
+			if ref.Relation == "" {
+				unconditioned[ref.Type] = true
+			}
+			continue
+		}
+		name := ref.Condition.Name
+		switch {
+		case ref.Wildcard:
+			return nil, fmt.Sprintf("conditional wildcard %s:* with %s is not supported", ref.Type, name)
+		case ref.Relation != "":
+			return nil, fmt.Sprintf("conditional userset %s#%s with %s is not supported", ref.Type, ref.Relation, name)
+		}
+		if prev, ok := conditioned[ref.Type]; ok {
+			return nil, fmt.Sprintf("subject type %s is granted under both condition %s and %s", ref.Type, prev, name)
+		}
+		conditioned[ref.Type] = name
+
+		expr, err := ParseCondition(ref.Condition.Expression, ref.Condition.Parameters)
+		if err != nil {
+			return nil, fmt.Sprintf("condition %s: %v", name, err)
+		}
+		conditions = append(conditions, ConditionInfo{
+			SubjectType: ref.Type,
+			Name:        name,
+			Expression:  ref.Condition.Expression,
+			Parameters:  ref.Condition.Parameters,
+			Expr:        expr,
+		})
+	}
+	for _, c := range conditions {
+		// Tuples record no condition, so a tuple of this subject type could
+		// be either grant.
+		if unconditioned[c.SubjectType] {
+			return nil, fmt.Sprintf("subject type %s is granted both with and without condition %s", c.SubjectType, c.Name)
+		}
+	}
+	return conditions, ""
+}
+
+// applyConditionSupport disables generation for relations whose conditions
+// cannot be evaluated, and for every relation that reads a conditioned one.
+//
+// A conditioned relation's own check function ignores its conditional
+// grants, and check_permission_with_context adds them back. Another relation
+// that reaches it through its closure, a userset, a TTU link or an exclusion
+// would read the conditional tuples without evaluating the condition (or, for
+// an exclusion, would stop honouring them), so such dependents are not
+// generated and check_permission denies them. Conditions are also not
+// combined with the relation's own exclusions or intersections, which the
+// conditional grant would bypass.
+//
+// The list functions of a conditioned relation evaluate its conditional
+// grants against p_context, as the _ctx check function does. They are
+// generated for the Direct list strategy (direct and implied relations)
+// only; other strategies are not listable.
+func applyConditionSupport(analyses []RelationAnalysis, lookup map[string]map[string]*RelationAnalysis) {
+	// conditionReason doubles as the marker of relations disabled here, so
+	// their dependents are disabled in turn.
+	disable := func(a *RelationAnalysis, reason string) {
+		a.conditionReason = reason
+		a.Capabilities = GenerationCapabilities{CheckReason: reason, ListReason: reason}
+	}
+
+	for i := range analyses {
+		a := &analyses[i]
+		switch {
+		case a.conditionReason != "":
+			disable(a, a.conditionReason)
+		case len(a.Conditions) > 0 && a.Features.HasExclusion:
+			disable(a, "conditions cannot be combined with an exclusion on the same relation")
+		case len(a.Conditions) > 0 && a.Features.HasIntersection:
+			disable(a, "conditions cannot be combined with an intersection on the same relation")
+		case len(a.Conditions) > 0 && a.Capabilities.ListAllowed && a.ListStrategy != ListStrategyDirect:
+			a.Capabilities.ListAllowed = false
+			a.Capabilities.ListReason = "conditions are listed only on direct and implied relations; use check_permission_with_context"
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := range analyses {
+			a := &analyses[i]
+			if a.conditionReason != "" {
+				continue
+			}
+			for _, dep := range relationDependencies(*a) {
+				if dep[0] == a.ObjectType && dep[1] == a.Relation {
+					continue
+				}
+				d, ok := lookup[dep[0]][dep[1]]
+				if !ok {
+					continue
+				}
+				name := dep[0] + "." + dep[1]
+				switch {
+				case len(d.Conditions) > 0:
+					disable(a, "depends on "+name+", whose conditional grants only check_permission_with_context evaluates")
+				case d.conditionReason != "":
+					disable(a, "depends on "+name+", which is not generated")
+				default:
+					continue
+				}
+				changed = true
+				break
+			}
+		}
+	}
+}
+
+// relationDependencies lists the (object type, relation) pairs whose tuples
+// or results a's generated functions read.
+func relationDependencies(a RelationAnalysis) [][2]string {
+	var deps [][2]string
+	add := func(objectType, relation string) {
+		if relation != "" {
+			deps = append(deps, [2]string{objectType, relation})
+		}
+	}
+	addParent := func(p ParentRelationInfo) {
+		add(a.ObjectType, p.LinkingRelation)
+		for _, t := range p.AllowedLinkingTypes {
+			add(t, p.Relation)
+		}
+	}
+	addGroups := func(groups []IntersectionGroupInfo) {
+		for _, g := range groups {
+			for _, part := range g.Parts {
+				add(a.ObjectType, part.Relation)
+				add(a.ObjectType, part.ExcludedRelation)
+				if part.ParentRelation != nil {
+					addParent(*part.ParentRelation)
+				}
+			}
+		}
+	}
+
+	for _, rel := range a.SatisfyingRelations {
+		add(a.ObjectType, rel)
+	}
+	for _, rel := range a.ExcludedRelations {
+		add(a.ObjectType, rel)
+	}
+	for _, p := range a.UsersetPatterns {
+		add(p.SubjectType, p.SubjectRelation)
+		for _, rel := range p.SatisfyingRelations {
+			add(p.SubjectType, rel)
+		}
+	}
+	for _, p := range a.ParentRelations {
+		addParent(p)
+	}
+	for _, p := range a.ExcludedParentRelations {
+		addParent(p)
+	}
+	addGroups(a.IntersectionGroups)
+	addGroups(a.ExcludedIntersectionGroups)
+	return deps
+}
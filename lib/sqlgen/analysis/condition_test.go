@@ -0,0 +1,164 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pthm/melange/pkg/schema"
+)
+
+func TestParseCondition(t *testing.T) {
+	params := map[string]string{
+		"region":  "string",
+		"allowed": "list<string>",
+		"count":   "int",
+		"ratio":   "double",
+		"active":  "bool",
+		"now":     "timestamp",
+	}
+	tests := []struct {
+		name string
+		expr string
+		want string // Op of the root node
+	}{
+		{"string equality", `region == "eu"`, "=="},
+		{"string inequality", `region != 'us'`, "!="},
+		{"number ordering", `count <= 10`, "<="},
+		{"negative number", `ratio > -0.5`, ">"},
+		{"bool param", `active`, "param"},
+		{"negation", `!active`, "!"},
+		{"timestamp literal", `now < timestamp("2030-01-01T00:00:00Z")`, "<"},
+		{"in list literal", `region in ["eu", "uk"]`, "in"},
+		{"in list param", `region in allowed`, "in"},
+		{"precedence", `active || region == "eu" && count > 1`, "||"},
+		{"parentheses", `(active || region == "eu") && count > 1`, "&&"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCondition(tt.expr, params)
+			if err != nil {
+				t.Fatalf("ParseCondition(%q): %v", tt.expr, err)
+			}
+			if got.Op != tt.want || got.Type != "bool" {
+				t.Errorf("ParseCondition(%q) root = %s (%s), want %s (bool)", tt.expr, got.Op, got.Type, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCondition_Rejects(t *testing.T) {
+	params := map[string]string{
+		"region":  "string",
+		"allowed": "list<string>",
+		"count":   "int",
+		"active":  "bool",
+		"ttl":     "duration",
+	}
+	for _, expr := range []string{
+		`region`,                  // not bool
+		`count + 1 > 2`,           // arithmetic
+		`region.size() > 0`,       // field access
+		`region == 1`,             // mixed types
+		`active < true`,           // ordering bools
+		`allowed == ["eu"]`,       // comparing lists
+		`unknown == "eu"`,         // undeclared parameter
+		`ttl > 0`,                 // unsupported parameter type
+		`region == null`,          // null
+		`size(allowed) > 0`,       // functions
+		`timestamp("yesterday")`,  // invalid timestamp
+		`region == "eu" &&`,       // truncated
+		`region == "eu") || true`, // unbalanced
+	} {
+		if _, err := ParseCondition(expr, params); err == nil {
+			t.Errorf("ParseCondition(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestComputeCanGenerate_Conditions(t *testing.T) {
+	inRegion := &schema.Condition{
+		Name:       "in_region",
+		Expression: `region == "eu"`,
+		Parameters: map[string]string{"region": "string"},
+	}
+	types := []TypeDefinition{
+		{Name: "user"},
+		{Name: "group", Relations: []RelationDefinition{
+			{Name: "member", SubjectTypeRefs: []SubjectTypeRef{{Type: "user"}}},
+		}},
+		{Name: "doc", Relations: []RelationDefinition{
+			{Name: "owner", SubjectTypeRefs: []SubjectTypeRef{{Type: "user"}}},
+			{Name: "viewer", ImpliedBy: []string{"owner"}, SubjectTypeRefs: []SubjectTypeRef{
+				{Type: "user", Condition: inRegion},
+				{Type: "group", Relation: "member"},
+			}},
+			{Name: "reader", ImpliedBy: []string{"owner"}, SubjectTypeRefs: []SubjectTypeRef{
+				{Type: "user", Condition: inRegion},
+			}},
+			{Name: "can_view", ImpliedBy: []string{"viewer"}},
+			{Name: "can_share", ImpliedBy: []string{"can_view"}},
+			{Name: "mixed", SubjectTypeRefs: []SubjectTypeRef{
+				{Type: "user"},
+				{Type: "user", Condition: inRegion},
+			}},
+			{Name: "wild", SubjectTypeRefs: []SubjectTypeRef{
+				{Type: "user", Wildcard: true, Condition: inRegion},
+			}},
+			{Name: "invalid", SubjectTypeRefs: []SubjectTypeRef{
+				{Type: "user", Condition: &schema.Condition{Name: "bad", Expression: "region + 1"}},
+			}},
+			{Name: "blocked", SubjectTypeRefs: []SubjectTypeRef{{Type: "user"}}},
+			{Name: "restricted", ExcludedRelations: []string{"blocked"}, SubjectTypeRefs: []SubjectTypeRef{
+				{Type: "user", Condition: inRegion},
+			}},
+		}},
+	}
+
+	analyses := ComputeCanGenerate(AnalyzeRelations(types, ComputeRelationClosure(types)))
+	lookup := make(map[string]*RelationAnalysis)
+	for i := range analyses {
+		if analyses[i].ObjectType == "doc" {
+			lookup[analyses[i].Relation] = &analyses[i]
+		}
+	}
+
+	viewer := lookup["viewer"]
+	if !viewer.Capabilities.CheckAllowed || viewer.Capabilities.ListAllowed {
+		t.Errorf("doc.viewer: want check only, got %+v", viewer.Capabilities)
+	}
+	if !strings.Contains(viewer.Capabilities.ListReason, "direct and implied relations") {
+		t.Errorf("doc.viewer: ListReason = %q", viewer.Capabilities.ListReason)
+	}
+	if c := lookup["reader"].Capabilities; !c.CheckAllowed || !c.ListAllowed {
+		t.Errorf("doc.reader: conditioned direct/implied relation must be listable, got %+v", c)
+	}
+	if len(viewer.Conditions) != 1 || viewer.Conditions[0].SubjectType != "user" || viewer.Conditions[0].Name != "in_region" {
+		t.Errorf("doc.viewer conditions = %+v", viewer.Conditions)
+	}
+	for _, st := range viewer.DirectSubjectTypes {
+		if st == "user" {
+			t.Errorf("doc.viewer: conditional subject type user must not be a direct subject type: %v", viewer.DirectSubjectTypes)
+		}
+	}
+	if !lookup["owner"].Capabilities.CheckAllowed || !lookup["owner"].Capabilities.ListAllowed {
+		t.Errorf("doc.owner: unconditioned relation must stay generated, got %+v", lookup["owner"].Capabilities)
+	}
+
+	disabled := map[string]string{
+		"can_view":   "depends on doc.viewer",
+		"can_share":  "depends on doc.can_view, which is not generated",
+		"mixed":      "granted both with and without condition",
+		"wild":       "conditional wildcard",
+		"invalid":    "condition bad:",
+		"restricted": "cannot be combined with an exclusion",
+	}
+	for rel, reason := range disabled {
+		c := lookup[rel].Capabilities
+		if c.CheckAllowed || c.ListAllowed {
+			t.Errorf("doc.%s: want not generated, got %+v", rel, c)
+		}
+		if !strings.Contains(c.CheckReason, reason) {
+			t.Errorf("doc.%s: CheckReason = %q, want it to mention %q", rel, c.CheckReason, reason)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package analysis
+
+import (
+	"slices"
+	"strings"
+)
+
+// isPurelyImplied reports whether f's only access path is implication by
+// other relations on the same type, as in "define a: b".
+func isPurelyImplied(f RelationFeatures) bool {
+	return f.HasImplied && !f.HasDirect && !f.HasUserset && !f.HasRecursive &&
+		!f.HasExclusion && !f.HasIntersection && !f.HasCondition
+}
+
+// markBaselessImpliedCycles sets cycleReason on relations that can never be
+// satisfied because they only imply through relations that loop back without
+// reaching a base, as in "define a: b" and "define b: a". Every relation
+// reachable from such a relation is purely implied, so no tuple, userset or
+// parent can ever grant it; generating it would only recurse until the depth
+// limit. Schema validation rejects these models, so this guards callers that
+// analyze a model without validating it first.
+func markBaselessImpliedCycles(analyses []RelationAnalysis) {
+	implied := make(map[string]map[string][]string)
+	for _, a := range analyses {
+		if !isPurelyImplied(a.Features) {
+			continue
+		}
+		if implied[a.ObjectType] == nil {
+			implied[a.ObjectType] = make(map[string][]string)
+		}
+		implied[a.ObjectType][a.Relation] = a.DirectImpliedBy
+	}
+
+	for i := range analyses {
+		a := &analyses[i]
+		graph := implied[a.ObjectType]
+		if _, ok := graph[a.Relation]; !ok {
+			continue
+		}
+		if cycle := baselessCycle(graph, a.Relation); cycle != nil {
+			a.cycleReason = "cyclic implication without base: " + strings.Join(cycle, " -> ")
+		}
+	}
+}
+
+// baselessCycle returns a cycle reachable from rel when every relation
+// reachable from it is in graph, the purely implied relations of one type,
+// and nil when some path reaches a relation with a base.
+func baselessCycle(graph map[string][]string, rel string) []string {
+	seen := map[string]bool{rel: true}
+	queue := []string{rel}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, s := range graph[next] {
+			if _, ok := graph[s]; !ok {
+				return nil
+			}
+			if !seen[s] {
+				seen[s] = true
+				queue = append(queue, s)
+			}
+		}
+	}
+
+	// Every relation reached has an outgoing implication and the set is
+	// finite, so following the first one from rel must revisit a relation.
+	var path []string
+	for cur := rel; ; cur = graph[cur][0] {
+		if i := slices.Index(path, cur); i >= 0 {
+			return append(path[i:], cur)
+		}
+		path = append(path, cur)
+	}
+}
@@ -0,0 +1,45 @@
+package analysis
+
+import "testing"
+
+func TestBaselessImpliedCycle(t *testing.T) {
+	types := []TypeDefinition{
+		{Name: "user"},
+		{Name: "doc", Relations: []RelationDefinition{
+			// a and b only imply each other; c implies into the loop.
+			{Name: "a", ImpliedBy: []string{"b"}},
+			{Name: "b", ImpliedBy: []string{"a"}},
+			{Name: "c", ImpliedBy: []string{"a"}},
+			// owner and editor loop too, but owner has a base.
+			{Name: "owner", ImpliedBy: []string{"editor"}, SubjectTypeRefs: []SubjectTypeRef{{Type: "user"}}},
+			{Name: "editor", ImpliedBy: []string{"owner"}},
+			// viewer has a base and implies a: the loop adds nothing to it.
+			{Name: "viewer", ImpliedBy: []string{"a"}, SubjectTypeRefs: []SubjectTypeRef{{Type: "user"}}},
+		}},
+	}
+
+	analyses := ComputeCanGenerate(AnalyzeRelations(types, ComputeRelationClosure(types)))
+	lookup := make(map[string]*RelationAnalysis)
+	for i := range analyses {
+		lookup[analyses[i].Relation] = &analyses[i]
+	}
+
+	for rel, reason := range map[string]string{
+		"a": "cyclic implication without base: a -> b -> a",
+		"b": "cyclic implication without base: b -> a -> b",
+		"c": "cyclic implication without base: a -> b -> a",
+	} {
+		caps := lookup[rel].Capabilities
+		if caps.CheckAllowed || caps.ListAllowed {
+			t.Errorf("doc.%s: generation allowed, want it refused", rel)
+		}
+		if caps.CheckReason != reason || caps.ListReason != reason {
+			t.Errorf("doc.%s reasons = %q / %q, want %q", rel, caps.CheckReason, caps.ListReason, reason)
+		}
+	}
+	for _, rel := range []string{"owner", "editor", "viewer"} {
+		if a := lookup[rel]; !a.Capabilities.CheckAllowed || a.cycleReason != "" {
+			t.Errorf("doc.%s: want check generated, got %+v", rel, a.Capabilities)
+		}
+	}
+}
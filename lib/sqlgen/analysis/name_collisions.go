@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+)
+
+// NameCollision is a set of relations whose generated function names fold to
+// the same identifier. PostgreSQL folds unquoted identifiers to lowercase, so
+// "document.Viewer" and "document.viewer" would otherwise both compile to
+// check_document_viewer and overwrite each other.
+type NameCollision struct {
+	// Identifier is the folded "type_relation" the relations share.
+	Identifier string
+	// Relations are the colliding relations as "type.relation", sorted.
+	Relations []string
+	// Kept is the relation that keeps the plain name: the first one whose
+	// type and relation are already folded ("document.viewer"), so adding
+	// "Viewer" to a schema never renames the functions of "viewer". Empty
+	// when none is, in which case every relation's names are hashed.
+	Kept string
+}
+
+// FindNameCollisions returns the relations of types whose function names
+// would collide, sorted by identifier. Every relation of a collision except
+// Kept gets the hash suffix of sqldsl.RelationIdentifier in all of its
+// function names, and the dispatchers route to those names.
+func FindNameCollisions(types []TypeDefinition) []NameCollision {
+	type member struct{ objectType, relation string }
+	groups := make(map[string][]member)
+	for _, t := range types {
+		for _, r := range t.Relations {
+			id := sqldsl.Ident(t.Name) + "_" + sqldsl.Ident(r.Name)
+			groups[id] = append(groups[id], member{t.Name, r.Name})
+		}
+	}
+
+	var collisions []NameCollision
+	for id, members := range groups {
+		if len(members) < 2 {
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool {
+			if members[i].objectType != members[j].objectType {
+				return members[i].objectType < members[j].objectType
+			}
+			return members[i].relation < members[j].relation
+		})
+		c := NameCollision{Identifier: id}
+		for _, m := range members {
+			key := m.objectType + "." + m.relation
+			c.Relations = append(c.Relations, key)
+			if c.Kept == "" && sqldsl.Ident(m.objectType) == m.objectType && sqldsl.Ident(m.relation) == m.relation {
+				c.Kept = key
+			}
+		}
+		collisions = append(collisions, c)
+	}
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i].Identifier < collisions[j].Identifier })
+	return collisions
+}
+
+// nameCollisionIndex returns the relations of collisions whose names are
+// hashed, or nil when there are none.
+func nameCollisionIndex(types []TypeDefinition) sqldsl.NameCollisions {
+	var index sqldsl.NameCollisions
+	for _, c := range FindNameCollisions(types) {
+		for _, key := range c.Relations {
+			if key == c.Kept {
+				continue
+			}
+			// Relation names cannot contain dots; type names can.
+			dot := strings.LastIndex(key, ".")
+			objectType, relation := key[:dot], key[dot+1:]
+			if index == nil {
+				index = make(sqldsl.NameCollisions)
+			}
+			if index[objectType] == nil {
+				index[objectType] = make(map[string]bool)
+			}
+			index[objectType][relation] = true
+		}
+	}
+	return index
+}
@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindNameCollisions(t *testing.T) {
+	user := []SubjectTypeRef{{Type: "user"}}
+	types := []TypeDefinition{
+		{Name: "user"},
+		{Name: "document", Relations: []RelationDefinition{
+			{Name: "viewer", SubjectTypeRefs: user},
+			{Name: "Viewer", SubjectTypeRefs: user},
+			{Name: "Owner", SubjectTypeRefs: user},
+			{Name: "OWNER", SubjectTypeRefs: user},
+			{Name: "editor", SubjectTypeRefs: user},
+		}},
+		{Name: "folder", Relations: []RelationDefinition{
+			{Name: "Viewer", SubjectTypeRefs: user},
+		}},
+	}
+
+	got := FindNameCollisions(types)
+	want := []NameCollision{
+		{Identifier: "document_owner", Relations: []string{"document.OWNER", "document.Owner"}},
+		{Identifier: "document_viewer", Relations: []string{"document.Viewer", "document.viewer"}, Kept: "document.viewer"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindNameCollisions() = %+v, want %+v", got, want)
+	}
+
+	analyses := AnalyzeRelations(types, ComputeRelationClosure(types))
+	for _, a := range analyses {
+		hashed := a.NameCollisions.Hashed(a.ObjectType, a.Relation)
+		wantHashed := a.ObjectType == "document" && (a.Relation == "Viewer" || a.Relation == "Owner" || a.Relation == "OWNER")
+		if hashed != wantHashed {
+			t.Errorf("%s.%s hashed = %v, want %v", a.ObjectType, a.Relation, hashed, wantHashed)
+		}
+	}
+
+	// folder.Viewer collides with nothing, so its names stay as they were.
+	if got := FindNameCollisions(types[2:]); got != nil {
+		t.Errorf("FindNameCollisions(folder) = %+v, want none", got)
+	}
+	if analyses := AnalyzeRelations(types[2:], nil); analyses[0].NameCollisions != nil {
+		t.Errorf("NameCollisions = %v, want nil without collisions", analyses[0].NameCollisions)
+	}
+}
@@ -0,0 +1,72 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCallArgs_NamedAndDefaultsOmitted(t *testing.T) {
+	got := Func{Name: "list_doc_viewer_obj", Args: listObjectsCallArgs(SubjectType, SubjectID)}.SQL()
+	want := "list_doc_viewer_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id)"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = Func{Name: "list_doc_viewer_sub", Args: listSubjectsCallArgs(Col{Table: "link", Column: "subject_id"}, SubjectType)}.SQL()
+	want = "list_doc_viewer_sub(p_object_id => link.subject_id, p_subject_type => p_subject_type)"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCallArgs_FollowsSignatureOrder(t *testing.T) {
+	// Values are keyed by name, so the rendered order comes from the signature
+	// rather than from the caller.
+	args := CallArgs(ListSubjectsDispatcherArgs(), map[string]Expr{
+		"p_subject_type": SubjectType,
+		"p_relation":     Lit("viewer"),
+		"p_object_id":    ObjectID,
+		"p_object_type":  ObjectType,
+		"p_limit":        Int(10),
+	})
+	got := Func{Name: "list_accessible_subjects", Args: args}.SQL()
+	want := "list_accessible_subjects(p_object_type => p_object_type, p_object_id => p_object_id, p_relation => 'viewer', p_subject_type => p_subject_type, p_limit => 10)"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCallArgs_PanicsOnSignatureMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]Expr
+	}{
+		{"missing required", map[string]Expr{"p_subject_type": SubjectType}},
+		{"unknown argument", map[string]Expr{"p_subject_type": SubjectType, "p_subject_id": SubjectID, "p_tenant": Lit("t")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			CallArgs(ListObjectsArgs(), tt.values)
+		})
+	}
+}
+
+func TestListDispatcher_ForwardsNamedArgs(t *testing.T) {
+	analyses := []RelationAnalysis{{
+		ObjectType:   "doc",
+		Relation:     "viewer",
+		Capabilities: GenerationCapabilities{ListAllowed: true},
+	}}
+	sql, err := generateListObjectsDispatcher(analyses, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sql, "list_doc_viewer_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id, p_limit => p_limit, p_after => p_after, p_subject_types => p_subject_types, p_exclude_relation => p_exclude_relation, p_context => p_context, p_consistency => p_consistency)") {
+		t.Errorf("dispatcher does not forward named args:\n%s", sql)
+	}
+}
@@ -81,14 +81,15 @@ func BuildCheckBlocks(plan CheckPlan) (CheckBlocks, error) {
 }
 
 func buildDirectCheck(plan CheckPlan) Expr {
-	q := Tuples(plan.DatabaseSchema, "").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "").
 		ObjectType(plan.ObjectType).
 		Relations(plan.RelationList...).
 		Where(
 			Eq{Left: Col{Column: "object_id"}, Right: ObjectID},
 			In{Expr: Col{Column: "subject_type"}, Values: plan.AllowedSubjectTypes},
 			Eq{Left: Col{Column: "subject_type"}, Right: SubjectType},
-			SubjectIDMatch(Col{Column: "subject_id"}, SubjectID, plan.AllowWildcard),
+			plan.directSubjectIDMatch(Col{Column: "subject_id"}),
+			conditionalTupleGuard(plan.Analysis, ""),
 		).
 		Select("1")
 
@@ -115,6 +116,10 @@ func buildUsersetCheck(plan CheckPlan) Expr {
 	checks := make([]Expr, 0, len(sorted))
 
 	for _, pattern := range sorted {
+		if usesSelfRefClosure(plan, pattern) {
+			checks = append(checks, buildSelfRefUsersetCheck(plan, pattern, visitedWithKey))
+			continue
+		}
 		checks = append(checks, buildUsersetPatternCheck(plan, pattern, visitedWithKey))
 	}
 
@@ -134,9 +139,9 @@ func buildUsersetPatternCheck(plan CheckPlan, pattern UsersetPattern, visitedWit
 		Eq{Left: UsersetRelation{Source: grantTuple}, Right: Lit(pattern.SubjectRelation)},
 	}
 
-	if pattern.IsComplex {
-		// Complex pattern: use check_permission_internal for recursive membership verification
-		q := Tuples(plan.DatabaseSchema, "grant_tuple").
+	if pattern.IsComplex || plan.Strict {
+		// Complex pattern (or strict variant): use check_permission_internal for recursive membership verification
+		q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "grant_tuple").
 			ObjectType(plan.ObjectType).
 			Relations(plan.Relation).
 			Where(append(baseWhere, CheckPermission{
@@ -155,7 +160,7 @@ func buildUsersetPatternCheck(plan CheckPlan, pattern UsersetPattern, visitedWit
 	}
 
 	// Simple pattern: use tuple JOIN for membership lookup
-	q := Tuples(plan.DatabaseSchema, "grant_tuple").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "grant_tuple").
 		ObjectType(plan.ObjectType).
 		Relations(plan.Relation).
 		Where(baseWhere...).
@@ -180,7 +185,7 @@ func buildExclusionCheck(plan CheckPlan) Expr {
 
 	// Simple exclusions: EXISTS (excluded tuple)
 	for _, rel := range plan.Exclusions.SimpleExcludedRelations {
-		q := Tuples(plan.DatabaseSchema, "excl").
+		q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "excl").
 			ObjectType(plan.ObjectType).
 			Relations(rel).
 			Where(
@@ -216,7 +221,7 @@ func buildExclusionCheck(plan CheckPlan) Expr {
 }
 
 func buildTTUExclusionCheck(plan CheckPlan, rel ExcludedParentRelation) Expr {
-	linkQuery := Tuples(plan.DatabaseSchema, "link").
+	linkQuery := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "link").
 		ObjectType(plan.ObjectType).
 		Relations(rel.LinkingRelation).
 		Where(
@@ -411,27 +416,13 @@ func buildUsersetSubjectChecks(plan CheckPlan) (selfCheck, computedCheck SelectS
 	// t.relation) and fed c.satisfying_relation to the m join. That set is exactly
 	// plan.Analysis.SatisfyingRelations, so test t.relation against it directly and
 	// drop the `c` closure VALUES entirely — leaving only the subject-side subj_c.
+	subjClosure, subjectMatch := usersetSubjClosureJoin(plan)
 	computedCheck = SelectStmt{
 		ColumnExprs: []Expr{Int(1)},
-		FromExpr:    TableAs("", "melange_tuples", "t"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 		Joins: []JoinClause{
-			{
-				Type:      "INNER",
-				TableExpr: UsersetTable(plan.Inline.UsersetRows, "m"),
-				On: And(
-					Eq{Left: Col{Table: "m", Column: "object_type"}, Right: Lit(plan.ObjectType)},
-					Eq{Left: Col{Table: "m", Column: "relation"}, Right: Col{Table: "t", Column: "relation"}},
-					Eq{Left: Col{Table: "m", Column: "subject_type"}, Right: Col{Table: "t", Column: "subject_type"}},
-				),
-			},
-			{
-				Type:      "INNER",
-				TableExpr: ClosureTable(usersetSubjClosureRows(plan), "subj_c"),
-				On: And(
-					Eq{Left: Col{Table: "subj_c", Column: "object_type"}, Right: Col{Table: "t", Column: "subject_type"}},
-					Eq{Left: Col{Table: "subj_c", Column: "satisfying_relation"}, Right: UsersetRelation{Source: SubjectID}},
-				),
-			},
+			usersetRestrictionJoin(plan),
+			subjClosure,
 		},
 		Where: And(
 			Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
@@ -442,13 +433,7 @@ func buildUsersetSubjectChecks(plan CheckPlan) (selfCheck, computedCheck SelectS
 			In{Expr: Col{Table: "t", Column: "relation"}, Values: plan.Analysis.SatisfyingRelations},
 			Ne{Left: Col{Table: "t", Column: "subject_id"}, Right: Lit("*")},
 			HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
-			// Finding 1.3: equality on the indexed t.subject_id (sargable) folds the
-			// object-id match and the subj_c.relation join into one predicate —
-			// t.subject_id = <requested object>#<satisfying userset relation>.
-			Eq{
-				Left:  Col{Table: "t", Column: "subject_id"},
-				Right: NormalizedUsersetSubject(SubjectID, Col{Table: "subj_c", Column: "relation"}),
-			},
+			subjectMatch,
 		),
 		Limit: 1,
 	}
@@ -456,6 +441,83 @@ func buildUsersetSubjectChecks(plan CheckPlan) (selfCheck, computedCheck SelectS
 	return selfCheck, computedCheck
 }
 
+// usersetRestrictionJoin returns the m join of the userset-subject computed
+// check, keeping tuples whose userset type the tuple's relation accepts: the
+// userset VALUES, or with plan.Inline.ClosureFunction melange_userset_rows
+// keyed on the tuple's relation.
+func usersetRestrictionJoin(plan CheckPlan) JoinClause {
+	if plan.Inline.ClosureFunction {
+		return JoinClause{
+			Type: "INNER",
+			TableExpr: FunctionCallExpr{
+				Schema: plan.DatabaseSchema,
+				Name:   UsersetFunctionName,
+				Args:   []Expr{Lit(plan.ObjectType), Col{Table: "t", Column: "relation"}},
+				Alias:  "m",
+			},
+			On: Eq{Left: Col{Table: "m", Column: "subject_type"}, Right: Col{Table: "t", Column: "subject_type"}},
+		}
+	}
+	return JoinClause{
+		Type:      "INNER",
+		TableExpr: UsersetTable(plan.Inline.UsersetRows, "m"),
+		On: And(
+			Eq{Left: Col{Table: "m", Column: "object_type"}, Right: Lit(plan.ObjectType)},
+			Eq{Left: Col{Table: "m", Column: "relation"}, Right: Col{Table: "t", Column: "relation"}},
+			Eq{Left: Col{Table: "m", Column: "subject_type"}, Right: Col{Table: "t", Column: "subject_type"}},
+		),
+	}
+}
+
+// usersetSubjClosureJoin returns the subj_c join of the userset-subject
+// computed check and the predicate matching the tuple's userset against the
+// requested one.
+//
+// By default subj_c is the narrowed closure VALUES (usersetSubjClosureRows)
+// keyed on the requested relation, and Finding 1.3's equality on the indexed
+// t.subject_id (sargable) folds the object-id match and the subj_c.relation
+// join into one predicate — t.subject_id = <requested object>#<satisfying
+// userset relation>.
+//
+// With plan.Inline.ClosureFunction subj_c is melange_closure_rows keyed on the
+// tuple's own userset relation, which must have the requested relation among
+// its satisfying relations. No closure row names the stored relation up
+// front, so the object ids are compared instead; the lookup stays on the
+// (object_type, object_id, relation) prefix of the tuples index.
+func usersetSubjClosureJoin(plan CheckPlan) (JoinClause, Expr) {
+	if plan.Inline.ClosureFunction {
+		join := JoinClause{
+			Type: "INNER",
+			TableExpr: FunctionCallExpr{
+				Schema: plan.DatabaseSchema,
+				Name:   ClosureFunctionName,
+				Args: []Expr{
+					Col{Table: "t", Column: "subject_type"},
+					UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
+				},
+				Alias: "subj_c",
+			},
+			On: Eq{Left: Col{Table: "subj_c", Column: "satisfying_relation"}, Right: UsersetRelation{Source: SubjectID}},
+		}
+		return join, Eq{
+			Left:  UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}},
+			Right: UsersetObjectID{Source: SubjectID},
+		}
+	}
+	join := JoinClause{
+		Type:      "INNER",
+		TableExpr: ClosureTable(usersetSubjClosureRows(plan), "subj_c"),
+		On: And(
+			Eq{Left: Col{Table: "subj_c", Column: "object_type"}, Right: Col{Table: "t", Column: "subject_type"}},
+			Eq{Left: Col{Table: "subj_c", Column: "satisfying_relation"}, Right: UsersetRelation{Source: SubjectID}},
+		),
+	}
+	return join, Eq{
+		Left:  Col{Table: "t", Column: "subject_id"},
+		Right: NormalizedUsersetSubject(SubjectID, Col{Table: "subj_c", Column: "relation"}),
+	}
+}
+
 func buildParentRelationBlocks(plan CheckPlan) []ParentRelationBlock {
 	parents := make([]ParentRelationInfo, len(plan.Analysis.ParentRelations))
 	copy(parents, plan.Analysis.ParentRelations)
@@ -467,7 +529,7 @@ func buildParentRelationBlocks(plan CheckPlan) []ParentRelationBlock {
 	blocks := make([]ParentRelationBlock, 0, len(parents))
 
 	for _, parent := range parents {
-		q := Tuples(plan.DatabaseSchema, "link").
+		q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "link").
 			ObjectType(plan.ObjectType).
 			Relations(parent.LinkingRelation).
 			Where(Eq{Left: Col{Table: "link", Column: "object_id"}, Right: ObjectID}).
@@ -512,9 +574,12 @@ func buildImpliedFunctionCalls(plan CheckPlan) []ImpliedFunctionCheck {
 	calls := make([]ImpliedFunctionCheck, 0, len(relations))
 
 	for _, rel := range relations {
-		funcName := functionName(plan.ObjectType, rel)
+		funcName := functionName(plan.Analysis.NameCollisions, plan.ObjectType, rel)
 		if plan.NoWildcard {
-			funcName = functionNameForNoWildcardRef(plan.NeedsNoWildcard, plan.ObjectType, rel)
+			funcName = functionNameForNoWildcardRef(plan.Analysis.NameCollisions, plan.NeedsNoWildcard, plan.ObjectType, rel)
+		}
+		if plan.Strict {
+			funcName = functionNameForStrictRef(plan.Analysis.NameCollisions, plan.StrictIndex, plan.ObjectType, rel)
 		}
 
 		calls = append(calls, ImpliedFunctionCheck{
@@ -588,7 +653,7 @@ func intersectionPartScore(part IntersectionPart, plan CheckPlan) int {
 // Simple relations have no userset, recursion, exclusion, or intersection logic,
 // so they can be checked with a direct tuple lookup instead of a function call.
 func buildSimpleRelationCheck(plan CheckPlan, relation string) Expr {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(relation).
 		Where(
@@ -658,7 +723,7 @@ func buildIntersectionPartCheck(plan CheckPlan, part IntersectionPart, visitedWi
 }
 
 func buildParentCheck(plan CheckPlan, parent *ParentRelationInfo, visitedWithKey Expr) Expr {
-	q := Tuples(plan.DatabaseSchema, "link").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "link").
 		ObjectType(plan.ObjectType).
 		Relations(parent.LinkingRelation).
 		Where(
@@ -708,7 +773,7 @@ func buildThisCheck(plan CheckPlan, part IntersectionPart, visitedWithKey Expr)
 		)
 	}
 
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(part.Relation).
 		Where(
@@ -0,0 +1,126 @@
+package sqlgen
+
+import "fmt"
+
+// selfRefClosureCTE names the recursive CTE buildSelfRefUsersetCheck walks.
+const selfRefClosureCTE = "member_closure"
+
+// usesSelfRefClosure reports whether buildUsersetCheck resolves pattern with
+// buildSelfRefUsersetCheck: pattern names the relation being checked on its
+// own type, as [group#member] does on group.member, and a direct tuple on a
+// nested object is enough to hold the relation there. An exclusion or
+// intersection could deny on that object despite the tuple, and the strict
+// variant re-checks every userset, so those keep the
+// check_permission_internal walk.
+func usesSelfRefClosure(plan CheckPlan, pattern UsersetPattern) bool {
+	if pattern.SubjectType != plan.ObjectType || pattern.SubjectRelation != plan.Relation {
+		return false
+	}
+	return !plan.Strict && !plan.HasExclusion && !plan.HasIntersection
+}
+
+// selfRefClosureIsComplete reports whether direct tuples on the objects
+// reached through the self-referential userset are the only other way to
+// hold the relation. Any other path (another userset, a complex closure
+// relation or TTU) could grant on a nested object without a direct tuple,
+// so buildSelfRefUsersetCheck keeps the check_permission_internal walk as a
+// fallback for it.
+func selfRefClosureIsComplete(plan CheckPlan) bool {
+	if plan.HasParentRelations || len(plan.ComplexClosure) > 0 {
+		return false
+	}
+	for _, pattern := range plan.Analysis.UsersetPatterns {
+		if pattern.SubjectType != plan.ObjectType || pattern.SubjectRelation != plan.Relation {
+			return false
+		}
+	}
+	return true
+}
+
+// buildSelfRefUsersetCheck resolves a self-referential userset such as
+// [group#member] on group.member with one recursive CTE, mirroring the
+// member_expansion CTE of the list functions: it walks from p_object_id
+// through nested group:x#member grants and looks for a direct tuple for the
+// subject on any object it reaches, so group:a#member -> group:b#member ->
+// user:x is answered without a check_permission_internal call per level.
+//
+// The walk starts at the length of p_visited and stops at the plan's depth
+// limit, raising M2002 through depthLimitGuard when it is cut short, so the
+// limit is shared with the calls that led here. Objects whose key is already
+// in p_visited are not expanded, as check_permission_internal would deny
+// them as a cycle.
+//
+// The recursive walk remains as a fallback when the relation has other
+// access paths (see selfRefClosureIsComplete), and for userset subjects,
+// whose matches on nested objects the top-level userset subject checks
+// resolve.
+func buildSelfRefUsersetCheck(plan CheckPlan, pattern UsersetPattern, visitedWithKey Expr) Expr {
+	me := Col{Table: "mc", Column: "object_id"}
+	grant := Col{Table: "t", Column: "subject_id"}
+
+	base := SelectStmt{
+		ColumnExprs: []Expr{ObjectID, Raw("COALESCE(array_length(p_visited, 1), 0)")},
+	}
+	recursive := SelectStmt{
+		Distinct:    true,
+		ColumnExprs: []Expr{UsersetObjectID{Source: grant}, Raw("mc.depth + 1")},
+		FromExpr:    TableAs("", selfRefClosureCTE, "mc"),
+		Joins: []JoinClause{{
+			Type:  "INNER",
+			Table: TuplesTableName(plan.TuplesTable),
+			Alias: "t",
+			On:    Eq{Left: Col{Table: "t", Column: "object_id"}, Right: me},
+		}},
+		Where: And(
+			Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
+			Eq{Left: Col{Table: "t", Column: "relation"}, Right: Lit(plan.Relation)},
+			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(plan.ObjectType)},
+			HasUserset{Source: grant},
+			Eq{Left: UsersetRelation{Source: grant}, Right: Lit(plan.Relation)},
+			Not(ArrayContains{Value: VisitedKey(plan.ObjectType, plan.Relation, UsersetObjectID{Source: grant}), Array: Visited}),
+			Lt{Left: Col{Table: "mc", Column: "depth"}, Right: Int(plan.maxDepth())},
+		),
+	}
+	cteBody := appendUnionAll(
+		formatQueryBlockSQL(nil, base.SQL()),
+		formatQueryBlockSQL([]string{
+			"-- Self-referential userset expansion",
+			fmt.Sprintf("-- Patterns like [%s#%s] on %s.%s", pattern.SubjectType, pattern.SubjectRelation, plan.ObjectType, plan.Relation),
+		}, recursive.SQL()),
+	)
+
+	direct := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "d").
+		ObjectType(plan.ObjectType).
+		Relations(plan.RelationList...).
+		Where(
+			Eq{Left: Col{Table: "d", Column: "object_id"}, Right: me},
+			In{Expr: Col{Table: "d", Column: "subject_type"}, Values: plan.AllowedSubjectTypes},
+			Eq{Left: Col{Table: "d", Column: "subject_type"}, Right: SubjectType},
+			plan.directSubjectIDMatch(Col{Table: "d", Column: "subject_id"}),
+			conditionalTupleGuard(plan.Analysis, "d"),
+		).
+		Select("1")
+
+	closure := Exists{Query: WithCTE{
+		Recursive: true,
+		CTEs: []CTEDef{{
+			Name:    selfRefClosureCTE,
+			Columns: []string{"object_id", "depth"},
+			Query:   Raw(cteBody),
+		}},
+		Query: SelectStmt{
+			ColumnExprs: []Expr{Int(1)},
+			FromExpr:    TableAs("", selfRefClosureCTE, "mc"),
+			Where: And(
+				depthLimitGuard(plan.DatabaseSchema, selfRefClosureCTE, []string{"object_id"}, plan.maxDepth()),
+				Exists{Query: direct},
+			),
+		},
+	}}
+
+	fallback := buildUsersetPatternCheck(plan, pattern, visitedWithKey)
+	if selfRefClosureIsComplete(plan) {
+		fallback = And(HasUserset{Source: SubjectID}, fallback)
+	}
+	return Or(closure, fallback)
+}
@@ -114,11 +114,12 @@ func TestCase2_SargableNarrowedCanonical(t *testing.T) {
 	sql := blocks.UsersetSubjectComputedCheck.SQL()
 
 	// (a) sargable equality on the indexed t.subject_id.
-	if !strings.Contains(sql, "t.subject_id = split_part(p_subject_id, '#', 1) || '#' || subj_c.relation") {
+	if !strings.Contains(sql, "t.subject_id = left(p_subject_id, length(p_subject_id) - strpos(reverse(p_subject_id), '#')) || '#' || subj_c.relation") {
 		t.Errorf("missing sargable subject_id equality:\n%s", sql)
 	}
-	// (b) canonical parse: no substring form survives.
-	if strings.Contains(sql, "substring(") {
+	// (b) canonical parse: the object ID goes through UsersetObjectID, not an
+	// ad hoc substring on the first '#'.
+	if strings.Contains(sql, "substring(p_subject_id from 1 for") {
 		t.Errorf("non-canonical substring parse survived:\n%s", sql)
 	}
 	// (c) narrowed VALUES: keep the one compatible row, drop the two unrelated.
@@ -0,0 +1,92 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+// check_any / check_all must route every relation through the check_permission
+// dispatcher (so they share its closure, userset, and depth-limit semantics)
+// and short-circuit via EXISTS / NOT EXISTS rather than aggregating all rows.
+func TestCheckCombinators(t *testing.T) {
+	t.Run("check_any", func(t *testing.T) {
+		sql := generateCheckAnyDispatcher("", false)
+		for _, want := range []string{
+			"CREATE OR REPLACE FUNCTION check_any(",
+			"p_relations TEXT[]",
+			"RETURNS BOOLEAN",
+			"SELECT EXISTS (",
+			"FROM unnest(p_relations) AS r(relation)",
+			"check_permission(p_subject_type, p_subject_id, r.relation, p_object_type, p_object_id) = 1",
+		} {
+			if !strings.Contains(sql, want) {
+				t.Errorf("check_any missing %q in:\n%s", want, sql)
+			}
+		}
+	})
+
+	t.Run("check_all", func(t *testing.T) {
+		sql := generateCheckAllDispatcher("", false)
+		for _, want := range []string{
+			"CREATE OR REPLACE FUNCTION check_all(",
+			"SELECT NOT EXISTS (",
+			"check_permission(p_subject_type, p_subject_id, r.relation, p_object_type, p_object_id) <> 1",
+		} {
+			if !strings.Contains(sql, want) {
+				t.Errorf("check_all missing %q in:\n%s", want, sql)
+			}
+		}
+	})
+
+	t.Run("schema qualified", func(t *testing.T) {
+		sql := generateCheckAnyDispatcher("authz", false)
+		if !strings.Contains(sql, `"authz"."check_any"(`) || !strings.Contains(sql, `"authz"."check_permission"(`) {
+			t.Errorf("expected schema-qualified function and dispatcher call, got:\n%s", sql)
+		}
+		if strings.Contains(sql, "SET search_path") {
+			t.Errorf("combinator calls only qualified functions and should omit search_path:\n%s", sql)
+		}
+	})
+
+	t.Run("collected as dispatchers", func(t *testing.T) {
+		gen, err := GenerateSQL(nil, InlineSQLData{}, "")
+		if err != nil {
+			t.Fatalf("GenerateSQL: %v", err)
+		}
+		names := make(map[string]bool)
+		for _, nf := range CollectDispatcherFunctions(gen, ListGeneratedSQL{}) {
+			names[nf.Name] = true
+		}
+		if !names["check_any"] || !names["check_all"] {
+			t.Errorf("CollectDispatcherFunctions missing combinators: %v", names)
+		}
+	})
+}
+
+// check_permission_batch must route every distinct element through the
+// check_permission dispatcher and return rows in input order.
+func TestBatchDispatcher(t *testing.T) {
+	sql := generateBatchDispatcher("authz", false)
+	for _, want := range []string{
+		`CREATE OR REPLACE FUNCTION "authz"."check_permission_batch"(`,
+		"p_checks JSONB",
+		"RETURNS TABLE(index INTEGER, allowed BOOLEAN)",
+		"jsonb_array_elements(p_checks) WITH ORDINALITY",
+		"c.value->>'subject_type' AS subject_type",
+		"results AS MATERIALIZED (",
+		"SELECT DISTINCT subject_type, subject_id, relation, object_type, object_id FROM requests",
+		`"authz"."check_permission"(d.subject_type, d.subject_id, d.relation, d.object_type, d.object_id) = 1 AS allowed`,
+		"ORDER BY r.idx",
+		"PARALLEL RESTRICTED",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("check_permission_batch missing %q in:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "SET search_path") {
+		t.Errorf("batch dispatcher calls only qualified functions and should omit search_path:\n%s", sql)
+	}
+	if memo := generateBatchDispatcher("", true); !strings.Contains(memo, "PARALLEL UNSAFE") {
+		t.Errorf("memoized batch dispatcher must be PARALLEL UNSAFE:\n%s", memo)
+	}
+}
@@ -0,0 +1,210 @@
+package sqlgen
+
+import "fmt"
+
+// EvidenceFunctionPrefix starts the name of every audit variant of a check
+// function emitted when GenerateSQLOptions.EnableCheckEvidence is set.
+const EvidenceFunctionPrefix = "check_with_evidence_"
+
+// evidenceFunctionName returns the name of the audit variant of a check
+// function.
+func evidenceFunctionName(collisions NameCollisions, objectType, relation string) string {
+	return RelationIdentifier(collisions, EvidenceFunctionPrefix, objectType, relation, "")
+}
+
+// evidenceColumns are the tuple columns an evidence function reports.
+var evidenceColumns = []string{"object_type", "object_id", "relation", "subject_type", "subject_id"}
+
+// evidenceOrder keeps the reported tuple deterministic when several qualify:
+// exact subject matches before wildcards, then by relation and subject.
+const evidenceOrder = "ORDER BY (CASE WHEN %[1]s.subject_id = '*' THEN 1 ELSE 0 END), %[1]s.relation, %[1]s.subject_type, %[1]s.subject_id\nLIMIT 1"
+
+// CollectEvidenceFunctionNames returns the check_with_evidence_* names
+// generated for analyses when GenerateSQLOptions.EnableCheckEvidence is set.
+func CollectEvidenceFunctionNames(analyses []RelationAnalysis) []string {
+	var names []string
+	for _, a := range analyses {
+		if a.Capabilities.CheckAllowed {
+			names = append(names, evidenceFunctionName(a.NameCollisions, a.ObjectType, a.Relation))
+		}
+	}
+	return names
+}
+
+// generateEvidenceFunction renders check_with_evidence_{type}_{relation},
+// which answers the same question as check_{type}_{relation} and also reports
+// the tuple that granted access.
+//
+// The decision comes from check_permission_internal, so the answer always
+// matches check_permission. When it allows, the function looks for evidence in
+// order of how directly the tuple grants:
+//   - a direct tuple on the object in the relation's simple closure (the
+//     direct check block, selecting tuple columns instead of 1)
+//   - a userset grant tuple (group:eng#member) whose userset holds the subject
+//   - a TTU linking tuple whose parent grants the subject the parent relation
+//   - the evidence of a complex implied relation, via its own evidence function
+//   - any tuple on the object naming the subject, as the closest tuple in the
+//     path of a purely computed grant such as an intersection
+//
+// If none exists the row is allowed with NULL tuple columns.
+func generateEvidenceFunction(a RelationAnalysis, inline InlineSQLData, databaseSchema, tuplesTable string, evidenceRelations map[string]map[string]bool) string {
+	plan := BuildCheckPlan(a, inline, databaseSchema, false).withTuplesTable(tuplesTable)
+
+	body := []Stmt{
+		If{
+			Cond: CheckPermission{
+				Schema:      databaseSchema,
+				Subject:     SubjectParams(),
+				Relation:    a.Relation,
+				Object:      LiteralObject(a.ObjectType, ObjectID),
+				ExpectAllow: false,
+			},
+			Then: []Stmt{
+				ReturnQuery{Query: SelectStmt{ColumnExprs: evidenceRow(Bool(false), "")}.SQL()},
+				Return{},
+			},
+		},
+	}
+
+	found := If{Cond: Raw("FOUND"), Then: []Stmt{Return{}}}
+	addStep := func(comment, query string) {
+		body = append(body, Comment{Text: comment}, ReturnQuery{Query: query}, found)
+	}
+
+	if plan.HasDirect || plan.HasImplied {
+		q := TuplesWithTable(tuplesTable, databaseSchema, "t").
+			ObjectType(a.ObjectType).
+			Relations(plan.RelationList...).
+			Where(
+				Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
+				In{Expr: Col{Table: "t", Column: "subject_type"}, Values: plan.AllowedSubjectTypes},
+				Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: SubjectType},
+				plan.directSubjectIDMatch(Col{Table: "t", Column: "subject_id"}),
+				conditionalTupleGuard(a, "t"),
+			)
+		addStep("Direct tuple", evidenceTupleQuery(q, "t"))
+	}
+
+	for _, pattern := range a.UsersetPatterns {
+		grantTuple := Col{Table: "t", Column: "subject_id"}
+		q := TuplesWithTable(tuplesTable, databaseSchema, "t").
+			ObjectType(a.ObjectType).
+			Relations(a.Relation).
+			Where(
+				Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
+				Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(pattern.SubjectType)},
+				HasUserset{Source: grantTuple},
+				Eq{Left: UsersetRelation{Source: grantTuple}, Right: Lit(pattern.SubjectRelation)},
+				CheckPermission{
+					Schema:   databaseSchema,
+					Subject:  SubjectParams(),
+					Relation: pattern.SubjectRelation,
+					Object: ObjectRef{
+						Type: Lit(pattern.SubjectType),
+						ID:   UsersetObjectID{Source: grantTuple},
+					},
+					ExpectAllow: true,
+				},
+			)
+		addStep("Userset grant: "+pattern.SubjectType+"#"+pattern.SubjectRelation, evidenceTupleQuery(q, "t"))
+	}
+
+	for _, parent := range a.ParentRelations {
+		q := TuplesWithTable(tuplesTable, databaseSchema, "t").
+			ObjectType(a.ObjectType).
+			Relations(parent.LinkingRelation).
+			Where(
+				Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
+				CheckPermission{
+					Schema:   databaseSchema,
+					Subject:  SubjectParams(),
+					Relation: parent.Relation,
+					Object: ObjectRef{
+						Type: Col{Table: "t", Column: "subject_type"},
+						ID:   Col{Table: "t", Column: "subject_id"},
+					},
+					ExpectAllow: true,
+				},
+			)
+		if len(parent.AllowedLinkingTypes) > 0 {
+			q.WhereSubjectTypeIn(parent.AllowedLinkingTypes...)
+		}
+		addStep("Parent link: "+parent.Relation+" from "+parent.LinkingRelation, evidenceTupleQuery(q, "t"))
+	}
+
+	for _, rel := range a.ComplexClosureRelations {
+		if !evidenceRelations[a.ObjectType][rel] {
+			continue
+		}
+		q := SelectStmt{
+			ColumnExprs: []Expr{Raw("e.*")},
+			FromExpr: FunctionCallExpr{
+				Schema: databaseSchema,
+				Name:   evidenceFunctionName(a.NameCollisions, a.ObjectType, rel),
+				Args:   []Expr{SubjectType, SubjectID, ObjectID},
+				Alias:  "e",
+			},
+			Where: And(
+				Col{Table: "e", Column: "allowed"},
+				IsNotNull{Expr: Col{Table: "e", Column: "object_id"}},
+			),
+		}
+		addStep("Implied relation: "+rel, q.SQL())
+	}
+
+	q := TuplesWithTable(tuplesTable, databaseSchema, "t").
+		ObjectType(a.ObjectType).
+		Where(
+			Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
+			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: SubjectType},
+			SubjectIDMatch(Col{Table: "t", Column: "subject_id"}, SubjectID, true),
+		)
+	addStep("Closest tuple: any tuple on the object naming the subject", evidenceTupleQuery(q, "t"))
+
+	body = append(body,
+		Comment{Text: "Allowed with no single tuple to report"},
+		ReturnQuery{Query: SelectStmt{ColumnExprs: evidenceRow(Bool(true), "")}.SQL()},
+		Return{},
+	)
+
+	fn := PlpgsqlFunction{
+		Schema: databaseSchema,
+		Name:   evidenceFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+		Args: []FuncArg{
+			{Name: "p_subject_type", Type: "TEXT"},
+			{Name: "p_subject_id", Type: "TEXT"},
+			{Name: "p_object_id", Type: "TEXT"},
+		},
+		Returns: "TABLE (allowed BOOLEAN, object_type TEXT, object_id TEXT, relation TEXT, subject_type TEXT, subject_id TEXT)",
+		Body:    body,
+		Header: []string{
+			"Generated audit function for " + a.ObjectType + "." + a.Relation,
+			"Returns the check result and the tuple that granted it",
+			"Runs the full check plus one lookup per access path. Intended for audit logging, not request paths",
+		},
+	}
+	return fn.SQL() + "\n"
+}
+
+// evidenceTupleQuery selects the deterministic first tuple matched by q as an
+// allowed evidence row.
+func evidenceTupleQuery(q *TupleQuery, alias string) string {
+	q.SelectExpr(evidenceRow(Bool(true), alias)...)
+	return q.Build().SQL() + "\n" + fmt.Sprintf(evidenceOrder, alias)
+}
+
+// evidenceRow returns the evidence columns for allowed, read from the tuple
+// alias, or NULL when alias is empty.
+func evidenceRow(allowed Expr, alias string) []Expr {
+	row := []Expr{allowed}
+	for _, c := range evidenceColumns {
+		if alias == "" {
+			row = append(row, Cast{Expr: Null{}, Type: "TEXT"})
+			continue
+		}
+		// melange_tuples may be a view exposing varchar columns, which
+		// RETURN QUERY rejects against the TEXT result columns.
+		row = append(row, Cast{Expr: Col{Table: alias, Column: c}, Type: "TEXT"})
+	}
+	return row
+}
@@ -0,0 +1,92 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+// checkEvidenceAnalyses is document viewer granted directly, through a group
+// userset, a folder parent, and the complex implied relation editor.
+func checkEvidenceAnalyses() []RelationAnalysis {
+	check := GenerationCapabilities{CheckAllowed: true}
+	return []RelationAnalysis{
+		{ObjectType: "document", Relation: "editor", Capabilities: check,
+			Features:            RelationFeatures{HasDirect: true, HasExclusion: true},
+			DirectSubjectTypes:  []string{"user"},
+			SatisfyingRelations: []string{"editor"}},
+		{ObjectType: "document", Relation: "viewer", Capabilities: check,
+			Features:                RelationFeatures{HasDirect: true, HasUserset: true, HasRecursive: true},
+			DirectSubjectTypes:      []string{"user"},
+			SatisfyingRelations:     []string{"viewer", "editor"},
+			ComplexClosureRelations: []string{"editor"},
+			UsersetPatterns:         []UsersetPattern{{SubjectType: "group", SubjectRelation: "member"}},
+			ParentRelations:         []ParentRelationInfo{{Relation: "viewer", LinkingRelation: "parent", AllowedLinkingTypes: []string{"folder"}}}},
+		{ObjectType: "document", Relation: "owner", Features: RelationFeatures{HasDirect: true}},
+	}
+}
+
+func TestEvidenceFunction_SearchesEachAccessPath(t *testing.T) {
+	sql := generateEvidenceFunction(checkEvidenceAnalyses()[1], InlineSQLData{}, "", "", map[string]map[string]bool{
+		"document": {"editor": true, "viewer": true},
+	})
+
+	assertContains(t, sql, "CREATE OR REPLACE FUNCTION check_with_evidence_document_viewer(")
+	assertContains(t, sql, "RETURNS TABLE (allowed BOOLEAN, object_type TEXT, object_id TEXT, relation TEXT, subject_type TEXT, subject_id TEXT)")
+	assertContains(t, sql, "IF check_permission_internal(p_subject_type, p_subject_id, 'viewer', 'document', p_object_id, ARRAY[]::TEXT[]) = 0 THEN")
+	assertContains(t, sql, "SELECT FALSE, NULL::TEXT, NULL::TEXT, NULL::TEXT, NULL::TEXT, NULL::TEXT;")
+	assertContains(t, sql, "SELECT TRUE, t.object_type::TEXT, t.object_id::TEXT, t.relation::TEXT, t.subject_type::TEXT, t.subject_id::TEXT")
+	assertContains(t, sql, "check_permission_internal(p_subject_type, p_subject_id, 'member', 'group', left(t.subject_id, length(t.subject_id) - strpos(reverse(t.subject_id), '#')), ARRAY[]::TEXT[]) = 1")
+	assertContains(t, sql, "t.relation IN ('parent')")
+	assertContains(t, sql, "check_permission_internal(p_subject_type, p_subject_id, 'viewer', t.subject_type, t.subject_id, ARRAY[]::TEXT[]) = 1")
+	assertContains(t, sql, "FROM check_with_evidence_document_editor(p_subject_type, p_subject_id, p_object_id) AS e")
+	assertContains(t, sql, "LIMIT 1;")
+
+	// Evidence is searched from the most direct grant to the closest tuple.
+	order := []string{"-- Direct tuple", "-- Userset grant: group#member", "-- Parent link: viewer from parent", "-- Implied relation: editor", "-- Closest tuple"}
+	last := -1
+	for _, marker := range order {
+		i := strings.Index(sql, marker)
+		if i <= last {
+			t.Fatalf("%q out of order in:\n%s", marker, sql)
+		}
+		last = i
+	}
+}
+
+func TestEvidenceFunction_SkipsImpliedRelationsWithoutEvidence(t *testing.T) {
+	sql := generateEvidenceFunction(checkEvidenceAnalyses()[1], InlineSQLData{}, "", "", map[string]map[string]bool{
+		"document": {"viewer": true},
+	})
+	assertNotContains(t, sql, "check_with_evidence_document_editor")
+}
+
+func TestCheckEvidence_GatedByOption(t *testing.T) {
+	analyses := checkEvidenceAnalyses()
+
+	off, err := GenerateSQL(analyses, InlineSQLData{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(off.EvidenceFunctions) != 0 {
+		t.Fatalf("evidence functions generated without EnableCheckEvidence: %d", len(off.EvidenceFunctions))
+	}
+
+	on, err := GenerateSQLWithOptions(analyses, InlineSQLData{}, "authz", GenerateSQLOptions{EnableCheckEvidence: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(on.EvidenceFunctions) != 2 {
+		t.Fatalf("expected 2 evidence functions (owner has no check function), got %d", len(on.EvidenceFunctions))
+	}
+	assertContains(t, on.EvidenceFunctions[0], `CREATE OR REPLACE FUNCTION "authz"."check_with_evidence_document_editor"(`)
+
+	var names []string
+	for _, nf := range CollectNamedFunctions(on, ListGeneratedSQL{}, analyses) {
+		if strings.HasPrefix(nf.Name, EvidenceFunctionPrefix) {
+			names = append(names, nf.Name)
+		}
+	}
+	if got, want := strings.Join(names, ","), strings.Join(CollectEvidenceFunctionNames(analyses), ","); got != want {
+		t.Errorf("named evidence functions = %s, want %s", got, want)
+	}
+}
@@ -3,13 +3,16 @@ package sqlgen
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/pthm/melange/lib/sqlgen/sqldsl"
 )
 
-func generateCheckFunction(a RelationAnalysis, inline InlineSQLData, databaseSchema string, noWildcard bool, complexityByRelation map[string]map[string]int, needsNW map[string]map[string]bool) (string, error) {
-	plan := BuildCheckPlanWithOrdering(a, filterInlineForCheck(inline, a), databaseSchema, noWildcard, complexityByRelation)
+func generateCheckFunction(a RelationAnalysis, inline InlineSQLData, databaseSchema, tuplesTable string, noWildcard bool, complexityByRelation map[string]map[string]int, needsNW map[string]map[string]bool, maxDepth int, nullGuards bool) (string, error) {
+	plan := BuildCheckPlanWithOrdering(a, filterInlineForCheck(inline, a), databaseSchema, noWildcard, complexityByRelation).withTuplesTable(tuplesTable)
 	plan.NeedsNoWildcard = needsNW
+	plan.MaxDepth = maxDepth
+	plan.NullGuards = nullGuards
 	blocks, err := BuildCheckBlocks(plan)
 	if err != nil {
 		return "", fmt.Errorf("building check blocks for %s.%s: %w", a.ObjectType, a.Relation, err)
@@ -17,7 +20,7 @@ func generateCheckFunction(a RelationAnalysis, inline InlineSQLData, databaseSch
 	return RenderCheckFunction(plan, blocks)
 }
 
-func generateDispatcher(analyses []RelationAnalysis, databaseSchema string, noWildcard bool, needsNW map[string]map[string]bool) (string, error) {
+func generateDispatcher(analyses []RelationAnalysis, databaseSchema string, noWildcard bool, needsNW map[string]map[string]bool, maxDepth int, nullGuards bool) (string, error) {
 	fnName := "check_permission"
 	if noWildcard {
 		fnName = "check_permission_nw"
@@ -27,7 +30,7 @@ func generateDispatcher(analyses []RelationAnalysis, databaseSchema string, noWi
 	if len(cases) == 0 {
 		return renderEmptyDispatcher(databaseSchema, fnName), nil
 	}
-	return renderDispatcherWithCases(databaseSchema, fnName, cases), nil
+	return renderDispatcherWithCases(databaseSchema, fnName, cases, maxDepth, nullGuards), nil
 }
 
 func buildDispatcherCases(analyses []RelationAnalysis, databaseSchema string, noWildcard bool, needsNW map[string]map[string]bool) []DispatcherCase {
@@ -65,16 +68,13 @@ func isInlineable(f RelationFeatures) bool {
 		!f.HasUserset && !f.HasRecursive && !f.HasExclusion && !f.HasIntersection
 }
 
-func renderDispatcherWithCases(databaseSchema, fnName string, cases []DispatcherCase) string {
+func renderDispatcherWithCases(databaseSchema, fnName string, cases []DispatcherCase, maxDepth int, nullGuards bool) string {
 	internalName := fnName + "_internal"
 
 	body := append([]Stmt{
 		Comment{Text: "Depth limit check: prevent excessively deep permission resolution chains"},
 		Comment{Text: "This catches both recursive TTU patterns and long userset chains"},
-		If{
-			Cond: Gte{Left: ArrayLength{Array: Visited}, Right: Int(25)},
-			Then: []Stmt{Raise{Message: "resolution too complex", ErrCode: "M2002"}},
-		},
+		depthLimitCheck(maxDepth),
 	}, dispatchIfChain(cases, checkDispatchCall, Int(0))...)
 
 	internalFn := PlpgsqlFunction{
@@ -86,7 +86,7 @@ func renderDispatcherWithCases(databaseSchema, fnName string, cases []Dispatcher
 		Header: []string{
 			"Generated internal dispatcher for " + internalName,
 			"Routes to specialized functions with p_visited for cycle detection in TTU patterns",
-			"Enforces depth limit of 25 to prevent stack overflow from deep permission chains",
+			fmt.Sprintf("Enforces depth limit of %d to prevent stack overflow from deep permission chains", maxDepth),
 			"Phase 5: All relations use specialized functions - no generic fallback",
 		},
 		// The internal dispatcher routes to specialized functions that may recurse
@@ -96,14 +96,21 @@ func renderDispatcherWithCases(databaseSchema, fnName string, cases []Dispatcher
 		// Body references only schema-qualified check_{type}_{rel} calls, no
 		// unqualified melange_tuples — search_path is unnecessary here.
 		NoSearchPath: true,
+		NullGuards:   nullGuards,
 	}
 
-	publicFn := SqlFunction{
+	return internalFn.SQL() + "\n\n" + dispatcherPublicWrapper(databaseSchema, fnName).SQL() + "\n"
+}
+
+// dispatcherPublicWrapper is the public fnName entry point, which calls
+// fnName_internal with an empty p_visited.
+func dispatcherPublicWrapper(databaseSchema, fnName string) SqlFunction {
+	return SqlFunction{
 		Schema:  databaseSchema,
 		Name:    fnName,
 		Args:    dispatcherPublicArgs(),
 		Returns: "INTEGER",
-		Body:    Raw("SELECT " + sqldsl.PrefixIdent(internalName, databaseSchema) + "(p_subject_type, p_subject_id, p_relation, p_object_type, p_object_id, ARRAY[]::TEXT[])"),
+		Body:    Raw("SELECT " + sqldsl.PrefixIdent(fnName+"_internal", databaseSchema) + "(p_subject_type, p_subject_id, p_relation, p_object_type, p_object_id, ARRAY[]::TEXT[])"),
 		Header: []string{
 			"Generated dispatcher for " + fnName,
 			"Routes to specialized functions for all known type/relation pairs",
@@ -112,8 +119,6 @@ func renderDispatcherWithCases(databaseSchema, fnName string, cases []Dispatcher
 		// lets the planner inline this LANGUAGE sql wrapper.
 		NoSearchPath: true,
 	}
-
-	return internalFn.SQL() + "\n\n" + publicFn.SQL() + "\n"
 }
 
 func renderEmptyDispatcher(databaseSchema, fnName string) string {
@@ -142,12 +147,12 @@ func renderEmptyDispatcher(databaseSchema, fnName string) string {
 	return internalFn.SQL() + "\n\n" + publicFn.SQL() + "\n"
 }
 
-func generateBulkDispatcher(analyses []RelationAnalysis, databaseSchema string) string {
+func generateBulkDispatcher(analyses []RelationAnalysis, databaseSchema, tuplesTable string) string {
 	cases := buildDispatcherCases(analyses, databaseSchema, false, nil)
 	if len(cases) == 0 {
 		return renderEmptyBulkDispatcher(databaseSchema)
 	}
-	return renderBulkDispatcherWithCases(cases, databaseSchema)
+	return renderBulkDispatcherWithCases(cases, databaseSchema, tuplesTable)
 }
 
 func renderEmptyBulkDispatcher(databaseSchema string) string {
@@ -165,6 +170,151 @@ func renderEmptyBulkDispatcher(databaseSchema string) string {
 	return fn.SQL() + "\n"
 }
 
+// Combinator dispatcher names. Both take the same subject/object pair plus a
+// relation list and route every element through check_permission, so they
+// inherit its full semantics (closure, usersets, depth limit) for free.
+const (
+	checkAnyFunctionName = "check_any"
+	checkAllFunctionName = "check_all"
+)
+
+// generateCheckAnyDispatcher renders check_any, which returns TRUE when any of
+// p_relations grants access. EXISTS stops at the first matching row, so later
+// relations are never evaluated once one allows. memo marks it PARALLEL UNSAFE
+// to match the memoized check_permission it calls.
+func generateCheckAnyDispatcher(databaseSchema string, memo bool) string {
+	fn := SqlFunction{
+		Schema:  databaseSchema,
+		Name:    checkAnyFunctionName,
+		Args:    combinatorDispatcherArgs(),
+		Returns: "BOOLEAN",
+		Body: Raw("SELECT " + Exists{Query: combinatorRelationsQuery(
+			Eq{Left: combinatorCheckCall(databaseSchema), Right: Int(1)},
+		)}.SQL()),
+		Header: []string{
+			"Generated combinator " + checkAnyFunctionName,
+			"Returns TRUE if any relation in p_relations grants access (short-circuits on the first allow)",
+		},
+		// Calls only the schema-qualified check_permission dispatcher.
+		NoSearchPath:   true,
+		ParallelUnsafe: memo,
+	}
+	return fn.SQL() + "\n"
+}
+
+// generateCheckAllDispatcher renders check_all, which returns TRUE only when
+// every relation in p_relations grants access. It is the dual of check_any:
+// NOT EXISTS stops at the first denying relation. An empty list is vacuously
+// TRUE, so callers gating on check_all must not pass an empty array. memo is as
+// for generateCheckAnyDispatcher.
+func generateCheckAllDispatcher(databaseSchema string, memo bool) string {
+	fn := SqlFunction{
+		Schema:  databaseSchema,
+		Name:    checkAllFunctionName,
+		Args:    combinatorDispatcherArgs(),
+		Returns: "BOOLEAN",
+		Body: Raw("SELECT " + NotExists{Query: combinatorRelationsQuery(
+			Ne{Left: combinatorCheckCall(databaseSchema), Right: Int(1)},
+		)}.SQL()),
+		Header: []string{
+			"Generated combinator " + checkAllFunctionName,
+			"Returns TRUE if every relation in p_relations grants access (short-circuits on the first deny)",
+		},
+		NoSearchPath:   true,
+		ParallelUnsafe: memo,
+	}
+	return fn.SQL() + "\n"
+}
+
+// combinatorRelationsQuery selects one row per element of p_relations that
+// satisfies cond. unnest preserves array order, so relations are evaluated in
+// the order the caller listed them.
+func combinatorRelationsQuery(cond Expr) SelectStmt {
+	return SelectStmt{
+		ColumnExprs: []Expr{Int(1)},
+		FromExpr:    FunctionCallExpr{Name: "unnest", Args: []Expr{Param("p_relations")}, Alias: "r(relation)"},
+		Where:       cond,
+	}
+}
+
+// combinatorCheckCall is the check_permission call for the current element.
+func combinatorCheckCall(databaseSchema string) Expr {
+	return Func{
+		Schema: databaseSchema,
+		Name:   "check_permission",
+		Args:   []Expr{SubjectType, SubjectID, Col{Table: "r", Column: "relation"}, ObjectType, ObjectID},
+	}
+}
+
+// BatchDispatcherFunctionName is the SQL entry point for checking a JSONB
+// array of permission checks in one call.
+const BatchDispatcherFunctionName = "check_permission_batch"
+
+// batchCheckFields are the keys read from each element of p_checks, in
+// check_permission argument order.
+var batchCheckFields = []string{"subject_type", "subject_id", "relation", "object_type", "object_id"}
+
+// generateBatchDispatcher renders check_permission_batch, which evaluates a
+// JSONB array of {subject_type, subject_id, relation, object_type, object_id}
+// objects and returns one (index, allowed) row per element, in input order.
+// index is the element's 0-based position in the array. Every distinct check
+// is evaluated once through check_permission, so the batch cannot diverge
+// from single checks; duplicates share the result. The results CTE is
+// MATERIALIZED so the planner cannot pull the check_permission call up into
+// the join and evaluate it once per duplicate again. memo is as for
+// generateCheckAnyDispatcher.
+func generateBatchDispatcher(databaseSchema string, memo bool) string {
+	requestCols := make([]string, len(batchCheckFields))
+	distinctCols := make([]string, len(batchCheckFields))
+	checkArgs := make([]Expr, len(batchCheckFields))
+	joinConds := make([]string, len(batchCheckFields))
+	for i, f := range batchCheckFields {
+		requestCols[i] = fmt.Sprintf("c.value->>%s AS %s", Lit(f).SQL(), f)
+		distinctCols[i] = "d." + f
+		checkArgs[i] = Col{Table: "d", Column: f}
+		joinConds[i] = fmt.Sprintf("res.%s IS NOT DISTINCT FROM r.%s", f, f)
+	}
+	check := Func{Schema: databaseSchema, Name: "check_permission", Args: checkArgs}
+
+	body := fmt.Sprintf(`WITH requests AS (
+        SELECT (c.ordinality - 1)::INTEGER AS idx,
+            %s
+        FROM jsonb_array_elements(p_checks) WITH ORDINALITY AS c(value, ordinality)
+    ),
+    results AS MATERIALIZED (
+        SELECT %s,
+            %s = 1 AS allowed
+        FROM (SELECT DISTINCT %s FROM requests) d
+    )
+    SELECT r.idx, res.allowed
+    FROM requests r
+    JOIN results res ON %s
+    ORDER BY r.idx`,
+		strings.Join(requestCols, ",\n            "),
+		strings.Join(distinctCols, ", "),
+		check.SQL(),
+		strings.Join(batchCheckFields, ", "),
+		strings.Join(joinConds, "\n        AND "),
+	)
+
+	fn := SqlFunction{
+		Schema:  databaseSchema,
+		Name:    BatchDispatcherFunctionName,
+		Args:    []FuncArg{{Name: "p_checks", Type: "JSONB"}},
+		Returns: "TABLE(index INTEGER, allowed BOOLEAN)",
+		Body:    Raw(body),
+		Header: []string{
+			"Generated batch dispatcher " + BatchDispatcherFunctionName,
+			"Checks each element of a JSONB array through check_permission, evaluating duplicates once",
+			"Returns (index, allowed) per element in input order; index is 0-based",
+		},
+		// Calls only the schema-qualified check_permission dispatcher.
+		NoSearchPath:   true,
+		ParallelUnsafe: memo,
+	}
+	return fn.SQL() + "\n"
+}
+
 // bulkUnnestExpr is the shared UNNEST expression used to expand bulk request arrays
 // into rows with an ordinality index.
 const bulkUnnestExpr = "UNNEST(p_subject_types, p_subject_ids, p_relations, p_object_types, p_object_ids)\n" +
@@ -192,13 +342,13 @@ func groupCasesByObjectType(cases []DispatcherCase) []typeGroup {
 	return groups
 }
 
-func renderBulkDispatcherWithCases(cases []DispatcherCase, databaseSchema string) string {
+func renderBulkDispatcherWithCases(cases []DispatcherCase, databaseSchema, tuplesTable string) string {
 	groups := groupCasesByObjectType(cases)
 
 	// Build one IF block per object type + a final fallback RETURN QUERY.
 	body := make([]Stmt, 0, len(groups)+1)
 	for _, g := range groups {
-		body = append(body, buildBulkTypeGroupIf(g))
+		body = append(body, buildBulkTypeGroupIf(g, tuplesTable))
 	}
 	body = append(body, buildBulkUnknownTypeFallback(cases))
 
@@ -220,7 +370,7 @@ func renderBulkDispatcherWithCases(cases []DispatcherCase, databaseSchema string
 // buildBulkTypeGroupIf builds an IF block for a single object type group.
 // The condition checks if the object type is present in p_object_types,
 // then executes a RETURN QUERY with a CTE filtered to that type.
-func buildBulkTypeGroupIf(g typeGroup) If {
+func buildBulkTypeGroupIf(g typeGroup, tuplesTable string) If {
 	rSubjectType := Col{Table: "r", Column: "subject_type"}
 	rSubjectID := Col{Table: "r", Column: "subject_id"}
 	rObjectID := Col{Table: "r", Column: "object_id"}
@@ -233,7 +383,7 @@ func buildBulkTypeGroupIf(g typeGroup) If {
 	knownRelations := make([]string, 0, len(g.Cases))
 
 	for _, c := range g.Cases {
-		allowedExpr := buildInlineCheckExpr(c, rSubjectType, rSubjectID, rObjectID)
+		allowedExpr := buildInlineCheckExpr(c, tuplesTable, rSubjectType, rSubjectID, rObjectID)
 		branch := SelectStmt{
 			ColumnExprs: []Expr{rIdx, allowedExpr},
 			FromExpr:    requestsTable,
@@ -270,7 +420,7 @@ func buildBulkTypeGroupIf(g typeGroup) If {
 // buildInlineCheckExpr builds the allowed expression for a single dispatcher case.
 // For inlineable relations, it generates a CASE/EXISTS expression.
 // For non-inlineable relations, it generates a function call.
-func buildInlineCheckExpr(c DispatcherCase, rSubjectType, rSubjectID, rObjectID Expr) Expr {
+func buildInlineCheckExpr(c DispatcherCase, tuplesTable string, rSubjectType, rSubjectID, rObjectID Expr) Expr {
 	if !c.Inlineable {
 		return Func{Schema: c.DatabaseSchema, Name: c.CheckFunctionName, Args: []Expr{rSubjectType, rSubjectID, rObjectID, EmptyArray{}}}
 	}
@@ -290,7 +440,7 @@ func buildInlineCheckExpr(c DispatcherCase, rSubjectType, rSubjectID, rObjectID
 				// Direct tuple check with subject type restriction
 				Cond: Exists{Query: SelectStmt{
 					ColumnExprs: []Expr{Int(1)},
-					FromExpr:    TableRef{Name: "melange_tuples", Alias: "t"},
+					FromExpr:    TuplesTableAs(tuplesTable, "t"),
 					Where: And(
 						Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: rSubjectType},
 						Eq{Left: Col{Table: "t", Column: "subject_id"}, Right: rSubjectID},
@@ -340,9 +490,9 @@ func buildBulkUnknownTypeFallback(cases []DispatcherCase) ReturnQuery {
 
 func functionNameForDispatcher(a RelationAnalysis, noWildcard bool, needsNW map[string]map[string]bool) string {
 	if noWildcard {
-		return functionNameForNoWildcardRef(needsNW, a.ObjectType, a.Relation)
+		return functionNameForNoWildcardRef(a.NameCollisions, needsNW, a.ObjectType, a.Relation)
 	}
-	return functionName(a.ObjectType, a.Relation)
+	return functionName(a.NameCollisions, a.ObjectType, a.Relation)
 }
 
 // dispatchIfChain renders a routing dispatcher as an IF-chain nested by object
@@ -419,6 +569,16 @@ func bulkDispatcherArgs() []FuncArg {
 	}
 }
 
+func combinatorDispatcherArgs() []FuncArg {
+	return []FuncArg{
+		{Name: "p_subject_type", Type: "TEXT"},
+		{Name: "p_subject_id", Type: "TEXT"},
+		{Name: "p_object_type", Type: "TEXT"},
+		{Name: "p_object_id", Type: "TEXT"},
+		{Name: "p_relations", Type: "TEXT[]"},
+	}
+}
+
 func dispatcherPublicArgs() []FuncArg {
 	return []FuncArg{
 		{Name: "p_subject_type", Type: "TEXT"},
@@ -0,0 +1,184 @@
+package sqlgen
+
+import "fmt"
+
+// CheckMemoRouteFunction is the routing function emitted alongside the
+// memoizing check_permission_internal when GenerateSQLOptions.EnableCheckMemo
+// is set. It holds the dispatch IF-chain the internal dispatcher normally
+// carries; callers tracking installed functions must expect it in memo mode.
+const CheckMemoRouteFunction = "check_permission_route"
+
+// checkMemoSlots is the number of transaction-local GUC slots backing the
+// memo. The memo is a direct-mapped cache: a key hashes to one slot and a
+// colliding key evicts it. Eviction only costs a recomputation, so a small
+// fixed table bounds the per-transaction GUC footprint.
+const checkMemoSlots = 256
+
+// GUCs holding the memo state. All are written with set_config(..., true), so
+// they are transaction-local and never outlive the transaction that set them.
+// That keeps the memo correct behind a transaction-mode pooler.
+const (
+	// checkMemoScopeGUC identifies the top-level check_permission call in
+	// progress. Empty outside one, which disables the memo.
+	checkMemoScopeGUC = "melange.memo_scope"
+	// checkMemoSeqGUC counts top-level calls in the transaction so each gets a
+	// fresh scope and cannot read entries written by an earlier call.
+	checkMemoSeqGUC = "melange.memo_seq"
+	// checkMemoSlotPrefix prefixes the slot number to form each slot's GUC name.
+	checkMemoSlotPrefix = "melange.memo_"
+)
+
+// generateMemoDispatcher renders the check_permission dispatcher with
+// per-request memoization of check_permission_internal.
+//
+// Intersection and exclusion rules can reach the same sub-check several times
+// while evaluating one relation (e.g. `viewer: editor and member` where editor
+// itself requires member). In memo mode the public check_permission opens a
+// scope, and check_permission_internal looks each call up in the scope's memo
+// before routing to the specialized function, storing the result on a miss.
+//
+// Allows and denies are keyed differently. A specialized function cuts a cycle
+// by returning 0 when it meets a key already in p_visited, so a deny may be an
+// artifact of the path that reached it and is reused only under the same
+// p_visited (sibling sub-checks of one function share it). An allow names a
+// real derivation and is reused under any p_visited, which is what lets the
+// `editor` sub-check above be shared across branches. Calls outside a scope
+// (list functions, explain, check_permission_bulk, direct check_<type>_<rel>
+// calls) see an empty scope and route straight through without touching
+// session state.
+func generateMemoDispatcher(analyses []RelationAnalysis, databaseSchema string, maxDepth int, nullGuards bool) string {
+	const fnName = "check_permission"
+	internalName := fnName + "_internal"
+	routeCall := Func{
+		Schema: databaseSchema,
+		Name:   CheckMemoRouteFunction,
+		Args:   []Expr{SubjectType, SubjectID, Raw("p_relation"), ObjectType, ObjectID, Visited},
+	}
+
+	cases := buildDispatcherCases(analyses, databaseSchema, false, nil)
+	routeFn := PlpgsqlFunction{
+		Schema:  databaseSchema,
+		Name:    CheckMemoRouteFunction,
+		Args:    dispatcherInternalArgs(),
+		Returns: "INTEGER",
+		Body:    dispatchIfChain(cases, checkDispatchCall, Int(0)),
+		Header: []string{
+			"Generated routing function for memoized " + internalName,
+			"Routes to specialized functions; the depth limit and memo lookup live in " + internalName,
+		},
+		Cost:         recursiveCheckCost,
+		NoSearchPath: true,
+	}
+
+	slotOf := func(key string) Expr {
+		return Concat{Parts: []Expr{
+			Lit(checkMemoSlotPrefix),
+			Paren{Expr: Raw("hashtext(" + key + ") & " + Int(checkMemoSlots-1).SQL())},
+		}}
+	}
+	// A slot holds "<key>=<result>"; comparing the whole value rejects an
+	// entry from a colliding key.
+	hit := func(slot, key, result string) Expr {
+		return Eq{Left: currentSettingExpr(Param(slot)), Right: Concat{Parts: []Expr{Param(key), Lit("=" + result)}}}
+	}
+	internalFn := PlpgsqlFunction{
+		Schema:  databaseSchema,
+		Name:    internalName,
+		Args:    dispatcherInternalArgs(),
+		Returns: "INTEGER",
+		Decls: []Decl{
+			{Name: "v_scope", Type: "TEXT"},
+			{Name: "v_allow_key", Type: "TEXT"},
+			{Name: "v_allow_slot", Type: "TEXT"},
+			{Name: "v_deny_key", Type: "TEXT"},
+			{Name: "v_deny_slot", Type: "TEXT"},
+			{Name: "v_result", Type: "INTEGER"},
+		},
+		Body: []Stmt{
+			Comment{Text: "Depth limit check: prevent excessively deep permission resolution chains"},
+			depthLimitCheck(maxDepth),
+			Assign{Name: "v_scope", Value: currentSettingExpr(Lit(checkMemoScopeGUC))},
+			Comment{Text: "Outside a top-level check_permission call: no memo"},
+			If{
+				Cond: Raw("v_scope IS NULL OR v_scope = ''"),
+				Then: []Stmt{ReturnValue{Value: routeCall}},
+			},
+			Comment{Text: "An allow holds under any p_visited; a deny only under the p_visited it was computed with"},
+			Assign{Name: "v_allow_key", Value: Concat{Parts: []Expr{
+				Param("v_scope"),
+				Raw("ROW(p_subject_type, p_subject_id, p_relation, p_object_type, p_object_id)::TEXT"),
+			}}},
+			Assign{Name: "v_allow_slot", Value: slotOf("v_allow_key")},
+			If{
+				Cond: hit("v_allow_slot", "v_allow_key", "1"),
+				Then: []Stmt{ReturnInt{Value: 1}},
+			},
+			Assign{Name: "v_deny_key", Value: Concat{Parts: []Expr{Param("v_allow_key"), Raw("p_visited::TEXT")}}},
+			Assign{Name: "v_deny_slot", Value: slotOf("v_deny_key")},
+			If{
+				Cond: hit("v_deny_slot", "v_deny_key", "0"),
+				Then: []Stmt{ReturnInt{Value: 0}},
+			},
+			Assign{Name: "v_result", Value: routeCall},
+			If{
+				Cond: Eq{Left: Param("v_result"), Right: Int(1)},
+				Then: []Stmt{RawStmt{SQLText: "PERFORM " + setConfigExpr(Param("v_allow_slot"), Concat{Parts: []Expr{Param("v_allow_key"), Lit("=1")}}).SQL() + ";"}},
+				Else: []Stmt{RawStmt{SQLText: "PERFORM " + setConfigExpr(Param("v_deny_slot"), Concat{Parts: []Expr{Param("v_deny_key"), Lit("=0")}}).SQL() + ";"}},
+			},
+			ReturnValue{Value: Param("v_result")},
+		},
+		Header: []string{
+			"Generated memoizing internal dispatcher for " + internalName,
+			fmt.Sprintf("Enforces depth limit of %d, then serves repeated sub-checks within one check_permission call from a transaction-local memo", maxDepth),
+		},
+		Cost:         recursiveCheckCost,
+		NoSearchPath: true,
+		// set_config is rejected inside a parallel operation.
+		ParallelUnsafe: true,
+		NullGuards:     nullGuards,
+	}
+
+	publicFn := PlpgsqlFunction{
+		Schema:  databaseSchema,
+		Name:    fnName,
+		Args:    dispatcherPublicArgs(),
+		Returns: "INTEGER",
+		Decls: []Decl{
+			{Name: "v_outer", Type: "TEXT"},
+			{Name: "v_scope", Type: "TEXT"},
+			{Name: "v_result", Type: "INTEGER"},
+		},
+		Body: []Stmt{
+			Comment{Text: "Open a fresh memo scope, restoring the enclosing one (if any) afterwards"},
+			Assign{Name: "v_outer", Value: Raw("COALESCE(" + currentSettingExpr(Lit(checkMemoScopeGUC)).SQL() + ", '')")},
+			Assign{Name: "v_scope", Value: Raw("(COALESCE(NULLIF(" + currentSettingExpr(Lit(checkMemoSeqGUC)).SQL() + ", ''), '0')::BIGINT + 1)::TEXT")},
+			RawStmt{SQLText: "PERFORM " + setConfigExpr(Lit(checkMemoSeqGUC), Param("v_scope")).SQL() + ";"},
+			RawStmt{SQLText: "PERFORM " + setConfigExpr(Lit(checkMemoScopeGUC), Param("v_scope")).SQL() + ";"},
+			Assign{Name: "v_result", Value: Func{
+				Schema: databaseSchema,
+				Name:   internalName,
+				Args:   []Expr{SubjectType, SubjectID, Raw("p_relation"), ObjectType, ObjectID, EmptyArray{}},
+			}},
+			RawStmt{SQLText: "PERFORM " + setConfigExpr(Lit(checkMemoScopeGUC), Param("v_outer")).SQL() + ";"},
+			ReturnValue{Value: Param("v_result")},
+		},
+		Header: []string{
+			"Generated dispatcher for " + fnName + " (memoized)",
+			"Routes to specialized functions for all known type/relation pairs",
+		},
+		NoSearchPath:   true,
+		ParallelUnsafe: true,
+	}
+
+	return routeFn.SQL() + "\n\n" + internalFn.SQL() + "\n\n" + publicFn.SQL() + "\n"
+}
+
+// currentSettingExpr reads a GUC, yielding NULL when it was never set.
+func currentSettingExpr(name Expr) Expr {
+	return Func{Name: "current_setting", Args: []Expr{name, Bool(true)}}
+}
+
+// setConfigExpr sets a GUC for the rest of the current transaction.
+func setConfigExpr(name, value Expr) Expr {
+	return Func{Name: "set_config", Args: []Expr{name, value, Bool(true)}}
+}
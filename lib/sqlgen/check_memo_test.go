@@ -0,0 +1,68 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func checkMemoAnalyses() []RelationAnalysis {
+	check := GenerationCapabilities{CheckAllowed: true}
+	return []RelationAnalysis{
+		{ObjectType: "document", Relation: "member", Capabilities: check, Features: RelationFeatures{HasDirect: true}},
+		{ObjectType: "document", Relation: "editor", Capabilities: check, Features: RelationFeatures{HasDirect: true, HasIntersection: true}},
+	}
+}
+
+func TestMemoDispatcher_LooksUpBeforeRouting(t *testing.T) {
+	sql := generateMemoDispatcher(checkMemoAnalyses(), "", DefaultMaxDepth, true)
+
+	internal := sql[strings.Index(sql, "CREATE OR REPLACE FUNCTION check_permission_internal("):strings.Index(sql, "CREATE OR REPLACE FUNCTION check_permission(")]
+	assertContains(t, internal, "IF array_length(p_visited, 1) >= 25 THEN")
+	assertContains(t, internal, "'melange.memo_' || (hashtext(v_allow_key) & 255)")
+	assertContains(t, internal, "v_deny_key := v_allow_key || p_visited::TEXT;")
+	assertContains(t, internal, "PERFORM set_config(v_allow_slot, v_allow_key || '=1', TRUE);")
+	assertContains(t, internal, "PERFORM set_config(v_deny_slot, v_deny_key || '=0', TRUE);")
+	assertContains(t, internal, "PARALLEL UNSAFE")
+	assertNotContains(t, internal, "check_document_editor(")
+
+	lookup := strings.Index(internal, "current_setting(v_allow_slot, TRUE)")
+	route := strings.LastIndex(internal, "check_permission_route(")
+	if lookup < 0 || route < lookup {
+		t.Errorf("memo lookup must precede routing:\n%s", internal)
+	}
+
+	routeFn := sql[:strings.Index(sql, "CREATE OR REPLACE FUNCTION check_permission_internal(")]
+	assertContains(t, routeFn, "CREATE OR REPLACE FUNCTION check_permission_route(")
+	assertContains(t, routeFn, "RETURN check_document_editor(p_subject_type, p_subject_id, p_object_id, p_visited);")
+	assertNotContains(t, routeFn, "set_config")
+
+	public := sql[strings.Index(sql, "CREATE OR REPLACE FUNCTION check_permission("):]
+	assertContains(t, public, "PERFORM set_config('melange.memo_scope', v_scope, TRUE);")
+	assertContains(t, public, "PERFORM set_config('melange.memo_scope', v_outer, TRUE);")
+	assertContains(t, public, "LANGUAGE plpgsql STABLE PARALLEL UNSAFE")
+}
+
+func TestCheckMemo_GatedByOption(t *testing.T) {
+	analyses := checkMemoAnalyses()
+
+	off, err := GenerateSQL(analyses, InlineSQLData{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sql := range []string{off.Dispatcher, off.CheckAnyDispatcher, off.CheckAllDispatcher} {
+		assertNotContains(t, sql, "melange.memo_")
+		assertNotContains(t, sql, "PARALLEL UNSAFE")
+	}
+
+	on, err := GenerateSQLWithOptions(analyses, InlineSQLData{}, "", GenerateSQLOptions{EnableCheckMemo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, on.Dispatcher, "CREATE OR REPLACE FUNCTION "+CheckMemoRouteFunction+"(")
+	assertContains(t, on.CheckAnyDispatcher, "PARALLEL UNSAFE")
+	assertContains(t, on.CheckAllDispatcher, "PARALLEL UNSAFE")
+	// The no-wildcard dispatcher is never memoized.
+	if on.DispatcherNoWildcard != off.DispatcherNoWildcard {
+		t.Error("EnableCheckMemo changed check_permission_nw")
+	}
+}
@@ -8,6 +8,7 @@ type CheckPlan struct {
 	Analysis       RelationAnalysis
 	Inline         InlineSQLData
 	DatabaseSchema string
+	TuplesTable    string // Tuples relation; empty means DefaultTuplesTable
 
 	// Function identity
 	FunctionName              string
@@ -19,6 +20,7 @@ type CheckPlan struct {
 	// Feature configuration
 	AllowWildcard bool            // Whether wildcards are allowed
 	NoWildcard    bool            // True if this is a no-wildcard variant
+	Strict        bool            // True if this is a strict variant (see generateStrictCheckFunction)
 	Exclusions    ExclusionConfig // Exclusion rules configuration
 
 	// Feature flags (derived from analysis)
@@ -56,6 +58,29 @@ type CheckPlan struct {
 	// base function (identical body, no _nw emitted). Nil means "always assume a
 	// _nw variant exists" (backward-compatible for direct plan-builder callers).
 	NeedsNoWildcard map[string]map[string]bool
+
+	// StrictIndex maps (object_type, relation) to whether a distinct _strict
+	// check function is emitted. Used when Strict is true to route each
+	// complex-closure callee to its _strict function or its base function.
+	StrictIndex map[string]map[string]bool
+
+	// PoolerSafe drops session-GUC fallbacks from rendered bodies so results
+	// never depend on SET state left behind on a pooled server connection.
+	// Only explain consults a GUC today. See GenerateSQLOptions.PoolerSafe.
+	PoolerSafe bool
+
+	// MaxDepth is the p_visited length at which the function raises M2002.
+	// Zero means DefaultMaxDepth. See GenerateSQLOptions.MaxDepth.
+	MaxDepth int
+
+	// NullGuards raises 22004 when a required argument is NULL. See
+	// GenerateSQLOptions.DisableNullGuards.
+	NullGuards bool
+}
+
+// maxDepth returns p.MaxDepth, or DefaultMaxDepth when it is unset.
+func (p CheckPlan) maxDepth() int {
+	return resolveMaxDepth(p.MaxDepth)
 }
 
 // BuildCheckPlan creates a plan for generating a check function.
@@ -74,10 +99,10 @@ func BuildCheckPlanWithOrdering(a RelationAnalysis, inline InlineSQLData, databa
 	hasWildcard := a.Features.HasWildcard && !noWildcard
 
 	// Determine function names
-	funcName := functionName(a.ObjectType, a.Relation)
+	funcName := functionName(a.NameCollisions, a.ObjectType, a.Relation)
 	internalFn := "check_permission_internal"
 	if noWildcard {
-		funcName = functionNameNoWildcard(a.ObjectType, a.Relation)
+		funcName = functionNameNoWildcard(a.NameCollisions, a.ObjectType, a.Relation)
 		internalFn = "check_permission_nw_internal"
 	}
 
@@ -126,6 +151,7 @@ func BuildCheckPlanWithOrdering(a RelationAnalysis, inline InlineSQLData, databa
 		plan.Exclusions = buildExclusionInput(
 			a,
 			databaseSchema,
+			"",
 			ObjectID, // p_object_id parameter
 			SubjectType,
 			SubjectID,
@@ -135,6 +161,31 @@ func BuildCheckPlanWithOrdering(a RelationAnalysis, inline InlineSQLData, databa
 	return plan
 }
 
+// withTuplesTable returns p reading tuples, including those its exclusions
+// look up, from table. The plan builders leave TuplesTable empty (the
+// default) so their signatures stay independent of generation options.
+func (p CheckPlan) withTuplesTable(table string) CheckPlan {
+	p.TuplesTable = table
+	p.Exclusions.TuplesTable = table
+	return p
+}
+
+// directSubjectIDMatch matches column, the subject_id of a direct tuple,
+// against p_subject_id. A '*' tuple matches only for the subject types whose
+// restriction allows a wildcard, so [user:*, service] does not let a stray
+// service:* tuple grant every service.
+func (p CheckPlan) directSubjectIDMatch(column Expr) Expr {
+	return directSubjectIDMatch(column, p.AllowWildcard, p.AllowedSubjectTypes, p.Analysis.ClosureWildcardSubjectTypes)
+}
+
+// directSubjectIDMatch is CheckPlan.directSubjectIDMatch for either plan.
+func directSubjectIDMatch(column Expr, allowWildcard bool, allowedTypes, wildcardTypes []string) Expr {
+	if !allowWildcard || len(allowedTypes) == 0 {
+		return SubjectIDMatch(column, SubjectID, allowWildcard)
+	}
+	return SubjectIDMatchByType(SubjectType, column, SubjectID, allowedTypes, wildcardTypes)
+}
+
 // DetermineCheckFunctionType returns which type of check function to generate.
 // Returns one of: "direct", "intersection", "recursive", "recursive_intersection"
 func (p CheckPlan) DetermineCheckFunctionType() string {
@@ -53,14 +53,15 @@ func renderCheckDirectFunctionFromBlocks(plan CheckPlan, blocks CheckBlocks) (st
 
 func renderDirectFn(plan CheckPlan, decls []Decl, body []Stmt) (string, error) {
 	fn := PlpgsqlFunction{
-		Schema:  plan.DatabaseSchema,
-		Name:    plan.FunctionName,
-		Args:    checkFunctionArgs(),
-		Returns: "INTEGER",
-		Decls:   decls,
-		Body:    body,
-		Header:  checkFunctionHeader(plan),
-		Cost:    checkFunctionCost(plan),
+		Schema:     plan.DatabaseSchema,
+		Name:       plan.FunctionName,
+		Args:       checkFunctionArgs(),
+		Returns:    "INTEGER",
+		Decls:      decls,
+		Body:       body,
+		Header:     checkFunctionHeader(plan),
+		Cost:       checkFunctionCost(plan),
+		NullGuards: plan.NullGuards,
 	}
 	return fn.SQL() + "\n", nil
 }
@@ -104,9 +105,10 @@ func renderCheckIntersectionFunctionFromBlocks(plan CheckPlan, blocks CheckBlock
 			{Name: "v_userset_check", Type: "INTEGER := 0"},
 			{Name: "v_has_access", Type: "BOOLEAN := FALSE"},
 		},
-		Body:   body,
-		Header: checkFunctionHeader(plan),
-		Cost:   checkFunctionCost(plan),
+		Body:       body,
+		Header:     checkFunctionHeader(plan),
+		Cost:       checkFunctionCost(plan),
+		NullGuards: plan.NullGuards,
 	}
 
 	return fn.SQL() + "\n", nil
@@ -115,21 +117,22 @@ func renderCheckIntersectionFunctionFromBlocks(plan CheckPlan, blocks CheckBlock
 func renderCheckRecursiveFunctionFromBlocks(plan CheckPlan, blocks CheckBlocks) (string, error) {
 	visitedWithKey := Raw("p_visited || ARRAY[v_key]")
 
-	body := buildCycleDetectionStmts()
+	body := buildCycleDetectionStmts(plan.maxDepth())
 	body = append(body, buildUsersetSubjectStmts(plan, blocks, true)...)
 	body = append(body, buildStandaloneAccessPathStmts(plan, blocks, visitedWithKey)...)
 	body = append(body, buildExclusionWithAccessStmts(plan, blocks)...)
 	body = append(body, ReturnInt{Value: 0})
 
 	fn := PlpgsqlFunction{
-		Schema:  plan.DatabaseSchema,
-		Name:    plan.FunctionName,
-		Args:    checkFunctionArgs(),
-		Returns: "INTEGER",
-		Decls:   recursiveCheckDecls(plan),
-		Body:    body,
-		Header:  checkFunctionHeader(plan),
-		Cost:    checkFunctionCost(plan),
+		Schema:     plan.DatabaseSchema,
+		Name:       plan.FunctionName,
+		Args:       checkFunctionArgs(),
+		Returns:    "INTEGER",
+		Decls:      recursiveCheckDecls(plan),
+		Body:       body,
+		Header:     checkFunctionHeader(plan),
+		Cost:       checkFunctionCost(plan),
+		NullGuards: plan.NullGuards,
 	}
 
 	return fn.SQL() + "\n", nil
@@ -138,7 +141,7 @@ func renderCheckRecursiveFunctionFromBlocks(plan CheckPlan, blocks CheckBlocks)
 func renderCheckRecursiveIntersectionFunctionFromBlocks(plan CheckPlan, blocks CheckBlocks) (string, error) {
 	visitedWithKey := Raw("p_visited || ARRAY[v_key]")
 
-	body := buildCycleDetectionStmts()
+	body := buildCycleDetectionStmts(plan.maxDepth())
 	body = append(body, buildUsersetSubjectStmts(plan, blocks, true)...)
 	body = append(body, Comment{Text: "Relation has intersection; only render standalone paths if HasStandaloneAccess is true"})
 
@@ -151,14 +154,15 @@ func renderCheckRecursiveIntersectionFunctionFromBlocks(plan CheckPlan, blocks C
 	body = append(body, ReturnInt{Value: 0})
 
 	fn := PlpgsqlFunction{
-		Schema:  plan.DatabaseSchema,
-		Name:    plan.FunctionName,
-		Args:    checkFunctionArgs(),
-		Returns: "INTEGER",
-		Decls:   recursiveCheckDecls(plan),
-		Body:    body,
-		Header:  checkFunctionHeader(plan),
-		Cost:    checkFunctionCost(plan),
+		Schema:     plan.DatabaseSchema,
+		Name:       plan.FunctionName,
+		Args:       checkFunctionArgs(),
+		Returns:    "INTEGER",
+		Decls:      recursiveCheckDecls(plan),
+		Body:       body,
+		Header:     checkFunctionHeader(plan),
+		Cost:       checkFunctionCost(plan),
+		NullGuards: plan.NullGuards,
 	}
 
 	return fn.SQL() + "\n", nil
@@ -269,7 +273,7 @@ func buildUsersetSubjectStmts(plan CheckPlan, blocks CheckBlocks, converge bool)
 	return []Stmt{
 		Comment{Text: "Userset subject handling"},
 		If{
-			Cond: Gt{Left: Position{Needle: Lit("#"), Haystack: SubjectID}, Right: Int(0)},
+			Cond: Gt{Left: StrPos{Source: SubjectID, Substr: "#"}, Right: Int(0)},
 			Then: thenStmts,
 		},
 	}
@@ -298,17 +302,14 @@ func buildAccessCheckExpr(blocks CheckBlocks, visitedExpr Expr, databaseSchema s
 	}
 }
 
-func buildCycleDetectionStmts() []Stmt {
+func buildCycleDetectionStmts(maxDepth int) []Stmt {
 	return []Stmt{
 		Comment{Text: "Cycle detection"},
 		If{
 			Cond: ArrayContains{Value: Raw("v_key"), Array: Visited},
 			Then: []Stmt{ReturnInt{Value: 0}},
 		},
-		If{
-			Cond: Gte{Left: ArrayLength{Array: Visited}, Right: Int(25)},
-			Then: []Stmt{Raise{Message: "resolution too complex", ErrCode: "M2002"}},
-		},
+		depthLimitCheck(maxDepth),
 	}
 }
 
@@ -365,7 +366,7 @@ func renderParentRelationExistsFromBlocks(plan CheckPlan, parent ParentRelationB
 		Raw(visitedExpr),
 	)
 
-	q := Tuples(plan.DatabaseSchema, "link").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "link").
 		ObjectType(plan.ObjectType).
 		Relations(parent.LinkingRelation).
 		Where(
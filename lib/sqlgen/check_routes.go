@@ -0,0 +1,100 @@
+package sqlgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+)
+
+// RoutesTable is the routing table read by the table-routed check_permission
+// dispatcher (GenerateSQLOptions.TableRoutedDispatcher). One row per checkable
+// (object_type, relation) names its specialized check function.
+const RoutesTable = "melange_routes"
+
+// generateTableRoutedDispatcher renders check_permission as a routing table
+// plus a fixed-size internal dispatcher that looks the specialized function up
+// and calls it with EXECUTE.
+//
+// The static dispatcher's body grows with the schema: one IF arm per relation,
+// which PL/pgSQL parses and compiles on the first call in every session. Here
+// the body is constant and the schema lives in rows, so the first call costs
+// the same for ten relations or ten thousand. Every call then pays a primary
+// key lookup and an EXECUTE, whose plan is never cached, instead of a few
+// simple-expression compares; the trade only pays off when schemas are large
+// and sessions short-lived.
+//
+// The emitted SQL creates the table if needed and replaces its rows, so
+// re-applying it after a schema change reroutes every relation in the same
+// transaction that installs the new functions.
+func generateTableRoutedDispatcher(analyses []RelationAnalysis, databaseSchema string, maxDepth int, nullGuards bool) string {
+	const fnName = "check_permission"
+	internalName := fnName + "_internal"
+	table := sqldsl.PrefixIdent(RoutesTable, databaseSchema)
+
+	var sb strings.Builder
+	sb.WriteString("-- Generated routing table for " + internalName + "\n")
+	sb.WriteString("CREATE TABLE IF NOT EXISTS " + table + " (\n")
+	sb.WriteString("    object_type TEXT NOT NULL,\n")
+	sb.WriteString("    relation TEXT NOT NULL,\n")
+	sb.WriteString("    fn_name TEXT NOT NULL,\n")
+	sb.WriteString("    PRIMARY KEY (object_type, relation)\n")
+	sb.WriteString(");\n\n")
+	sb.WriteString("DELETE FROM " + table + ";\n")
+
+	cases := buildDispatcherCases(analyses, databaseSchema, false, nil)
+	if len(cases) > 0 {
+		sb.WriteString("INSERT INTO " + table + " (object_type, relation, fn_name) VALUES\n")
+		for i, c := range cases {
+			fn := sqldsl.PrefixIdent(c.CheckFunctionName, databaseSchema)
+			sb.WriteString("    (" + Lit(c.ObjectType).SQL() + ", " + Lit(c.Relation).SQL() + ", " + Lit(fn).SQL() + ")")
+			if i < len(cases)-1 {
+				sb.WriteString(",\n")
+			}
+		}
+		sb.WriteString(";\n")
+	}
+
+	route := SelectStmt{
+		ColumnExprs: []Expr{Col{Table: "r", Column: "fn_name"}},
+		FromExpr:    TableAs(databaseSchema, RoutesTable, "r"),
+		Where: And(
+			Eq{Left: Col{Table: "r", Column: "object_type"}, Right: ObjectType},
+			Eq{Left: Col{Table: "r", Column: "relation"}, Right: Raw("p_relation")},
+		),
+	}
+	internalFn := PlpgsqlFunction{
+		Schema:  databaseSchema,
+		Name:    internalName,
+		Args:    dispatcherInternalArgs(),
+		Returns: "INTEGER",
+		Decls: []Decl{
+			{Name: "v_fn", Type: "TEXT"},
+			{Name: "v_result", Type: "INTEGER"},
+		},
+		Body: []Stmt{
+			Comment{Text: "Depth limit check: prevent excessively deep permission resolution chains"},
+			depthLimitCheck(maxDepth),
+			SelectInto{Query: route, Variable: "v_fn"},
+			Comment{Text: "Unknown type/relation pair: deny"},
+			If{
+				Cond: Raw("v_fn IS NULL"),
+				Then: []Stmt{ReturnInt{Value: 0}},
+			},
+			RawStmt{SQLText: "EXECUTE 'SELECT ' || v_fn || '($1, $2, $3, $4)' INTO v_result USING p_subject_type, p_subject_id, p_object_id, p_visited;"},
+			ReturnValue{Value: Raw("v_result")},
+		},
+		Header: []string{
+			"Generated table-routed internal dispatcher for " + internalName,
+			"Looks up the specialized function in " + RoutesTable + " and calls it dynamically",
+			fmt.Sprintf("Enforces depth limit of %d to prevent stack overflow from deep permission chains", maxDepth),
+		},
+		Cost: recursiveCheckCost,
+		// Reads only the schema-qualified routing table and calls the
+		// schema-qualified function names stored in it.
+		NoSearchPath: true,
+		NullGuards:   nullGuards,
+	}
+
+	return sb.String() + "\n" + internalFn.SQL() + "\n\n" + dispatcherPublicWrapper(databaseSchema, fnName).SQL() + "\n"
+}
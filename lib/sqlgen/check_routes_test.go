@@ -0,0 +1,63 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTableRoutedDispatcher_RoutesThroughTable(t *testing.T) {
+	sql := generateTableRoutedDispatcher(checkMemoAnalyses(), "authz", DefaultMaxDepth, true)
+
+	routes := sql[:strings.Index(sql, "CREATE OR REPLACE FUNCTION")]
+	assertContains(t, routes, `CREATE TABLE IF NOT EXISTS "authz"."melange_routes" (`)
+	assertContains(t, routes, "PRIMARY KEY (object_type, relation)")
+	assertContains(t, routes, `DELETE FROM "authz"."melange_routes";`)
+	assertContains(t, routes, `('document', 'member', '"authz"."check_document_member"')`)
+	assertContains(t, routes, `('document', 'editor', '"authz"."check_document_editor"')`)
+
+	internal := sql[strings.Index(sql, `CREATE OR REPLACE FUNCTION "authz"."check_permission_internal"(`):strings.Index(sql, `CREATE OR REPLACE FUNCTION "authz"."check_permission"(`)]
+	assertContains(t, internal, "IF array_length(p_visited, 1) >= 25 THEN")
+	assertContains(t, internal, `FROM "authz"."melange_routes" AS r`)
+	assertContains(t, internal, "IF v_fn IS NULL THEN")
+	assertContains(t, internal, "EXECUTE 'SELECT ' || v_fn || '($1, $2, $3, $4)' INTO v_result USING p_subject_type, p_subject_id, p_object_id, p_visited;")
+	// The body no longer grows with the schema.
+	assertNotContains(t, internal, "check_document_member(")
+	assertNotContains(t, internal, "check_document_editor(")
+
+	public := sql[strings.Index(sql, `CREATE OR REPLACE FUNCTION "authz"."check_permission"(`):]
+	assertContains(t, public, `"authz"."check_permission_internal"(`)
+}
+
+func TestTableRoutedDispatcher_NoRelations(t *testing.T) {
+	sql := generateTableRoutedDispatcher(nil, "", DefaultMaxDepth, true)
+
+	assertContains(t, sql, "DELETE FROM melange_routes;")
+	assertNotContains(t, sql, "INSERT INTO melange_routes")
+	assertContains(t, sql, "CREATE OR REPLACE FUNCTION check_permission_internal(")
+}
+
+func TestTableRoutedDispatcher_GatedByOption(t *testing.T) {
+	analyses := checkMemoAnalyses()
+
+	off, err := GenerateSQL(analyses, InlineSQLData{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNotContains(t, off.Dispatcher, RoutesTable)
+	assertContains(t, off.Dispatcher, "check_document_editor(")
+
+	on, err := GenerateSQLWithOptions(analyses, InlineSQLData{}, "", GenerateSQLOptions{TableRoutedDispatcher: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, on.Dispatcher, "CREATE TABLE IF NOT EXISTS "+RoutesTable)
+	// Only check_permission is routed through the table.
+	if on.DispatcherNoWildcard != off.DispatcherNoWildcard {
+		t.Error("TableRoutedDispatcher changed check_permission_nw")
+	}
+
+	_, err = GenerateSQLWithOptions(analyses, InlineSQLData{}, "", GenerateSQLOptions{TableRoutedDispatcher: true, EnableCheckMemo: true})
+	if err == nil {
+		t.Error("expected an error combining TableRoutedDispatcher with EnableCheckMemo")
+	}
+}
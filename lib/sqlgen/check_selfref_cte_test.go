@@ -0,0 +1,69 @@
+package sqlgen
+
+import "testing"
+
+const checkSelfRefTestSchema = `model
+  schema 1.1
+type user
+type group
+  relations
+    define member: [user, group#member]
+type club
+  relations
+    define member: [user]
+type team
+  relations
+    define member: [user, team#member, club#member]
+type org
+  relations
+    define blocked: [user]
+    define member: [user, org#member] but not blocked
+`
+
+// A self-referential userset is walked by a recursive CTE rather than one
+// check_permission_internal call per nesting level. check_permission_internal
+// remains only for userset subjects when direct tuples and the userset are
+// the relation's only access paths.
+func TestCheckSelfRefUsersetUsesRecursiveCTE(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, checkSelfRefTestSchema)
+	gen, err := GenerateSQLWithOptions(analyses, inline, "", GenerateSQLOptions{MaxDepth: 10})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+
+	group := functionNamed(t, gen.Functions, "check_group_member")
+	assertContains(t, group, "WITH RECURSIVE member_closure(object_id, depth) AS (")
+	assertContains(t, group, "SELECT p_object_id, COALESCE(array_length(p_visited, 1), 0)")
+	assertContains(t, group, "= ANY(p_visited)) AND mc.depth < 10)")
+	assertContains(t, group, "WHERE dl.depth >= 10")
+	assertContains(t, group, "melange_depth_exceeded()")
+	assertContains(t, group, "d.object_id = mc.object_id")
+	assertContains(t, group, "OR (position('#' in p_subject_id) > 0 AND EXISTS (")
+
+	// team.member also holds through club#member, which a nested team can
+	// grant without a direct tuple, so the per-level walk stays as a fallback.
+	team := functionNamed(t, gen.Functions, "check_team_member")
+	assertContains(t, team, "WITH RECURSIVE member_closure(object_id, depth) AS (")
+	assertNotContains(t, team, "OR (position('#' in p_subject_id) > 0 AND EXISTS (")
+	assertContains(t, team, "check_permission_internal(p_subject_type, p_subject_id, 'member', 'team'")
+
+	// A nested org can exclude a subject it holds a direct tuple for.
+	org := functionNamed(t, gen.Functions, "check_org_member")
+	assertNotContains(t, org, "member_closure")
+	assertContains(t, org, "check_permission_internal(p_subject_type, p_subject_id, 'member', 'org'")
+}
+
+// The strict variant keeps resolving nested usersets through
+// check_permission_internal.
+func TestCheckSelfRefUsersetStrictKeepsInternalWalk(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, checkSelfRefTestSchema)
+	gen, err := GenerateSQLWithOptions(analyses, inline, "", GenerateSQLOptions{EnableStrictCheck: true})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+
+	// club#member is simple on team.member, so team gets a strict variant.
+	strict := functionNamed(t, gen.StrictFunctions, "check_team_member_strict")
+	assertNotContains(t, strict, "member_closure")
+	assertContains(t, strict, "check_permission_internal(p_subject_type, p_subject_id, 'member', 'team'")
+}
@@ -0,0 +1,133 @@
+package sqlgen
+
+import "fmt"
+
+// StrictDispatcherFunctionName is the check entrypoint emitted when
+// GenerateSQLOptions.EnableStrictCheck is set. Its internal dispatcher is
+// StrictDispatcherFunctionName + "_internal".
+const StrictDispatcherFunctionName = "check_permission_strict"
+
+// functionNameStrict returns the name of the strict variant of a check
+// function.
+func functionNameStrict(collisions NameCollisions, objectType, relation string) string {
+	return RelationIdentifier(collisions, "check_", objectType, relation, "_strict")
+}
+
+// hasSimpleUserset reports whether a has a userset pattern the base check
+// function resolves by joining the membership tuple directly. The strict
+// variant calls check_permission_internal for these instead.
+func hasSimpleUserset(a RelationAnalysis) bool {
+	for _, p := range a.UsersetPatterns {
+		if !p.IsComplex {
+			return true
+		}
+	}
+	return false
+}
+
+// buildStrictIndex maps object_type -> relation -> whether a strict variant is
+// emitted. A relation needs one when it has a simple userset pattern or calls
+// the function of an implied relation that has a strict variant; any other
+// relation would get a body identical to its base function, so absent entries
+// route the strict dispatcher to the base function.
+func buildStrictIndex(analyses []RelationAnalysis) map[string]map[string]bool {
+	byType := make(map[string]map[string]bool)
+	mark := func(a RelationAnalysis) {
+		if byType[a.ObjectType] == nil {
+			byType[a.ObjectType] = make(map[string]bool)
+		}
+		byType[a.ObjectType][a.Relation] = true
+	}
+	for _, a := range analyses {
+		if a.Capabilities.CheckAllowed && hasSimpleUserset(a) {
+			mark(a)
+		}
+	}
+	// Implied relations nest, so propagate until nothing changes.
+	for changed := true; changed; {
+		changed = false
+		for _, a := range analyses {
+			if !a.Capabilities.CheckAllowed || byType[a.ObjectType][a.Relation] {
+				continue
+			}
+			for _, rel := range a.ComplexClosureRelations {
+				if byType[a.ObjectType][rel] {
+					mark(a)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return byType
+}
+
+// functionNameForStrictRef returns the check function name the strict variant
+// of a relation calls for (objectType, relation): its strict variant when one
+// is emitted, otherwise the base function.
+func functionNameForStrictRef(collisions NameCollisions, strictIdx map[string]map[string]bool, objectType, relation string) string {
+	if strictIdx[objectType][relation] {
+		return functionNameStrict(collisions, objectType, relation)
+	}
+	return functionName(collisions, objectType, relation)
+}
+
+// CollectStrictFunctionNames returns the function names generated for
+// analyses when GenerateSQLOptions.EnableStrictCheck is set: the strict
+// variants and the strict dispatcher pair.
+func CollectStrictFunctionNames(analyses []RelationAnalysis) []string {
+	strictIdx := buildStrictIndex(analyses)
+	var names []string
+	for _, a := range analyses {
+		if strictIdx[a.ObjectType][a.Relation] {
+			names = append(names, functionNameStrict(a.NameCollisions, a.ObjectType, a.Relation))
+		}
+	}
+	return append(names, StrictDispatcherFunctionName, StrictDispatcherFunctionName+"_internal")
+}
+
+// generateStrictCheckFunction renders check_{type}_{relation}_strict, the
+// variant of the check function that resolves every userset grant through
+// check_permission_internal and calls the strict variants of implied
+// relations. Other access paths render as in the base function.
+func generateStrictCheckFunction(a RelationAnalysis, inline InlineSQLData, databaseSchema, tuplesTable string, complexityByRelation map[string]map[string]int, needsNW, strictIdx map[string]map[string]bool, maxDepth int) (string, error) {
+	plan := BuildCheckPlanWithOrdering(a, filterInlineForCheck(inline, a), databaseSchema, false, complexityByRelation).withTuplesTable(tuplesTable)
+	plan.NeedsNoWildcard = needsNW
+	plan.FunctionName = functionNameStrict(a.NameCollisions, a.ObjectType, a.Relation)
+	plan.Strict = true
+	plan.StrictIndex = strictIdx
+	plan.MaxDepth = maxDepth
+	// Every userset grant now calls check_permission_internal, as a complex
+	// pattern does in the base function.
+	plan.HasComplexUsersets = true
+	plan.NeedsPLpgSQL = true
+	blocks, err := BuildCheckBlocks(plan)
+	if err != nil {
+		return "", fmt.Errorf("building strict check blocks for %s.%s: %w", a.ObjectType, a.Relation, err)
+	}
+	return RenderCheckFunction(plan, blocks)
+}
+
+// generateStrictDispatcher renders check_permission_strict and its internal
+// dispatcher. Each relation routes to its strict variant when strictIdx has
+// one and to the base check function otherwise, so strict checks answer for
+// every relation check_permission does.
+func generateStrictDispatcher(analyses []RelationAnalysis, databaseSchema string, strictIdx map[string]map[string]bool, maxDepth int, nullGuards bool) string {
+	var cases []DispatcherCase
+	for _, a := range analyses {
+		if !a.Capabilities.CheckAllowed {
+			continue
+		}
+		name := functionNameForStrictRef(a.NameCollisions, strictIdx, a.ObjectType, a.Relation)
+		cases = append(cases, DispatcherCase{
+			DatabaseSchema:    databaseSchema,
+			ObjectType:        a.ObjectType,
+			Relation:          a.Relation,
+			CheckFunctionName: name,
+		})
+	}
+	if len(cases) == 0 {
+		return renderEmptyDispatcher(databaseSchema, StrictDispatcherFunctionName)
+	}
+	return renderDispatcherWithCases(databaseSchema, StrictDispatcherFunctionName, cases, maxDepth, nullGuards)
+}
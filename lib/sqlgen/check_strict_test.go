@@ -0,0 +1,88 @@
+package sqlgen
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const strictTestSchema = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type document
+  relations
+    define owner: [user]
+    define editor: [group#member]
+    define viewer: [user] or editor
+`
+
+func TestGenerateSQLStrictCheck(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, strictTestSchema)
+
+	base, err := GenerateSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	strict, err := GenerateSQLWithOptions(analyses, inline, "", GenerateSQLOptions{EnableStrictCheck: true})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	if !reflect.DeepEqual(base.Functions, strict.Functions) || base.Dispatcher != strict.Dispatcher {
+		t.Error("EnableStrictCheck changed the base check functions")
+	}
+	if len(base.StrictFunctions) != 0 || base.DispatcherStrict != "" {
+		t.Error("strict functions generated without EnableStrictCheck")
+	}
+
+	fns := strings.Join(strict.StrictFunctions, "\n")
+	// editor's membership is re-checked instead of joined.
+	assertContains(t, fns, "FUNCTION check_document_editor_strict(")
+	assertContains(t, fns, "check_permission_internal(p_subject_type, p_subject_id, 'member', 'group', left(grant_tuple.subject_id, length(grant_tuple.subject_id) - strpos(reverse(grant_tuple.subject_id), '#'))")
+	// viewer has no userset of its own but implies editor, so its strict
+	// variant calls editor's.
+	assertContains(t, fns, "FUNCTION check_document_viewer_strict(")
+	assertContains(t, fns, "IF check_document_editor_strict(p_subject_type, p_subject_id, p_object_id, ")
+	assertNotContains(t, fns, "check_document_owner_strict")
+
+	assertContains(t, strict.DispatcherStrict, "FUNCTION check_permission_strict(")
+	assertContains(t, strict.DispatcherStrict, "check_document_viewer_strict(")
+	assertContains(t, strict.DispatcherStrict, "check_document_owner(")
+	assertContains(t, strict.DispatcherStrict, "check_group_member(")
+
+	wantNames := []string{
+		"check_document_editor_strict",
+		"check_document_viewer_strict",
+		StrictDispatcherFunctionName,
+		StrictDispatcherFunctionName + "_internal",
+	}
+	gotNames := CollectStrictFunctionNames(analyses)
+	for _, name := range wantNames {
+		if !slices.Contains(gotNames, name) {
+			t.Errorf("CollectStrictFunctionNames missing %s: %v", name, gotNames)
+		}
+	}
+	if len(gotNames) != len(wantNames) {
+		t.Errorf("CollectStrictFunctionNames = %v, want %v", gotNames, wantNames)
+	}
+
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{EnableStrictCheck: true})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+	var named []string
+	for _, fn := range CollectNamedFunctions(strict, list, analyses) {
+		named = append(named, fn.Name)
+	}
+	for _, name := range wantNames[:2] {
+		if !slices.Contains(named, name) {
+			t.Errorf("CollectNamedFunctions missing %s", name)
+		}
+	}
+}
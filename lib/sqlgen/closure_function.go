@@ -0,0 +1,115 @@
+package sqlgen
+
+// ClosureFunctionName and UsersetFunctionName are the model lookup functions
+// emitted when GenerateSQLOptions.ClosureFunction is set. Callers tracking
+// installed functions must expect them in that mode.
+const (
+	ClosureFunctionName = "melange_closure_rows"
+	UsersetFunctionName = "melange_userset_rows"
+)
+
+// generateClosureFunction renders melange_closure_rows(p_object_type,
+// p_relation), which returns the satisfying relations of one relation from the
+// whole-model closure.
+//
+// The function is LANGUAGE sql IMMUTABLE with no SET clause, so the planner
+// inlines it into the calling query and folds it when the arguments are
+// constants. Check and list functions call it instead of carrying their own
+// closure VALUES, which keeps the closure in one place: a schema change that
+// only alters the closure rewrites this function rather than every function
+// embedding it.
+func generateClosureFunction(inline InlineSQLData, databaseSchema string) string {
+	return generateModelLookupFunction(databaseSchema, ClosureFunctionName,
+		ClosureTable(inline.ClosureRows, "c"), []string{"satisfying_relation"},
+		"Generated closure lookup: relations that satisfy (p_object_type, p_relation)")
+}
+
+// generateUsersetFunction renders melange_userset_rows(p_object_type,
+// p_relation), which returns the userset restrictions ([group#member]) one
+// relation accepts. It is generateClosureFunction for the userset rows, and
+// check functions call it instead of carrying their own userset VALUES.
+func generateUsersetFunction(inline InlineSQLData, databaseSchema string) string {
+	return generateModelLookupFunction(databaseSchema, UsersetFunctionName,
+		UsersetTable(inline.UsersetRows, "c"), []string{"subject_type", "subject_relation"},
+		"Generated userset lookup: subject_type#subject_relation accepted by (p_object_type, p_relation)")
+}
+
+// generateModelLookupFunction renders an IMMUTABLE SQL function returning
+// columns of the rows in source, aliased c, keyed on their object_type and
+// relation.
+func generateModelLookupFunction(databaseSchema, name string, source TableExpr, columns []string, header string) string {
+	returns := "TABLE ("
+	selected := make([]string, len(columns))
+	for i, c := range columns {
+		if i > 0 {
+			returns += ", "
+		}
+		returns += c + " TEXT"
+		selected[i] = "c." + c
+	}
+	returns += ")"
+
+	fn := SqlFunction{
+		Schema: databaseSchema,
+		Name:   name,
+		Args: []FuncArg{
+			{Name: "p_object_type", Type: "TEXT"},
+			{Name: "p_relation", Type: "TEXT"},
+		},
+		Returns: returns,
+		Body: SelectStmt{
+			Columns:  selected,
+			FromExpr: source,
+			Where: And(
+				Eq{Left: Col{Table: "c", Column: "object_type"}, Right: Param("p_object_type")},
+				Eq{Left: Col{Table: "c", Column: "relation"}, Right: Param("p_relation")},
+			),
+		},
+		Header: []string{
+			header,
+			"IMMUTABLE and without SET so the planner can inline it into callers",
+		},
+		NoSearchPath: true,
+		Immutable:    true,
+	}
+	return fn.SQL() + "\n"
+}
+
+// closureLookup returns the FROM source and predicates selecting the closure
+// rows of (objectType, relation) under alias, for a query that then filters
+// or selects alias.satisfying_relation.
+//
+// By default the source is the closure VALUES inlined into the function, or
+// the per-function `closure` CTE when hoisted is set (see hoistClosureCTE).
+// With plan.Inline.ClosureFunction it is a call to melange_closure_rows, which
+// takes the lookup key as arguments, so no predicates are returned and no CTE
+// is hoisted.
+func closureLookup(plan ListPlan, alias string, objectType, relation Expr, hoisted bool) (TableExpr, []Expr) {
+	if plan.Inline.ClosureFunction {
+		return FunctionCallExpr{
+			Schema: plan.DatabaseSchema,
+			Name:   ClosureFunctionName,
+			Args:   []Expr{objectType, relation},
+			Alias:  alias,
+		}, nil
+	}
+	from := ClosureTable(plan.Inline.ClosureRows, alias)
+	if hoisted {
+		from = closureCTERef(alias)
+	}
+	return from, []Expr{
+		Eq{Left: Col{Table: alias, Column: "object_type"}, Right: objectType},
+		Eq{Left: Col{Table: alias, Column: "relation"}, Right: relation},
+	}
+}
+
+// closureContains returns the EXISTS subquery testing whether satisfying is
+// in the closure of (objectType, relation). See closureLookup.
+func closureContains(plan ListPlan, alias string, objectType, relation, satisfying Expr, hoisted bool) SelectStmt {
+	from, where := closureLookup(plan, alias, objectType, relation, hoisted)
+	return SelectStmt{
+		ColumnExprs: []Expr{Int(1)},
+		FromExpr:    from,
+		Where:       And(append(where, Eq{Left: Col{Table: alias, Column: "satisfying_relation"}, Right: satisfying})...),
+	}
+}
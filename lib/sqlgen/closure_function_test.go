@@ -0,0 +1,198 @@
+package sqlgen
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func closureFunctionInline() InlineSQLData {
+	return InlineSQLData{ClosureRows: []ValuesRow{
+		{Lit("group"), Lit("member"), Lit("member")},
+		{Lit("group"), Lit("member"), Lit("admin")},
+	}}
+}
+
+func TestClosureFunction_InlinableImmutableLookup(t *testing.T) {
+	sql := generateClosureFunction(closureFunctionInline(), "authz")
+
+	assertContains(t, sql, `CREATE OR REPLACE FUNCTION "authz"."melange_closure_rows"(`)
+	assertContains(t, sql, "RETURNS TABLE (satisfying_relation TEXT)")
+	assertContains(t, sql, "('group', 'member', 'admin')")
+	assertContains(t, sql, "c.object_type = p_object_type AND c.relation = p_relation")
+	assertContains(t, sql, "$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;")
+	// A SET clause would stop the planner from inlining it.
+	assertNotContains(t, sql, "SET search_path")
+}
+
+func TestClosureFunction_InlinableImmutableUsersetLookup(t *testing.T) {
+	sql := generateUsersetFunction(InlineSQLData{UsersetRows: []ValuesRow{
+		{Lit("document"), Lit("viewer"), Lit("group"), Lit("member")},
+	}}, "authz")
+
+	assertContains(t, sql, `CREATE OR REPLACE FUNCTION "authz"."melange_userset_rows"(`)
+	assertContains(t, sql, "RETURNS TABLE (subject_type TEXT, subject_relation TEXT)")
+	assertContains(t, sql, "('document', 'viewer', 'group', 'member')")
+	assertContains(t, sql, "c.object_type = p_object_type AND c.relation = p_relation")
+	assertContains(t, sql, "$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;")
+	assertNotContains(t, sql, "SET search_path")
+}
+
+func TestClosureFunction_ListBlocksCallIt(t *testing.T) {
+	plan := ListPlan{
+		ObjectType:             "document",
+		Relation:               "viewer",
+		DatabaseSchema:         "authz",
+		Inline:                 closureFunctionInline(),
+		AllSatisfyingRelations: []string{"viewer"},
+	}
+
+	inline := buildListSubjectsUsersetFilterDirectBlock(plan).Query.SQL()
+	assertContains(t, inline, "AS subj_c(object_type, relation, satisfying_relation)")
+	assertNotContains(t, inline, "melange_closure_rows")
+
+	plan.Inline.ClosureFunction = true
+	called := buildListSubjectsUsersetFilterDirectBlock(plan).Query.SQL()
+	assertContains(t, called, `FROM "authz"."melange_closure_rows"(v_filter_type, substring(t.subject_id from length(t.subject_id) - strpos(reverse(t.subject_id), '#') + 2)) AS subj_c`)
+	assertContains(t, called, "subj_c.satisfying_relation = v_filter_relation")
+	assertNotContains(t, called, "VALUES")
+
+	// Blocks that read the hoisted closure CTE call the function instead, so
+	// there is nothing left to hoist.
+	match := buildUsersetFilterRelationMatchExpr(plan, "t.subject_id").SQL()
+	assertContains(t, match, `"authz"."melange_closure_rows"(v_filter_type, substring(t.subject_id from length(t.subject_id) - strpos(reverse(t.subject_id), '#') + 2)) AS subj_c`)
+	assertNotContains(t, hoistClosureCTE("WITH base_results AS (SELECT 1 WHERE "+match+")", plan.Inline.ClosureRows), "closure(object_type")
+}
+
+func TestClosureFunction_GatedByOption(t *testing.T) {
+	off, err := GenerateSQL(nil, closureFunctionInline(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off.ClosureFunction != "" || off.UsersetFunction != "" {
+		t.Error("lookup functions generated without ClosureFunction")
+	}
+
+	on, err := GenerateSQLWithOptions(nil, closureFunctionInline(), "", GenerateSQLOptions{ClosureFunction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, nf := range CollectDispatcherFunctions(on, ListGeneratedSQL{}) {
+		names = append(names, nf.Name)
+	}
+	for _, want := range []string{ClosureFunctionName, UsersetFunctionName} {
+		if !slices.Contains(names, want) {
+			t.Errorf("%s missing from dispatcher functions", want)
+		}
+	}
+}
+
+// Check functions look the userset-subject closure and userset restrictions
+// up through the functions too, so none of them embeds model rows.
+func TestClosureFunction_CheckBlocksCallIt(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define admin: [user]
+    define member: [user, group#member] or admin
+
+type document
+  relations
+    define viewer: [group#member]
+`)
+	generate := func(opts GenerateSQLOptions) string {
+		t.Helper()
+		gen, err := GenerateSQLWithOptions(analyses, inline, "authz", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fn := range gen.Functions {
+			if strings.Contains(fn, `FUNCTION "authz"."check_document_viewer"(`) {
+				return fn
+			}
+		}
+		t.Fatal("check_document_viewer not generated")
+		return ""
+	}
+
+	inlined := generate(GenerateSQLOptions{})
+	assertContains(t, inlined, "AS subj_c(object_type, relation, satisfying_relation)")
+	assertNotContains(t, inlined, "melange_closure_rows")
+
+	called := generate(GenerateSQLOptions{ClosureFunction: true})
+	assertContains(t, called, `INNER JOIN "authz"."melange_closure_rows"(t.subject_type, substring(t.subject_id from length(t.subject_id) - strpos(reverse(t.subject_id), '#') + 2)) AS subj_c`)
+	assertContains(t, called, "subj_c.satisfying_relation = substring(p_subject_id from")
+	assertContains(t, called, `INNER JOIN "authz"."melange_userset_rows"('document', t.relation) AS m ON m.subject_type = t.subject_type`)
+	assertNotContains(t, called, "VALUES")
+}
+
+// modelLookupSchema returns a model of n types: user, group, and n-2
+// resource types, each granting to users and group members directly, through
+// an implied relation and through a TTU to the previous resource type.
+func modelLookupSchema(n int) string {
+	var b strings.Builder
+	b.WriteString("model\n  schema 1.1\n\ntype user\n\ntype group\n  relations\n    define member: [user, group#member]\n")
+	for i := range n - 2 {
+		fmt.Fprintf(&b, "\ntype resource%d\n  relations\n", i)
+		viewer := "[user, group#member] or editor"
+		if i > 0 {
+			fmt.Fprintf(&b, "    define parent: [resource%d]\n", i-1)
+			viewer += " or viewer from parent"
+		}
+		b.WriteString("    define owner: [user, group#member]\n")
+		b.WriteString("    define editor: [user, group#member] or owner\n")
+		fmt.Fprintf(&b, "    define viewer: %s\n", viewer)
+	}
+	return b.String()
+}
+
+// On the 50-type modelLookupSchema the per-relation check, explain and list
+// bodies embed 1,015 closure and userset VALUES tables. With ClosureFunction
+// they embed none, and the bodies shrink from 6,025,225 to 5,759,292 bytes
+// (-4.4%) plus 19,343 bytes for the two lookup functions. The filtered VALUES
+// were already small per function; what the lookups remove is their growth
+// with the model.
+func TestClosureFunction_BodiesEmbedNoModelRows(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, modelLookupSchema(50))
+	generate := func(opts GenerateSQLOptions) (GeneratedSQL, []string) {
+		t.Helper()
+		gen, err := GenerateSQLWithOptions(analyses, inline, "authz", opts)
+		if err != nil {
+			t.Fatalf("GenerateSQLWithOptions: %v", err)
+		}
+		list, err := GenerateListSQLWithOptions(analyses, inline, "authz", opts)
+		if err != nil {
+			t.Fatalf("GenerateListSQLWithOptions: %v", err)
+		}
+		return gen, slices.Concat(gen.Functions, gen.NoWildcardFunctions, gen.ExplainFunctions,
+			list.ListObjectsFunctions, list.ListSubjectsFunctions)
+	}
+	size := func(bodies []string) (n int) {
+		for _, b := range bodies {
+			n += len(b)
+		}
+		return n
+	}
+
+	_, offBodies := generate(GenerateSQLOptions{})
+	on, onBodies := generate(GenerateSQLOptions{ClosureFunction: true})
+
+	joined := strings.Join(onBodies, "\n")
+	assertNotContains(t, joined, "(VALUES")
+	assertContains(t, joined, `"authz"."melange_closure_rows"(t.subject_type,`)
+	assertContains(t, joined, `"authz"."melange_userset_rows"('resource47', t.relation) AS m`)
+	assertContains(t, on.ClosureFunction, "('resource47', 'viewer', 'owner')")
+
+	lookups := len(on.ClosureFunction) + len(on.UsersetFunction)
+	t.Logf("tables: %d inline; bodies: %d bytes inline, %d bytes with lookups (+%d bytes of lookup functions)",
+		strings.Count(strings.Join(offBodies, "\n"), "(VALUES"), size(offBodies), size(onBodies), lookups)
+	if size(onBodies)+lookups >= size(offBodies) {
+		t.Errorf("ClosureFunction should shrink generated SQL: %d+%d >= %d bytes", size(onBodies), lookups, size(offBodies))
+	}
+}
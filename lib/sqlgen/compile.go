@@ -55,6 +55,12 @@ type GeneratedSQL struct {
 	// wildcard-reachability walk.
 	NoWildcardIndex map[string]map[string]bool
 
+	// ContextFunctions contains CREATE OR REPLACE FUNCTION statements for the
+	// check_{type}_{relation}_ctx variants, one per relation with conditional
+	// grants. Each takes a p_context JSONB and evaluates the conditions
+	// against it.
+	ContextFunctions []string
+
 	// Dispatcher contains the check_permission dispatcher function
 	// that routes requests to specialized functions based on object type and relation.
 	Dispatcher string
@@ -62,10 +68,45 @@ type GeneratedSQL struct {
 	// DispatcherNoWildcard contains the check_permission_nw dispatcher.
 	DispatcherNoWildcard string
 
+	// StrictFunctions contains the check_{type}_{relation}_strict variants,
+	// one per relation with a userset grant the base function resolves by
+	// joining the membership tuple directly. Empty unless
+	// GenerateSQLOptions.EnableStrictCheck is set; see
+	// generateStrictCheckFunction.
+	StrictFunctions []string
+
+	// DispatcherStrict contains check_permission_strict and its internal
+	// dispatcher. Empty unless GenerateSQLOptions.EnableStrictCheck is set.
+	DispatcherStrict string
+
+	// ContextDispatcher contains check_permission_with_context, which routes
+	// relations with conditional grants to their _ctx function and all others
+	// to check_permission.
+	ContextDispatcher string
+
+	// ContextualTuplesFunction contains check_permission_with_contextual_tuples,
+	// which checks with extra tuples passed as JSONB. Empty when the tuples
+	// table is schema-qualified or Dialect is DialectCockroach; see
+	// generateContextualTuplesFunction.
+	ContextualTuplesFunction string
+
 	// BulkDispatcher contains the check_permission_bulk function that evaluates
 	// multiple permission checks in a single SQL call using UNION ALL branches.
 	BulkDispatcher string
 
+	// BatchDispatcher contains check_permission_batch, which checks a JSONB
+	// array of requests through check_permission and returns one row per
+	// element in input order.
+	BatchDispatcher string
+
+	// CheckAnyDispatcher contains the check_any combinator, which returns TRUE
+	// when any relation in a list grants access. Routes through check_permission.
+	CheckAnyDispatcher string
+
+	// CheckAllDispatcher contains the check_all combinator, which returns TRUE
+	// only when every relation in a list grants access.
+	CheckAllDispatcher string
+
 	// ExplainFunctions contains CREATE OR REPLACE FUNCTION statements for the
 	// per-relation explain_{type}_{relation} functions. Each returns JSONB
 	// shaped to melange.Trace and is the codegen companion to check_*.
@@ -90,20 +131,47 @@ type GeneratedSQL struct {
 
 	// ExpandDispatcher contains the expand_permission public + internal
 	// functions that route to per-relation expand_* by (object_type, relation).
-	// Returns an empty Leaf.Users sentinel for unknown / not-yet-supported
-	// pairs so OpenFGA tooling deserialises without special-casing.
+	// Returns an empty Leaf.Users sentinel for unknown pairs so OpenFGA
+	// tooling deserialises without special-casing, and raises M2004 for a
+	// checkable relation with no expand function.
 	ExpandDispatcher string
 
 	// ExpandEligible records the (object_type, relation) pairs for which an
-	// expand function was generated. Stage 2 slice 2.1 gates many shapes
-	// (TTU, intersection, exclusion, usersets, wildcards, complex usersets)
-	// out — those route to the dispatcher's empty-leaf sentinel until the
-	// follow-up slices land.
+	// expand function was generated. Checkable relations missing from it
+	// raise M2004 (relation not expandable) in the dispatcher.
 	ExpandEligible map[string]map[string]bool
 
+	// EffectiveAccessFunction contains the effective_access audit function.
+	// Empty unless GenerateSQLOptions.EnableEffectiveAccess is set; see
+	// generateEffectiveAccessFunction.
+	EffectiveAccessFunction string
+
+	// ModelRelationsFunction contains melange_model_relations, the SQL
+	// inventory of the model's relations. Always generated; see
+	// generateModelRelationsFunction.
+	ModelRelationsFunction string
+
+	// ClosureFunction and UsersetFunction contain melange_closure_rows and
+	// melange_userset_rows, the closure and userset lookups the check and list
+	// functions call. Empty unless GenerateSQLOptions.ClosureFunction is set;
+	// see generateClosureFunction.
+	ClosureFunction string
+	UsersetFunction string
+
+	// HealthcheckFunction contains melange_healthcheck. GenerateSQL leaves it
+	// empty because it embeds the schema hash and every generated function
+	// name; the migrator fills it in. See GenerateHealthcheckFunction.
+	HealthcheckFunction string
+
+	// EvidenceFunctions contains the check_with_evidence_{type}_{relation}
+	// audit functions, one per relation with a check function. Empty unless
+	// GenerateSQLOptions.EnableCheckEvidence is set; see
+	// generateEvidenceFunction.
+	EvidenceFunctions []string
+
 	// IndexRecommendations lists composite indexes that make the generated
 	// functions efficient against melange_tuples. Advisory only — users
-	// translate the DDL to their source tables. See RecommendIndexes.
+	// translate the DDL to their source tables. See RecommendIndexesWithTable.
 	IndexRecommendations []IndexRecommendation
 }
 
@@ -120,6 +188,167 @@ type GenerateSQLOptions struct {
 	// wins (typically queries whose inner CTE is recomputed many times due to
 	// inlining and produces non-trivial row counts).
 	EnableMaterializedCTEs bool
+
+	// EnableEffectiveAccess emits effective_access(p_subject_type,
+	// p_subject_id, p_root_type, p_root_id), an audit function reporting every
+	// relation a subject holds on an object and on everything reachable from it
+	// through TTU links. It composes many list functions per call, so it is off
+	// by default and intended for support tooling rather than request paths.
+	EnableEffectiveAccess bool
+
+	// EnableCheckEvidence emits check_with_evidence_{type}_{relation}(
+	// p_subject_type, p_subject_id, p_object_id) per relation, returning the
+	// check result together with the tuple that granted it, for audit logs
+	// that must record why access was allowed. Each call runs the full check
+	// and then searches for evidence, so it is off by default.
+	EnableCheckEvidence bool
+
+	// EnableStrictCheck emits check_permission_strict, a third check
+	// entrypoint beside check_permission and check_permission_nw. It answers
+	// as check_permission does, but resolves every userset grant
+	// ([group#member]) on the checked relation through
+	// check_permission_internal instead of joining the membership tuple
+	// directly, trading speed for full evaluation of membership during
+	// sensitive operations. Relations without such grants route to their
+	// base check function. See generateStrictCheckFunction.
+	EnableStrictCheck bool
+
+	// PoolerSafe emits bodies that read no session-level state, for
+	// deployments behind a transaction-mode pooler such as PgBouncer. There a
+	// session SET lingers on the server connection and leaks into whichever
+	// client is routed to it next, so explain functions stop consulting the
+	// melange.max_explain_nodes GUC and resolve the node cap from p_max_nodes
+	// or the built-in default only. Nothing else in the generated SQL holds
+	// session state: search_path is pinned per function, and no body creates
+	// temp objects or prepared statements.
+	PoolerSafe bool
+
+	// EnableCheckMemo memoizes check_permission_internal within each top-level
+	// check_permission call, so a sub-check that intersections or exclusions
+	// reach more than once is evaluated once. The memo lives in
+	// transaction-local settings (compatible with PoolerSafe), which makes
+	// check_permission, its internal dispatcher, check_permission_batch and
+	// check_any/check_all PARALLEL UNSAFE: queries calling them no longer get parallel plans. See
+	// generateMemoDispatcher.
+	EnableCheckMemo bool
+
+	// TableRoutedDispatcher emits check_permission_internal as a fixed-size
+	// function that looks the specialized function up in the melange_routes
+	// table and calls it with EXECUTE, instead of an IF-chain with one arm
+	// per relation. It plans faster on the first call of each session for
+	// very large schemas but costs more per call, and callers need SELECT on
+	// melange_routes. Incompatible with EnableCheckMemo. See
+	// generateTableRoutedDispatcher.
+	TableRoutedDispatcher bool
+
+	// AnytimeListObjects makes unpaged calls to recursive (TTU) list_objects
+	// functions return objects granted at the base level (direct tuples,
+	// usersets, non-recursive parents) before objects reached through the
+	// recursion, so a client reading rows as they arrive can act on that
+	// subset first. The complete set is unchanged; only its order differs.
+	// PL/pgSQL still collects the whole result before the first row leaves
+	// the function, and paged calls keep ordering by object_id. See
+	// renderAnytimeListObjectsQuery.
+	AnytimeListObjects bool
+
+	// ClosureFunction emits melange_closure_rows(p_object_type, p_relation)
+	// and melange_userset_rows(p_object_type, p_relation), IMMUTABLE SQL
+	// functions returning the relation closure and userset restrictions, and
+	// makes check and list functions call them instead of each inlining those
+	// rows as VALUES. Bodies then stop growing with the model, and a schema
+	// change that only alters the rows rewrites the two functions rather than
+	// every function embedding them. See generateClosureFunction.
+	ClosureFunction bool
+
+	// EnableListObjectsCursor emits list_{type}_{relation}_objects_cursor for
+	// each listable relation and the list_accessible_objects_cursor
+	// dispatcher. Each opens and returns a refcursor over the unpaged rows of
+	// the matching list_objects call, so a client can FETCH a large result in
+	// batches within a transaction. It adds a function per listable relation,
+	// so it is opt-in. See generateListObjectsCursorFunction.
+	EnableListObjectsCursor bool
+
+	// ExpandWildcardSubjects makes list_subjects functions called with
+	// p_expand_wildcard draw the subjects a wildcard grant covers from the
+	// SubjectsView relation (subject_type, subject_id), which the user
+	// creates to list every known subject per type, instead of from the
+	// subjects that appear in tuples. Subjects without tuples are then
+	// listed too. It applies to wildcard grants found by the direct lookup
+	// and to v_filter_type:* grants on the userset filter path. The view is
+	// read only when p_expand_wildcard is set, so calls without it need no
+	// view. See buildListSubjectsWildcardExpansionBlock.
+	ExpandWildcardSubjects bool
+
+	// ObjectDelimiter separates type from id in the "type:id" strings taken
+	// by the string overloads of check_permission and the list dispatchers.
+	// Empty means DefaultObjectDelimiter (":"). It must not contain "#"; see
+	// ValidateObjectDelimiter. Tuples store type and id in separate columns
+	// and are unaffected, but callers must build their strings with the same
+	// delimiter. Expand and explain output keep OpenFGA's ":" form.
+	ObjectDelimiter string
+
+	// TuplesTable is the relation generated functions read tuples from,
+	// e.g. "tenant_42.tuples". Empty means DefaultTuplesTable
+	// ("melange_tuples"). The name is rendered as written and is not
+	// prefixed with the database schema, so an unqualified name resolves
+	// through search_path; see ValidateTuplesTable. Contextual tuples shadow
+	// an unqualified name with a pg_temp view, which a schema-qualified name
+	// bypasses, so checkers must be told the same name
+	// (melange.WithTuplesTable).
+	TuplesTable string
+
+	// MaxDepth is the recursion depth limit of the generated SQL. Zero means
+	// DefaultMaxDepth (25); it must not be negative, see ValidateMaxDepth.
+	// Check resolution raises M2002 once it nests this deep. Recursive list
+	// functions walk their CTEs to this depth and raise M2002 when the walk
+	// reaches it, rather than return a truncated result, and relations whose
+	// userset chains are this deep get functions that raise immediately,
+	// provided ComputeCanGenerateWithMaxDepth was given the same limit.
+	MaxDepth int
+
+	// SchemaHash is the SchemaHash of the model being generated. When set,
+	// check_permission gets an overload with a trailing
+	// p_expected_schema_hash that raises M2005 unless it matches, so clients
+	// can fail fast when the installed functions were generated from another
+	// schema. The migrator sets it to the hash melange_healthcheck reports.
+	// Empty omits the overload. See generateSchemaHashOverload.
+	SchemaHash string
+
+	// Dialect is the database the functions are created in. Empty means
+	// DialectPostgres. DialectCockroach drops the function options
+	// CockroachDB rejects (see Dialect.Adapt), so the functions load there
+	// without search_path pinning or COMMENT ON FUNCTION metadata, and
+	// explain functions resolve their node cap as under PoolerSafe. It
+	// cannot be combined with EnableCheckMemo or EnableListObjectsCursor;
+	// see ValidateDialect.
+	Dialect Dialect
+
+	// TraceBlocks prefixes the comment of every UNION branch in generated
+	// list functions with a tag naming the relation being generated, the
+	// feature that produced the branch (direct, implied, userset, ttu or
+	// intersection), and the userset or TTU pattern it follows, e.g.
+	// "-- [relation=document.viewer feature=userset pattern=group#member]".
+	// It is a debugging aid for mapping generated SQL back to the analysis
+	// (see test/cmd/dumpsql -trace); default output is unchanged. Check
+	// functions are not tagged.
+	TraceBlocks bool
+
+	// DisableNullGuards omits the NULL checks at the top of generated check
+	// and list functions and of the check_permission_internal,
+	// list_accessible_objects and list_accessible_subjects dispatchers. By
+	// default each raises 22004 (null_value_not_allowed), naming the function
+	// and the parameter, when a required argument is NULL, rather than
+	// comparing against NULL and silently denying or listing nothing.
+	// Arguments with a default (p_limit, p_after, ...) stay nullable. The
+	// checks run on every call, recursive ones included; deployments that
+	// never pass NULL can turn them off.
+	DisableNullGuards bool
+}
+
+// nullGuards reports whether generated functions check their required
+// arguments for NULL.
+func (o GenerateSQLOptions) nullGuards() bool {
+	return !o.DisableNullGuards
 }
 
 // GenerateSQL generates specialized SQL functions for all relations in the schema
@@ -142,13 +371,47 @@ func GenerateSQL(analyses []RelationAnalysis, inline InlineSQLData, databaseSche
 
 // GenerateSQLWithOptions is the option-aware variant of GenerateSQL.
 //
-// Currently the option set only affects list-function codegen (via
-// GenerateListSQLWithOptions); check-function output is independent of the
-// options today. The option is accepted here to keep a single public surface
-// the migrator can configure once.
-func GenerateSQLWithOptions(analyses []RelationAnalysis, inline InlineSQLData, databaseSchema string, _ GenerateSQLOptions) (GeneratedSQL, error) {
+// EnableEffectiveAccess, EnableCheckEvidence, PoolerSafe, EnableCheckMemo,
+// TableRoutedDispatcher, ClosureFunction, ObjectDelimiter, TuplesTable,
+// MaxDepth, and DisableNullGuards are the options that affect this output;
+// EnableMaterializedCTEs applies to list-function codegen (via
+// GenerateListSQLWithOptions). The full option set is accepted here to keep a
+// single public surface the migrator can configure once.
+func GenerateSQLWithOptions(analyses []RelationAnalysis, inline InlineSQLData, databaseSchema string, opts GenerateSQLOptions) (GeneratedSQL, error) {
+	return generateSQL(analyses, inline, databaseSchema, opts, nil)
+}
+
+// generateSQL implements GenerateSQLWithOptions and GenerateSQLCached. A nil
+// cache renders every per-relation function.
+func generateSQL(analyses []RelationAnalysis, inline InlineSQLData, databaseSchema string, opts GenerateSQLOptions, cache *GenerationCache) (GeneratedSQL, error) {
+	if opts.EnableCheckMemo && opts.TableRoutedDispatcher {
+		return GeneratedSQL{}, fmt.Errorf("EnableCheckMemo and TableRoutedDispatcher cannot be combined")
+	}
+	if err := ValidateObjectDelimiter(opts.ObjectDelimiter); err != nil {
+		return GeneratedSQL{}, err
+	}
+	if err := ValidateTuplesTable(opts.TuplesTable); err != nil {
+		return GeneratedSQL{}, err
+	}
+	if err := ValidateMaxDepth(opts.MaxDepth); err != nil {
+		return GeneratedSQL{}, err
+	}
+	if err := ValidateDialect(opts); err != nil {
+		return GeneratedSQL{}, err
+	}
+	tuplesTable := TuplesTableName(opts.TuplesTable)
+	maxDepth := opts.maxDepth()
+	nullGuards := opts.nullGuards()
+	poolerSafe := opts.poolerSafe()
+
 	var result GeneratedSQL
 
+	inline.ClosureFunction = opts.ClosureFunction
+	if inline.ClosureFunction {
+		result.ClosureFunction = generateClosureFunction(inline, databaseSchema)
+		result.UsersetFunction = generateUsersetFunction(inline, databaseSchema)
+	}
+
 	complexityByRelation := buildClosureComplexityIndex(analyses)
 	// needsNW maps type->relation->whether a distinct _nw check function is
 	// emitted. A _nw body is byte-identical to its base unless the relation can
@@ -171,25 +434,69 @@ func GenerateSQLWithOptions(analyses []RelationAnalysis, inline InlineSQLData, d
 	// the caller chases, so an ineligible callee doesn't disable the
 	// caller.
 	expandEligible := make(map[string]map[string]bool, len(analyses))
+	// Per-relation functions are emitted callees first; dispatchers keep
+	// the input order.
+	ordered := callOrder(analyses)
 
 	// Generate specialized function for each relation
-	for _, a := range analyses {
+	for _, a := range ordered {
 		if !a.Capabilities.CheckAllowed {
 			continue
 		}
-		fn, err := generateCheckFunction(a, inline, databaseSchema, false, complexityByRelation, needsNW)
+		// cached renders one of a's functions through cache, keyed by the
+		// fingerprint of a and everything else the generator reads.
+		cached := func(kind string, render func() (string, error)) (string, error) {
+			if cache == nil {
+				return render()
+			}
+			key, err := fingerprint(kind, a, inline, databaseSchema, tuplesTable, complexityByRelation, needsNW, poolerSafe, maxDepth, nullGuards)
+			if err != nil {
+				return "", err
+			}
+			return cache.fetch(key, render)
+		}
+
+		fn, err := cached("check", func() (string, error) {
+			return generateCheckFunction(a, inline, databaseSchema, tuplesTable, false, complexityByRelation, needsNW, maxDepth, nullGuards)
+		})
 		if err != nil {
-			return GeneratedSQL{}, fmt.Errorf("generating check function: %w", err)
+			return GeneratedSQL{}, relationError(a, FunctionKindCheck, err)
 		}
+		fn = withFunctionMetadata(fn, a, FunctionKindCheck, functionName(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, checkFunctionArgs())
 		result.Functions = append(result.Functions, fn)
 		if needsNW[a.ObjectType][a.Relation] {
-			noWildcardFn, err := generateCheckFunction(a, inline, databaseSchema, true, complexityByRelation, needsNW)
+			noWildcardFn, err := cached("check_nw", func() (string, error) {
+				return generateCheckFunction(a, inline, databaseSchema, tuplesTable, true, complexityByRelation, needsNW, maxDepth, nullGuards)
+			})
 			if err != nil {
-				return GeneratedSQL{}, fmt.Errorf("generating no-wildcard check function: %w", err)
+				return GeneratedSQL{}, relationError(a, FunctionKindCheckNoWildcard, err)
 			}
+			noWildcardFn = withFunctionMetadata(noWildcardFn, a, FunctionKindCheckNoWildcard,
+				functionNameNoWildcard(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, checkFunctionArgs())
 			result.NoWildcardFunctions = append(result.NoWildcardFunctions, noWildcardFn)
 		}
-		if expandFn, ok := generateExpandFunction(a, databaseSchema); ok {
+		if len(a.Conditions) > 0 {
+			contextFn, err := cached("check_ctx", func() (string, error) {
+				return generateContextFunction(a, databaseSchema, tuplesTable), nil
+			})
+			if err != nil {
+				return GeneratedSQL{}, relationError(a, FunctionKindCheckContext, err)
+			}
+			contextFn = withFunctionMetadata(contextFn, a, FunctionKindCheckContext,
+				contextFunctionName(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, contextFunctionArgs())
+			result.ContextFunctions = append(result.ContextFunctions, contextFn)
+		}
+		// An ineligible relation caches as "", since no expand body is empty.
+		expandFn, err := cached("expand", func() (string, error) {
+			fn, _ := generateExpandFunction(a, databaseSchema, tuplesTable)
+			return fn, nil
+		})
+		if err != nil {
+			return GeneratedSQL{}, relationError(a, FunctionKindExpand, err)
+		}
+		if expandFn != "" {
+			expandFn = withFunctionMetadata(expandFn, a, FunctionKindExpand,
+				expandFunctionName(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, expandFunctionArgs())
 			result.ExpandFunctions = append(result.ExpandFunctions, expandFn)
 			if expandEligible[a.ObjectType] == nil {
 				expandEligible[a.ObjectType] = make(map[string]bool)
@@ -199,37 +506,104 @@ func GenerateSQLWithOptions(analyses []RelationAnalysis, inline InlineSQLData, d
 		if !explainEligible[a.ObjectType][a.Relation] {
 			continue
 		}
-		explainFn, err := generateExplainFunction(a, inline, databaseSchema, complexityByRelation)
+		explainFn, err := cached("explain", func() (string, error) {
+			return generateExplainFunction(a, inline, databaseSchema, tuplesTable, complexityByRelation, poolerSafe, maxDepth)
+		})
 		if err != nil {
-			return GeneratedSQL{}, fmt.Errorf("generating explain function: %w", err)
+			return GeneratedSQL{}, relationError(a, FunctionKindExplain, err)
 		}
+		explainFn = withFunctionMetadata(explainFn, a, FunctionKindExplain,
+			explainFunctionName(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, explainFunctionArgs())
 		result.ExplainFunctions = append(result.ExplainFunctions, explainFn)
 	}
 	result.ExpandEligible = expandEligible
 
 	// Generate dispatchers
 	var err error
-	result.Dispatcher, err = generateDispatcher(analyses, databaseSchema, false, nil)
-	if err != nil {
-		return GeneratedSQL{}, fmt.Errorf("generating dispatcher: %w", err)
+	switch {
+	case opts.EnableCheckMemo:
+		result.Dispatcher = generateMemoDispatcher(analyses, databaseSchema, maxDepth, nullGuards)
+	case opts.TableRoutedDispatcher:
+		result.Dispatcher = generateTableRoutedDispatcher(analyses, databaseSchema, maxDepth, nullGuards)
+	default:
+		result.Dispatcher, err = generateDispatcher(analyses, databaseSchema, false, nil, maxDepth, nullGuards)
+		if err != nil {
+			return GeneratedSQL{}, fmt.Errorf("generating dispatcher: %w", err)
+		}
 	}
-	result.DispatcherNoWildcard, err = generateDispatcher(analyses, databaseSchema, true, needsNW)
+	result.Dispatcher += "\n" + generateCheckStringOverload(databaseSchema, opts.objectDelimiter(), opts.EnableCheckMemo)
+	if opts.SchemaHash != "" {
+		result.Dispatcher += "\n" + generateSchemaHashOverload(databaseSchema, opts.SchemaHash, opts.EnableCheckMemo)
+	}
+	result.DispatcherNoWildcard, err = generateDispatcher(analyses, databaseSchema, true, needsNW, maxDepth, nullGuards)
 	if err != nil {
 		return GeneratedSQL{}, fmt.Errorf("generating no-wildcard dispatcher: %w", err)
 	}
-	result.ExplainDispatcher, err = generateExplainDispatcher(analyses, databaseSchema, explainEligible)
+	result.ContextDispatcher = generateContextDispatcher(analyses, databaseSchema)
+	if !opts.Dialect.IsCockroach() {
+		result.ContextualTuplesFunction = generateContextualTuplesFunction(databaseSchema, tuplesTable)
+	}
+	result.ExplainDispatcher, err = generateExplainDispatcher(analyses, databaseSchema, explainEligible, maxDepth)
 	if err != nil {
 		return GeneratedSQL{}, fmt.Errorf("generating explain dispatcher: %w", err)
 	}
 	result.ExpandDispatcher = generateExpandDispatcher(analyses, databaseSchema, expandEligible)
 
 	// Generate bulk dispatcher
-	result.BulkDispatcher = generateBulkDispatcher(analyses, databaseSchema)
+	result.BulkDispatcher = generateBulkDispatcher(analyses, databaseSchema, tuplesTable)
+
+	// The batch dispatcher and combinators are schema-independent wrappers
+	// over check_permission.
+	result.BatchDispatcher = generateBatchDispatcher(databaseSchema, opts.EnableCheckMemo)
+	result.CheckAnyDispatcher = generateCheckAnyDispatcher(databaseSchema, opts.EnableCheckMemo)
+	result.CheckAllDispatcher = generateCheckAllDispatcher(databaseSchema, opts.EnableCheckMemo)
+
+	result.ModelRelationsFunction = generateModelRelationsFunction(analyses, databaseSchema)
+
+	if opts.EnableEffectiveAccess {
+		result.EffectiveAccessFunction = generateEffectiveAccessFunction(analyses, databaseSchema, tuplesTable, maxDepth)
+	}
+
+	if opts.EnableStrictCheck {
+		strictIdx := buildStrictIndex(analyses)
+		for _, a := range ordered {
+			if !strictIdx[a.ObjectType][a.Relation] {
+				continue
+			}
+			fn, err := generateStrictCheckFunction(a, inline, databaseSchema, tuplesTable, complexityByRelation, needsNW, strictIdx, maxDepth)
+			if err != nil {
+				return GeneratedSQL{}, relationError(a, FunctionKindCheckStrict, err)
+			}
+			fn = withFunctionMetadata(fn, a, FunctionKindCheckStrict,
+				functionNameStrict(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, checkFunctionArgs())
+			result.StrictFunctions = append(result.StrictFunctions, fn)
+		}
+		result.DispatcherStrict = generateStrictDispatcher(analyses, databaseSchema, strictIdx, maxDepth, nullGuards)
+	}
+
+	if opts.EnableCheckEvidence {
+		evidenceRelations := make(map[string]map[string]bool)
+		for _, a := range analyses {
+			if !a.Capabilities.CheckAllowed {
+				continue
+			}
+			if evidenceRelations[a.ObjectType] == nil {
+				evidenceRelations[a.ObjectType] = make(map[string]bool)
+			}
+			evidenceRelations[a.ObjectType][a.Relation] = true
+		}
+		for _, a := range ordered {
+			if a.Capabilities.CheckAllowed {
+				result.EvidenceFunctions = append(result.EvidenceFunctions, generateEvidenceFunction(a, inline, databaseSchema, tuplesTable, evidenceRelations))
+			}
+		}
+	}
 
 	// Index recommendations are advisory and derived from the same analyses;
 	// emitting them here keeps the per-schema output self-contained.
-	result.IndexRecommendations = RecommendIndexes(analyses)
+	result.IndexRecommendations = RecommendIndexesWithTable(analyses, tuplesTable)
 
+	adaptGeneratedSQL(&result, opts.Dialect)
 	return result, nil
 }
 
@@ -270,20 +644,29 @@ func buildNoWildcardIndex(analyses []RelationAnalysis) map[string]map[string]boo
 // _nw variant of (objectType, relation): the _nw function when one is emitted,
 // otherwise the base function (whose body is identical). needsNW may be nil, in
 // which case the _nw name is always used (assume emitted).
-func functionNameForNoWildcardRef(needsNW map[string]map[string]bool, objectType, relation string) string {
+func functionNameForNoWildcardRef(collisions NameCollisions, needsNW map[string]map[string]bool, objectType, relation string) string {
 	if needsNW == nil || needsNW[objectType][relation] {
-		return functionNameNoWildcard(objectType, relation)
+		return functionNameNoWildcard(collisions, objectType, relation)
+	}
+	return functionName(collisions, objectType, relation)
+}
+
+// nameCollisions returns the RelationAnalysis.NameCollisions every analysis
+// of a schema shares.
+func nameCollisions(analyses []RelationAnalysis) NameCollisions {
+	if len(analyses) == 0 {
+		return nil
 	}
-	return functionName(objectType, relation)
+	return analyses[0].NameCollisions
 }
 
 // functionName returns the name for a specialized check function.
-func functionName(objectType, relation string) string {
-	return SafeIdentifier("check_", objectType, relation, "")
+func functionName(collisions NameCollisions, objectType, relation string) string {
+	return RelationIdentifier(collisions, "check_", objectType, relation, "")
 }
 
-func functionNameNoWildcard(objectType, relation string) string {
-	return SafeIdentifier("check_", objectType, relation, "_nw")
+func functionNameNoWildcard(collisions NameCollisions, objectType, relation string) string {
+	return RelationIdentifier(collisions, "check_", objectType, relation, "_nw")
 }
 
 // computeHasStandaloneAccess determines if the relation has access paths outside of intersections.
@@ -344,17 +727,19 @@ type NamedFunction struct {
 //
 // The analyses slice must be the same slice, in the same order, passed to
 // GenerateSQL and GenerateListSQL that produced generatedSQL and listSQL.
-// The function walks all three in lockstep; mismatched ordering will silently
-// produce incorrect name-to-SQL pairings.
+// The function walks all three in lockstep, in callOrder, so the result is
+// callees first; mismatched ordering will silently produce incorrect
+// name-to-SQL pairings.
 func CollectNamedFunctions(
 	generatedSQL GeneratedSQL,
 	listSQL ListGeneratedSQL,
 	analyses []RelationAnalysis,
 ) []NamedFunction {
 	var result []NamedFunction
-	checkIdx, noWildcardIdx, explainIdx, expandIdx := 0, 0, 0, 0
-	listObjIdx, listSubjIdx := 0, 0
+	checkIdx, noWildcardIdx, contextIdx, explainIdx, expandIdx, strictFnIdx, evidenceIdx := 0, 0, 0, 0, 0, 0, 0
+	listObjIdx, listSubjIdx, listCursorIdx := 0, 0, 0
 	explainEligible := generatedSQL.ExplainEligible
+	strictIdx := buildStrictIndex(analyses)
 	expandEligible := generatedSQL.ExpandEligible
 	// Reuse the index computed during generation; fall back for callers that
 	// construct GeneratedSQL without it.
@@ -363,46 +748,74 @@ func CollectNamedFunctions(
 		needsNW = buildNoWildcardIndex(analyses)
 	}
 
-	for _, a := range analyses {
+	for _, a := range callOrder(analyses) {
 		if a.Capabilities.CheckAllowed {
 			result = append(result, NamedFunction{
-				Name: functionName(a.ObjectType, a.Relation),
+				Name: functionName(a.NameCollisions, a.ObjectType, a.Relation),
 				SQL:  generatedSQL.Functions[checkIdx],
 			})
 			checkIdx++
 			if needsNW[a.ObjectType][a.Relation] {
 				result = append(result, NamedFunction{
-					Name: functionNameNoWildcard(a.ObjectType, a.Relation),
+					Name: functionNameNoWildcard(a.NameCollisions, a.ObjectType, a.Relation),
 					SQL:  generatedSQL.NoWildcardFunctions[noWildcardIdx],
 				})
 				noWildcardIdx++
 			}
+			if len(a.Conditions) > 0 {
+				result = append(result, NamedFunction{
+					Name: contextFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+					SQL:  generatedSQL.ContextFunctions[contextIdx],
+				})
+				contextIdx++
+			}
 			if expandEligible[a.ObjectType][a.Relation] {
 				result = append(result, NamedFunction{
-					Name: expandFunctionName(a.ObjectType, a.Relation),
+					Name: expandFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
 					SQL:  generatedSQL.ExpandFunctions[expandIdx],
 				})
 				expandIdx++
 			}
 			if explainEligible[a.ObjectType][a.Relation] {
 				result = append(result, NamedFunction{
-					Name: explainFunctionName(a.ObjectType, a.Relation),
+					Name: explainFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
 					SQL:  generatedSQL.ExplainFunctions[explainIdx],
 				})
 				explainIdx++
 			}
+			if strictFnIdx < len(generatedSQL.StrictFunctions) && strictIdx[a.ObjectType][a.Relation] {
+				result = append(result, NamedFunction{
+					Name: functionNameStrict(a.NameCollisions, a.ObjectType, a.Relation),
+					SQL:  generatedSQL.StrictFunctions[strictFnIdx],
+				})
+				strictFnIdx++
+			}
+			if evidenceIdx < len(generatedSQL.EvidenceFunctions) {
+				result = append(result, NamedFunction{
+					Name: evidenceFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+					SQL:  generatedSQL.EvidenceFunctions[evidenceIdx],
+				})
+				evidenceIdx++
+			}
 		}
 		if a.Capabilities.ListAllowed {
 			result = append(result, NamedFunction{
-				Name: listObjectsFunctionName(a.ObjectType, a.Relation),
+				Name: listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
 				SQL:  listSQL.ListObjectsFunctions[listObjIdx],
 			})
 			listObjIdx++
 			result = append(result, NamedFunction{
-				Name: listSubjectsFunctionName(a.ObjectType, a.Relation),
+				Name: listSubjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
 				SQL:  listSQL.ListSubjectsFunctions[listSubjIdx],
 			})
 			listSubjIdx++
+			if listCursorIdx < len(listSQL.ListObjectsCursorFunctions) {
+				result = append(result, NamedFunction{
+					Name: listObjectsCursorFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+					SQL:  listSQL.ListObjectsCursorFunctions[listCursorIdx],
+				})
+				listCursorIdx++
+			}
 		}
 	}
 
@@ -417,11 +830,26 @@ func CollectDispatcherFunctions(generatedSQL GeneratedSQL, listSQL ListGenerated
 	all := []NamedFunction{
 		{Name: "check_permission", SQL: generatedSQL.Dispatcher},
 		{Name: "check_permission_nw", SQL: generatedSQL.DispatcherNoWildcard},
+		{Name: StrictDispatcherFunctionName, SQL: generatedSQL.DispatcherStrict},
+		{Name: ContextDispatcherFunctionName, SQL: generatedSQL.ContextDispatcher},
+		{Name: ContextualTuplesFunctionName, SQL: generatedSQL.ContextualTuplesFunction},
 		{Name: "check_permission_bulk", SQL: generatedSQL.BulkDispatcher},
+		{Name: BatchDispatcherFunctionName, SQL: generatedSQL.BatchDispatcher},
+		{Name: "check_any", SQL: generatedSQL.CheckAnyDispatcher},
+		{Name: "check_all", SQL: generatedSQL.CheckAllDispatcher},
 		{Name: "explain_permission", SQL: generatedSQL.ExplainDispatcher},
 		{Name: "expand_permission", SQL: generatedSQL.ExpandDispatcher},
+		{Name: "effective_access", SQL: generatedSQL.EffectiveAccessFunction},
+		{Name: ModelRelationsFunctionName, SQL: generatedSQL.ModelRelationsFunction},
+		{Name: HealthcheckFunctionName, SQL: generatedSQL.HealthcheckFunction},
+		{Name: ClosureFunctionName, SQL: generatedSQL.ClosureFunction},
+		{Name: UsersetFunctionName, SQL: generatedSQL.UsersetFunction},
 		{Name: "list_accessible_objects", SQL: listSQL.ListObjectsDispatcher},
 		{Name: "list_accessible_subjects", SQL: listSQL.ListSubjectsDispatcher},
+		{Name: ListObjectsAnyFunctionName, SQL: listSQL.ListObjectsAnyDispatcher},
+		{Name: ListSubjectsMultiFunctionName, SQL: listSQL.ListSubjectsMultiDispatcher},
+		{Name: DepthExceededFunctionName, SQL: listSQL.DepthExceededFunction},
+		{Name: ListObjectsCursorDispatcherName, SQL: listSQL.ListObjectsCursorDispatcher},
 	}
 	result := make([]NamedFunction, 0, len(all))
 	for _, nf := range all {
@@ -432,6 +860,34 @@ func CollectDispatcherFunctions(generatedSQL GeneratedSQL, listSQL ListGenerated
 	return result
 }
 
+// CheckFunctionName returns the name of a's specialized check function,
+// check_{type}_{relation}, which check_permission routes to. It takes
+// (p_subject_type, p_subject_id, p_object_id) and exists only when
+// a.Capabilities.CheckAllowed is set.
+func CheckFunctionName(a RelationAnalysis) string {
+	return functionName(a.NameCollisions, a.ObjectType, a.Relation)
+}
+
+// RelationFunctionNames returns the name of every specialized function a
+// relation can be generated as, including opt-in kinds such as strict,
+// evidence and cursor functions. Which of them exist depends on the relation
+// and the generation options; use it to pick a relation's functions out of
+// CollectNamedFunctions.
+func RelationFunctionNames(a RelationAnalysis) []string {
+	return []string{
+		functionName(a.NameCollisions, a.ObjectType, a.Relation),
+		functionNameNoWildcard(a.NameCollisions, a.ObjectType, a.Relation),
+		contextFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+		functionNameStrict(a.NameCollisions, a.ObjectType, a.Relation),
+		expandFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+		explainFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+		evidenceFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+		listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+		listSubjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+		listObjectsCursorFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+	}
+}
+
 // CollectFunctionNames returns all function names that will be generated for the given analyses.
 // This is used for migration tracking and orphan detection to identify stale functions
 // that need to be dropped when the schema changes.
@@ -439,8 +895,9 @@ func CollectDispatcherFunctions(generatedSQL GeneratedSQL, listSQL ListGenerated
 // The returned list includes:
 //   - Specialized check functions: check_{type}_{relation}
 //   - No-wildcard check variants: check_{type}_{relation}_nw
+//   - Context check variants: check_{type}_{relation}_ctx
 //   - Specialized list functions: list_{type}_{relation}_obj, list_{type}_{relation}_sub
-//   - Dispatcher functions (always included): check_permission, list_accessible_objects, etc.
+//   - Dispatcher functions (always included): check_permission, check_any, list_accessible_objects, etc.
 func CollectFunctionNames(analyses []RelationAnalysis) []string {
 	var names []string
 	explainEligible := ComputeExplainEligibility(analyses)
@@ -449,21 +906,24 @@ func CollectFunctionNames(analyses []RelationAnalysis) []string {
 
 	for _, a := range analyses {
 		if a.Capabilities.CheckAllowed {
-			names = append(names, functionName(a.ObjectType, a.Relation))
+			names = append(names, functionName(a.NameCollisions, a.ObjectType, a.Relation))
 			if needsNW[a.ObjectType][a.Relation] {
-				names = append(names, functionNameNoWildcard(a.ObjectType, a.Relation))
+				names = append(names, functionNameNoWildcard(a.NameCollisions, a.ObjectType, a.Relation))
+			}
+			if len(a.Conditions) > 0 {
+				names = append(names, contextFunctionName(a.NameCollisions, a.ObjectType, a.Relation))
 			}
 			if expandEligible[a.ObjectType][a.Relation] {
-				names = append(names, expandFunctionName(a.ObjectType, a.Relation))
+				names = append(names, expandFunctionName(a.NameCollisions, a.ObjectType, a.Relation))
 			}
 			if explainEligible[a.ObjectType][a.Relation] {
-				names = append(names, explainFunctionName(a.ObjectType, a.Relation))
+				names = append(names, explainFunctionName(a.NameCollisions, a.ObjectType, a.Relation))
 			}
 		}
 		if a.Capabilities.ListAllowed {
 			names = append(names,
-				listObjectsFunctionName(a.ObjectType, a.Relation),
-				listSubjectsFunctionName(a.ObjectType, a.Relation),
+				listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+				listSubjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
 			)
 		}
 	}
@@ -474,13 +934,22 @@ func CollectFunctionNames(analyses []RelationAnalysis) []string {
 		"check_permission_internal",
 		"check_permission_nw",
 		"check_permission_nw_internal",
+		ContextDispatcherFunctionName,
 		"check_permission_bulk",
+		BatchDispatcherFunctionName,
+		"check_any",
+		"check_all",
 		"explain_permission",
 		"explain_permission_internal",
 		"expand_permission",
 		"expand_permission_internal",
 		"list_accessible_objects",
 		"list_accessible_subjects",
+		ListObjectsAnyFunctionName,
+		ListSubjectsMultiFunctionName,
+		ModelRelationsFunctionName,
+		DepthExceededFunctionName,
+		HealthcheckFunctionName,
 	)
 
 	return names
@@ -47,7 +47,7 @@ func TestComplexClosure_ComposesWhenComposable(t *testing.T) {
 
 	sql := complexClosureSQL(t, closurePlan(lookup))
 
-	if !strings.Contains(sql, "t.object_id IN (SELECT obj.object_id FROM list_doc_reader_obj(p_subject_type, p_subject_id, NULL, NULL) obj)") {
+	if !strings.Contains(sql, "t.object_id IN (SELECT obj.object_id FROM list_doc_reader_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) obj)") {
 		t.Errorf("expected set-oriented composition against list_doc_reader_obj, got:\n%s", sql)
 	}
 	// Userset-typed subjects keep a guarded per-candidate check for parity.
@@ -176,7 +176,7 @@ func TestComplexClosureSubjects_ComposesWhenComposable(t *testing.T) {
 
 	sql := complexClosureSubjectsSQL(t, closureSubjectsPlan(lookup, RelationFeatures{}))
 
-	if !strings.Contains(sql, "t.subject_id IN (SELECT sub.subject_id FROM list_doc_reader_sub(p_object_id, p_subject_type) sub)") {
+	if !strings.Contains(sql, "t.subject_id IN (SELECT sub.subject_id FROM list_doc_reader_sub(p_object_id => p_object_id, p_subject_type => p_subject_type) sub)") {
 		t.Errorf("expected set-oriented composition against list_doc_reader_sub, got:\n%s", sql)
 	}
 	if strings.Contains(sql, "check_permission_internal") {
@@ -271,7 +271,7 @@ func TestComplexClosure_RecursiveComposes(t *testing.T) {
 	block := buildRecursiveComplexClosureBlock(closurePlan(lookup), "reader")
 	sql := block.Query.SQL()
 
-	if !strings.Contains(sql, "list_doc_reader_obj(p_subject_type, p_subject_id, NULL, NULL)") {
+	if !strings.Contains(sql, "list_doc_reader_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id)") {
 		t.Errorf("expected recursive block to compose against list_doc_reader_obj, got:\n%s", sql)
 	}
 }
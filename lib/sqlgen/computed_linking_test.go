@@ -0,0 +1,66 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateSQL_ComputedLinkingRelation(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, `
+model
+  schema 1.1
+
+type user
+
+type folder
+  relations
+    define parent: [folder]
+    define shared_in: [folder]
+    define container: parent or shared_in
+    define viewer: [user] or viewer from container
+
+type document
+  relations
+    define folder: [folder]
+    define location: folder
+    define viewer: viewer from location
+`)
+
+	gen, err := GenerateSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatalf("GenerateSQL: %v", err)
+	}
+	list, err := GenerateListSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatalf("GenerateListSQL: %v", err)
+	}
+
+	find := func(fns []string, name string) string {
+		for _, fn := range fns {
+			if strings.Contains(fn, "FUNCTION "+name+"(") {
+				return fn
+			}
+		}
+		t.Fatalf("%s not generated", name)
+		return ""
+	}
+
+	for _, fn := range []string{
+		find(gen.Functions, functionName(nil, "folder", "viewer")),
+		find(list.ListObjectsFunctions, listObjectsFunctionName(nil, "folder", "viewer")),
+	} {
+		for _, rel := range []string{"'parent'", "'shared_in'"} {
+			if !strings.Contains(fn, rel) {
+				t.Errorf("folder.viewer does not scan linking relation %s:\n%s", rel, fn)
+			}
+		}
+		if strings.Contains(fn, "'container'") || strings.Contains(fn, "'''") {
+			t.Errorf("folder.viewer looks up tuples under a relation other than 'parent' and 'shared_in':\n%s", fn)
+		}
+	}
+
+	docViewer := find(gen.Functions, functionName(nil, "document", "viewer"))
+	if !strings.Contains(docViewer, "'folder'") || strings.Contains(docViewer, "'location'") {
+		t.Errorf("document.viewer should link through 'folder', not 'location':\n%s", docViewer)
+	}
+}
@@ -0,0 +1,208 @@
+package sqlgen
+
+import "strings"
+
+// ContextDispatcherFunctionName is the check entry point that evaluates
+// OpenFGA conditions against a JSONB request context. It is emitted with
+// every schema, so callers can use it whether or not the model has
+// conditions.
+const ContextDispatcherFunctionName = "check_permission_with_context"
+
+// contextFunctionName returns the name of the condition-evaluating variant of
+// a check function.
+func contextFunctionName(collisions NameCollisions, objectType, relation string) string {
+	return RelationIdentifier(collisions, "check_", objectType, relation, "_ctx")
+}
+
+// conditionalTupleGuard excludes the relation's conditional tuples from a
+// tuple lookup on alias, or returns nil when the relation has no conditions.
+// The direct lookup of a check function also matches the subject types of
+// its closure relations, which may include a type the relation itself grants
+// only under a condition; the condition is evaluated by the _ctx variant.
+func conditionalTupleGuard(a RelationAnalysis, alias string) Expr {
+	if len(a.Conditions) == 0 {
+		return nil
+	}
+	types := make([]string, len(a.Conditions))
+	for i, c := range a.Conditions {
+		types[i] = c.SubjectType
+	}
+	return Not(And(
+		Eq{Left: Col{Table: alias, Column: "relation"}, Right: Lit(a.Relation)},
+		In{Expr: Col{Table: alias, Column: "subject_type"}, Values: types},
+	))
+}
+
+// generateContextFunction renders check_{type}_{relation}_ctx, which allows
+// whatever check_{type}_{relation} allows, plus each conditional direct grant
+// whose condition holds against p_context. A parameter missing from the
+// context makes its comparison NULL, which denies; under || the other operand
+// can still allow, as CEL's || absorbs errors.
+func generateContextFunction(a RelationAnalysis, databaseSchema, tuplesTable string) string {
+	base := Func{
+		Schema: databaseSchema,
+		Name:   functionName(a.NameCollisions, a.ObjectType, a.Relation),
+		Args:   []Expr{SubjectType, SubjectID, ObjectID, Visited},
+	}
+	lines := []string{"SELECT CASE", "    WHEN " + base.SQL() + " = 1 THEN 1"}
+	for _, c := range a.Conditions {
+		q := TuplesWithTable(tuplesTable, databaseSchema, "t").
+			ObjectType(a.ObjectType).
+			Relations(a.Relation).
+			Where(
+				Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
+				Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(c.SubjectType)},
+				Eq{Left: Col{Table: "t", Column: "subject_id"}, Right: SubjectID},
+			).
+			Select("1")
+		grant := And(
+			Eq{Left: SubjectType, Right: Lit(c.SubjectType)},
+			conditionHolds(c),
+			Exists{Query: q},
+		)
+		lines = append(lines,
+			"    -- Condition "+c.Name+" on "+c.SubjectType,
+			"    WHEN "+grant.SQL()+" THEN 1")
+	}
+	lines = append(lines, "    ELSE 0", "END")
+
+	fn := SqlFunction{
+		Schema:  databaseSchema,
+		Name:    contextFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+		Args:    contextFunctionArgs(),
+		Returns: "INTEGER",
+		Body:    Raw(strings.Join(lines, "\n")),
+		Header: []string{
+			"Generated context check function for " + a.ObjectType + "." + a.Relation,
+			"Allows what " + functionName(a.NameCollisions, a.ObjectType, a.Relation) + " allows, plus conditional grants whose condition holds against p_context",
+		},
+	}
+	return fn.SQL() + "\n"
+}
+
+// contextFunctionArgs returns the signature of a check_{type}_{relation}_ctx
+// function.
+func contextFunctionArgs() []FuncArg {
+	return []FuncArg{
+		{Name: "p_subject_type", Type: "TEXT"},
+		{Name: "p_subject_id", Type: "TEXT"},
+		{Name: "p_object_id", Type: "TEXT"},
+		{Name: "p_context", Type: "JSONB"},
+		{Name: "p_visited", Type: "TEXT []", Default: EmptyArray{}},
+	}
+}
+
+// generateContextDispatcher renders check_permission_with_context, which
+// routes relations with conditions to their _ctx function and every other
+// relation to check_permission, ignoring the context.
+func generateContextDispatcher(analyses []RelationAnalysis, databaseSchema string) string {
+	fallback := Func{
+		Schema: databaseSchema,
+		Name:   "check_permission",
+		Args:   []Expr{SubjectType, SubjectID, Raw("p_relation"), Raw("p_object_type"), ObjectID},
+	}
+
+	var cases []string
+	for _, a := range analyses {
+		if !a.Capabilities.CheckAllowed || len(a.Conditions) == 0 {
+			continue
+		}
+		call := Func{
+			Schema: databaseSchema,
+			Name:   contextFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+			Args:   []Expr{SubjectType, SubjectID, ObjectID, Raw("p_context")},
+		}
+		cases = append(cases, "WHEN p_object_type = "+Lit(a.ObjectType).SQL()+" AND p_relation = "+Lit(a.Relation).SQL()+" THEN "+call.SQL())
+	}
+
+	body := "SELECT " + fallback.SQL()
+	if len(cases) > 0 {
+		body = "SELECT CASE\n    " + strings.Join(cases, "\n    ") + "\n    ELSE " + fallback.SQL() + "\nEND"
+	}
+
+	fn := SqlFunction{
+		Schema: databaseSchema,
+		Name:   ContextDispatcherFunctionName,
+		Args: []FuncArg{
+			{Name: "p_subject_type", Type: "TEXT"},
+			{Name: "p_subject_id", Type: "TEXT"},
+			{Name: "p_relation", Type: "TEXT"},
+			{Name: "p_object_type", Type: "TEXT"},
+			{Name: "p_object_id", Type: "TEXT"},
+			{Name: "p_context", Type: "JSONB"},
+		},
+		Returns: "INTEGER",
+		Body:    Raw(body),
+		Header: []string{
+			"Generated dispatcher for " + ContextDispatcherFunctionName,
+			"Evaluates conditional grants against p_context; other relations route to check_permission",
+		},
+		// Calls only schema-qualified functions.
+		NoSearchPath: true,
+	}
+	return fn.SQL() + "\n"
+}
+
+// conditionHolds renders c's expression as a predicate over p_context that is
+// FALSE rather than NULL when a parameter is missing. The _ctx check functions
+// and the list functions share it, so both allow the same conditional grants.
+func conditionHolds(c ConditionInfo) Expr {
+	return Raw("COALESCE(" + renderConditionExpr(c.Expr) + ", FALSE)")
+}
+
+// renderConditionExpr renders a parsed condition as a SQL boolean expression
+// over p_context. Parameters are read with ->> and cast to their type.
+func renderConditionExpr(e ConditionExpr) string {
+	switch e.Op {
+	case "string":
+		return Lit(e.Value).SQL()
+	case "number":
+		return e.Value
+	case "bool":
+		return strings.ToUpper(e.Value)
+	case "timestamp":
+		return Lit(e.Value).SQL() + "::TIMESTAMPTZ"
+	case "param":
+		return renderConditionParam(e)
+	case "!":
+		return "(NOT " + renderConditionExpr(e.Args[0]) + ")"
+	case "&&", "||":
+		op := " AND "
+		if e.Op == "||" {
+			op = " OR "
+		}
+		return "(" + renderConditionExpr(e.Args[0]) + op + renderConditionExpr(e.Args[1]) + ")"
+	case "in":
+		left, list := renderConditionExpr(e.Args[0]), e.Args[1]
+		if list.Op == "param" {
+			field := "p_context->" + Lit(list.Value).SQL()
+			return "(jsonb_typeof(" + field + ") = 'array' AND (" + field + ") ? " + left + ")"
+		}
+		values := make([]string, len(list.Args))
+		for i, v := range list.Args {
+			values[i] = v.Value
+		}
+		return "(" + In{Expr: Raw(left), Values: values}.SQL() + ")"
+	}
+	op := e.Op
+	switch op {
+	case "==":
+		op = "="
+	case "!=":
+		op = "<>"
+	}
+	return "(" + renderConditionExpr(e.Args[0]) + " " + op + " " + renderConditionExpr(e.Args[1]) + ")"
+}
+
+func renderConditionParam(e ConditionExpr) string {
+	value := "(p_context->>" + Lit(e.Value).SQL() + ")"
+	switch e.Type {
+	case "number":
+		return value + "::NUMERIC"
+	case "bool":
+		return value + "::BOOLEAN"
+	case "timestamp":
+		return value + "::TIMESTAMPTZ"
+	}
+	return value
+}
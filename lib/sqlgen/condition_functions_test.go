@@ -0,0 +1,91 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+// conditionAnalysis is doc.viewer: [user with in_region, group#member] or owner.
+func conditionAnalysis(t *testing.T) RelationAnalysis {
+	t.Helper()
+	expr, err := ParseCondition(`region in allowed || region == "eu" && level >= 2`, map[string]string{
+		"region":  "string",
+		"allowed": "list<string>",
+		"level":   "int",
+	})
+	if err != nil {
+		t.Fatalf("ParseCondition: %v", err)
+	}
+	return RelationAnalysis{
+		ObjectType:          "doc",
+		Relation:            "viewer",
+		Capabilities:        GenerationCapabilities{CheckAllowed: true},
+		Features:            RelationFeatures{HasDirect: true, HasImplied: true, HasCondition: true},
+		SatisfyingRelations: []string{"viewer", "owner"},
+		DirectSubjectTypes:  []string{"user"},
+		Conditions:          []ConditionInfo{{SubjectType: "user", Name: "in_region", Expr: expr}},
+	}
+}
+
+func TestContextFunction_EvaluatesCondition(t *testing.T) {
+	sql := generateContextFunction(conditionAnalysis(t), "", "")
+
+	assertContains(t, sql, "CREATE OR REPLACE FUNCTION check_doc_viewer_ctx(")
+	assertContains(t, sql, "p_context JSONB")
+	assertContains(t, sql, "WHEN check_doc_viewer(p_subject_type, p_subject_id, p_object_id, p_visited) = 1 THEN 1")
+	assertContains(t, sql, "-- Condition in_region on user")
+	assertContains(t, sql, "p_subject_type = 'user' AND COALESCE(")
+	assertContains(t, sql, "(jsonb_typeof(p_context->'allowed') = 'array' AND (p_context->'allowed') ? (p_context->>'region'))")
+	assertContains(t, sql, "((p_context->>'region') = 'eu')")
+	assertContains(t, sql, "((p_context->>'level')::NUMERIC >= 2)")
+	assertContains(t, sql, "t.relation IN ('viewer')")
+	assertContains(t, sql, "t.subject_type = 'user'")
+	assertContains(t, sql, "ELSE 0")
+}
+
+func TestContextDispatcher(t *testing.T) {
+	plain := RelationAnalysis{ObjectType: "doc", Relation: "owner", Capabilities: GenerationCapabilities{CheckAllowed: true}}
+
+	t.Run("routes conditioned relations", func(t *testing.T) {
+		sql := generateContextDispatcher([]RelationAnalysis{plain, conditionAnalysis(t)}, "")
+		assertContains(t, sql, "CREATE OR REPLACE FUNCTION check_permission_with_context(")
+		assertContains(t, sql, "WHEN p_object_type = 'doc' AND p_relation = 'viewer' THEN check_doc_viewer_ctx(p_subject_type, p_subject_id, p_object_id, p_context)")
+		assertContains(t, sql, "ELSE check_permission(p_subject_type, p_subject_id, p_relation, p_object_type, p_object_id)")
+		assertNotContains(t, sql, "'owner'")
+	})
+
+	t.Run("without conditions", func(t *testing.T) {
+		sql := generateContextDispatcher([]RelationAnalysis{plain}, "")
+		assertContains(t, sql, "SELECT check_permission(p_subject_type, p_subject_id, p_relation, p_object_type, p_object_id)")
+		assertNotContains(t, sql, "CASE")
+	})
+
+	t.Run("schema qualified", func(t *testing.T) {
+		sql := generateContextDispatcher([]RelationAnalysis{conditionAnalysis(t)}, "authz")
+		assertContains(t, sql, `"authz"."check_doc_viewer_ctx"(`)
+		assertContains(t, sql, `"authz"."check_permission"(`)
+		assertNotContains(t, sql, "SET search_path")
+	})
+}
+
+// The plain check function must not read conditional tuples: its direct
+// lookup also matches user tuples of the closure relation owner.
+func TestCheckFunction_ExcludesConditionalTuples(t *testing.T) {
+	gen, err := GenerateSQL([]RelationAnalysis{conditionAnalysis(t)}, InlineSQLData{}, "")
+	if err != nil {
+		t.Fatalf("GenerateSQL: %v", err)
+	}
+	check := strings.Join(gen.Functions, "\n")
+	assertContains(t, check, "NOT ((relation = 'viewer' AND subject_type IN ('user')))")
+
+	if len(gen.ContextFunctions) != 1 || !strings.Contains(gen.ContextFunctions[0], "check_doc_viewer_ctx(") {
+		t.Errorf("ContextFunctions = %v, want check_doc_viewer_ctx", gen.ContextFunctions)
+	}
+	names := make(map[string]bool)
+	for _, name := range CollectFunctionNames([]RelationAnalysis{conditionAnalysis(t)}) {
+		names[name] = true
+	}
+	if !names["check_doc_viewer_ctx"] || !names[ContextDispatcherFunctionName] {
+		t.Errorf("CollectFunctionNames missing context functions: %v", names)
+	}
+}
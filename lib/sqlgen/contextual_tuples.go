@@ -0,0 +1,119 @@
+package sqlgen
+
+import "strings"
+
+// ContextualTuplesFunctionName is the check entry point that takes
+// contextual tuples as a JSONB argument. It is emitted with every schema
+// whose tuples relation is unqualified; see generateContextualTuplesFunction.
+const ContextualTuplesFunctionName = "check_permission_with_contextual_tuples"
+
+// contextualTupleColumns are the keys each element of p_contextual_tuples
+// carries, in melange_tuples column order.
+var contextualTupleColumns = []string{"subject_type", "subject_id", "relation", "object_type", "object_id"}
+
+// generateContextualTuplesFunction renders
+// check_permission_with_contextual_tuples, the SQL counterpart of
+// Checker.CheckWithContextualTuples. It answers check_permission as if the
+// tuples in p_contextual_tuples, a JSON array of objects keyed like the
+// melange_tuples columns, were stored.
+//
+// The function uses the same mechanism as the Go runtime: it creates a temp
+// view named like the tuples relation that UNIONs the base relation with the
+// contextual rows, calls check_permission, and drops the view. Check
+// functions read the tuples relation unqualified, so pg_temp shadows it on
+// every direct, userset and tuple-to-userset path without a separate code
+// path per pattern. The view is created and dropped inside the call, and an
+// error rolls it back with the rest of the statement.
+//
+// Returns "" when tuplesTable is schema-qualified, since check functions then
+// name it in full and a temp view cannot shadow it.
+func generateContextualTuplesFunction(databaseSchema, tuplesTable string) string {
+	if strings.Contains(tuplesTable, ".") {
+		return ""
+	}
+
+	typedColumns := make([]string, len(contextualTupleColumns))
+	var missing []Expr
+	for i, c := range contextualTupleColumns {
+		typedColumns[i] = c + " TEXT"
+		missing = append(missing, IsNull{Expr: Col{Table: "ctx", Column: c}})
+	}
+	columns := strings.Join(contextualTupleColumns, ", ")
+	// jsonb_to_recordset needs a column definition list after its alias.
+	recordAlias := "ctx(" + strings.Join(typedColumns, ", ") + ")"
+
+	check := Func{
+		Schema: databaseSchema,
+		Name:   "check_permission",
+		Args:   []Expr{SubjectType, SubjectID, Raw("p_relation"), ObjectType, ObjectID},
+	}
+	incomplete := SelectStmt{
+		ColumnExprs: []Expr{Int(1)},
+		FromExpr: FunctionCallExpr{
+			Name:  "jsonb_to_recordset",
+			Args:  []Expr{Raw("p_contextual_tuples")},
+			Alias: recordAlias,
+		},
+		Where: Or(missing...),
+	}
+	base := SelectStmt{
+		ColumnExprs: []Expr{Raw("format('%I.%I', n.nspname, c.relname)")},
+		FromExpr:    TableAs("pg_catalog", "pg_class", "c"),
+		Joins: []JoinClause{{
+			Type:      "INNER",
+			TableExpr: TableAs("pg_catalog", "pg_namespace", "n"),
+			On:        Eq{Left: Col{Table: "n", Column: "oid"}, Right: Col{Table: "c", Column: "relnamespace"}},
+		}},
+		Where: And(
+			Eq{Left: Col{Table: "c", Column: "oid"}, Right: Func{Name: "to_regclass", Args: []Expr{Lit(tuplesTable)}}},
+			Ne{Left: Col{Table: "n", Column: "oid"}, Right: Func{Schema: "pg_catalog", Name: "pg_my_temp_schema"}},
+		),
+	}
+	shadow := "CREATE TEMP VIEW " + tuplesTable + " AS SELECT " + columns + " FROM %s UNION ALL SELECT " +
+		columns + " FROM jsonb_to_recordset(%L::JSONB) AS " + recordAlias
+
+	fn := PlpgsqlFunction{
+		Schema: databaseSchema,
+		Name:   ContextualTuplesFunctionName,
+		Args: []FuncArg{
+			{Name: "p_subject_type", Type: "TEXT"},
+			{Name: "p_subject_id", Type: "TEXT"},
+			{Name: "p_relation", Type: "TEXT"},
+			{Name: "p_object_type", Type: "TEXT"},
+			{Name: "p_object_id", Type: "TEXT"},
+			{Name: "p_contextual_tuples", Type: "JSONB"},
+		},
+		Returns: "INTEGER",
+		Decls: []Decl{
+			{Name: "v_base", Type: "TEXT"},
+			{Name: "v_result", Type: "INTEGER"},
+		},
+		Body: []Stmt{
+			If{
+				Cond: Raw("p_contextual_tuples IS NULL OR jsonb_array_length(p_contextual_tuples) = 0"),
+				Then: []Stmt{ReturnValue{Value: check}},
+			},
+			If{
+				Cond: Exists{Query: incomplete},
+				Then: []Stmt{Raise{Message: "contextual tuples need " + strings.Join(contextualTupleColumns, ", "), ErrCode: ErrCodeInvalidArgument}},
+			},
+			Comment{Text: "The relation check functions read, unless a temp view already shadows it"},
+			SelectInto{Query: base, Variable: "v_base"},
+			If{
+				Cond: Raw("v_base IS NULL"),
+				Then: []Stmt{Raise{Message: "tuples relation " + tuplesTable + " not found or already shadowed", ErrCode: ErrCodeNotInPrerequisiteState}},
+			},
+			RawStmt{SQLText: "EXECUTE format(" + Lit(shadow).SQL() + ", v_base, p_contextual_tuples);"},
+			Assign{Name: "v_result", Value: check},
+			RawStmt{SQLText: "DROP VIEW pg_temp." + tuplesTable + ";"},
+			ReturnValue{Value: Raw("v_result")},
+		},
+		Header: []string{
+			"Generated check with contextual tuples: " + ContextualTuplesFunctionName,
+			"Shadows " + tuplesTable + " with a temp view over the base relation and p_contextual_tuples for one check_permission call",
+		},
+		// Creates a temp view.
+		Volatile: true,
+	}
+	return fn.SQL() + "\n"
+}
@@ -0,0 +1,43 @@
+package sqlgen
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGenerateSQL_ContextualTuplesFunction(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, maxDepthTestSchema)
+
+	gen, err := GenerateSQLWithOptions(analyses, inline, "authz", GenerateSQLOptions{TuplesTable: "tuples"})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	fn := gen.ContextualTuplesFunction
+	assertContains(t, fn, `FUNCTION "authz"."check_permission_with_contextual_tuples"(`)
+	assertContains(t, fn, "p_contextual_tuples JSONB\n) RETURNS INTEGER")
+	assertContains(t, fn, "LANGUAGE plpgsql VOLATILE PARALLEL UNSAFE")
+	// The view shadows the configured relation and unions in the JSONB rows.
+	assertContains(t, fn, "to_regclass('tuples')")
+	assertContains(t, fn, "CREATE TEMP VIEW tuples AS SELECT subject_type, subject_id, relation, object_type, object_id FROM %s UNION ALL")
+	assertContains(t, fn, "jsonb_to_recordset(%L::JSONB)")
+	assertContains(t, fn, `v_result := "authz"."check_permission"(p_subject_type, p_subject_id, p_relation, p_object_type, p_object_id);`)
+	assertContains(t, fn, "DROP VIEW pg_temp.tuples;")
+	assertContains(t, fn, "USING ERRCODE = '22023'")
+
+	var names []string
+	for _, nf := range CollectDispatcherFunctions(gen, ListGeneratedSQL{}) {
+		names = append(names, nf.Name)
+	}
+	if !slices.Contains(names, ContextualTuplesFunctionName) {
+		t.Errorf("CollectDispatcherFunctions = %v, want %s", names, ContextualTuplesFunctionName)
+	}
+
+	// A schema-qualified tuples relation cannot be shadowed through pg_temp.
+	qualified, err := GenerateSQLWithOptions(analyses, inline, "authz", GenerateSQLOptions{TuplesTable: "tenant_42.tuples"})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	if qualified.ContextualTuplesFunction != "" {
+		t.Errorf("schema-qualified tuples table should omit the function:\n%s", qualified.ContextualTuplesFunction)
+	}
+}
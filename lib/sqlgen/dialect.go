@@ -0,0 +1,96 @@
+package sqlgen
+
+import "fmt"
+
+// ValidateDialect reports whether opts can be rendered for opts.Dialect.
+// CockroachDB has no refcursor type and no custom session settings, so
+// EnableListObjectsCursor and EnableCheckMemo are rejected for it, as is
+// TableRoutedDispatcher, whose dispatcher runs dynamic SQL with EXECUTE.
+func ValidateDialect(opts GenerateSQLOptions) error {
+	if !opts.Dialect.Valid() {
+		return fmt.Errorf("unknown dialect %q (want one of %v)", opts.Dialect, Dialects)
+	}
+	if !opts.Dialect.IsCockroach() {
+		return nil
+	}
+	switch {
+	case opts.EnableListObjectsCursor:
+		return fmt.Errorf("dialect %s does not support EnableListObjectsCursor", opts.Dialect)
+	case opts.EnableCheckMemo:
+		return fmt.Errorf("dialect %s does not support EnableCheckMemo", opts.Dialect)
+	case opts.TableRoutedDispatcher:
+		return fmt.Errorf("dialect %s does not support TableRoutedDispatcher", opts.Dialect)
+	}
+	return nil
+}
+
+// poolerSafe reports whether generated bodies must avoid session settings:
+// when opts.PoolerSafe is set, or when the dialect does not support custom
+// ones.
+func (opts GenerateSQLOptions) poolerSafe() bool {
+	return opts.PoolerSafe || opts.Dialect.IsCockroach()
+}
+
+// adaptGeneratedSQL rewrites every statement in result for d.
+func adaptGeneratedSQL(result *GeneratedSQL, d Dialect) {
+	if !d.IsCockroach() {
+		return
+	}
+	for _, fns := range []*[]string{
+		&result.Functions,
+		&result.NoWildcardFunctions,
+		&result.ContextFunctions,
+		&result.StrictFunctions,
+		&result.ExplainFunctions,
+		&result.ExpandFunctions,
+		&result.EvidenceFunctions,
+	} {
+		adaptAll(*fns, d)
+	}
+	for _, fn := range []*string{
+		&result.Dispatcher,
+		&result.DispatcherNoWildcard,
+		&result.DispatcherStrict,
+		&result.ContextDispatcher,
+		&result.ContextualTuplesFunction,
+		&result.BulkDispatcher,
+		&result.BatchDispatcher,
+		&result.CheckAnyDispatcher,
+		&result.CheckAllDispatcher,
+		&result.ExplainDispatcher,
+		&result.ExpandDispatcher,
+		&result.EffectiveAccessFunction,
+		&result.ModelRelationsFunction,
+		&result.ClosureFunction,
+		&result.UsersetFunction,
+	} {
+		*fn = d.Adapt(*fn)
+	}
+}
+
+// adaptListGeneratedSQL rewrites every statement in result for d.
+func adaptListGeneratedSQL(result *ListGeneratedSQL, d Dialect) {
+	if !d.IsCockroach() {
+		return
+	}
+	adaptAll(result.ListObjectsFunctions, d)
+	adaptAll(result.ListSubjectsFunctions, d)
+	adaptAll(result.ListObjectsCursorFunctions, d)
+	for _, fn := range []*string{
+		&result.ListObjectsDispatcher,
+		&result.ListSubjectsDispatcher,
+		&result.ListObjectsAnyDispatcher,
+		&result.ListSubjectsMultiDispatcher,
+		&result.DepthExceededFunction,
+		&result.ListObjectsCursorDispatcher,
+	} {
+		*fn = d.Adapt(*fn)
+	}
+}
+
+// adaptAll rewrites each statement in fns for d in place.
+func adaptAll(fns []string, d Dialect) {
+	for i, fn := range fns {
+		fns[i] = d.Adapt(fn)
+	}
+}
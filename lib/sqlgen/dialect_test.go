@@ -0,0 +1,89 @@
+package sqlgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// allGeneratedSQL joins every statement of gen and list.
+func allGeneratedSQL(gen GeneratedSQL, list ListGeneratedSQL) string {
+	var parts []string
+	for _, v := range []reflect.Value{reflect.ValueOf(gen), reflect.ValueOf(list)} {
+		for i := range v.NumField() {
+			switch f := v.Field(i).Interface().(type) {
+			case string:
+				parts = append(parts, f)
+			case []string:
+				parts = append(parts, f...)
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func TestGenerateSQL_CockroachDialect(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, maxDepthTestSchema)
+	generate := func(opts GenerateSQLOptions) string {
+		t.Helper()
+		gen, err := GenerateSQLWithOptions(analyses, inline, "authz", opts)
+		if err != nil {
+			t.Fatalf("GenerateSQLWithOptions: %v", err)
+		}
+		list, err := GenerateListSQLWithOptions(analyses, inline, "authz", opts)
+		if err != nil {
+			t.Fatalf("GenerateListSQLWithOptions: %v", err)
+		}
+		return allGeneratedSQL(gen, list)
+	}
+
+	features := GenerateSQLOptions{
+		EnableEffectiveAccess: true,
+		EnableCheckEvidence:   true,
+		EnableStrictCheck:     true,
+		ClosureFunction:       true,
+	}
+	postgres := generate(features)
+	explicit := features
+	explicit.Dialect = DialectPostgres
+	if generate(explicit) != postgres {
+		t.Error("Dialect postgres should render like the default")
+	}
+	assertContains(t, postgres, "PARALLEL SAFE")
+	assertContains(t, postgres, "SET search_path = ")
+	assertContains(t, postgres, "COMMENT ON FUNCTION ")
+
+	cockroach := features
+	cockroach.Dialect = DialectCockroach
+	out := generate(cockroach)
+	for _, unsupported := range []string{"PARALLEL ", " ROWS ", " COST ", "SET search_path", "COMMENT ON FUNCTION", "melange.max_explain_nodes"} {
+		assertNotContains(t, out, unsupported)
+	}
+	assertContains(t, out, "$$ LANGUAGE plpgsql STABLE;")
+	// Every function but check_permission_with_contextual_tuples, which
+	// shadows the tuples relation with a temp view.
+	assertNotContains(t, out, ContextualTuplesFunctionName)
+	if strings.Count(out, "AS $$") != strings.Count(postgres, "AS $$")-1 {
+		t.Error("cockroach dialect should keep every function body quoted")
+	}
+	if strings.Count(out, "CREATE OR REPLACE FUNCTION") != strings.Count(postgres, "CREATE OR REPLACE FUNCTION")-1 {
+		t.Error("cockroach dialect should emit the same functions")
+	}
+}
+
+func TestGenerateSQL_CockroachDialectRejects(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, maxDepthTestSchema)
+	for name, opts := range map[string]GenerateSQLOptions{
+		"unknown dialect": {Dialect: "mysql"},
+		"check memo":      {Dialect: DialectCockroach, EnableCheckMemo: true},
+		"cursor":          {Dialect: DialectCockroach, EnableListObjectsCursor: true},
+		"table routed":    {Dialect: DialectCockroach, TableRoutedDispatcher: true},
+	} {
+		if _, err := GenerateSQLWithOptions(analyses, inline, "", opts); err == nil {
+			t.Errorf("%s: GenerateSQLWithOptions should fail", name)
+		}
+		if _, err := GenerateListSQLWithOptions(analyses, inline, "", opts); err == nil {
+			t.Errorf("%s: GenerateListSQLWithOptions should fail", name)
+		}
+	}
+}
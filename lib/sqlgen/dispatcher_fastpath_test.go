@@ -27,7 +27,7 @@ func TestBulkDispatcher_UnknownTypeFallbackDenominatedByTypeOnly(t *testing.T) {
 		analyses[i].DirectSubjectTypes = []string{"user"}
 	}
 
-	sql := generateBulkDispatcher(analyses, "")
+	sql := generateBulkDispatcher(analyses, "", "")
 
 	// Final fallback keys on object_type alone over the distinct known types.
 	if !strings.Contains(sql, "t.object_type NOT IN ('document', 'folder')") {
@@ -50,7 +50,7 @@ func TestListDispatcher_PerTypeSentinel(t *testing.T) {
 		mkAnalysis("folder", "viewer", RelationFeatures{HasDirect: true}, true),
 	}
 
-	sql, err := generateListObjectsDispatcher(analyses, "")
+	sql, err := generateListObjectsDispatcher(analyses, "", true)
 	if err != nil {
 		t.Fatalf("generateListObjectsDispatcher: %v", err)
 	}
@@ -91,11 +91,11 @@ func TestDispatchers_SimpleExpressionFastPath(t *testing.T) {
 		analyses[i].DirectSubjectTypes = []string{"user"}
 	}
 
-	checkSQL, err := generateDispatcher(analyses, "", false, nil)
+	checkSQL, err := generateDispatcher(analyses, "", false, nil, DefaultMaxDepth, true)
 	if err != nil {
 		t.Fatalf("generateDispatcher: %v", err)
 	}
-	explainSQL, err := generateExplainDispatcher(analyses, "", ComputeExplainEligibility(analyses))
+	explainSQL, err := generateExplainDispatcher(analyses, "", ComputeExplainEligibility(analyses), DefaultMaxDepth)
 	if err != nil {
 		t.Fatalf("generateExplainDispatcher: %v", err)
 	}
@@ -117,3 +117,73 @@ func TestDispatchers_SimpleExpressionFastPath(t *testing.T) {
 		}
 	}
 }
+
+// Both list dispatchers route every listable relation to its list function and
+// raise M2003 for a checkable relation without one, rather than returning an
+// empty result that reads as "no access". Relations with no access paths keep
+// the empty fallback, matching check_permission's deny.
+func TestListDispatchers_RaiseForNonListableRelations(t *testing.T) {
+	analyses := []RelationAnalysis{
+		mkAnalysis("document", "viewer", RelationFeatures{HasDirect: true}, true),
+		mkAnalysis("document", "auditor", RelationFeatures{HasDirect: true, HasUserset: true}, false),
+		mkAnalysis("folder", "owner", RelationFeatures{HasDirect: true}, true),
+		{ObjectType: "folder", Relation: "ghost"},
+	}
+
+	objects, err := generateListObjectsDispatcher(analyses, "", true)
+	if err != nil {
+		t.Fatalf("generateListObjectsDispatcher: %v", err)
+	}
+	subjects, err := generateListSubjectsDispatcher(analyses, "", true)
+	if err != nil {
+		t.Fatalf("generateListSubjectsDispatcher: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		sql    string
+		suffix string
+	}{
+		"list_accessible_objects":  {objects, "_obj"},
+		"list_accessible_subjects": {subjects, "_sub"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assertContains(t, tc.sql, "list_document_viewer"+tc.suffix+"(")
+			assertContains(t, tc.sql, "list_folder_owner"+tc.suffix+"(")
+			assertContains(t, tc.sql, "RAISE EXCEPTION 'relation not listable: document.auditor' USING ERRCODE = 'M2003';")
+			assertNotContains(t, tc.sql, "list_document_auditor")
+			assertNotContains(t, tc.sql, "ghost")
+
+			raise := strings.Index(tc.sql, "relation not listable: document.auditor")
+			guard := strings.LastIndex(tc.sql[:raise], "IF p_relation = ")
+			if guard < 0 || !strings.HasPrefix(tc.sql[guard:], "IF p_relation = 'auditor' THEN") {
+				t.Errorf("M2003 must be raised under the auditor relation guard:\n%s", tc.sql)
+			}
+		})
+	}
+}
+
+// The expand dispatcher raises M2004 for a checkable relation the renderer
+// produced no expand function for, instead of returning the empty tree that
+// reads as "no one has access". Relations with no access paths keep the
+// sentinel, matching check_permission's deny.
+func TestExpandDispatcher_RaisesForNonExpandableRelations(t *testing.T) {
+	viewer := mkAnalysis("document", "viewer", RelationFeatures{HasDirect: true}, true)
+	viewer.DirectSubjectTypes = []string{"user"}
+	analyses := []RelationAnalysis{
+		viewer,
+		// HasDirect without subject types leaves BuildExpandPlan no rewrite.
+		mkAnalysis("document", "auditor", RelationFeatures{HasDirect: true}, true),
+		{ObjectType: "folder", Relation: "ghost"},
+	}
+
+	sql := generateExpandDispatcher(analyses, "", ComputeExpandEligibility(analyses))
+	assertContains(t, sql, "expand_document_viewer(")
+	assertContains(t, sql, "IF (p_object_type = 'document' AND p_relation = 'auditor') THEN")
+	assertContains(t, sql, "RAISE EXCEPTION 'relation not expandable: document.auditor' USING ERRCODE = 'M2004';")
+	assertNotContains(t, sql, "expand_document_auditor")
+	assertNotContains(t, sql, "ghost")
+
+	// With nothing expandable the guard alone still needs the PL/pgSQL body.
+	sql = generateExpandDispatcher(analyses[1:], "", ComputeExpandEligibility(analyses[1:]))
+	assertContains(t, sql, "relation not expandable: document.auditor")
+}
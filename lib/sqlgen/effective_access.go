@@ -0,0 +1,236 @@
+package sqlgen
+
+import (
+	"slices"
+	"strings"
+)
+
+// effectiveAccessFunctionName is the audit function emitted when
+// GenerateSQLOptions.EnableEffectiveAccess is set.
+const effectiveAccessFunctionName = "effective_access"
+
+// hierarchyEdge is one TTU link in the object hierarchy: a childType object
+// points at a parentType object through linkingRelation
+// (e.g. repository#org@organization:acme).
+type hierarchyEdge struct {
+	ParentType      string
+	ChildType       string
+	LinkingRelation string
+}
+
+// collectHierarchyEdges returns the distinct TTU edges of the model, sorted so
+// the generated SQL is deterministic. Only top-level TTU rules (ParentRelations)
+// contribute; TTUs nested inside intersections or exclusions do not grant on
+// their own and are out of scope for the hierarchy walk.
+func collectHierarchyEdges(analyses []RelationAnalysis) []hierarchyEdge {
+	seen := make(map[hierarchyEdge]bool)
+	var edges []hierarchyEdge
+	for _, a := range analyses {
+		for _, pr := range a.ParentRelations {
+			for _, parentType := range pr.AllowedLinkingTypes {
+				e := hierarchyEdge{ParentType: parentType, ChildType: a.ObjectType, LinkingRelation: pr.LinkingRelation}
+				if !seen[e] {
+					seen[e] = true
+					edges = append(edges, e)
+				}
+			}
+		}
+	}
+	slices.SortFunc(edges, func(x, y hierarchyEdge) int {
+		return strings.Compare(x.ParentType+"\x00"+x.ChildType+"\x00"+x.LinkingRelation,
+			y.ParentType+"\x00"+y.ChildType+"\x00"+y.LinkingRelation)
+	})
+	return edges
+}
+
+// reachableTypes returns rootType followed by every type reachable from it by
+// walking edges parent → child, in breadth-first order.
+func reachableTypes(rootType string, edges []hierarchyEdge) []string {
+	order := []string{rootType}
+	seen := map[string]bool{rootType: true}
+	for i := 0; i < len(order); i++ {
+		for _, e := range edges {
+			if e.ParentType == order[i] && !seen[e.ChildType] {
+				seen[e.ChildType] = true
+				order = append(order, e.ChildType)
+			}
+		}
+	}
+	return order
+}
+
+// generateEffectiveAccessFunction renders effective_access, which reports every
+// (object_type, object_id, relation) the subject holds on the root object and
+// on each object reachable from it through TTU links.
+//
+// The function walks the hierarchy once into arrays, raising M2002 if the walk
+// reaches the depth limit, then branches on p_root_type and unions one
+// list_{type}_{relation}_obj call per relation on each type reachable from
+// that root, semi-joined to the walked objects of that type. Relations without
+// a list function (ListAllowed false) are not reported.
+//
+// maxDepth bounds the walk. It is the depth limit check_permission_internal
+// enforces, so a hierarchy deep enough to hit it raises the same M2002 error
+// instead of returning a truncated answer.
+func generateEffectiveAccessFunction(analyses []RelationAnalysis, databaseSchema, tuplesTable string, maxDepth int) string {
+	edges := collectHierarchyEdges(analyses)
+	collisions := nameCollisions(analyses)
+
+	relationsByType := make(map[string][]string)
+	var rootTypes []string
+	for _, a := range analyses {
+		if !slices.Contains(rootTypes, a.ObjectType) {
+			rootTypes = append(rootTypes, a.ObjectType)
+		}
+		if a.Capabilities.ListAllowed {
+			relationsByType[a.ObjectType] = append(relationsByType[a.ObjectType], a.Relation)
+		}
+	}
+
+	body := []Stmt{
+		Comment{Text: "Walk the TTU hierarchy from the root once, bounded by the depth limit"},
+		RawStmt{SQLText: SelectIntoVar{
+			Query:    effectiveAccessHierarchyQuery(edges, tuplesTable, maxDepth),
+			Variable: "v_node_types, v_node_ids, v_depth",
+		}.SQL() + ";"},
+		If{
+			Cond: Gte{Left: Param("v_depth"), Right: Int(maxDepth)},
+			Then: []Stmt{Raise{Message: "resolution too complex", ErrCode: ErrCodeResolutionTooComplex}},
+		},
+	}
+
+	for _, rootType := range rootTypes {
+		var queries []SQLer
+		for _, objectType := range reachableTypes(rootType, edges) {
+			for _, rel := range relationsByType[objectType] {
+				queries = append(queries, effectiveAccessRelationQuery(databaseSchema, collisions, objectType, rel))
+			}
+		}
+		if len(queries) == 0 {
+			continue
+		}
+		body = append(body, If{
+			Cond: Eq{Left: Param("p_root_type"), Right: Lit(rootType)},
+			Then: []Stmt{
+				ReturnQuery{Query: UnionAll{Queries: queries}.SQL()},
+				Return{},
+			},
+		})
+	}
+	body = append(body,
+		Comment{Text: "Unknown root type - return empty result"},
+		Return{},
+	)
+
+	fn := PlpgsqlFunction{
+		Schema: databaseSchema,
+		Name:   effectiveAccessFunctionName,
+		Args: []FuncArg{
+			{Name: "p_subject_type", Type: "TEXT"},
+			{Name: "p_subject_id", Type: "TEXT"},
+			{Name: "p_root_type", Type: "TEXT"},
+			{Name: "p_root_id", Type: "TEXT"},
+		},
+		Returns: "TABLE (object_type TEXT, object_id TEXT, relation TEXT)",
+		Decls: []Decl{
+			{Name: "v_node_types", Type: "TEXT[]"},
+			{Name: "v_node_ids", Type: "TEXT[]"},
+			{Name: "v_depth", Type: "INT"},
+		},
+		Body: body,
+		Header: []string{
+			"Generated audit function " + effectiveAccessFunctionName,
+			"Reports every relation the subject holds on the root object and on each object reachable from it via TTU links",
+			"Expensive: composes one list function per reachable (type, relation). Intended for support and debugging",
+		},
+	}
+	return fn.SQL() + "\n"
+}
+
+// effectiveAccessHierarchyQuery collects the objects reachable from the root
+// into parallel type/id arrays plus the deepest level the walk reached.
+// UNION (not UNION ALL) keeps revisits at the same depth from multiplying; a
+// cycle is cut off by the depth bound and surfaces as M2002.
+func effectiveAccessHierarchyQuery(edges []hierarchyEdge, tuplesTable string, maxDepth int) SQLer {
+	edgeMatches := make([]Expr, 0, len(edges))
+	for _, e := range edges {
+		edgeMatches = append(edgeMatches, And(
+			Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(e.ChildType)},
+			Eq{Left: Col{Table: "t", Column: "relation"}, Right: Lit(e.LinkingRelation)},
+			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(e.ParentType)},
+		))
+	}
+
+	seed := SelectStmt{
+		ColumnExprs: []Expr{Param("p_root_type"), Param("p_root_id"), Int(0)},
+	}
+	step := SelectStmt{
+		ColumnExprs: []Expr{
+			Col{Table: "t", Column: "object_type"},
+			Col{Table: "t", Column: "object_id"},
+			Add{Left: Col{Table: "h", Column: "depth"}, Right: Int(1)},
+		},
+		FromExpr: TableAs("", "hierarchy", "h"),
+		Joins: []JoinClause{{
+			Type:      "INNER",
+			TableExpr: TuplesTableAs(tuplesTable, "t"),
+			On: And(
+				Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Col{Table: "h", Column: "object_type"}},
+				Eq{Left: Col{Table: "t", Column: "subject_id"}, Right: Col{Table: "h", Column: "object_id"}},
+			),
+		}},
+		Where: And(
+			Or(edgeMatches...),
+			Lt{Left: Col{Table: "h", Column: "depth"}, Right: Int(maxDepth)},
+		),
+	}
+
+	return WithCTE{
+		Recursive: true,
+		CTEs: []CTEDef{
+			{
+				Name:    "hierarchy",
+				Columns: []string{"object_type", "object_id", "depth"},
+				Query:   Raw(seed.SQL() + "\nUNION\n" + step.SQL()),
+			},
+			{
+				Name: "nodes",
+				Query: SelectStmt{
+					Distinct:    true,
+					ColumnExprs: []Expr{Col{Table: "h", Column: "object_type"}, Col{Table: "h", Column: "object_id"}},
+					FromExpr:    TableAs("", "hierarchy", "h"),
+				},
+			},
+		},
+		Query: SelectStmt{
+			ColumnExprs: []Expr{
+				Func{Name: "array_agg", Args: []Expr{Col{Table: "n", Column: "object_type"}}},
+				Func{Name: "array_agg", Args: []Expr{Col{Table: "n", Column: "object_id"}}},
+				Raw("(SELECT max(h.depth) FROM hierarchy h)"),
+			},
+			FromExpr: TableAs("", "nodes", "n"),
+		},
+	}
+}
+
+// effectiveAccessRelationQuery selects the walked objects of objectType on which
+// the subject holds rel, using the relation's list function as the source.
+func effectiveAccessRelationQuery(databaseSchema string, collisions NameCollisions, objectType, rel string) SelectStmt {
+	return SelectStmt{
+		ColumnExprs: []Expr{Cast{Expr: Lit(objectType), Type: "TEXT"}, Col{Table: "obj", Column: "object_id"}, Cast{Expr: Lit(rel), Type: "TEXT"}},
+		FromExpr: FunctionCallExpr{
+			Schema: databaseSchema,
+			Name:   listObjectsFunctionName(collisions, objectType, rel),
+			Args:   listObjectsCallArgs(SubjectType, SubjectID),
+			Alias:  "obj",
+		},
+		Where: Exists{Query: SelectStmt{
+			ColumnExprs: []Expr{Int(1)},
+			FromExpr:    FunctionCallExpr{Name: "unnest", Args: []Expr{Param("v_node_types"), Param("v_node_ids")}, Alias: "n(object_type, object_id)"},
+			Where: And(
+				Eq{Left: Col{Table: "n", Column: "object_type"}, Right: Lit(objectType)},
+				Eq{Left: Col{Table: "n", Column: "object_id"}, Right: Col{Table: "obj", Column: "object_id"}},
+			),
+		}},
+	}
+}
@@ -0,0 +1,79 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+// effectiveAccessAnalyses is organization <- repository <- issue via TTU, plus
+// an unrelated team type that no hierarchy reaches.
+func effectiveAccessAnalyses() []RelationAnalysis {
+	list := GenerationCapabilities{ListAllowed: true}
+	return []RelationAnalysis{
+		{ObjectType: "organization", Relation: "member", Capabilities: list},
+		{ObjectType: "repository", Relation: "reader", Capabilities: list,
+			ParentRelations: []ParentRelationInfo{{Relation: "member", LinkingRelation: "org", AllowedLinkingTypes: []string{"organization"}}}},
+		{ObjectType: "issue", Relation: "viewer", Capabilities: list,
+			ParentRelations: []ParentRelationInfo{{Relation: "reader", LinkingRelation: "repo", AllowedLinkingTypes: []string{"repository"}}}},
+		{ObjectType: "team", Relation: "member", Capabilities: list},
+	}
+}
+
+func TestReachableTypes_FollowsTTUEdges(t *testing.T) {
+	edges := collectHierarchyEdges(effectiveAccessAnalyses())
+	if len(edges) != 2 {
+		t.Fatalf("expected 2 edges, got %v", edges)
+	}
+
+	got := strings.Join(reachableTypes("organization", edges), ",")
+	if got != "organization,repository,issue" {
+		t.Errorf("reachable from organization = %s", got)
+	}
+	got = strings.Join(reachableTypes("issue", edges), ",")
+	if got != "issue" {
+		t.Errorf("reachable from issue = %s", got)
+	}
+}
+
+func TestEffectiveAccess_BranchesOnlyCallReachableListFunctions(t *testing.T) {
+	sql := generateEffectiveAccessFunction(effectiveAccessAnalyses(), "", "", DefaultMaxDepth)
+
+	assertContains(t, sql, "RETURNS TABLE (object_type TEXT, object_id TEXT, relation TEXT)")
+	assertContains(t, sql, "t.object_type = 'repository' AND t.relation = 'org' AND t.subject_type = 'organization'")
+	assertContains(t, sql, "h.depth < 25")
+	assertContains(t, sql, "IF v_depth >= 25 THEN")
+	assertContains(t, sql, "ERRCODE = 'M2002'")
+
+	orgBranch := sql[strings.Index(sql, "IF p_root_type = 'organization'"):strings.Index(sql, "IF p_root_type = 'repository'")]
+	for _, fn := range []string{"list_organization_member_obj", "list_repository_reader_obj", "list_issue_viewer_obj"} {
+		assertContains(t, orgBranch, fn)
+	}
+	assertNotContains(t, orgBranch, "list_team_member_obj")
+
+	teamBranch := sql[strings.Index(sql, "IF p_root_type = 'team'"):]
+	assertContains(t, teamBranch, "list_team_member_obj")
+	assertNotContains(t, teamBranch, "list_repository_reader_obj")
+}
+
+func TestEffectiveAccess_GatedByOption(t *testing.T) {
+	analyses := effectiveAccessAnalyses()
+
+	off, err := GenerateSQL(analyses, InlineSQLData{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off.EffectiveAccessFunction != "" {
+		t.Error("effective_access generated without EnableEffectiveAccess")
+	}
+	for _, nf := range CollectDispatcherFunctions(off, ListGeneratedSQL{}) {
+		if nf.Name == "effective_access" {
+			t.Error("effective_access collected without EnableEffectiveAccess")
+		}
+	}
+
+	on, err := GenerateSQLWithOptions(analyses, InlineSQLData{}, "", GenerateSQLOptions{EnableEffectiveAccess: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, on.EffectiveAccessFunction, "CREATE OR REPLACE FUNCTION effective_access(")
+}
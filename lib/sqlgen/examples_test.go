@@ -147,8 +147,8 @@ func TestUsersetCheck(t *testing.T) {
 	checks := []string{
 		"FROM melange_tuples AS grant_tuple",
 		"INNER JOIN melange_tuples AS membership ON",
-		"split_part(grant_tuple.subject_id, '#', 1)",
-		"split_part(grant_tuple.subject_id, '#', 2) = 'member'",
+		"left(grant_tuple.subject_id, length(grant_tuple.subject_id) - strpos(reverse(grant_tuple.subject_id), '#'))",
+		"substring(grant_tuple.subject_id from length(grant_tuple.subject_id) - strpos(reverse(grant_tuple.subject_id), '#') + 2) = 'member'",
 		"position('#' in grant_tuple.subject_id) > 0",
 		"membership.relation IN ('member', 'admin')",
 	}
@@ -284,9 +284,9 @@ func TestListObjectsUsersetPatternSimple(t *testing.T) {
 		"INNER JOIN melange_tuples AS m ON",
 		"t.subject_type = 'group'",
 		"position('#' in t.subject_id) > 0",
-		"split_part(t.subject_id, '#', 2) = 'member'",
+		"substring(t.subject_id from length(t.subject_id) - strpos(reverse(t.subject_id), '#') + 2) = 'member'",
 		"m.object_type = 'group'",
-		"split_part(t.subject_id, '#', 1)",
+		"left(t.subject_id, length(t.subject_id) - strpos(reverse(t.subject_id), '#'))",
 		"m.relation IN ('member', 'admin')",
 		"m.subject_type = p_subject_type",
 		"p_subject_type IN ('user')",
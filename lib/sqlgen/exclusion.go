@@ -27,6 +27,7 @@ type ExcludedIntersectionGroup struct {
 // It classifies exclusions by complexity and generates appropriate SQL predicates.
 type ExclusionConfig struct {
 	DatabaseSchema string
+	TuplesTable    string // Tuples relation; empty means DefaultTuplesTable
 	ObjectType     string // The object type being checked
 
 	ObjectIDExpr    Expr // Expression for the object ID (typically a column or parameter)
@@ -112,7 +113,7 @@ func (c ExclusionConfig) ttuLinkQuery(rel ExcludedParentRelation) *TupleQuery {
 		Type: Col{Table: "link", Column: "subject_type"},
 		ID:   Col{Table: "link", Column: "subject_id"},
 	}
-	q := Tuples(c.DatabaseSchema, "link").
+	q := TuplesWithTable(c.TuplesTable, c.DatabaseSchema, "link").
 		ObjectType(c.ObjectType).
 		Relations(rel.LinkingRelation).
 		Select("1").
@@ -143,7 +144,7 @@ func (c ExclusionConfig) BuildPredicates() []Expr {
 
 	for _, rel := range c.SimpleExcludedRelations {
 		predicates = append(predicates, simpleExclusionQuery(
-			c.DatabaseSchema, c.ObjectType, rel, c.ObjectIDExpr, c.SubjectTypeExpr, c.SubjectIDExpr,
+			c.DatabaseSchema, c.TuplesTable, c.ObjectType, rel, c.ObjectIDExpr, c.SubjectTypeExpr, c.SubjectIDExpr,
 		))
 	}
 
@@ -187,7 +188,7 @@ func (c ExclusionConfig) complexExclusionAntiJoin(rel string) Expr {
 		return nil
 	}
 	membership := composedListObjectsMembership(
-		c.DatabaseSchema, c.ObjectType, rel, c.ObjectIDExpr,
+		c.DatabaseSchema, c.Compose.Lookup[c.ObjectType+"."+rel].NameCollisions, c.ObjectType, rel, c.ObjectIDExpr,
 		c.SubjectTypeExpr, c.SubjectIDExpr, "excl_obj",
 		c.checkPermission(rel, c.objectRef(), true),
 	)
@@ -221,8 +222,8 @@ func (c ExclusionConfig) buildIntersectionPart(part ExcludedIntersectionPart) Ex
 	)
 }
 
-func simpleExclusionQuery(databaseSchema, objectType, relation string, objectID, subjectType, subjectID Expr) NotExists {
-	excl := Tuples(databaseSchema, "excl").
+func simpleExclusionQuery(databaseSchema, tuplesTable, objectType, relation string, objectID, subjectType, subjectID Expr) NotExists {
+	excl := TuplesWithTable(tuplesTable, databaseSchema, "excl").
 		ObjectType(objectType).
 		Relations(relation).
 		Select("1").
@@ -240,8 +241,9 @@ func simpleExclusionQuery(databaseSchema, objectType, relation string, objectID,
 // SimpleExclusion creates a NOT EXISTS exclusion for a simple "but not" rule.
 // This checks for the absence of a tuple granting the excluded relation to the subject.
 // Wildcards are handled: if a wildcard tuple exists for the excluded relation, access is denied.
+// The tuple is looked up in DefaultTuplesTable.
 func SimpleExclusion(databaseSchema, objectType, relation string, objectID, subjectType, subjectID Expr) Expr {
-	return simpleExclusionQuery(databaseSchema, objectType, relation, objectID, subjectType, subjectID)
+	return simpleExclusionQuery(databaseSchema, "", objectType, relation, objectID, subjectType, subjectID)
 }
 
 // BuildExclusionCTE builds a CTE that materializes all excluded subjects.
@@ -270,7 +272,7 @@ func (c ExclusionConfig) BuildExclusionCTE() string {
 	// Alias the tuples table and qualify subject_id so it cannot collide with
 	// the enclosing function's OUT parameter of the same name (which would
 	// otherwise raise "column reference subject_id is ambiguous" at runtime).
-	q := Tuples(c.DatabaseSchema, "e").
+	q := TuplesWithTable(c.TuplesTable, c.DatabaseSchema, "e").
 		ObjectType(c.ObjectType).
 		Relations(c.SimpleExcludedRelations...).
 		Where(
@@ -48,7 +48,7 @@ func TestComplexExclusion_AntiJoinWhenComposable(t *testing.T) {
 
 	sql := predicatesSQL(exclusionWithCompose(lookup))
 
-	if !strings.Contains(sql, "t.object_id IN (SELECT excl_obj.object_id FROM list_doc_blocked_obj(p_subject_type, p_subject_id, NULL, NULL) excl_obj)") {
+	if !strings.Contains(sql, "t.object_id IN (SELECT excl_obj.object_id FROM list_doc_blocked_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) excl_obj)") {
 		t.Errorf("expected set-oriented anti-join against list_doc_blocked_obj, got:\n%s", sql)
 	}
 	// Userset-typed subjects keep a guarded per-candidate check for parity.
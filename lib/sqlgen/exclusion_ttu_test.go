@@ -0,0 +1,78 @@
+package sqlgen
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestGenerateSQL_ExclusionOfTTU covers "but not" excluding a tuple-to-userset,
+// both written inline and through a relation that is itself a TTU. Neither may
+// fall back to the generic functions: the inline form checks the linked org,
+// and the named form is a complex exclusion checked via
+// check_permission_internal (or the list_objects anti-join).
+func TestGenerateSQL_ExclusionOfTTU(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, `
+model
+  schema 1.1
+
+type user
+
+type org
+  relations
+    define banned: [user]
+
+type doc
+  relations
+    define org: [org]
+    define editor: [user]
+    define banned_from_parent: banned from org
+    define can_view: editor but not banned_from_parent
+    define can_edit: editor but not (banned from org)
+`)
+
+	for _, a := range analyses {
+		if a.ObjectType != "doc" || (a.Relation != "can_view" && a.Relation != "can_edit") {
+			continue
+		}
+		if !a.Capabilities.CheckAllowed || !a.Capabilities.ListAllowed {
+			t.Errorf("doc.%s falls back to generic: check %q, list %q", a.Relation, a.Capabilities.CheckReason, a.Capabilities.ListReason)
+		}
+		if a.Relation == "can_view" && !slices.Contains(a.ComplexExcludedRelations, "banned_from_parent") {
+			t.Errorf("doc.can_view: ComplexExcludedRelations = %v, want banned_from_parent", a.ComplexExcludedRelations)
+		}
+	}
+
+	gen, err := GenerateSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatalf("GenerateSQL: %v", err)
+	}
+	list, err := GenerateListSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatalf("GenerateListSQL: %v", err)
+	}
+	find := func(fns []string, name string) string {
+		for _, fn := range fns {
+			if strings.Contains(fn, "FUNCTION "+name+"(") {
+				return fn
+			}
+		}
+		t.Fatalf("%s not generated", name)
+		return ""
+	}
+
+	for _, tc := range []struct {
+		fn, want string
+	}{
+		{find(gen.Functions, functionName(nil, "doc", "can_view")), "'banned_from_parent', 'doc', p_object_id, p_visited) = 1"},
+		{find(list.ListObjectsFunctions, listObjectsFunctionName(nil, "doc", "can_view")), "list_doc_banned_from_parent_obj("},
+		{find(list.ListSubjectsFunctions, listSubjectsFunctionName(nil, "doc", "can_view")), "'banned_from_parent', 'doc', p_object_id, ARRAY[]::TEXT[]) = 0"},
+		{find(gen.Functions, functionName(nil, "doc", "can_edit")), "link.relation IN ('org')"},
+		{find(list.ListObjectsFunctions, listObjectsFunctionName(nil, "doc", "can_edit")), "'banned', link.subject_type, link.subject_id"},
+		{find(list.ListSubjectsFunctions, listSubjectsFunctionName(nil, "doc", "can_edit")), "'banned', link.subject_type, link.subject_id"},
+	} {
+		if !strings.Contains(tc.fn, tc.want) {
+			t.Errorf("expected %q in:\n%s", tc.want, tc.fn)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+// A user:* grant matches every querying user, so the exclusion must be keyed
+// on the querying subject (p_subject_id), never on the matched tuple's
+// subject_id, which is "*" for the wildcard row and would let a banned user
+// through.
+func TestExclusion_WildcardBase(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, `model
+  schema 1.1
+
+type user
+
+type doc
+  relations
+    define banned: [user]
+    define public: [user:*]
+    define direct: [user:*] but not banned
+    define implied: public but not banned
+`)
+	gen, err := GenerateSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := GenerateListSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const excludedSubject = "excl.subject_type = p_subject_type AND (excl.subject_id = p_subject_id OR excl.subject_id = '*')"
+	for _, rel := range []string{"direct", "implied"} {
+		check := functionNamed(t, gen.Functions, functionName(nil, "doc", rel))
+		assertContains(t, check, "(subject_id = p_subject_id OR subject_id = '*')")
+		assertContains(t, check, excludedSubject)
+
+		listObjects := functionNamed(t, list.ListObjectsFunctions, listObjectsFunctionName(nil, "doc", rel))
+		assertContains(t, listObjects, "(t.subject_id = p_subject_id OR t.subject_id = '*')")
+		assertContains(t, listObjects, excludedSubject)
+		if strings.Contains(listObjects, "excl.subject_id = t.subject_id") {
+			t.Errorf("list_objects for doc.%s excludes on the tuple's subject:\n%s", rel, listObjects)
+		}
+	}
+
+	// With p_expand_wildcard, each known user is excluded on its own ID.
+	listSubjects := functionNamed(t, list.ListSubjectsFunctions, listSubjectsFunctionName(nil, "doc", "direct"))
+	assertContains(t, listSubjects, "(excl.subject_id = u.subject_id OR excl.subject_id = '*')")
+}
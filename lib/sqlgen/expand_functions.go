@@ -37,11 +37,12 @@ func expandDispatcherPublicArgs() []FuncArg {
 // generateExpandFunction wraps RenderExpandFunction with the per-relation
 // plan derivation, returning ("", false) when BuildExpandPlan reports the
 // relation ineligible.
-func generateExpandFunction(a RelationAnalysis, databaseSchema string) (string, bool) {
+func generateExpandFunction(a RelationAnalysis, databaseSchema, tuplesTable string) (string, bool) {
 	plan, ok := BuildExpandPlan(a, databaseSchema)
 	if !ok {
 		return "", false
 	}
+	plan.TuplesTable = tuplesTable
 	return RenderExpandFunction(plan), true
 }
 
@@ -51,14 +52,17 @@ func generateExpandFunction(a RelationAnalysis, databaseSchema string) (string,
 // pure-SQL public wrapper for the hot-path planner symmetry.
 //
 // eligible records the (object_type, relation) pairs for which an expand
-// function was generated; unsupported pairs route to the no-entry
-// sentinel rather than crashing.
+// function was generated. A checkable relation without one raises M2004
+// rather than returning a tree that reads as "no one has access"; pairs
+// the model doesn't define, or whose relation has no access paths, route
+// to the no-entry sentinel, matching check_permission's deny.
 func generateExpandDispatcher(analyses []RelationAnalysis, databaseSchema string, eligible map[string]map[string]bool) string {
 	cases := buildExpandDispatcherCases(analyses, databaseSchema, eligible)
-	if len(cases) == 0 {
+	guards := buildExpandNotExpandableGuards(analyses, eligible)
+	if len(cases) == 0 && len(guards) == 0 {
 		return renderEmptyExpandDispatcher(databaseSchema)
 	}
-	return renderExpandDispatcherWithCases(databaseSchema, cases)
+	return renderExpandDispatcherWithCases(databaseSchema, cases, guards)
 }
 
 // buildExpandDispatcherCases mirrors buildDispatcherCases / buildExplain*
@@ -74,13 +78,36 @@ func buildExpandDispatcherCases(analyses []RelationAnalysis, databaseSchema stri
 			DatabaseSchema:    databaseSchema,
 			ObjectType:        a.ObjectType,
 			Relation:          a.Relation,
-			CheckFunctionName: expandFunctionName(a.ObjectType, a.Relation),
+			CheckFunctionName: expandFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
 		})
 	}
 	return cases
 }
 
-func renderExpandDispatcherWithCases(databaseSchema string, cases []DispatcherCase) string {
+// buildExpandNotExpandableGuards returns one IF per checkable relation the
+// expand renderer could not generate, raising M2004 (relation not
+// expandable) when the dispatcher is asked for it.
+func buildExpandNotExpandableGuards(analyses []RelationAnalysis, eligible map[string]map[string]bool) []Stmt {
+	var guards []Stmt
+	for _, a := range analyses {
+		if !a.Capabilities.CheckAllowed || eligible[a.ObjectType][a.Relation] {
+			continue
+		}
+		guards = append(guards, If{
+			Cond: And(
+				Eq{Left: ObjectType, Right: Lit(a.ObjectType)},
+				Eq{Left: Raw("p_relation"), Right: Lit(a.Relation)},
+			),
+			Then: []Stmt{Raise{Message: "relation not expandable: " + a.ObjectType + "." + a.Relation, ErrCode: ErrCodeRelationNotExpandable}},
+		})
+	}
+	return guards
+}
+
+// renderExpandDispatcherWithCases renders the PL/pgSQL dispatcher: guards
+// run first, so a not-expandable relation raises before the IF-chain would
+// route it to the sentinel.
+func renderExpandDispatcherWithCases(databaseSchema string, cases []DispatcherCase, guards []Stmt) string {
 	internalFn := PlpgsqlFunction{
 		Schema:  databaseSchema,
 		Name:    "expand_permission_internal",
@@ -88,14 +115,13 @@ func renderExpandDispatcherWithCases(databaseSchema string, cases []DispatcherCa
 		Returns: "JSONB",
 		// IF-chain, not RETURN CASE: measurably faster on the hot path (see
 		// dispatchIfChain in check_functions.go).
-		Body: dispatchIfChain(cases, expandDispatchCall, Raw(expandNoEntrySentinelSQL())),
+		Body: append(guards, dispatchIfChain(cases, expandDispatchCall, Raw(expandNoEntrySentinelSQL()))...),
 		Header: []string{
 			"Generated internal dispatcher for expand_permission",
 			"Routes (object_type, relation) to specialised expand_* functions",
-			"Returns an empty Leaf.Users sentinel for unknown / not-yet-supported",
-			"pairs so OpenFGA tooling deserialises without special-casing.",
-			"Callers that need to distinguish 'no one has access' from 'expand",
-			"not supported for this relation' should compare against Check.",
+			"Raises M2004 for checkable relations with no expand function, and",
+			"returns an empty Leaf.Users sentinel for unknown pairs so OpenFGA",
+			"tooling deserialises without special-casing.",
 		},
 		// Routes only to schema-qualified expand_{type}_{rel} calls, no
 		// unqualified melange_tuples.
@@ -123,7 +149,7 @@ func renderExpandDispatcherWithCases(databaseSchema string, cases []DispatcherCa
 }
 
 // renderEmptyExpandDispatcher emits a no-op dispatcher when the schema
-// has no eligible relations. Returns a structurally valid UsersetTree
+// has no eligible or not-expandable relations. Returns a structurally valid UsersetTree
 // with an empty Users leaf so callers parsing the JSON don't choke.
 func renderEmptyExpandDispatcher(databaseSchema string) string {
 	body := "SELECT " + expandNoEntrySentinelSQL()
@@ -163,14 +189,11 @@ func expandDispatchCall(c DispatcherCase) Expr {
 }
 
 // expandNoEntrySentinelSQL emits the JSONB UsersetTree returned when
-// the dispatcher has no CASE branch for the requested (object_type,
-// relation). Shape matches OpenFGA's UsersetTree exactly — a root
-// node carrying the requested name and an empty Leaf.Users — so
-// OpenFGA tooling deserialises without an adapter.
-//
-// Callers that need to distinguish "no users have this permission"
-// from "expand isn't yet supported for this relation" should cross-
-// reference against Check (which has full feature coverage).
+// the dispatcher has no branch for the requested (object_type,
+// relation) — a pair the model doesn't define, or one with no access
+// paths. Shape matches OpenFGA's UsersetTree exactly — a root node
+// carrying the requested name and an empty Leaf.Users — so OpenFGA
+// tooling deserialises without an adapter.
 func expandNoEntrySentinelSQL() string {
 	// Name is built inline rather than via BuildExpandNodeName because
 	// the relation portion is a runtime column ref (p_relation), not a
@@ -23,8 +23,8 @@ import (
 // Checker.ExpandRecursive.
 
 // expandFunctionName returns "expand_{type}_{relation}".
-func expandFunctionName(objectType, relation string) string {
-	return SafeIdentifier("expand_", objectType, relation, "")
+func expandFunctionName(collisions NameCollisions, objectType, relation string) string {
+	return RelationIdentifier(collisions, "expand_", objectType, relation, "")
 }
 
 // expandFunctionArgs returns the per-relation expand function signature.
@@ -59,8 +59,10 @@ func expandFunctionHeader(plan ExpandPlan) []string {
 // Union envelope around per-rewrite children.
 type ExpandPlan struct {
 	DatabaseSchema string
+	TuplesTable    string // Tuples relation; empty means DefaultTuplesTable
 	ObjectType     string
 	Relation       string
+	NameCollisions NameCollisions // From the analysis; see RelationAnalysis.NameCollisions
 	Rewrites       []ExpandRewrite
 	// Exclusions captures the relation's `but not` subtrahends. Each
 	// entry wraps into a `Difference{base, subtract}` node, with the
@@ -150,10 +152,22 @@ func ComputeExpandEligibility(analyses []RelationAnalysis) map[string]map[string
 	return eligible
 }
 
+// tuplesTable returns the tuples relation the expand leaves read. Unlike
+// check and list functions, expand qualifies it with the database schema,
+// unless the configured name already carries a schema of its own.
+func (p ExpandPlan) tuplesTable() string {
+	name := TuplesTableName(p.TuplesTable)
+	if strings.Contains(name, ".") {
+		return name
+	}
+	return sqldsl.PrefixIdent(name, p.DatabaseSchema)
+}
+
 // BuildExpandPlan derives the per-rewrite plan from a RelationAnalysis.
-// Returns (plan, true) when the relation is eligible; the dispatcher routes
-// ineligible relations to the no-entry sentinel. A plan with no rewrites
-// is ineligible — the caller returns the sentinel rather than an empty tree.
+// Returns (plan, true) when the relation is eligible; the dispatcher raises
+// M2004 for an ineligible relation that check_permission can still answer.
+// A plan with no rewrites is ineligible — the caller raises rather than
+// returning an empty tree.
 func BuildExpandPlan(a RelationAnalysis, databaseSchema string) (ExpandPlan, bool) {
 	if !expandLocalSupported(a) {
 		return ExpandPlan{}, false
@@ -162,6 +176,7 @@ func BuildExpandPlan(a RelationAnalysis, databaseSchema string) (ExpandPlan, boo
 		DatabaseSchema: databaseSchema,
 		ObjectType:     a.ObjectType,
 		Relation:       a.Relation,
+		NameCollisions: a.NameCollisions,
 	}
 	// A single "direct" rewrite covers concrete users (`[user]`), wildcards
 	// (`[user:*]`), and userset references (`[group#member]`) — all three
@@ -224,8 +239,8 @@ func BuildExpandPlan(a RelationAnalysis, databaseSchema string) (ExpandPlan, boo
 	}
 	if len(plan.Rewrites) == 0 {
 		// Relation has no concrete access paths — let the dispatcher
-		// sentinel handle it rather than emitting a structurally empty
-		// tree the caller would have to special-case.
+		// handle it rather than emitting a structurally empty tree the
+		// caller would have to special-case.
 		return ExpandPlan{}, false
 	}
 	return plan, true
@@ -236,7 +251,7 @@ func BuildExpandPlan(a RelationAnalysis, databaseSchema string) (ExpandPlan, boo
 // flags: plain relations, TTU, intersection, exclusion (Difference chaining),
 // wildcards, and userset references. Relations with none of these (HasDirect,
 // HasImplied, HasRecursive, HasIntersection, HasUserset all false) have no
-// concrete access paths and route to the dispatcher's no-entry sentinel.
+// concrete access paths and get no expand function.
 func expandLocalSupported(a RelationAnalysis) bool {
 	f := a.Features
 	return f.HasDirect || f.HasImplied || f.HasRecursive || f.HasIntersection || f.HasUserset
@@ -282,7 +297,7 @@ func RenderExpandFunction(plan ExpandPlan) string {
 
 	fn := PlpgsqlFunction{
 		Schema:  plan.DatabaseSchema,
-		Name:    expandFunctionName(plan.ObjectType, plan.Relation),
+		Name:    expandFunctionName(plan.NameCollisions, plan.ObjectType, plan.Relation),
 		Args:    expandFunctionArgs(),
 		Returns: "JSONB",
 		Body:    []Stmt{ReturnValue{Value: Raw(body)}},
@@ -447,7 +462,7 @@ func buildExpandIntersectionPartNode(plan ExpandPlan, objectTypeLit string, part
 // empty — that's a valid OpenFGA response meaning "no parents to
 // inherit from".
 func buildExpandTTULeaf(plan ExpandPlan, ttu ParentRelationInfo) string {
-	tuplesTable := sqldsl.PrefixIdent("melange_tuples", plan.DatabaseSchema)
+	tuplesTable := plan.tuplesTable()
 
 	// Tupleset is built inline rather than via BuildExpandNodeName
 	// because the tupleset references the current object (whose id is
@@ -506,7 +521,7 @@ func buildExpandTTULeaf(plan ExpandPlan, ttu ParentRelationInfo) string {
 // users_truncated field is omitted from the JSONB via the helper's CASE
 // wrapper.
 func buildExpandDirectLeaf(plan ExpandPlan, subjectTypes []string) string {
-	tuplesTable := sqldsl.PrefixIdent("melange_tuples", plan.DatabaseSchema)
+	tuplesTable := plan.tuplesTable()
 	subjectTypeList := formatSQLStringList(subjectTypes)
 
 	// The subject-type allow-list (from the schema) AND the per-call
@@ -319,12 +319,12 @@ func TestRenderExplainFunction_UsersetFlow(t *testing.T) {
 		"v_child_trace JSONB",
 		// FOR-loop driver projects the parent object id from the userset reference
 		"FOR v_userset_grant IN",
-		"split_part(grant_tuple.subject_id, '#', 1) AS group_id",
+		"left(grant_tuple.subject_id, length(grant_tuple.subject_id) - strpos(reverse(grant_tuple.subject_id), '#')) AS group_id",
 		// Filters: grant subject type matches, subject_id is a userset ref,
 		// suffix matches the pattern's SubjectRelation
 		"grant_tuple.subject_type = 'group'",
 		"position('#' in grant_tuple.subject_id) > 0",
-		"split_part(grant_tuple.subject_id, '#', 2) = 'member'",
+		"substring(grant_tuple.subject_id from length(grant_tuple.subject_id) - strpos(reverse(grant_tuple.subject_id), '#') + 2) = 'member'",
 		// Dispatcher call recurses into the membership relation
 		"explain_permission_internal(p_subject_type, p_subject_id, 'member', 'group', v_userset_grant.group_id, p_visited || ARRAY[v_key], p_max_nodes)",
 		// NodeUserset wrap with informative label
@@ -556,6 +556,29 @@ func TestRenderExplainFunction_TruncationDeclsAndCheck(t *testing.T) {
 	}
 }
 
+// TestGenerateSQL_PoolerSafeSkipsSessionGUC pins that pooler-safe output reads
+// no session state: explain resolves its node cap without current_setting.
+func TestGenerateSQL_PoolerSafeSkipsSessionGUC(t *testing.T) {
+	analyses := []RelationAnalysis{mkAnalysis("doc", "viewer", RelationFeatures{HasDirect: true}, false)}
+	analyses[0].Capabilities = GenerationCapabilities{CheckAllowed: true}
+
+	got, err := GenerateSQLWithOptions(analyses, InlineSQLData{}, "", GenerateSQLOptions{PoolerSafe: true})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	if len(got.ExplainFunctions) == 0 {
+		t.Fatal("expected an explain function")
+	}
+	for _, fn := range got.ExplainFunctions {
+		if strings.Contains(fn, "current_setting(") {
+			t.Errorf("pooler-safe explain function reads a session GUC:\n%s", fn)
+		}
+		if !strings.Contains(fn, "v_max_nodes INTEGER := COALESCE(p_max_nodes, 100)") {
+			t.Errorf("pooler-safe explain function lost its node cap:\n%s", fn)
+		}
+	}
+}
+
 // TestRenderExplainFunction_NoImpliedCallsSkipsDecl verifies that the
 // v_child_trace local is only declared when the body actually recurses
 // into a sibling explain. Otherwise we'd carry an unused JSONB variable
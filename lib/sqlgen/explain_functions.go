@@ -44,12 +44,14 @@ func explainDispatcherPublicArgs() []FuncArg {
 	}
 }
 
-func generateExplainFunction(a RelationAnalysis, inline InlineSQLData, databaseSchema string, complexityByRelation map[string]map[string]int) (string, error) {
+func generateExplainFunction(a RelationAnalysis, inline InlineSQLData, databaseSchema, tuplesTable string, complexityByRelation map[string]map[string]int, poolerSafe bool, maxDepth int) (string, error) {
 	// Explain shares check's plan/blocks pipeline, so it must apply the same
 	// closure/userset filter check uses (generateCheckFunction). Without it the
 	// explain leaf embedded the full, unfiltered model VALUES — the last function
 	// kind still scaling with unrelated schema growth (Fix C invariant).
-	plan := BuildCheckPlanWithOrdering(a, filterInlineForCheck(inline, a), databaseSchema, false, complexityByRelation)
+	plan := BuildCheckPlanWithOrdering(a, filterInlineForCheck(inline, a), databaseSchema, false, complexityByRelation).withTuplesTable(tuplesTable)
+	plan.PoolerSafe = poolerSafe
+	plan.MaxDepth = maxDepth
 	blocks, err := BuildCheckBlocks(plan)
 	if err != nil {
 		return "", fmt.Errorf("building check blocks for explain %s.%s: %w", a.ObjectType, a.Relation, err)
@@ -70,12 +72,12 @@ func generateExplainFunction(a RelationAnalysis, inline InlineSQLData, databaseS
 //
 // eligible is the precomputed (object_type, relation) → bool map from
 // ComputeExplainEligibility; only eligible pairs become CASE branches.
-func generateExplainDispatcher(analyses []RelationAnalysis, databaseSchema string, eligible map[string]map[string]bool) (string, error) {
+func generateExplainDispatcher(analyses []RelationAnalysis, databaseSchema string, eligible map[string]map[string]bool, maxDepth int) (string, error) {
 	cases := buildExplainDispatcherCases(analyses, databaseSchema, eligible)
 	if len(cases) == 0 {
 		return renderEmptyExplainDispatcher(databaseSchema), nil
 	}
-	return renderExplainDispatcherWithCases(databaseSchema, cases), nil
+	return renderExplainDispatcherWithCases(databaseSchema, cases, maxDepth), nil
 }
 
 // buildExplainDispatcherCases mirrors buildDispatcherCases but only emits
@@ -95,13 +97,13 @@ func buildExplainDispatcherCases(analyses []RelationAnalysis, databaseSchema str
 			DatabaseSchema:    databaseSchema,
 			ObjectType:        a.ObjectType,
 			Relation:          a.Relation,
-			CheckFunctionName: explainFunctionName(a.ObjectType, a.Relation),
+			CheckFunctionName: explainFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
 		})
 	}
 	return cases
 }
 
-func renderExplainDispatcherWithCases(databaseSchema string, cases []DispatcherCase) string {
+func renderExplainDispatcherWithCases(databaseSchema string, cases []DispatcherCase, maxDepth int) string {
 	noEntry := Raw(explainNoEntrySentinelSQL(
 		"explain not yet supported for this (object_type, relation) — no generated explain function for the requested pair. Confirm the pair exists in the migrated schema.",
 	))
@@ -115,10 +117,7 @@ func renderExplainDispatcherWithCases(databaseSchema string, cases []DispatcherC
 		// dispatchIfChain in check_functions.go).
 		Body: append([]Stmt{
 			Comment{Text: "Depth limit check shared with check_permission_internal"},
-			If{
-				Cond: Gte{Left: ArrayLength{Array: Visited}, Right: Int(25)},
-				Then: []Stmt{Raise{Message: "resolution too complex", ErrCode: "M2002"}},
-			},
+			depthLimitCheck(maxDepth),
 		}, dispatchIfChain(cases, explainDispatchCall, noEntry)...),
 		Header: []string{
 			"Generated internal dispatcher for explain_permission",
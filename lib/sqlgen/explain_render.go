@@ -51,7 +51,7 @@ func RenderExplainFunction(plan CheckPlan, blocks CheckBlocks) (string, error) {
 
 	fn := PlpgsqlFunction{
 		Schema:  plan.DatabaseSchema,
-		Name:    explainFunctionName(plan.ObjectType, plan.Relation),
+		Name:    explainFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, plan.Relation),
 		Args:    explainFunctionArgs(),
 		Returns: "JSONB",
 		Decls:   explainFunctionDecls(plan, blocks),
@@ -122,8 +122,8 @@ func explainFunctionDecls(plan CheckPlan, blocks CheckBlocks) []Decl {
 		// p_max_nodes > session GUC melange.max_explain_nodes > built-in
 		// default (100). `current_setting`'s second-arg true returns NULL
 		// when the GUC is unset instead of raising; COALESCE then falls to
-		// the default.
-		{Name: "v_max_nodes", Type: "INTEGER := COALESCE(p_max_nodes, current_setting('melange.max_explain_nodes', true)::INTEGER, 100)"},
+		// the default. PoolerSafe drops the GUC tier.
+		{Name: "v_max_nodes", Type: "INTEGER := " + explainMaxNodesExpr(plan)},
 		// v_truncated is flipped to TRUE when this function bails because
 		// v_node_count crossed v_max_nodes. The Trace envelope's
 		// `truncated` field surfaces the flag so callers can tell the
@@ -156,6 +156,16 @@ func explainFunctionDecls(plan CheckPlan, blocks CheckBlocks) []Decl {
 	return decls
 }
 
+// explainMaxNodesExpr resolves the effective node cap. Pooler-safe bodies
+// skip the session GUC: behind a transaction-mode pooler a session SET from
+// one client would silently cap another client's traces.
+func explainMaxNodesExpr(plan CheckPlan) string {
+	if plan.PoolerSafe {
+		return "COALESCE(p_max_nodes, 100)"
+	}
+	return "COALESCE(p_max_nodes, current_setting('melange.max_explain_nodes', true)::INTEGER, 100)"
+}
+
 // buildExplainCycleDetection emits the standard cycle / depth-limit guard
 // plus a truncation pre-check. Same shape as buildCycleDetectionStmts but
 // the cycle branch returns a Trace with a NodeCycle root, and an
@@ -173,10 +183,7 @@ func buildExplainCycleDetection(plan CheckPlan) []Stmt {
 				ReturnValue{Value: Raw(buildExplainTraceRoot(plan, "false", "v_root"))},
 			},
 		},
-		If{
-			Cond: Gte{Left: ArrayLength{Array: Visited}, Right: Int(25)},
-			Then: []Stmt{Raise{Message: "resolution too complex", ErrCode: "M2002"}},
-		},
+		depthLimitCheck(plan.maxDepth()),
 	}
 }
 
@@ -277,14 +284,15 @@ func buildExplainDirectAttempt(plan CheckPlan, blocks CheckBlocks) []Stmt {
 // SELECT … LIMIT 1 so we can capture a single evidence row rather than just
 // proving existence.
 func buildExplainDirectSelect(plan CheckPlan) SelectStmt {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(plan.RelationList...).
 		SelectCol("subject_type", "subject_id", "relation", "object_type", "object_id").
 		Where(
 			Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: SubjectType},
-			SubjectIDMatch(Col{Table: "t", Column: "subject_id"}, SubjectID, plan.AllowWildcard),
+			plan.directSubjectIDMatch(Col{Table: "t", Column: "subject_id"}),
+			conditionalTupleGuard(plan.Analysis, "t"),
 		).
 		Limit(1)
 	if len(plan.AllowedSubjectTypes) > 0 {
@@ -317,7 +325,7 @@ func buildExplainImpliedAttempts(plan CheckPlan, blocks CheckBlocks) []Stmt {
 	stmts := []Stmt{Comment{Text: "Implied function call attempts"}}
 
 	for _, call := range blocks.ImpliedFunctionCalls {
-		explainFnName := explainFunctionName(plan.ObjectType, call.Relation)
+		explainFnName := explainFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, call.Relation)
 		callExpr := fmt.Sprintf("%s(p_subject_type, p_subject_id, p_object_id, p_visited || ARRAY[v_key], p_max_nodes)",
 			sqldsl.PrefixIdent(explainFnName, plan.DatabaseSchema))
 
@@ -447,7 +455,7 @@ func buildExplainParentLoopStmt(plan CheckPlan, blocks CheckBlocks, parent Paren
 // FOR-loop SELECT: every melange_tuples row that links this object to a
 // parent via the linking relation, projected as (parent_type, parent_id).
 func buildExplainParentLinkingSelect(plan CheckPlan, parent ParentRelationBlock) SelectStmt {
-	q := Tuples(plan.DatabaseSchema, "link").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "link").
 		ObjectType(plan.ObjectType).
 		Relations(parent.LinkingRelation).
 		SelectExpr(
@@ -505,9 +513,7 @@ func buildExplainUsersetSubjectStmts(plan CheckPlan, blocks CheckBlocks) []Stmt
 	selfRefOuterCond := AndExpr{Exprs: []Expr{
 		Eq{Left: SubjectType, Right: Lit(plan.ObjectType)},
 		Eq{
-			Left: Func{Name: "split_part", Args: []Expr{
-				SubjectID, Lit("#"), Int(1),
-			}},
+			Left:  UsersetObjectID{Source: SubjectID},
 			Right: ObjectID,
 		},
 	}}
@@ -552,7 +558,7 @@ func buildExplainUsersetSubjectStmts(plan CheckPlan, blocks CheckBlocks) []Stmt
 	return []Stmt{
 		Comment{Text: "Userset subject handling (subject is itself a userset reference)"},
 		If{
-			Cond: Gt{Left: Position{Needle: Lit("#"), Haystack: SubjectID}, Right: Int(0)},
+			Cond: Gt{Left: StrPos{Source: SubjectID, Substr: "#"}, Right: Int(0)},
 			Then: innerThen,
 		},
 	}
@@ -648,15 +654,11 @@ func buildExplainUsersetLoopStmt(plan CheckPlan, blocks CheckBlocks, pattern Use
 // recursive call.
 func buildExplainUsersetGrantSelect(plan CheckPlan, pattern UsersetPattern) SelectStmt {
 	groupIDExpr := Alias{
-		Expr: Func{Name: "split_part", Args: []Expr{
-			Col{Table: "grant_tuple", Column: "subject_id"},
-			Lit("#"),
-			Int(1),
-		}},
+		Expr: UsersetObjectID{Source: Col{Table: "grant_tuple", Column: "subject_id"}},
 		Name: "group_id",
 	}
 
-	q := Tuples(plan.DatabaseSchema, "grant_tuple").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "grant_tuple").
 		ObjectType(plan.ObjectType).
 		Relations(plan.Relation).
 		SelectExpr(groupIDExpr).
@@ -906,8 +908,8 @@ func buildExplainTraceRoot(plan CheckPlan, resultExpr, rootExpr string) string {
 }
 
 // explainFunctionName mirrors functionName: explain_{type}_{relation}.
-func explainFunctionName(objectType, relation string) string {
-	return SafeIdentifier("explain_", objectType, relation, "")
+func explainFunctionName(collisions NameCollisions, objectType, relation string) string {
+	return RelationIdentifier(collisions, "explain_", objectType, relation, "")
 }
 
 // explainLocalSupported is the local-only check: does this relation's
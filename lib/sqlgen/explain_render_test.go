@@ -102,7 +102,7 @@ func TestGenerateExplainDispatcher_Routes(t *testing.T) {
 		mkAnalysis("document", "editor", RelationFeatures{HasDirect: true}, true),
 	}
 	eligible := ComputeExplainEligibility(analyses)
-	got, err := generateExplainDispatcher(analyses, "", eligible)
+	got, err := generateExplainDispatcher(analyses, "", eligible, DefaultMaxDepth)
 	if err != nil {
 		t.Fatalf("generateExplainDispatcher: %v", err)
 	}
@@ -128,7 +128,7 @@ func TestGenerateExplainDispatcher_Routes(t *testing.T) {
 // Output must still be a structurally valid Trace shape so the runtime can
 // deserialise without special-casing.
 func TestGenerateExplainDispatcher_Empty(t *testing.T) {
-	got, err := generateExplainDispatcher(nil, "", nil)
+	got, err := generateExplainDispatcher(nil, "", nil, DefaultMaxDepth)
 	if err != nil {
 		t.Fatalf("generateExplainDispatcher(nil): %v", err)
 	}
@@ -29,6 +29,8 @@ type (
 	Concat            = sqldsl.Concat
 	Position          = sqldsl.Position
 	Substring         = sqldsl.Substring
+	SplitPart         = sqldsl.SplitPart
+	StrPos            = sqldsl.StrPos
 	Cast              = sqldsl.Cast
 	UsersetNormalized = sqldsl.UsersetNormalized
 
@@ -84,6 +86,7 @@ type (
 	// Function call types
 	FuncCallEq       = sqldsl.FuncCallEq
 	FuncCallNe       = sqldsl.FuncCallNe
+	NamedArg         = sqldsl.NamedArg
 	InFunctionSelect = sqldsl.InFunctionSelect
 	InCTESelect      = sqldsl.InCTESelect
 
@@ -93,6 +96,9 @@ type (
 	ArrayContains = sqldsl.ArrayContains
 	ArrayLength   = sqldsl.ArrayLength
 
+	// Naming types
+	NameCollisions = sqldsl.NameCollisions
+
 	// CTE types
 	CTEDef        = sqldsl.CTEDef
 	WithCTE       = sqldsl.WithCTE
@@ -117,18 +123,22 @@ var (
 	Not                             = sqldsl.Not
 	ExistsExpr                      = sqldsl.ExistsExpr
 	TableAs                         = sqldsl.TableAs
+	TuplesTableName                 = sqldsl.TuplesTableName
+	TuplesTableAs                   = sqldsl.TuplesTableAs
 	TypedClosureValuesTable         = sqldsl.TypedClosureValuesTable
 	TypedUsersetValuesTable         = sqldsl.TypedUsersetValuesTable
 	ClosureTable                    = sqldsl.ClosureTable
 	UsersetTable                    = sqldsl.UsersetTable
 	Ident                           = sqldsl.Ident
 	SafeIdentifier                  = sqldsl.SafeIdentifier
+	RelationIdentifier              = sqldsl.RelationIdentifier
 	RenderBlocks                    = sqldsl.RenderBlocks
 	RenderUnionBlocks               = sqldsl.RenderUnionBlocks
 	IndentLines                     = sqldsl.IndentLines
 	WrapWithPagination              = sqldsl.WrapWithPagination
 	WrapWithPaginationWildcardFirst = sqldsl.WrapWithPaginationWildcardFirst
 	SubjectIDMatch                  = sqldsl.SubjectIDMatch
+	SubjectIDMatchByType            = sqldsl.SubjectIDMatchByType
 	NormalizedUsersetSubject        = sqldsl.NormalizedUsersetSubject
 	SelectAs                        = sqldsl.SelectAs
 	SubjectParams                   = sqldsl.SubjectParams
@@ -150,6 +160,12 @@ var (
 	MultiLineComment                = sqldsl.MultiLineComment
 )
 
+// sqldsl function names that hash case-colliding relations
+var (
+	ListObjectsFunctionNameWithCollisions  = sqldsl.ListObjectsFunctionNameWithCollisions
+	ListSubjectsFunctionNameWithCollisions = sqldsl.ListSubjectsFunctionNameWithCollisions
+)
+
 // analysis types
 type (
 	TypeDefinition         = analysis.TypeDefinition
@@ -165,9 +181,12 @@ type (
 	IntersectionGroupInfo  = analysis.IntersectionGroupInfo
 	IndirectAnchorInfo     = analysis.IndirectAnchorInfo
 	AnchorPathStep         = analysis.AnchorPathStep
+	ConditionInfo          = analysis.ConditionInfo
+	ConditionExpr          = analysis.ConditionExpr
 	RelationAnalysis       = analysis.RelationAnalysis
 	GenerationCapabilities = analysis.GenerationCapabilities
 	ListStrategy           = analysis.ListStrategy
+	NameCollision          = analysis.NameCollision
 )
 
 const (
@@ -178,20 +197,37 @@ const (
 	ListStrategyDepthExceeded  = analysis.ListStrategyDepthExceeded
 	ListStrategySelfRefUserset = analysis.ListStrategySelfRefUserset
 	ListStrategyComposed       = analysis.ListStrategyComposed
+
+	DefaultMaxDepth = analysis.DefaultMaxDepth
 )
 
 var (
-	ComputeRelationClosure = analysis.ComputeRelationClosure
-	AnalyzeRelations       = analysis.AnalyzeRelations
-	ComputeCanGenerate     = analysis.ComputeCanGenerate
-	DetermineListStrategy  = analysis.DetermineListStrategy
-	BuildAnalysisLookup    = analysis.BuildAnalysisLookup
+	ComputeRelationClosure         = analysis.ComputeRelationClosure
+	AnalyzeRelations               = analysis.AnalyzeRelations
+	ComputeCanGenerate             = analysis.ComputeCanGenerate
+	ComputeCanGenerateWithMaxDepth = analysis.ComputeCanGenerateWithMaxDepth
+	DependentRelations             = analysis.DependentRelations
+	DetermineListStrategy          = analysis.DetermineListStrategy
+	BuildAnalysisLookup            = analysis.BuildAnalysisLookup
+	ParseCondition                 = analysis.ParseCondition
+	FindNameCollisions             = analysis.FindNameCollisions
 )
 
+// DefaultTuplesTable is the relation generated SQL reads tuples from unless
+// GenerateSQLOptions.TuplesTable names another.
+const DefaultTuplesTable = sqldsl.DefaultTuplesTable
+
+// SubjectsView is the relation GenerateSQLOptions.ExpandWildcardSubjects
+// reads the known subjects of each type from.
+const SubjectsView = sqldsl.SubjectsView
+
 // tuples types
 type TupleQuery = tuples.TupleQuery
 
-var Tuples = tuples.Tuples
+var (
+	Tuples          = tuples.Tuples
+	TuplesWithTable = tuples.TuplesWithTable
+)
 
 // plpgsql types
 type (
@@ -211,6 +247,24 @@ type (
 	PlpgsqlFunction = plpgsql.PlpgsqlFunction
 	SqlFunction     = plpgsql.SqlFunction
 	ForLoop         = plpgsql.ForLoop
+	Dialect         = plpgsql.Dialect
+)
+
+// SQLSTATE codes raised by the generated functions.
+const (
+	ErrCodeResolutionTooComplex   = plpgsql.ErrCodeResolutionTooComplex
+	ErrCodeRelationNotListable    = plpgsql.ErrCodeRelationNotListable
+	ErrCodeRelationNotExpandable  = plpgsql.ErrCodeRelationNotExpandable
+	ErrCodeSchemaHashMismatch     = plpgsql.ErrCodeSchemaHashMismatch
+	ErrCodeNullArgument           = plpgsql.ErrCodeNullArgument
+	ErrCodeInvalidArgument        = plpgsql.ErrCodeInvalidArgument
+	ErrCodeNotInPrerequisiteState = plpgsql.ErrCodeNotInPrerequisiteState
+)
+
+// Dialects generated SQL can target. See GenerateSQLOptions.Dialect.
+const (
+	DialectPostgres  = plpgsql.DialectPostgres
+	DialectCockroach = plpgsql.DialectCockroach
 )
 
 var (
@@ -222,6 +276,12 @@ var (
 	ListSubjectsFunctionHeader = plpgsql.ListSubjectsFunctionHeader
 	ListObjectsDispatcherArgs  = plpgsql.ListObjectsDispatcherArgs
 	ListSubjectsDispatcherArgs = plpgsql.ListSubjectsDispatcherArgs
+	ConsistencyArg             = plpgsql.ConsistencyArg
+	CallArgs                   = plpgsql.CallArgs
+	ForwardArgs                = plpgsql.ForwardArgs
+	CommentOnFunction          = plpgsql.CommentOnFunction
+	Dialects                   = plpgsql.Dialects
+	DropFunction               = plpgsql.DropFunction
 )
 
 // inline types
@@ -233,6 +293,9 @@ var (
 	BuildUsersetTypedRows = inline.BuildUsersetTypedRows
 )
 
+// paginationCursor is p_after with an empty cursor read as none.
+const paginationCursor = sqldsl.PaginationCursor
+
 // Materialized-CTE-aware pagination helpers used by render functions. Render
 // callers pass plan.MaterializeCTEs() which reflects the
 // GenerateSQLOptions.EnableMaterializedCTEs opt-in (default false: PG decides
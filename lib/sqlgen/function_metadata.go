@@ -0,0 +1,122 @@
+package sqlgen
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Function kinds recorded in FunctionMetadata.Kind.
+const (
+	FunctionKindCheck             = "check"
+	FunctionKindCheckNoWildcard   = "check_nw"
+	FunctionKindCheckContext      = "check_ctx"
+	FunctionKindCheckStrict       = "check_strict"
+	FunctionKindExplain           = "explain"
+	FunctionKindExpand            = "expand"
+	FunctionKindListObjects       = "list_objects"
+	FunctionKindListSubjects      = "list_subjects"
+	FunctionKindListObjectsCursor = "list_objects_cursor"
+)
+
+// functionMetadataPrefix starts every metadata comment, so comments written
+// by hand or by other tools are never mistaken for melange metadata.
+const functionMetadataPrefix = "melange: "
+
+// FunctionMetadata describes how a per-relation function was generated. It is
+// attached to the function as a COMMENT ON FUNCTION, so the strategy behind a
+// deployed function can be read from pg_description (e.g. \df+ in psql)
+// without access to the schema that produced it. It is also the lookup from a
+// name SafeIdentifier shortened with a hash back to its type and relation.
+type FunctionMetadata struct {
+	Kind       string `json:"kind"`
+	ObjectType string `json:"object_type"`
+	Relation   string `json:"relation"`
+	// Features is RelationFeatures.String() for the relation.
+	Features string `json:"features"`
+	// ListStrategy is the list strategy chosen for the relation. Empty when
+	// list functions are not generated for it.
+	ListStrategy string `json:"list_strategy,omitempty"`
+	// ClosureRelations are the satisfying relations inlined into the
+	// function's relation filters.
+	ClosureRelations []string `json:"closure_relations,omitempty"`
+}
+
+// NewFunctionMetadata returns the metadata for the kind function of a.
+func NewFunctionMetadata(a RelationAnalysis, kind string) FunctionMetadata {
+	md := FunctionMetadata{
+		Kind:             kind,
+		ObjectType:       a.ObjectType,
+		Relation:         a.Relation,
+		Features:         a.Features.String(),
+		ClosureRelations: a.SatisfyingRelations,
+	}
+	if a.Capabilities.ListAllowed {
+		md.ListStrategy = a.ListStrategy.String()
+	}
+	return md
+}
+
+// Comment renders md as the text of a COMMENT ON FUNCTION.
+func (md FunctionMetadata) Comment() string {
+	// Marshalling a struct of strings cannot fail.
+	b, _ := json.Marshal(md)
+	return functionMetadataPrefix + string(b)
+}
+
+// Equal reports whether md and other describe the same generated function.
+func (md FunctionMetadata) Equal(other FunctionMetadata) bool {
+	return md.Comment() == other.Comment()
+}
+
+// ParseFunctionMetadata parses a comment written by FunctionMetadata.Comment.
+// ok is false for any other comment, including none.
+func ParseFunctionMetadata(comment string) (md FunctionMetadata, ok bool) {
+	text, found := strings.CutPrefix(comment, functionMetadataPrefix)
+	if !found {
+		return FunctionMetadata{}, false
+	}
+	if err := json.Unmarshal([]byte(text), &md); err != nil {
+		return FunctionMetadata{}, false
+	}
+	return md, true
+}
+
+// ExpectedFunctionMetadata returns the metadata comment each per-relation
+// function generated from analyses carries, keyed by function name.
+// Dispatchers and the opt-in evidence functions carry no metadata; the
+// opt-in strict variants carry it but are left out, as they are installed
+// only when enabled.
+func ExpectedFunctionMetadata(analyses []RelationAnalysis) map[string]FunctionMetadata {
+	needsNW := buildNoWildcardIndex(analyses)
+	explainEligible := ComputeExplainEligibility(analyses)
+	expandEligible := ComputeExpandEligibility(analyses)
+	expected := make(map[string]FunctionMetadata)
+	for _, a := range analyses {
+		if a.Capabilities.CheckAllowed {
+			expected[functionName(a.NameCollisions, a.ObjectType, a.Relation)] = NewFunctionMetadata(a, FunctionKindCheck)
+			if needsNW[a.ObjectType][a.Relation] {
+				expected[functionNameNoWildcard(a.NameCollisions, a.ObjectType, a.Relation)] = NewFunctionMetadata(a, FunctionKindCheckNoWildcard)
+			}
+			if len(a.Conditions) > 0 {
+				expected[contextFunctionName(a.NameCollisions, a.ObjectType, a.Relation)] = NewFunctionMetadata(a, FunctionKindCheckContext)
+			}
+			if expandEligible[a.ObjectType][a.Relation] {
+				expected[expandFunctionName(a.NameCollisions, a.ObjectType, a.Relation)] = NewFunctionMetadata(a, FunctionKindExpand)
+			}
+			if explainEligible[a.ObjectType][a.Relation] {
+				expected[explainFunctionName(a.NameCollisions, a.ObjectType, a.Relation)] = NewFunctionMetadata(a, FunctionKindExplain)
+			}
+		}
+		if a.Capabilities.ListAllowed {
+			expected[listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation)] = NewFunctionMetadata(a, FunctionKindListObjects)
+			expected[listSubjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation)] = NewFunctionMetadata(a, FunctionKindListSubjects)
+		}
+	}
+	return expected
+}
+
+// withFunctionMetadata appends the COMMENT ON FUNCTION carrying a's metadata
+// to fn, the CREATE statement of the kind function named name.
+func withFunctionMetadata(fn string, a RelationAnalysis, kind, name, databaseSchema string, args []FuncArg) string {
+	return fn + "\n" + CommentOnFunction(databaseSchema, name, args, NewFunctionMetadata(a, kind).Comment())
+}
@@ -0,0 +1,84 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFunctionMetadata_RoundTrip(t *testing.T) {
+	a := conditionAnalysis(t)
+	md := NewFunctionMetadata(a, FunctionKindCheck)
+	if md.Features != "Direct+Implied+Condition" || md.ListStrategy != "" {
+		t.Errorf("metadata = %+v", md)
+	}
+
+	got, ok := ParseFunctionMetadata(md.Comment())
+	if !ok || !got.Equal(md) {
+		t.Errorf("ParseFunctionMetadata(%q) = %+v, %v", md.Comment(), got, ok)
+	}
+	for _, comment := range []string{"", "hand-written note", "melange: not json"} {
+		if _, ok := ParseFunctionMetadata(comment); ok {
+			t.Errorf("ParseFunctionMetadata(%q) accepted a foreign comment", comment)
+		}
+	}
+}
+
+func TestGenerateSQL_CommentsFunctions(t *testing.T) {
+	a := conditionAnalysis(t)
+	gen, err := GenerateSQL([]RelationAnalysis{a}, InlineSQLData{}, "authz")
+	if err != nil {
+		t.Fatalf("GenerateSQL: %v", err)
+	}
+	fn := gen.Functions[0]
+	assertContains(t, fn, `COMMENT ON FUNCTION "authz"."check_doc_viewer"(TEXT, TEXT, TEXT, TEXT []) IS 'melange: {"kind":"check","object_type":"doc","relation":"viewer","features":"Direct+Implied+Condition","closure_relations":["viewer","owner"]}';`)
+	if strings.Index(fn, "COMMENT ON") < strings.Index(fn, "$$ LANGUAGE") {
+		t.Errorf("comment must follow the CREATE statement:\n%s", fn)
+	}
+
+	// Every per-relation function carries the comment ExpectedFunctionMetadata
+	// predicts, so doctor can compare them.
+	named := CollectNamedFunctions(gen, ListGeneratedSQL{}, []RelationAnalysis{a})
+	sqlByName := make(map[string]string, len(named))
+	for _, nf := range named {
+		sqlByName[nf.Name] = nf.SQL
+	}
+	expected := ExpectedFunctionMetadata([]RelationAnalysis{a})
+	for _, name := range []string{"check_doc_viewer", "check_doc_viewer_ctx", "explain_doc_viewer", "expand_doc_viewer"} {
+		md, ok := expected[name]
+		if !ok {
+			t.Errorf("ExpectedFunctionMetadata missing %s", name)
+			continue
+		}
+		assertContains(t, sqlByName[name], `COMMENT ON FUNCTION "authz"."`+name+`"(`)
+		assertContains(t, sqlByName[name], md.Comment())
+	}
+	if len(expected) != 4 {
+		t.Errorf("ExpectedFunctionMetadata = %v, want 4 functions", expected)
+	}
+}
+
+// Names over PostgreSQL's identifier limit are shortened with a hash; the
+// dispatcher routes to the same shortened name and the metadata comment
+// records the original type and relation.
+func TestGenerateSQL_LongNames(t *testing.T) {
+	a := conditionAnalysis(t)
+	a.ObjectType = "organization_" + strings.Repeat("x", 40)
+	a.Relation = "can_view_" + strings.Repeat("y", 40)
+	a.Conditions = nil
+	a.Features.HasCondition = false
+
+	gen, err := GenerateSQL([]RelationAnalysis{a}, InlineSQLData{}, "")
+	if err != nil {
+		t.Fatalf("GenerateSQL: %v", err)
+	}
+	name := functionName(nil, a.ObjectType, a.Relation)
+	if len(name) > 63 {
+		t.Fatalf("functionName = %q (%d bytes), want at most 63", name, len(name))
+	}
+	if again := functionName(nil, a.ObjectType, a.Relation); again != name {
+		t.Errorf("functionName not deterministic: %q then %q", name, again)
+	}
+	assertContains(t, gen.Functions[0], "CREATE OR REPLACE FUNCTION "+name+"(")
+	assertContains(t, gen.Functions[0], `"object_type":"`+a.ObjectType+`","relation":"`+a.Relation+`"`)
+	assertContains(t, gen.Dispatcher, name+"(")
+}
@@ -0,0 +1,89 @@
+package sqlgen
+
+import "slices"
+
+// callOrder returns analyses reordered so every relation comes after the
+// relations its generated functions call, per relationReferences: a
+// check_{type}_{relation} body is emitted after the check functions it
+// names, and likewise for the list, explain, expand and strict variants.
+// generateSQL, GenerateListSQL and CollectNamedFunctions all walk this order,
+// so the per-relation function slices, the named-function pairing and the
+// order the migrator applies them in agree.
+//
+// The call graph can be cyclic: recursive TTU parents, self-referential
+// usersets, and relations that reach each other through
+// check_permission_internal. Each strongly connected component is emitted as
+// one block, after everything it calls and in input order within the block.
+// No statement inside a cycle can be created after all of its callees, so
+// this relies on every function that can sit on a cycle being LANGUAGE
+// plpgsql, whose body PostgreSQL resolves at first call rather than at
+// CREATE. The LANGUAGE sql functions, which check_function_bodies validates
+// at CREATE, only call functions the migrator applies before them (see
+// Migrator.applyGeneratedSQL).
+//
+// The result is deterministic for a given input order. Relations outside
+// analyses (unknown references) are ignored.
+func callOrder(analyses []RelationAnalysis) []RelationAnalysis {
+	index := make(map[string]int, len(analyses))
+	for i, a := range analyses {
+		index[a.ObjectType+"."+a.Relation] = i
+	}
+	callees := make([][]int, len(analyses))
+	for i := range analyses {
+		for _, ref := range relationReferences(&analyses[i]) {
+			if j, ok := index[ref]; ok {
+				callees[i] = append(callees[i], j)
+			}
+		}
+	}
+
+	// Tarjan's algorithm completes a component only after every component it
+	// reaches, which is exactly callees-first.
+	var (
+		next    int
+		order   = make([]int, len(analyses)) // discovery index + 1; 0 = unvisited
+		low     = make([]int, len(analyses))
+		onStack = make([]bool, len(analyses))
+		stack   []int
+		sorted  = make([]RelationAnalysis, 0, len(analyses))
+	)
+	var visit func(int)
+	visit = func(v int) {
+		next++
+		order[v], low[v] = next, next
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range callees[v] {
+			switch {
+			case order[w] == 0:
+				visit(w)
+				low[v] = min(low[v], low[w])
+			case onStack[w]:
+				low[v] = min(low[v], order[w])
+			}
+		}
+		if low[v] != order[v] {
+			return
+		}
+		var component []int
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		slices.Sort(component)
+		for _, i := range component {
+			sorted = append(sorted, analyses[i])
+		}
+	}
+	for v := range analyses {
+		if order[v] == 0 {
+			visit(v)
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,106 @@
+package sqlgen
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func relationKeys(analyses []RelationAnalysis) []string {
+	keys := make([]string, len(analyses))
+	for i, a := range analyses {
+		keys[i] = a.ObjectType + "." + a.Relation
+	}
+	return keys
+}
+
+func TestCallOrder(t *testing.T) {
+	analyses := []RelationAnalysis{
+		{ObjectType: "document", Relation: "viewer", SatisfyingRelations: []string{"viewer", "editor"},
+			ParentRelations: []ParentRelationInfo{{Relation: "viewer", LinkingRelation: "parent", AllowedLinkingTypes: []string{"folder"}}}},
+		{ObjectType: "document", Relation: "editor", SatisfyingRelations: []string{"editor", "owner"}},
+		{ObjectType: "document", Relation: "owner"},
+		// folder.viewer and team.member call each other.
+		{ObjectType: "folder", Relation: "viewer",
+			UsersetPatterns: []UsersetPattern{{SubjectType: "team", SubjectRelation: "member"}}},
+		{ObjectType: "team", Relation: "member",
+			ParentRelations: []ParentRelationInfo{{Relation: "viewer", LinkingRelation: "space", AllowedLinkingTypes: []string{"folder", "unknown"}}}},
+	}
+
+	got := relationKeys(callOrder(analyses))
+	want := []string{"document.owner", "document.editor", "folder.viewer", "team.member", "document.viewer"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("callOrder = %v, want %v", got, want)
+	}
+	if again := relationKeys(callOrder(analyses)); !slices.Equal(again, got) {
+		t.Errorf("callOrder is not deterministic: %v then %v", got, again)
+	}
+}
+
+func TestGenerateSQL_EmitsCalleesFirst(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, `
+model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define viewer: [user] or editor or viewer from parent
+    define editor: [user] or owner
+    define owner: [user]
+    define parent: [workspace]
+
+type workspace
+  relations
+    define viewer: [user, team#member]
+
+type team
+  relations
+    define member: [user]
+`)
+
+	// ComputeCanGenerate already sorts by its own dependency edges; reverse
+	// it so the order has to come from callOrder.
+	slices.Reverse(analyses)
+
+	gen, err := GenerateSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatalf("GenerateSQL: %v", err)
+	}
+	list, err := GenerateListSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatalf("GenerateListSQL: %v", err)
+	}
+
+	position := func(fns []string, name string) int {
+		for i, fn := range fns {
+			if strings.Contains(fn, "FUNCTION "+name+"(") {
+				return i
+			}
+		}
+		t.Fatalf("%s not generated", name)
+		return -1
+	}
+	for _, c := range []struct {
+		fns  []string
+		name func(collisions NameCollisions, objectType, relation string) string
+	}{
+		{gen.Functions, functionName},
+		{list.ListObjectsFunctions, listObjectsFunctionName},
+	} {
+		viewer := position(c.fns, c.name(nil, "document", "viewer"))
+		for _, callee := range [][2]string{{"document", "editor"}, {"document", "owner"}, {"workspace", "viewer"}} {
+			if p := position(c.fns, c.name(nil, callee[0], callee[1])); p > viewer {
+				t.Errorf("%s emitted at %d, after its caller at %d", c.name(nil, callee[0], callee[1]), p, viewer)
+			}
+		}
+	}
+
+	// The named pairing walks the same order.
+	for _, nf := range CollectNamedFunctions(gen, list, analyses) {
+		if strings.HasPrefix(nf.Name, "check_") && !strings.Contains(nf.SQL, "FUNCTION "+nf.Name+"(") {
+			t.Errorf("%s paired with the wrong SQL", nf.Name)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package sqlgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// GenerationCache reuses per-relation function bodies across GenerateSQLCached
+// calls. Each body is keyed by a fingerprint of everything its generator
+// reads, so when only one relation of a large schema changes, only the
+// functions whose inputs changed are rendered again.
+//
+// The cache keeps the entries used by the most recent call and drops the
+// rest, so it holds one schema's worth of SQL however many times it is
+// reused. It is safe for concurrent use, but concurrent calls for different
+// schemas evict each other's entries.
+type GenerationCache struct {
+	mu      sync.Mutex
+	entries map[string]string
+	used    map[string]bool
+	hits    int
+	misses  int
+}
+
+// NewGenerationCache returns an empty cache.
+func NewGenerationCache() *GenerationCache {
+	return &GenerationCache{entries: make(map[string]string)}
+}
+
+// Stats returns the number of function bodies served from the cache and
+// rendered afresh since the cache was created.
+func (c *GenerationCache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Len returns the number of cached function bodies.
+func (c *GenerationCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// GenerateSQLCached is GenerateSQLWithOptions with the check, no-wildcard,
+// context, expand and explain function of each relation served from cache
+// when its fingerprint matches a cached entry. The output is byte-identical to
+// GenerateSQLWithOptions. Dispatchers and the other schema-wide functions
+// read every relation and are always rendered, as are the opt-in evidence
+// functions. A nil cache disables caching.
+func GenerateSQLCached(analyses []RelationAnalysis, inline InlineSQLData, databaseSchema string, opts GenerateSQLOptions, cache *GenerationCache) (GeneratedSQL, error) {
+	result, err := generateSQL(analyses, inline, databaseSchema, opts, cache)
+	if err == nil {
+		cache.prune()
+	}
+	return result, err
+}
+
+// fetch returns the body cached under key, rendering and storing it with
+// render on a miss. A nil cache always renders.
+func (c *GenerationCache) fetch(key string, render func() (string, error)) (string, error) {
+	if c == nil {
+		return render()
+	}
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]string)
+	}
+	if c.used == nil {
+		c.used = make(map[string]bool)
+	}
+	c.used[key] = true
+	if sql, ok := c.entries[key]; ok {
+		c.hits++
+		c.mu.Unlock()
+		return sql, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	sql, err := render()
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.entries[key] = sql
+	c.mu.Unlock()
+	return sql, nil
+}
+
+// prune drops the entries the last generation did not use, such as bodies of
+// relations that were since removed or changed.
+func (c *GenerationCache) prune() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if !c.used[key] {
+			delete(c.entries, key)
+		}
+	}
+	c.used = nil
+}
+
+// relationFingerprint is the input of one per-relation generator, encoded
+// to JSON and hashed by fingerprint.
+type relationFingerprint struct {
+	// Kind names the generator ("check", "check_nw", "check_ctx", "expand",
+	// "explain").
+	Kind string
+
+	Analysis       RelationAnalysis
+	DatabaseSchema string
+	TuplesTable    string
+	PoolerSafe     bool
+	MaxDepth       int
+	NullGuards     bool
+
+	// ClosureRows and UsersetRows are the rendered inline rows left by
+	// filterInlineForCheck, the only ones the check and explain bodies embed.
+	// ClosureFunction bodies embed none and call the lookup functions
+	// instead.
+	ClosureRows     []string
+	UsersetRows     []string
+	ClosureFunction bool
+
+	// Complexity and NeedsNoWildcard are the rows of the schema-wide indexes
+	// the check plan reads: the relation's own type, plus every type a TTU
+	// link can reach (parentRelationScore). Keeping the rest out is what lets
+	// an unrelated relation change without missing here.
+	Complexity      map[string]map[string]int
+	NeedsNoWildcard map[string]bool
+}
+
+// fingerprint returns the cache key for the generator kind applied to a.
+func fingerprint(kind string, a RelationAnalysis, inline InlineSQLData, databaseSchema, tuplesTable string, complexityByRelation map[string]map[string]int, needsNW map[string]map[string]bool, poolerSafe bool, maxDepth int, nullGuards bool) (string, error) {
+	filtered := filterInlineForCheck(inline, a)
+	fp := relationFingerprint{
+		Kind:            kind,
+		Analysis:        a,
+		DatabaseSchema:  databaseSchema,
+		TuplesTable:     tuplesTable,
+		PoolerSafe:      poolerSafe,
+		MaxDepth:        maxDepth,
+		NullGuards:      nullGuards,
+		ClosureRows:     renderValuesRows(filtered.ClosureRows),
+		UsersetRows:     renderValuesRows(filtered.UsersetRows),
+		ClosureFunction: filtered.ClosureFunction,
+		Complexity:      make(map[string]map[string]int),
+		NeedsNoWildcard: needsNW[a.ObjectType],
+	}
+	fp.Complexity[a.ObjectType] = complexityByRelation[a.ObjectType]
+	for _, p := range fingerprintParentRelations(a) {
+		for _, t := range p.AllowedLinkingTypes {
+			fp.Complexity[t] = complexityByRelation[t]
+		}
+	}
+
+	encoded, err := json.Marshal(fp)
+	if err != nil {
+		return "", fmt.Errorf("fingerprinting %s.%s: %w", a.ObjectType, a.Relation, err)
+	}
+	h := sha256.Sum256(encoded)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// fingerprintParentRelations returns every TTU the analysis carries, wherever
+// it appears.
+func fingerprintParentRelations(a RelationAnalysis) []ParentRelationInfo {
+	parents := make([]ParentRelationInfo, 0, len(a.ParentRelations))
+	parents = append(parents, a.ParentRelations...)
+	parents = append(parents, a.ExcludedParentRelations...)
+	parents = append(parents, a.ClosureParentRelations...)
+	for _, groups := range [][]IntersectionGroupInfo{a.IntersectionGroups, a.ExcludedIntersectionGroups} {
+		for _, g := range groups {
+			for _, part := range g.Parts {
+				if part.ParentRelation != nil {
+					parents = append(parents, *part.ParentRelation)
+				}
+			}
+		}
+	}
+	return parents
+}
+
+func renderValuesRows(rows []ValuesRow) []string {
+	out := make([]string, len(rows))
+	for i, row := range rows {
+		out[i] = row.SQL()
+	}
+	return out
+}
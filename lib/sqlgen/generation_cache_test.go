@@ -0,0 +1,158 @@
+package sqlgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+const cacheTestSchema = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type org
+  relations
+    define parent: [org]
+    define viewer: [user]
+
+type folder
+  relations
+    define parent: [folder]
+    define owner: [user]
+    define viewer: [user, user:*, group#member] or owner or viewer from parent
+
+type document
+  relations
+    define parent: [folder]
+    define org: [org]
+    define owner: [user]
+    define editor: [user] or owner
+    define viewer: [user] or editor or viewer from parent or viewer from org
+    define blocked: [user]
+    define can_view: viewer but not blocked
+    define can_share: editor and owner from parent
+`
+
+// compileForCacheTest runs the same analysis pipeline as the migrator.
+func compileForCacheTest(t *testing.T, src string) ([]RelationAnalysis, InlineSQLData) {
+	t.Helper()
+	types, err := parser.ParseSchemaString(src)
+	if err != nil {
+		t.Fatalf("parsing schema: %v", err)
+	}
+	closure := schema.ComputeRelationClosure(types)
+	analyses := ComputeCanGenerate(AnalyzeRelations(types, closure))
+	return analyses, BuildInlineSQLData(closure, analyses)
+}
+
+// generateCachedAndFresh generates src through cache and without it, and
+// fails unless both outputs are byte-identical.
+func generateCachedAndFresh(t *testing.T, src string, opts GenerateSQLOptions, cache *GenerationCache) GeneratedSQL {
+	t.Helper()
+	analyses, inline := compileForCacheTest(t, src)
+	fresh, err := GenerateSQLWithOptions(analyses, inline, "authz", opts)
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	cached, err := GenerateSQLCached(analyses, inline, "authz", opts, cache)
+	if err != nil {
+		t.Fatalf("GenerateSQLCached: %v", err)
+	}
+	if !reflect.DeepEqual(fresh, cached) {
+		t.Fatal("cached output differs from fresh output")
+	}
+	return cached
+}
+
+func TestGenerateSQLCached(t *testing.T) {
+	cache := NewGenerationCache()
+
+	out := generateCachedAndFresh(t, cacheTestSchema, GenerateSQLOptions{}, cache)
+	perRelation := len(out.Functions) + len(out.NoWildcardFunctions) + len(out.ExpandFunctions) + len(out.ExplainFunctions)
+	if hits, misses := cache.Stats(); hits != 0 || misses == 0 {
+		t.Fatalf("cold cache: hits=%d misses=%d, want only misses", hits, misses)
+	}
+	size := cache.Len()
+	if size < perRelation {
+		t.Fatalf("cache holds %d entries, want at least %d", size, perRelation)
+	}
+
+	// Regenerating the same schema renders nothing.
+	_, missesBefore := cache.Stats()
+	generateCachedAndFresh(t, cacheTestSchema, GenerateSQLOptions{}, cache)
+	if _, misses := cache.Stats(); misses != missesBefore {
+		t.Errorf("unchanged schema rendered %d functions, want 0", misses-missesBefore)
+	}
+
+	// Changing one relation re-renders its own functions and those that read
+	// it, but not unrelated types.
+	changed := strings.Replace(cacheTestSchema, "define blocked: [user]", "define blocked: [user, group#member]", 1)
+	_, missesBefore = cache.Stats()
+	hitsBefore, _ := cache.Stats()
+	generateCachedAndFresh(t, changed, GenerateSQLOptions{}, cache)
+	hits, misses := cache.Stats()
+	if misses == missesBefore {
+		t.Error("changed relation was served from cache")
+	}
+	if hits == hitsBefore {
+		t.Error("no unchanged relation was served from cache")
+	}
+
+	// Entries for the old definition are dropped.
+	if cache.Len() != size {
+		t.Errorf("cache holds %d entries after the change, want %d", cache.Len(), size)
+	}
+}
+
+// Every option that changes a per-relation body is part of the fingerprint,
+// so switching options on a warm cache still matches fresh output.
+func TestGenerateSQLCached_Options(t *testing.T) {
+	cache := NewGenerationCache()
+	for _, opts := range []GenerateSQLOptions{
+		{},
+		{PoolerSafe: true},
+		{EnableCheckMemo: true},
+		{TableRoutedDispatcher: true},
+		{EnableCheckEvidence: true, EnableEffectiveAccess: true},
+		{},
+	} {
+		generateCachedAndFresh(t, cacheTestSchema, opts, cache)
+	}
+}
+
+// Schema edits that only change another relation's complexity or wildcard
+// reachability must still reach the functions that read it.
+func TestGenerateSQLCached_CrossRelationInputs(t *testing.T) {
+	cache := NewGenerationCache()
+	for _, edit := range [][2]string{
+		// folder.viewer loses its wildcard, changing folder's _nw routing.
+		{"define viewer: [user, user:*, group#member] or owner or viewer from parent", "define viewer: [user, group#member] or owner or viewer from parent"},
+		// org.viewer becomes recursive, reordering document.viewer's TTUs.
+		{"    define viewer: [user]\n\ntype folder", "    define viewer: [user] or viewer from parent\n\ntype folder"},
+		// document.editor gains a userset, changing its closure rows.
+		{"define editor: [user] or owner", "define editor: [user, group#member] or owner"},
+	} {
+		if !strings.Contains(cacheTestSchema, edit[0]) {
+			t.Fatalf("edit %q does not apply", edit[0])
+		}
+		// Warm the cache with the unedited schema, as pruning keeps only the
+		// previous call's entries.
+		generateCachedAndFresh(t, cacheTestSchema, GenerateSQLOptions{}, cache)
+		generateCachedAndFresh(t, strings.Replace(cacheTestSchema, edit[0], edit[1], 1), GenerateSQLOptions{}, cache)
+	}
+}
+
+func TestGenerateSQLCached_NilCache(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, cacheTestSchema)
+	if _, err := GenerateSQLCached(analyses, inline, "", GenerateSQLOptions{}, nil); err != nil {
+		t.Fatalf("GenerateSQLCached with nil cache: %v", err)
+	}
+}
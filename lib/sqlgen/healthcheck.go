@@ -0,0 +1,66 @@
+package sqlgen
+
+import (
+	"slices"
+
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+)
+
+// HealthcheckFunctionName is the health function emitted with every
+// migration. Callers tracking installed functions must expect it.
+const HealthcheckFunctionName = "melange_healthcheck"
+
+// GenerateHealthcheckFunction renders melange_healthcheck(), which returns a
+// JSONB object for monitors that can run SQL but not the melange CLI:
+//
+//   - tuples_table_exists: whether the tuples relation resolves
+//   - function_count: how many of functionNames are installed in the
+//     database schema, counting overloads once
+//   - expected_function_count: how many functionNames there are
+//   - schema_hash: schemaHash, the SchemaHash of the model it was generated
+//     from
+//
+// GenerateSQL cannot render it: the hash and the full set of function names
+// are only known to the migrator, so the migrator and the migration compiler
+// call this once they have both and store the result in
+// GeneratedSQL.HealthcheckFunction. functionNames should include
+// HealthcheckFunctionName. With no databaseSchema the function has no
+// search_path of its own and counts functions in the caller's current schema.
+func GenerateHealthcheckFunction(databaseSchema, tuplesTable, schemaHash string, functionNames []string, dialect Dialect) string {
+	names := slices.Clone(functionNames)
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	installed := SelectStmt{
+		ColumnExprs: []Expr{Raw("count(DISTINCT p.proname)::INTEGER")},
+		FromExpr:    TableAs("pg_catalog", "pg_proc", "p"),
+		Joins: []JoinClause{{
+			Type:      "INNER",
+			TableExpr: TableAs("pg_catalog", "pg_namespace", "n"),
+			On:        Eq{Left: Col{Table: "n", Column: "oid"}, Right: Col{Table: "p", Column: "pronamespace"}},
+		}},
+		Where: And(
+			Eq{Left: Col{Table: "n", Column: "nspname"}, Right: Raw(sqldsl.PostgresSchemaExpr(databaseSchema))},
+			In{Expr: Col{Table: "p", Column: "proname"}, Values: names},
+		),
+	}
+	body := SelectStmt{
+		ColumnExprs: []Expr{Func{Name: "jsonb_build_object", Args: []Expr{
+			Lit("tuples_table_exists"), IsNotNull{Expr: Func{Name: "to_regclass", Args: []Expr{Lit(TuplesTableName(tuplesTable))}}},
+			Lit("function_count"), Paren{Expr: installed},
+			Lit("expected_function_count"), Int(len(names)),
+			Lit("schema_hash"), Lit(schemaHash),
+		}}},
+	}
+
+	fn := SqlFunction{
+		Schema:  databaseSchema,
+		Name:    HealthcheckFunctionName,
+		Returns: "JSONB",
+		Body:    body,
+		Header: []string{
+			"Generated health check: tuples relation, installed functions and schema hash",
+		},
+	}
+	return dialect.Adapt(fn.SQL()) + "\n"
+}
@@ -0,0 +1,59 @@
+package sqlgen
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHealthcheck_ReportsTablesFunctionsAndHash(t *testing.T) {
+	names := []string{"check_permission", HealthcheckFunctionName, "check_permission", "list_accessible_objects"}
+	sql := GenerateHealthcheckFunction("authz", "", "abc123", names, "")
+
+	assertContains(t, sql, `CREATE OR REPLACE FUNCTION "authz"."melange_healthcheck"(`)
+	assertContains(t, sql, "RETURNS JSONB")
+	assertContains(t, sql, "'tuples_table_exists', to_regclass('melange_tuples') IS NOT NULL")
+	assertContains(t, sql, "n.nspname = 'authz'")
+	assertContains(t, sql, "p.proname IN ('check_permission', 'list_accessible_objects', 'melange_healthcheck')")
+	assertContains(t, sql, "'expected_function_count', 3")
+	assertContains(t, sql, "'schema_hash', 'abc123'")
+	assertContains(t, sql, "LANGUAGE sql STABLE")
+	assertContains(t, sql, "SET search_path = 'authz'")
+}
+
+func TestHealthcheck_CustomTuplesTableAndNoSchema(t *testing.T) {
+	sql := GenerateHealthcheckFunction("", "authz_tuples", "h", []string{HealthcheckFunctionName}, "")
+
+	assertContains(t, sql, "CREATE OR REPLACE FUNCTION melange_healthcheck(")
+	assertContains(t, sql, "to_regclass('authz_tuples')")
+	assertContains(t, sql, "n.nspname = current_schema()")
+	assertNotContains(t, sql, "search_path")
+}
+
+func TestHealthcheck_CockroachDialect(t *testing.T) {
+	sql := GenerateHealthcheckFunction("authz", "", "h", []string{HealthcheckFunctionName}, DialectCockroach)
+	for _, unsupported := range []string{"PARALLEL ", "SET search_path"} {
+		assertNotContains(t, sql, unsupported)
+	}
+}
+
+func TestHealthcheck_FilledByCaller(t *testing.T) {
+	analyses := checkEvidenceAnalyses()
+	generated, err := GenerateSQL(analyses, InlineSQLData{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if generated.HealthcheckFunction != "" {
+		t.Error("GenerateSQL should leave the healthcheck function to the migrator")
+	}
+	names := CollectFunctionNames(analyses)
+	if !slices.Contains(names, HealthcheckFunctionName) {
+		t.Errorf("%s missing from function names", HealthcheckFunctionName)
+	}
+
+	generated.HealthcheckFunction = GenerateHealthcheckFunction("", "", "h", names, "")
+	if !slices.ContainsFunc(CollectDispatcherFunctions(generated, ListGeneratedSQL{}), func(nf NamedFunction) bool {
+		return nf.Name == HealthcheckFunctionName
+	}) {
+		t.Errorf("%s missing from dispatcher functions", HealthcheckFunctionName)
+	}
+}
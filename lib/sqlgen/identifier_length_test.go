@@ -29,7 +29,7 @@ func TestGeneratedFunctionNameBuildersWithinIdentifierLimit(t *testing.T) {
 	}
 	builders := []struct {
 		name string
-		fn   func(objectType, relation string) string
+		fn   func(collisions NameCollisions, objectType, relation string) string
 	}{
 		{"check", functionName},
 		{"check_nw", functionNameNoWildcard},
@@ -43,7 +43,7 @@ func TestGeneratedFunctionNameBuildersWithinIdentifierLimit(t *testing.T) {
 		// A given (type, relation) must map to distinct names across families.
 		seen := map[string]string{}
 		for _, b := range builders {
-			got := b.fn(c.typ, c.rel)
+			got := b.fn(nil, c.typ, c.rel)
 			if len(got) > sqldsl.PostgresMaxIdentifierLength {
 				t.Errorf("%s(%q, %q) len=%d exceeds limit %d: %q",
 					b.name, c.typ, c.rel, len(got), sqldsl.PostgresMaxIdentifierLength, got)
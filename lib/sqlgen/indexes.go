@@ -14,8 +14,9 @@ import (
 // source tables back the UNION ALL branches of their view, since PostgreSQL
 // cannot create indexes on views directly.
 type IndexRecommendation struct {
-	// BaseTable is the table the DDL targets. Always "melange_tuples" in the
-	// schema-driven flow; doctor integration may rewrite to source tables.
+	// BaseTable is the table the DDL targets: the configured tuples relation
+	// (melange_tuples by default) in the schema-driven flow; doctor
+	// integration may rewrite to source tables.
 	BaseTable string
 
 	// Columns is the ordered composite — earliest-most-selective first.
@@ -32,6 +33,14 @@ type IndexRecommendation struct {
 	DDL string
 }
 
+// Partial-index predicates emitted by RecommendIndexes. The userset predicate
+// matches the guard the generated SQL puts on userset lookups, so PostgreSQL
+// can prove the index applies.
+const (
+	wildcardIndexPredicate = "subject_id = '*'"
+	usersetIndexPredicate  = "position('#' in subject_id) > 0"
+)
+
 // indexShape is the dedup key for a recommendation.
 type indexShape struct {
 	columns string // joined by comma
@@ -51,10 +60,25 @@ type indexShape struct {
 //
 // Relations with HasWildcard additionally get a partial index over wildcard
 // rows so wildcard membership lookups don't scan the whole subject_id space.
+// Relations with userset patterns, their own or through implied relations,
+// get two partial indexes over userset rows (subject_id containing '#'): an
+// object-keyed one for the userset branches of check_* and list_*_sub, and an
+// object-type-keyed one for the membership joins of list_*_obj, which scan
+// every userset grant of a relation without an object_id. Userset rows are
+// usually a small fraction of the table, so both stay small, and schemas
+// without usersets get neither.
 //
 // Recommendations are deduplicated: an index that benefits multiple functions
 // appears once with all function names in BenefitsFunctions.
+//
+// The DDL targets DefaultTuplesTable; see RecommendIndexesWithTable.
 func RecommendIndexes(analyses []RelationAnalysis) []IndexRecommendation {
+	return RecommendIndexesWithTable(analyses, "")
+}
+
+// RecommendIndexesWithTable is RecommendIndexes with DDL targeting
+// tuplesTable, or DefaultTuplesTable when it is empty.
+func RecommendIndexesWithTable(analyses []RelationAnalysis, tuplesTable string) []IndexRecommendation {
 	// Accumulate per shape; merge function lists across relations.
 	byShape := make(map[indexShape]*IndexRecommendation)
 
@@ -63,7 +87,7 @@ func RecommendIndexes(analyses []RelationAnalysis) []IndexRecommendation {
 		rec, ok := byShape[shape]
 		if !ok {
 			rec = &IndexRecommendation{
-				BaseTable:   "melange_tuples",
+				BaseTable:   TuplesTableName(tuplesTable),
 				Columns:     columns,
 				WhereClause: where,
 			}
@@ -75,6 +99,7 @@ func RecommendIndexes(analyses []RelationAnalysis) []IndexRecommendation {
 	objectKeyed := []string{"object_type", "object_id", "relation", "subject_type", "subject_id"}
 	subjectKeyed := []string{"subject_type", "subject_id", "relation", "object_type", "object_id"}
 	wildcardKeyed := []string{"object_type", "object_id", "relation"}
+	usersetListKeyed := []string{"object_type", "relation", "subject_type", "subject_id"}
 
 	// A _nw check function is only emitted for relations that reach a wildcard;
 	// don't list a _nw beneficiary that isn't generated.
@@ -89,31 +114,42 @@ func RecommendIndexes(analyses []RelationAnalysis) []IndexRecommendation {
 		// is generated (check uses the same prefix as list_*_sub).
 		var objBenefits []string
 		if a.Capabilities.CheckAllowed {
-			objBenefits = append(objBenefits, functionName(a.ObjectType, a.Relation))
+			objBenefits = append(objBenefits, functionName(a.NameCollisions, a.ObjectType, a.Relation))
 			if needsNW[a.ObjectType][a.Relation] {
-				objBenefits = append(objBenefits, functionNameNoWildcard(a.ObjectType, a.Relation))
+				objBenefits = append(objBenefits, functionNameNoWildcard(a.NameCollisions, a.ObjectType, a.Relation))
 			}
 		}
 		if a.Capabilities.ListAllowed {
-			objBenefits = append(objBenefits, listSubjectsFunctionName(a.ObjectType, a.Relation))
+			objBenefits = append(objBenefits, listSubjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation))
 		}
 		add(objectKeyed, "", objBenefits...)
 
 		// Subject-keyed: covers list_*_obj only.
 		if a.Capabilities.ListAllowed {
-			add(subjectKeyed, "", listObjectsFunctionName(a.ObjectType, a.Relation))
+			add(subjectKeyed, "", listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation))
 		}
 
 		// Wildcard partial: only relations that accept [type:*] grants.
 		if a.Features.HasWildcard {
 			var wildcardBenefits []string
 			if a.Capabilities.CheckAllowed {
-				wildcardBenefits = append(wildcardBenefits, functionName(a.ObjectType, a.Relation))
+				wildcardBenefits = append(wildcardBenefits, functionName(a.NameCollisions, a.ObjectType, a.Relation))
 			}
 			if a.Capabilities.ListAllowed {
-				wildcardBenefits = append(wildcardBenefits, listSubjectsFunctionName(a.ObjectType, a.Relation))
+				wildcardBenefits = append(wildcardBenefits, listSubjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation))
+			}
+			add(wildcardKeyed, wildcardIndexPredicate, wildcardBenefits...)
+		}
+
+		// Userset partials: only relations whose generated SQL looks up
+		// [type#relation] grants. The check and list_*_sub userset branches
+		// run in the same functions as the object-keyed lookup, _nw variants
+		// included; list_*_obj joins userset grants to membership rows.
+		if len(a.UsersetPatterns) > 0 || len(a.ClosureUsersetPatterns) > 0 {
+			add(objectKeyed, usersetIndexPredicate, objBenefits...)
+			if a.Capabilities.ListAllowed {
+				add(usersetListKeyed, usersetIndexPredicate, listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation))
 			}
-			add(wildcardKeyed, "subject_id = '*'", wildcardBenefits...)
 		}
 	}
 
@@ -130,7 +166,7 @@ func RecommendIndexes(analyses []RelationAnalysis) []IndexRecommendation {
 }
 
 // renderIndexDDL formats the CREATE INDEX statement. The index name is
-// derived from the columns (and the wildcard predicate, if present) so that
+// derived from the columns (and the partial predicate, if present) so that
 // every distinct shape gets a distinct name without colliding across schemas.
 func renderIndexDDL(rec IndexRecommendation) string {
 	name := indexName(rec)
@@ -146,24 +182,30 @@ func renderIndexDDL(rec IndexRecommendation) string {
 
 // indexName builds a stable, descriptive name for a recommendation. The first
 // two columns drive the name (which dimension is leading is the meaningful
-// distinction); the wildcard variant gets an explicit suffix.
+// distinction); the wildcard and userset partials get an explicit suffix.
 //
-// Recommendations target melange_tuples so the column list disambiguates rather
-// than the table name; PostgreSQL's 63-byte limit isn't a concern here because
-// column tokens are all short and bounded by what we emit.
+// The name starts with the base table's unqualified name, since CREATE INDEX
+// places the index in the table's schema. Names over PostgreSQL's 63-byte
+// limit, possible only with a long configured table name, are shortened by
+// SafeIdentifier.
 func indexName(rec IndexRecommendation) string {
-	suffix := ""
-	if rec.WhereClause != "" {
+	var suffix string
+	switch rec.WhereClause {
+	case "":
+	case usersetIndexPredicate:
+		suffix = "_userset"
+	default:
 		suffix = "_wildcard"
 	}
 	// Use first two columns as the kind discriminator: "object_type_object_id"
 	// vs. "subject_type_subject_id". The full column list isn't needed in the
 	// name — the DDL itself is the authoritative spec.
+	_, table := SplitTuplesTable(rec.BaseTable)
 	cols := rec.Columns
 	if len(cols) >= 2 {
-		return "idx_melange_tuples_by_" + cols[0] + "_" + cols[1] + suffix
+		return SafeIdentifier("idx_", table, "by_"+cols[0]+"_"+cols[1], suffix)
 	}
-	return "idx_melange_tuples_" + strings.Join(cols, "_") + suffix
+	return SafeIdentifier("idx_", table, strings.Join(cols, "_"), suffix)
 }
 
 func dedupeStrings(in []string) []string {
@@ -96,6 +96,48 @@ func TestRecommendIndexes_Wildcard(t *testing.T) {
 	}
 }
 
+func TestRecommendIndexes_Userset(t *testing.T) {
+	viewer := mkAnalysis("document", "viewer", RelationFeatures{HasDirect: true, HasUserset: true}, true)
+	viewer.UsersetPatterns = []UsersetPattern{{SubjectType: "group", SubjectRelation: "member"}}
+	// can_view reaches the userset grant through viewer, so its functions run
+	// the same userset lookups.
+	canView := mkAnalysis("document", "can_view", RelationFeatures{HasImplied: true}, true)
+	canView.ClosureUsersetPatterns = viewer.UsersetPatterns
+	analyses := []RelationAnalysis{
+		viewer,
+		canView,
+		mkAnalysis("document", "owner", RelationFeatures{HasDirect: true}, true),
+	}
+
+	recs := RecommendIndexes(analyses)
+
+	if got, want := len(recs), 4; got != want {
+		t.Fatalf("got %d recommendations, want %d", got, want)
+	}
+
+	userset := findRec(t, recs, []string{"object_type", "object_id", "relation", "subject_type", "subject_id"}, "position('#' in subject_id) > 0")
+	if !strings.Contains(userset.DDL, "idx_melange_tuples_by_object_type_object_id_userset ON") {
+		t.Errorf("userset DDL should have _userset suffix in index name: %q", userset.DDL)
+	}
+	if !strings.HasSuffix(userset.DDL, "WHERE position('#' in subject_id) > 0;") {
+		t.Errorf("userset DDL missing predicate: %q", userset.DDL)
+	}
+	// Only the relations that reach a userset grant benefit.
+	want := []string{"check_document_can_view", "check_document_viewer", "list_document_can_view_sub", "list_document_viewer_sub"}
+	if got := userset.BenefitsFunctions; !slices.Equal(got, want) {
+		t.Errorf("userset BenefitsFunctions = %v, want %v", got, want)
+	}
+
+	listObj := findRec(t, recs, []string{"object_type", "relation", "subject_type", "subject_id"}, "position('#' in subject_id) > 0")
+	if !strings.Contains(listObj.DDL, "idx_melange_tuples_by_object_type_relation_userset ON") {
+		t.Errorf("list_*_obj userset DDL has unexpected index name: %q", listObj.DDL)
+	}
+	want = []string{"list_document_can_view_obj", "list_document_viewer_obj"}
+	if got := listObj.BenefitsFunctions; !slices.Equal(got, want) {
+		t.Errorf("list_*_obj userset BenefitsFunctions = %v, want %v", got, want)
+	}
+}
+
 func TestRecommendIndexes_DeduplicatesAcrossRelations(t *testing.T) {
 	// Two relations on the same object type — both generate the same two
 	// shapes, so we expect 2 recs total with BenefitsFunctions covering both.
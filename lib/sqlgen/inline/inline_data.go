@@ -21,6 +21,11 @@ type InlineSQLData struct {
 	// UsersetRows contains typed expression rows for userset data.
 	// Each row has 4 columns: object_type, relation, subject_type, subject_relation.
 	UsersetRows []sqldsl.ValuesRow
+	// ClosureFunction has generated functions look the rows up through
+	// melange_closure_rows and melange_userset_rows instead of embedding them.
+	// GenerateSQLWithOptions and GenerateListSQLWithOptions set it from
+	// GenerateSQLOptions.ClosureFunction.
+	ClosureFunction bool
 }
 
 // BuildInlineSQLData builds inline SQL data for tools and tests.
@@ -29,6 +29,10 @@ import "strings"
 // making the embedded VALUES independent of unrelated schema growth: a
 // userset-subject check no longer scans closure rows for object types it can
 // never reference.
+//
+// With inline.ClosureFunction the function looks rows up through
+// melange_closure_rows and melange_userset_rows and embeds none, so none are
+// kept.
 func filterInlineForCheck(inline InlineSQLData, a RelationAnalysis) InlineSQLData {
 	closureTypes := map[string]bool{a.ObjectType: true}
 	for _, t := range a.AllowedSubjectTypes {
@@ -41,6 +45,9 @@ func filterInlineForCheck(inline InlineSQLData, a RelationAnalysis) InlineSQLDat
 		closureTypes[p.SubjectType] = true // load-bearing (see doc)
 	}
 
+	if inline.ClosureFunction {
+		return InlineSQLData{ClosureFunction: true}
+	}
 	return InlineSQLData{
 		ClosureRows: filterRowsByObjectType(inline.ClosureRows, closureTypes),
 		UsersetRows: filterRowsByObjectType(inline.UsersetRows, map[string]bool{a.ObjectType: true}),
@@ -72,7 +79,8 @@ func filterInlineForCheck(inline InlineSQLData, a RelationAnalysis) InlineSQLDat
 // closure/userset lookup can key on. Adding a.ObjectType and AllowedSubjectTypes
 // is extra safety. The result is a safe superset: it can only keep extra rows,
 // never drop a needed one, so the generated SQL is semantically identical while
-// the embedded VALUES stop growing with unrelated schema.
+// the embedded VALUES stop growing with unrelated schema. As with
+// filterInlineForCheck, closure-function mode keeps no rows.
 func filterInlineForList(inline InlineSQLData, a RelationAnalysis) InlineSQLData {
 	keep := map[string]bool{a.ObjectType: true}
 	for _, t := range a.AllowedSubjectTypes {
@@ -83,6 +91,9 @@ func filterInlineForList(inline InlineSQLData, a RelationAnalysis) InlineSQLData
 			keep[ref[:i]] = true
 		}
 	}
+	if inline.ClosureFunction {
+		return InlineSQLData{ClosureFunction: true}
+	}
 	return InlineSQLData{
 		ClosureRows: filterRowsByObjectType(inline.ClosureRows, keep),
 		UsersetRows: filterRowsByObjectType(inline.UsersetRows, keep),
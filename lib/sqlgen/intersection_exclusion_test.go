@@ -0,0 +1,67 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestListObjects_IntersectionWithExclusion(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, `
+model
+  schema 1.1
+
+type user
+
+type doc
+  relations
+    define parent: [doc]
+    define editor: [user]
+    define member: [user]
+    define suspended: [user]
+    define viewer: [user] or viewer from parent
+    define can_edit: (editor and member) but not suspended
+    define can_move: (editor and viewer from parent) but not suspended
+    define can_share: ((editor and member) but not suspended) or viewer from parent
+`)
+
+	list, err := GenerateListSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatalf("GenerateListSQL: %v", err)
+	}
+	find := func(relation string) string {
+		name := listObjectsFunctionName(nil, "doc", relation)
+		for _, fn := range list.ListObjectsFunctions {
+			if strings.Contains(fn, "FUNCTION "+name+"(") {
+				return fn
+			}
+		}
+		t.Fatalf("%s not generated", name)
+		return ""
+	}
+
+	// The exclusion applies to the intersection's result, not to one part.
+	canEdit := find("can_edit")
+	intersect := strings.Index(canEdit, "INTERSECT")
+	exclusion := strings.Index(canEdit, "excl.relation IN ('suspended') AND excl.object_id = ig.object_id")
+	if intersect < 0 || exclusion < intersect {
+		t.Errorf("can_edit: expected the exclusion over the intersection result, got:\n%s", canEdit)
+	}
+
+	// Without direct types, check never reads tuples stored under the
+	// relation itself, so list must not either.
+	for _, relation := range []string{"can_edit", "can_move", "can_share"} {
+		if fn := find(relation); strings.Contains(fn, "t.relation IN ('"+relation+"')") {
+			t.Errorf("%s: unexpected direct tuple lookup, got:\n%s", relation, fn)
+		}
+	}
+
+	// A scoped exclusion stays on its intersection and leaves the TTU arm alone.
+	canShare := find("can_share")
+	ttu := canShare[strings.Index(canShare, "-- TTU path via parent -> viewer"):strings.Index(canShare, "-- Intersection group 0")]
+	if strings.Contains(ttu, "suspended") {
+		t.Errorf("can_share: TTU arm excludes suspended, got:\n%s", ttu)
+	}
+	if !strings.Contains(canShare, "list_doc_suspended_obj") {
+		t.Errorf("can_share: expected the intersection to exclude suspended, got:\n%s", canShare)
+	}
+}
@@ -61,7 +61,7 @@ func TestIntersectionGroup_ComposesWhenComposable(t *testing.T) {
 
 	// Positive part "editor" → emit the specialized list set directly as the
 	// FROM source, no object-type-wide melange_tuples scan.
-	if !strings.Contains(sql, "FROM list_doc_editor_obj(p_subject_type, p_subject_id, NULL, NULL) AS obj") {
+	if !strings.Contains(sql, "FROM list_doc_editor_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) AS obj") {
 		t.Errorf("expected direct list set for positive part editor, got:\n%s", sql)
 	}
 	// Positive part keeps a userset-guarded per-candidate check arm for parity.
@@ -169,3 +169,80 @@ func TestIntersectionGroup_FallsBackWhenCyclic(t *testing.T) {
 		t.Errorf("expected excluded relation banned to still compose, got:\n%s", sql)
 	}
 }
+
+// "((a and b) or (c and d)) but not e": the relation-level exclusion reads only
+// the candidate object and the subject, so each group carries it exactly once
+// against its own ig.object_id and the union of groups needs no outer copy.
+func TestIntersectionGroups_ExclusionAppliedOncePerGroup(t *testing.T) {
+	plan := ListPlan{
+		ObjectType:   "doc",
+		Relation:     "viewer",
+		HasExclusion: true,
+		Analysis: RelationAnalysis{
+			ObjectType: "doc",
+			Relation:   "viewer",
+			IntersectionGroups: []IntersectionGroupInfo{
+				{Parts: []IntersectionPart{{Relation: "a"}, {Relation: "b"}}},
+				{Parts: []IntersectionPart{{Relation: "c"}, {Relation: "d"}}},
+			},
+			SimpleExcludedRelations: []string{"e"},
+		},
+	}
+
+	blocks, err := buildListObjectsIntersectionGroupBlocks(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 group blocks, got %d", len(blocks))
+	}
+
+	exclusion := "excl.relation IN ('e') AND excl.object_id = ig.object_id"
+	for idx, block := range blocks {
+		sql := block.Query.SQL()
+		if n := strings.Count(sql, exclusion); n != 1 {
+			t.Errorf("group %d: expected exclusion once, got %d:\n%s", idx, n, sql)
+		}
+	}
+}
+
+// A TTU arm outside the intersection groups, as in
+// "can_manage: admin from org or (editor and can_manage from parent)", gets its
+// own block: composed against the cross-type parent's list set, and checked
+// per candidate when the arm recurses through the relation itself.
+func TestStandaloneParentBlocks(t *testing.T) {
+	plan := ListPlan{
+		ObjectType: "folder",
+		Relation:   "can_manage",
+		Analysis: RelationAnalysis{
+			ObjectType: "folder",
+			Relation:   "can_manage",
+			ParentRelations: []ParentRelationInfo{
+				{Relation: "admin", LinkingRelation: "org", AllowedLinkingTypes: []string{"org"}},
+				{Relation: "can_manage", LinkingRelation: "parent", AllowedLinkingTypes: []string{"folder"}},
+			},
+		},
+		AnalysisLookup: map[string]*RelationAnalysis{
+			"org.admin": {
+				ObjectType:   "org",
+				Relation:     "admin",
+				Capabilities: GenerationCapabilities{ListAllowed: true},
+				ListStrategy: ListStrategyDirect,
+			},
+		},
+	}
+
+	blocks := buildListObjectsStandaloneParentBlocks(plan)
+	if len(blocks) != 2 {
+		t.Fatalf("buildListObjectsStandaloneParentBlocks() returned %d blocks, want 2", len(blocks))
+	}
+
+	org := blocks[0].Query.SQL()
+	assertContains(t, org, "t.relation IN ('org')")
+	assertContains(t, org, "FROM list_org_admin_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) parent_obj")
+
+	parent := blocks[1].Query.SQL()
+	assertContains(t, parent, "t.relation IN ('parent')")
+	assertContains(t, parent, "check_permission_internal(p_subject_type, p_subject_id, 'can_manage', t.subject_type, t.subject_id, ARRAY[]::TEXT[]) = 1")
+	assertNotContains(t, parent, "list_folder_can_manage_obj")
+}
@@ -238,13 +238,13 @@ func composableListSubjectsTarget(plan ListPlan, targetType, targetRelation stri
 // short-circuits. Callers gate composability first and pass the per-candidate
 // check as the fallback arm; anti-join sites negate the result. The alias only
 // affects the rendered subquery alias and must stay unique within a predicate.
-func composedListObjectsMembership(schema, targetType, targetRel string, objectIDExpr, subjectType, subjectID Expr, alias string, check Expr) Expr {
+func composedListObjectsMembership(schema string, collisions NameCollisions, targetType, targetRel string, objectIDExpr, subjectType, subjectID Expr, alias string, check Expr) Expr {
 	return Or(
 		InFunctionSelect{
 			Expr:      objectIDExpr,
 			Schema:    schema,
-			FuncName:  ListObjectsFunctionName(targetType, targetRel),
-			Args:      []Expr{subjectType, subjectID, Null{}, Null{}},
+			FuncName:  ListObjectsFunctionNameWithCollisions(collisions, targetType, targetRel),
+			Args:      listObjectsCallArgs(subjectType, subjectID),
 			Alias:     alias,
 			SelectCol: "object_id",
 		},
@@ -275,7 +275,7 @@ func complexClosureMembership(plan ListPlan, rel string) Expr {
 	if !composableListTarget(plan, plan.ObjectType, rel) {
 		return check
 	}
-	return composedListObjectsMembership(plan.DatabaseSchema, plan.ObjectType, rel, Col{Table: "t", Column: "object_id"}, SubjectType, SubjectID, "obj", check)
+	return composedListObjectsMembership(plan.DatabaseSchema, plan.Analysis.NameCollisions, plan.ObjectType, rel, Col{Table: "t", Column: "object_id"}, SubjectType, SubjectID, "obj", check)
 }
 
 // complexClosureSubjectMembership returns the predicate proving the candidate
@@ -313,8 +313,8 @@ func complexClosureSubjectMembership(plan ListPlan, rel string) Expr {
 	return InFunctionSelect{
 		Expr:      Col{Table: "t", Column: "subject_id"},
 		Schema:    plan.DatabaseSchema,
-		FuncName:  listSubjectsFunctionName(plan.ObjectType, rel),
-		Args:      []Expr{ObjectID, SubjectType},
+		FuncName:  listSubjectsFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, rel),
+		Args:      listSubjectsCallArgs(ObjectID, SubjectType),
 		Alias:     "sub",
 		SelectCol: "subject_id",
 	}
@@ -358,7 +358,7 @@ func intersectionPartMembership(plan ListPlan, rel string, objectID Expr) Expr {
 	if !intersectionPartComposable(plan, rel) {
 		return check
 	}
-	return composedListObjectsMembership(plan.DatabaseSchema, plan.ObjectType, rel, objectID, SubjectType, SubjectID, "obj", check)
+	return composedListObjectsMembership(plan.DatabaseSchema, plan.Analysis.NameCollisions, plan.ObjectType, rel, objectID, SubjectType, SubjectID, "obj", check)
 }
 
 // intersectionPartExclusion returns the "but not rel" predicate for a nested
@@ -385,7 +385,7 @@ func intersectionPartExclusion(plan ListPlan, rel string, objectID Expr) Expr {
 		Object:      LiteralObject(plan.ObjectType, objectID),
 		ExpectAllow: true,
 	}
-	return Not(composedListObjectsMembership(plan.DatabaseSchema, plan.ObjectType, rel, objectID, SubjectType, SubjectID, "excl_obj", positiveCheck))
+	return Not(composedListObjectsMembership(plan.DatabaseSchema, plan.Analysis.NameCollisions, plan.ObjectType, rel, objectID, SubjectType, SubjectID, "excl_obj", positiveCheck))
 }
 
 // usersetMembership returns the membership predicate for a complex userset
@@ -413,5 +413,5 @@ func usersetMembership(plan ListPlan, pattern listUsersetPatternInput) Expr {
 	if !composableListTarget(plan, pattern.SubjectType, pattern.SubjectRelation) {
 		return check
 	}
-	return composedListObjectsMembership(plan.DatabaseSchema, pattern.SubjectType, pattern.SubjectRelation, UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}}, SubjectType, SubjectID, "obj", check)
+	return composedListObjectsMembership(plan.DatabaseSchema, plan.Analysis.NameCollisions, pattern.SubjectType, pattern.SubjectRelation, UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}}, SubjectType, SubjectID, "obj", check)
 }
@@ -36,17 +36,26 @@ var relationReferencesReviewedFields = map[string]bool{
 	"SelfReferentialUsersets":      true,
 
 	// Reviewed: no relation references.
-	"ObjectType":                true,
-	"Relation":                  true,
-	"Features":                  true,
-	"Capabilities":              true,
-	"ListStrategy":              true,
-	"HasComplexUsersetPatterns": true,
-	"DirectSubjectTypes":        true,
-	"AllowedSubjectTypes":       true,
-	"MaxUsersetDepth":           true,
-	"ExceedsDepthLimit":         true,
-	"HasSelfReferentialUserset": true,
+	"ObjectType":                  true,
+	"Relation":                    true,
+	"Features":                    true,
+	"Capabilities":                true,
+	"ListStrategy":                true,
+	"HasComplexUsersetPatterns":   true,
+	"DirectSubjectTypes":          true,
+	"AllowedSubjectTypes":         true,
+	"WildcardSubjectTypes":        true,
+	"ClosureWildcardSubjectTypes": true,
+	"MaxUsersetDepth":             true,
+	"ExceedsDepthLimit":           true,
+	"HasSelfReferentialUserset":   true,
+	"NameCollisions":              true,
+	"Conditions":                  true,
+	"conditionReason":             true,
+	"cycleReason":                 true,
+	"linkingReason":               true,
+	"rewriteReason":               true,
+	"listIneligibility":           true,
 }
 
 func TestRelationReferencesFieldCoverage(t *testing.T) {
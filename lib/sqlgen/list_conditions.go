@@ -0,0 +1,55 @@
+package sqlgen
+
+// buildListObjectsConditionBlocks builds one block per conditional direct
+// grant of the relation, returning the objects granted to the query subject
+// under a condition that holds against p_context. The direct block skips
+// these tuples (see conditionalTupleGuard), so list_objects returns exactly
+// the objects check_permission_with_context allows for the same context.
+func buildListObjectsConditionBlocks(plan ListPlan) []TypedQueryBlock {
+	var blocks []TypedQueryBlock
+	for _, c := range plan.Analysis.Conditions {
+		q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
+			ObjectType(plan.ObjectType).
+			Relations(plan.Relation).
+			Where(
+				Eq{Left: SubjectType, Right: Lit(c.SubjectType)},
+				Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: SubjectType},
+				Eq{Left: Col{Table: "t", Column: "subject_id"}, Right: SubjectID},
+				subjectTypesFilter(SubjectType),
+				conditionHolds(c),
+			).
+			SelectCol("object_id").
+			Distinct()
+		blocks = append(blocks, TypedQueryBlock{
+			Comments: plan.traceComments(TraceNodeDirect, "", "-- Condition "+c.Name+" on "+c.SubjectType+", evaluated against p_context"),
+			Query:    q.Build(),
+		})
+	}
+	return blocks
+}
+
+// buildListSubjectsConditionBlocks is the list_subjects counterpart of
+// buildListObjectsConditionBlocks: the subjects of the requested type granted
+// on the object under a condition that holds against p_context.
+func buildListSubjectsConditionBlocks(plan ListPlan) []TypedQueryBlock {
+	var blocks []TypedQueryBlock
+	for _, c := range plan.Analysis.Conditions {
+		q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
+			ObjectType(plan.ObjectType).
+			Relations(plan.Relation).
+			Where(
+				Eq{Left: Param("p_subject_type"), Right: Lit(c.SubjectType)},
+				Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
+				Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Param("p_subject_type")},
+				NoUserset{Source: Col{Table: "t", Column: "subject_id"}},
+				conditionHolds(c),
+			).
+			SelectCol("subject_id").
+			Distinct()
+		blocks = append(blocks, TypedQueryBlock{
+			Comments: plan.traceComments(TraceNodeDirect, "", "-- Condition "+c.Name+" on "+c.SubjectType+", evaluated against p_context"),
+			Query:    q.Build(),
+		})
+	}
+	return blocks
+}
@@ -0,0 +1,62 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+const listConditionsSchema = `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define owner: [user]
+    define viewer: [user with in_region] or owner
+
+condition in_region(region: string) {
+  region == "eu"
+}
+`
+
+func TestListConditions(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, listConditionsSchema)
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+
+	var objects, subjects string
+	for _, fn := range list.ListObjectsFunctions {
+		if strings.Contains(fn, "FUNCTION list_document_viewer_obj(") {
+			objects = fn
+		}
+	}
+	for _, fn := range list.ListSubjectsFunctions {
+		if strings.Contains(fn, "FUNCTION list_document_viewer_sub(") {
+			subjects = fn
+		}
+	}
+	if objects == "" || subjects == "" {
+		t.Fatal("conditioned direct/implied relation has no list functions")
+	}
+
+	for name, sql := range map[string]string{"list_objects": objects, "list_subjects": subjects} {
+		// The closure lookup skips the conditional tuples, which only the
+		// condition block returns, and only when the condition holds.
+		assertContains(t, sql, "p_context JSONB DEFAULT NULL")
+		assertContains(t, sql, "NOT ((t.relation = 'viewer' AND t.subject_type IN ('user')))")
+		assertContains(t, sql, "-- Condition in_region on user, evaluated against p_context")
+		assertContains(t, sql, `COALESCE(((p_context->>'region') = 'eu'), FALSE)`)
+		if strings.Count(sql, "COALESCE(") != 1 {
+			t.Errorf("%s: condition should be evaluated in exactly one block:\n%s", name, sql)
+		}
+	}
+	assertContains(t, objects, "p_subject_type = 'user'")
+	assertContains(t, subjects, "p_subject_type = 'user'")
+
+	assertContains(t, list.ListObjectsDispatcher, "p_context => p_context")
+	assertContains(t, list.ListSubjectsDispatcher, "p_context => p_context")
+	assertContains(t, list.ListSubjectsDispatcher, "DROP FUNCTION IF EXISTS list_accessible_subjects(TEXT, TEXT, TEXT, TEXT, INT, TEXT, BOOLEAN);")
+}
@@ -0,0 +1,38 @@
+package sqlgen
+
+// excludeRelation removes from query, the base query of a list_objects
+// function, the objects on which the subject also holds p_exclude_relation
+// ("shared with me but not owned by me"). The excluded objects come from
+// list_accessible_objects for the plan's object type, which routes to
+// list_{type}_{exclude}_objects, so an exclude relation without a list
+// function raises M2003 and an unknown one excludes nothing. Conditional
+// grants of the exclude relation count under the same p_context. A NULL
+// p_exclude_relation is a one-time filter: the dispatcher is never called.
+//
+// The EXCEPT runs before pagination, so pages never come back short because
+// of excluded objects.
+func (p ListPlan) excludeRelation(query string) string {
+	return "SELECT b.object_id::TEXT AS object_id\nFROM (\n" + IndentLines(query, "    ") + "\n) AS b\n" +
+		"-- Objects the subject also holds p_exclude_relation on\nEXCEPT\n" + p.excludedObjects().SQL()
+}
+
+// excludedObjects selects the objects excludeRelation removes: those the
+// subject holds p_exclude_relation on, or none when it is NULL.
+func (p ListPlan) excludedObjects() SelectStmt {
+	return SelectStmt{
+		ColumnExprs: []Expr{Col{Table: "x", Column: "object_id"}},
+		FromExpr: FunctionCallExpr{
+			Schema: p.DatabaseSchema,
+			Name:   "list_accessible_objects",
+			Args: CallArgs(ListObjectsDispatcherArgs(), map[string]Expr{
+				"p_subject_type": SubjectType,
+				"p_subject_id":   SubjectID,
+				"p_relation":     Param("p_exclude_relation"),
+				"p_object_type":  Lit(p.ObjectType),
+				"p_context":      Param("p_context"),
+			}),
+			Alias: "x",
+		},
+		Where: IsNotNull{Expr: Param("p_exclude_relation")},
+	}
+}
@@ -0,0 +1,45 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+// p_exclude_relation must subtract the dispatcher's list for the excluded
+// relation on the same type before pagination, so pages are never short,
+// and the dispatcher must forward it.
+func TestListObjectsExcludeRelation(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, subjectTypesSchema)
+	list, err := GenerateListSQLWithOptions(analyses, inline, "authz", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+
+	var viewer string
+	for _, fn := range list.ListObjectsFunctions {
+		if strings.Contains(fn, `FUNCTION "authz"."list_document_viewer_obj"(`) {
+			viewer = fn
+		}
+	}
+
+	assertContains(t, viewer, "p_subject_types TEXT[] DEFAULT NULL,\n    p_exclude_relation TEXT DEFAULT NULL,\n    p_context JSONB DEFAULT NULL,\n    p_consistency TEXT DEFAULT NULL\n)")
+	assertContains(t, viewer, `            ) AS b
+            -- Objects the subject also holds p_exclude_relation on
+            EXCEPT
+            SELECT x.object_id
+            FROM "authz"."list_accessible_objects"(p_subject_type => p_subject_type, p_subject_id => p_subject_id, p_relation => p_exclude_relation, p_object_type => 'document', p_context => p_context) AS x
+            WHERE p_exclude_relation IS NOT NULL
+        ),
+        paged AS (`)
+
+	// Both signatures the function had before are dropped.
+	if !strings.HasPrefix(viewer, `DROP FUNCTION IF EXISTS "authz"."list_document_viewer_obj"(TEXT, TEXT, INT, TEXT);
+DROP FUNCTION IF EXISTS "authz"."list_document_viewer_obj"(TEXT, TEXT, INT, TEXT, TEXT[]);
+`) {
+		t.Errorf("list_document_viewer_obj does not drop its old signatures first:\n%s", viewer[:300])
+	}
+
+	assertContains(t, list.ListObjectsDispatcher, `DROP FUNCTION IF EXISTS "authz"."list_accessible_objects"(TEXT, TEXT, TEXT, TEXT, INT, TEXT, TEXT[]);`)
+	assertContains(t, list.ListObjectsDispatcher, "p_exclude_relation => p_exclude_relation")
+	assertNotContains(t, list.ListObjectsAnyDispatcher, "p_exclude_relation")
+}
@@ -1,6 +1,10 @@
 package sqlgen
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
 
 // ListGeneratedSQL contains all SQL generated for list functions.
 // This is separate from check function generation to keep concerns isolated.
@@ -21,6 +25,29 @@ type ListGeneratedSQL struct {
 	// ListSubjectsDispatcher contains the list_accessible_subjects dispatcher function
 	// that routes to specialized functions or falls back to generic.
 	ListSubjectsDispatcher string
+
+	// ListObjectsAnyDispatcher contains list_accessible_objects_any, which
+	// lists objects for any of several relations at once.
+	ListObjectsAnyDispatcher string
+
+	// ListSubjectsMultiDispatcher contains list_accessible_subjects_multi,
+	// which lists subjects for several subject type filters at once.
+	ListSubjectsMultiDispatcher string
+
+	// DepthExceededFunction contains melange_depth_exceeded, which recursive
+	// list functions call to raise M2002 when a walk reaches the depth limit.
+	// Always generated; see generateDepthExceededFunction.
+	DepthExceededFunction string
+
+	// ListObjectsCursorFunctions contains list_{type}_{relation}_objects_cursor
+	// for each listable relation, in analyses order. Empty unless
+	// GenerateSQLOptions.EnableListObjectsCursor is set; see
+	// generateListObjectsCursorFunction.
+	ListObjectsCursorFunctions []string
+
+	// ListObjectsCursorDispatcher contains list_accessible_objects_cursor.
+	// Empty unless GenerateSQLOptions.EnableListObjectsCursor is set.
+	ListObjectsCursorDispatcher string
 }
 
 // GenerateListSQL generates specialized SQL functions for list operations using
@@ -41,14 +68,33 @@ func GenerateListSQL(analyses []RelationAnalysis, inline InlineSQLData, database
 // GenerateListSQLWithOptions is the option-aware variant of GenerateListSQL.
 // The opts.EnableMaterializedCTEs flag is threaded into each ListPlan so render
 // functions can decide whether to emit "AS MATERIALIZED" on paged/returned.
+// With opts.EnableListObjectsCursor the result also carries the cursor
+// functions and their dispatcher. With opts.ClosureFunction the list functions
+// call melange_closure_rows, which GenerateSQLWithOptions emits.
 func GenerateListSQLWithOptions(analyses []RelationAnalysis, inline InlineSQLData, databaseSchema string, opts GenerateSQLOptions) (ListGeneratedSQL, error) {
+	if err := ValidateObjectDelimiter(opts.ObjectDelimiter); err != nil {
+		return ListGeneratedSQL{}, err
+	}
+	if err := ValidateTuplesTable(opts.TuplesTable); err != nil {
+		return ListGeneratedSQL{}, err
+	}
+	if err := ValidateMaxDepth(opts.MaxDepth); err != nil {
+		return ListGeneratedSQL{}, err
+	}
+	if err := ValidateDialect(opts); err != nil {
+		return ListGeneratedSQL{}, err
+	}
+
 	var result ListGeneratedSQL
 
+	inline.ClosureFunction = opts.ClosureFunction
+
 	// Build analysis lookup for TTU parent relation complexity detection
 	analysisLookup := buildAnalysisLookup(analyses)
 
-	// Generate specialized functions for each relation that can be generated
-	for _, a := range analyses {
+	// Generate specialized functions for each relation that can be generated,
+	// callees first (see callOrder)
+	for _, a := range callOrder(analyses) {
 		if !a.Capabilities.ListAllowed {
 			continue
 		}
@@ -61,35 +107,80 @@ func GenerateListSQLWithOptions(analyses []RelationAnalysis, inline InlineSQLDat
 		// Generate list_objects function
 		objFn, err := generateListObjectsFunctionWithLookup(a, relInline, databaseSchema, analysisLookup, opts)
 		if err != nil {
-			return ListGeneratedSQL{}, fmt.Errorf("generating list_objects function for %s.%s: %w",
-				a.ObjectType, a.Relation, err)
+			return ListGeneratedSQL{}, relationError(a, FunctionKindListObjects, err)
 		}
+		objFn = withFunctionMetadata(objFn, a, FunctionKindListObjects,
+			listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, ListObjectsArgs())
+		objFn = withLegacyDrop(objFn, listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, ListObjectsArgs(), "p_subject_types", "p_exclude_relation", "p_context", "p_consistency")
 		result.ListObjectsFunctions = append(result.ListObjectsFunctions, objFn)
 
 		// Generate list_subjects function
 		subjFn, err := generateListSubjectsFunctionWithLookup(a, relInline, databaseSchema, analysisLookup, opts)
 		if err != nil {
-			return ListGeneratedSQL{}, fmt.Errorf("generating list_subjects function for %s.%s: %w",
-				a.ObjectType, a.Relation, err)
+			return ListGeneratedSQL{}, relationError(a, FunctionKindListSubjects, err)
 		}
+		subjFn = withFunctionMetadata(subjFn, a, FunctionKindListSubjects,
+			listSubjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, ListSubjectsArgs())
+		subjFn = withLegacyDrop(subjFn, listSubjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, ListSubjectsArgs(), "p_expand_wildcard", "p_context", "p_consistency")
 		result.ListSubjectsFunctions = append(result.ListSubjectsFunctions, subjFn)
+
+		if opts.EnableListObjectsCursor {
+			cursorFn := withFunctionMetadata(generateListObjectsCursorFunction(a, databaseSchema), a, FunctionKindListObjectsCursor,
+				listObjectsCursorFunctionName(a.NameCollisions, a.ObjectType, a.Relation), databaseSchema, listObjectsCursorArgs())
+			result.ListObjectsCursorFunctions = append(result.ListObjectsCursorFunctions, cursorFn)
+		}
 	}
 
 	// Generate dispatchers (always generated, even if no specialized functions)
 	var err error
-	result.ListObjectsDispatcher, err = generateListObjectsDispatcher(analyses, databaseSchema)
+	result.ListObjectsDispatcher, err = generateListObjectsDispatcher(analyses, databaseSchema, opts.nullGuards())
 	if err != nil {
 		return ListGeneratedSQL{}, fmt.Errorf("generating list_objects dispatcher: %w", err)
 	}
+	result.ListObjectsDispatcher = withLegacyDrop(result.ListObjectsDispatcher, "list_accessible_objects", databaseSchema, ListObjectsDispatcherArgs(), "p_subject_types", "p_exclude_relation", "p_context", "p_consistency")
+	result.ListObjectsDispatcher += "\n" + generateListObjectsStringOverload(databaseSchema, opts.objectDelimiter())
 
-	result.ListSubjectsDispatcher, err = generateListSubjectsDispatcher(analyses, databaseSchema)
+	result.ListSubjectsDispatcher, err = generateListSubjectsDispatcher(analyses, databaseSchema, opts.nullGuards())
 	if err != nil {
 		return ListGeneratedSQL{}, fmt.Errorf("generating list_subjects dispatcher: %w", err)
 	}
+	result.ListSubjectsDispatcher = withLegacyDrop(result.ListSubjectsDispatcher, "list_accessible_subjects", databaseSchema, ListSubjectsDispatcherArgs(), "p_expand_wildcard", "p_context", "p_consistency")
+	result.ListSubjectsDispatcher += "\n" + generateListSubjectsStringOverload(databaseSchema, opts.objectDelimiter())
+
+	result.ListObjectsAnyDispatcher = generateListObjectsAnyDispatcher(databaseSchema)
+	result.ListSubjectsMultiDispatcher = generateListSubjectsMultiDispatcher(databaseSchema)
+	result.DepthExceededFunction = generateDepthExceededFunction(databaseSchema)
 
+	if opts.EnableListObjectsCursor {
+		result.ListObjectsCursorDispatcher = generateListObjectsCursorDispatcher(databaseSchema)
+	}
+
+	adaptListGeneratedSQL(&result, opts.Dialect)
 	return result, nil
 }
 
+// withLegacyDrop prefixes fn with a DROP of each overload of name the
+// function had before the added arguments, given oldest first, were appended
+// to args (p_expand_wildcard, p_context and then p_consistency on
+// list_subjects; p_subject_types, p_exclude_relation, p_context and then
+// p_consistency on list_objects). CREATE OR REPLACE cannot change a
+// function's arguments: it would install an overload beside the old one, and
+// every call leaving the added arguments to their defaults would then be
+// ambiguous.
+func withLegacyDrop(fn, name, databaseSchema string, args []FuncArg, added ...string) string {
+	var drops []string
+	for i := range added {
+		legacy := make([]FuncArg, 0, len(args))
+		for _, arg := range args {
+			if !slices.Contains(added[i:], arg.Name) {
+				legacy = append(legacy, arg)
+			}
+		}
+		drops = append(drops, DropFunction(databaseSchema, name, legacy))
+	}
+	return strings.Join(drops, "\n") + "\n" + fn
+}
+
 // buildAnalysisLookup creates a map for quick analysis lookup by "objectType.relation".
 func buildAnalysisLookup(analyses []RelationAnalysis) map[string]*RelationAnalysis {
 	lookup := make(map[string]*RelationAnalysis, len(analyses))
@@ -100,12 +191,42 @@ func buildAnalysisLookup(analyses []RelationAnalysis) map[string]*RelationAnalys
 	return lookup
 }
 
-func listObjectsFunctionName(objectType, relation string) string {
-	return ListObjectsFunctionName(objectType, relation)
+func listObjectsFunctionName(collisions NameCollisions, objectType, relation string) string {
+	return ListObjectsFunctionNameWithCollisions(collisions, objectType, relation)
+}
+
+func listSubjectsFunctionName(collisions NameCollisions, objectType, relation string) string {
+	return ListSubjectsFunctionNameWithCollisions(collisions, objectType, relation)
+}
+
+// listObjectsCallArgs builds the arguments for one generated function calling
+// a specialized list_objects function. Calls are built from ListObjectsArgs in
+// named notation so pagination (and any parameter added later) is left to its
+// default instead of being padded positionally at every call site.
+func listObjectsCallArgs(subjectType, subjectID Expr) []Expr {
+	return CallArgs(ListObjectsArgs(), map[string]Expr{
+		"p_subject_type": subjectType,
+		"p_subject_id":   subjectID,
+	})
+}
+
+// listSubjectsCallArgs is the list_subjects counterpart of listObjectsCallArgs.
+func listSubjectsCallArgs(objectID, subjectType Expr) []Expr {
+	return CallArgs(ListSubjectsArgs(), map[string]Expr{
+		"p_object_id":    objectID,
+		"p_subject_type": subjectType,
+	})
 }
 
-func listSubjectsFunctionName(objectType, relation string) string {
-	return ListSubjectsFunctionName(objectType, relation)
+// listAccessibleSubjectsCallArgs builds the arguments for calling the
+// list_accessible_subjects dispatcher from a generated function.
+func listAccessibleSubjectsCallArgs(objectType, objectID Expr, relation string, subjectType Expr) []Expr {
+	return CallArgs(ListSubjectsDispatcherArgs(), map[string]Expr{
+		"p_object_type":  objectType,
+		"p_object_id":    objectID,
+		"p_relation":     Lit(relation),
+		"p_subject_type": subjectType,
+	})
 }
 
 // generateListObjectsFunctionWithLookup generates a list_objects function with analysis lookup for TTU optimization.
@@ -113,8 +234,12 @@ func generateListObjectsFunctionWithLookup(a RelationAnalysis, inline InlineSQLD
 	// inline is pre-filtered by the caller (filterInlineForList) so the embedded
 	// closure/userset tables stop growing with unrelated schema.
 	// Route to appropriate generator based on ListStrategy
-	plan := BuildListObjectsPlanWithLookup(a, inline, databaseSchema, lookup)
+	plan := BuildListObjectsPlanWithLookup(a, inline, databaseSchema, lookup).withTuplesTable(opts.TuplesTable)
 	plan.EnableMaterializedCTEs = opts.EnableMaterializedCTEs
+	plan.AnytimeListObjects = opts.AnytimeListObjects
+	plan.TraceBlocks = opts.TraceBlocks
+	plan.MaxDepth = opts.maxDepth()
+	plan.NullGuards = opts.nullGuards()
 
 	switch a.ListStrategy {
 	case ListStrategyDirect, ListStrategyUserset, ListStrategyIntersection:
@@ -158,8 +283,12 @@ func generateListSubjectsFunctionWithLookup(a RelationAnalysis, inline InlineSQL
 	// inline is pre-filtered by the caller (filterInlineForList) so the embedded
 	// closure/userset tables stop growing with unrelated schema.
 	// Route to appropriate generator based on ListStrategy
-	plan := BuildListSubjectsPlanWithLookup(a, inline, databaseSchema, lookup)
+	plan := BuildListSubjectsPlanWithLookup(a, inline, databaseSchema, lookup).withTuplesTable(opts.TuplesTable)
 	plan.EnableMaterializedCTEs = opts.EnableMaterializedCTEs
+	plan.TraceBlocks = opts.TraceBlocks
+	plan.MaxDepth = opts.maxDepth()
+	plan.NullGuards = opts.nullGuards()
+	plan.ExpandWildcardSubjects = opts.ExpandWildcardSubjects
 
 	switch a.ListStrategy {
 	case ListStrategyDirect, ListStrategyUserset:
@@ -326,6 +455,9 @@ type ListDispatcherCase struct {
 	ObjectType     string
 	Relation       string
 	FunctionName   string
+	// NotListable marks a checkable relation with no list function. The
+	// dispatcher raises M2003 for it instead of routing.
+	NotListable bool
 }
 
 // buildListParentRelations builds template data for TTU pattern expansion in list templates.
@@ -2,8 +2,6 @@ package sqlgen
 
 import (
 	"strings"
-
-	"github.com/pthm/melange/lib/sqlgen/sqldsl"
 )
 
 // listUsersetPatternInput contains data for building userset pattern blocks.
@@ -102,9 +100,10 @@ func buildListUsersetPatternInputs(a RelationAnalysis) []listUsersetPatternInput
 
 // buildExclusionInput creates an ExclusionConfig from a RelationAnalysis.
 // This configures exclusion predicates for SQL query generation.
-func buildExclusionInput(a RelationAnalysis, databaseSchema string, objectIDExpr, subjectTypeExpr, subjectIDExpr Expr) ExclusionConfig {
+func buildExclusionInput(a RelationAnalysis, databaseSchema, tuplesTable string, objectIDExpr, subjectTypeExpr, subjectIDExpr Expr) ExclusionConfig {
 	return ExclusionConfig{
 		DatabaseSchema:           databaseSchema,
+		TuplesTable:              tuplesTable,
 		ObjectType:               a.ObjectType,
 		ObjectIDExpr:             objectIDExpr,
 		SubjectTypeExpr:          subjectTypeExpr,
@@ -163,9 +162,10 @@ func convertIntersectionGroups(groups []IntersectionGroupInfo) []ExcludedInterse
 
 // buildSimpleComplexExclusionInput creates an ExclusionConfig with only simple and complex
 // exclusions (no TTU or intersection exclusions).
-func buildSimpleComplexExclusionInput(a RelationAnalysis, databaseSchema string, objectIDExpr, subjectTypeExpr, subjectIDExpr Expr) ExclusionConfig {
+func buildSimpleComplexExclusionInput(a RelationAnalysis, databaseSchema, tuplesTable string, objectIDExpr, subjectTypeExpr, subjectIDExpr Expr) ExclusionConfig {
 	return ExclusionConfig{
 		DatabaseSchema:           databaseSchema,
+		TuplesTable:              tuplesTable,
 		ObjectType:               a.ObjectType,
 		ObjectIDExpr:             objectIDExpr,
 		SubjectTypeExpr:          subjectTypeExpr,
@@ -190,7 +190,7 @@ func buildWhereFromPredicates(predicates []Expr) Expr {
 }
 
 // buildUsersetWildcardTailQuery builds the wildcard handling tail as a typed query for list_subjects functions.
-func buildUsersetWildcardTailQuery(a RelationAnalysis, databaseSchema string) SQLer {
+func buildUsersetWildcardTailQuery(a RelationAnalysis, databaseSchema string) SelectStmt {
 	if a.Features.HasWildcard {
 		return SelectStmt{
 			ColumnExprs: []Expr{Col{Table: "br", Column: "subject_id"}},
@@ -246,7 +246,7 @@ func wildcardSubjectsTailWhere(schema, relation, objectType string, hasExclusion
 }
 
 // buildDispatcherBody builds the common routing logic for list dispatcher functions.
-func buildDispatcherBody(cases []ListDispatcherCase, callArgs string) []Stmt {
+func buildDispatcherBody(cases []ListDispatcherCase, callArgs []Expr) []Stmt {
 	var stmts []Stmt
 	if len(cases) > 0 {
 		stmts = append(stmts, Comment{Text: "Route to specialized functions, nested by object type then relation"})
@@ -264,12 +264,16 @@ func buildDispatcherBody(cases []ListDispatcherCase, callArgs string) []Stmt {
 		for _, ot := range order {
 			inner := make([]Stmt, 0, len(byType[ot]))
 			for _, c := range byType[ot] {
+				then := []Stmt{
+					ReturnQuery{Query: "SELECT * FROM " + Func{Schema: c.DatabaseSchema, Name: c.FunctionName, Args: callArgs}.SQL()},
+					Return{},
+				}
+				if c.NotListable {
+					then = []Stmt{Raise{Message: "relation not listable: " + c.ObjectType + "." + c.Relation, ErrCode: ErrCodeRelationNotListable}}
+				}
 				inner = append(inner, If{
 					Cond: Eq{Left: Param("p_relation"), Right: Lit(c.Relation)},
-					Then: []Stmt{
-						ReturnQuery{Query: "SELECT * FROM " + sqldsl.PrefixIdent(c.FunctionName, c.DatabaseSchema) + "(" + callArgs + ")"},
-						Return{},
-					},
+					Then: then,
 				})
 			}
 			// Known type, unknown relation → return empty immediately, mirroring
@@ -291,7 +295,7 @@ func buildDispatcherBody(cases []ListDispatcherCase, callArgs string) []Stmt {
 }
 
 // generateListObjectsDispatcher generates the list_accessible_objects dispatcher function.
-func generateListObjectsDispatcher(analyses []RelationAnalysis, databaseSchema string) (string, error) {
+func generateListObjectsDispatcher(analyses []RelationAnalysis, databaseSchema string, nullGuards bool) (string, error) {
 	cases := collectListDispatcherCases(analyses, listObjectsFunctionName, databaseSchema)
 
 	fn := PlpgsqlFunction{
@@ -303,16 +307,17 @@ func generateListObjectsDispatcher(analyses []RelationAnalysis, databaseSchema s
 			"Generated dispatcher for list_accessible_objects",
 			"Routes to specialized functions for all type/relation pairs",
 		},
-		Body: buildDispatcherBody(cases, "p_subject_type, p_subject_id, p_limit, p_after"),
+		Body: buildDispatcherBody(cases, ForwardArgs(ListObjectsArgs())),
 		// Routes only to schema-qualified list_{type}_{rel}_obj calls, no
 		// unqualified melange_tuples.
 		NoSearchPath: true,
+		NullGuards:   nullGuards,
 	}
 	return fn.SQL(), nil
 }
 
 // generateListSubjectsDispatcher generates the list_accessible_subjects dispatcher function.
-func generateListSubjectsDispatcher(analyses []RelationAnalysis, databaseSchema string) (string, error) {
+func generateListSubjectsDispatcher(analyses []RelationAnalysis, databaseSchema string, nullGuards bool) (string, error) {
 	cases := collectListDispatcherCases(analyses, listSubjectsFunctionName, databaseSchema)
 
 	fn := PlpgsqlFunction{
@@ -324,26 +329,57 @@ func generateListSubjectsDispatcher(analyses []RelationAnalysis, databaseSchema
 			"Generated dispatcher for list_accessible_subjects",
 			"Routes to specialized functions for all type/relation pairs",
 		},
-		Body: buildDispatcherBody(cases, "p_object_id, p_subject_type, p_limit, p_after"),
+		Body: buildDispatcherBody(cases, ForwardArgs(ListSubjectsArgs())),
 		// Routes only to schema-qualified list_{type}_{rel}_sub calls, no
 		// unqualified melange_tuples.
 		NoSearchPath: true,
+		NullGuards:   nullGuards,
 	}
 	return fn.SQL(), nil
 }
 
-// collectListDispatcherCases gathers eligible analyses into dispatcher cases.
-func collectListDispatcherCases(analyses []RelationAnalysis, nameFunc func(string, string) string, databaseSchema string) []ListDispatcherCase {
+// normalizeTypeOnlyFilter distinguishes a type-only filter from a
+// type#relation filter at the top of each list_subjects function. A subject
+// type with a trailing '#' (e.g. "document#") names no relation, so it is
+// rewritten to the bare type and takes the regular subject path instead of
+// the userset filter path, whether the function is called directly or through
+// list_accessible_subjects.
+func normalizeTypeOnlyFilter() []Stmt {
+	return []Stmt{
+		Comment{Text: "Type-only filter: a trailing '#' names no relation, so read it as the bare type"},
+		If{
+			Cond: Like{Expr: SubjectType, Pattern: Lit("%#")},
+			Then: []Stmt{
+				Assign{Name: "p_subject_type", Value: Func{Name: "rtrim", Args: []Expr{SubjectType, Lit("#")}}},
+			},
+		},
+	}
+}
+
+// collectListDispatcherCases gathers analyses into dispatcher cases: one
+// routing case per listable relation, and a NotListable case per relation that
+// has a check function but no list function, so listing it raises instead of
+// silently returning nothing. Relations with no access paths get no case and
+// fall through to the empty result, matching check_permission's deny.
+func collectListDispatcherCases(analyses []RelationAnalysis, nameFunc func(NameCollisions, string, string) string, databaseSchema string) []ListDispatcherCase {
 	var cases []ListDispatcherCase
 	for _, a := range analyses {
 		if !a.Capabilities.ListAllowed {
+			if a.Capabilities.CheckAllowed {
+				cases = append(cases, ListDispatcherCase{
+					DatabaseSchema: databaseSchema,
+					ObjectType:     a.ObjectType,
+					Relation:       a.Relation,
+					NotListable:    true,
+				})
+			}
 			continue
 		}
 		cases = append(cases, ListDispatcherCase{
 			DatabaseSchema: databaseSchema,
 			ObjectType:     a.ObjectType,
 			Relation:       a.Relation,
-			FunctionName:   nameFunc(a.ObjectType, a.Relation),
+			FunctionName:   nameFunc(a.NameCollisions, a.ObjectType, a.Relation),
 		})
 	}
 	return cases
@@ -0,0 +1,63 @@
+package sqlgen
+
+import "slices"
+
+// ListObjectsAnyFunctionName is the list_objects combinator that takes several
+// relations at once and lists the objects the subject has any of them on.
+const ListObjectsAnyFunctionName = "list_accessible_objects_any"
+
+// generateListObjectsAnyDispatcher renders list_accessible_objects_any, which
+// lists the objects the subject has any relation in p_relations on ("viewer
+// or editor"), each once. Every relation goes through list_accessible_objects,
+// so the combinator inherits its routing: a relation without a list function
+// raises M2003, and an unknown relation lists nothing.
+//
+// The union runs over the full lists and pagination applies to the combined,
+// deduplicated set ordered by object_id, so a cursor is valid across
+// relations and an object granted by two relations is returned once.
+func generateListObjectsAnyDispatcher(databaseSchema string) string {
+	query := SelectStmt{
+		ColumnExprs: []Expr{Col{Table: "l", Column: "object_id"}},
+		FromExpr:    FunctionCallExpr{Name: "unnest", Args: []Expr{Param("p_relations")}, Alias: "r(relation)"},
+		Joins: []JoinClause{{
+			Type: "CROSS",
+			TableExpr: LateralFunction{
+				Schema: databaseSchema,
+				Name:   "list_accessible_objects",
+				Args: CallArgs(ListObjectsDispatcherArgs(), map[string]Expr{
+					"p_subject_type":  SubjectType,
+					"p_subject_id":    SubjectID,
+					"p_relation":      Col{Table: "r", Column: "relation"},
+					"p_object_type":   ObjectType,
+					"p_subject_types": Param("p_subject_types"),
+					"p_context":       Param("p_context"),
+					"p_consistency":   Param("p_consistency"),
+				}),
+				Alias: "l",
+			},
+		}},
+	}
+
+	// p_exclude_relation is not taken: callers can exclude on each relation
+	// through list_accessible_objects.
+	args := ListObjectsDispatcherArgs()
+	args = slices.DeleteFunc(args, func(a FuncArg) bool { return a.Name == "p_exclude_relation" })
+	args[2] = FuncArg{Name: "p_relations", Type: "TEXT[]"}
+
+	fn := SqlFunction{
+		Schema:  databaseSchema,
+		Name:    ListObjectsAnyFunctionName,
+		Args:    args,
+		Returns: "TABLE (object_id TEXT, next_cursor TEXT) ROWS 100",
+		Body:    Raw(wrapWithPaginationOpts(query.SQL(), "object_id", false)),
+		Header: []string{
+			"Generated combinator " + ListObjectsAnyFunctionName,
+			"Lists objects the subject has any relation in p_relations on",
+		},
+		// Calls only the schema-qualified list_accessible_objects dispatcher.
+		NoSearchPath: true,
+	}
+	// p_context and p_consistency were appended after the combinator first
+	// shipped.
+	return withLegacyDrop(fn.SQL(), ListObjectsAnyFunctionName, databaseSchema, args, "p_context", "p_consistency") + "\n"
+}
@@ -0,0 +1,21 @@
+package sqlgen
+
+import "testing"
+
+// list_accessible_objects_any must route every relation through the
+// list_accessible_objects dispatcher and paginate the deduplicated union, so
+// one cursor walks the combined set.
+func TestListObjectsAnyDispatcher(t *testing.T) {
+	sql := generateListObjectsAnyDispatcher("authz")
+
+	assertContains(t, sql, `CREATE OR REPLACE FUNCTION "authz"."list_accessible_objects_any"(`)
+	assertContains(t, sql, "p_subject_id TEXT,\n    p_relations TEXT[],\n    p_object_type TEXT,\n    p_limit INT DEFAULT NULL,")
+	assertContains(t, sql, "FROM unnest(p_relations) AS r(relation)")
+	assertContains(t, sql, `CROSS JOIN LATERAL "authz"."list_accessible_objects"(p_subject_type => p_subject_type, p_subject_id => p_subject_id, p_relation => r.relation, p_object_type => p_object_type, p_subject_types => p_subject_types, p_context => p_context, p_consistency => p_consistency) AS l`)
+	assertContains(t, sql, `DROP FUNCTION IF EXISTS "authz"."list_accessible_objects_any"(TEXT, TEXT, TEXT[], TEXT, INT, TEXT, TEXT[]);`)
+	assertContains(t, sql, `DROP FUNCTION IF EXISTS "authz"."list_accessible_objects_any"(TEXT, TEXT, TEXT[], TEXT, INT, TEXT, TEXT[], JSONB);`)
+	assertContains(t, sql, "p_context JSONB DEFAULT NULL,\n    p_consistency TEXT DEFAULT NULL\n)")
+	assertContains(t, sql, "SELECT DISTINCT br.object_id")
+	assertContains(t, sql, "ORDER BY br.object_id")
+	assertNotContains(t, sql, "SET search_path")
+}
@@ -0,0 +1,88 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func anytimeRecursiveBlocks(recursive bool) (ListPlan, RecursiveBlockSet) {
+	plan := ListPlan{
+		FunctionName: "list_folder_viewer_obj",
+		ObjectType:   "folder",
+		Relation:     "viewer",
+		Analysis:     RelationAnalysis{ObjectType: "folder", Relation: "viewer"},
+	}
+	blocks := RecursiveBlockSet{
+		BaseBlocks: []TypedQueryBlock{{
+			Comments:     []string{"-- Direct tuple lookup"},
+			Query:        TuplesWithTable("", "", "t").ObjectType("folder").Relations("viewer").Select("t.object_id").Build(),
+			Propagatable: true,
+		}},
+		SelfCandidateBlock: buildListObjectsSelfCandidateBlock(plan),
+	}
+	if recursive {
+		blocks.RecursiveBlock = &TypedQueryBlock{
+			Comments: []string{"-- Self-referential TTU"},
+			Query: SelectStmt{
+				ColumnExprs: []Expr{Raw("child.object_id"), Raw("a.depth + 1 AS depth"), Raw("TRUE AS propagatable")},
+				FromExpr:    TableAs("", "accessible", "a"),
+				Where:       Lt{Left: Col{Table: "a", Column: "depth"}, Right: Int(25)},
+			},
+		}
+	}
+	return plan, blocks
+}
+
+func TestAnytimeListObjects_BaseGrantsFirst(t *testing.T) {
+	plan, blocks := anytimeRecursiveBlocks(true)
+	plan.AnytimeListObjects = true
+
+	sql, err := RenderListObjectsRecursiveFunction(plan, blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, sql, "IF (p_limit IS NULL AND NULLIF(p_after, '') IS NULL) THEN")
+	unpaged := sql[strings.Index(sql, "IF (p_limit IS NULL"):strings.Index(sql, "END IF;")]
+	assertContains(t, unpaged, "WHERE (acc.depth = 0 AND NOT EXISTS (SELECT 1 FROM excluded AS x WHERE x.object_id = acc.object_id))")
+	assertContains(t, unpaged, "WHERE (acc.depth > 0 AND NOT EXISTS (SELECT 1 FROM early AS e WHERE e.object_id = acc.object_id) AND CASE WHEN EXISTS (SELECT 1 FROM accessible AS dl WHERE dl.depth >= 25")
+	assertContains(t, unpaged, "self_candidate(object_id) AS (")
+	assertContains(t, unpaged, "RETURN;")
+	assertNotContains(t, unpaged, "\n    UNION\n")
+
+	// p_exclude_relation removes objects from every branch, as the paged
+	// query's EXCEPT does.
+	assertContains(t, unpaged, "excluded AS (")
+	assertContains(t, unpaged, "p_relation => p_exclude_relation")
+	assertContains(t, unpaged, "AND NOT EXISTS (SELECT 1 FROM excluded AS x WHERE x.object_id = s.object_id))")
+
+	// Branches come out early, deep, then self-candidate.
+	early := strings.Index(unpaged, "FROM early AS e\n")
+	deep := strings.Index(unpaged, "FROM deep AS d\n")
+	self := strings.Index(unpaged, "FROM self_candidate AS s")
+	if early < 0 || deep < early || self < deep {
+		t.Errorf("expected early, deep, self_candidate branches in order:\n%s", unpaged)
+	}
+
+	// Paged calls still order by object_id.
+	paged := sql[strings.Index(sql, "END IF;"):]
+	assertContains(t, paged, "ORDER BY br.object_id::TEXT")
+}
+
+func TestAnytimeListObjects_GatedByOptionAndRecursion(t *testing.T) {
+	plan, blocks := anytimeRecursiveBlocks(true)
+	off, err := RenderListObjectsRecursiveFunction(plan, blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNotContains(t, off, "p_limit IS NULL AND NULLIF(p_after, '') IS NULL")
+
+	// Without a recursive block there is nothing to stream ahead of.
+	plan, blocks = anytimeRecursiveBlocks(false)
+	plan.AnytimeListObjects = true
+	flat, err := RenderListObjectsRecursiveFunction(plan, blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNotContains(t, flat, "p_limit IS NULL AND NULLIF(p_after, '') IS NULL")
+}
@@ -34,18 +34,21 @@ func BuildListObjectsBlocks(plan ListPlan) (BlockSet, error) {
 func buildStandaloneAccessBlocks(plan ListPlan) ([]TypedQueryBlock, error) {
 	var blocks []TypedQueryBlock
 
-	directBlock, err := buildListObjectsDirectBlock(plan)
-	if err != nil {
-		return nil, err
-	}
-	blocks = append(blocks, directBlock)
-
-	if plan.HasUsersetSubject {
-		usersetBlock, err := buildListObjectsUsersetSubjectBlock(plan)
+	// As in check, tuples stored under the relation or its simple closure
+	// only grant when the relation has direct types or implied relations.
+	// Without them, as in `(editor and viewer from parent) but not blocked`,
+	// check never reads such tuples, so neither does list.
+	if plan.Analysis.Features.HasDirect || plan.Analysis.Features.HasImplied {
+		directBlock, err := buildListObjectsDirectBlock(plan)
 		if err != nil {
 			return nil, err
 		}
-		blocks = append(blocks, usersetBlock)
+		blocks = append(blocks, directBlock)
+	}
+	blocks = append(blocks, buildListObjectsConditionBlocks(plan)...)
+
+	if plan.HasUsersetSubject {
+		blocks = append(blocks, buildListObjectsUsersetSubjectBlock(plan, plan.RelationList))
 	}
 
 	complexBlocks, err := buildTypedListObjectsComplexClosureBlocks(plan)
@@ -68,18 +71,54 @@ func buildStandaloneAccessBlocks(plan ListPlan) ([]TypedQueryBlock, error) {
 		blocks = append(blocks, usersetPatternBlocks...)
 	}
 
+	blocks = append(blocks, buildListObjectsStandaloneParentBlocks(plan)...)
+
 	return blocks, nil
 }
 
+// buildListObjectsStandaloneParentBlocks builds a block for each TTU arm
+// outside the relation's intersection groups, such as "admin from org" in
+// `can_manage: admin from org or (editor and can_manage from parent)`.
+// Relations without intersection take the Recursive or Composed strategy, so
+// these arms only reach this builder alongside intersection groups. A
+// self-referential arm is resolved per candidate by check_permission_internal,
+// as in an intersection's TTU part, rather than by a recursive CTE.
+func buildListObjectsStandaloneParentBlocks(plan ListPlan) []TypedQueryBlock {
+	var blocks []TypedQueryBlock
+	for _, pr := range plan.Analysis.ParentRelations {
+		q := parentRelationObjectsQuery(plan, pr, "t")
+		for _, pred := range plan.Exclusions.BuildPredicates() {
+			q.Where(pred)
+		}
+		blocks = append(blocks, TypedQueryBlock{
+			Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(pr.LinkingRelation, pr.Relation), fmt.Sprintf("-- TTU path via %s -> %s", pr.LinkingRelation, pr.Relation)),
+			Query:    q.Build(),
+		})
+	}
+	return blocks
+}
+
+// subjectTypesFilter is the p_subject_types guard on a block reading tuples of
+// the listed relation: it keeps a tuple only when its subject type, given by
+// subjectType, is in p_subject_types. A NULL p_subject_types keeps every type.
+func subjectTypesFilter(subjectType Expr) Expr {
+	return Or(
+		IsNull{Expr: Param("p_subject_types")},
+		ArrayContains{Value: subjectType, Array: Param("p_subject_types")},
+	)
+}
+
 // buildListObjectsDirectBlock builds the direct tuple lookup query block.
 func buildListObjectsDirectBlock(plan ListPlan) (TypedQueryBlock, error) {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(plan.RelationList...).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: SubjectType},
 			In{Expr: SubjectType, Values: plan.AllowedSubjectTypes},
-			SubjectIDMatch(Col{Table: "t", Column: "subject_id"}, SubjectID, plan.AllowWildcard),
+			subjectTypesFilter(SubjectType),
+			plan.directSubjectIDMatch(Col{Table: "t", Column: "subject_id"}),
+			conditionalTupleGuard(plan.Analysis, "t"),
 		).
 		SelectCol("object_id").
 		Distinct()
@@ -90,10 +129,10 @@ func buildListObjectsDirectBlock(plan ListPlan) (TypedQueryBlock, error) {
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeDirect, "",
 			"-- Direct tuple lookup with simple closure relations",
 			"-- Type guard: only return results if subject type is in allowed subject types",
-		},
+		),
 		Query: q.Build(),
 	}, nil
 }
@@ -106,15 +145,12 @@ func buildListObjectsDirectBlock(plan ListPlan) (TypedQueryBlock, error) {
 // candidate arm so a userset query subject is admitted as a candidate wherever
 // a plain subject would be.
 func usersetSubjectCandidateMatch(plan ListPlan) Expr {
-	closureExistsStmt := SelectStmt{
-		ColumnExprs: []Expr{Int(1)},
-		FromExpr:    ClosureTable(plan.Inline.ClosureRows, "c"),
-		Where: And(
-			Eq{Left: Col{Table: "c", Column: "object_type"}, Right: SubjectType},
-			Eq{Left: Col{Table: "c", Column: "relation"}, Right: UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}}},
-			Eq{Left: Col{Table: "c", Column: "satisfying_relation"}, Right: UsersetRelation{Source: SubjectID}},
-		),
-	}
+	closureExistsStmt := closureContains(plan, "c",
+		SubjectType,
+		UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
+		UsersetRelation{Source: SubjectID},
+		false,
+	)
 
 	// Subject match: either exact match or userset object ID match with closure exists
 	subjectMatch := Or(
@@ -145,19 +181,26 @@ func complexClosureCandidateMatch(plan ListPlan) Expr {
 	return Or(
 		And(
 			In{Expr: SubjectType, Values: plan.AllowedSubjectTypes},
-			SubjectIDMatch(Col{Table: "t", Column: "subject_id"}, SubjectID, plan.AllowWildcard),
+			plan.directSubjectIDMatch(Col{Table: "t", Column: "subject_id"}),
 		),
 		usersetSubjectCandidateMatch(plan),
 	)
 }
 
-// buildListObjectsUsersetSubjectBlock builds the userset subject matching block.
-func buildListObjectsUsersetSubjectBlock(plan ListPlan) (TypedQueryBlock, error) {
-	q := Tuples(plan.DatabaseSchema, "t").
+// buildListObjectsUsersetSubjectBlock builds the userset subject matching block:
+// tuples on relations that name the userset query subject (e.g. group:eng#member)
+// directly, or a userset of the same object whose relation the subject's
+// relation satisfies. Every strategy whose base blocks enumerate RelationList
+// tuples needs it; without it a userset query subject only reaches objects
+// through the membership-join arms, which look for the subject *inside* the
+// userset and so return nothing when the userset itself is the grantee.
+func buildListObjectsUsersetSubjectBlock(plan ListPlan, relations []string) TypedQueryBlock {
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
-		Relations(plan.RelationList...).
+		Relations(relations...).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: SubjectType},
+			subjectTypesFilter(SubjectType),
 			usersetSubjectCandidateMatch(plan),
 		).
 		SelectCol("object_id").
@@ -169,12 +212,12 @@ func buildListObjectsUsersetSubjectBlock(plan ListPlan) (TypedQueryBlock, error)
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeUserset, "",
 			"-- Userset subject matching: subject IS a userset (e.g., group:fga#member)",
 			"-- matches tuples where subject_id has equivalent or satisfying relation via closure",
-		},
+		),
 		Query: q.Build(),
-	}, nil
+	}
 }
 
 // buildTypedListObjectsComplexClosureBlocks builds blocks for complex closure relations.
@@ -185,7 +228,7 @@ func buildTypedListObjectsComplexClosureBlocks(plan ListPlan) ([]TypedQueryBlock
 
 	var blocks []TypedQueryBlock
 	for _, rel := range plan.ComplexClosure {
-		q := Tuples(plan.DatabaseSchema, "t").
+		q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 			ObjectType(plan.ObjectType).
 			Relations(rel).
 			Where(
@@ -202,10 +245,10 @@ func buildTypedListObjectsComplexClosureBlocks(plan ListPlan) ([]TypedQueryBlock
 		}
 
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{
+			Comments: plan.traceComments(TraceNodeImplied, rel,
 				"-- Complex closure relations: find candidates via tuples, validate via list composition (or check_permission_internal fallback)",
 				"-- These relations have exclusions or other complex features that require full permission check",
-			},
+			),
 			Query: q.Build(),
 		})
 	}
@@ -221,13 +264,13 @@ func buildListObjectsIntersectionClosureBlocks(plan ListPlan) ([]TypedQueryBlock
 
 	var blocks []TypedQueryBlock
 	for _, rel := range plan.Analysis.IntersectionClosureRelations {
-		funcName := listObjectsFunctionName(plan.ObjectType, rel)
+		funcName := listObjectsFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, rel)
 		stmt := SelectStmt{
 			ColumnExprs: []Expr{Col{Table: "icr", Column: "object_id"}},
 			FromExpr: FunctionCallExpr{
 				Schema: plan.DatabaseSchema,
 				Name:   funcName,
-				Args:   []Expr{SubjectType, SubjectID, Null{}, Null{}},
+				Args:   listObjectsCallArgs(SubjectType, SubjectID),
 				Alias:  "icr",
 			},
 		}
@@ -239,6 +282,7 @@ func buildListObjectsIntersectionClosureBlocks(plan ListPlan) ([]TypedQueryBlock
 			exclusionConfig := buildExclusionInput(
 				plan.Analysis,
 				plan.DatabaseSchema,
+				plan.TuplesTable,
 				Col{Table: "icr", Column: "object_id"},
 				SubjectType,
 				SubjectID,
@@ -250,9 +294,9 @@ func buildListObjectsIntersectionClosureBlocks(plan ListPlan) ([]TypedQueryBlock
 		}
 
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{
-				"-- Compose with intersection closure relation: " + rel,
-			},
+			Comments: plan.traceComments(TraceNodeIntersection, rel,
+				"-- Compose with intersection closure relation: "+rel,
+			),
 			Query: stmt,
 		})
 	}
@@ -264,6 +308,12 @@ func buildListObjectsIntersectionClosureBlocks(plan ListPlan) ([]TypedQueryBlock
 // Each intersection group represents an AND of parts that must all be satisfied.
 // Multiple groups are OR'd together (UNION).
 // Within each group, parts are AND'd together (INTERSECT).
+//
+// A relation-level exclusion is applied inside each group block rather than
+// once around the UNION. The two are equivalent: every exclusion predicate
+// reads only the candidate object_id and the query subject, never anything
+// group-specific, so (G0 ∖ E) ∪ (G1 ∖ E) = (G0 ∪ G1) ∖ E for
+// "((a and b) or (c and d)) but not e". No enclosing block re-applies it.
 func buildListObjectsIntersectionGroupBlocks(plan ListPlan) ([]TypedQueryBlock, error) {
 	if len(plan.Analysis.IntersectionGroups) == 0 {
 		return nil, nil
@@ -304,10 +354,13 @@ func buildIntersectionGroupBlock(plan ListPlan, idx int, group IntersectionGroup
 	// Apply exclusion predicates to the intersection result
 	// Exclusions are configured at the relation level and applied after the INTERSECT
 	// We need to rebuild the exclusion config with the correct object_id reference (ig.object_id)
+	// Applying them per group is equivalent to applying them once to the union
+	// of groups; see buildListObjectsIntersectionGroupBlocks.
 	if plan.HasExclusion {
 		exclusionConfig := buildExclusionInput(
 			plan.Analysis,
 			plan.DatabaseSchema,
+			plan.TuplesTable,
 			Col{Table: "ig", Column: "object_id"}, // Use ig.object_id for intersection result
 			SubjectType,
 			SubjectID,
@@ -319,9 +372,9 @@ func buildIntersectionGroupBlock(plan ListPlan, idx int, group IntersectionGroup
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeIntersection, "",
 			fmt.Sprintf("-- Intersection group %d: all parts must be satisfied", idx),
-		},
+		),
 		Query: intersectQuery,
 	}, nil
 }
@@ -337,47 +390,13 @@ func buildIntersectionPartQuery(plan ListPlan, part IntersectionPart) SelectStmt
 
 	case part.ParentRelation != nil:
 		alias = "child"
-		pr := part.ParentRelation
-		childType := Col{Table: alias, Column: "subject_type"}
-		childID := Col{Table: alias, Column: "subject_id"}
-		// The parent object (child.subject_id, of type child.subject_type) must
-		// satisfy pr.Relation. Compose against the parent's list_objects set (a
-		// set-based semi-join) instead of a per-row check_permission_internal when
-		// composition is cycle-safe, keeping a userset-guarded check arm for
-		// parity. Per linking type, since the list function name is per-type.
-		check := CheckPermission{
-			Schema:      plan.DatabaseSchema,
-			Subject:     SubjectParams(),
-			Relation:    pr.Relation,
-			Object:      ObjectRef{Type: childType, ID: childID},
-			ExpectAllow: true,
-		}
-		var membership Expr = check
-		if len(pr.AllowedLinkingTypes) > 0 {
-			arms := make([]Expr, 0, len(pr.AllowedLinkingTypes))
-			for _, ptype := range pr.AllowedLinkingTypes {
-				typed := Eq{Left: childType, Right: Lit(ptype)}
-				if composableListTarget(plan, ptype, pr.Relation) {
-					arms = append(arms, And(typed, composedListObjectsMembership(
-						plan.DatabaseSchema, ptype, pr.Relation, childID, SubjectType, SubjectID, "parent_obj", check)))
-				} else {
-					arms = append(arms, And(typed, check))
-				}
-			}
-			membership = Or(arms...)
-		}
-		q = Tuples(plan.DatabaseSchema, alias).
-			ObjectType(plan.ObjectType).
-			Relations(pr.LinkingRelation).
-			SelectCol("object_id").
-			Where(membership).
-			Distinct()
+		q = parentRelationObjectsQuery(plan, *part.ParentRelation, alias)
 
 	default:
 		if intersectionPartComposable(plan, part.Relation) {
 			return buildIntersectionComposedPartQuery(plan, part)
 		}
-		q = Tuples(plan.DatabaseSchema, alias).
+		q = TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, alias).
 			ObjectType(plan.ObjectType).
 			SelectCol("object_id").
 			Where(intersectionPartMembership(plan, part.Relation, Col{Table: alias, Column: "object_id"})).
@@ -391,6 +410,45 @@ func buildIntersectionPartQuery(plan ListPlan, part IntersectionPart) SelectStmt
 	return q.Build()
 }
 
+// parentRelationObjectsQuery selects the objects of plan's type linked through
+// pr.LinkingRelation to a parent on which the subject holds pr.Relation.
+func parentRelationObjectsQuery(plan ListPlan, pr ParentRelationInfo, alias string) *TupleQuery {
+	childType := Col{Table: alias, Column: "subject_type"}
+	childID := Col{Table: alias, Column: "subject_id"}
+	// The parent object (subject_id, of type subject_type) must satisfy
+	// pr.Relation. Compose against the parent's list_objects set (a set-based
+	// semi-join) instead of a per-row check_permission_internal when
+	// composition is cycle-safe, keeping a userset-guarded check arm for
+	// parity. Per linking type, since the list function name is per-type.
+	check := CheckPermission{
+		Schema:      plan.DatabaseSchema,
+		Subject:     SubjectParams(),
+		Relation:    pr.Relation,
+		Object:      ObjectRef{Type: childType, ID: childID},
+		ExpectAllow: true,
+	}
+	var membership Expr = check
+	if len(pr.AllowedLinkingTypes) > 0 {
+		arms := make([]Expr, 0, len(pr.AllowedLinkingTypes))
+		for _, ptype := range pr.AllowedLinkingTypes {
+			typed := Eq{Left: childType, Right: Lit(ptype)}
+			if composableListTarget(plan, ptype, pr.Relation) {
+				arms = append(arms, And(typed, composedListObjectsMembership(
+					plan.DatabaseSchema, plan.Analysis.NameCollisions, ptype, pr.Relation, childID, SubjectType, SubjectID, "parent_obj", check)))
+			} else {
+				arms = append(arms, And(typed, check))
+			}
+		}
+		membership = Or(arms...)
+	}
+	return TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, alias).
+		ObjectType(plan.ObjectType).
+		Relations(pr.LinkingRelation).
+		SelectCol("object_id").
+		Where(membership).
+		Distinct()
+}
+
 // buildIntersectionComposedPartQuery builds a composable positive intersection
 // part as the specialized list-set directly, dropping the object-type-wide
 // melange_tuples scan the membership predicate otherwise filters against.
@@ -408,8 +466,8 @@ func buildIntersectionComposedPartQuery(plan ListPlan, part IntersectionPart) Se
 		ColumnExprs: []Expr{Col{Table: "obj", Column: "object_id"}},
 		FromExpr: FunctionCallExpr{
 			Schema: plan.DatabaseSchema,
-			Name:   ListObjectsFunctionName(plan.ObjectType, part.Relation),
-			Args:   []Expr{SubjectType, SubjectID, Null{}, Null{}},
+			Name:   ListObjectsFunctionNameWithCollisions(plan.Analysis.NameCollisions, plan.ObjectType, part.Relation),
+			Args:   listObjectsCallArgs(SubjectType, SubjectID),
 			Alias:  "obj",
 		},
 	}
@@ -421,7 +479,7 @@ func buildIntersectionComposedPartQuery(plan ListPlan, part IntersectionPart) Se
 		Object:      LiteralObject(plan.ObjectType, Col{Table: "t", Column: "object_id"}),
 		ExpectAllow: true,
 	}
-	usersetArm := Tuples(plan.DatabaseSchema, "t").
+	usersetArm := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		SelectCol("object_id").
 		Where(And(HasUserset{Source: SubjectID}, usersetCheck)).
@@ -447,13 +505,14 @@ func buildIntersectionComposedPartQuery(plan ListPlan, part IntersectionPart) Se
 // check_permission allows.
 func buildIntersectionThisPartQuery(plan ListPlan, part IntersectionPart) SelectStmt {
 	alias := "t"
-	directArm := Tuples(plan.DatabaseSchema, alias).
+	directArm := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, alias).
 		ObjectType(plan.ObjectType).
 		Relations(plan.Relation).
 		SelectCol("object_id").
 		Where(
 			Eq{Left: Col{Table: alias, Column: "subject_type"}, Right: SubjectType},
 			In{Expr: SubjectType, Values: plan.AllowedSubjectTypes},
+			subjectTypesFilter(SubjectType),
 			SubjectIDMatch(Col{Table: alias, Column: "subject_id"}, SubjectID, part.HasWildcard),
 		).
 		Distinct().
@@ -489,11 +548,12 @@ func buildIntersectionThisPartQuery(plan ListPlan, part IntersectionPart) Select
 // group membership. Mirrors buildListObjectsSimpleUsersetBlock / its complex
 // variant, restricted to the wrapping relation's own grant tuples.
 func buildIntersectionThisUsersetArm(plan ListPlan, pattern listUsersetPatternInput) SelectStmt {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(plan.Relation).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(pattern.SubjectType)},
+			subjectTypesFilter(Lit(pattern.SubjectType)),
 			HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
 			Eq{
 				Left:  UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
@@ -540,11 +600,12 @@ func buildListObjectsUsersetPatternBlocks(plan ListPlan) ([]TypedQueryBlock, err
 // relation's list function when composition is safe, or a per-candidate
 // check_permission_internal call otherwise.
 func buildListObjectsComplexUsersetBlock(plan ListPlan, pattern listUsersetPatternInput) (TypedQueryBlock, error) {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(pattern.SourceRelations...).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(pattern.SubjectType)},
+			subjectTypesFilter(Lit(pattern.SubjectType)),
 			HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
 			Eq{
 				Left:  UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
@@ -560,20 +621,21 @@ func buildListObjectsComplexUsersetBlock(plan ListPlan, pattern listUsersetPatte
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{
-			"-- Via " + pattern.SubjectType + "#" + pattern.SubjectRelation + " (complex userset, validated by check_permission)",
-		},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation),
+			"-- Via "+pattern.SubjectType+"#"+pattern.SubjectRelation+" (complex userset, validated by check_permission)",
+		),
 		Query: q.Build(),
 	}, nil
 }
 
 // buildListObjectsSimpleUsersetBlock builds a block for simple userset patterns.
 func buildListObjectsSimpleUsersetBlock(plan ListPlan, pattern listUsersetPatternInput) (TypedQueryBlock, error) {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(pattern.SourceRelations...).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(pattern.SubjectType)},
+			subjectTypesFilter(Lit(pattern.SubjectType)),
 			HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
 			Eq{
 				Left:  UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
@@ -598,9 +660,9 @@ func buildListObjectsSimpleUsersetBlock(plan ListPlan, pattern listUsersetPatter
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{
-			"-- Via " + pattern.SubjectType + "#" + pattern.SubjectRelation + " (simple userset, JOIN with membership tuples)",
-		},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation),
+			"-- Via "+pattern.SubjectType+"#"+pattern.SubjectRelation+" (simple userset, JOIN with membership tuples)",
+		),
 		Query: q.Build(),
 	}, nil
 }
@@ -619,7 +681,7 @@ func buildListObjectsSelfCandidateBlock(plan ListPlan) *TypedQueryBlock {
 	}
 
 	return &TypedQueryBlock{
-		Comments: []string{"-- Self-candidate: subject is userset on same object type"},
+		Comments: plan.traceComments(TraceNodeUserset, "", "-- Self-candidate: subject is userset on same object type"),
 		Query:    stmt,
 	}
 }
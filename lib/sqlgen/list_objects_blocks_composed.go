@@ -65,7 +65,7 @@ func BuildListObjectsComposedBlocks(plan ListPlan) (ComposedObjectsBlockSet, err
 
 	// Build main composed query blocks
 	firstStep := anchor.Path[0]
-	exclusions := buildSimpleComplexExclusionInput(plan.Analysis, plan.DatabaseSchema, Col{Table: "t", Column: "object_id"}, SubjectType, SubjectID)
+	exclusions := buildSimpleComplexExclusionInput(plan.Analysis, plan.DatabaseSchema, plan.TuplesTable, Col{Table: "t", Column: "object_id"}, SubjectType, SubjectID)
 
 	switch firstStep.Type {
 	case "ttu":
@@ -110,9 +110,9 @@ func buildComposedObjectsSelfBlock(plan ListPlan) (*TypedQueryBlock, error) {
 	}
 
 	return &TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeUserset, "",
 			"-- Self-candidate: when subject is a userset on the same object type",
-		},
+		),
 		Query: stmt,
 	}, nil
 }
@@ -125,8 +125,8 @@ func buildComposedTTUObjectsBlock(plan ListPlan, anchor *IndirectAnchorInfo, tar
 	inSubquery := InFunctionSelect{
 		Expr:      Col{Table: "t", Column: "subject_id"},
 		Schema:    plan.DatabaseSchema,
-		FuncName:  ListObjectsFunctionName(targetType, anchor.Path[0].TargetRelation),
-		Args:      []Expr{SubjectType, SubjectID, Null{}, Null{}},
+		FuncName:  ListObjectsFunctionNameWithCollisions(plan.Analysis.NameCollisions, targetType, anchor.Path[0].TargetRelation),
+		Args:      listObjectsCallArgs(SubjectType, SubjectID),
 		Alias:     "obj",
 		SelectCol: "object_id",
 	}
@@ -143,14 +143,14 @@ func buildComposedTTUObjectsBlock(plan ListPlan, anchor *IndirectAnchorInfo, tar
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: "t", Column: "object_id"}},
-		FromExpr:    TableAs("", "melange_tuples", "t"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 		Where:       And(conditions...),
 	}
 
 	return &TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(anchor.Path[0].LinkingRelation, anchor.Path[0].TargetRelation),
 			fmt.Sprintf("-- TTU composition: %s -> %s", anchor.Path[0].LinkingRelation, targetType),
-		},
+		),
 		Query: stmt,
 	}, nil
 }
@@ -176,7 +176,7 @@ func buildComposedRecursiveTTUObjectsBlock(plan ListPlan, anchor *IndirectAnchor
 
 	membership := check
 	if composableListTarget(plan, recursiveType, targetRel) {
-		membership = composedListObjectsMembership(plan.DatabaseSchema, recursiveType, targetRel, Col{Table: "t", Column: "subject_id"}, SubjectType, SubjectID, "obj", check)
+		membership = composedListObjectsMembership(plan.DatabaseSchema, plan.Analysis.NameCollisions, recursiveType, targetRel, Col{Table: "t", Column: "subject_id"}, SubjectType, SubjectID, "obj", check)
 	}
 
 	conditions := make([]Expr, 0, 4+len(exclusionPreds))
@@ -191,14 +191,14 @@ func buildComposedRecursiveTTUObjectsBlock(plan ListPlan, anchor *IndirectAnchor
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: "t", Column: "object_id"}},
-		FromExpr:    TableAs("", "melange_tuples", "t"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 		Where:       And(conditions...),
 	}
 
 	return &TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(anchor.Path[0].LinkingRelation, anchor.Path[0].TargetRelation),
 			fmt.Sprintf("-- Recursive TTU: %s -> %s", anchor.Path[0].LinkingRelation, recursiveType),
-		},
+		),
 		Query: stmt,
 	}, nil
 }
@@ -220,12 +220,12 @@ func buildComposedUsersetObjectsBlock(plan ListPlan, firstStep AnchorPathStep, e
 	exclusionPreds := exclusions.BuildPredicates()
 
 	// split_part(t.subject_id, '#', 1) extracts the object_id from the userset
-	usersetObjectID := Raw("split_part(t.subject_id, '#', 1)")
+	usersetObjectID := UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}}
 	check := CheckPermissionInternalExpr(plan.DatabaseSchema, SubjectParams(), firstStep.SubjectRelation, ObjectRef{Type: Lit(firstStep.SubjectType), ID: usersetObjectID}, true)
 
 	membership := check
 	if composableListTarget(plan, firstStep.SubjectType, firstStep.SubjectRelation) {
-		membership = composedListObjectsMembership(plan.DatabaseSchema, firstStep.SubjectType, firstStep.SubjectRelation, usersetObjectID, SubjectType, SubjectID, "obj", check)
+		membership = composedListObjectsMembership(plan.DatabaseSchema, plan.Analysis.NameCollisions, firstStep.SubjectType, firstStep.SubjectRelation, usersetObjectID, SubjectType, SubjectID, "obj", check)
 	}
 
 	conditions := make([]Expr, 0, 6+len(exclusionPreds))
@@ -242,14 +242,14 @@ func buildComposedUsersetObjectsBlock(plan ListPlan, firstStep AnchorPathStep, e
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: "t", Column: "object_id"}},
-		FromExpr:    TableAs("", "melange_tuples", "t"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 		Where:       And(conditions...),
 	}
 
 	return &TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(firstStep.SubjectType, firstStep.SubjectRelation),
 			fmt.Sprintf("-- Userset composition: %s#%s", firstStep.SubjectType, firstStep.SubjectRelation),
-		},
+		),
 		Query: stmt,
 	}, nil
 }
@@ -11,8 +11,8 @@ type RecursiveBlockSet struct {
 	RecursiveBlock     *TypedQueryBlock
 	SelfCandidateBlock *TypedQueryBlock
 	// HoistedCTEs are shared list_*_obj computations lifted out of the base
-	// blocks. The same list_<parent>_obj(p_subject_type, p_subject_id, NULL,
-	// NULL) call was previously inlined in both the userset-subject arm and the
+	// blocks. The same list_<parent>_obj(p_subject_type => p_subject_type,
+	// p_subject_id => p_subject_id) call was previously inlined in both the userset-subject arm and the
 	// cross-type-TTU subject-first arm; these CTEs compute each distinct call
 	// once and the arms reference them. Rendered before the accessible CTE.
 	HoistedCTEs []CTEDef
@@ -76,7 +76,7 @@ func collectHoistedListObjTargets(plan ListPlan, parentRelations []ListParentRel
 	targets = make(hoistedListObjTargets, len(order))
 	ctes = make([]CTEDef, 0, len(order))
 	for _, t := range order {
-		name := SafeIdentifier("", t.typ, t.rel, "_objs")
+		name := RelationIdentifier(plan.Analysis.NameCollisions, "", t.typ, t.rel, "_objs")
 		targets[hoistKey(t.typ, t.rel)] = name
 		ctes = append(ctes, CTEDef{
 			Name: name,
@@ -84,8 +84,8 @@ func collectHoistedListObjTargets(plan ListPlan, parentRelations []ListParentRel
 				ColumnExprs: []Expr{Col{Table: "o", Column: "object_id"}},
 				FromExpr: FunctionCallExpr{
 					Schema: plan.DatabaseSchema,
-					Name:   ListObjectsFunctionName(t.typ, t.rel),
-					Args:   []Expr{SubjectType, SubjectID, Null{}, Null{}},
+					Name:   ListObjectsFunctionNameWithCollisions(plan.Analysis.NameCollisions, t.typ, t.rel),
+					Args:   listObjectsCallArgs(SubjectType, SubjectID),
 					Alias:  "o",
 				},
 			},
@@ -183,6 +183,14 @@ func buildRecursiveBaseBlocks(plan ListPlan, parentRelations []ListParentRelatio
 	// Emitting separate blocks ensures per-relation propagatability.
 	blocks = append(blocks, buildRecursiveDirectBlocks(plan, propagatable)...)
 
+	// A userset query subject named directly on a tuple seeds the walk exactly
+	// like a direct grant, so it is split by propagatability the same way.
+	if plan.HasUsersetSubject {
+		blocks = append(blocks, splitBlocksByPropagation(plan.RelationList, propagatable, func(rels []string) TypedQueryBlock {
+			return buildListObjectsUsersetSubjectBlock(plan, rels)
+		})...)
+	}
+
 	for _, rel := range plan.ComplexClosure {
 		block := buildRecursiveComplexClosureBlock(plan, rel)
 		block.Propagatable = propagatable[rel]
@@ -223,13 +231,14 @@ func buildRecursiveDirectBlocks(plan ListPlan, propagatable map[string]bool) []T
 
 // buildRecursiveDirectBlock builds a single direct tuple lookup block for the given relations.
 func buildRecursiveDirectBlock(plan ListPlan, relations []string) TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(relations...).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: SubjectType},
 			In{Expr: SubjectType, Values: plan.AllowedSubjectTypes},
-			SubjectIDMatch(Col{Table: "t", Column: "subject_id"}, SubjectID, plan.AllowWildcard),
+			subjectTypesFilter(SubjectType),
+			plan.directSubjectIDMatch(Col{Table: "t", Column: "subject_id"}),
 		).
 		SelectCol("object_id").
 		Distinct()
@@ -239,14 +248,14 @@ func buildRecursiveDirectBlock(plan ListPlan, relations []string) TypedQueryBloc
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Direct tuple lookup with simple closure relations"},
+		Comments: plan.traceComments(TraceNodeDirect, "", "-- Direct tuple lookup with simple closure relations"),
 		Query:    q.Build(),
 	}
 }
 
 // buildRecursiveComplexClosureBlock builds a block for a single complex closure relation.
 func buildRecursiveComplexClosureBlock(plan ListPlan, rel string) TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(rel).
 		Where(
@@ -262,26 +271,26 @@ func buildRecursiveComplexClosureBlock(plan ListPlan, rel string) TypedQueryBloc
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Complex closure relation: %s", rel)},
+		Comments: plan.traceComments(TraceNodeImplied, rel, fmt.Sprintf("-- Complex closure relation: %s", rel)),
 		Query:    q.Build(),
 	}
 }
 
 // buildRecursiveIntersectionClosureBlock builds a block for a single intersection closure relation.
 func buildRecursiveIntersectionClosureBlock(plan ListPlan, rel string) TypedQueryBlock {
-	funcName := listObjectsFunctionName(plan.ObjectType, rel)
+	funcName := listObjectsFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, rel)
 	stmt := SelectStmt{
 		ColumnExprs: []Expr{Col{Table: "icr", Column: "object_id"}},
 		FromExpr: FunctionCallExpr{
 			Schema: plan.DatabaseSchema,
 			Name:   funcName,
-			Args:   []Expr{SubjectType, SubjectID, Null{}, Null{}},
+			Args:   listObjectsCallArgs(SubjectType, SubjectID),
 			Alias:  "icr",
 		},
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Intersection closure: %s", rel)},
+		Comments: plan.traceComments(TraceNodeIntersection, rel, fmt.Sprintf("-- Intersection closure: %s", rel)),
 		Query:    stmt,
 	}
 }
@@ -291,11 +300,12 @@ func buildRecursiveIntersectionClosureBlock(plan ListPlan, rel string) TypedQuer
 // relation's list function when composition is safe, or a per-candidate
 // check_permission_internal call otherwise.
 func buildRecursiveComplexUsersetBlock(plan ListPlan, pattern listUsersetPatternInput, hoisted hoistedListObjTargets) TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(pattern.SourceRelations...).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(pattern.SubjectType)},
+			subjectTypesFilter(Lit(pattern.SubjectType)),
 			HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
 			Eq{
 				Left:  UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
@@ -311,18 +321,19 @@ func buildRecursiveComplexUsersetBlock(plan ListPlan, pattern listUsersetPattern
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Userset: %s#%s (complex)", pattern.SubjectType, pattern.SubjectRelation)},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation), fmt.Sprintf("-- Userset: %s#%s (complex)", pattern.SubjectType, pattern.SubjectRelation)),
 		Query:    q.Build(),
 	}
 }
 
 // buildRecursiveSimpleUsersetBlock builds a block for simple userset patterns.
 func buildRecursiveSimpleUsersetBlock(plan ListPlan, pattern listUsersetPatternInput) TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(pattern.SourceRelations...).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(pattern.SubjectType)},
+			subjectTypesFilter(Lit(pattern.SubjectType)),
 			HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
 			Eq{
 				Left:  UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
@@ -347,7 +358,7 @@ func buildRecursiveSimpleUsersetBlock(plan ListPlan, pattern listUsersetPatternI
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Userset: %s#%s (simple)", pattern.SubjectType, pattern.SubjectRelation)},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation), fmt.Sprintf("-- Userset: %s#%s (simple)", pattern.SubjectType, pattern.SubjectRelation)),
 		Query:    q.Build(),
 	}
 }
@@ -460,6 +471,7 @@ func buildCrossTypeTTUBlocks(plan ListPlan, parentRelations []ListParentRelation
 			crossExclusions = buildExclusionInput(
 				plan.Analysis,
 				plan.DatabaseSchema,
+				plan.TuplesTable,
 				Col{Table: "child", Column: "object_id"},
 				SubjectType,
 				SubjectID,
@@ -519,8 +531,8 @@ func buildSubjectFirstCrossTypeTTUBlock(plan ListPlan, parent ListParentRelation
 	} else {
 		parentSource = FunctionCallExpr{
 			Schema: plan.DatabaseSchema,
-			Name:   ListObjectsFunctionName(parentType, parent.Relation),
-			Args:   []Expr{SubjectType, SubjectID, Null{}, Null{}},
+			Name:   ListObjectsFunctionNameWithCollisions(plan.Analysis.NameCollisions, parentType, parent.Relation),
+			Args:   listObjectsCallArgs(SubjectType, SubjectID),
 			Alias:  "parent_obj",
 		}
 	}
@@ -532,7 +544,7 @@ func buildSubjectFirstCrossTypeTTUBlock(plan ListPlan, parent ListParentRelation
 		Joins: []JoinClause{
 			{
 				Type:  "INNER",
-				Table: "melange_tuples",
+				Table: TuplesTableName(plan.TuplesTable),
 				Alias: "child",
 				On: And(
 					Eq{Left: Col{Table: "child", Column: "object_type"}, Right: Lit(plan.ObjectType)},
@@ -546,7 +558,7 @@ func buildSubjectFirstCrossTypeTTUBlock(plan ListPlan, parent ListParentRelation
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Cross-type TTU subject-first: %s -> %s.%s", parent.LinkingRelation, parentType, parent.Relation)},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), fmt.Sprintf("-- Cross-type TTU subject-first: %s -> %s.%s", parent.LinkingRelation, parentType, parent.Relation)),
 		Query:    stmt,
 	}
 }
@@ -588,7 +600,7 @@ func sourceRelationCheck(plan ListPlan, parent ListParentRelationData) Expr {
 	if !composableListTarget(plan, plan.ObjectType, parent.SourceRelation) {
 		return check
 	}
-	return composedListObjectsMembership(plan.DatabaseSchema, plan.ObjectType, parent.SourceRelation, Col{Table: "child", Column: "object_id"}, SubjectType, SubjectID, "src_obj", check)
+	return composedListObjectsMembership(plan.DatabaseSchema, plan.Analysis.NameCollisions, plan.ObjectType, parent.SourceRelation, Col{Table: "child", Column: "object_id"}, SubjectType, SubjectID, "src_obj", check)
 }
 
 // buildCheckPermissionCrossTypeTTUBlock builds the per-candidate check block
@@ -610,7 +622,7 @@ func buildCheckPermissionCrossTypeTTUBlock(plan ListPlan, parent ListParentRelat
 		accessCheck = sourceRelationCheck(plan, parent)
 	}
 
-	q := Tuples(plan.DatabaseSchema, "child").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "child").
 		ObjectType(plan.ObjectType).
 		Relations(parent.LinkingRelation).
 		Where(In{Expr: Col{Table: "child", Column: "subject_type"}, Values: crossTypes})
@@ -629,7 +641,7 @@ func buildCheckPermissionCrossTypeTTUBlock(plan ListPlan, parent ListParentRelat
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{comment},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), comment),
 		Query:    q.Build(),
 	}
 }
@@ -641,6 +653,7 @@ func buildRecursiveTTUBlock(plan ListPlan, linkingRelations []string) *TypedQuer
 	exclusions := buildExclusionInput(
 		plan.Analysis,
 		plan.DatabaseSchema,
+		plan.TuplesTable,
 		Col{Table: "child", Column: "object_id"},
 		SubjectType,
 		SubjectID,
@@ -655,7 +668,7 @@ func buildRecursiveTTUBlock(plan ListPlan, linkingRelations []string) *TypedQuer
 			{
 				Type:   "INNER",
 				Schema: "",
-				Table:  "melange_tuples",
+				Table:  TuplesTableName(plan.TuplesTable),
 				Alias:  "child",
 				On: And(
 					Eq{Left: Col{Table: "child", Column: "object_type"}, Right: Lit(plan.ObjectType)},
@@ -667,7 +680,7 @@ func buildRecursiveTTUBlock(plan ListPlan, linkingRelations []string) *TypedQuer
 		},
 		Where: And(
 			Col{Table: "a", Column: "propagatable"},
-			Lt{Left: Col{Table: "a", Column: "depth"}, Right: Int(25)},
+			Lt{Left: Col{Table: "a", Column: "depth"}, Right: Int(plan.maxDepth())},
 		),
 	}
 
@@ -676,7 +689,7 @@ func buildRecursiveTTUBlock(plan ListPlan, linkingRelations []string) *TypedQuer
 	}
 
 	return &TypedQueryBlock{
-		Comments: []string{"-- Self-referential TTU: follow linking relations to accessible parents"},
+		Comments: plan.traceComments(TraceNodeTTU, "", "-- Self-referential TTU: follow linking relations to accessible parents"),
 		Query:    stmt,
 	}
 }
@@ -690,7 +703,7 @@ func dequoteLinkingRelations(sqlList string) []string {
 	parts := strings.Split(sqlList, ",")
 	result := make([]string, 0, len(parts))
 	for _, part := range parts {
-		trimmed := strings.TrimSpace(strings.Trim(part, "'"))
+		trimmed := strings.Trim(strings.TrimSpace(part), "'")
 		if trimmed != "" {
 			result = append(result, trimmed)
 		}
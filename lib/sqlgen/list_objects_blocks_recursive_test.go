@@ -39,7 +39,7 @@ func TestBuildCrossTypeTTUBlocksUsesSubjectFirstParentList(t *testing.T) {
 	}
 
 	sql := blocks[0].Query.SQL()
-	assertContains(t, sql, "FROM list_namespace_admin_obj(p_subject_type, p_subject_id, NULL, NULL) AS parent_obj")
+	assertContains(t, sql, "FROM list_namespace_admin_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) AS parent_obj")
 	assertContains(t, sql, "INNER JOIN melange_tuples AS child ON")
 	assertContains(t, sql, "child.object_type = 'channel'")
 	assertContains(t, sql, "child.relation = 'namespace'")
@@ -144,7 +144,7 @@ func TestBuildCrossTypeTTUBlocksVerifiesClosureSourceRelationWhenConstrained(t *
 	}
 
 	sql := blocks[0].Query.SQL()
-	assertContains(t, sql, "FROM list_namespace_admin_obj(p_subject_type, p_subject_id, NULL, NULL) AS parent_obj")
+	assertContains(t, sql, "FROM list_namespace_admin_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) AS parent_obj")
 	assertContains(t, sql, "check_permission_internal(p_subject_type, p_subject_id, 'reader', 'channel', child.object_id, ARRAY[]::TEXT[]) = 1")
 }
 
@@ -189,7 +189,7 @@ func TestBuildCrossTypeTTUBlocksSkipsClosureSourceCheckWhenUnconstrained(t *test
 	}
 
 	sql := blocks[0].Query.SQL()
-	assertContains(t, sql, "FROM list_namespace_admin_obj(p_subject_type, p_subject_id, NULL, NULL) AS parent_obj")
+	assertContains(t, sql, "FROM list_namespace_admin_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) AS parent_obj")
 	assertNotContains(t, sql, "check_permission_internal")
 }
 
@@ -291,7 +291,7 @@ func TestBuildCrossTypeTTUBlocksComposesAcyclicRecursiveParent(t *testing.T) {
 	}
 
 	sql := blocks[0].Query.SQL()
-	assertContains(t, sql, "FROM list_workspace_manage_obj(p_subject_type, p_subject_id, NULL, NULL) AS parent_obj")
+	assertContains(t, sql, "FROM list_workspace_manage_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) AS parent_obj")
 	assertNotContains(t, sql, "check_permission_internal")
 
 	parity := blocks[1].Query.SQL()
@@ -337,7 +337,7 @@ func TestBuildRecursiveComplexUsersetBlockComposesWhenSafe(t *testing.T) {
 	}
 
 	sql := buildRecursiveComplexUsersetBlock(plan, pattern, nil).Query.SQL()
-	assertContains(t, sql, "IN (SELECT obj.object_id FROM list_workspace_view_obj(p_subject_type, p_subject_id, NULL, NULL) obj)")
+	assertContains(t, sql, "IN (SELECT obj.object_id FROM list_workspace_view_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) obj)")
 	// Userset-typed subjects keep a guarded per-candidate check arm.
 	assertContains(t, sql, "position('#' in p_subject_id) > 0")
 	assertContains(t, sql, "check_permission_internal")
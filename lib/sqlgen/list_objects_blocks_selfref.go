@@ -33,6 +33,12 @@ func BuildListObjectsSelfRefUsersetBlocks(plan ListPlan) (SelfRefUsersetBlockSet
 func buildSelfRefUsersetBaseBlocks(plan ListPlan) ([]TypedQueryBlock, error) {
 	blocks := make([]TypedQueryBlock, 0, 8)
 	blocks = append(blocks, buildSelfRefUsersetDirectBlock(plan))
+	if plan.HasUsersetSubject {
+		// Seeds the member expansion from usersets that name the query subject
+		// (group:all#member holding group:eng#member), not only from the
+		// subject's own object, which the self-candidate block adds outside.
+		blocks = append(blocks, buildListObjectsUsersetSubjectBlock(plan, plan.RelationList))
+	}
 	blocks = append(blocks, buildSelfRefUsersetComplexClosureBlocks(plan)...)
 	blocks = append(blocks, buildSelfRefUsersetIntersectionClosureBlocks(plan)...)
 	blocks = append(blocks, buildSelfRefUsersetPatternBlocks(plan)...)
@@ -40,13 +46,14 @@ func buildSelfRefUsersetBaseBlocks(plan ListPlan) ([]TypedQueryBlock, error) {
 }
 
 func buildSelfRefUsersetDirectBlock(plan ListPlan) TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(plan.RelationList...).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: SubjectType},
 			In{Expr: SubjectType, Values: plan.AllowedSubjectTypes},
-			SubjectIDMatch(Col{Table: "t", Column: "subject_id"}, SubjectID, plan.AllowWildcard),
+			subjectTypesFilter(SubjectType),
+			plan.directSubjectIDMatch(Col{Table: "t", Column: "subject_id"}),
 		).
 		SelectCol("object_id").
 		Distinct()
@@ -56,7 +63,7 @@ func buildSelfRefUsersetDirectBlock(plan ListPlan) TypedQueryBlock {
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Direct tuple lookup with simple closure relations"},
+		Comments: plan.traceComments(TraceNodeDirect, "", "-- Direct tuple lookup with simple closure relations"),
 		Query:    q.Build(),
 	}
 }
@@ -68,13 +75,13 @@ func buildSelfRefUsersetComplexClosureBlocks(plan ListPlan) []TypedQueryBlock {
 
 	blocks := make([]TypedQueryBlock, 0, len(plan.ComplexClosure))
 	for _, rel := range plan.ComplexClosure {
-		q := Tuples(plan.DatabaseSchema, "t").
+		q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 			ObjectType(plan.ObjectType).
 			Relations(rel).
 			Where(
 				Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: SubjectType},
 				In{Expr: SubjectType, Values: plan.AllowedSubjectTypes},
-				SubjectIDMatch(Col{Table: "t", Column: "subject_id"}, SubjectID, plan.AllowWildcard),
+				plan.directSubjectIDMatch(Col{Table: "t", Column: "subject_id"}),
 				CheckPermission{
 					Schema:      plan.DatabaseSchema,
 					Subject:     SubjectParams(),
@@ -91,7 +98,7 @@ func buildSelfRefUsersetComplexClosureBlocks(plan ListPlan) []TypedQueryBlock {
 		}
 
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{fmt.Sprintf("-- Complex closure relation: %s", rel)},
+			Comments: plan.traceComments(TraceNodeImplied, rel, fmt.Sprintf("-- Complex closure relation: %s", rel)),
 			Query:    q.Build(),
 		})
 	}
@@ -108,13 +115,13 @@ func buildSelfRefUsersetIntersectionClosureBlocks(plan ListPlan) []TypedQueryBlo
 	blocks := make([]TypedQueryBlock, 0, len(intersectionRels))
 	for _, rel := range intersectionRels {
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{fmt.Sprintf("-- Compose with intersection closure relation: %s", rel)},
+			Comments: plan.traceComments(TraceNodeIntersection, rel, fmt.Sprintf("-- Compose with intersection closure relation: %s", rel)),
 			Query: SelectStmt{
 				ColumnExprs: []Expr{Col{Table: "icr", Column: "object_id"}},
 				FromExpr: FunctionCallExpr{
 					Schema: plan.DatabaseSchema,
-					Name:   listObjectsFunctionName(plan.ObjectType, rel),
-					Args:   []Expr{SubjectType, SubjectID, Null{}, Null{}},
+					Name:   listObjectsFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, rel),
+					Args:   listObjectsCallArgs(SubjectType, SubjectID),
 					Alias:  "icr",
 				},
 			},
@@ -146,11 +153,12 @@ func buildSelfRefUsersetPatternBlocks(plan ListPlan) []TypedQueryBlock {
 }
 
 func buildSelfRefUsersetComplexPatternBlock(plan ListPlan, pattern listUsersetPatternInput) TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(pattern.SourceRelations...).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(pattern.SubjectType)},
+			subjectTypesFilter(Lit(pattern.SubjectType)),
 			HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
 			Eq{Left: UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}}, Right: Lit(pattern.SubjectRelation)},
 			CheckPermissionInternalExpr(
@@ -172,7 +180,7 @@ func buildSelfRefUsersetComplexPatternBlock(plan ListPlan, pattern listUsersetPa
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Via %s#%s (complex userset)", pattern.SubjectType, pattern.SubjectRelation)},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation), fmt.Sprintf("-- Via %s#%s (complex userset)", pattern.SubjectType, pattern.SubjectRelation)),
 		Query:    q.Build(),
 	}
 }
@@ -204,6 +212,7 @@ func buildSelfRefUsersetSimplePatternBlock(plan ListPlan, pattern listUsersetPat
 		Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
 		In{Expr: Col{Table: "t", Column: "relation"}, Values: pattern.SourceRelations},
 		Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(pattern.SubjectType)},
+		subjectTypesFilter(Lit(pattern.SubjectType)),
 		HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
 		Eq{Left: UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}}, Right: Lit(pattern.SubjectRelation)},
 	}
@@ -222,15 +231,15 @@ func buildSelfRefUsersetSimplePatternBlock(plan ListPlan, pattern listUsersetPat
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Via %s#%s (simple userset)", pattern.SubjectType, pattern.SubjectRelation)},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation), fmt.Sprintf("-- Via %s#%s (simple userset)", pattern.SubjectType, pattern.SubjectRelation)),
 		Query: SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{Col{Table: "t", Column: "object_id"}},
-			FromExpr:    TableAs("", "melange_tuples", "t"),
+			FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 			Joins: []JoinClause{{
 				Type:   "INNER",
 				Schema: "",
-				Table:  "melange_tuples",
+				Table:  TuplesTableName(plan.TuplesTable),
 				Alias:  "m",
 				On:     And(membershipConditions...),
 			}},
@@ -248,15 +257,15 @@ func buildSelfRefUsersetRecursiveBlock(plan ListPlan) *TypedQueryBlock {
 		Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(plan.ObjectType)},
 		HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
 		Eq{Left: UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}}, Right: Lit(plan.Relation)},
-		Raw("me.depth < 25"),
+		Lt{Left: Col{Table: "me", Column: "depth"}, Right: Int(plan.maxDepth())},
 	)
 	conditions = append(conditions, exclusionPreds...)
 
 	return &TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(plan.ObjectType, plan.Relation),
 			"-- Self-referential userset expansion",
 			fmt.Sprintf("-- Patterns like [%s#%s] on %s.%s", plan.ObjectType, plan.Relation, plan.ObjectType, plan.Relation),
-		},
+		),
 		Query: SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{Col{Table: "t", Column: "object_id"}, Raw("me.depth + 1 AS depth")},
@@ -264,7 +273,7 @@ func buildSelfRefUsersetRecursiveBlock(plan ListPlan) *TypedQueryBlock {
 			Joins: []JoinClause{{
 				Type:   "INNER",
 				Schema: "",
-				Table:  "melange_tuples",
+				Table:  TuplesTableName(plan.TuplesTable),
 				Alias:  "t",
 				On:     Eq{Left: UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}}, Right: Col{Table: "me", Column: "object_id"}},
 			}},
@@ -56,7 +56,7 @@ func TestComposedRecursiveTTU_SemiJoinWhenComposable(t *testing.T) {
 
 	sql := composedRecursiveTTUSQL(t, lookup)
 
-	if !strings.Contains(sql, "t.subject_id IN (SELECT obj.object_id FROM list_folder_viewer_obj(p_subject_type, p_subject_id, NULL, NULL) obj)") {
+	if !strings.Contains(sql, "t.subject_id IN (SELECT obj.object_id FROM list_folder_viewer_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) obj)") {
 		t.Errorf("expected set-oriented semi-join against list_folder_viewer_obj, got:\n%s", sql)
 	}
 	// Userset-typed subjects keep a guarded per-candidate check for parity.
@@ -55,7 +55,7 @@ func TestComposedListObjects_SelfCandidateFoldedIntoUnion(t *testing.T) {
 		t.Errorf("expected self-candidate folded into main UNION:\n%s", sql)
 	}
 	// Reflexive self predicate preserved (userset-defines-itself).
-	if !strings.Contains(sql, "split_part(p_subject_id, '#', 2) IN ('inherited_view')") {
+	if !strings.Contains(sql, "substring(p_subject_id from length(p_subject_id) - strpos(reverse(p_subject_id), '#') + 2) IN ('inherited_view')") {
 		t.Errorf("reflexive self-candidate predicate must be preserved:\n%s", sql)
 	}
 }
@@ -49,7 +49,7 @@ func TestComposedUserset_SemiJoinWhenComposable(t *testing.T) {
 
 	sql := composedUsersetSQL(t, lookup)
 
-	if !strings.Contains(sql, "split_part(t.subject_id, '#', 1) IN (SELECT obj.object_id FROM list_group_member_obj(p_subject_type, p_subject_id, NULL, NULL) obj)") {
+	if !strings.Contains(sql, "left(t.subject_id, length(t.subject_id) - strpos(reverse(t.subject_id), '#')) IN (SELECT obj.object_id FROM list_group_member_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) obj)") {
 		t.Errorf("expected set-oriented semi-join against list_group_member_obj, got:\n%s", sql)
 	}
 	// Userset-typed query subjects keep a guarded per-candidate check for parity;
@@ -57,7 +57,7 @@ func TestComposedUserset_SemiJoinWhenComposable(t *testing.T) {
 	if !strings.Contains(sql, "position('#' in p_subject_id) > 0") {
 		t.Errorf("expected userset-subject parity guard, got:\n%s", sql)
 	}
-	if !strings.Contains(sql, "check_permission_internal(p_subject_type, p_subject_id, 'member', 'group', split_part(t.subject_id, '#', 1)") {
+	if !strings.Contains(sql, "check_permission_internal(p_subject_type, p_subject_id, 'member', 'group', left(t.subject_id, length(t.subject_id) - strpos(reverse(t.subject_id), '#'))") {
 		t.Errorf("expected guarded per-candidate check for userset subjects, got:\n%s", sql)
 	}
 }
@@ -83,7 +83,7 @@ func TestComposedUserset_FallsBackWhenCyclic(t *testing.T) {
 	if strings.Contains(sql, "list_group_member_obj") {
 		t.Errorf("cyclic composition must fall back to per-candidate check, got semi-join:\n%s", sql)
 	}
-	if !strings.Contains(sql, "check_permission_internal(p_subject_type, p_subject_id, 'member', 'group', split_part(t.subject_id, '#', 1)") {
+	if !strings.Contains(sql, "check_permission_internal(p_subject_type, p_subject_id, 'member', 'group', left(t.subject_id, length(t.subject_id) - strpos(reverse(t.subject_id), '#'))") {
 		t.Errorf("expected per-candidate check fallback, got:\n%s", sql)
 	}
 }
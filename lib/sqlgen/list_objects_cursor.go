@@ -0,0 +1,110 @@
+package sqlgen
+
+// ListObjectsCursorDispatcherName is the cursor entrypoint emitted when
+// GenerateSQLOptions.EnableListObjectsCursor is set.
+const ListObjectsCursorDispatcherName = "list_accessible_objects_cursor"
+
+// listObjectsCursorFunctionName returns the name of the cursor variant of a
+// list_objects function.
+func listObjectsCursorFunctionName(collisions NameCollisions, objectType, relation string) string {
+	return RelationIdentifier(collisions, "list_", objectType, relation, "_objects_cursor")
+}
+
+// listObjectsCursorArgs are the arguments of the cursor functions: the
+// subject only, since the caller pages with FETCH instead of p_limit/p_after,
+// and p_consistency.
+func listObjectsCursorArgs() []FuncArg {
+	return []FuncArg{
+		{Name: "p_subject_type", Type: "TEXT"},
+		{Name: "p_subject_id", Type: "TEXT"},
+		ConsistencyArg(),
+	}
+}
+
+// CollectListObjectsCursorFunctionNames returns the function names generated
+// for analyses when GenerateSQLOptions.EnableListObjectsCursor is set: the
+// cursor dispatcher and a cursor function per listable relation.
+func CollectListObjectsCursorFunctionNames(analyses []RelationAnalysis) []string {
+	names := []string{ListObjectsCursorDispatcherName}
+	for _, a := range analyses {
+		if a.Capabilities.ListAllowed {
+			names = append(names, listObjectsCursorFunctionName(a.NameCollisions, a.ObjectType, a.Relation))
+		}
+	}
+	return names
+}
+
+// generateListObjectsCursorFunction renders
+// list_{type}_{relation}_objects_cursor, which opens and returns a refcursor
+// over the rows of list_{type}_{relation}_obj, so a client can FETCH them in
+// batches within its transaction instead of receiving them in one result.
+//
+// The cursor reads the list function unpaged, so the rows are exactly those
+// of an unpaged call: distinct, and ordered by object_id as the cursor query
+// states explicitly. PostgreSQL still evaluates the list function in full on
+// the first FETCH, spilling to disk past work_mem; what the cursor bounds is
+// the memory of the client.
+func generateListObjectsCursorFunction(a RelationAnalysis, databaseSchema string) string {
+	return renderListObjectsCursorFunction(databaseSchema, listObjectsCursorFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+		listObjectsCursorArgs(), FunctionCallExpr{
+			Schema: databaseSchema,
+			Name:   listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation),
+			Args:   listObjectsCallArgs(SubjectType, SubjectID),
+			Alias:  "o",
+		}, []string{
+			"Generated cursor function for " + a.ObjectType + "." + a.Relation,
+			"Returns a refcursor over " + listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation) + ", ordered by object_id",
+		})
+}
+
+// generateListObjectsCursorDispatcher renders list_accessible_objects_cursor,
+// the cursor counterpart of list_accessible_objects. It opens its cursor over
+// list_accessible_objects, so it routes the same way, including for
+// relations without a cursor function of their own.
+func generateListObjectsCursorDispatcher(databaseSchema string) string {
+	args := append(ListObjectsDispatcherArgs()[:4:4], ConsistencyArg())
+	return renderListObjectsCursorFunction(databaseSchema, ListObjectsCursorDispatcherName, args, FunctionCallExpr{
+		Schema: databaseSchema,
+		Name:   "list_accessible_objects",
+		Args: CallArgs(ListObjectsDispatcherArgs(), map[string]Expr{
+			"p_subject_type": SubjectType,
+			"p_subject_id":   SubjectID,
+			"p_relation":     Param("p_relation"),
+			"p_object_type":  ObjectType,
+			"p_consistency":  Param("p_consistency"),
+		}),
+		Alias: "o",
+	}, []string{
+		"Generated cursor dispatcher " + ListObjectsCursorDispatcherName,
+		"Returns a refcursor over list_accessible_objects, ordered by object_id",
+	})
+}
+
+// renderListObjectsCursorFunction renders a function that opens a cursor over
+// the object_id column of source, ordered by object_id, and returns it.
+func renderListObjectsCursorFunction(databaseSchema, name string, args []FuncArg, source FunctionCallExpr, header []string) string {
+	query := SelectStmt{
+		ColumnExprs: []Expr{Col{Table: source.Alias, Column: "object_id"}},
+		FromExpr:    source,
+	}.SQL() + "\nORDER BY " + source.Alias + ".object_id"
+
+	fn := PlpgsqlFunction{
+		Schema:  databaseSchema,
+		Name:    name,
+		Args:    args,
+		Returns: "refcursor",
+		Decls:   []Decl{{Name: "v_cursor", Type: "refcursor"}},
+		Body: []Stmt{
+			RawStmt{SQLText: "OPEN v_cursor FOR\n" + IndentLines(query, "    ") + ";"},
+			ReturnValue{Value: Raw("v_cursor")},
+		},
+		Header: header,
+		// Calls only the schema-qualified list function, and the cursor
+		// outlives the call, so a SET clause would not cover its FETCHes.
+		NoSearchPath: true,
+		// The cursor is a portal that outlives the calling query, which is
+		// no place for a parallel plan.
+		ParallelUnsafe: true,
+	}
+	return fn.SQL() + "\n"
+}
@@ -0,0 +1,66 @@
+package sqlgen
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGenerateListObjectsCursor(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, strictTestSchema)
+
+	base, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{EnableListObjectsCursor: true})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+	if !reflect.DeepEqual(base.ListObjectsFunctions, list.ListObjectsFunctions) || base.ListObjectsDispatcher != list.ListObjectsDispatcher {
+		t.Error("EnableListObjectsCursor changed the list_objects functions")
+	}
+	if len(base.ListObjectsCursorFunctions) != 0 || base.ListObjectsCursorDispatcher != "" {
+		t.Error("cursor functions generated without EnableListObjectsCursor")
+	}
+
+	fns := strings.Join(list.ListObjectsCursorFunctions, "\n")
+	assertContains(t, fns, "FUNCTION list_document_viewer_objects_cursor(\n    p_subject_type TEXT,\n    p_subject_id TEXT,\n    p_consistency TEXT DEFAULT NULL\n) RETURNS refcursor")
+	assertContains(t, fns, "OPEN v_cursor FOR")
+	assertContains(t, fns, "FROM list_document_viewer_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) AS o")
+	assertContains(t, fns, "ORDER BY o.object_id;")
+	assertContains(t, fns, "RETURN v_cursor;")
+	assertContains(t, fns, "PARALLEL UNSAFE")
+
+	assertContains(t, list.ListObjectsCursorDispatcher, "FUNCTION list_accessible_objects_cursor(")
+	assertContains(t, list.ListObjectsCursorDispatcher, "FROM list_accessible_objects(p_subject_type => p_subject_type, p_subject_id => p_subject_id, p_relation => p_relation, p_object_type => p_object_type, p_consistency => p_consistency) AS o")
+
+	names := CollectListObjectsCursorFunctionNames(analyses)
+	if !slices.Contains(names, ListObjectsCursorDispatcherName) || !slices.Contains(names, "list_document_viewer_objects_cursor") {
+		t.Errorf("CollectListObjectsCursorFunctionNames = %v", names)
+	}
+	if len(names) != len(list.ListObjectsCursorFunctions)+1 {
+		t.Errorf("CollectListObjectsCursorFunctionNames has %d names, generated %d functions and a dispatcher",
+			len(names), len(list.ListObjectsCursorFunctions))
+	}
+
+	gen, err := GenerateSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	var named []string
+	for _, fn := range CollectNamedFunctions(gen, list, analyses) {
+		named = append(named, fn.Name)
+	}
+	if !slices.Contains(named, "list_document_viewer_objects_cursor") {
+		t.Error("CollectNamedFunctions missing list_document_viewer_objects_cursor")
+	}
+	var dispatchers []string
+	for _, fn := range CollectDispatcherFunctions(gen, list) {
+		dispatchers = append(dispatchers, fn.Name)
+	}
+	if !slices.Contains(dispatchers, ListObjectsCursorDispatcherName) {
+		t.Errorf("CollectDispatcherFunctions missing %s: %v", ListObjectsCursorDispatcherName, dispatchers)
+	}
+}
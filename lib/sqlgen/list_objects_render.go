@@ -15,6 +15,7 @@ func RenderListObjectsFunction(plan ListPlan, blocks BlockSet) (string, error) {
 		Body: []Stmt{
 			ReturnQuery{Query: paginatedQuery},
 		},
+		NullGuards: plan.NullGuards,
 	}
 	return fn.SQL(), nil
 }
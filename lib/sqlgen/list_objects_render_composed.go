@@ -35,7 +35,7 @@ func RenderListObjectsComposedFunction(plan ListPlan, blocks ComposedObjectsBloc
 		Comment{Text: "Skip the guard for userset subjects since composed inner calls handle userset subjects"},
 		If{
 			Cond: And(
-				Eq{Left: Position{Needle: Lit("#"), Haystack: SubjectID}, Right: Int(0)},
+				Eq{Left: StrPos{Source: SubjectID, Substr: "#"}, Right: Int(0)},
 				NotIn{Expr: SubjectType, Values: blocks.AllowedSubjectTypes},
 			),
 			Then: []Stmt{Return{}},
@@ -53,7 +53,8 @@ func RenderListObjectsComposedFunction(plan ListPlan, blocks ComposedObjectsBloc
 			fmt.Sprintf("Features: %s", plan.FeaturesString()),
 			fmt.Sprintf("Indirect anchor: %s.%s via %s", blocks.AnchorType, blocks.AnchorRelation, blocks.FirstStepType),
 		},
-		Body: body,
+		Body:       body,
+		NullGuards: plan.NullGuards,
 	}
 	return fn.SQL(), nil
 }
@@ -9,18 +9,23 @@ func RenderListObjectsRecursiveFunction(plan ListPlan, blocks RecursiveBlockSet)
 	exclusionConfig := buildExclusionInput(
 		plan.Analysis,
 		plan.DatabaseSchema,
+		plan.TuplesTable,
 		Col{Table: "acc", Column: "object_id"},
 		SubjectType,
 		SubjectID,
 	)
 
+	exclusions := exclusionConfig.BuildPredicates()
 	finalStmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: "acc", Column: "object_id"}},
 		FromExpr:    TableAs("", "accessible", "acc"),
+		Where:       buildWhereFromPredicates(exclusions),
 	}
-	if predicates := exclusionConfig.BuildPredicates(); len(predicates) > 0 {
-		finalStmt.Where = And(predicates...)
+	var guard Expr
+	if recursive {
+		guard = depthLimitGuard(plan.DatabaseSchema, "accessible", []string{"object_id"}, plan.maxDepth())
+		finalStmt.Where = And(finalStmt.Where, guard)
 	}
 
 	// Only genuinely self-referential relations need the recursive machinery.
@@ -52,25 +57,106 @@ func RenderListObjectsRecursiveFunction(plan ListPlan, blocks RecursiveBlockSet)
 
 	paginatedQuery := plan.wrapPagination(query, "object_id")
 
+	body := []Stmt{ReturnQuery{Query: paginatedQuery}}
+	if plan.AnytimeListObjects && recursive {
+		anytime := renderAnytimeListObjectsQuery(ctes, exclusions, guard, blocks.SelfCandidateBlock, plan.excludedObjects())
+		body = []Stmt{
+			Comment{Text: "Unpaged: base-level grants first, then objects found by recursion"},
+			If{
+				Cond: And(IsNull{Expr: Raw("p_limit")}, IsNull{Expr: Raw(paginationCursor)}),
+				Then: []Stmt{ReturnQuery{Query: anytime}, Return{}},
+			},
+			ReturnQuery{Query: paginatedQuery},
+		}
+	}
+
 	fn := PlpgsqlFunction{
 		Schema:  plan.DatabaseSchema,
 		Name:    plan.FunctionName,
 		Args:    ListObjectsArgs(),
 		Returns: ListObjectsReturns(),
 		Header:  ListObjectsFunctionHeader(plan.ObjectType, plan.Relation, plan.FeaturesString()),
-		// Recursion is bounded inside the accessible CTE (WHERE a.depth < 25).
-		// list_objects is best-effort to that depth: chains deeper than the bound
-		// are truncated rather than raising M2002 the way check_permission does
-		// (a pathological edge case that a global pre-check could not detect
-		// per-query anyway without re-walking the whole graph on every call).
-		Body: []Stmt{
-			ReturnQuery{Query: paginatedQuery},
-		},
+		// Recursion is bounded inside the accessible CTE (WHERE a.depth <
+		// plan.maxDepth()). A chain reaching the bound raises M2002 through
+		// the depthLimitGuard on the final SELECT, the way check_permission
+		// does, rather than returning the objects found before the cut.
+		Body:       body,
+		NullGuards: plan.NullGuards,
 	}
 
 	return fn.SQL(), nil
 }
 
+// renderAnytimeListObjectsQuery renders the unpaged list_objects query of
+// GenerateSQLOptions.AnytimeListObjects. It returns the same set as the
+// default query, split into UNION ALL branches in the order a client should
+// see them: objects granted at depth 0 of the accessible CTE (direct tuples,
+// usersets, and non-recursive TTU parents), then objects only the recursion
+// reaches, then the userset self-candidate. Each branch skips ids an earlier
+// branch returned, so no id repeats and DISTINCT is not needed across
+// branches, which would otherwise reorder the rows. The depth limit guard
+// filters the objects the recursion reaches. Every branch also skips the
+// objects in excluded, the p_exclude_relation objects the paged query removes
+// with ListPlan.excludeRelation; its EXCEPT would not keep the branch order.
+func renderAnytimeListObjectsQuery(ctes []CTEDef, exclusions []Expr, guard Expr, self *TypedQueryBlock, excluded SelectStmt) string {
+	accObjectID := Col{Table: "acc", Column: "object_id"}
+	depth := Col{Table: "acc", Column: "depth"}
+	// Rendered on one line: a multi-line subquery would defeat SelectStmt's
+	// indentation.
+	seenIn := func(cte, alias string, id Expr) Expr {
+		return Raw("NOT EXISTS (SELECT 1 FROM " + cte + " AS " + alias + " WHERE " +
+			Eq{Left: Col{Table: alias, Column: "object_id"}, Right: id}.SQL() + ")")
+	}
+
+	ctes = append(ctes, CTEDef{Name: "excluded", Query: excluded})
+	exclusions = append(exclusions, seenIn("excluded", "x", accObjectID))
+
+	early := SelectStmt{
+		Distinct:    true,
+		ColumnExprs: []Expr{accObjectID},
+		FromExpr:    TableAs("", "accessible", "acc"),
+		Where:       And(append([]Expr{Eq{Left: depth, Right: Int(0)}}, exclusions...)...),
+	}
+	deep := SelectStmt{
+		Distinct:    true,
+		ColumnExprs: []Expr{accObjectID},
+		FromExpr:    TableAs("", "accessible", "acc"),
+		Where: And(append([]Expr{
+			Gt{Left: depth, Right: Int(0)},
+			seenIn("early", "e", accObjectID),
+			guard,
+		}, exclusions...)...),
+	}
+	ctes = append(ctes,
+		CTEDef{Name: "early", Query: early},
+		CTEDef{Name: "deep", Query: deep},
+	)
+
+	branch := func(cte, alias string) SelectStmt {
+		return SelectStmt{
+			ColumnExprs: []Expr{
+				Cast{Expr: Col{Table: alias, Column: "object_id"}, Type: "TEXT"},
+				Cast{Expr: Null{}, Type: "TEXT"},
+			},
+			FromExpr: TableAs("", cte, alias),
+		}
+	}
+	branches := []SQLer{branch("early", "e"), branch("deep", "d")}
+	if self != nil {
+		ctes = append(ctes, CTEDef{Name: "self_candidate", Columns: []string{"object_id"}, Query: self.Query})
+		selfBranch := branch("self_candidate", "s")
+		selfID := Col{Table: "s", Column: "object_id"}
+		selfBranch.Where = And(seenIn("early", "e", selfID), seenIn("deep", "d", selfID), seenIn("excluded", "x", selfID))
+		branches = append(branches, selfBranch)
+	}
+
+	return WithCTE{
+		Recursive: true,
+		CTEs:      ctes,
+		Query:     UnionAll{Queries: branches},
+	}.SQL()
+}
+
 func renderRecursiveCTEBody(blocks RecursiveBlockSet, recursive bool) string {
 	baseBlocksSQL := make([]string, 0, len(blocks.BaseBlocks))
 	for _, block := range blocks.BaseBlocks {
@@ -11,6 +11,7 @@ func RenderListObjectsSelfRefUsersetFunction(plan ListPlan, blocks SelfRefUserse
 	exclusionConfig := buildExclusionInput(
 		plan.Analysis,
 		plan.DatabaseSchema,
+		plan.TuplesTable,
 		Col{Table: "me", Column: "object_id"},
 		SubjectType,
 		SubjectID,
@@ -22,6 +23,9 @@ func RenderListObjectsSelfRefUsersetFunction(plan ListPlan, blocks SelfRefUserse
 		FromExpr:    TableAs("", "member_expansion", "me"),
 		Where:       buildWhereFromPredicates(exclusionConfig.BuildPredicates()),
 	}
+	if blocks.RecursiveBlock != nil {
+		finalStmt.Where = And(finalStmt.Where, depthLimitGuard(plan.DatabaseSchema, "member_expansion", []string{"object_id"}, plan.maxDepth()))
+	}
 
 	cteSQL := WithCTE{
 		Recursive: true,
@@ -48,6 +52,7 @@ func RenderListObjectsSelfRefUsersetFunction(plan ListPlan, blocks SelfRefUserse
 		Body: []Stmt{
 			ReturnQuery{Query: plan.wrapPagination(query, "object_id")},
 		},
+		NullGuards: plan.NullGuards,
 	}
 
 	return fn.SQL(), nil
@@ -0,0 +1,50 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+const subjectTypesSchema = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type document
+  relations
+    define owner: [user]
+    define viewer: [user, group#member] or owner
+`
+
+func TestListObjectsSubjectTypes(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, subjectTypesSchema)
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+
+	var viewer string
+	for _, fn := range list.ListObjectsFunctions {
+		if strings.Contains(fn, "FUNCTION list_document_viewer_obj(") {
+			viewer = fn
+		}
+	}
+
+	assertContains(t, viewer, "p_subject_types TEXT[] DEFAULT NULL")
+	// The direct lookup filters on the query subject's type, the group#member
+	// block on the type of the userset granting access.
+	assertContains(t, viewer, "(p_subject_types IS NULL OR p_subject_type = ANY(p_subject_types))")
+	assertContains(t, viewer, "(p_subject_types IS NULL OR 'group' = ANY(p_subject_types))")
+
+	// Both the functions and the dispatcher drop the signature they had
+	// before p_subject_types, so no ambiguous overload is left behind.
+	if !strings.HasPrefix(viewer, "DROP FUNCTION IF EXISTS list_document_viewer_obj(TEXT, TEXT, INT, TEXT);\n") {
+		t.Errorf("list_document_viewer_obj does not drop its old signature first:\n%s", viewer[:200])
+	}
+	assertContains(t, list.ListObjectsDispatcher, "DROP FUNCTION IF EXISTS list_accessible_objects(TEXT, TEXT, TEXT, TEXT, INT, TEXT);")
+	assertContains(t, list.ListObjectsDispatcher, "p_subject_types => p_subject_types")
+}
@@ -0,0 +1,76 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+// usersetSubjectPlan is document.viewer: [group#member] or viewer from parent,
+// reduced to what the base-block builders read.
+func usersetSubjectPlan() ListPlan {
+	return ListPlan{
+		ObjectType:        "document",
+		Relation:          "viewer",
+		RelationList:      []string{"viewer"},
+		HasUsersetSubject: true,
+		Analysis: RelationAnalysis{
+			ObjectType: "document",
+			Relation:   "viewer",
+		},
+	}
+}
+
+func findUsersetSubjectBlock(blocks []TypedQueryBlock) (TypedQueryBlock, bool) {
+	for _, b := range blocks {
+		for _, c := range b.Comments {
+			if strings.Contains(c, "Userset subject matching") {
+				return b, true
+			}
+		}
+	}
+	return TypedQueryBlock{}, false
+}
+
+func TestUsersetSubjectBlock_ClosureKeyedOnSubjectType(t *testing.T) {
+	sql := buildListObjectsUsersetSubjectBlock(usersetSubjectPlan(), []string{"viewer"}).Query.SQL()
+
+	// group:eng#owner satisfies a tuple naming group:eng#member when the closure
+	// of group.member contains owner, so the closure lookup must be keyed on the
+	// userset's type (the query subject type), not on the listed object type.
+	assertContains(t, sql, "c.object_type = p_subject_type")
+	assertContains(t, sql, "c.relation = substring(t.subject_id from length(t.subject_id) - strpos(reverse(t.subject_id), '#') + 2)")
+	assertContains(t, sql, "c.satisfying_relation = substring(p_subject_id from length(p_subject_id) - strpos(reverse(p_subject_id), '#') + 2)")
+	if strings.Contains(sql, "c.object_type = 'document'") {
+		t.Errorf("closure lookup keyed on the object type:\n%s", sql)
+	}
+}
+
+func TestRecursiveBaseBlocks_IncludeUsersetSubject(t *testing.T) {
+	plan := usersetSubjectPlan()
+	blocks, err := buildRecursiveBaseBlocks(plan, nil, map[string]bool{"viewer": true}, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, ok := findUsersetSubjectBlock(blocks)
+	if !ok {
+		t.Fatal("recursive base blocks have no userset subject block; a userset query subject named on a tuple is never listed")
+	}
+	if !block.Propagatable {
+		t.Error("userset subject block on a propagatable relation must seed the recursive walk")
+	}
+	assertContains(t, block.Query.SQL(), "t.subject_id = p_subject_id")
+}
+
+func TestSelfRefUsersetBaseBlocks_IncludeUsersetSubject(t *testing.T) {
+	plan := usersetSubjectPlan()
+	plan.ObjectType, plan.Relation, plan.RelationList = "group", "member", []string{"member"}
+
+	blocks, err := buildSelfRefUsersetBaseBlocks(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := findUsersetSubjectBlock(blocks); !ok {
+		t.Fatal("self-referential userset base blocks have no userset subject block; nested groups are not expanded for userset subjects")
+	}
+}
@@ -1,11 +1,14 @@
 package sqlgen
 
+import "slices"
+
 // ListPlan contains all computed data needed to generate a list function.
 type ListPlan struct {
 	// Input data
 	Analysis       RelationAnalysis
 	Inline         InlineSQLData
 	DatabaseSchema string
+	TuplesTable    string // Tuples relation; empty means DefaultTuplesTable
 
 	// Function identity
 	FunctionName string
@@ -53,6 +56,35 @@ type ListPlan struct {
 	// from GenerateSQLOptions for callers that profile a workload where
 	// forced materialization helps.
 	EnableMaterializedCTEs bool
+
+	// AnytimeListObjects orders unpaged recursive list_objects results so
+	// base-level grants come before objects found by recursion. Wired from
+	// GenerateSQLOptions.AnytimeListObjects; see renderAnytimeListObjectsQuery.
+	AnytimeListObjects bool
+
+	// TraceBlocks tags every block comment with the relation and feature
+	// that produced it. Wired from GenerateSQLOptions.TraceBlocks; see
+	// traceComments.
+	TraceBlocks bool
+
+	// MaxDepth bounds the recursive CTEs of the function; a walk reaching it
+	// raises M2002 (see depthLimitGuard). Zero means DefaultMaxDepth. Wired
+	// from GenerateSQLOptions.MaxDepth.
+	MaxDepth int
+
+	// NullGuards raises 22004 when a required argument is NULL. Wired from
+	// GenerateSQLOptions.DisableNullGuards.
+	NullGuards bool
+
+	// ExpandWildcardSubjects reads the subjects p_expand_wildcard expands a
+	// wildcard into from SubjectsView instead of the tuples. Wired from
+	// GenerateSQLOptions.ExpandWildcardSubjects.
+	ExpandWildcardSubjects bool
+}
+
+// maxDepth returns p.MaxDepth, or DefaultMaxDepth when it is unset.
+func (p ListPlan) maxDepth() int {
+	return resolveMaxDepth(p.MaxDepth)
 }
 
 // MaterializeCTEs reports whether multi-referenced CTEs in generated list
@@ -62,9 +94,11 @@ func (p ListPlan) MaterializeCTEs() bool {
 	return p.EnableMaterializedCTEs
 }
 
-// wrapPagination applies plan-aware materialization to the cursor pagination wrapper.
+// wrapPagination applies plan-aware materialization to the cursor pagination
+// wrapper used by list_objects, after dropping the objects p_exclude_relation
+// names (see excludeRelation).
 func (p ListPlan) wrapPagination(query, idColumn string) string {
-	return wrapWithPaginationOpts(query, idColumn, p.MaterializeCTEs())
+	return wrapWithPaginationOpts(p.excludeRelation(query), idColumn, p.MaterializeCTEs())
 }
 
 // wrapPaginationWildcardFirst applies plan-aware materialization to the
@@ -81,11 +115,12 @@ func (p ListPlan) wrapExclusionCTEAndPagination(query, exclusionCTE string) stri
 
 // BuildListObjectsPlanWithLookup creates a plan with analysis lookup for TTU optimization.
 func BuildListObjectsPlanWithLookup(a RelationAnalysis, inline InlineSQLData, databaseSchema string, lookup map[string]*RelationAnalysis) ListPlan {
-	plan := buildBasePlan(a, inline, databaseSchema, listObjectsFunctionName(a.ObjectType, a.Relation), lookup)
+	plan := buildBasePlan(a, inline, databaseSchema, listObjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation), lookup)
 	if a.Features.HasExclusion {
 		plan.Exclusions = buildExclusionInput(
 			a,
 			databaseSchema,
+			"",
 			Col{Table: "t", Column: "object_id"},
 			SubjectType,
 			SubjectID,
@@ -105,11 +140,12 @@ func BuildListObjectsPlanWithLookup(a RelationAnalysis, inline InlineSQLData, da
 
 // BuildListSubjectsPlanWithLookup creates a plan with analysis lookup for TTU optimization.
 func BuildListSubjectsPlanWithLookup(a RelationAnalysis, inline InlineSQLData, databaseSchema string, lookup map[string]*RelationAnalysis) ListPlan {
-	plan := buildBasePlan(a, inline, databaseSchema, listSubjectsFunctionName(a.ObjectType, a.Relation), lookup)
+	plan := buildBasePlan(a, inline, databaseSchema, listSubjectsFunctionName(a.NameCollisions, a.ObjectType, a.Relation), lookup)
 	if a.Features.HasExclusion {
 		plan.Exclusions = buildExclusionInput(
 			a,
 			databaseSchema,
+			"",
 			ObjectID,
 			Col{Table: "t", Column: "subject_type"},
 			Col{Table: "t", Column: "subject_id"},
@@ -161,10 +197,50 @@ func buildBasePlan(a RelationAnalysis, inline InlineSQLData, databaseSchema, fun
 	}
 }
 
+// withTuplesTable returns p reading tuples, including those its exclusions
+// look up, from table. See CheckPlan.withTuplesTable.
+func (p ListPlan) withTuplesTable(table string) ListPlan {
+	p.TuplesTable = table
+	p.Exclusions.TuplesTable = table
+	return p
+}
+
+// directSubjectIDMatch matches column against p_subject_id in a direct tuple
+// lookup. See CheckPlan.directSubjectIDMatch.
+func (p ListPlan) directSubjectIDMatch(column Expr) Expr {
+	return directSubjectIDMatch(column, p.AllowWildcard, p.AllowedSubjectTypes, p.Analysis.ClosureWildcardSubjectTypes)
+}
+
+// wildcardSubjectTypeGuard returns the condition under which a '*' tuple of
+// type p_subject_type counts in a list_subjects direct lookup: p_subject_type
+// is one of the types whose restriction allows a wildcard. It is nil when the
+// allowed types all agree, which ExcludeWildcard already covers.
+func (p ListPlan) wildcardSubjectTypeGuard() Expr {
+	if !p.AllowWildcard {
+		return nil
+	}
+	wildcards := p.Analysis.ClosureWildcardSubjectTypes
+	for _, t := range p.AllowedSubjectTypes {
+		if !slices.Contains(wildcards, t) {
+			if len(wildcards) == 0 {
+				return Bool(false)
+			}
+			return In{Expr: Param("p_subject_type"), Values: wildcards}
+		}
+	}
+	return nil
+}
+
 func (p ListPlan) ExcludeWildcard() bool {
 	return !p.AllowWildcard
 }
 
+// ExpandsWildcard reports whether the direct tuple lookup can return a
+// wildcard grant, which p_expand_wildcard replaces with concrete subjects.
+func (p ListPlan) ExpandsWildcard() bool {
+	return p.Analysis.Features.HasWildcard && !p.ExcludeWildcard()
+}
+
 func (p ListPlan) FeaturesString() string {
 	return p.Analysis.Features.String()
 }
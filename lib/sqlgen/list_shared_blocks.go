@@ -1,5 +1,10 @@
 package sqlgen
 
+import (
+	"fmt"
+	"strings"
+)
+
 // TypedQueryBlock represents a query with optional comments.
 // Uses SelectStmt for type-safe DSL construction.
 type TypedQueryBlock struct {
@@ -21,3 +26,38 @@ type BlockSet struct {
 	Secondary     []TypedQueryBlock
 	SecondarySelf *TypedQueryBlock
 }
+
+// traceComments returns the comments of a block the plan renders. With
+// TraceBlocks, each line gets a tag naming the relation being generated, the
+// feature that produced the block, and the userset or TTU pattern it follows
+// when there is one:
+//
+//	-- [relation=document.viewer feature=userset pattern=group#member] Via group#member ...
+//
+// Otherwise the comments are returned unchanged.
+func (p ListPlan) traceComments(feature TraceNodeType, pattern string, comments ...string) []string {
+	if !p.TraceBlocks {
+		return comments
+	}
+	tag := fmt.Sprintf("[relation=%s.%s feature=%s", p.ObjectType, p.Relation, feature)
+	if pattern != "" {
+		tag += " pattern=" + pattern
+	}
+	tag += "]"
+
+	traced := make([]string, len(comments))
+	for i, c := range comments {
+		traced[i] = "-- " + tag + " " + strings.TrimPrefix(c, "-- ")
+	}
+	return traced
+}
+
+// usersetTracePattern formats a userset pattern for traceComments.
+func usersetTracePattern(subjectType, subjectRelation string) string {
+	return subjectType + "#" + subjectRelation
+}
+
+// ttuTracePattern formats a tuple-to-userset path for traceComments.
+func ttuTracePattern(linkingRelation, relation string) string {
+	return linkingRelation + "->" + relation
+}
@@ -20,6 +20,19 @@ func applyExclusionPredicates(q *TupleQuery, config ExclusionConfig, useCTE bool
 	}
 }
 
+// memberExclusionPredicates returns the relation's exclusion predicates for a
+// subject expanded from a userset grant. plan.Exclusions tests the grant
+// tuple's own subject (t.subject_type, t.subject_id), which in a userset block
+// is the userset ("group", "eng#member"), not the member being returned, so
+// userset blocks test the member columns instead. Returns nil when the CTE
+// anti-join applies exclusions to the final results.
+func memberExclusionPredicates(plan ListPlan, subjectType, subjectID Expr) []Expr {
+	if plan.UseCTEExclusion || !plan.HasExclusion {
+		return nil
+	}
+	return buildExclusionInput(plan.Analysis, plan.DatabaseSchema, plan.TuplesTable, ObjectID, subjectType, subjectID).BuildPredicates()
+}
+
 // BuildListSubjectsBlocks builds all query blocks for a list_subjects function.
 // Returns a BlockSet with Primary and optionally Secondary blocks.
 func BuildListSubjectsBlocks(plan ListPlan) (BlockSet, error) {
@@ -60,8 +73,11 @@ func buildListSubjectsUsersetFilterBlocks(plan ListPlan) (blocks []TypedQueryBlo
 
 	selfBlock = buildListSubjectsUsersetFilterSelfBlock(plan)
 
-	blocks = make([]TypedQueryBlock, 0, 1+len(intersectionBlocks))
+	wildcardBlocks := buildListSubjectsUsersetFilterWildcardBlocks(plan)
+
+	blocks = make([]TypedQueryBlock, 0, 1+len(wildcardBlocks)+len(intersectionBlocks))
 	blocks = append(blocks, directBlock)
+	blocks = append(blocks, wildcardBlocks...)
 	blocks = append(blocks, intersectionBlocks...)
 
 	return blocks, selfBlock, nil
@@ -72,15 +88,12 @@ func buildListSubjectsUsersetFilterBlocks(plan ListPlan) (blocks []TypedQueryBlo
 // Finds tuples where the subject is a userset (e.g., group:fga#member_c4) and checks
 // if the userset relation satisfies the filter relation via closure.
 func buildListSubjectsUsersetFilterDirectBlock(plan ListPlan) TypedQueryBlock {
-	closureExistsStmt := SelectStmt{
-		ColumnExprs: []Expr{Int(1)},
-		FromExpr:    ClosureTable(plan.Inline.ClosureRows, "subj_c"),
-		Where: And(
-			Eq{Left: Col{Table: "subj_c", Column: "object_type"}, Right: Param("v_filter_type")},
-			Eq{Left: Col{Table: "subj_c", Column: "relation"}, Right: UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}}},
-			Eq{Left: Col{Table: "subj_c", Column: "satisfying_relation"}, Right: Param("v_filter_relation")},
-		),
-	}
+	closureExistsStmt := closureContains(plan, "subj_c",
+		Param("v_filter_type"),
+		UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
+		Param("v_filter_relation"),
+		false,
+	)
 
 	subjectExpr := Alias{
 		Expr: NormalizedUsersetSubject(Col{Table: "t", Column: "subject_id"}, Param("v_filter_relation")),
@@ -90,7 +103,7 @@ func buildListSubjectsUsersetFilterDirectBlock(plan ListPlan) TypedQueryBlock {
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{subjectExpr},
-		FromExpr:    TableAs("", "melange_tuples", "t"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 		Where: And(
 			Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
 			In{Expr: Col{Table: "t", Column: "relation"}, Values: plan.AllSatisfyingRelations},
@@ -112,13 +125,134 @@ func buildListSubjectsUsersetFilterDirectBlock(plan ListPlan) TypedQueryBlock {
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeUserset, "",
 			"-- Userset filter: find userset tuples that match and return normalized references",
-		},
+		),
 		Query: stmt,
 	}
 }
 
+// buildListSubjectsUsersetFilterWildcardBlocks builds the userset filter blocks
+// for a wildcard grant of the filter type, such as document:1#viewer@group:*.
+// The direct block only reads subjects holding a '#', so it never sees the
+// '*'. A wildcard grant covers every v_filter_type#v_filter_relation userset,
+// so the first block returns '*' when v_filter_relation is a relation of
+// v_filter_type, and the second, taken instead with p_expand_wildcard, returns
+// the userset of every object of that type holding v_filter_relation, or a
+// relation that satisfies it, through a tuple of its own. With
+// plan.ExpandWildcardSubjects it returns the userset of every object of that
+// type in SubjectsView instead. Both confirm the result with
+// check_permission_internal, as the direct block does.
+func buildListSubjectsUsersetFilterWildcardBlocks(plan ListPlan) []TypedQueryBlock {
+	if !plan.ExpandsWildcard() {
+		return nil
+	}
+
+	grant := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "w").
+		ObjectType(plan.ObjectType).
+		Relations(plan.AllSatisfyingRelations...).
+		Where(
+			Eq{Left: Col{Table: "w", Column: "object_id"}, Right: ObjectID},
+			Eq{Left: Col{Table: "w", Column: "subject_type"}, Right: Param("v_filter_type")},
+			IsWildcard{Source: Col{Table: "w", Column: "subject_id"}},
+		).
+		Select("1")
+	filterRelationExists := closureContains(plan, "subj_c",
+		Param("v_filter_type"),
+		Param("v_filter_relation"),
+		Param("v_filter_relation"),
+		false,
+	)
+	wildcard := SelectStmt{
+		ColumnExprs: []Expr{Alias{Expr: Lit("*"), Name: "subject_id"}},
+		Where: And(
+			Not(Param("p_expand_wildcard")),
+			Exists{Query: grant.Build()},
+			ExistsExpr(filterRelationExists),
+			CheckPermission{
+				Schema:      plan.DatabaseSchema,
+				Subject:     SubjectRef{Type: Param("v_filter_type"), ID: Lit("*")},
+				Relation:    plan.Relation,
+				Object:      LiteralObject(plan.ObjectType, ObjectID),
+				ExpectAllow: true,
+			},
+		),
+	}
+
+	expanded := usersetFilterExpandedWildcardQuery(plan, grant.Build(), filterRelationExists)
+	expandedComment := "-- Userset filter wildcard expanded to the known usersets of the filter type (p_expand_wildcard)"
+	if plan.ExpandWildcardSubjects {
+		expandedComment = "-- Userset filter wildcard expanded to the usersets of the filter type in " + SubjectsView + " (p_expand_wildcard)"
+	}
+
+	return []TypedQueryBlock{
+		{
+			Comments: plan.traceComments(TraceNodeDirect, "",
+				"-- Userset filter wildcard: a v_filter_type:* grant covers every userset of the filter type",
+			),
+			Query: wildcard,
+		},
+		{
+			Comments: plan.traceComments(TraceNodeDirect, "",
+				expandedComment,
+			),
+			Query: expanded,
+		},
+	}
+}
+
+// usersetFilterExpandedWildcardQuery returns the usersets a v_filter_type:*
+// grant expands to under p_expand_wildcard: those of objects holding the
+// filter relation through a tuple, or, with plan.ExpandWildcardSubjects, those
+// of every object of v_filter_type in SubjectsView.
+func usersetFilterExpandedWildcardQuery(plan ListPlan, grant SelectStmt, filterRelationExists SelectStmt) SelectStmt {
+	usersetCheck := func(usersetID Expr) Expr {
+		return CheckPermission{
+			Schema:      plan.DatabaseSchema,
+			Subject:     SubjectRef{Type: Param("v_filter_type"), ID: usersetID},
+			Relation:    plan.Relation,
+			Object:      LiteralObject(plan.ObjectType, ObjectID),
+			ExpectAllow: true,
+		}
+	}
+
+	if plan.ExpandWildcardSubjects {
+		usersetID := Concat{Parts: []Expr{Col{Table: "u", Column: "subject_id"}, Lit("#"), Param("v_filter_relation")}}
+		return SelectStmt{
+			Distinct:    true,
+			ColumnExprs: []Expr{Alias{Expr: usersetID, Name: "subject_id"}},
+			FromExpr:    TableAs("", SubjectsView, "u"),
+			Where: And(
+				Param("p_expand_wildcard"),
+				Eq{Left: Col{Table: "u", Column: "subject_type"}, Right: Param("v_filter_type")},
+				ExistsExpr(filterRelationExists),
+				Exists{Query: grant},
+				usersetCheck(usersetID),
+			),
+		}
+	}
+
+	usersetID := Concat{Parts: []Expr{Col{Table: "u", Column: "object_id"}, Lit("#"), Param("v_filter_relation")}}
+	holdsFilterRelation := closureContains(plan, "subj_c",
+		Param("v_filter_type"),
+		Param("v_filter_relation"),
+		Col{Table: "u", Column: "relation"},
+		false,
+	)
+	return SelectStmt{
+		Distinct:    true,
+		ColumnExprs: []Expr{Alias{Expr: usersetID, Name: "subject_id"}},
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "u"),
+		Where: And(
+			Param("p_expand_wildcard"),
+			Eq{Left: Col{Table: "u", Column: "object_type"}, Right: Param("v_filter_type")},
+			ExistsExpr(holdsFilterRelation),
+			Exists{Query: grant},
+			usersetCheck(usersetID),
+		),
+	}
+}
+
 // buildListSubjectsUsersetFilterSelfBlock builds the self-referential userset filter block.
 // Returns the object itself as a userset reference when filter type matches object type.
 func buildListSubjectsUsersetFilterSelfBlock(plan ListPlan) *TypedQueryBlock {
@@ -140,11 +274,11 @@ func buildListSubjectsUsersetFilterSelfBlock(plan ListPlan) *TypedQueryBlock {
 	}
 
 	return &TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeUserset, "",
 			"-- Self-candidate: when filter type matches object type",
 			"-- e.g., querying document:1.viewer with filter document#writer",
 			"-- should return document:1#writer if writer satisfies the relation",
-		},
+		),
 		Query: stmt,
 	}
 }
@@ -152,6 +286,10 @@ func buildListSubjectsUsersetFilterSelfBlock(plan ListPlan) *TypedQueryBlock {
 // buildListSubjectsRegularBlocks builds the regular (non-userset-filter) path blocks.
 func buildListSubjectsRegularBlocks(plan ListPlan) ([]TypedQueryBlock, error) {
 	blocks := []TypedQueryBlock{buildListSubjectsDirectBlock(plan)}
+	blocks = append(blocks, buildListSubjectsConditionBlocks(plan)...)
+	if plan.ExpandsWildcard() {
+		blocks = append(blocks, buildListSubjectsWildcardExpansionBlock(plan))
+	}
 
 	blocks = append(blocks, buildTypedListSubjectsComplexClosureBlocks(plan)...)
 
@@ -176,22 +314,82 @@ func buildListSubjectsRegularBlocks(plan ListPlan) ([]TypedQueryBlock, error) {
 
 // buildListSubjectsDirectBlock builds the direct tuple lookup block for list_subjects.
 func buildListSubjectsDirectBlock(plan ListPlan) TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(plan.RelationList...).
 		Where(
 			Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
 			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Param("p_subject_type")},
 			NoUserset{Source: Col{Table: "t", Column: "subject_id"}},
+			conditionalTupleGuard(plan.Analysis, "t"),
 		).
 		SelectCol("subject_id").
 		Distinct()
 
 	applyWildcardExclusion(q, plan, "t")
+	if guard := plan.wildcardSubjectTypeGuard(); guard != nil {
+		q.Where(Or(Ne{Left: Col{Table: "t", Column: "subject_id"}, Right: Lit("*")}, guard))
+	}
+	if plan.ExpandsWildcard() {
+		// The expansion block returns the subjects '*' stands for instead.
+		q.Where(Or(
+			Not(Param("p_expand_wildcard")),
+			Ne{Left: Col{Table: "t", Column: "subject_id"}, Right: Lit("*")},
+		))
+	}
 	applyExclusionPredicates(q, plan.Exclusions, plan.UseCTEExclusion)
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Path 1: Direct tuple lookup with simple closure relations"},
+		Comments: plan.traceComments(TraceNodeDirect, "", "-- Path 1: Direct tuple lookup with simple closure relations"),
+		Query:    q.Build(),
+	}
+}
+
+// buildListSubjectsWildcardExpansionBlock builds the block that, when the
+// caller passes p_expand_wildcard, answers a wildcard grant on the object with
+// every concrete subject of the requested type found anywhere in the tuples
+// table, or listed in SubjectsView with plan.ExpandWildcardSubjects. The
+// direct block drops the '*' row in that case.
+func buildListSubjectsWildcardExpansionBlock(plan ListPlan) TypedQueryBlock {
+	grant := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "w").
+		ObjectType(plan.ObjectType).
+		Relations(plan.RelationList...).
+		Where(
+			Eq{Left: Col{Table: "w", Column: "object_id"}, Right: ObjectID},
+			Eq{Left: Col{Table: "w", Column: "subject_type"}, Right: Param("p_subject_type")},
+			IsWildcard{Source: Col{Table: "w", Column: "subject_id"}},
+			plan.wildcardSubjectTypeGuard(),
+		).
+		Select("1")
+
+	subjectID := Col{Table: "u", Column: "subject_id"}
+	where := append([]Expr{
+		Param("p_expand_wildcard"),
+		Eq{Left: Col{Table: "u", Column: "subject_type"}, Right: Param("p_subject_type")},
+		NoUserset{Source: subjectID},
+		Ne{Left: subjectID, Right: Lit("*")},
+		Exists{Query: grant.Build()},
+	}, memberExclusionPredicates(plan, Param("p_subject_type"), subjectID)...)
+
+	if plan.ExpandWildcardSubjects {
+		return TypedQueryBlock{
+			Comments: plan.traceComments(TraceNodeDirect, "", "-- Path 1b: Wildcard grant expanded to the subjects of the type in "+SubjectsView+" (p_expand_wildcard)"),
+			Query: SelectStmt{
+				Distinct:    true,
+				ColumnExprs: []Expr{subjectID},
+				FromExpr:    TableAs("", SubjectsView, "u"),
+				Where:       And(where...),
+			},
+		}
+	}
+
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "u").
+		Where(where...).
+		SelectCol("subject_id").
+		Distinct()
+
+	return TypedQueryBlock{
+		Comments: plan.traceComments(TraceNodeDirect, "", "-- Path 1b: Wildcard grant expanded to the known subjects of the type (p_expand_wildcard)"),
 		Query:    q.Build(),
 	}
 }
@@ -204,7 +402,7 @@ func buildTypedListSubjectsComplexClosureBlocks(plan ListPlan) []TypedQueryBlock
 
 	blocks := make([]TypedQueryBlock, 0, len(plan.ComplexClosure))
 	for _, rel := range plan.ComplexClosure {
-		q := Tuples(plan.DatabaseSchema, "t").
+		q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 			ObjectType(plan.ObjectType).
 			Relations(rel).
 			Where(
@@ -229,7 +427,7 @@ func buildTypedListSubjectsComplexClosureBlocks(plan ListPlan) []TypedQueryBlock
 		applyExclusionPredicates(q, plan.Exclusions, plan.UseCTEExclusion)
 
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{"-- Complex closure: validate via check_permission_internal"},
+			Comments: plan.traceComments(TraceNodeImplied, rel, "-- Complex closure: validate via check_permission_internal"),
 			Query:    q.Build(),
 		})
 	}
@@ -249,8 +447,8 @@ func buildListSubjectsIntersectionClosureBlocks(plan ListPlan, subjectTypeExpr,
 	for _, rel := range plan.Analysis.IntersectionClosureRelations {
 		funcCall := FunctionCallExpr{
 			Schema: plan.DatabaseSchema,
-			Name:   listSubjectsFunctionName(plan.ObjectType, rel),
-			Args:   []Expr{ObjectID, Raw(subjectTypeExpr)},
+			Name:   listSubjectsFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, rel),
+			Args:   listSubjectsCallArgs(ObjectID, Raw(subjectTypeExpr)),
 			Alias:  "ics",
 		}
 
@@ -271,7 +469,7 @@ func buildListSubjectsIntersectionClosureBlocks(plan ListPlan, subjectTypeExpr,
 		}
 
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{fmt.Sprintf("-- Compose with intersection closure: %s", rel)},
+			Comments: plan.traceComments(TraceNodeIntersection, rel, fmt.Sprintf("-- Compose with intersection closure: %s", rel)),
 			Query:    stmt,
 		})
 	}
@@ -303,7 +501,7 @@ func buildListSubjectsUsersetPatternBlocks(plan ListPlan) ([]TypedQueryBlock, er
 // buildListSubjectsComplexUsersetBlock builds a block for complex userset patterns.
 // Uses LATERAL join with userset's list_subjects function for userset-to-userset chains.
 func buildListSubjectsComplexUsersetBlock(plan ListPlan, pattern listUsersetPatternInput) TypedQueryBlock {
-	funcName := listSubjectsFunctionName(pattern.SubjectType, pattern.SubjectRelation)
+	funcName := listSubjectsFunctionName(plan.Analysis.NameCollisions, pattern.SubjectType, pattern.SubjectRelation)
 
 	conditions := []Expr{
 		Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
@@ -317,46 +515,28 @@ func buildListSubjectsComplexUsersetBlock(plan ListPlan, pattern listUsersetPatt
 		},
 	}
 
-	// For exclusions on LATERAL join blocks, we must check each returned subject
-	// individually since the exclusion applies to the result (ls.subject_id), not the
-	// userset tuple (t.subject_id). Use the exclusion config with the returned subject's
-	// columns to build correct NOT EXISTS predicates.
-	if !plan.UseCTEExclusion && plan.HasExclusion {
-		resultExclusions := buildSimpleComplexExclusionInput(
-			plan.Analysis,
-			plan.DatabaseSchema,
-			ObjectID,
-			Param("p_subject_type"),
-			Col{Table: "ls", Column: "subject_id"},
-		)
-		conditions = append(conditions, resultExclusions.BuildPredicates()...)
-	}
+	conditions = append(conditions, memberExclusionPredicates(plan, Param("p_subject_type"), Col{Table: "ls", Column: "subject_id"})...)
 
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: "ls", Column: "subject_id"}},
-		FromExpr:    TableAs("", "melange_tuples", "t"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 		Joins: []JoinClause{{
 			Type: "CROSS JOIN LATERAL",
 			TableExpr: FunctionCallExpr{
 				Schema: plan.DatabaseSchema,
 				Name:   funcName,
-				Args: []Expr{
-					UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}},
-					Param("p_subject_type"),
-					Null{},
-					Null{},
-				},
-				Alias: "ls",
+				Args:   listSubjectsCallArgs(UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}}, SubjectType),
+				Alias:  "ls",
 			},
 		}},
 		Where: And(conditions...),
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation),
 			fmt.Sprintf("-- Via %s#%s (complex userset, LATERAL join)", pattern.SubjectType, pattern.SubjectRelation),
-		},
+		),
 		Query: stmt,
 	}
 }
@@ -364,7 +544,7 @@ func buildListSubjectsComplexUsersetBlock(plan ListPlan, pattern listUsersetPatt
 // buildListSubjectsSimpleUsersetBlock builds a block for simple userset patterns.
 // Uses JOIN with membership tuples to expand group membership.
 func buildListSubjectsSimpleUsersetBlock(plan ListPlan, pattern listUsersetPatternInput) TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(pattern.SourceRelations...).
 		Where(
@@ -404,12 +584,14 @@ func buildListSubjectsSimpleUsersetBlock(plan ListPlan, pattern listUsersetPatte
 		})
 	}
 
-	applyExclusionPredicates(q, plan.Exclusions, plan.UseCTEExclusion)
+	for _, pred := range memberExclusionPredicates(plan, Param("p_subject_type"), Col{Table: "m", Column: "subject_id"}) {
+		q.Where(pred)
+	}
 
 	return TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation),
 			fmt.Sprintf("-- Via %s#%s (simple userset, JOIN)", pattern.SubjectType, pattern.SubjectRelation),
-		},
+		),
 		Query: q.Build(),
 	}
 }
@@ -13,7 +13,7 @@ func BuildListSubjectsComposedBlocks(plan ListPlan) (ComposedSubjectsBlockSet, e
 	}
 
 	candidateBlocks := buildComposedSubjectsCandidateBlocks(plan, anchor)
-	exclusions := buildSimpleComplexExclusionInput(plan.Analysis, plan.DatabaseSchema, ObjectID, SubjectType, Col{Table: "sc", Column: "subject_id"})
+	exclusions := buildSimpleComplexExclusionInput(plan.Analysis, plan.DatabaseSchema, plan.TuplesTable, ObjectID, SubjectType, Col{Table: "sc", Column: "subject_id"})
 
 	return ComposedSubjectsBlockSet{
 		SelfBlock:           buildComposedSubjectsSelfBlock(plan),
@@ -28,18 +28,10 @@ func BuildListSubjectsComposedBlocks(plan ListPlan) (ComposedSubjectsBlockSet, e
 }
 
 func buildComposedSubjectsSelfBlock(plan ListPlan) *TypedQueryBlock {
-	closureStmt := SelectStmt{
-		ColumnExprs: []Expr{Int(1)},
-		FromExpr:    ClosureTable(plan.Inline.ClosureRows, "c"),
-		Where: And(
-			Eq{Left: Col{Table: "c", Column: "object_type"}, Right: Lit(plan.ObjectType)},
-			Eq{Left: Col{Table: "c", Column: "relation"}, Right: Lit(plan.Relation)},
-			Eq{Left: Col{Table: "c", Column: "satisfying_relation"}, Right: Param("v_filter_relation")},
-		),
-	}
+	closureStmt := closureContains(plan, "c", Lit(plan.ObjectType), Lit(plan.Relation), Param("v_filter_relation"), false)
 
 	return &TypedQueryBlock{
-		Comments: []string{"-- Self-candidate: object_id#filter_relation when filter type matches object type"},
+		Comments: plan.traceComments(TraceNodeUserset, "", "-- Self-candidate: object_id#filter_relation when filter type matches object type"),
 		Query: SelectStmt{
 			ColumnExprs: []Expr{SelectAs(Concat{Parts: []Expr{ObjectID, Lit("#"), Param("v_filter_relation")}}, "subject_id")},
 			Where: And(
@@ -74,17 +66,17 @@ func buildComposedTTUSubjectsBlock(plan ListPlan, anchor *IndirectAnchorInfo, ta
 	firstStep := anchor.Path[0]
 
 	return TypedQueryBlock{
-		Comments: []string{"-- From " + targetType + " parents"},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(anchor.Path[0].LinkingRelation, anchor.Path[0].TargetRelation), "-- From "+targetType+" parents"),
 		Query: SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{Col{Table: "s", Column: "subject_id"}},
-			FromExpr:    TableAs("", "melange_tuples", "link"),
+			FromExpr:    TuplesTableAs(plan.TuplesTable, "link"),
 			Joins: []JoinClause{{
 				Type: "CROSS",
 				TableExpr: LateralFunction{
 					Schema: plan.DatabaseSchema,
-					Name:   ListSubjectsFunctionName(targetType, firstStep.TargetRelation),
-					Args:   []Expr{Col{Table: "link", Column: "subject_id"}, SubjectType},
+					Name:   ListSubjectsFunctionNameWithCollisions(plan.Analysis.NameCollisions, targetType, firstStep.TargetRelation),
+					Args:   listSubjectsCallArgs(Col{Table: "link", Column: "subject_id"}, SubjectType),
 					Alias:  "s",
 				},
 			}},
@@ -102,17 +94,17 @@ func buildComposedUsersetSubjectsBlock(plan ListPlan, firstStep AnchorPathStep)
 	subjectIDCol := Col{Table: "t", Column: "subject_id"}
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Userset: " + firstStep.SubjectType + "#" + firstStep.SubjectRelation + " grants"},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(firstStep.SubjectType, firstStep.SubjectRelation), "-- Userset: "+firstStep.SubjectType+"#"+firstStep.SubjectRelation+" grants"),
 		Query: SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{Col{Table: "s", Column: "subject_id"}},
-			FromExpr:    TableAs("", "melange_tuples", "t"),
+			FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 			Joins: []JoinClause{{
 				Type: "CROSS",
 				TableExpr: LateralFunction{
 					Schema: plan.DatabaseSchema,
-					Name:   ListSubjectsFunctionName(firstStep.SubjectType, firstStep.SubjectRelation),
-					Args:   []Expr{Raw("split_part(t.subject_id, '#', 1)"), SubjectType},
+					Name:   ListSubjectsFunctionNameWithCollisions(plan.Analysis.NameCollisions, firstStep.SubjectType, firstStep.SubjectRelation),
+					Args:   listSubjectsCallArgs(UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}}, SubjectType),
 					Alias:  "s",
 				},
 			}},
@@ -11,33 +11,33 @@ type SubjectsIntersectionBlockSet struct {
 	UsersetFilterSelfBlock       *TypedQueryBlock
 }
 
-// buildDirectSubjectSelectStmt creates a SELECT DISTINCT subject_id FROM melange_tuples t.
-func buildDirectSubjectSelectStmt(conditions []Expr) SelectStmt {
+// buildDirectSubjectSelectStmt creates a SELECT DISTINCT subject_id FROM the tuples table t.
+func buildDirectSubjectSelectStmt(tuplesTable string, conditions []Expr) SelectStmt {
 	return SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: "t", Column: "subject_id"}},
-		FromExpr:    TableAs("", "melange_tuples", "t"),
+		FromExpr:    TuplesTableAs(tuplesTable, "t"),
 		Where:       And(conditions...),
 	}
 }
 
 // buildTTUSubjectSelectStmt creates a TTU join query selecting subject_id from pt.
-func buildTTUSubjectSelectStmt(conditions []Expr) SelectStmt {
+func buildTTUSubjectSelectStmt(tuplesTable string, conditions []Expr) SelectStmt {
 	return SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: "pt", Column: "subject_id"}},
-		FromExpr:    TableAs("", "melange_tuples", "link"),
-		Joins:       []JoinClause{ttuJoin()},
+		FromExpr:    TuplesTableAs(tuplesTable, "link"),
+		Joins:       []JoinClause{ttuJoin(tuplesTable)},
 		Where:       And(conditions...),
 	}
 }
 
 // ttuJoin returns the standard TTU join clause.
-func ttuJoin() JoinClause {
+func ttuJoin(tuplesTable string) JoinClause {
 	return JoinClause{
 		Type:   "INNER",
 		Schema: "",
-		Table:  "melange_tuples",
+		Table:  TuplesTableName(tuplesTable),
 		Alias:  "pt",
 		On: And(
 			Eq{Left: Col{Table: "pt", Column: "object_type"}, Right: Col{Table: "link", Column: "subject_type"}},
@@ -47,18 +47,18 @@ func ttuJoin() JoinClause {
 }
 
 // buildUsersetFilterTTUSelectStmt creates a userset filter TTU query.
-func buildUsersetFilterTTUSelectStmt(objectType, linkingRelation string, subjectExpr, relationMatch Expr) SelectStmt {
+func buildUsersetFilterTTUSelectStmt(tuplesTable, objectType, linkingRelation string, subjectExpr, relationMatch Expr) SelectStmt {
 	return SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{subjectExpr},
-		FromExpr:    TableAs("", "melange_tuples", "link"),
-		Joins:       []JoinClause{ttuJoin()},
+		FromExpr:    TuplesTableAs(tuplesTable, "link"),
+		Joins:       []JoinClause{ttuJoin(tuplesTable)},
 		Where: And(
 			Eq{Left: Col{Table: "link", Column: "object_type"}, Right: Lit(objectType)},
 			Eq{Left: Col{Table: "link", Column: "object_id"}, Right: ObjectID},
 			Eq{Left: Col{Table: "link", Column: "relation"}, Right: Lit(linkingRelation)},
 			Eq{Left: Col{Table: "pt", Column: "subject_type"}, Right: Param("v_filter_type")},
-			Gt{Left: Raw("position('#' in pt.subject_id)"), Right: Int(0)},
+			HasUserset{Source: Col{Table: "pt", Column: "subject_id"}},
 			relationMatch,
 		),
 	}
@@ -99,12 +99,12 @@ func buildListSubjectsIntersectionBaseBlock(plan ListPlan, excludeWildcard bool)
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: "t", Column: "subject_id"}},
-		FromExpr:    TableAs("", "melange_tuples", "t"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 		Where:       And(conditions...),
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Base: direct tuple lookup"},
+		Comments: plan.traceComments(TraceNodeDirect, "", "-- Base: direct tuple lookup"),
 		Query:    stmt,
 	}
 }
@@ -113,9 +113,6 @@ func buildListSubjectsIntersectionPartBlocks(plan ListPlan, excludeWildcard bool
 	var blocks []TypedQueryBlock
 	for _, group := range plan.Analysis.IntersectionGroups {
 		for _, part := range group.Parts {
-			if part.IsThis {
-				continue
-			}
 			blocks = append(blocks, buildListSubjectsIntersectionPartBlock(plan, part, excludeWildcard))
 		}
 	}
@@ -123,6 +120,26 @@ func buildListSubjectsIntersectionPartBlocks(plan ListPlan, excludeWildcard bool
 }
 
 func buildListSubjectsIntersectionPartBlock(plan ListPlan, part IntersectionPart, excludeWildcard bool) TypedQueryBlock {
+	if part.IsThis {
+		// The [user] part is satisfied by direct tuples on the wrapping
+		// relation itself (buildIntersectionThisPartQuery on the objects side).
+		conditions := []Expr{
+			Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
+			Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
+			Eq{Left: Col{Table: "t", Column: "relation"}, Right: Lit(plan.Relation)},
+			Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: SubjectType},
+			In{Expr: SubjectType, Values: plan.AllowedSubjectTypes},
+		}
+		if excludeWildcard {
+			conditions = append(conditions, Ne{Left: Col{Table: "t", Column: "subject_id"}, Right: Lit("*")})
+		}
+
+		return TypedQueryBlock{
+			Comments: plan.traceComments(TraceNodeIntersection, plan.Relation, fmt.Sprintf("-- Intersection part: direct %s", plan.Relation)),
+			Query:    buildDirectSubjectSelectStmt(plan.TuplesTable, conditions),
+		}
+	}
+
 	if part.ParentRelation != nil {
 		conditions := []Expr{
 			Eq{Left: Col{Table: "link", Column: "object_type"}, Right: Lit(plan.ObjectType)},
@@ -135,8 +152,8 @@ func buildListSubjectsIntersectionPartBlock(plan ListPlan, part IntersectionPart
 		}
 
 		return TypedQueryBlock{
-			Comments: []string{fmt.Sprintf("-- Intersection part: via %s", part.ParentRelation.LinkingRelation)},
-			Query:    buildTTUSubjectSelectStmt(conditions),
+			Comments: plan.traceComments(TraceNodeIntersection, ttuTracePattern(part.ParentRelation.LinkingRelation, part.ParentRelation.Relation), fmt.Sprintf("-- Intersection part: via %s", part.ParentRelation.LinkingRelation)),
+			Query:    buildTTUSubjectSelectStmt(plan.TuplesTable, conditions),
 		}
 	}
 
@@ -151,8 +168,8 @@ func buildListSubjectsIntersectionPartBlock(plan ListPlan, part IntersectionPart
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Intersection part: %s", part.Relation)},
-		Query:    buildDirectSubjectSelectStmt(conditions),
+		Comments: plan.traceComments(TraceNodeIntersection, part.Relation, fmt.Sprintf("-- Intersection part: %s", part.Relation)),
+		Query:    buildDirectSubjectSelectStmt(plan.TuplesTable, conditions),
 	}
 }
 
@@ -195,15 +212,15 @@ func buildListSubjectsIntersectionUsersetPatternBlock(plan ListPlan, pattern lis
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Userset pattern: %s#%s", pattern.SubjectType, pattern.SubjectRelation)},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation), fmt.Sprintf("-- Userset pattern: %s#%s", pattern.SubjectType, pattern.SubjectRelation)),
 		Query: SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{Col{Table: memberAlias, Column: "subject_id"}},
-			FromExpr:    TableAs("", "melange_tuples", grantAlias),
+			FromExpr:    TuplesTableAs(plan.TuplesTable, grantAlias),
 			Joins: []JoinClause{{
 				Type:   "INNER",
 				Schema: "",
-				Table:  "melange_tuples",
+				Table:  TuplesTableName(plan.TuplesTable),
 				Alias:  memberAlias,
 				On:     joinCond,
 			}},
@@ -240,8 +257,8 @@ func buildListSubjectsIntersectionTTUBlock(plan ListPlan, parent ListParentRelat
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- TTU: subjects via %s -> %s", parent.LinkingRelation, parent.Relation)},
-		Query:    buildTTUSubjectSelectStmt(conditions),
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), fmt.Sprintf("-- TTU: subjects via %s -> %s", parent.LinkingRelation, parent.Relation)),
+		Query:    buildTTUSubjectSelectStmt(plan.TuplesTable, conditions),
 	}
 }
 
@@ -254,8 +271,8 @@ func buildListSubjectsIntersectionPoolBlock(plan ListPlan, excludeWildcard bool)
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Subject pool: all subjects of requested type"},
-		Query:    buildDirectSubjectSelectStmt(conditions),
+		Comments: plan.traceComments(TraceNodeIntersection, "", "-- Subject pool: all subjects of requested type"),
+		Query:    buildDirectSubjectSelectStmt(plan.TuplesTable, conditions),
 	}
 }
 
@@ -268,39 +285,31 @@ func buildListSubjectsIntersectionUsersetCandidates(plan ListPlan) []TypedQueryB
 }
 
 func buildListSubjectsIntersectionUsersetFilterBaseBlock(plan ListPlan) TypedQueryBlock {
-	relationMatch := buildUsersetFilterRelationMatchExpr("t.subject_id")
+	relationMatch := buildUsersetFilterRelationMatchExpr(plan, "t.subject_id")
 	subjectExpr := Alias{Expr: NormalizedUsersetSubject(Col{Table: "t", Column: "subject_id"}, Param("v_filter_relation")), Name: "subject_id"}
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Userset filter: direct userset tuples"},
+		Comments: plan.traceComments(TraceNodeUserset, "", "-- Userset filter: direct userset tuples"),
 		Query: SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{subjectExpr},
-			FromExpr:    TableAs("", "melange_tuples", "t"),
+			FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 			Where: And(
 				Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
 				Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
 				In{Expr: Col{Table: "t", Column: "relation"}, Values: plan.AllSatisfyingRelations},
 				Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Param("v_filter_type")},
-				Gt{Left: Raw("position('#' in t.subject_id)"), Right: Int(0)},
+				HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
 				relationMatch,
 			),
 		},
 	}
 }
 
-func buildUsersetFilterRelationMatchExpr(subjectIDExpr string) Expr {
+func buildUsersetFilterRelationMatchExpr(plan ListPlan, subjectIDExpr string) Expr {
 	relationExtract := UsersetRelation{Source: Raw(subjectIDExpr)}
 	// The closure table is hoisted into a per-function `closure` CTE (Finding 7).
-	closureExistsStmt := SelectStmt{
-		Columns:  []string{"1"},
-		FromExpr: closureCTERef("subj_c"),
-		Where: And(
-			Eq{Left: Col{Table: "subj_c", Column: "object_type"}, Right: Param("v_filter_type")},
-			Eq{Left: Col{Table: "subj_c", Column: "relation"}, Right: relationExtract},
-			Eq{Left: Col{Table: "subj_c", Column: "satisfying_relation"}, Right: Param("v_filter_relation")},
-		),
-	}
+	closureExistsStmt := closureContains(plan, "subj_c", Param("v_filter_type"), relationExtract, Param("v_filter_relation"), true)
 	return Or(
 		Eq{Left: relationExtract, Right: Param("v_filter_relation")},
 		Exists{Query: closureExistsStmt},
@@ -322,30 +331,30 @@ func buildListSubjectsIntersectionUsersetFilterPartBlocks(plan ListPlan) []Typed
 
 func buildListSubjectsIntersectionUsersetFilterPartBlock(plan ListPlan, part IntersectionPart) TypedQueryBlock {
 	if part.ParentRelation != nil {
-		relationMatch := buildUsersetFilterRelationMatchExpr("pt.subject_id")
+		relationMatch := buildUsersetFilterRelationMatchExpr(plan, "pt.subject_id")
 		subjectExpr := Alias{Expr: NormalizedUsersetSubject(Col{Table: "pt", Column: "subject_id"}, Param("v_filter_relation")), Name: "subject_id"}
 
 		return TypedQueryBlock{
-			Comments: []string{fmt.Sprintf("-- Userset filter intersection part: via %s", part.ParentRelation.LinkingRelation)},
-			Query:    buildUsersetFilterTTUSelectStmt(plan.ObjectType, part.ParentRelation.LinkingRelation, subjectExpr, relationMatch),
+			Comments: plan.traceComments(TraceNodeIntersection, ttuTracePattern(part.ParentRelation.LinkingRelation, part.ParentRelation.Relation), fmt.Sprintf("-- Userset filter intersection part: via %s", part.ParentRelation.LinkingRelation)),
+			Query:    buildUsersetFilterTTUSelectStmt(plan.TuplesTable, plan.ObjectType, part.ParentRelation.LinkingRelation, subjectExpr, relationMatch),
 		}
 	}
 
-	relationMatch := buildUsersetFilterRelationMatchExpr("t.subject_id")
+	relationMatch := buildUsersetFilterRelationMatchExpr(plan, "t.subject_id")
 	subjectExpr := Alias{Expr: NormalizedUsersetSubject(Col{Table: "t", Column: "subject_id"}, Param("v_filter_relation")), Name: "subject_id"}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Userset filter intersection part: %s", part.Relation)},
+		Comments: plan.traceComments(TraceNodeIntersection, part.Relation, fmt.Sprintf("-- Userset filter intersection part: %s", part.Relation)),
 		Query: SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{subjectExpr},
-			FromExpr:    TableAs("", "melange_tuples", "t"),
+			FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 			Where: And(
 				Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
 				Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
 				Eq{Left: Col{Table: "t", Column: "relation"}, Right: Lit(part.Relation)},
 				Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Param("v_filter_type")},
-				Gt{Left: Raw("position('#' in t.subject_id)"), Right: Int(0)},
+				HasUserset{Source: Col{Table: "t", Column: "subject_id"}},
 				relationMatch,
 			),
 		},
@@ -366,17 +375,17 @@ func buildListSubjectsIntersectionUsersetFilterTTUBlocks(plan ListPlan) []TypedQ
 }
 
 func buildListSubjectsIntersectionUsersetFilterTTUBlock(plan ListPlan, parent ListParentRelationData) TypedQueryBlock {
-	relationMatch := buildUsersetFilterRelationMatchExpr("pt.subject_id")
+	relationMatch := buildUsersetFilterRelationMatchExpr(plan, "pt.subject_id")
 	subjectExpr := Alias{Expr: NormalizedUsersetSubject(Col{Table: "pt", Column: "subject_id"}, Param("v_filter_relation")), Name: "subject_id"}
 
-	stmt := buildUsersetFilterTTUSelectStmt(plan.ObjectType, parent.LinkingRelation, subjectExpr, relationMatch)
+	stmt := buildUsersetFilterTTUSelectStmt(plan.TuplesTable, plan.ObjectType, parent.LinkingRelation, subjectExpr, relationMatch)
 	if len(parent.AllowedLinkingTypesSlice) > 0 {
 		// Add type restriction to existing WHERE clause
 		stmt.Where = And(stmt.Where, In{Expr: Col{Table: "link", Column: "subject_type"}, Values: parent.AllowedLinkingTypesSlice})
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Userset filter TTU: via %s -> %s", parent.LinkingRelation, parent.Relation)},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), fmt.Sprintf("-- Userset filter TTU: via %s -> %s", parent.LinkingRelation, parent.Relation)),
 		Query:    stmt,
 	}
 }
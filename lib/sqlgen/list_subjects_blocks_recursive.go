@@ -85,7 +85,7 @@ func buildListSubjectsRecursiveRegularBlocks(plan ListPlan) ([]TypedQueryBlock,
 
 // buildListSubjectsRecursiveDirectBlock builds the direct tuple lookup block for recursive list_subjects.
 func buildListSubjectsRecursiveDirectBlock(plan ListPlan) TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(plan.RelationList...).
 		Where(
@@ -104,7 +104,7 @@ func buildListSubjectsRecursiveDirectBlock(plan ListPlan) TypedQueryBlock {
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Direct tuple lookup with simple closure relations"},
+		Comments: plan.traceComments(TraceNodeDirect, "", "-- Direct tuple lookup with simple closure relations"),
 		Query:    q.Build(),
 	}
 }
@@ -118,7 +118,7 @@ func buildListSubjectsRecursiveComplexClosureBlocks(plan ListPlan) []TypedQueryB
 	tailValidated := regularBranchTailValidated(plan)
 	blocks := make([]TypedQueryBlock, 0, len(plan.ComplexClosure))
 	for _, rel := range plan.ComplexClosure {
-		q := Tuples(plan.DatabaseSchema, "t").
+		q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 			ObjectType(plan.ObjectType).
 			Relations(rel).
 			Where(
@@ -142,7 +142,7 @@ func buildListSubjectsRecursiveComplexClosureBlocks(plan ListPlan) []TypedQueryB
 		}
 
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{fmt.Sprintf("-- Complex closure relation: %s", rel)},
+			Comments: plan.traceComments(TraceNodeImplied, rel, fmt.Sprintf("-- Complex closure relation: %s", rel)),
 			Query:    q.Build(),
 		})
 	}
@@ -184,7 +184,7 @@ func buildListSubjectsRecursiveComplexUsersetBlock(plan ListPlan, pattern listUs
 
 	const grantAlias, memberAlias = "g", "m"
 
-	memberExclusions := buildExclusionInput(plan.Analysis, plan.DatabaseSchema, ObjectID, Col{Table: memberAlias, Column: "subject_type"}, Col{Table: memberAlias, Column: "subject_id"})
+	memberExclusions := buildExclusionInput(plan.Analysis, plan.DatabaseSchema, plan.TuplesTable, ObjectID, Col{Table: memberAlias, Column: "subject_type"}, Col{Table: memberAlias, Column: "subject_id"})
 
 	checkExpr := CheckPermissionInternalExpr(
 		plan.DatabaseSchema,
@@ -239,11 +239,11 @@ func buildListSubjectsRecursiveComplexUsersetBlock(plan ListPlan, pattern listUs
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: memberAlias, Column: "subject_id"}},
-		FromExpr:    TableAs("", "melange_tuples", grantAlias),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, grantAlias),
 		Joins: []JoinClause{{
 			Type:   "INNER",
 			Schema: "",
-			Table:  "melange_tuples",
+			Table:  TuplesTableName(plan.TuplesTable),
 			Alias:  memberAlias,
 			On:     joinCond,
 		}},
@@ -251,7 +251,7 @@ func buildListSubjectsRecursiveComplexUsersetBlock(plan ListPlan, pattern listUs
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Userset: %s#%s (complex)", pattern.SubjectType, pattern.SubjectRelation)},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation), fmt.Sprintf("-- Userset: %s#%s (complex)", pattern.SubjectType, pattern.SubjectRelation)),
 		Query:    stmt,
 	}
 }
@@ -284,7 +284,7 @@ func composableSubjectFirstUserset(plan ListPlan, pattern listUsersetPatternInpu
 func buildListSubjectsRecursiveComplexUsersetBlockComposed(plan ListPlan, pattern listUsersetPatternInput) TypedQueryBlock {
 	const grantAlias, memberAlias = "g", "m"
 
-	memberExclusions := buildExclusionInput(plan.Analysis, plan.DatabaseSchema, ObjectID, SubjectType, Col{Table: memberAlias, Column: "subject_id"})
+	memberExclusions := buildExclusionInput(plan.Analysis, plan.DatabaseSchema, plan.TuplesTable, ObjectID, SubjectType, Col{Table: memberAlias, Column: "subject_id"})
 
 	whereConditions := []Expr{
 		Eq{Left: Col{Table: grantAlias, Column: "object_type"}, Right: Lit(plan.ObjectType)},
@@ -319,13 +319,13 @@ func buildListSubjectsRecursiveComplexUsersetBlockComposed(plan ListPlan, patter
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: memberAlias, Column: "subject_id"}},
-		FromExpr:    TableAs("", "melange_tuples", grantAlias),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, grantAlias),
 		Joins: []JoinClause{{
 			Type: "CROSS",
 			TableExpr: LateralFunction{
 				Schema: plan.DatabaseSchema,
-				Name:   listSubjectsFunctionName(pattern.SubjectType, pattern.SubjectRelation),
-				Args:   []Expr{UsersetObjectID{Source: Col{Table: grantAlias, Column: "subject_id"}}, SubjectType},
+				Name:   listSubjectsFunctionName(plan.Analysis.NameCollisions, pattern.SubjectType, pattern.SubjectRelation),
+				Args:   listSubjectsCallArgs(UsersetObjectID{Source: Col{Table: grantAlias, Column: "subject_id"}}, SubjectType),
 				Alias:  memberAlias,
 			},
 		}},
@@ -333,7 +333,7 @@ func buildListSubjectsRecursiveComplexUsersetBlockComposed(plan ListPlan, patter
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Userset: %s#%s (complex, compose list_subjects)", pattern.SubjectType, pattern.SubjectRelation)},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation), fmt.Sprintf("-- Userset: %s#%s (complex, compose list_subjects)", pattern.SubjectType, pattern.SubjectRelation)),
 		Query:    stmt,
 	}
 }
@@ -342,7 +342,7 @@ func buildListSubjectsRecursiveComplexUsersetBlockComposed(plan ListPlan, patter
 func buildListSubjectsRecursiveSimpleUsersetBlock(plan ListPlan, pattern listUsersetPatternInput) TypedQueryBlock {
 	const grantAlias, memberAlias = "g", "s"
 
-	memberExclusions := buildExclusionInput(plan.Analysis, plan.DatabaseSchema, ObjectID, Col{Table: memberAlias, Column: "subject_type"}, Col{Table: memberAlias, Column: "subject_id"})
+	memberExclusions := buildExclusionInput(plan.Analysis, plan.DatabaseSchema, plan.TuplesTable, ObjectID, Col{Table: memberAlias, Column: "subject_type"}, Col{Table: memberAlias, Column: "subject_id"})
 
 	joinCond := And(
 		Eq{Left: Col{Table: memberAlias, Column: "object_type"}, Right: Lit(pattern.SubjectType)},
@@ -384,11 +384,11 @@ func buildListSubjectsRecursiveSimpleUsersetBlock(plan ListPlan, pattern listUse
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: memberAlias, Column: "subject_id"}},
-		FromExpr:    TableAs("", "melange_tuples", grantAlias),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, grantAlias),
 		Joins: []JoinClause{{
 			Type:   "INNER",
 			Schema: "",
-			Table:  "melange_tuples",
+			Table:  TuplesTableName(plan.TuplesTable),
 			Alias:  memberAlias,
 			On:     joinCond,
 		}},
@@ -396,7 +396,7 @@ func buildListSubjectsRecursiveSimpleUsersetBlock(plan ListPlan, pattern listUse
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- Userset: %s#%s (simple)", pattern.SubjectType, pattern.SubjectRelation)},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation), fmt.Sprintf("-- Userset: %s#%s (simple)", pattern.SubjectType, pattern.SubjectRelation)),
 		Query:    stmt,
 	}
 }
@@ -581,7 +581,7 @@ func collectParentSatisfyingRelations(plan ListPlan, parent ListParentRelationDa
 // buildListSubjectsRecursiveTTUBlockParentClosure builds a TTU block using parent closure optimization.
 // This scans for direct grants on parent ancestors - only correct for simple parent relations.
 func buildListSubjectsRecursiveTTUBlockParentClosure(plan ListPlan, parent ListParentRelationData) TypedQueryBlock {
-	exclusions := buildExclusionInput(plan.Analysis, plan.DatabaseSchema, ObjectID, SubjectType, Col{Table: "t", Column: "subject_id"})
+	exclusions := buildExclusionInput(plan.Analysis, plan.DatabaseSchema, plan.TuplesTable, ObjectID, SubjectType, Col{Table: "t", Column: "subject_id"})
 
 	// Collect satisfying relations for the parent relation across all parent types.
 	// For implied relations like "can_read: member", we need to look up tuples with
@@ -608,7 +608,7 @@ func buildListSubjectsRecursiveTTUBlockParentClosure(plan ListPlan, parent ListP
 		Joins: []JoinClause{{
 			Type:   "INNER",
 			Schema: "",
-			Table:  "melange_tuples",
+			Table:  TuplesTableName(plan.TuplesTable),
 			Alias:  "t",
 			On: And(
 				Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Col{Table: "p", Column: "subject_type"}},
@@ -619,7 +619,7 @@ func buildListSubjectsRecursiveTTUBlockParentClosure(plan ListPlan, parent ListP
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- TTU: subjects via %s -> %s (parent closure optimization)", parent.LinkingRelation, parent.Relation)},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), fmt.Sprintf("-- TTU: subjects via %s -> %s (parent closure optimization)", parent.LinkingRelation, parent.Relation)),
 		Query:    stmt,
 	}
 }
@@ -664,7 +664,7 @@ func buildListSubjectsRecursiveTTUBlockParentClosureUserset(plan ListPlan, paren
 func buildListSubjectsRecursiveTTUBlockParentClosureUsersetPattern(plan ListPlan, parent ListParentRelationData, pattern listUsersetPatternInput) TypedQueryBlock {
 	const grantAlias, memberAlias = "g", "m"
 
-	memberExclusions := buildExclusionInput(plan.Analysis, plan.DatabaseSchema, ObjectID, Col{Table: memberAlias, Column: "subject_type"}, Col{Table: memberAlias, Column: "subject_id"})
+	memberExclusions := buildExclusionInput(plan.Analysis, plan.DatabaseSchema, plan.TuplesTable, ObjectID, Col{Table: memberAlias, Column: "subject_type"}, Col{Table: memberAlias, Column: "subject_id"})
 
 	whereConditions := []Expr{
 		In{Expr: Col{Table: grantAlias, Column: "relation"}, Values: pattern.SourceRelations},
@@ -689,7 +689,7 @@ func buildListSubjectsRecursiveTTUBlockParentClosureUsersetPattern(plan ListPlan
 			{
 				Type:   "INNER",
 				Schema: "",
-				Table:  "melange_tuples",
+				Table:  TuplesTableName(plan.TuplesTable),
 				Alias:  grantAlias,
 				On: And(
 					Eq{Left: Col{Table: grantAlias, Column: "object_type"}, Right: Col{Table: "p", Column: "subject_type"}},
@@ -699,7 +699,7 @@ func buildListSubjectsRecursiveTTUBlockParentClosureUsersetPattern(plan ListPlan
 			{
 				Type:   "INNER",
 				Schema: "",
-				Table:  "melange_tuples",
+				Table:  TuplesTableName(plan.TuplesTable),
 				Alias:  memberAlias,
 				On: And(
 					Eq{Left: Col{Table: memberAlias, Column: "object_type"}, Right: Lit(pattern.SubjectType)},
@@ -712,7 +712,7 @@ func buildListSubjectsRecursiveTTUBlockParentClosureUsersetPattern(plan ListPlan
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- TTU userset: subjects via %s -> %s -> %s#%s (parent closure)", parent.LinkingRelation, parent.Relation, pattern.SubjectType, pattern.SubjectRelation)},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), fmt.Sprintf("-- TTU userset: subjects via %s -> %s -> %s#%s (parent closure)", parent.LinkingRelation, parent.Relation, pattern.SubjectType, pattern.SubjectRelation)),
 		Query:    stmt,
 	}
 }
@@ -784,14 +784,14 @@ func buildSubjectFirstTTUClosureSubjectBlocks(plan ListPlan, parent ListParentRe
 		ColumnExprs: []Expr{Col{Table: "sub", Column: "subject_id"}},
 		FromExpr: FunctionCallExpr{
 			Schema: plan.DatabaseSchema,
-			Name:   listSubjectsFunctionName(plan.ObjectType, parent.SourceRelation),
-			Args:   []Expr{ObjectID, SubjectType},
+			Name:   listSubjectsFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, parent.SourceRelation),
+			Args:   listSubjectsCallArgs(ObjectID, SubjectType),
 			Alias:  "sub",
 		},
 	}
 
 	return []TypedQueryBlock{{
-		Comments: []string{fmt.Sprintf("-- TTU subject-first: subjects via %s -> %s (closure pattern from %s - compose list_subjects)", parent.LinkingRelation, parent.Relation, parent.SourceRelation)},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), fmt.Sprintf("-- TTU subject-first: subjects via %s -> %s (closure pattern from %s - compose list_subjects)", parent.LinkingRelation, parent.Relation, parent.SourceRelation)),
 		Query:    stmt,
 	}}
 }
@@ -804,13 +804,13 @@ func buildSubjectFirstTTUSubjectBlock(plan ListPlan, parent ListParentRelationDa
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{Col{Table: "sub", Column: "subject_id"}},
-		FromExpr:    TableAs("", "melange_tuples", "link"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "link"),
 		Joins: []JoinClause{{
 			Type: "CROSS",
 			TableExpr: LateralFunction{
 				Schema: plan.DatabaseSchema,
-				Name:   listSubjectsFunctionName(parentType, parent.Relation),
-				Args:   []Expr{Col{Table: "link", Column: "subject_id"}, SubjectType},
+				Name:   listSubjectsFunctionName(plan.Analysis.NameCollisions, parentType, parent.Relation),
+				Args:   listSubjectsCallArgs(Col{Table: "link", Column: "subject_id"}, SubjectType),
 				Alias:  "sub",
 			},
 		}},
@@ -823,7 +823,7 @@ func buildSubjectFirstTTUSubjectBlock(plan ListPlan, parent ListParentRelationDa
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- TTU subject-first: subjects via %s -> %s.%s (compose list_subjects)", parent.LinkingRelation, parentType, parent.Relation)},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), fmt.Sprintf("-- TTU subject-first: subjects via %s -> %s.%s (compose list_subjects)", parent.LinkingRelation, parentType, parent.Relation)),
 		Query:    stmt,
 	}
 }
@@ -885,14 +885,14 @@ func buildListSubjectsRecursiveTTUBlockSubjectPool(plan ListPlan, parent ListPar
 		Joins: []JoinClause{{
 			Type:   "CROSS",
 			Schema: "",
-			Table:  "melange_tuples",
+			Table:  TuplesTableName(plan.TuplesTable),
 			Alias:  "link",
 		}},
 		Where: And(append(linkWhere, Raw(checkCallSQL))...),
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{comment},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), comment),
 		Query:    stmt,
 	}
 }
@@ -901,6 +901,7 @@ func buildListSubjectsRecursiveTTUBlockSubjectPool(plan ListPlan, parent ListPar
 func buildSubjectsRecursiveUsersetFilterTypedBlocks(plan ListPlan, parentRelations []ListParentRelationData) ([]TypedQueryBlock, error) {
 	blocks := make([]TypedQueryBlock, 0, 8)
 	blocks = append(blocks, buildListSubjectsRecursiveUsersetFilterDirectBlock(plan))
+	blocks = append(blocks, buildListSubjectsUsersetFilterWildcardBlocks(plan)...)
 
 	for _, parent := range parentRelations {
 		blocks = append(blocks,
@@ -912,19 +913,19 @@ func buildSubjectsRecursiveUsersetFilterTypedBlocks(plan ListPlan, parentRelatio
 
 	filterUsersetExpr := Concat{Parts: []Expr{Param("v_filter_type"), Lit("#"), Param("v_filter_relation")}}
 	for _, rel := range plan.Analysis.IntersectionClosureRelations {
-		funcName := listSubjectsFunctionName(plan.ObjectType, rel)
+		funcName := listSubjectsFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, rel)
 		stmt := SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{Col{Table: "ics", Column: "subject_id"}},
 			FromExpr: FunctionCallExpr{
 				Schema: plan.DatabaseSchema,
 				Name:   funcName,
-				Args:   []Expr{ObjectID, filterUsersetExpr},
+				Args:   listSubjectsCallArgs(ObjectID, filterUsersetExpr),
 				Alias:  "ics",
 			},
 		}
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{fmt.Sprintf("-- Intersection closure: %s", rel)},
+			Comments: plan.traceComments(TraceNodeIntersection, rel, fmt.Sprintf("-- Intersection closure: %s", rel)),
 			Query:    stmt,
 		})
 	}
@@ -942,15 +943,12 @@ func buildListSubjectsRecursiveUsersetFilterDirectBlock(plan ListPlan) TypedQuer
 		ExpectAllow: true,
 	}
 
-	closureStmt := SelectStmt{
-		ColumnExprs: []Expr{Int(1)},
-		FromExpr:    closureCTERef("c"),
-		Where: And(
-			Eq{Left: Col{Table: "c", Column: "object_type"}, Right: Param("v_filter_type")},
-			Eq{Left: Col{Table: "c", Column: "relation"}, Right: UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}}},
-			Eq{Left: Col{Table: "c", Column: "satisfying_relation"}, Right: Param("v_filter_relation")},
-		),
-	}
+	closureStmt := closureContains(plan, "c",
+		Param("v_filter_type"),
+		UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
+		Param("v_filter_relation"),
+		true,
+	)
 
 	subjectExpr := Alias{
 		Expr: Concat{Parts: []Expr{
@@ -964,7 +962,7 @@ func buildListSubjectsRecursiveUsersetFilterDirectBlock(plan ListPlan) TypedQuer
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{subjectExpr},
-		FromExpr:    TableAs("", "melange_tuples", "t"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "t"),
 		Where: And(
 			Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
 			Eq{Left: Col{Table: "t", Column: "object_id"}, Right: ObjectID},
@@ -977,38 +975,33 @@ func buildListSubjectsRecursiveUsersetFilterDirectBlock(plan ListPlan) TypedQuer
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Direct userset tuples"},
+		Comments: plan.traceComments(TraceNodeUserset, "", "-- Direct userset tuples"),
 		Query:    stmt,
 	}
 }
 
 // buildListSubjectsRecursiveUsersetFilterTTUBlock builds the TTU path block for userset filter.
 func buildListSubjectsRecursiveUsersetFilterTTUBlock(plan ListPlan, parent ListParentRelationData) TypedQueryBlock {
+	closureFrom, closureWhere := closureLookup(plan, "c", Col{Table: "link", Column: "subject_type"}, Lit(parent.Relation), true)
 	closureRelStmt := SelectStmt{
 		Columns:  []string{"c.satisfying_relation"},
-		FromExpr: closureCTERef("c"),
-		Where: And(
-			Eq{Left: Col{Table: "c", Column: "object_type"}, Right: Col{Table: "link", Column: "subject_type"}},
-			Eq{Left: Col{Table: "c", Column: "relation"}, Right: Lit(parent.Relation)},
-		),
+		FromExpr: closureFrom,
+		Where:    And(closureWhere...),
 	}
 
-	closureExistsStmt := SelectStmt{
-		Columns:  []string{"1"},
-		FromExpr: closureCTERef("subj_c"),
-		Where: And(
-			Eq{Left: Col{Table: "subj_c", Column: "object_type"}, Right: Param("v_filter_type")},
-			Eq{Left: Col{Table: "subj_c", Column: "relation"}, Right: UsersetRelation{Source: Col{Table: "pt", Column: "subject_id"}}},
-			Eq{Left: Col{Table: "subj_c", Column: "satisfying_relation"}, Right: Param("v_filter_relation")},
-		),
-	}
+	closureExistsStmt := closureContains(plan, "subj_c",
+		Param("v_filter_type"),
+		UsersetRelation{Source: Col{Table: "pt", Column: "subject_id"}},
+		Param("v_filter_relation"),
+		true,
+	)
 
 	whereConditions := []Expr{
 		Eq{Left: Col{Table: "link", Column: "object_type"}, Right: Lit(plan.ObjectType)},
 		Eq{Left: Col{Table: "link", Column: "object_id"}, Right: ObjectID},
 		Eq{Left: Col{Table: "link", Column: "relation"}, Right: Lit(parent.LinkingRelation)},
 		Eq{Left: Col{Table: "pt", Column: "subject_type"}, Right: Param("v_filter_type")},
-		Gt{Left: Raw("position('#' in pt.subject_id)"), Right: Int(0)},
+		HasUserset{Source: Col{Table: "pt", Column: "subject_id"}},
 		Or(
 			Eq{Left: UsersetRelation{Source: Col{Table: "pt", Column: "subject_id"}}, Right: Param("v_filter_relation")},
 			Exists{Query: closureExistsStmt},
@@ -1024,11 +1017,11 @@ func buildListSubjectsRecursiveUsersetFilterTTUBlock(plan ListPlan, parent ListP
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{subjectExpr},
-		FromExpr:    TableAs("", "melange_tuples", "link"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "link"),
 		Joins: []JoinClause{{
 			Type:   "INNER",
 			Schema: "",
-			Table:  "melange_tuples",
+			Table:  TuplesTableName(plan.TuplesTable),
 			Alias:  "pt",
 			On: And(
 				Eq{Left: Col{Table: "pt", Column: "object_type"}, Right: Col{Table: "link", Column: "subject_type"}},
@@ -1040,22 +1033,19 @@ func buildListSubjectsRecursiveUsersetFilterTTUBlock(plan ListPlan, parent ListP
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{fmt.Sprintf("-- TTU userset: %s -> %s", parent.LinkingRelation, parent.Relation)},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), fmt.Sprintf("-- TTU userset: %s -> %s", parent.LinkingRelation, parent.Relation)),
 		Query:    stmt,
 	}
 }
 
 // buildListSubjectsRecursiveUsersetFilterTTUIntermediateBlock builds the intermediate TTU block.
 func buildListSubjectsRecursiveUsersetFilterTTUIntermediateBlock(plan ListPlan, parent ListParentRelationData) TypedQueryBlock {
-	closureExistsStmt := SelectStmt{
-		Columns:  []string{"1"},
-		FromExpr: closureCTERef("c"),
-		Where: And(
-			Eq{Left: Col{Table: "c", Column: "object_type"}, Right: Col{Table: "link", Column: "subject_type"}},
-			Eq{Left: Col{Table: "c", Column: "relation"}, Right: Lit(parent.Relation)},
-			Eq{Left: Col{Table: "c", Column: "satisfying_relation"}, Right: Param("v_filter_relation")},
-		),
-	}
+	closureExistsStmt := closureContains(plan, "c",
+		Col{Table: "link", Column: "subject_type"},
+		Lit(parent.Relation),
+		Param("v_filter_relation"),
+		true,
+	)
 
 	whereConditions := []Expr{
 		Eq{Left: Col{Table: "link", Column: "object_type"}, Right: Lit(plan.ObjectType)},
@@ -1074,12 +1064,12 @@ func buildListSubjectsRecursiveUsersetFilterTTUIntermediateBlock(plan ListPlan,
 	stmt := SelectStmt{
 		Distinct:    true,
 		ColumnExprs: []Expr{subjectExpr},
-		FromExpr:    TableAs("", "melange_tuples", "link"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "link"),
 		Where:       And(whereConditions...),
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{"-- TTU intermediate: parent object as userset reference"},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), "-- TTU intermediate: parent object as userset reference"),
 		Query:    stmt,
 	}
 }
@@ -1089,12 +1079,12 @@ func buildListSubjectsRecursiveUsersetFilterTTUNestedBlock(plan ListPlan, parent
 	lateralCall := LateralFunction{
 		Schema: plan.DatabaseSchema,
 		Name:   "list_accessible_subjects",
-		Args: []Expr{
+		Args: listAccessibleSubjectsCallArgs(
 			Col{Table: "link", Column: "subject_type"},
 			Col{Table: "link", Column: "subject_id"},
-			Lit(parent.Relation),
+			parent.Relation,
 			SubjectType,
-		},
+		),
 		Alias: "nested",
 	}
 
@@ -1110,7 +1100,7 @@ func buildListSubjectsRecursiveUsersetFilterTTUNestedBlock(plan ListPlan, parent
 
 	stmt := SelectStmt{
 		ColumnExprs: []Expr{Col{Table: "nested", Column: "subject_id"}},
-		FromExpr:    TableAs("", "melange_tuples", "link"),
+		FromExpr:    TuplesTableAs(plan.TuplesTable, "link"),
 		Joins: []JoinClause{{
 			Type:      "CROSS",
 			TableExpr: lateralCall,
@@ -1119,7 +1109,7 @@ func buildListSubjectsRecursiveUsersetFilterTTUNestedBlock(plan ListPlan, parent
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{"-- TTU nested: multi-hop chain resolution"},
+		Comments: plan.traceComments(TraceNodeTTU, ttuTracePattern(parent.LinkingRelation, parent.Relation), "-- TTU nested: multi-hop chain resolution"),
 		Query:    stmt,
 	}
 }
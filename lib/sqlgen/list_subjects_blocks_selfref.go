@@ -50,25 +50,22 @@ func buildSelfRefUsersetFilterBlocks(plan ListPlan) (blocks []TypedQueryBlock, s
 }
 
 func buildSelfRefUsersetFilterBaseBlock(plan ListPlan) TypedQueryBlock {
-	closureExistsStmt := SelectStmt{
-		ColumnExprs: []Expr{Int(1)},
-		FromExpr:    ClosureTable(plan.Inline.ClosureRows, "subj_c"),
-		Where: And(
-			Eq{Left: Col{Table: "subj_c", Column: "object_type"}, Right: Param("v_filter_type")},
-			Eq{Left: Col{Table: "subj_c", Column: "relation"}, Right: UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}}},
-			Eq{Left: Col{Table: "subj_c", Column: "satisfying_relation"}, Right: Param("v_filter_relation")},
-		),
-	}
+	closureExistsStmt := closureContains(plan, "subj_c",
+		Param("v_filter_type"),
+		UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
+		Param("v_filter_relation"),
+		false,
+	)
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Userset filter: find userset tuples that match filter type/relation"},
+		Comments: plan.traceComments(TraceNodeUserset, "", "-- Userset filter: find userset tuples that match filter type/relation"),
 		Query: SelectStmt{
 			Distinct: true,
 			ColumnExprs: []Expr{
-				Raw("split_part(t.subject_id, '#', 1) AS userset_object_id"),
+				Alias{Expr: UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}}, Name: "userset_object_id"},
 				Raw("0 AS depth"),
 			},
-			FromExpr: TableAs("", "melange_tuples", "t"),
+			FromExpr: TuplesTableAs(plan.TuplesTable, "t"),
 			Where: And(
 				Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
 				In{Expr: Col{Table: "t", Column: "relation"}, Values: plan.AllSatisfyingRelations},
@@ -103,17 +100,17 @@ func buildSelfRefUsersetFilterIntersectionBlocks(plan ListPlan) []TypedQueryBloc
 
 	for _, rel := range intersectionRels {
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{fmt.Sprintf("-- Compose with intersection closure relation: %s", rel)},
+			Comments: plan.traceComments(TraceNodeIntersection, rel, fmt.Sprintf("-- Compose with intersection closure relation: %s", rel)),
 			Query: SelectStmt{
 				Distinct: true,
 				ColumnExprs: []Expr{
-					Raw("split_part(icr.subject_id, '#', 1) AS userset_object_id"),
+					Alias{Expr: UsersetObjectID{Source: Col{Table: "icr", Column: "subject_id"}}, Name: "userset_object_id"},
 					Raw("0 AS depth"),
 				},
 				FromExpr: FunctionCallExpr{
 					Schema: plan.DatabaseSchema,
-					Name:   listSubjectsFunctionName(plan.ObjectType, rel),
-					Args:   []Expr{ObjectID, filterUsersetExpr},
+					Name:   listSubjectsFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, rel),
+					Args:   listSubjectsCallArgs(ObjectID, filterUsersetExpr),
 					Alias:  "icr",
 				},
 			},
@@ -124,15 +121,7 @@ func buildSelfRefUsersetFilterIntersectionBlocks(plan ListPlan) []TypedQueryBloc
 }
 
 func buildSelfRefUsersetFilterSelfBlock(plan ListPlan) *TypedQueryBlock {
-	closureStmt := SelectStmt{
-		ColumnExprs: []Expr{Int(1)},
-		FromExpr:    ClosureTable(plan.Inline.ClosureRows, "c"),
-		Where: And(
-			Eq{Left: Col{Table: "c", Column: "object_type"}, Right: Lit(plan.ObjectType)},
-			Eq{Left: Col{Table: "c", Column: "relation"}, Right: Lit(plan.Relation)},
-			Eq{Left: Col{Table: "c", Column: "satisfying_relation"}, Right: Param("v_filter_relation")},
-		),
-	}
+	closureStmt := closureContains(plan, "c", Lit(plan.ObjectType), Lit(plan.Relation), Param("v_filter_relation"), false)
 
 	subjectExpr := Alias{
 		Expr: Concat{Parts: []Expr{
@@ -144,7 +133,7 @@ func buildSelfRefUsersetFilterSelfBlock(plan ListPlan) *TypedQueryBlock {
 	}
 
 	return &TypedQueryBlock{
-		Comments: []string{"-- Self-candidate: when filter type matches object type"},
+		Comments: plan.traceComments(TraceNodeUserset, "", "-- Self-candidate: when filter type matches object type"),
 		Query: SelectStmt{
 			ColumnExprs: []Expr{subjectExpr},
 			Where: And(
@@ -157,17 +146,17 @@ func buildSelfRefUsersetFilterSelfBlock(plan ListPlan) *TypedQueryBlock {
 
 func buildSelfRefUsersetFilterRecursiveBlock(plan ListPlan) *TypedQueryBlock {
 	return &TypedQueryBlock{
-		Comments: []string{"-- Recursive userset expansion for filter path"},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(plan.ObjectType, plan.Relation), "-- Recursive userset expansion for filter path"),
 		Query: SelectStmt{
 			ColumnExprs: []Expr{
-				Raw("split_part(t.subject_id, '#', 1) AS userset_object_id"),
+				Alias{Expr: UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}}, Name: "userset_object_id"},
 				Raw("ue.depth + 1 AS depth"),
 			},
 			FromExpr: TableAs("", "userset_expansion", "ue"),
 			Joins: []JoinClause{{
 				Type:   "INNER",
 				Schema: "",
-				Table:  "melange_tuples",
+				Table:  TuplesTableName(plan.TuplesTable),
 				Alias:  "t",
 				On: And(
 					Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Raw("v_filter_type")},
@@ -177,7 +166,7 @@ func buildSelfRefUsersetFilterRecursiveBlock(plan ListPlan) *TypedQueryBlock {
 					Like{Expr: Col{Table: "t", Column: "subject_id"}, Pattern: Raw("'%#' || v_filter_relation")},
 				),
 			}},
-			Where: Raw("ue.depth < 25"),
+			Where: Lt{Left: Col{Table: "ue", Column: "depth"}, Right: Int(plan.maxDepth())},
 		},
 	}
 }
@@ -187,6 +176,7 @@ func buildSelfRefUsersetRegularBlocks(plan ListPlan) (blocks []TypedQueryBlock,
 	exclusions := buildExclusionInput(
 		plan.Analysis,
 		plan.DatabaseSchema,
+		plan.TuplesTable,
 		ObjectID,
 		SubjectType,
 		Col{Table: "t", Column: "subject_id"},
@@ -203,7 +193,7 @@ func buildSelfRefUsersetRegularBlocks(plan ListPlan) (blocks []TypedQueryBlock,
 }
 
 func buildSelfRefUsersetRegularDirectBlock(plan ListPlan, exclusions ExclusionConfig) TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(plan.RelationList...).
 		WhereObjectID(ObjectID).
@@ -220,7 +210,7 @@ func buildSelfRefUsersetRegularDirectBlock(plan ListPlan, exclusions ExclusionCo
 	applyExclusionPredicates(q, exclusions, false)
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Path 1: Direct tuple lookup on the object itself"},
+		Comments: plan.traceComments(TraceNodeDirect, "", "-- Path 1: Direct tuple lookup on the object itself"),
 		Query:    q.Build(),
 	}
 }
@@ -232,7 +222,7 @@ func buildSelfRefUsersetRegularComplexClosureBlocks(plan ListPlan, exclusions Ex
 
 	blocks := make([]TypedQueryBlock, 0, len(plan.ComplexClosure))
 	for _, rel := range plan.ComplexClosure {
-		q := Tuples(plan.DatabaseSchema, "t").
+		q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 			ObjectType(plan.ObjectType).
 			Relations(rel).
 			WhereObjectID(ObjectID).
@@ -255,7 +245,7 @@ func buildSelfRefUsersetRegularComplexClosureBlocks(plan ListPlan, exclusions Ex
 		applyExclusionPredicates(q, exclusions, false)
 
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{fmt.Sprintf("-- Complex closure relation: %s", rel)},
+			Comments: plan.traceComments(TraceNodeImplied, rel, fmt.Sprintf("-- Complex closure relation: %s", rel)),
 			Query:    q.Build(),
 		})
 	}
@@ -272,13 +262,13 @@ func buildSelfRefUsersetRegularIntersectionClosureBlocks(plan ListPlan) []TypedQ
 	blocks := make([]TypedQueryBlock, 0, len(intersectionRels))
 	for _, rel := range intersectionRels {
 		blocks = append(blocks, TypedQueryBlock{
-			Comments: []string{fmt.Sprintf("-- Compose with intersection closure relation: %s", rel)},
+			Comments: plan.traceComments(TraceNodeIntersection, rel, fmt.Sprintf("-- Compose with intersection closure relation: %s", rel)),
 			Query: SelectStmt{
 				ColumnExprs: []Expr{Col{Table: "icr", Column: "subject_id"}},
 				FromExpr: FunctionCallExpr{
 					Schema: plan.DatabaseSchema,
-					Name:   listSubjectsFunctionName(plan.ObjectType, rel),
-					Args:   []Expr{ObjectID, SubjectType},
+					Name:   listSubjectsFunctionName(plan.Analysis.NameCollisions, plan.ObjectType, rel),
+					Args:   listSubjectsCallArgs(ObjectID, SubjectType),
 					Alias:  "icr",
 				},
 			},
@@ -312,6 +302,7 @@ func buildSelfRefUsersetRegularExpansionBlock(plan ListPlan) TypedQueryBlock {
 	perGroupExclusions := buildExclusionInput(
 		plan.Analysis,
 		plan.DatabaseSchema,
+		plan.TuplesTable,
 		Col{Table: "uo", Column: "userset_object_id"},
 		SubjectType,
 		Col{Table: "t", Column: "subject_id"},
@@ -320,6 +311,7 @@ func buildSelfRefUsersetRegularExpansionBlock(plan ListPlan) TypedQueryBlock {
 	rootExclusions := buildExclusionInput(
 		plan.Analysis,
 		plan.DatabaseSchema,
+		plan.TuplesTable,
 		ObjectID,
 		SubjectType,
 		Col{Table: "t", Column: "subject_id"},
@@ -327,7 +319,7 @@ func buildSelfRefUsersetRegularExpansionBlock(plan ListPlan) TypedQueryBlock {
 	conditions = append(conditions, rootExclusions.BuildPredicates()...)
 
 	return TypedQueryBlock{
-		Comments: []string{"-- Path 2: Expand userset subjects from all reachable userset objects"},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(plan.ObjectType, plan.Relation), "-- Path 2: Expand userset subjects from all reachable userset objects"),
 		Query: SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{Col{Table: "t", Column: "subject_id"}},
@@ -335,7 +327,7 @@ func buildSelfRefUsersetRegularExpansionBlock(plan ListPlan) TypedQueryBlock {
 			Joins: []JoinClause{{
 				Type:   "INNER",
 				Schema: "",
-				Table:  "melange_tuples",
+				Table:  TuplesTableName(plan.TuplesTable),
 				Alias:  "t",
 				On:     Eq{Left: Col{Table: "t", Column: "object_id"}, Right: Col{Table: "uo", Column: "userset_object_id"}},
 			}},
@@ -378,6 +370,7 @@ func buildSelfRefUsersetRegularComplexPatternBlock(plan ListPlan, pattern listUs
 	subjectExclusions := buildExclusionInput(
 		plan.Analysis,
 		plan.DatabaseSchema,
+		plan.TuplesTable,
 		ObjectID,
 		SubjectType,
 		Col{Table: "s", Column: "subject_id"},
@@ -389,26 +382,21 @@ func buildSelfRefUsersetRegularComplexPatternBlock(plan ListPlan, pattern listUs
 	}
 
 	return TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation),
 			fmt.Sprintf("-- Non-self userset expansion: %s#%s", pattern.SubjectType, pattern.SubjectRelation),
 			"-- Complex userset: use LATERAL list function",
-		},
+		),
 		Query: SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{Col{Table: "s", Column: "subject_id"}},
-			FromExpr:    TableAs("", "melange_tuples", "g"),
+			FromExpr:    TuplesTableAs(plan.TuplesTable, "g"),
 			Joins: []JoinClause{{
 				Type: "CROSS",
 				TableExpr: LateralFunction{
 					Schema: plan.DatabaseSchema,
-					Name:   listSubjectsFunctionName(pattern.SubjectType, pattern.SubjectRelation),
-					Args: []Expr{
-						Func{Name: "split_part", Args: []Expr{Col{Table: "g", Column: "subject_id"}, Lit("#"), Int(1)}},
-						SubjectType,
-						Null{},
-						Null{},
-					},
-					Alias: "s",
+					Name:   listSubjectsFunctionName(plan.Analysis.NameCollisions, pattern.SubjectType, pattern.SubjectRelation),
+					Args:   listSubjectsCallArgs(UsersetObjectID{Source: Col{Table: "g", Column: "subject_id"}}, SubjectType),
+					Alias:  "s",
 				},
 			}},
 			Where: whereClause,
@@ -420,6 +408,7 @@ func buildSelfRefUsersetRegularSimplePatternBlock(plan ListPlan, pattern listUse
 	subjectExclusions := buildExclusionInput(
 		plan.Analysis,
 		plan.DatabaseSchema,
+		plan.TuplesTable,
 		ObjectID,
 		SubjectType,
 		Col{Table: "s", Column: "subject_id"},
@@ -460,18 +449,18 @@ func buildSelfRefUsersetRegularSimplePatternBlock(plan ListPlan, pattern listUse
 	grantConditions = append(grantConditions, subjectExclusions.BuildPredicates()...)
 
 	return TypedQueryBlock{
-		Comments: []string{
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(pattern.SubjectType, pattern.SubjectRelation),
 			fmt.Sprintf("-- Non-self userset expansion: %s#%s", pattern.SubjectType, pattern.SubjectRelation),
 			"-- Simple userset: JOIN with membership tuples",
-		},
+		),
 		Query: SelectStmt{
 			Distinct:    true,
 			ColumnExprs: []Expr{Col{Table: "s", Column: "subject_id"}},
-			FromExpr:    TableAs("", "melange_tuples", "g"),
+			FromExpr:    TuplesTableAs(plan.TuplesTable, "g"),
 			Joins: []JoinClause{{
 				Type:   "INNER",
 				Schema: "",
-				Table:  "melange_tuples",
+				Table:  TuplesTableName(plan.TuplesTable),
 				Alias:  "s",
 				On:     And(membershipConditions...),
 			}},
@@ -481,33 +470,36 @@ func buildSelfRefUsersetRegularSimplePatternBlock(plan ListPlan, pattern listUse
 }
 
 func buildSelfRefUsersetObjectsBaseBlock(plan ListPlan) *TypedQueryBlock {
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		ObjectType(plan.ObjectType).
 		Relations(plan.RelationList...).
-		Select("split_part(t.subject_id, '#', 1) AS userset_object_id", "0 AS depth").
+		SelectExpr(
+			Alias{Expr: UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}}, Name: "userset_object_id"},
+			Raw("0 AS depth"),
+		).
 		WhereObjectID(ObjectID).
 		Where(Eq{Left: Col{Table: "t", Column: "subject_type"}, Right: Lit(plan.ObjectType)}).
 		WhereUsersetRelationLike(plan.Relation)
 
 	return &TypedQueryBlock{
-		Comments: []string{"-- Base case: find initial userset references"},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(plan.ObjectType, plan.Relation), "-- Base case: find initial userset references"),
 		Query:    q.Build(),
 	}
 }
 
 func buildSelfRefUsersetObjectsRecursiveBlock(plan ListPlan) *TypedQueryBlock {
 	return &TypedQueryBlock{
-		Comments: []string{"-- Recursive case: expand self-referential userset references"},
+		Comments: plan.traceComments(TraceNodeUserset, usersetTracePattern(plan.ObjectType, plan.Relation), "-- Recursive case: expand self-referential userset references"),
 		Query: SelectStmt{
 			ColumnExprs: []Expr{
-				Raw("split_part(t.subject_id, '#', 1) AS userset_object_id"),
+				Alias{Expr: UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}}, Name: "userset_object_id"},
 				Raw("uo.depth + 1 AS depth"),
 			},
 			FromExpr: TableAs("", "userset_objects", "uo"),
 			Joins: []JoinClause{{
 				Type:   "INNER",
 				Schema: "",
-				Table:  "melange_tuples",
+				Table:  TuplesTableName(plan.TuplesTable),
 				Alias:  "t",
 				On: And(
 					Eq{Left: Col{Table: "t", Column: "object_type"}, Right: Lit(plan.ObjectType)},
@@ -517,7 +509,7 @@ func buildSelfRefUsersetObjectsRecursiveBlock(plan ListPlan) *TypedQueryBlock {
 					Like{Expr: Col{Table: "t", Column: "subject_id"}, Pattern: Lit("%#" + plan.Relation)},
 				),
 			}},
-			Where: Raw("uo.depth < 25"),
+			Where: Lt{Left: Col{Table: "uo", Column: "depth"}, Right: Int(plan.maxDepth())},
 		},
 	}
 }
@@ -60,7 +60,7 @@ func TestClosureTTUSubjects_ComposeWhenComposable(t *testing.T) {
 
 	sql := closureBlocksSQL(plan, parent)
 
-	if !strings.Contains(sql, "list_doc_reader_sub(p_object_id, p_subject_type)") {
+	if !strings.Contains(sql, "list_doc_reader_sub(p_object_id => p_object_id, p_subject_type => p_subject_type)") {
 		t.Errorf("expected composed SELECT from list_doc_reader_sub, got:\n%s", sql)
 	}
 	if strings.Contains(sql, "check_permission_internal") {
@@ -44,7 +44,7 @@ func TestComplexUsersetSubjects_ComposesWhenSafe(t *testing.T) {
 
 	sql := buildListSubjectsRecursiveComplexUsersetBlock(plan, pattern).Query.SQL()
 
-	if !strings.Contains(sql, "CROSS JOIN LATERAL list_group_member_sub(split_part(g.subject_id, '#', 1), p_subject_type)") {
+	if !strings.Contains(sql, "CROSS JOIN LATERAL list_group_member_sub(p_object_id => left(g.subject_id, length(g.subject_id) - strpos(reverse(g.subject_id), '#')), p_subject_type => p_subject_type)") {
 		t.Errorf("expected lateral compose against list_group_member_sub, got:\n%s", sql)
 	}
 	if strings.Contains(sql, "check_permission_internal") {
@@ -0,0 +1,141 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+const expandWildcardSchema = `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define blocked: [user]
+    define owner: [user]
+    define viewer: [user:*] but not blocked
+`
+
+func TestListSubjectsExpandWildcard(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, expandWildcardSchema)
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+
+	fns := map[string]string{}
+	for _, fn := range list.ListSubjectsFunctions {
+		for _, rel := range []string{"viewer", "owner"} {
+			if strings.Contains(fn, "FUNCTION list_document_"+rel+"_sub(") {
+				fns[rel] = fn
+			}
+		}
+	}
+
+	viewer := fns["viewer"]
+	assertContains(t, viewer, "p_expand_wildcard BOOLEAN DEFAULT FALSE")
+	// The direct lookup drops '*' only when expanding, and the expansion
+	// block replaces it with the known subjects that pass the exclusion.
+	assertContains(t, viewer, "(NOT (p_expand_wildcard) OR t.subject_id <> '*')")
+	assertContains(t, viewer, "WHERE (p_expand_wildcard AND u.subject_type = p_subject_type")
+	assertContains(t, viewer, "w.subject_id = '*'")
+	assertContains(t, viewer, "excl.subject_id = u.subject_id")
+
+	// owner cannot hold a wildcard: the parameter is accepted and unused.
+	owner := fns["owner"]
+	assertContains(t, owner, "p_expand_wildcard BOOLEAN DEFAULT FALSE")
+	assertNotContains(t, owner, "FROM melange_tuples AS u")
+
+	// Both the functions and the dispatcher drop the signature they had
+	// before p_expand_wildcard, so no ambiguous overload is left behind.
+	if !strings.HasPrefix(viewer, "DROP FUNCTION IF EXISTS list_document_viewer_sub(TEXT, TEXT, INT, TEXT);\n") {
+		t.Errorf("list_document_viewer_sub does not drop its old signature first:\n%s", viewer[:200])
+	}
+	assertContains(t, list.ListSubjectsDispatcher, "DROP FUNCTION IF EXISTS list_accessible_subjects(TEXT, TEXT, TEXT, TEXT, INT, TEXT);")
+	assertContains(t, list.ListSubjectsDispatcher, "p_expand_wildcard => p_expand_wildcard")
+}
+
+const usersetFilterWildcardSchema = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, user:*]
+
+type folder
+  relations
+    define parent: [folder]
+    define viewer: [group#member, group:*] or viewer from parent
+
+type document
+  relations
+    define viewer: [group#member, group:*]
+`
+
+func TestListSubjectsUsersetFilterWildcard(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, usersetFilterWildcardSchema)
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+
+	fns := map[string]string{}
+	for _, fn := range list.ListSubjectsFunctions {
+		for _, name := range []string{"list_document_viewer_sub", "list_folder_viewer_sub"} {
+			if strings.Contains(fn, "FUNCTION "+name+"(") {
+				fns[name] = fn
+			}
+		}
+	}
+
+	// Both the plain and the recursive userset filter paths answer a
+	// group:* grant: '*' by default, the known group usersets when expanding.
+	for _, name := range []string{"list_document_viewer_sub", "list_folder_viewer_sub"} {
+		sql := fns[name]
+		filter := sql[:strings.Index(sql, "ELSE")]
+		assertContains(t, filter, "SELECT '*' AS subject_id")
+		assertContains(t, filter, "WHERE (NOT (p_expand_wildcard) AND EXISTS (")
+		assertContains(t, filter, "w.subject_type = v_filter_type AND w.subject_id = '*'")
+		// The filter relation must be a relation of the filter type.
+		assertContains(t, filter, "subj_c.object_type = v_filter_type AND subj_c.relation = v_filter_relation AND subj_c.satisfying_relation = v_filter_relation")
+		assertContains(t, filter, "check_permission_internal(v_filter_type, '*', 'viewer'")
+		assertContains(t, filter, "SELECT u.object_id || '#' || v_filter_relation AS subject_id")
+		assertContains(t, filter, "WHERE (p_expand_wildcard AND u.object_type = v_filter_type AND EXISTS (")
+		assertContains(t, filter, "subj_c.satisfying_relation = u.relation")
+		assertContains(t, filter, "check_permission_internal(v_filter_type, u.object_id || '#' || v_filter_relation, 'viewer'")
+	}
+}
+
+func TestListSubjectsExpandWildcardSubjectsView(t *testing.T) {
+	opts := GenerateSQLOptions{ExpandWildcardSubjects: true}
+
+	analyses, inline := compileForCacheTest(t, expandWildcardSchema)
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", opts)
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+	viewer := functionNamed(t, list.ListSubjectsFunctions, listSubjectsFunctionName(nil, "document", "viewer"))
+	// The expansion reads the view, and still applies the exclusion.
+	assertContains(t, viewer, "FROM melange_subjects AS u")
+	assertNotContains(t, viewer, "FROM melange_tuples AS u")
+	assertContains(t, viewer, "WHERE (p_expand_wildcard AND u.subject_type = p_subject_type")
+	assertContains(t, viewer, "excl.subject_id = u.subject_id")
+
+	analyses, inline = compileForCacheTest(t, usersetFilterWildcardSchema)
+	list, err = GenerateListSQLWithOptions(analyses, inline, "", opts)
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+	for _, obj := range []string{"document", "folder"} {
+		sql := functionNamed(t, list.ListSubjectsFunctions, listSubjectsFunctionName(nil, obj, "viewer"))
+		filter := sql[:strings.Index(sql, "ELSE")]
+		assertContains(t, filter, "SELECT u.subject_id || '#' || v_filter_relation AS subject_id")
+		assertContains(t, filter, "FROM melange_subjects AS u")
+		assertContains(t, filter, "WHERE (p_expand_wildcard AND u.subject_type = v_filter_type AND EXISTS (")
+		assertContains(t, filter, "check_permission_internal(v_filter_type, u.subject_id || '#' || v_filter_relation, 'viewer'")
+		assertNotContains(t, filter, "FROM melange_tuples AS u")
+	}
+}
@@ -0,0 +1,41 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+// For `viewer: [user] and editor` the [user] part is a candidate source on
+// the subjects side: its direct tuples sit on viewer itself.
+func TestListSubjectsIntersection_ThisPartIsCandidate(t *testing.T) {
+	plan := ListPlan{
+		ObjectType:          "document",
+		Relation:            "viewer",
+		AllowedSubjectTypes: []string{"user"},
+		Analysis: RelationAnalysis{
+			IntersectionGroups: []IntersectionGroupInfo{{Parts: []IntersectionPart{
+				{IsThis: true},
+				{Relation: "editor"},
+			}}},
+		},
+	}
+
+	blocks := buildListSubjectsIntersectionPartBlocks(plan, false)
+	if len(blocks) != 2 {
+		t.Fatalf("expected a candidate block per part, got %d", len(blocks))
+	}
+
+	this := blocks[0]
+	if got := strings.Join(this.Comments, "\n"); !strings.Contains(got, "direct viewer") {
+		t.Errorf("unexpected comment for the [user] part: %s", got)
+	}
+	sql := this.Query.SQL()
+	assertContains(t, sql, "t.relation = 'viewer'")
+	assertContains(t, sql, "p_subject_type IN ('user')")
+	assertNotContains(t, sql, "t.subject_id <> '*'")
+
+	sql = buildListSubjectsIntersectionPartBlocks(plan, true)[0].Query.SQL()
+	assertContains(t, sql, "t.subject_id <> '*'")
+
+	assertContains(t, blocks[1].Query.SQL(), "t.relation = 'editor'")
+}
@@ -0,0 +1,64 @@
+package sqlgen
+
+// ListSubjectsMultiFunctionName is the list_subjects combinator that takes
+// several subject type filters at once, as OpenFGA's ListUsers does.
+const ListSubjectsMultiFunctionName = "list_accessible_subjects_multi"
+
+// generateListSubjectsMultiDispatcher renders list_accessible_subjects_multi,
+// which lists the subjects with p_relation on the object for every filter in
+// p_subject_types and returns their union. Each row carries the filter that
+// produced it, so a caller can tell a user "alice" from a group "eng" and a
+// userset filter ("group#member") from the plain type.
+//
+// Every filter goes through list_accessible_subjects, so userset filters and
+// plain types route exactly as they do there: a relation without a list
+// function raises M2003 and an unknown filter lists nothing. The union is not
+// paginated; like ListUsers it returns every subject in one call. p_context
+// and p_consistency are passed to every filter's listing.
+func generateListSubjectsMultiDispatcher(databaseSchema string) string {
+	query := SelectStmt{
+		Distinct: true,
+		ColumnExprs: []Expr{
+			Col{Table: "f", Column: "subject_type"},
+			Col{Table: "s", Column: "subject_id"},
+		},
+		FromExpr: FunctionCallExpr{Name: "unnest", Args: []Expr{Param("p_subject_types")}, Alias: "f(subject_type)"},
+		Joins: []JoinClause{{
+			Type: "CROSS",
+			TableExpr: LateralFunction{
+				Schema: databaseSchema,
+				Name:   "list_accessible_subjects",
+				Args: CallArgs(ListSubjectsDispatcherArgs(), map[string]Expr{
+					"p_object_type":  ObjectType,
+					"p_object_id":    ObjectID,
+					"p_relation":     Param("p_relation"),
+					"p_subject_type": Col{Table: "f", Column: "subject_type"},
+					"p_context":      Param("p_context"),
+					"p_consistency":  Param("p_consistency"),
+				}),
+				Alias: "s",
+			},
+		}},
+	}
+
+	args := append(ListSubjectsDispatcherArgs()[:3:3],
+		FuncArg{Name: "p_subject_types", Type: "TEXT[]"},
+		FuncArg{Name: "p_context", Type: "JSONB", Default: Null{}},
+		ConsistencyArg())
+	fn := SqlFunction{
+		Schema:  databaseSchema,
+		Name:    ListSubjectsMultiFunctionName,
+		Args:    args,
+		Returns: "TABLE (subject_type TEXT, subject_id TEXT) ROWS 100",
+		Body:    query,
+		Header: []string{
+			"Generated combinator " + ListSubjectsMultiFunctionName,
+			"Lists the subjects with p_relation on the object for each filter in p_subject_types",
+		},
+		// Calls only the schema-qualified list_accessible_subjects dispatcher.
+		NoSearchPath: true,
+	}
+	// p_context and p_consistency were appended after the combinator first
+	// shipped.
+	return withLegacyDrop(fn.SQL(), ListSubjectsMultiFunctionName, databaseSchema, args, "p_context", "p_consistency") + "\n"
+}
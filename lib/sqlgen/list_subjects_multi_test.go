@@ -0,0 +1,17 @@
+package sqlgen
+
+import "testing"
+
+// list_accessible_subjects_multi must list every filter through the
+// list_accessible_subjects dispatcher and tag each subject with its filter.
+func TestListSubjectsMultiDispatcher(t *testing.T) {
+	sql := generateListSubjectsMultiDispatcher("authz")
+
+	assertContains(t, sql, `CREATE OR REPLACE FUNCTION "authz"."list_accessible_subjects_multi"(`)
+	assertContains(t, sql, "p_relation TEXT,\n    p_subject_types TEXT[],\n    p_context JSONB DEFAULT NULL,\n    p_consistency TEXT DEFAULT NULL\n) RETURNS TABLE (subject_type TEXT, subject_id TEXT)")
+	assertContains(t, sql, "SELECT DISTINCT f.subject_type, s.subject_id")
+	assertContains(t, sql, "FROM unnest(p_subject_types) AS f(subject_type)")
+	assertContains(t, sql, `CROSS JOIN LATERAL "authz"."list_accessible_subjects"(p_object_type => p_object_type, p_object_id => p_object_id, p_relation => p_relation, p_subject_type => f.subject_type, p_context => p_context, p_consistency => p_consistency) AS s`)
+	assertContains(t, sql, `DROP FUNCTION IF EXISTS "authz"."list_accessible_subjects_multi"(TEXT, TEXT, TEXT, TEXT[]);`)
+	assertNotContains(t, sql, "SET search_path")
+}
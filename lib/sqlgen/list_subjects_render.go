@@ -19,7 +19,7 @@ func RenderListSubjectsFunction(plan ListPlan, blocks BlockSet) (string, error)
 	// Build main IF statement: check if subject_type is a userset filter
 	mainIf := If{
 		Cond: Gt{
-			Left:  Position{Needle: Lit("#"), Haystack: SubjectType},
+			Left:  StrPos{Source: SubjectType, Substr: "#"},
 			Right: Int(0),
 		},
 		Then: thenBranch,
@@ -36,10 +36,11 @@ func RenderListSubjectsFunction(plan ListPlan, blocks BlockSet) (string, error)
 			{Name: "v_filter_type", Type: "TEXT"},
 			{Name: "v_filter_relation", Type: "TEXT"},
 		},
-		Body: []Stmt{
+		Body: append(normalizeTypeOnlyFilter(),
 			Comment{Text: "Check if subject_type is a userset filter (e.g., \"document#viewer\")"},
 			mainIf,
-		},
+		),
+		NullGuards: plan.NullGuards,
 	}
 
 	return fn.SQL(), nil
@@ -33,15 +33,15 @@ func RenderListSubjectsComposedFunction(plan ListPlan, blocks ComposedSubjectsBl
 	regularPaginatedSQL := plan.wrapPaginationWildcardFirst(regularQuery)
 
 	// Build the body using plpgsql DSL types
-	body := []Stmt{
+	body := append(normalizeTypeOnlyFilter(),
 		// Determine if this is a userset filter request
-		Assign{Name: "v_is_userset_filter", Value: Gt{Left: Position{Needle: Lit("#"), Haystack: SubjectType}, Right: Int(0)}},
+		Assign{Name: "v_is_userset_filter", Value: HasUserset{Source: SubjectType}},
 		If{
 			Cond: Param("v_is_userset_filter"),
 			Then: []Stmt{
 				// Extract filter type and relation from userset subject type
-				Assign{Name: "v_filter_type", Value: Raw("split_part(p_subject_type, '#', 1)")},
-				Assign{Name: "v_filter_relation", Value: Raw("split_part(p_subject_type, '#', 2)")},
+				Assign{Name: "v_filter_type", Value: UsersetObjectID{Source: SubjectType}},
+				Assign{Name: "v_filter_relation", Value: UsersetRelation{Source: SubjectType}},
 				Comment{Text: "Self-candidate: when filter type matches object type"},
 				If{
 					Cond: Eq{Left: Param("v_filter_type"), Right: Lit(plan.ObjectType)},
@@ -67,7 +67,7 @@ func RenderListSubjectsComposedFunction(plan ListPlan, blocks ComposedSubjectsBl
 				ReturnQuery{Query: regularPaginatedSQL},
 			},
 		},
-	}
+	)
 
 	fn := PlpgsqlFunction{
 		Schema:  plan.DatabaseSchema,
@@ -84,7 +84,8 @@ func RenderListSubjectsComposedFunction(plan ListPlan, blocks ComposedSubjectsBl
 			{Name: "v_filter_type", Type: "TEXT"},
 			{Name: "v_filter_relation", Type: "TEXT"},
 		},
-		Body: body,
+		Body:       body,
+		NullGuards: plan.NullGuards,
 	}
 	return fn.SQL(), nil
 }
@@ -116,7 +117,7 @@ func buildRegularQueryWithExclusions(plan ListPlan, blocks ComposedSubjectsBlock
 	}
 
 	if blocks.HasExclusions {
-		exclusions := buildSimpleComplexExclusionInput(plan.Analysis, plan.DatabaseSchema, ObjectID, SubjectType, Col{Table: "sc", Column: "subject_id"})
+		exclusions := buildSimpleComplexExclusionInput(plan.Analysis, plan.DatabaseSchema, plan.TuplesTable, ObjectID, SubjectType, Col{Table: "sc", Column: "subject_id"})
 		if preds := exclusions.BuildPredicates(); len(preds) > 0 {
 			query.Where = And(preds...)
 		}
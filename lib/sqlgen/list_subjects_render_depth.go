@@ -13,13 +13,14 @@ func RenderListSubjectsDepthExceededFunction(plan ListPlan) string {
 		Header: []string{
 			fmt.Sprintf("Generated list_subjects function for %s.%s", plan.ObjectType, plan.Relation),
 			fmt.Sprintf("Features: %s", plan.FeaturesString()),
-			fmt.Sprintf("DEPTH EXCEEDED: Userset chain depth %d exceeds 25 level limit", plan.Analysis.MaxUsersetDepth),
+			fmt.Sprintf("DEPTH EXCEEDED: Userset chain depth %d exceeds %d level limit", plan.Analysis.MaxUsersetDepth, plan.maxDepth()),
 		},
 		Body: []Stmt{
-			Comment{Text: fmt.Sprintf("This relation has userset chain depth %d which exceeds the 25 level limit.", plan.Analysis.MaxUsersetDepth)},
+			Comment{Text: fmt.Sprintf("This relation has userset chain depth %d which exceeds the %d level limit.", plan.Analysis.MaxUsersetDepth, plan.maxDepth())},
 			Comment{Text: "Raise M2002 immediately without any computation."},
-			Raise{Message: "resolution too complex", ErrCode: "M2002"},
+			Raise{Message: "resolution too complex", ErrCode: ErrCodeResolutionTooComplex},
 		},
+		NullGuards: plan.NullGuards,
 	}
 	return fn.SQL()
 }
@@ -18,7 +18,7 @@ func renderUsersetFilterThenBranch(usersetFilterPaginatedQuery string) []Stmt {
 		return []Stmt{Return{}}
 	}
 
-	hashPos := Position{Needle: Lit("#"), Haystack: SubjectType}
+	hashPos := StrPos{Source: SubjectType, Substr: "#"}
 	return []Stmt{
 		Assign{
 			Name: "v_filter_type",
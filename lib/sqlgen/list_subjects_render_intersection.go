@@ -34,7 +34,7 @@ func RenderListSubjectsIntersectionFunction(plan ListPlan, blocks SubjectsInters
 	// Build main IF statement
 	mainIf := If{
 		Cond: Gt{
-			Left:  Position{Needle: Lit("#"), Haystack: SubjectType},
+			Left:  StrPos{Source: SubjectType, Substr: "#"},
 			Right: Int(0),
 		},
 		Then: thenBranch,
@@ -51,10 +51,11 @@ func RenderListSubjectsIntersectionFunction(plan ListPlan, blocks SubjectsInters
 			{Name: "v_filter_type", Type: "TEXT"},
 			{Name: "v_filter_relation", Type: "TEXT"},
 		},
-		Body: []Stmt{
+		Body: append(normalizeTypeOnlyFilter(),
 			Comment{Text: "Check if p_subject_type is a userset filter (contains '#')"},
 			mainIf,
-		},
+		),
+		NullGuards: plan.NullGuards,
 	}
 
 	return fn.SQL(), nil
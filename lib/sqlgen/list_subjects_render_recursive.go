@@ -17,7 +17,7 @@ func RenderListSubjectsRecursiveFunction(plan ListPlan, blocks SubjectsRecursive
 
 	mainIf := If{
 		Cond: Gt{
-			Left:  Position{Needle: Lit("#"), Haystack: SubjectType},
+			Left:  StrPos{Source: SubjectType, Substr: "#"},
 			Right: Int(0),
 		},
 		Then: renderUsersetFilterThenBranch(usersetFilterPaginatedQuery),
@@ -37,10 +37,11 @@ func RenderListSubjectsRecursiveFunction(plan ListPlan, blocks SubjectsRecursive
 			{Name: "v_filter_type", Type: "TEXT"},
 			{Name: "v_filter_relation", Type: "TEXT"},
 		},
-		Body: []Stmt{
+		Body: append(normalizeTypeOnlyFilter(),
 			Comment{Text: "Check if p_subject_type is a userset filter (contains '#')"},
 			mainIf,
-		},
+		),
+		NullGuards: plan.NullGuards,
 	}
 
 	return fn.SQL(), nil
@@ -119,6 +120,10 @@ func buildSubjectsRecursiveRegularQuery(plan ListPlan, regularBlocks, ttuBlocks
 
 	// Build the final query with wildcard handling
 	wildcardTailQuery := buildSubjectsWildcardTailQuery(plan)
+	if needsParentClosure {
+		wildcardTailQuery.Where = And(wildcardTailQuery.Where,
+			depthLimitGuard(plan.DatabaseSchema, "parent_closure", []string{"subject_type", "subject_id"}, plan.maxDepth()))
+	}
 
 	// Build the full CTE query - use RECURSIVE if we have parent_closure
 	recursive := needsParentClosure
@@ -180,7 +185,7 @@ func buildParentClosureCTESQL(plan ListPlan) string {
 			Col{Table: "link", Column: "subject_id"},
 			Raw("0 AS depth"),
 		},
-		FromExpr: TableAs("", "melange_tuples", "link"),
+		FromExpr: TuplesTableAs(plan.TuplesTable, "link"),
 		Where:    And(baseWhere...),
 	}
 
@@ -204,7 +209,7 @@ func buildParentClosureCTESQL(plan ListPlan) string {
 		Joins: []JoinClause{{
 			Type:   "INNER",
 			Schema: "",
-			Table:  "melange_tuples",
+			Table:  TuplesTableName(plan.TuplesTable),
 			Alias:  "link",
 			On: And(
 				Eq{Left: Col{Table: "link", Column: "object_type"}, Right: Col{Table: "p", Column: "subject_type"}},
@@ -214,7 +219,7 @@ func buildParentClosureCTESQL(plan ListPlan) string {
 		Where: And(
 			Eq{Left: Col{Table: "p", Column: "subject_type"}, Right: Lit(plan.ObjectType)},
 			In{Expr: Col{Table: "link", Column: "relation"}, Values: linkingRelations},
-			Lt{Left: Col{Table: "p", Column: "depth"}, Right: Int(25)},
+			Lt{Left: Col{Table: "p", Column: "depth"}, Right: Int(plan.maxDepth())},
 		),
 	}
 
@@ -226,7 +231,7 @@ func buildParentClosureCTESQL(plan ListPlan) string {
 func buildSubjectPoolCTESQL(plan ListPlan) string {
 	excludeWildcard := plan.ExcludeWildcard()
 
-	q := Tuples(plan.DatabaseSchema, "t").
+	q := TuplesWithTable(plan.TuplesTable, plan.DatabaseSchema, "t").
 		Select("t.subject_id").
 		WhereSubjectType(SubjectType).
 		Where(In{Expr: SubjectType, Values: plan.AllowedSubjectTypes}).
@@ -241,7 +246,7 @@ func buildSubjectPoolCTESQL(plan ListPlan) string {
 // buildSubjectsWildcardTailQuery builds the final SELECT with wildcard expansion.
 // The returned SQL has no trailing semicolon because it is composed into a larger
 // pagination CTE by the caller.
-func buildSubjectsWildcardTailQuery(plan ListPlan) SQLer {
+func buildSubjectsWildcardTailQuery(plan ListPlan) SelectStmt {
 	if plan.AllowWildcard {
 		return SelectStmt{
 			ColumnExprs: []Expr{Col{Table: "br", Column: "subject_id"}},
@@ -5,7 +5,7 @@ package sqlgen
 // =============================================================================
 func RenderListSubjectsSelfRefUsersetFunction(plan ListPlan, blocks SelfRefUsersetSubjectsBlockSet) (string, error) {
 	usersetFilterPaginatedQuery := plan.wrapPaginationWildcardFirst(
-		trimTrailingSemicolon(renderSelfRefUsersetFilterQuery(blocks)),
+		trimTrailingSemicolon(renderSelfRefUsersetFilterQuery(plan, blocks)),
 	)
 	regularPaginatedQuery := plan.wrapPaginationWildcardFirst(
 		trimTrailingSemicolon(renderSelfRefUsersetRegularQuery(plan, blocks)),
@@ -23,7 +23,7 @@ func RenderListSubjectsSelfRefUsersetFunction(plan ListPlan, blocks SelfRefUsers
 	// Build main IF statement: check if subject_type is a userset filter
 	mainIf := If{
 		Cond: Gt{
-			Left:  Position{Needle: Lit("#"), Haystack: SubjectType},
+			Left:  StrPos{Source: SubjectType, Substr: "#"},
 			Right: Int(0),
 		},
 		Then: thenBranch,
@@ -40,16 +40,17 @@ func RenderListSubjectsSelfRefUsersetFunction(plan ListPlan, blocks SelfRefUsers
 			{Name: "v_filter_type", Type: "TEXT"},
 			{Name: "v_filter_relation", Type: "TEXT"},
 		},
-		Body: []Stmt{
+		Body: append(normalizeTypeOnlyFilter(),
 			Comment{Text: "Check if p_subject_type is a userset filter (contains '#')"},
 			mainIf,
-		},
+		),
+		NullGuards: plan.NullGuards,
 	}
 
 	return fn.SQL(), nil
 }
 
-func renderSelfRefUsersetFilterQuery(blocks SelfRefUsersetSubjectsBlockSet) string {
+func renderSelfRefUsersetFilterQuery(plan ListPlan, blocks SelfRefUsersetSubjectsBlockSet) string {
 	baseBlocks := renderTypedQueryBlocks(blocks.UsersetFilterBlocks)
 	cteBody := RenderUnionBlocks(baseBlocks)
 
@@ -58,18 +59,22 @@ func renderSelfRefUsersetFilterQuery(blocks SelfRefUsersetSubjectsBlockSet) stri
 		cteBody = appendUnion(cteBody, formatQueryBlockSQL(recursiveBlock.Comments, recursiveBlock.Query.SQL()))
 	}
 
-	resultBlocks := []QueryBlock{{
-		Comments: []string{"-- Userset filter: return normalized userset references"},
-		Query: SelectStmt{
-			Distinct: true,
-			ColumnExprs: []Expr{
-				Alias{
-					Expr: Concat{Parts: []Expr{Col{Table: "ue", Column: "userset_object_id"}, Lit("#"), Param("v_filter_relation")}},
-					Name: "subject_id",
-				},
+	expansion := SelectStmt{
+		Distinct: true,
+		ColumnExprs: []Expr{
+			Alias{
+				Expr: Concat{Parts: []Expr{Col{Table: "ue", Column: "userset_object_id"}, Lit("#"), Param("v_filter_relation")}},
+				Name: "subject_id",
 			},
-			FromExpr: TableAs("", "userset_expansion", "ue"),
 		},
+		FromExpr: TableAs("", "userset_expansion", "ue"),
+	}
+	if blocks.UsersetFilterRecursiveBlock != nil {
+		expansion.Where = depthLimitGuard(plan.DatabaseSchema, "userset_expansion", []string{"userset_object_id"}, plan.maxDepth())
+	}
+	resultBlocks := []QueryBlock{{
+		Comments: []string{"-- Userset filter: return normalized userset references"},
+		Query:    expansion,
 	}}
 
 	if blocks.UsersetFilterSelfBlock != nil {
@@ -112,7 +117,11 @@ func renderSelfRefUsersetRegularQuery(plan ListPlan, blocks SelfRefUsersetSubjec
 		}})
 	}
 
-	cteQuery := MultiCTE(true, ctes, buildUsersetWildcardTailQuery(plan.Analysis, plan.DatabaseSchema))
+	tail := buildUsersetWildcardTailQuery(plan.Analysis, plan.DatabaseSchema)
+	if blocks.UsersetObjectsBaseBlock != nil && blocks.UsersetObjectsRecursiveBlock != nil {
+		tail.Where = And(tail.Where, depthLimitGuard(plan.DatabaseSchema, "userset_objects", []string{"userset_object_id"}, plan.maxDepth()))
+	}
+	cteQuery := MultiCTE(true, ctes, tail)
 
 	return cteQuery.SQL()
 }
@@ -50,7 +50,7 @@ func TestSubjectFirstTTU_ComposesWhenSafe(t *testing.T) {
 		t.Fatalf("expected 1 subject-first block, got %d", len(blocks))
 	}
 	sql := blocks[0].Query.SQL()
-	if !strings.Contains(sql, "CROSS JOIN LATERAL list_org_admin_sub(link.subject_id, p_subject_type) AS sub") {
+	if !strings.Contains(sql, "CROSS JOIN LATERAL list_org_admin_sub(p_object_id => link.subject_id, p_subject_type => p_subject_type) AS sub") {
 		t.Errorf("expected LATERAL compose with list_org_admin_sub, got:\n%s", sql)
 	}
 	if strings.Contains(sql, "check_permission_internal") {
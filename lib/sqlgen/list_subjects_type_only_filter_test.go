@@ -0,0 +1,70 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+// typeOnlyFilterTestSchema gives list_subjects functions from each renderer:
+// plain direct grants, a recursive parent, a self-referential userset, an
+// intersection and an indirect anchor.
+const typeOnlyFilterTestSchema = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type folder
+  relations
+    define parent: [folder]
+    define viewer: [user] or viewer from parent
+
+type document
+  relations
+    define parent: [folder]
+    define owner: [user]
+    define editor: [user] and owner
+    define viewer: viewer from parent
+`
+
+// A filter with a trailing '#' (e.g. "doc#") names no relation. Every
+// specialized list_subjects function must rewrite it to the bare type before
+// choosing a path, so a direct call takes the regular subject path rather
+// than the userset filter path, just as a call through the dispatcher does.
+func TestListSubjects_NormalizesTypeOnlyFilter(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, typeOnlyFilterTestSchema)
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+
+	normalize := "IF p_subject_type LIKE '%#' THEN"
+	assign := "p_subject_type := rtrim(p_subject_type, '#');"
+	for _, name := range []string{
+		"list_group_member_sub",
+		"list_folder_viewer_sub",
+		"list_document_owner_sub",
+		"list_document_editor_sub",
+		"list_document_viewer_sub",
+	} {
+		fn := functionNamed(t, list.ListSubjectsFunctions, name)
+		for _, want := range []string{normalize, assign} {
+			if !strings.Contains(fn, want) {
+				t.Fatalf("%s missing %q:\n%s", name, want, fn)
+			}
+		}
+		if strings.Index(fn, assign) > strings.Index(fn, "v_filter_type :=") {
+			t.Errorf("%s must normalize a type-only filter before splitting it:\n%s", name, fn)
+		}
+	}
+
+	if strings.Contains(list.ListSubjectsDispatcher, "rtrim(") {
+		t.Errorf("list_accessible_subjects should leave normalizing to the functions it routes to:\n%s", list.ListSubjectsDispatcher)
+	}
+	if strings.Contains(list.ListObjectsDispatcher, "rtrim(") {
+		t.Errorf("list_accessible_objects has no subject filter to normalize:\n%s", list.ListObjectsDispatcher)
+	}
+}
@@ -0,0 +1,55 @@
+package sqlgen
+
+import "testing"
+
+// usersetExclusionPlan is list_subjects for
+// "viewer: [group#member] but not suspended" with a complex suspended
+// relation and a TTU exclusion, so no exclusion goes through the CTE.
+func usersetExclusionPlan() ListPlan {
+	a := RelationAnalysis{
+		ObjectType:               "document",
+		Relation:                 "viewer",
+		Features:                 RelationFeatures{HasUserset: true, HasExclusion: true},
+		ComplexExcludedRelations: []string{"suspended"},
+		ExcludedParentRelations: []ParentRelationInfo{{
+			Relation:            "banned",
+			LinkingRelation:     "parent",
+			AllowedLinkingTypes: []string{"folder"},
+		}},
+	}
+	return BuildListSubjectsPlanWithLookup(a, InlineSQLData{}, "", nil)
+}
+
+func usersetExclusionPattern(complex bool) listUsersetPatternInput {
+	return listUsersetPatternInput{
+		SubjectType:         "group",
+		SubjectRelation:     "member",
+		SatisfyingRelations: []string{"member"},
+		SourceRelations:     []string{"viewer"},
+		IsComplex:           complex,
+	}
+}
+
+func TestListSubjectsUsersetBlocks_ExcludeExpandedMembers(t *testing.T) {
+	plan := usersetExclusionPlan()
+	if plan.UseCTEExclusion {
+		t.Fatal("fixture must not use the CTE exclusion")
+	}
+
+	tests := []struct {
+		name   string
+		sql    string
+		member string
+	}{
+		{"simple", buildListSubjectsSimpleUsersetBlock(plan, usersetExclusionPattern(false)).Query.SQL(), "m.subject_id"},
+		{"complex", buildListSubjectsComplexUsersetBlock(plan, usersetExclusionPattern(true)).Query.SQL(), "ls.subject_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertContains(t, tt.sql, "check_permission_internal(p_subject_type, "+tt.member+", 'suspended', 'document', p_object_id")
+			assertContains(t, tt.sql, "check_permission_internal(p_subject_type, "+tt.member+", 'banned', link.subject_type, link.subject_id")
+			// The grant tuple's subject is the userset, never the member.
+			assertNotContains(t, tt.sql, "check_permission_internal(t.subject_type, t.subject_id, 'suspended'")
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package sqlgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const traceTestSchema = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type folder
+  relations
+    define viewer: [user]
+
+type document
+  relations
+    define parent: [folder]
+    define owner: [user]
+    define blocked: [user]
+    define viewer: [user, group#member] or owner or viewer from parent
+    define can_view: viewer but not blocked
+`
+
+func TestGenerateListSQL_TraceBlocks(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, traceTestSchema)
+
+	plain, err := GenerateListSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatalf("GenerateListSQL: %v", err)
+	}
+	off, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+	if !reflect.DeepEqual(plain, off) {
+		t.Fatal("output without TraceBlocks differs from GenerateListSQL")
+	}
+	untraced := strings.Join(append(plain.ListObjectsFunctions, plain.ListSubjectsFunctions...), "\n")
+	assertNotContains(t, untraced, "[relation=")
+
+	traced, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{TraceBlocks: true})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+	sql := strings.Join(append(traced.ListObjectsFunctions, traced.ListSubjectsFunctions...), "\n")
+	for _, want := range []string{
+		"-- [relation=document.viewer feature=direct] Direct tuple lookup",
+		"-- [relation=document.viewer feature=userset pattern=group#member] Userset: group#member",
+		"-- [relation=document.viewer feature=ttu pattern=parent->viewer] TTU",
+		"-- [relation=document.can_view feature=",
+	} {
+		assertContains(t, sql, want)
+	}
+
+	// Every comment line of a block carries the tag, not only the first.
+	for _, line := range strings.Split(sql, "\n") {
+		if strings.Contains(line, "-- Type guard:") {
+			t.Errorf("untagged block comment: %q", strings.TrimSpace(line))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package sqlgen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+)
+
+// DepthExceededFunctionName is the helper recursive list functions, and the
+// self-referential userset walk of check functions, call to raise M2002 from
+// inside a query. It is emitted with every schema.
+const DepthExceededFunctionName = "melange_depth_exceeded"
+
+// ValidateMaxDepth reports whether maxDepth can bound recursion in generated
+// SQL. Zero selects DefaultMaxDepth.
+func ValidateMaxDepth(maxDepth int) error {
+	if maxDepth < 0 {
+		return fmt.Errorf("max depth %d must not be negative", maxDepth)
+	}
+	return nil
+}
+
+// maxDepth returns opts.MaxDepth, or DefaultMaxDepth when it is unset.
+func (opts GenerateSQLOptions) maxDepth() int {
+	return resolveMaxDepth(opts.MaxDepth)
+}
+
+// resolveMaxDepth returns maxDepth, or DefaultMaxDepth when it is unset.
+func resolveMaxDepth(maxDepth int) int {
+	if maxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return maxDepth
+}
+
+// depthLimitCheck raises M2002 once p_visited holds maxDepth entries, the
+// guard every recursive check entrypoint runs before resolving further.
+func depthLimitCheck(maxDepth int) If {
+	return If{
+		Cond: Gte{Left: ArrayLength{Array: Visited}, Right: Int(maxDepth)},
+		Then: []Stmt{Raise{Message: "resolution too complex", ErrCode: ErrCodeResolutionTooComplex}},
+	}
+}
+
+// generateDepthExceededFunction renders melange_depth_exceeded(), which
+// raises M2002 and never returns. A plain RAISE cannot appear inside a query,
+// so recursive CTEs call it from depthLimitGuard.
+func generateDepthExceededFunction(databaseSchema string) string {
+	fn := PlpgsqlFunction{
+		Schema:  databaseSchema,
+		Name:    DepthExceededFunctionName,
+		Returns: "BOOLEAN",
+		Body:    []Stmt{Raise{Message: "resolution too complex", ErrCode: ErrCodeResolutionTooComplex}},
+		Header: []string{
+			"Generated depth limit helper: raises M2002 when a recursive list query reaches the depth limit",
+		},
+		// Reads no tables.
+		NoSearchPath: true,
+	}
+	return fn.SQL() + "\n"
+}
+
+// depthLimitGuard returns a WHERE predicate for the final SELECT over the
+// recursive CTE cte, whose rows carry a depth column and are identified by
+// keys. The CTE stops expanding at maxDepth, so a row reaching that depth
+// means the walk may have been cut short: the predicate then calls
+// melange_depth_exceeded to raise M2002 rather than let the function return
+// a truncated result.
+//
+// A cycle in the tuples also drives the walk to maxDepth, revisiting rows it
+// has already produced, so only a row whose keys do not appear at a
+// shallower depth counts. The predicate references no column of the outer
+// query, so PostgreSQL evaluates it once per call. It is rendered on one
+// line: a multi-line subquery would defeat SelectStmt's indentation.
+func depthLimitGuard(databaseSchema, cte string, keys []string, maxDepth int) Expr {
+	depth := strconv.Itoa(maxDepth)
+	matches := make([]string, len(keys))
+	for i, k := range keys {
+		matches[i] = "ds." + k + " = dl." + k
+	}
+	reached := "EXISTS (SELECT 1 FROM " + cte + " AS dl WHERE dl.depth >= " + depth +
+		" AND NOT EXISTS (SELECT 1 FROM " + cte + " AS ds WHERE " + strings.Join(matches, " AND ") +
+		" AND ds.depth < " + depth + "))"
+	return Raw("CASE WHEN " + reached + " THEN " +
+		sqldsl.PrefixIdent(DepthExceededFunctionName, databaseSchema) + "() ELSE TRUE END")
+}
@@ -0,0 +1,129 @@
+package sqlgen
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const maxDepthTestSchema = `model
+  schema 1.1
+
+type user
+
+type org
+  relations
+    define member: [user]
+
+type group
+  relations
+    define member: [user, group#member, org#member]
+
+type team
+  relations
+    define member: [user, org#member]
+
+type project
+  relations
+    define viewer: [user, team#member]
+
+type folder
+  relations
+    define parent: [folder]
+    define viewer: [user, group#member] or viewer from parent
+`
+
+func TestGenerateSQL_MaxDepth(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, maxDepthTestSchema)
+	opts := GenerateSQLOptions{MaxDepth: 40}
+
+	gen, err := GenerateSQLWithOptions(analyses, inline, "", opts)
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	checks := strings.Join(gen.Functions, "\n")
+	assertContains(t, checks, ">= 40 THEN")
+	assertNotContains(t, checks, ">= 25 THEN")
+	assertContains(t, gen.Dispatcher, ">= 40 THEN")
+
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", opts)
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+	objects := strings.Join(list.ListObjectsFunctions, "\n")
+	assertContains(t, objects, "depth < 40")
+	assertNotContains(t, objects, "depth < 25")
+	assertContains(t, objects, "dl.depth >= 40")
+	assertContains(t, objects, "THEN melange_depth_exceeded() ELSE TRUE END")
+	assertContains(t, list.DepthExceededFunction, "FUNCTION melange_depth_exceeded(\n) RETURNS BOOLEAN")
+	assertContains(t, list.DepthExceededFunction, "ERRCODE = 'M2002'")
+
+	dflt, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+	assertContains(t, strings.Join(dflt.ListObjectsFunctions, "\n"), "dl.depth >= 25")
+}
+
+func TestGenerateSQL_MaxDepthInvalid(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, maxDepthTestSchema)
+	opts := GenerateSQLOptions{MaxDepth: -1}
+	if _, err := GenerateSQLWithOptions(analyses, inline, "", opts); err == nil {
+		t.Error("GenerateSQLWithOptions accepted a negative MaxDepth")
+	}
+	if _, err := GenerateListSQLWithOptions(analyses, inline, "", opts); err == nil {
+		t.Error("GenerateListSQLWithOptions accepted a negative MaxDepth")
+	}
+}
+
+func TestDepthExceededFunctionCollected(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, maxDepthTestSchema)
+	gen, err := GenerateSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+
+	if !slices.Contains(CollectFunctionNames(analyses), DepthExceededFunctionName) {
+		t.Errorf("CollectFunctionNames missing %s", DepthExceededFunctionName)
+	}
+	var dispatchers []string
+	for _, fn := range CollectDispatcherFunctions(gen, list) {
+		dispatchers = append(dispatchers, fn.Name)
+	}
+	if !slices.Contains(dispatchers, DepthExceededFunctionName) {
+		t.Errorf("CollectDispatcherFunctions missing %s: %v", DepthExceededFunctionName, dispatchers)
+	}
+}
+
+func TestComputeCanGenerateWithMaxDepth(t *testing.T) {
+	analyses, _ := compileForCacheTest(t, maxDepthTestSchema)
+	depth := 0
+	for _, a := range analyses {
+		if a.ObjectType == "project" && a.Relation == "viewer" {
+			depth = a.MaxUsersetDepth
+		}
+	}
+	if depth <= 0 {
+		t.Fatalf("project.viewer MaxUsersetDepth = %d, want a positive userset chain", depth)
+	}
+
+	exceeds := func(analyses []RelationAnalysis) bool {
+		for _, a := range analyses {
+			if a.ObjectType == "project" && a.Relation == "viewer" {
+				return a.ExceedsDepthLimit
+			}
+		}
+		t.Fatal("project.viewer not analyzed")
+		return false
+	}
+	if exceeds(ComputeCanGenerateWithMaxDepth(analyses, depth+1)) {
+		t.Errorf("project.viewer exceeds a limit of %d with a chain of depth %d", depth+1, depth)
+	}
+	if !exceeds(ComputeCanGenerateWithMaxDepth(analyses, depth)) {
+		t.Errorf("project.viewer does not exceed a limit of %d with a chain of depth %d", depth, depth)
+	}
+}
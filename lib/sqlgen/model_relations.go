@@ -0,0 +1,63 @@
+package sqlgen
+
+import (
+	"cmp"
+	"slices"
+)
+
+// ModelRelationsFunctionName is the model inventory function emitted with
+// every schema. Callers tracking installed functions must expect it.
+const ModelRelationsFunctionName = "melange_model_relations"
+
+// generateModelRelationsFunction renders melange_model_relations(), which
+// returns one row per relation in the model with its feature set and whether
+// a specialized check and list function was generated for it.
+//
+// can_check and can_list read the same capabilities that gate check_* and
+// list_* generation, so the inventory cannot disagree with the installed
+// functions. The rows are inlined as VALUES at migrate time; the function is
+// IMMUTABLE and is rewritten whenever the schema changes. Rows are sorted by
+// object type and relation so the output does not depend on analysis order.
+func generateModelRelationsFunction(analyses []RelationAnalysis, databaseSchema string) string {
+	sorted := slices.SortedFunc(slices.Values(analyses), func(x, y RelationAnalysis) int {
+		return cmp.Or(cmp.Compare(x.ObjectType, y.ObjectType), cmp.Compare(x.Relation, y.Relation))
+	})
+	rows := make([]ValuesRow, 0, len(sorted))
+	for _, a := range sorted {
+		rows = append(rows, ValuesRow{
+			Lit(a.ObjectType),
+			Lit(a.Relation),
+			Lit(a.Features.String()),
+			Bool(a.Capabilities.CheckAllowed),
+			Bool(a.Capabilities.ListAllowed),
+		})
+	}
+	var where Expr
+	if len(rows) == 0 {
+		// A model with no relations still installs the function; the typed
+		// placeholder row keeps the column types and is filtered out.
+		rows = []ValuesRow{{Raw("NULL::TEXT"), Raw("NULL::TEXT"), Raw("NULL::TEXT"), Raw("NULL::BOOLEAN"), Raw("NULL::BOOLEAN")}}
+		where = Bool(false)
+	}
+	columns := []string{"object_type", "relation", "features", "can_check", "can_list"}
+	body := SelectStmt{
+		FromExpr: TypedValuesTable{Rows: rows, Alias: "r", Columns: columns},
+		Where:    where,
+	}
+	for _, c := range columns {
+		body.ColumnExprs = append(body.ColumnExprs, Col{Table: "r", Column: c})
+	}
+
+	fn := SqlFunction{
+		Schema:  databaseSchema,
+		Name:    ModelRelationsFunctionName,
+		Returns: "TABLE (object_type TEXT, relation TEXT, features TEXT, can_check BOOLEAN, can_list BOOLEAN)",
+		Body:    body,
+		Header: []string{
+			"Generated model inventory: every relation with its features and generated capabilities",
+		},
+		NoSearchPath: true,
+		Immutable:    true,
+	}
+	return fn.SQL() + "\n"
+}
@@ -0,0 +1,48 @@
+package sqlgen
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestModelRelations_ReportsGeneratedCapabilities(t *testing.T) {
+	analyses := []RelationAnalysis{
+		{ObjectType: "folder", Relation: "viewer", Features: RelationFeatures{HasDirect: true},
+			Capabilities: GenerationCapabilities{CheckAllowed: true, ListAllowed: true}},
+		{ObjectType: "document", Relation: "viewer", Features: RelationFeatures{HasDirect: true, HasRecursive: true},
+			Capabilities: GenerationCapabilities{CheckAllowed: true}},
+		{ObjectType: "document", Relation: "blocked"},
+	}
+	sql := generateModelRelationsFunction(analyses, "authz")
+
+	assertContains(t, sql, `CREATE OR REPLACE FUNCTION "authz"."melange_model_relations"(`)
+	assertContains(t, sql, "RETURNS TABLE (object_type TEXT, relation TEXT, features TEXT, can_check BOOLEAN, can_list BOOLEAN)")
+	assertContains(t, sql, "('document', 'blocked', 'None', FALSE, FALSE), ('document', 'viewer', 'Direct+Recursive', TRUE, FALSE), ('folder', 'viewer', 'Direct', TRUE, TRUE)")
+	assertContains(t, sql, "$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;")
+	assertNotContains(t, sql, "WHERE")
+}
+
+func TestModelRelations_EmptyModel(t *testing.T) {
+	sql := generateModelRelationsFunction(nil, "")
+	assertContains(t, sql, "(NULL::TEXT, NULL::TEXT, NULL::TEXT, NULL::BOOLEAN, NULL::BOOLEAN)")
+	assertContains(t, sql, "WHERE FALSE")
+}
+
+func TestModelRelations_AlwaysGenerated(t *testing.T) {
+	analyses := checkEvidenceAnalyses()
+	generated, err := GenerateSQL(analyses, InlineSQLData{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if generated.ModelRelationsFunction == "" {
+		t.Fatal("model relations function not generated")
+	}
+	if !slices.Contains(CollectFunctionNames(analyses), ModelRelationsFunctionName) {
+		t.Errorf("%s missing from function names", ModelRelationsFunctionName)
+	}
+	if !slices.ContainsFunc(CollectDispatcherFunctions(generated, ListGeneratedSQL{}), func(nf NamedFunction) bool {
+		return nf.Name == ModelRelationsFunctionName
+	}) {
+		t.Errorf("%s missing from dispatcher functions", ModelRelationsFunctionName)
+	}
+}
@@ -0,0 +1,77 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+)
+
+// Relations differing only by case get distinct functions, and every
+// reference to them — dispatchers, implied calls, name lists — uses the same
+// names.
+func TestNameCollisions_DistinctFunctions(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type document
+  relations
+    define viewer: [user]
+    define Viewer: [user, group#member]
+    define reader: Viewer
+`)
+	gen, err := GenerateSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := GenerateListSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collisions := NameCollisions{"document": {"Viewer": true}}
+	plain := functionName(nil, "document", "viewer")
+	hashed := functionName(collisions, "document", "Viewer")
+	if plain == hashed {
+		t.Fatalf("Viewer and viewer share %q", plain)
+	}
+	functionNamed(t, gen.Functions, plain)
+	functionNamed(t, gen.Functions, hashed)
+	functionNamed(t, list.ListObjectsFunctions, sqldsl.ListObjectsFunctionNameWithCollisions(collisions, "document", "Viewer"))
+	functionNamed(t, list.ListSubjectsFunctions, sqldsl.ListSubjectsFunctionNameWithCollisions(collisions, "document", "Viewer"))
+
+	// Each relation's dispatcher branch routes to its own function.
+	for _, c := range buildDispatcherCases(analyses, "", false, nil) {
+		if c.ObjectType != "document" {
+			continue
+		}
+		want := map[string]string{"viewer": plain, "Viewer": hashed, "reader": functionName(nil, "document", "reader")}[c.Relation]
+		if c.CheckFunctionName != want {
+			t.Errorf("dispatcher routes document.%s to %s, want %s", c.Relation, c.CheckFunctionName, want)
+		}
+	}
+
+	// reader resolves through Viewer, not viewer.
+	reader := functionNamed(t, gen.Functions, functionName(nil, "document", "reader"))
+	if !strings.Contains(reader, "'Viewer'") || strings.Contains(reader, "'viewer'") || strings.Contains(reader, plain+"(") {
+		t.Errorf("reader does not resolve through document.Viewer:\n%s", reader)
+	}
+
+	names := CollectFunctionNames(analyses)
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			t.Errorf("CollectFunctionNames lists %s twice", name)
+		}
+		seen[name] = true
+	}
+	if !seen[hashed] || !seen[plain] {
+		t.Errorf("CollectFunctionNames = %v, want both %s and %s", names, plain, hashed)
+	}
+}
@@ -0,0 +1,72 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+)
+
+const nullGuardsTestSchema = `model
+  schema 1.1
+
+type user
+
+type folder
+  relations
+    define viewer: [user]
+
+type document
+  relations
+    define parent: [folder]
+    define viewer: [user] or viewer from parent
+`
+
+// functionNamed returns the function in fns created under name.
+func functionNamed(t *testing.T, fns []string, name string) string {
+	t.Helper()
+	for _, fn := range fns {
+		if strings.Contains(fn, "FUNCTION "+name+"(") {
+			return fn
+		}
+	}
+	t.Fatalf("no function %s generated", name)
+	return ""
+}
+
+func TestGenerateSQL_NullGuards(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, nullGuardsTestSchema)
+	gen, err := GenerateSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+
+	check := functionNamed(t, gen.Functions, "check_document_viewer")
+	assertContains(t, check, "IF p_subject_id IS NULL THEN")
+	assertContains(t, check, "RAISE EXCEPTION 'check_document_viewer: p_subject_id must not be NULL' USING ERRCODE = '22004';")
+	assertContains(t, check, "RAISE EXCEPTION 'check_document_viewer: p_object_id must not be NULL' USING ERRCODE = '22004';")
+	assertContains(t, gen.Dispatcher, "RAISE EXCEPTION 'check_permission_internal: p_relation must not be NULL' USING ERRCODE = '22004';")
+
+	listFn := functionNamed(t, list.ListObjectsFunctions, "list_document_viewer_obj")
+	assertContains(t, listFn, "RAISE EXCEPTION 'list_document_viewer_obj: p_subject_type must not be NULL' USING ERRCODE = '22004';")
+	assertNotContains(t, listFn, "p_limit must not be NULL")
+	assertNotContains(t, listFn, "p_after must not be NULL")
+	assertContains(t, list.ListObjectsDispatcher, "RAISE EXCEPTION 'list_accessible_objects: p_object_type must not be NULL' USING ERRCODE = '22004';")
+	assertContains(t, list.ListSubjectsDispatcher, "RAISE EXCEPTION 'list_accessible_subjects: p_subject_type must not be NULL' USING ERRCODE = '22004';")
+}
+
+func TestGenerateSQL_DisableNullGuards(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, nullGuardsTestSchema)
+	opts := GenerateSQLOptions{DisableNullGuards: true}
+	gen, err := GenerateSQLWithOptions(analyses, inline, "", opts)
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", opts)
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+	assertNotContains(t, allGeneratedSQL(gen, list), "must not be NULL")
+}
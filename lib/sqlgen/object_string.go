@@ -0,0 +1,181 @@
+package sqlgen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+)
+
+// DefaultObjectDelimiter separates type from id in "type:id" strings, as in
+// OpenFGA. GenerateSQLOptions.ObjectDelimiter overrides it.
+const DefaultObjectDelimiter = ":"
+
+// ValidateObjectDelimiter reports whether delimiter can separate type from id
+// in object strings. The empty string selects DefaultObjectDelimiter. "#"
+// introduces the relation of a userset subject ("team:eng#member"), so a
+// delimiter containing it would make usersets ambiguous.
+func ValidateObjectDelimiter(delimiter string) error {
+	if strings.Contains(delimiter, "#") {
+		return fmt.Errorf("object delimiter %q must not contain %q, which separates a userset's relation", delimiter, "#")
+	}
+	return nil
+}
+
+// objectDelimiter returns opts.ObjectDelimiter, or DefaultObjectDelimiter
+// when it is unset.
+func (opts GenerateSQLOptions) objectDelimiter() string {
+	if opts.ObjectDelimiter == "" {
+		return DefaultObjectDelimiter
+	}
+	return opts.ObjectDelimiter
+}
+
+// ObjectStringOverloads maps each dispatcher that also has an overload taking
+// "type:id" strings to the argument types of that overload. DROP FUNCTION by
+// bare name fails once a name is overloaded, so callers dropping these
+// dispatchers drop the string overload by signature first.
+var ObjectStringOverloads = map[string]string{
+	"check_permission":         "TEXT, TEXT, TEXT",
+	"list_accessible_objects":  "TEXT, TEXT, TEXT, INT, TEXT",
+	"list_accessible_subjects": "TEXT, TEXT, TEXT, INT, TEXT",
+}
+
+// objectStringType and objectStringID split a "type:id" parameter on the
+// first occurrence of delimiter. Type names cannot contain the delimiter, so
+// the type ends there and the id is everything after it, further delimiters
+// and "#relation" suffixes included.
+func objectStringType(param, delimiter string) Expr {
+	return Func{Name: "split_part", Args: []Expr{Param(param), Lit(delimiter), Int(1)}}
+}
+
+func objectStringID(param, delimiter string) Expr {
+	return Func{Name: "substr", Args: []Expr{
+		Param(param),
+		Raw(objectStringDelimiterPos(param, delimiter) + " + " + strconv.Itoa(utf8.RuneCountInString(delimiter))),
+	}}
+}
+
+// objectStringDelimiterPos renders the 1-based character position of the
+// first delimiter in param, 0 when there is none.
+func objectStringDelimiterPos(param, delimiter string) string {
+	return Func{Name: "strpos", Args: []Expr{Param(param), Lit(delimiter)}}.SQL()
+}
+
+// checkObjectString raises ErrCodeInvalidArgument unless param has
+// a non-empty type and id on either side of delimiter. NULL passes through,
+// so a NULL argument behaves as it does in the tuple-column overloads. The
+// message goes through USING MESSAGE rather than a RAISE format string so a
+// "%" delimiter is not read as a placeholder.
+func checkObjectString(param, what, delimiter string) Stmt {
+	pos := objectStringDelimiterPos(param, delimiter)
+	return If{
+		Cond: Raw(fmt.Sprintf("%s <= 1 OR length(%s) < %s + %d", pos, param, pos, utf8.RuneCountInString(delimiter))),
+		Then: []Stmt{RawStmt{SQLText: fmt.Sprintf("RAISE EXCEPTION USING ERRCODE = '%s', MESSAGE = %s || quote_literal(%s) || %s;",
+			ErrCodeInvalidArgument, sqldsl.QuoteLiteral("malformed "+what+" "), param, sqldsl.QuoteLiteral(": expected type"+delimiter+"id"))}},
+	}
+}
+
+// The string overloads below match OpenFGA's string-based API. Each validates
+// and splits its "type:id" arguments on delimiter, then delegates to the
+// tuple-column overload of the same name, so routing and results are
+// identical.
+
+// generateCheckStringOverload renders check_permission(p_subject, p_relation,
+// p_object). memo marks it PARALLEL UNSAFE, as the memoized check_permission
+// it calls is.
+func generateCheckStringOverload(databaseSchema, delimiter string, memo bool) string {
+	fn := PlpgsqlFunction{
+		Schema: databaseSchema,
+		Name:   "check_permission",
+		Args: []FuncArg{
+			{Name: "p_subject", Type: "TEXT"},
+			{Name: "p_relation", Type: "TEXT"},
+			{Name: "p_object", Type: "TEXT"},
+		},
+		Returns: "INTEGER",
+		Body: []Stmt{
+			checkObjectString("p_subject", "subject", delimiter),
+			checkObjectString("p_object", "object", delimiter),
+			ReturnValue{Value: Func{Schema: databaseSchema, Name: "check_permission", Args: []Expr{
+				objectStringType("p_subject", delimiter), objectStringID("p_subject", delimiter),
+				Param("p_relation"),
+				objectStringType("p_object", delimiter), objectStringID("p_object", delimiter),
+			}}},
+		},
+		Header: []string{
+			"Generated dispatcher overload for check_permission",
+			"Takes subject and object as type:id strings",
+		},
+		NoSearchPath:   true,
+		ParallelUnsafe: memo,
+	}
+	return fn.SQL() + "\n"
+}
+
+// objectStringPaginationArgs are the optional trailing arguments of the list
+// string overloads, as on the tuple-column list dispatchers.
+func objectStringPaginationArgs() []FuncArg {
+	return []FuncArg{
+		{Name: "p_limit", Type: "INT", Default: Null{}},
+		{Name: "p_after", Type: "TEXT", Default: Null{}},
+	}
+}
+
+// generateListObjectsStringOverload renders list_accessible_objects(p_subject,
+// p_relation, p_object_type, p_limit, p_after).
+func generateListObjectsStringOverload(databaseSchema, delimiter string) string {
+	fn := PlpgsqlFunction{
+		Schema: databaseSchema,
+		Name:   "list_accessible_objects",
+		Args: append([]FuncArg{
+			{Name: "p_subject", Type: "TEXT"},
+			{Name: "p_relation", Type: "TEXT"},
+			{Name: "p_object_type", Type: "TEXT"},
+		}, objectStringPaginationArgs()...),
+		Returns: ListObjectsReturns(),
+		Body: []Stmt{
+			checkObjectString("p_subject", "subject", delimiter),
+			ReturnQuery{Query: "SELECT * FROM " + Func{Schema: databaseSchema, Name: "list_accessible_objects", Args: []Expr{
+				objectStringType("p_subject", delimiter), objectStringID("p_subject", delimiter),
+				Param("p_relation"), Param("p_object_type"), Param("p_limit"), Param("p_after"),
+			}}.SQL()},
+		},
+		Header: []string{
+			"Generated dispatcher overload for list_accessible_objects",
+			"Takes the subject as a type:id string",
+		},
+		NoSearchPath: true,
+	}
+	return fn.SQL() + "\n"
+}
+
+// generateListSubjectsStringOverload renders list_accessible_subjects(p_object,
+// p_relation, p_subject_type, p_limit, p_after).
+func generateListSubjectsStringOverload(databaseSchema, delimiter string) string {
+	fn := PlpgsqlFunction{
+		Schema: databaseSchema,
+		Name:   "list_accessible_subjects",
+		Args: append([]FuncArg{
+			{Name: "p_object", Type: "TEXT"},
+			{Name: "p_relation", Type: "TEXT"},
+			{Name: "p_subject_type", Type: "TEXT"},
+		}, objectStringPaginationArgs()...),
+		Returns: ListSubjectsReturns(),
+		Body: []Stmt{
+			checkObjectString("p_object", "object", delimiter),
+			ReturnQuery{Query: "SELECT * FROM " + Func{Schema: databaseSchema, Name: "list_accessible_subjects", Args: []Expr{
+				objectStringType("p_object", delimiter), objectStringID("p_object", delimiter),
+				Param("p_relation"), Param("p_subject_type"), Param("p_limit"), Param("p_after"),
+			}}.SQL()},
+		},
+		Header: []string{
+			"Generated dispatcher overload for list_accessible_subjects",
+			"Takes the object as a type:id string",
+		},
+		NoSearchPath: true,
+	}
+	return fn.SQL() + "\n"
+}
@@ -0,0 +1,66 @@
+package sqlgen
+
+import "testing"
+
+// The type:id overloads must validate their string arguments before splitting
+// them on the first delimiter and delegating to the tuple-column dispatchers.
+func TestObjectStringOverloads(t *testing.T) {
+	check := generateCheckStringOverload("authz", ":", false)
+	assertContains(t, check, `CREATE OR REPLACE FUNCTION "authz"."check_permission"(
+    p_subject TEXT,
+    p_relation TEXT,
+    p_object TEXT
+) RETURNS INTEGER`)
+	assertContains(t, check, `IF strpos(p_object, ':') <= 1 OR length(p_object) < strpos(p_object, ':') + 1 THEN
+        RAISE EXCEPTION USING ERRCODE = '22023', MESSAGE = 'malformed object ' || quote_literal(p_object) || ': expected type:id';`)
+	assertContains(t, check, `RETURN "authz"."check_permission"(split_part(p_subject, ':', 1), substr(p_subject, strpos(p_subject, ':') + 1), p_relation, split_part(p_object, ':', 1), substr(p_object, strpos(p_object, ':') + 1));`)
+	assertContains(t, check, "PARALLEL RESTRICTED")
+	assertNotContains(t, check, "SET search_path")
+	assertContains(t, generateCheckStringOverload("", ":", true), "PARALLEL UNSAFE")
+
+	objects := generateListObjectsStringOverload("", ":")
+	assertContains(t, objects, "p_subject TEXT,\n    p_relation TEXT,\n    p_object_type TEXT,\n    p_limit INT DEFAULT NULL,")
+	assertContains(t, objects, "SELECT * FROM list_accessible_objects(split_part(p_subject, ':', 1), substr(p_subject, strpos(p_subject, ':') + 1), p_relation, p_object_type, p_limit, p_after);")
+
+	subjects := generateListSubjectsStringOverload("", ":")
+	assertContains(t, subjects, "p_object TEXT,\n    p_relation TEXT,\n    p_subject_type TEXT,\n    p_limit INT DEFAULT NULL,")
+	assertContains(t, subjects, "SELECT * FROM list_accessible_subjects(split_part(p_object, ':', 1), substr(p_object, strpos(p_object, ':') + 1), p_relation, p_subject_type, p_limit, p_after);")
+}
+
+// A custom delimiter replaces ":" in validation, splitting and the error
+// message, and multi-character delimiters skip their full length.
+func TestObjectStringOverloadsDelimiter(t *testing.T) {
+	check := generateCheckStringOverload("", "::", false)
+	assertContains(t, check, `IF strpos(p_subject, '::') <= 1 OR length(p_subject) < strpos(p_subject, '::') + 2 THEN`)
+	assertContains(t, check, `|| ': expected type::id';`)
+	assertContains(t, check, `split_part(p_subject, '::', 1), substr(p_subject, strpos(p_subject, '::') + 2)`)
+
+	objects := generateListObjectsStringOverload("", "/")
+	assertContains(t, objects, `split_part(p_subject, '/', 1), substr(p_subject, strpos(p_subject, '/') + 1)`)
+	assertNotContains(t, objects, `':'`)
+
+	// A quote in the delimiter is escaped, not spliced into the SQL.
+	assertContains(t, generateListSubjectsStringOverload("", "'"), `strpos(p_object, '''')`)
+}
+
+func TestValidateObjectDelimiter(t *testing.T) {
+	for _, d := range []string{"", ":", "/", "::", "%"} {
+		if err := ValidateObjectDelimiter(d); err != nil {
+			t.Errorf("ValidateObjectDelimiter(%q) = %v, want nil", d, err)
+		}
+	}
+	for _, d := range []string{"#", ":#"} {
+		if err := ValidateObjectDelimiter(d); err == nil {
+			t.Errorf("ValidateObjectDelimiter(%q) = nil, want error", d)
+		}
+	}
+
+	_, err := GenerateSQLWithOptions(nil, InlineSQLData{}, "", GenerateSQLOptions{ObjectDelimiter: "#"})
+	if err == nil {
+		t.Error("GenerateSQLWithOptions accepted a \"#\" delimiter")
+	}
+	_, err = GenerateListSQLWithOptions(nil, InlineSQLData{}, "", GenerateSQLOptions{ObjectDelimiter: "#"})
+	if err == nil {
+		t.Error("GenerateListSQLWithOptions accepted a \"#\" delimiter")
+	}
+}
@@ -0,0 +1,86 @@
+package plpgsql
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Dialect names the database generated functions are created in. The zero
+// value renders for PostgreSQL.
+type Dialect string
+
+const (
+	// DialectPostgres renders for PostgreSQL. It is the default.
+	DialectPostgres Dialect = "postgres"
+
+	// DialectCockroach renders for CockroachDB, which accepts the generated
+	// PL/pgSQL and SQL bodies but not every PostgreSQL function option.
+	DialectCockroach Dialect = "cockroach"
+)
+
+// Dialects lists the supported dialects, default first.
+var Dialects = []Dialect{DialectPostgres, DialectCockroach}
+
+// Valid reports whether d is empty or one of Dialects.
+func (d Dialect) Valid() bool {
+	if d == "" {
+		return true
+	}
+	for _, known := range Dialects {
+		if d == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCockroach reports whether d renders for CockroachDB.
+func (d Dialect) IsCockroach() bool {
+	return d == DialectCockroach
+}
+
+var (
+	rowsEstimate  = regexp.MustCompile(` ROWS \d+ AS \$\$$`)
+	parallelLabel = regexp.MustCompile(` PARALLEL (SAFE|RESTRICTED|UNSAFE)`)
+	costEstimate  = regexp.MustCompile(` COST \d+`)
+)
+
+// Adapt rewrites SQL rendered by PlpgsqlFunction, SqlFunction,
+// CommentOnFunction and DropFunction for d. PostgreSQL output is returned
+// unchanged. For CockroachDB, which rejects these clauses, Adapt drops:
+//
+//   - the ROWS estimate of a set-returning function
+//   - the PARALLEL label and COST estimate
+//   - the SET search_path clause, so unqualified names in the body resolve
+//     through the caller's search_path
+//   - COMMENT ON FUNCTION statements, and with them the function metadata
+//
+// Adapt works line by line on the layout those renderers produce, so any
+// other SQL passes through untouched.
+func (d Dialect) Adapt(sql string) string {
+	if !d.IsCockroach() || sql == "" {
+		return sql
+	}
+	lines := strings.Split(sql, "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "COMMENT ON FUNCTION "):
+			continue
+		case strings.HasPrefix(line, ") RETURNS "):
+			line = rowsEstimate.ReplaceAllLiteralString(line, " AS $$")
+		case strings.HasPrefix(line, "$$ LANGUAGE "):
+			line = parallelLabel.ReplaceAllLiteralString(line, "")
+			line = costEstimate.ReplaceAllLiteralString(line, "")
+		case strings.HasPrefix(line, "SET search_path = ") && len(out) > 0 &&
+			strings.HasPrefix(out[len(out)-1], "$$ LANGUAGE "):
+			// The clause closes the statement: move its semicolon up.
+			if strings.HasSuffix(line, ";") {
+				out[len(out)-1] += ";"
+			}
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
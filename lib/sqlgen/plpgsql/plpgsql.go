@@ -163,6 +163,24 @@ func (f ForLoop) StmtSQL() string {
 	return sb.String()
 }
 
+// SQLSTATE codes the generated functions raise, one per failure category.
+// The melange runtime and the generated clients map each to a typed error, so
+// a raise site must use one of these rather than a literal. The M-prefixed
+// codes are melange's own; the rest are standard PostgreSQL codes.
+//
+// An unknown relation is deliberately not a failure: check functions deny it
+// and list functions list nothing, which the combinators (check_any,
+// list_accessible_objects_any) rely on.
+const (
+	ErrCodeResolutionTooComplex   = "M2002" // depth limit exceeded
+	ErrCodeRelationNotListable    = "M2003" // list dispatcher reached a relation without a list function
+	ErrCodeRelationNotExpandable  = "M2004" // expand dispatcher reached a relation without an expand function
+	ErrCodeSchemaHashMismatch     = "M2005" // p_expected_schema_hash differs from the hash the functions were generated with
+	ErrCodeNullArgument           = "22004" // null_value_not_allowed
+	ErrCodeInvalidArgument        = "22023" // invalid_parameter_value
+	ErrCodeNotInPrerequisiteState = "55000" // object_not_in_prerequisite_state
+)
+
 // Raise renders RAISE EXCEPTION 'message' USING ERRCODE = 'code';
 type Raise struct {
 	Message string
@@ -198,6 +216,19 @@ type PlpgsqlFunction struct {
 	// lets LANGUAGE sql wrappers inline and avoids a per-call GUC save/restore.
 	NoSearchPath bool
 	Cost         int // If non-zero, appends COST <n> so the planner treats this function as costlier than the default (100)
+	// ParallelUnsafe marks the function PARALLEL UNSAFE instead of RESTRICTED.
+	// Required when the body writes session state (set_config), which
+	// PostgreSQL rejects anywhere inside a parallel operation.
+	ParallelUnsafe bool
+	// Volatile marks the function VOLATILE PARALLEL UNSAFE instead of STABLE.
+	// Required when the body runs DDL, which PostgreSQL rejects in a
+	// non-volatile function.
+	Volatile bool
+	// NullGuards starts the body with a check of every argument without a
+	// default, raising 22004 (null_value_not_allowed) with the function and
+	// argument name when it is NULL. Arguments with a default, such as p_limit
+	// and p_after, stay nullable.
+	NullGuards bool
 }
 
 // SQL renders the complete CREATE OR REPLACE FUNCTION statement.
@@ -218,8 +249,13 @@ func (f PlpgsqlFunction) SQL() string {
 		}
 	}
 
+	body := f.Body
+	if f.NullGuards {
+		body = append(NullGuardStmts(f.Name, f.Args), body...)
+	}
+
 	sb.WriteString("BEGIN\n")
-	for _, stmt := range f.Body {
+	for _, stmt := range body {
 		for _, line := range strings.Split(stmt.StmtSQL(), "\n") {
 			sb.WriteString("    ")
 			sb.WriteString(line)
@@ -234,7 +270,12 @@ func (f PlpgsqlFunction) SQL() string {
 	// shadow — inaccessible to parallel workers — and the schema-qualified
 	// dispatchers/wrappers transitively call those leaves, so a SAFE marking would
 	// be unsound (a SAFE function may not call a RESTRICTED one).
-	sb.WriteString("$$ LANGUAGE plpgsql STABLE PARALLEL RESTRICTED")
+	if f.Volatile {
+		sb.WriteString("$$ LANGUAGE plpgsql VOLATILE ")
+	} else {
+		sb.WriteString("$$ LANGUAGE plpgsql STABLE ")
+	}
+	sb.WriteString(parallelMarking(f.ParallelUnsafe || f.Volatile))
 	if f.Cost > 0 {
 		fmt.Fprintf(&sb, " COST %d", f.Cost)
 	}
@@ -246,28 +287,157 @@ func (f PlpgsqlFunction) SQL() string {
 	return sb.String()
 }
 
+// NullGuardStmts returns one IF per argument of args without a default that
+// raises ErrCodeNullArgument when the argument is NULL. Without it
+// a NULL argument compares as unknown everywhere it is used, and the function
+// silently denies or lists nothing.
+func NullGuardStmts(name string, args []FuncArg) []Stmt {
+	var stmts []Stmt
+	for _, arg := range args {
+		if arg.Default != nil {
+			continue
+		}
+		stmts = append(stmts, If{
+			Cond: sqldsl.IsNull{Expr: sqldsl.Param(arg.Name)},
+			Then: []Stmt{Raise{Message: name + ": " + arg.Name + " must not be NULL", ErrCode: ErrCodeNullArgument}},
+		})
+	}
+	return stmts
+}
+
+// CommentOnFunction renders a COMMENT ON FUNCTION statement for the function
+// with the given signature. Argument types identify the overload, so args
+// must match the CREATE statement's.
+func CommentOnFunction(schema, name string, args []FuncArg, comment string) string {
+	var sb strings.Builder
+	sb.WriteString("COMMENT ON FUNCTION ")
+	sb.WriteString(sqldsl.PrefixIdent(name, schema))
+	sb.WriteString("(")
+	for i, arg := range args {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(arg.Type)
+	}
+	sb.WriteString(") IS ")
+	sb.WriteString(sqldsl.QuoteLiteral(comment))
+	sb.WriteString(";")
+	return sb.String()
+}
+
+// DropFunction renders DROP FUNCTION IF EXISTS for the overload of name taking
+// args' types.
+func DropFunction(schema, name string, args []FuncArg) string {
+	var sb strings.Builder
+	sb.WriteString("DROP FUNCTION IF EXISTS ")
+	sb.WriteString(sqldsl.PrefixIdent(name, schema))
+	sb.WriteString("(")
+	for i, arg := range args {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(arg.Type)
+	}
+	sb.WriteString(");")
+	return sb.String()
+}
+
 // =============================================================================
 // Convenience Constructors
 // =============================================================================
 
 // ListObjectsArgs returns the standard arguments for a list_objects function.
+// p_subject_types narrows the tuples granting the relation to those whose
+// subject type is listed; p_exclude_relation drops the objects on which the
+// subject also holds that relation. p_context is the request context the
+// relation's conditional grants are evaluated against; NULL lists none of
+// them, as check_permission allows none. p_consistency is the caller's
+// consistency preference, 'strong' or 'eventual'; see ConsistencyArg.
 func ListObjectsArgs() []FuncArg {
 	return []FuncArg{
 		{Name: "p_subject_type", Type: "TEXT"},
 		{Name: "p_subject_id", Type: "TEXT"},
 		{Name: "p_limit", Type: "INT", Default: sqldsl.Null{}},
 		{Name: "p_after", Type: "TEXT", Default: sqldsl.Null{}},
+		{Name: "p_subject_types", Type: "TEXT[]", Default: sqldsl.Null{}},
+		{Name: "p_exclude_relation", Type: "TEXT", Default: sqldsl.Null{}},
+		{Name: "p_context", Type: "JSONB", Default: sqldsl.Null{}},
+		ConsistencyArg(),
 	}
 }
 
 // ListSubjectsArgs returns the standard arguments for a list_subjects function.
+// p_expand_wildcard asks for a wildcard grant to be returned as the concrete
+// subjects it covers rather than as '*'. p_context and p_consistency are as
+// in ListObjectsArgs.
 func ListSubjectsArgs() []FuncArg {
 	return []FuncArg{
 		{Name: "p_object_id", Type: "TEXT"},
 		{Name: "p_subject_type", Type: "TEXT"},
 		{Name: "p_limit", Type: "INT", Default: sqldsl.Null{}},
 		{Name: "p_after", Type: "TEXT", Default: sqldsl.Null{}},
+		{Name: "p_expand_wildcard", Type: "BOOLEAN", Default: sqldsl.Bool(false)},
+		{Name: "p_context", Type: "JSONB", Default: sqldsl.Null{}},
+		ConsistencyArg(),
+	}
+}
+
+// ConsistencyArg returns p_consistency, the caller's consistency preference
+// ('strong' or 'eventual', as in OpenFGA). The functions taking it ignore
+// it: they read whatever database they run on, so the client honours the
+// preference by choosing the connection, the primary for 'strong'. It is
+// accepted so that clients can pass the preference through unchanged.
+func ConsistencyArg() FuncArg {
+	return FuncArg{Name: "p_consistency", Type: "TEXT", Default: sqldsl.Null{}}
+}
+
+// CallArgs builds named-notation arguments for calling a function with the
+// given signature. Arguments are emitted in signature order; parameters with a
+// Default may be omitted from values and fall back to it. A missing required
+// parameter or a value for a parameter not in the signature panics: both are
+// generator bugs, not model errors.
+func CallArgs(sig []FuncArg, values map[string]sqldsl.Expr) []sqldsl.Expr {
+	args := make([]sqldsl.Expr, 0, len(sig))
+	used := 0
+	for _, p := range sig {
+		v, ok := values[p.Name]
+		if !ok {
+			if p.Default == nil {
+				panic("plpgsql: missing required argument " + p.Name)
+			}
+			continue
+		}
+		args = append(args, sqldsl.NamedArg{Name: p.Name, Value: v})
+		used++
+	}
+	if used != len(values) {
+		for name := range values {
+			if !hasArg(sig, name) {
+				panic("plpgsql: unknown argument " + name)
+			}
+		}
 	}
+	return args
+}
+
+// ForwardArgs builds named-notation arguments that pass every parameter of
+// sig through unchanged, for wrappers and dispatchers whose own parameters
+// share the callee's names.
+func ForwardArgs(sig []FuncArg) []sqldsl.Expr {
+	args := make([]sqldsl.Expr, len(sig))
+	for i, p := range sig {
+		args[i] = sqldsl.NamedArg{Name: p.Name, Value: sqldsl.Param(p.Name)}
+	}
+	return args
+}
+
+func hasArg(sig []FuncArg, name string) bool {
+	for _, p := range sig {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
 }
 
 // ListObjectsReturns returns the standard RETURNS clause for list_objects.
@@ -305,6 +475,10 @@ func ListObjectsDispatcherArgs() []FuncArg {
 		{Name: "p_object_type", Type: "TEXT"},
 		{Name: "p_limit", Type: "INT", Default: sqldsl.Null{}},
 		{Name: "p_after", Type: "TEXT", Default: sqldsl.Null{}},
+		{Name: "p_subject_types", Type: "TEXT[]", Default: sqldsl.Null{}},
+		{Name: "p_exclude_relation", Type: "TEXT", Default: sqldsl.Null{}},
+		{Name: "p_context", Type: "JSONB", Default: sqldsl.Null{}},
+		ConsistencyArg(),
 	}
 }
 
@@ -317,6 +491,9 @@ func ListSubjectsDispatcherArgs() []FuncArg {
 		{Name: "p_subject_type", Type: "TEXT"},
 		{Name: "p_limit", Type: "INT", Default: sqldsl.Null{}},
 		{Name: "p_after", Type: "TEXT", Default: sqldsl.Null{}},
+		{Name: "p_expand_wildcard", Type: "BOOLEAN", Default: sqldsl.Bool(false)},
+		{Name: "p_context", Type: "JSONB", Default: sqldsl.Null{}},
+		ConsistencyArg(),
 	}
 }
 
@@ -337,6 +514,12 @@ type SqlFunction struct {
 	// NoSearchPath omits the SET search_path clause. See PlpgsqlFunction.NoSearchPath.
 	// For LANGUAGE sql wrappers this is what allows the planner to inline them.
 	NoSearchPath bool
+	// ParallelUnsafe marks the function PARALLEL UNSAFE. See PlpgsqlFunction.ParallelUnsafe.
+	ParallelUnsafe bool
+	// Immutable marks the function IMMUTABLE PARALLEL SAFE instead of STABLE.
+	// Only for bodies that read no tables or settings, such as constant
+	// VALUES lookups; ParallelUnsafe is ignored.
+	Immutable bool
 }
 
 // SQL renders the complete CREATE OR REPLACE FUNCTION statement as LANGUAGE sql.
@@ -349,7 +532,12 @@ func (f SqlFunction) SQL() string {
 	sb.WriteString("    ")
 	sb.WriteString(f.Body.SQL())
 	sb.WriteString(";\n")
-	sb.WriteString("$$ LANGUAGE sql STABLE PARALLEL RESTRICTED")
+	if f.Immutable {
+		sb.WriteString("$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE")
+	} else {
+		sb.WriteString("$$ LANGUAGE sql STABLE ")
+		sb.WriteString(parallelMarking(f.ParallelUnsafe))
+	}
 	if !f.NoSearchPath {
 		writeSearchPath(&sb, f.SearchPath, f.Schema)
 	}
@@ -358,6 +546,14 @@ func (f SqlFunction) SQL() string {
 	return sb.String()
 }
 
+// parallelMarking returns the PARALLEL clause for a generated function.
+func parallelMarking(unsafe bool) string {
+	if unsafe {
+		return "PARALLEL UNSAFE"
+	}
+	return "PARALLEL RESTRICTED"
+}
+
 // writeSearchPath appends a SET search_path clause to the function definition.
 // If searchPath is empty, it defaults to schema — generated functions always
 // need their schema in the search path so that unqualified melange_tuples
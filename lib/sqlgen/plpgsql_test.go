@@ -230,8 +230,11 @@ func TestPlpgsqlFunction(t *testing.T) {
 
 func TestListObjectsHelpers(t *testing.T) {
 	args := ListObjectsArgs()
-	if len(args) != 4 {
-		t.Errorf("ListObjectsArgs() = %d args, want 4", len(args))
+	if len(args) != 8 {
+		t.Errorf("ListObjectsArgs() = %d args, want 8", len(args))
+	}
+	if last := args[len(args)-1]; last.Name != "p_consistency" || last.Default == nil {
+		t.Errorf("last ListObjectsArgs() arg = %+v, want p_consistency with a default", last)
 	}
 
 	returns := ListObjectsReturns()
@@ -242,8 +245,11 @@ func TestListObjectsHelpers(t *testing.T) {
 
 func TestListSubjectsHelpers(t *testing.T) {
 	args := ListSubjectsArgs()
-	if len(args) != 4 {
-		t.Errorf("ListSubjectsArgs() = %d args, want 4", len(args))
+	if len(args) != 7 {
+		t.Errorf("ListSubjectsArgs() = %d args, want 7", len(args))
+	}
+	if last := args[len(args)-1]; last.Name != "p_consistency" || last.Default == nil {
+		t.Errorf("last ListSubjectsArgs() arg = %+v, want p_consistency with a default", last)
 	}
 
 	returns := ListSubjectsReturns()
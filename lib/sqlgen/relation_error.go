@@ -0,0 +1,26 @@
+package sqlgen
+
+import "fmt"
+
+// RelationError reports that one relation's function could not be
+// generated. GenerateSQL and GenerateListSQL return it wrapped, so a caller
+// can find the failing relation with errors.As and generate the rest of the
+// model without it (see DependentRelations).
+type RelationError struct {
+	ObjectType string
+	Relation   string
+	// Kind is the FunctionKind* of the function that failed.
+	Kind string
+	Err  error
+}
+
+func (e *RelationError) Error() string {
+	return fmt.Sprintf("generating %s function for %s.%s: %v", e.Kind, e.ObjectType, e.Relation, e.Err)
+}
+
+func (e *RelationError) Unwrap() error { return e.Err }
+
+// relationError wraps err, the failure to generate a's kind function.
+func relationError(a RelationAnalysis, kind string, err error) error {
+	return &RelationError{ObjectType: a.ObjectType, Relation: a.Relation, Kind: kind, Err: err}
+}
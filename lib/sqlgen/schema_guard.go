@@ -0,0 +1,55 @@
+package sqlgen
+
+import "github.com/pthm/melange/lib/sqlgen/sqldsl"
+
+// SchemaHashOverloads maps each dispatcher that also has an overload taking
+// p_expected_schema_hash to the argument types of that overload. As with
+// ObjectStringOverloads, callers dropping these dispatchers by name drop the
+// overload by signature first.
+var SchemaHashOverloads = map[string]string{
+	"check_permission": "TEXT, TEXT, TEXT, TEXT, TEXT, TEXT, TEXT",
+}
+
+// generateSchemaHashOverload renders check_permission with a trailing
+// p_expected_schema_hash, which raises ErrCodeSchemaHashMismatch unless it
+// equals schemaHash, the hash embedded at generation time. A client passing
+// the hash of the schema it was built against fails fast on version skew
+// instead of being routed by functions generated from another schema. A NULL
+// p_expected_schema_hash skips the comparison. It is an overload rather than
+// a defaulted argument because adding an argument to the installed
+// five-argument check_permission would leave both in place and make every
+// five-argument call ambiguous. A trailing p_consistency, ignored as on the
+// list functions (see ConsistencyArg), lets a client pass its consistency
+// preference to a check. memo marks it PARALLEL UNSAFE, as the memoized
+// check_permission it calls is.
+func generateSchemaHashOverload(databaseSchema, schemaHash string, memo bool) string {
+	args := dispatcherPublicArgs()
+	callArgs := make([]Expr, len(args))
+	for i, arg := range args {
+		callArgs[i] = Param(arg.Name)
+	}
+	args = append(args, FuncArg{Name: "p_expected_schema_hash", Type: "TEXT"}, ConsistencyArg())
+
+	fn := PlpgsqlFunction{
+		Schema:  databaseSchema,
+		Name:    "check_permission",
+		Args:    args,
+		Returns: "INTEGER",
+		Body: []Stmt{
+			If{
+				Cond: Ne{Left: Param("p_expected_schema_hash"), Right: Lit(schemaHash)},
+				Then: []Stmt{RawStmt{SQLText: "RAISE EXCEPTION USING ERRCODE = '" + ErrCodeSchemaHashMismatch +
+					"', MESSAGE = 'schema hash mismatch: expected ' || quote_literal(p_expected_schema_hash) || " +
+					sqldsl.QuoteLiteral(", installed functions were generated from "+schemaHash) + ";"}},
+			},
+			ReturnValue{Value: Func{Schema: databaseSchema, Name: "check_permission", Args: callArgs}},
+		},
+		Header: []string{
+			"Generated dispatcher overload for check_permission",
+			"Raises " + ErrCodeSchemaHashMismatch + " when p_expected_schema_hash is not the schema hash it was generated with",
+		},
+		NoSearchPath:   true,
+		ParallelUnsafe: memo,
+	}
+	return fn.SQL() + "\n"
+}
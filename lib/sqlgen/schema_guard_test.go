@@ -0,0 +1,45 @@
+package sqlgen
+
+import "testing"
+
+// The schema hash overload compares p_expected_schema_hash with the embedded
+// hash before delegating to the five-argument dispatcher, and is emitted only
+// when GenerateSQLOptions.SchemaHash is set.
+func TestSchemaHashOverload(t *testing.T) {
+	check := generateSchemaHashOverload("authz", "abc123", false)
+	assertContains(t, check, `CREATE OR REPLACE FUNCTION "authz"."check_permission"(
+    p_subject_type TEXT,
+    p_subject_id TEXT,
+    p_relation TEXT,
+    p_object_type TEXT,
+    p_object_id TEXT,
+    p_expected_schema_hash TEXT,
+    p_consistency TEXT DEFAULT NULL
+) RETURNS INTEGER`)
+	assertContains(t, check, `IF p_expected_schema_hash <> 'abc123' THEN
+        RAISE EXCEPTION USING ERRCODE = 'M2005', MESSAGE = 'schema hash mismatch: expected ' || quote_literal(p_expected_schema_hash) || ', installed functions were generated from abc123';`)
+	assertContains(t, check, `RETURN "authz"."check_permission"(p_subject_type, p_subject_id, p_relation, p_object_type, p_object_id);`)
+	assertNotContains(t, check, "SET search_path")
+	assertContains(t, generateSchemaHashOverload("", "abc123", true), "PARALLEL UNSAFE")
+
+	analyses, inline := compileForCacheTest(t, `model
+  schema 1.1
+
+type user
+
+type doc
+  relations
+    define viewer: [user]
+`)
+	gen, err := GenerateSQLWithOptions(analyses, inline, "", GenerateSQLOptions{SchemaHash: "abc123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, gen.Dispatcher, "p_expected_schema_hash TEXT")
+
+	gen, err = GenerateSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNotContains(t, gen.Dispatcher, "p_expected_schema_hash")
+}
@@ -38,7 +38,7 @@ func TestSourceRelationCheck_SemiJoinWhenComposable(t *testing.T) {
 
 	sql := sourceRelationCheck(sourceRelationPlan(lookup), sourceParent).SQL()
 
-	if !strings.Contains(sql, "child.object_id IN (SELECT src_obj.object_id FROM list_doc_editor_obj(p_subject_type, p_subject_id, NULL, NULL) src_obj)") {
+	if !strings.Contains(sql, "child.object_id IN (SELECT src_obj.object_id FROM list_doc_editor_obj(p_subject_type => p_subject_type, p_subject_id => p_subject_id) src_obj)") {
 		t.Errorf("expected set-oriented semi-join against list_doc_editor_obj, got:\n%s", sql)
 	}
 	// Userset-typed subjects keep a guarded per-candidate check for parity.
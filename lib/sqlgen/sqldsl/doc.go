@@ -38,11 +38,16 @@
 //	Not(expr)                         // NOT (expr)
 //	Exists{Query: subquery}           // EXISTS (subquery)
 //
+// String functions:
+//
+//	SplitPart{Source: col, Delim: "#", Field: 1} // split_part(col, '#', 1)
+//	StrPos{Source: col, Substr: "#"}            // position('#' in col)
+//
 // Authorization-specific helpers:
 //
 //	SubjectIDMatch(col, id, wildcard) // Match subject_id with optional wildcard
 //	HasUserset{Source: col}           // Check if subject_id contains '#'
-//	UsersetObjectID{Source: col}      // Extract object ID from userset (split_part)
+//	UsersetObjectID{Source: col}      // Extract object ID from userset (before the last '#')
 //
 // # Statement Types
 //
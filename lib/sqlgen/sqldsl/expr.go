@@ -179,6 +179,33 @@ func (p Position) SQL() string {
 	return "position(" + p.Needle.SQL() + " in " + p.Haystack.SQL() + ")"
 }
 
+// SplitPart represents SQL split_part(source, delim, field).
+// Returns the field-th (1-based) piece of source split on delim.
+type SplitPart struct {
+	Source Expr
+	Delim  string
+	Field  int
+}
+
+// SQL renders the split_part expression.
+func (s SplitPart) SQL() string {
+	return "split_part(" + s.Source.SQL() + ", " + Lit(s.Delim).SQL() + ", " + Int(s.Field).SQL() + ")"
+}
+
+// StrPos represents SQL position(substr in source).
+// Returns the 1-based position of the first occurrence of substr, or 0.
+// Unlike Position, the needle is always a literal, which keeps delimiter
+// lookups structural rather than interpolated.
+type StrPos struct {
+	Source Expr
+	Substr string
+}
+
+// SQL renders the position expression.
+func (p StrPos) SQL() string {
+	return "position(" + Lit(p.Substr).SQL() + " in " + p.Source.SQL() + ")"
+}
+
 // Substring represents SQL substring(source from start [for length]).
 // If For is nil, renders substring(source from start).
 // If For is provided, renders substring(source from start for length).
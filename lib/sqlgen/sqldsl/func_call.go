@@ -1,5 +1,20 @@
 package sqldsl
 
+// NamedArg is a function argument in named notation (name => value).
+// Calls between generated functions use it so that adding or reordering
+// parameters in a signature cannot silently shift positional arguments.
+//
+// Renders: p_subject_id => t.subject_id
+type NamedArg struct {
+	Name  string
+	Value Expr
+}
+
+// SQL renders the named argument.
+func (n NamedArg) SQL() string {
+	return n.Name + " => " + n.Value.SQL()
+}
+
 // FuncCallEq compares a function call result to a value.
 // Used for authorization check expressions like "check_permission(...) = 1".
 //
@@ -157,12 +172,12 @@ func InternalCheckCall(schema string, subjectType, subjectID Expr, relation stri
 //	    Expr:       Col{Table: "t", Column: "subject_id"},
 //	    Schema:     "public",
 //	    FuncName:   "list_doc_viewer_obj",
-//	    Args:       []Expr{SubjectType, SubjectID, Null{}, Null{}},
+//	    Args:       []Expr{SubjectType, SubjectID},
 //	    Alias:      "obj",
 //	    SelectCol:  "object_id",
 //	}
 //
-// Renders: t.subject_id IN (SELECT obj.object_id FROM list_doc_viewer_obj(p_subject_type, p_subject_id, NULL, NULL) obj)
+// Renders: t.subject_id IN (SELECT obj.object_id FROM list_doc_viewer_obj(p_subject_type, p_subject_id) obj)
 type InFunctionSelect struct {
 	Expr      Expr // The expression to check (left side of IN)
 	Schema    string
@@ -198,11 +213,25 @@ func (i InCTESelect) SQL() string {
 }
 
 // ListObjectsFunctionName generates a list_TYPE_RELATION_obj function name.
+// It ignores case collisions; see ListObjectsFunctionNameWithCollisions.
 func ListObjectsFunctionName(objectType, relation string) string {
-	return SafeIdentifier("list_", objectType, relation, "_obj")
+	return ListObjectsFunctionNameWithCollisions(nil, objectType, relation)
+}
+
+// ListObjectsFunctionNameWithCollisions is ListObjectsFunctionName with the
+// hashed name RelationIdentifier gives relations listed in collisions.
+func ListObjectsFunctionNameWithCollisions(collisions NameCollisions, objectType, relation string) string {
+	return RelationIdentifier(collisions, "list_", objectType, relation, "_obj")
 }
 
 // ListSubjectsFunctionName generates a list_TYPE_RELATION_sub function name.
+// It ignores case collisions; see ListSubjectsFunctionNameWithCollisions.
 func ListSubjectsFunctionName(objectType, relation string) string {
-	return SafeIdentifier("list_", objectType, relation, "_sub")
+	return ListSubjectsFunctionNameWithCollisions(nil, objectType, relation)
+}
+
+// ListSubjectsFunctionNameWithCollisions is ListSubjectsFunctionName with the
+// hashed name RelationIdentifier gives relations listed in collisions.
+func ListSubjectsFunctionNameWithCollisions(collisions NameCollisions, objectType, relation string) string {
+	return RelationIdentifier(collisions, "list_", objectType, relation, "_sub")
 }
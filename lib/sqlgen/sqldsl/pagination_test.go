@@ -81,3 +81,66 @@ func TestWrapWithExclusionCTEAndPaginationOpts(t *testing.T) {
 		t.Errorf("opt-off variant still needs the exclusion CTE; got: %s", matOff)
 	}
 }
+
+// The paged CTE casts the id column so cursor comparison, ordering, and the
+// returned column type do not depend on the tuples column type.
+func TestPagination_CastsIDColumnToText(t *testing.T) {
+	out := WrapWithPagination("SELECT 1", "object_id")
+	for _, want := range []string{
+		"SELECT DISTINCT br.object_id::TEXT AS object_id",
+		"br.object_id::TEXT > p_after",
+		"ORDER BY br.object_id::TEXT",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q; got: %s", want, out)
+		}
+	}
+
+	for _, out := range []string{
+		WrapWithPaginationWildcardFirst("SELECT 1"),
+		WrapWithExclusionCTEAndPagination("SELECT 1", "SELECT 1"),
+	} {
+		for _, want := range []string{
+			"SELECT br.subject_id::TEXT AS subject_id",
+			"br.subject_id::TEXT) >",
+			"END), br.subject_id::TEXT",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("missing %q; got: %s", want, out)
+			}
+		}
+	}
+}
+
+// Pages are stable: each id appears once, rows come back in cursor order, and
+// an empty cursor starts from the beginning like NULL.
+func TestPagination_StableCursor(t *testing.T) {
+	out := WrapWithPagination("SELECT 1 AS object_id UNION ALL SELECT 1", "object_id")
+	for _, want := range []string{
+		"SELECT DISTINCT br.object_id::TEXT",
+		"WHERE (NULLIF(p_after, '') IS NULL OR",
+		"CROSS JOIN next n\n    ORDER BY r.object_id",
+		"THEN (SELECT max(r.object_id) FROM returned r)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q; got: %s", want, out)
+		}
+	}
+
+	for _, out := range []string{
+		WrapWithPaginationWildcardFirst("SELECT 1"),
+		WrapWithExclusionCTEAndPagination("SELECT 1", "SELECT 1"),
+	} {
+		for _, want := range []string{
+			"WHERE NULLIF(p_after, '') IS NULL OR (",
+			"CROSS JOIN next n\n    ORDER BY (CASE WHEN r.subject_id = '*' THEN 0 ELSE 1 END), r.subject_id",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("missing %q; got: %s", want, out)
+			}
+		}
+	}
+	if out := WrapWithPaginationWildcardFirst("SELECT 1"); !strings.Contains(out, "FROM (SELECT DISTINCT subject_id FROM base_results) br") {
+		t.Errorf("wildcard-first wrapper must dedupe base_results; got: %s", out)
+	}
+}
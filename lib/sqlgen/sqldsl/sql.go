@@ -446,10 +446,22 @@ const PostgresMaxIdentifierLength = 63
 //	SafeIdentifier("check_", "user", "admin", "_nw")          → "check_user_admin_nw"
 //	SafeIdentifier("check_", "my_group", "<very long relation>", "_nw") → "check_my_gro_<truncated>_d70fe023_nw"
 func SafeIdentifier(prefix, objectType, relation, suffix string) string {
+	return safeIdentifier(prefix, objectType, relation, suffix, false)
+}
+
+// RelationIdentifier is SafeIdentifier for a relation's generated function
+// names. Relations listed in collisions always get the hash, whatever the
+// length, so two relations whose names fold to the same identifier
+// ("Viewer" and "viewer") get distinct functions.
+func RelationIdentifier(collisions NameCollisions, prefix, objectType, relation, suffix string) string {
+	return safeIdentifier(prefix, objectType, relation, suffix, collisions.Hashed(objectType, relation))
+}
+
+func safeIdentifier(prefix, objectType, relation, suffix string, forceHash bool) string {
 	typePart := Ident(objectType)
 	relPart := Ident(relation)
 	full := prefix + typePart + "_" + relPart + suffix
-	if len(full) <= PostgresMaxIdentifierLength {
+	if !forceHash && len(full) <= PostgresMaxIdentifierLength {
 		return full
 	}
 
@@ -477,6 +489,17 @@ func SafeIdentifier(prefix, objectType, relation, suffix string) string {
 	return prefix + typePart + "_" + relPart + "_" + hash + suffix
 }
 
+// NameCollisions maps object type -> relation -> true for the relations
+// whose generated function names carry a hash because Ident folds their
+// type and relation to the same identifier as another relation's. A nil
+// map hashes nothing.
+type NameCollisions map[string]map[string]bool
+
+// Hashed reports whether (objectType, relation) is listed.
+func (c NameCollisions) Hashed(objectType, relation string) bool {
+	return c[objectType][relation]
+}
+
 // truncateProportionally splits a character budget between two strings
 // proportional to their original lengths, trimming trailing underscores
 // from each truncated part (but never reducing a part to empty).
@@ -684,6 +707,30 @@ func IndentLines(input, indent string) string {
 // =============================================================================
 // Pagination Helpers
 // =============================================================================
+//
+// The paged CTE casts the id column to TEXT before comparing it with p_after,
+// ordering by it, and returning it. base_results may carry the tuples view's
+// own column type (varchar, citext), and without the cast the cursor compare
+// (resolved as text) and the ORDER BY (the column type's ordering) could
+// disagree, skipping or repeating rows across pages; RETURN QUERY also
+// rejects a varchar column for a TEXT result. For text columns the cast is a
+// no-op.
+//
+// Pages are stable: ids are returned in cursor order, each id at most once
+// (paged selects DISTINCT, as base_results may be a UNION ALL of blocks), and
+// the next cursor is the last id returned, so the following page resumes
+// strictly after it whatever tuples change in between. An empty cursor, like
+// NULL, starts from the beginning; see PaginationCursor.
+
+// PaginationCursor is the cursor the pagination wrappers test for absence:
+// p_after, with an empty string treated as no cursor. Unpaged fast paths
+// test it for NULL so they take the same view of "from the start".
+const PaginationCursor = "NULLIF(p_after, '')"
+
+// TuplesColumnTypeNote is the SQL comment migration headers carry about
+// melange_tuples column types. See the pagination notes above.
+const TuplesColumnTypeNote = "-- melange_tuples columns are compared as TEXT: expose them as text or varchar;\n" +
+	"-- cast citext, char(n), uuid, and integer columns with ::text in the view.\n"
 
 // materializedKeyword returns " MATERIALIZED" when materialize is true, else empty.
 // Used in pagination CTE templates to force PostgreSQL to compute multi-referenced
@@ -711,10 +758,10 @@ func WrapWithPaginationOpts(query, idColumn string, materialize bool) string {
 %s
     ),
     paged AS%s (
-        SELECT br.%s
+        SELECT DISTINCT br.%s::TEXT AS %s
         FROM base_results br
-        WHERE (p_after IS NULL OR br.%s > p_after)
-        ORDER BY br.%s
+        WHERE (%s IS NULL OR br.%s::TEXT > p_after)
+        ORDER BY br.%s::TEXT
         LIMIT CASE WHEN p_limit IS NULL THEN NULL ELSE p_limit + 1 END
     ),
     returned AS%s (
@@ -728,9 +775,11 @@ func WrapWithPaginationOpts(query, idColumn string, materialize bool) string {
     )
     SELECT r.%s, n.next_cursor
     FROM returned r
-    CROSS JOIN next n`,
-		IndentLines(query, "        "), mat, idColumn, idColumn, idColumn,
-		mat, idColumn, idColumn, idColumn, idColumn)
+    CROSS JOIN next n
+    ORDER BY r.%s`,
+		IndentLines(query, "        "), mat, idColumn, idColumn,
+		PaginationCursor, idColumn, idColumn,
+		mat, idColumn, idColumn, idColumn, idColumn, idColumn)
 }
 
 // WrapWithPaginationWildcardFirst wraps a query for list_subjects with wildcard-first ordering.
@@ -748,15 +797,15 @@ func WrapWithPaginationWildcardFirstOpts(query string, materialize bool) string
 %s
     ),
     paged AS%s (
-        SELECT br.subject_id
-        FROM base_results br
-        WHERE p_after IS NULL OR (
+        SELECT br.subject_id::TEXT AS subject_id
+        FROM (SELECT DISTINCT subject_id FROM base_results) br
+        WHERE `+PaginationCursor+` IS NULL OR (
             -- Compound comparison for wildcard-first ordering:
             -- (is_not_wildcard, subject_id) > (cursor_is_not_wildcard, cursor)
-            (CASE WHEN br.subject_id = '*' THEN 0 ELSE 1 END, br.subject_id) >
+            (CASE WHEN br.subject_id = '*' THEN 0 ELSE 1 END, br.subject_id::TEXT) >
             (CASE WHEN p_after = '*' THEN 0 ELSE 1 END, p_after)
         )
-        ORDER BY (CASE WHEN br.subject_id = '*' THEN 0 ELSE 1 END), br.subject_id
+        ORDER BY (CASE WHEN br.subject_id = '*' THEN 0 ELSE 1 END), br.subject_id::TEXT
         LIMIT CASE WHEN p_limit IS NULL THEN NULL ELSE p_limit + 1 END
     ),
     returned AS%s (
@@ -774,7 +823,8 @@ func WrapWithPaginationWildcardFirstOpts(query string, materialize bool) string
     )
     SELECT r.subject_id, n.next_cursor
     FROM returned r
-    CROSS JOIN next n`,
+    CROSS JOIN next n
+    ORDER BY (CASE WHEN r.subject_id = '*' THEN 0 ELSE 1 END), r.subject_id`,
 		IndentLines(query, "        "), mat, mat)
 }
 
@@ -824,15 +874,15 @@ func WrapWithExclusionCTEAndPaginationOpts(query, exclusionCTE string, materiali
         WHERE excl.subject_id IS NULL
     ),
     paged AS%s (
-        SELECT br.subject_id
+        SELECT br.subject_id::TEXT AS subject_id
         FROM base_results br
-        WHERE p_after IS NULL OR (
+        WHERE `+PaginationCursor+` IS NULL OR (
             -- Compound comparison for wildcard-first ordering:
             -- (is_not_wildcard, subject_id) > (cursor_is_not_wildcard, cursor)
-            (CASE WHEN br.subject_id = '*' THEN 0 ELSE 1 END, br.subject_id) >
+            (CASE WHEN br.subject_id = '*' THEN 0 ELSE 1 END, br.subject_id::TEXT) >
             (CASE WHEN p_after = '*' THEN 0 ELSE 1 END, p_after)
         )
-        ORDER BY (CASE WHEN br.subject_id = '*' THEN 0 ELSE 1 END), br.subject_id
+        ORDER BY (CASE WHEN br.subject_id = '*' THEN 0 ELSE 1 END), br.subject_id::TEXT
         LIMIT CASE WHEN p_limit IS NULL THEN NULL ELSE p_limit + 1 END
     ),
     returned AS%s (
@@ -850,7 +900,8 @@ func WrapWithExclusionCTEAndPaginationOpts(query, exclusionCTE string, materiali
     )
     SELECT r.subject_id, n.next_cursor
     FROM returned r
-    CROSS JOIN next n`,
+    CROSS JOIN next n
+    ORDER BY (CASE WHEN r.subject_id = '*' THEN 0 ELSE 1 END), r.subject_id`,
 		IndentLines(exclusionCTE, "        "),
 		IndentLines(query, "        "), mat, mat)
 }
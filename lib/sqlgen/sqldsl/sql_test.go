@@ -115,6 +115,25 @@ func TestSafeIdentifier_AllUnderscoreType(t *testing.T) {
 	}
 }
 
+func TestRelationIdentifier(t *testing.T) {
+	collisions := NameCollisions{"document": {"Viewer": true}}
+
+	if got := RelationIdentifier(collisions, "check_", "document", "viewer", ""); got != "check_document_viewer" {
+		t.Errorf("unlisted relation = %q, want check_document_viewer", got)
+	}
+	if got, want := RelationIdentifier(nil, "check_", "document", "Viewer", "_nw"), SafeIdentifier("check_", "document", "Viewer", "_nw"); got != want {
+		t.Errorf("nil collisions = %q, want SafeIdentifier's %q", got, want)
+	}
+
+	got := RelationIdentifier(collisions, "check_", "document", "Viewer", "_nw")
+	if !strings.HasPrefix(got, "check_document_viewer_") || !strings.HasSuffix(got, "_nw") || len(got) != len("check_document_viewer__nw")+8 {
+		t.Errorf("listed relation = %q, want check_document_viewer_<hash>_nw", got)
+	}
+	if got == RelationIdentifier(collisions, "check_", "document", "viewer", "_nw") {
+		t.Errorf("Viewer and viewer both named %q", got)
+	}
+}
+
 func TestTruncateProportionally_WithinBudget(t *testing.T) {
 	a, b := truncateProportionally("short", "name", 20)
 	if a != "short" || b != "name" {
@@ -197,3 +216,24 @@ func TestRenderUnionBlocks_DropsRedundantDistinct(t *testing.T) {
 		t.Errorf("caller's *SelectStmt was mutated; Distinct should stay true")
 	}
 }
+
+func TestSubjectIDMatchByType(t *testing.T) {
+	col := Col{Table: "t", Column: "subject_id"}
+
+	// Types that agree collapse to a single SubjectIDMatch.
+	if got, want := SubjectIDMatchByType(SubjectType, col, SubjectID, []string{"user"}, []string{"user"}).SQL(),
+		SubjectIDMatch(col, SubjectID, true).SQL(); got != want {
+		t.Errorf("all wildcard = %s, want %s", got, want)
+	}
+	if got, want := SubjectIDMatchByType(SubjectType, col, SubjectID, []string{"user", "service"}, nil).SQL(),
+		SubjectIDMatch(col, SubjectID, false).SQL(); got != want {
+		t.Errorf("no wildcard = %s, want %s", got, want)
+	}
+
+	got := SubjectIDMatchByType(SubjectType, col, SubjectID, []string{"user", "service"}, []string{"user"}).SQL()
+	want := "((p_subject_type = 'user' AND (t.subject_id = p_subject_id OR t.subject_id = '*')) OR " +
+		"(p_subject_type = 'service' AND (t.subject_id = p_subject_id AND NOT (t.subject_id = '*'))))"
+	if got != want {
+		t.Errorf("mixed = %s, want %s", got, want)
+	}
+}
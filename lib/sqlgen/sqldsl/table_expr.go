@@ -36,6 +36,34 @@ func TableAs(schema, name, alias string) TableRef {
 	return TableRef{Schema: schema, Name: name, Alias: alias}
 }
 
+// DefaultTuplesTable is the relation generated SQL reads tuples from unless
+// GenerateSQLOptions.TuplesTable names another.
+const DefaultTuplesTable = "melange_tuples"
+
+// TuplesTableName returns name, or DefaultTuplesTable when name is empty.
+// The name is rendered as written, never quoted or prefixed with the
+// database schema: the default stays unqualified so that a pg_temp view can
+// shadow it for contextual tuples, and a configured name may carry its own
+// schema ("tenant_42.tuples").
+func TuplesTableName(name string) string {
+	if name == "" {
+		return DefaultTuplesTable
+	}
+	return name
+}
+
+// SubjectsView is the relation GenerateSQLOptions.ExpandWildcardSubjects
+// reads the known subjects of each type from, with columns subject_type and
+// subject_id. Like the default tuples relation, it is rendered unqualified
+// and resolves through the function's search_path.
+const SubjectsView = "melange_subjects"
+
+// TuplesTableAs creates a reference to the tuples relation name (see
+// TuplesTableName) with an alias.
+func TuplesTableAs(name, alias string) TableRef {
+	return TableRef{Name: TuplesTableName(name), Alias: alias}
+}
+
 // FunctionCallExpr represents a function call that can be used as a table expression.
 // Used for LATERAL joins with table-returning functions.
 type FunctionCallExpr struct {
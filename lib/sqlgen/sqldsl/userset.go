@@ -2,23 +2,32 @@ package sqldsl
 
 // Userset operations for extracting components from userset identifiers.
 // Format: "object_id#relation" (e.g., "group:1#member")
-
-// UsersetObjectID extracts the object ID: "group:1#member" -> "group:1"
+//
+// Relation names never contain '#', but object IDs may, so a userset splits
+// on its last '#': "doc#v2#viewer" is object "doc#v2", relation "viewer".
+// Both halves are found from strpos on the reversed string, which PostgreSQL
+// and CockroachDB support alike (unlike split_part with a negative field).
+
+// UsersetObjectID extracts the object ID: "group:1#member" -> "group:1".
+// A value without '#' is returned unchanged.
 type UsersetObjectID struct {
 	Source Expr
 }
 
 func (u UsersetObjectID) SQL() string {
-	return "split_part(" + u.Source.SQL() + ", '#', 1)"
+	src := u.Source.SQL()
+	return "left(" + src + ", length(" + src + ") - " + lastHashOffset(u.Source) + ")"
 }
 
-// UsersetRelation extracts the relation: "group:1#member" -> "member"
+// UsersetRelation extracts the relation: "group:1#member" -> "member".
+// A value without '#' yields the empty string.
 type UsersetRelation struct {
 	Source Expr
 }
 
 func (u UsersetRelation) SQL() string {
-	return "split_part(" + u.Source.SQL() + ", '#', 2)"
+	src := u.Source.SQL()
+	return "substring(" + src + " from length(" + src + ") - " + lastHashOffset(u.Source) + " + 2)"
 }
 
 // HasUserset checks if an expression contains a userset marker (#).
@@ -50,7 +59,13 @@ func (w IsWildcard) SQL() string {
 
 // hashPosition returns the SQL for finding '#' position in an expression.
 func hashPosition(expr Expr) string {
-	return "position('#' in " + expr.SQL() + ")"
+	return StrPos{Source: expr, Substr: "#"}.SQL()
+}
+
+// lastHashOffset returns the SQL for the 1-based position of the last '#' in
+// an expression counted from its end, 0 when there is none.
+func lastHashOffset(expr Expr) string {
+	return "strpos(reverse(" + expr.SQL() + "), '#')"
 }
 
 // SubjectIDMatch creates a condition for matching subject IDs.
@@ -64,6 +79,35 @@ func SubjectIDMatch(column, subjectID Expr, allowWildcard bool) Expr {
 	return And(exactMatch, Not(IsWildcard{Source: column}))
 }
 
+// SubjectIDMatchByType is SubjectIDMatch for tuples of several subject types
+// where only wildcardTypes allow wildcards, as in [user:*, service]. When the
+// types disagree it ORs one arm per type, pairing subjectType = type with the
+// match that type allows; otherwise it is SubjectIDMatch for the shared flag.
+func SubjectIDMatchByType(subjectType, column, subjectID Expr, types, wildcardTypes []string) Expr {
+	allowed := make(map[string]bool, len(wildcardTypes))
+	for _, t := range wildcardTypes {
+		allowed[t] = true
+	}
+	mixed := false
+	for _, t := range types {
+		if allowed[t] != allowed[types[0]] {
+			mixed = true
+			break
+		}
+	}
+	if !mixed {
+		return SubjectIDMatch(column, subjectID, len(types) > 0 && allowed[types[0]])
+	}
+	arms := make([]Expr, 0, len(types))
+	for _, t := range types {
+		arms = append(arms, And(
+			Eq{Left: subjectType, Right: Lit(t)},
+			SubjectIDMatch(column, subjectID, allowed[t]),
+		))
+	}
+	return Or(arms...)
+}
+
 // UsersetNormalized replaces the relation in a userset with a new relation.
 // Example: "group:1#admin" with relation "member" -> "group:1#member"
 type UsersetNormalized struct {
@@ -72,14 +116,11 @@ type UsersetNormalized struct {
 }
 
 func (u UsersetNormalized) SQL() string {
-	src := u.Source.SQL()
-	pos := hashPosition(u.Source)
-	objectID := "substring(" + src + " from 1 for " + pos + " - 1)"
-	return objectID + " || '#' || " + u.Relation.SQL()
+	return UsersetObjectID{Source: u.Source}.SQL() + " || '#' || " + u.Relation.SQL()
 }
 
 // NormalizedUsersetSubject combines the object_id from a userset with a new relation.
-// Example: split_part(subject_id, '#', 1) || '#' || v_filter_relation
+// Example: "group:1#admin" with v_filter_relation -> "group:1#" || v_filter_relation
 func NormalizedUsersetSubject(subjectID, relation Expr) Expr {
 	return Concat{Parts: []Expr{
 		UsersetObjectID{Source: subjectID},
@@ -1,6 +1,8 @@
 package sqlgen
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -172,12 +174,12 @@ func TestUsersetOperations(t *testing.T) {
 		{
 			name:   "userset object id",
 			expr:   UsersetObjectID{Source: Col{Table: "t", Column: "subject_id"}},
-			expect: "split_part(t.subject_id, '#', 1)",
+			expect: "left(t.subject_id, length(t.subject_id) - strpos(reverse(t.subject_id), '#'))",
 		},
 		{
 			name:   "userset relation",
 			expr:   UsersetRelation{Source: Col{Table: "t", Column: "subject_id"}},
-			expect: "split_part(t.subject_id, '#', 2)",
+			expect: "substring(t.subject_id from length(t.subject_id) - strpos(reverse(t.subject_id), '#') + 2)",
 		},
 		{
 			name:   "has userset",
@@ -245,6 +247,20 @@ func TestStringFunctions(t *testing.T) {
 			expect: "''",
 		},
 
+		// SplitPart
+		{
+			name:   "split_part",
+			expr:   SplitPart{Source: Col{Table: "t", Column: "subject_id"}, Delim: "#", Field: 1},
+			expect: "split_part(t.subject_id, '#', 1)",
+		},
+
+		// StrPos
+		{
+			name:   "strpos",
+			expr:   StrPos{Source: SubjectType, Substr: "#"},
+			expect: "position('#' in p_subject_type)",
+		},
+
 		// Position
 		{
 			name:   "position",
@@ -280,12 +296,12 @@ func TestStringFunctions(t *testing.T) {
 		{
 			name:   "userset normalized",
 			expr:   UsersetNormalized{Source: Col{Table: "t", Column: "subject_id"}, Relation: Raw("v_filter_relation")},
-			expect: "substring(t.subject_id from 1 for position('#' in t.subject_id) - 1) || '#' || v_filter_relation",
+			expect: "left(t.subject_id, length(t.subject_id) - strpos(reverse(t.subject_id), '#')) || '#' || v_filter_relation",
 		},
 		{
 			name:   "userset normalized with literal",
 			expr:   UsersetNormalized{Source: SubjectID, Relation: Lit("member")},
-			expect: "substring(p_subject_id from 1 for position('#' in p_subject_id) - 1) || '#' || 'member'",
+			expect: "left(p_subject_id, length(p_subject_id) - strpos(reverse(p_subject_id), '#')) || '#' || 'member'",
 		},
 	}
 
@@ -356,7 +372,7 @@ func TestCheckAccessHelpers(t *testing.T) {
 }
 
 func TestTupleQueryBasic(t *testing.T) {
-	q := Tuples("", "t").
+	q := TuplesWithTable("", "", "t").
 		ObjectType("document").
 		Relations("viewer", "editor").
 		Select("t.object_id").
@@ -380,7 +396,7 @@ func TestTupleQueryBasic(t *testing.T) {
 }
 
 func TestTupleQueryWithSubjectFilters(t *testing.T) {
-	q := Tuples("", "t").
+	q := TuplesWithTable("", "", "t").
 		ObjectType("document").
 		Relations("viewer").
 		WhereSubjectType(SubjectType).
@@ -404,7 +420,7 @@ func TestTupleQueryWithSubjectFilters(t *testing.T) {
 }
 
 func TestTupleQueryWithJoin(t *testing.T) {
-	q := Tuples("", "t").
+	q := TuplesWithTable("", "", "t").
 		ObjectType("document").
 		Select("t.object_id").
 		JoinTuples("m",
@@ -417,7 +433,7 @@ func TestTupleQueryWithJoin(t *testing.T) {
 	checks := []string{
 		"INNER JOIN melange_tuples AS m ON",
 		"m.object_type = 'group'",
-		"split_part(t.subject_id, '#', 1)",
+		"left(t.subject_id, length(t.subject_id) - strpos(reverse(t.subject_id), '#'))",
 	}
 	for _, check := range checks {
 		if !strings.Contains(sql, check) {
@@ -427,7 +443,7 @@ func TestTupleQueryWithJoin(t *testing.T) {
 }
 
 func TestTupleQueryWithUserset(t *testing.T) {
-	q := Tuples("", "t").
+	q := TuplesWithTable("", "", "t").
 		ObjectType("document").
 		Relations("viewer").
 		WhereHasUserset().
@@ -438,7 +454,7 @@ func TestTupleQueryWithUserset(t *testing.T) {
 
 	checks := []string{
 		"position('#' in t.subject_id) > 0",
-		"split_part(t.subject_id, '#', 2) = 'member'",
+		"substring(t.subject_id from length(t.subject_id) - strpos(reverse(t.subject_id), '#') + 2) = 'member'",
 	}
 	for _, check := range checks {
 		if !strings.Contains(sql, check) {
@@ -448,7 +464,7 @@ func TestTupleQueryWithUserset(t *testing.T) {
 }
 
 func TestExistsNotExists(t *testing.T) {
-	q := Tuples("", "excl").
+	q := TuplesWithTable("", "", "excl").
 		ObjectType("document").
 		Relations("blocked").
 		Select("1")
@@ -939,7 +955,7 @@ func TestBuildInlineCheckExpr_SchemaQualification(t *testing.T) {
 			DirectSubjectTypes:  []string{"user"},
 			SatisfyingRelations: []string{"viewer"},
 		}
-		sql := buildInlineCheckExpr(c, rSubjectType, rSubjectID, rObjectID).SQL()
+		sql := buildInlineCheckExpr(c, "", rSubjectType, rSubjectID, rObjectID).SQL()
 		if !strings.Contains(sql, "FROM melange_tuples AS t") {
 			t.Errorf("expected unqualified melange_tuples, got:\n%s", sql)
 		}
@@ -954,7 +970,7 @@ func TestBuildInlineCheckExpr_SchemaQualification(t *testing.T) {
 			DirectSubjectTypes:  []string{"user"},
 			SatisfyingRelations: []string{"viewer"},
 		}
-		sql := buildInlineCheckExpr(c, rSubjectType, rSubjectID, rObjectID).SQL()
+		sql := buildInlineCheckExpr(c, "", rSubjectType, rSubjectID, rObjectID).SQL()
 		// melange_tuples must be unqualified so pg_temp can shadow it for contextual tuples
 		if !strings.Contains(sql, "FROM melange_tuples AS t") {
 			t.Errorf("expected unqualified melange_tuples (for pg_temp shadow), got:\n%s", sql)
@@ -969,9 +985,38 @@ func TestBuildInlineCheckExpr_SchemaQualification(t *testing.T) {
 			CheckFunctionName: "check_document_viewer",
 			Inlineable:        false,
 		}
-		sql := buildInlineCheckExpr(c, rSubjectType, rSubjectID, rObjectID).SQL()
+		sql := buildInlineCheckExpr(c, "", rSubjectType, rSubjectID, rObjectID).SQL()
 		if !strings.Contains(sql, `"authz"."check_document_viewer"`) {
 			t.Errorf("expected schema-qualified function call, got:\n%s", sql)
 		}
 	})
 }
+
+// With a database schema every call between generated functions is
+// schema-qualified, including the recursive list_accessible_subjects LATERAL
+// calls, so dispatchers resolve whatever the caller's search_path.
+func TestGeneratedCalls_SchemaQualification(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, cacheTestSchema)
+	unqualified := regexp.MustCompile(`(^|[^"\w])((check|list|explain|expand)_\w+|effective_access|melange_(closure_rows|userset_rows))\s*\(`)
+	for _, opts := range []GenerateSQLOptions{
+		{},
+		{EnableEffectiveAccess: true, EnableCheckEvidence: true, EnableCheckMemo: true},
+		{TableRoutedDispatcher: true, ClosureFunction: true, AnytimeListObjects: true},
+	} {
+		check, err := GenerateSQLWithOptions(analyses, inline, "authz", opts)
+		if err != nil {
+			t.Fatalf("GenerateSQLWithOptions: %v", err)
+		}
+		list, err := GenerateListSQLWithOptions(analyses, inline, "authz", opts)
+		if err != nil {
+			t.Fatalf("GenerateListSQLWithOptions: %v", err)
+		}
+		all := fmt.Sprint(check, list)
+		assertContains(t, all, `CROSS JOIN LATERAL "authz"."list_accessible_subjects"(`)
+		for _, line := range strings.Split(all, "\n") {
+			if code, _, _ := strings.Cut(line, "--"); unqualified.MatchString(code) {
+				t.Errorf("%+v: unqualified call: %s", opts, strings.TrimSpace(line))
+			}
+		}
+	}
+}
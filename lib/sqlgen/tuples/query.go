@@ -5,8 +5,10 @@ import (
 	"github.com/pthm/melange/lib/sqlgen/sqldsl"
 )
 
-// TupleQuery is a fluent builder for queries against melange_tuples.
+// TupleQuery is a fluent builder for queries against the tuples relation
+// (melange_tuples unless configured otherwise).
 type TupleQuery struct {
+	table       string
 	schema      string
 	alias       string
 	objectType  string
@@ -19,15 +21,29 @@ type TupleQuery struct {
 	limit       int
 }
 
-// Tuples creates a new TupleQuery with the given table alias.
+// Tuples creates a new TupleQuery against sqldsl.DefaultTuplesTable with the
+// given table alias.
 func Tuples(schema, alias string) *TupleQuery {
+	return TuplesWithTable("", schema, alias)
+}
+
+// TuplesWithTable creates a new TupleQuery reading table with the given
+// alias. An empty table selects sqldsl.DefaultTuplesTable; see
+// sqldsl.TuplesTableName.
+func TuplesWithTable(table, schema, alias string) *TupleQuery {
 	return &TupleQuery{
+		table:   sqldsl.TuplesTableName(table),
 		schema:  schema,
 		alias:   alias,
 		columns: []string{}, // Default empty, will be set with Select()
 	}
 }
 
+// Table returns the tuples relation the query reads.
+func (q *TupleQuery) Table() string {
+	return q.table
+}
+
 // Schema returns the query's schema.
 func (q *TupleQuery) Schema() string {
 	return q.schema
@@ -193,12 +209,13 @@ func (q *TupleQuery) addJoin(joinType, table, alias string, on []sqldsl.Expr) *T
 	return q
 }
 
-// JoinTuples adds an INNER JOIN to melange_tuples with the given alias.
-// melange_tuples is always unqualified so that pg_temp can shadow it for contextual tuples.
+// JoinTuples adds an INNER JOIN to the query's tuples relation with the given
+// alias. The relation is never prefixed with the schema so that pg_temp can
+// shadow the default melange_tuples for contextual tuples.
 func (q *TupleQuery) JoinTuples(alias string, on ...sqldsl.Expr) *TupleQuery {
 	q.joins = append(q.joins, sqldsl.JoinClause{
 		Type:  "INNER",
-		Table: "melange_tuples",
+		Table: q.table,
 		Alias: alias,
 		On:    sqldsl.And(on...),
 	})
@@ -233,7 +250,7 @@ func (q *TupleQuery) Build() sqldsl.SelectStmt {
 
 	stmt := sqldsl.SelectStmt{
 		Distinct: q.distinct,
-		FromExpr: sqldsl.TableAs("", "melange_tuples", q.alias),
+		FromExpr: sqldsl.TableAs("", q.table, q.alias),
 		Joins:    q.joins,
 		Where:    whereExpr,
 		Limit:    q.limit,
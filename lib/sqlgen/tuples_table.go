@@ -0,0 +1,44 @@
+package sqlgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+)
+
+// tuplesTableRe matches the names GenerateSQLOptions.TuplesTable accepts: a
+// lowercase identifier, optionally qualified by a lowercase schema. Generated
+// SQL renders the name unquoted (see TuplesTableName), so anything PostgreSQL
+// would fold or need quoting for is rejected rather than silently changed.
+var tuplesTableRe = regexp.MustCompile(`^[a-z_][a-z0-9_$]*(\.[a-z_][a-z0-9_$]*)?$`)
+
+// ValidateTuplesTable reports whether name can be used as the tuples
+// relation of generated SQL. The empty string selects DefaultTuplesTable.
+func ValidateTuplesTable(name string) error {
+	if name == "" {
+		return nil
+	}
+	if !tuplesTableRe.MatchString(name) {
+		return fmt.Errorf("tuples table %q must be a lowercase identifier, optionally schema-qualified (e.g. %q)", name, "tenant_42.tuples")
+	}
+	for _, part := range strings.Split(name, ".") {
+		if len(part) > sqldsl.PostgresMaxIdentifierLength {
+			return fmt.Errorf("tuples table %q: %q exceeds PostgreSQL's %d-byte identifier limit", name, part, sqldsl.PostgresMaxIdentifierLength)
+		}
+	}
+	return nil
+}
+
+// SplitTuplesTable splits a tuples table name into its schema, empty when
+// the name is unqualified, and relation name. The empty name is
+// DefaultTuplesTable. Callers looking an unqualified name up in the catalog
+// use their database schema.
+func SplitTuplesTable(name string) (schema, relation string) {
+	name = TuplesTableName(name)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
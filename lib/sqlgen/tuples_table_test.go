@@ -0,0 +1,91 @@
+package sqlgen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateTuplesTable(t *testing.T) {
+	for _, name := range []string{"", "melange_tuples", "tuples", "tenant_42.tuples", "_t$1"} {
+		if err := ValidateTuplesTable(name); err != nil {
+			t.Errorf("ValidateTuplesTable(%q) = %v, want nil", name, err)
+		}
+	}
+	long := strings.Repeat("t", 64)
+	for _, name := range []string{"Tuples", "a.b.c", ".tuples", "tuples.", "1tuples", `"tuples"`, "tuples; drop", long, "s." + long} {
+		if err := ValidateTuplesTable(name); err == nil {
+			t.Errorf("ValidateTuplesTable(%q) = nil, want error", name)
+		}
+	}
+
+	_, err := GenerateSQLWithOptions(nil, InlineSQLData{}, "", GenerateSQLOptions{TuplesTable: "a.b.c"})
+	if err == nil {
+		t.Error("GenerateSQLWithOptions accepted an invalid tuples table")
+	}
+	_, err = GenerateListSQLWithOptions(nil, InlineSQLData{}, "", GenerateSQLOptions{TuplesTable: "a.b.c"})
+	if err == nil {
+		t.Error("GenerateListSQLWithOptions accepted an invalid tuples table")
+	}
+}
+
+func TestSplitTuplesTable(t *testing.T) {
+	tests := []struct{ in, schema, relation string }{
+		{"", "", DefaultTuplesTable},
+		{"tuples", "", "tuples"},
+		{"tenant_42.tuples", "tenant_42", "tuples"},
+	}
+	for _, tt := range tests {
+		schema, relation := SplitTuplesTable(tt.in)
+		if schema != tt.schema || relation != tt.relation {
+			t.Errorf("SplitTuplesTable(%q) = %q, %q, want %q, %q", tt.in, schema, relation, tt.schema, tt.relation)
+		}
+	}
+}
+
+// Every generated function reads the configured relation, including the
+// opt-in audit functions, and none falls back to melange_tuples.
+func TestGenerateSQLTuplesTable(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, cacheTestSchema)
+	opts := GenerateSQLOptions{
+		TuplesTable:           "tenant_42.tuples",
+		EnableEffectiveAccess: true,
+		EnableCheckEvidence:   true,
+	}
+	check, err := GenerateSQLWithOptions(analyses, inline, "authz", opts)
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	list, err := GenerateListSQLWithOptions(analyses, inline, "authz", opts)
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+
+	all := fmt.Sprint(check, list)
+	assertContains(t, all, "FROM tenant_42.tuples AS ")
+	assertNotContains(t, all, "melange_tuples")
+
+	// Expand qualifies an unqualified name with the database schema, as it
+	// does melange_tuples.
+	check, err = GenerateSQLWithOptions(analyses, inline, "authz", GenerateSQLOptions{TuplesTable: "tuples"})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	assertContains(t, strings.Join(check.ExpandFunctions, "\n"), `"authz"."tuples"`)
+	assertNotContains(t, fmt.Sprint(check), "melange_tuples")
+}
+
+func TestRecommendIndexesTuplesTable(t *testing.T) {
+	analyses, _ := compileForCacheTest(t, cacheTestSchema)
+	recs := RecommendIndexesWithTable(analyses, "tenant_42.tuples")
+	if len(recs) == 0 {
+		t.Fatal("no recommendations")
+	}
+	for _, rec := range recs {
+		if rec.BaseTable != "tenant_42.tuples" {
+			t.Errorf("BaseTable = %q, want tenant_42.tuples", rec.BaseTable)
+		}
+		assertContains(t, rec.DDL, " ON tenant_42.tuples ")
+		assertContains(t, rec.DDL, "idx_tuples_")
+	}
+}
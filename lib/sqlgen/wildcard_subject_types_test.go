@@ -0,0 +1,58 @@
+package sqlgen
+
+import "testing"
+
+const wildcardSubjectTypesSchema = `model
+  schema 1.1
+
+type user
+
+type service
+
+type document
+  relations
+    define viewer: [user:*, service]
+    define reader: [user] or viewer
+    define public: [user:*]
+`
+
+func TestWildcardSubjectTypes_PerTypeDirectMatch(t *testing.T) {
+	analyses, inline := compileForCacheTest(t, wildcardSubjectTypesSchema)
+	for _, a := range analyses {
+		if a.ObjectType != "document" {
+			continue
+		}
+		want := "user"
+		if got := a.ClosureWildcardSubjectTypes; len(got) != 1 || got[0] != want {
+			t.Errorf("%s.ClosureWildcardSubjectTypes = %v, want [%s]", a.Relation, got, want)
+		}
+	}
+
+	gen, err := GenerateSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateSQLWithOptions: %v", err)
+	}
+	list, err := GenerateListSQLWithOptions(analyses, inline, "", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateListSQLWithOptions: %v", err)
+	}
+
+	userArm := "(p_subject_type = 'user' AND (subject_id = p_subject_id OR subject_id = '*'))"
+	serviceArm := "(p_subject_type = 'service' AND (subject_id = p_subject_id AND NOT (subject_id = '*')))"
+	for _, name := range []string{"check_document_viewer", "check_document_reader"} {
+		fn := functionNamed(t, gen.Functions, name)
+		assertContains(t, fn, userArm)
+		assertContains(t, fn, serviceArm)
+	}
+
+	// Only user is allowed, so the shared flag still applies.
+	public := functionNamed(t, gen.Functions, "check_document_public")
+	assertContains(t, public, "(subject_id = p_subject_id OR subject_id = '*')")
+	assertNotContains(t, public, "p_subject_type = 'user' AND")
+
+	objects := functionNamed(t, list.ListObjectsFunctions, "list_document_viewer_obj")
+	assertContains(t, objects, "(p_subject_type = 'service' AND (t.subject_id = p_subject_id AND NOT (t.subject_id = '*')))")
+
+	subjects := functionNamed(t, list.ListSubjectsFunctions, "list_document_viewer_sub")
+	assertContains(t, subjects, "(t.subject_id <> '*' OR p_subject_type IN ('user'))")
+}
@@ -117,12 +117,12 @@ func (b *BulkCheckBuilder) Execute() (*BulkCheckResults, error) {
 	for i := range b.requests {
 		r := &b.requests[i]
 		if c.validateUserset {
-			if err := c.validateUsersetSubject(ctx, c.q, r.subject.FGASubject()); err != nil {
+			if err := c.validateUsersetSubject(ctx, c.querier(ctx), r.subject.FGASubject()); err != nil {
 				return nil, fmt.Errorf("request %d: %w", i, err)
 			}
 		}
 		if c.validateRequest {
-			if err := c.validateCheckRequest(ctx, c.q, r.subject.FGASubject(), r.relation.FGARelation(), r.object.FGAObject()); err != nil {
+			if err := c.validateCheckRequest(ctx, c.querier(ctx), r.subject.FGASubject(), r.relation.FGARelation(), r.object.FGAObject()); err != nil {
 				return nil, fmt.Errorf("request %d: %w", i, err)
 			}
 		}
@@ -163,7 +163,7 @@ func (b *BulkCheckBuilder) Execute() (*BulkCheckResults, error) {
 	uniqueResults := make(map[int]checkOutcome, len(unique))
 	var uncachedKeys []int // indices into unique that need SQL
 	for i, key := range unique {
-		if c.cache != nil {
+		if c.cache != nil && readCache(ctx) {
 			subj := Object{Type: ObjectType(key.subjectType), ID: key.subjectID}
 			rel := Relation(key.relation)
 			obj := Object{Type: ObjectType(key.objectType), ID: key.objectID}
@@ -176,7 +176,7 @@ func (b *BulkCheckBuilder) Execute() (*BulkCheckResults, error) {
 	}
 
 	// 7. Determine querier — contextual tuples need connection pinning.
-	q := c.q
+	q := c.querier(ctx)
 	if len(b.contextualTuples) > 0 {
 		execer, cleanup, err := c.prepareContextualTuples(ctx, b.contextualTuples)
 		if err != nil {
@@ -234,7 +234,7 @@ func (b *BulkCheckBuilder) Execute() (*BulkCheckResults, error) {
 		}
 
 		// 9. Cache store for DB results.
-		if c.cache != nil {
+		if c.cache != nil && c.writeCache(ctx) {
 			for _, zeroIdx := range uncachedKeys {
 				hit, ok := uniqueResults[zeroIdx]
 				if !ok {
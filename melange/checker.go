@@ -58,7 +58,8 @@ func validateSchema(q Querier, databaseSchema string) {
 
 // Checker performs authorization checks against PostgreSQL.
 // It evaluates permissions using generated SQL functions and the
-// melange_tuples view (application data).
+// melange_tuples view (application data), or the relation named by
+// WithTuplesTable.
 //
 // Checkers are lightweight and safe to create per-request. They hold no state
 // beyond the database handle, cache, and decision override. The database handle
@@ -71,6 +72,7 @@ func validateSchema(q Querier, databaseSchema string) {
 // schema is not yet fully configured.
 type Checker struct {
 	q                  Querier
+	replica            Querier
 	cache              Cache
 	decision           Decision
 	useContextDecision bool
@@ -78,6 +80,9 @@ type Checker struct {
 	validateRequest    bool
 	validator          Validator
 	databaseSchema     string
+	tuplesTable        string
+	poolerSafe         bool
+	expectedSchemaHash string
 
 	// tuplesSchema caches the result of lookupTuplesSchema. The schema does
 	// not move during the Checker's lifetime, so the lookup query (a join
@@ -166,6 +171,59 @@ func WithDatabaseSchema(databaseSchema string) Option {
 	}
 }
 
+// WithTuplesTable names the tuples relation the generated functions read,
+// when they were generated with a TuplesTable other than melange_tuples (see
+// migrator.MigrateOptions.TuplesTable). The Checker needs it to shadow that
+// relation with contextual tuples and to recognize a missing relation as
+// ErrNoTuplesTable.
+//
+// Contextual tuples require an unqualified name: a schema-qualified relation
+// such as "tenant_42.tuples" cannot be shadowed by a temp view, so contextual
+// checks fail with ErrContextualTuplesUnsupported.
+func WithTuplesTable(name string) Option {
+	return func(ch *Checker) {
+		ch.tuplesTable = name
+	}
+}
+
+// WithPoolerSafe confines contextual tuples to a single transaction, for
+// databases reached through a transaction-mode pooler such as PgBouncer.
+//
+// By default contextual tuples live in a session-scoped temp view on a
+// dedicated connection. Under transaction pooling each transaction on that
+// connection may land on a different server backend, so the view can vanish
+// between setup and check, or linger for the next client. With this option,
+// when the Querier is a *sql.DB or *sql.Conn, setup and checks run inside one
+// transaction that is rolled back afterwards, discarding the view with it.
+// A *sql.Tx Querier is already transaction-scoped and is used as-is.
+func WithPoolerSafe() Option {
+	return func(ch *Checker) {
+		ch.poolerSafe = true
+	}
+}
+
+// WithReplica sets a read replica for reads whose context carries
+// ConsistencyEventual (see WithConsistency). Other reads, and every read
+// with contextual tuples, use the Querier passed to NewChecker.
+func WithReplica(q Querier) Option {
+	return func(ch *Checker) {
+		ch.replica = q
+	}
+}
+
+// WithExpectedSchemaHash makes Check pass hash to check_permission as
+// p_expected_schema_hash. If the installed functions were generated from a
+// schema with a different hash, Check fails with ErrSchemaHashMismatch rather
+// than answering from stale routing, e.g. when an application ships a schema
+// change before `melange migrate` has run. Use schema.SchemaHash of the types
+// the application was built against, the hash melange_healthcheck reports.
+// Only Check is guarded; list, bulk and explain calls are not.
+func WithExpectedSchemaHash(hash string) Option {
+	return func(ch *Checker) {
+		ch.expectedSchemaHash = hash
+	}
+}
+
 // NewChecker creates a checker that works with *sql.DB, *sql.Tx, or *sql.Conn.
 // Options allow callers to enable caching or decision overrides.
 //
@@ -224,19 +282,19 @@ func (c *Checker) Check(ctx context.Context, subject SubjectLike, relation Relat
 	}
 
 	if c.validateUserset {
-		if err := c.validateUsersetSubject(ctx, c.q, subject.FGASubject()); err != nil {
+		if err := c.validateUsersetSubject(ctx, c.querier(ctx), subject.FGASubject()); err != nil {
 			return false, err
 		}
 	}
 
 	if c.validateRequest {
-		if err := c.validateCheckRequest(ctx, c.q, subject.FGASubject(), relation.FGARelation(), object.FGAObject()); err != nil {
+		if err := c.validateCheckRequest(ctx, c.querier(ctx), subject.FGASubject(), relation.FGARelation(), object.FGAObject()); err != nil {
 			return false, err
 		}
 	}
 
 	// Check cache if available
-	if c.cache != nil {
+	if c.cache != nil && readCache(ctx) {
 		if allowed, cachedErr, found := c.cache.Get(subject.FGASubject(), relation.FGARelation(), object.FGAObject()); found {
 			return allowed, cachedErr
 		}
@@ -246,7 +304,7 @@ func (c *Checker) Check(ctx context.Context, subject SubjectLike, relation Relat
 	allowed, err := c.checkPermission(ctx, subject.FGASubject(), relation.FGARelation(), object.FGAObject())
 
 	// Store in cache if available (only cache successful checks - don't cache errors)
-	if c.cache != nil && err == nil {
+	if c.cache != nil && err == nil && c.writeCache(ctx) {
 		c.cache.Set(subject.FGASubject(), relation.FGARelation(), object.FGAObject(), allowed, nil)
 	}
 
@@ -292,13 +350,13 @@ func (c *Checker) CheckWithContextualTuples(
 	}
 
 	if c.validateUserset {
-		if err := c.validateUsersetSubject(ctx, c.q, subject.FGASubject()); err != nil {
+		if err := c.validateUsersetSubject(ctx, c.querier(ctx), subject.FGASubject()); err != nil {
 			return false, err
 		}
 	}
 
 	if c.validateRequest {
-		if err := c.validateCheckRequest(ctx, c.q, subject.FGASubject(), relation.FGARelation(), object.FGAObject()); err != nil {
+		if err := c.validateCheckRequest(ctx, c.querier(ctx), subject.FGASubject(), relation.FGARelation(), object.FGAObject()); err != nil {
 			return false, err
 		}
 	}
@@ -316,6 +374,33 @@ func (c *Checker) CheckWithContextualTuples(
 	return c.checkPermissionWithQuerier(ctx, execer, subject.FGASubject(), relation.FGARelation(), object.FGAObject())
 }
 
+// querier returns the handle for a read: the replica when one is configured
+// and ctx carries ConsistencyEventual, the primary otherwise.
+func (c *Checker) querier(ctx context.Context) Querier {
+	if c.readsReplica(ctx) {
+		return c.replica
+	}
+	return c.q
+}
+
+// readsReplica reports whether querier routes a read to the replica.
+func (c *Checker) readsReplica(ctx context.Context) bool {
+	return c.replica != nil && GetConsistency(ctx) == ConsistencyEventual
+}
+
+// readCache reports whether a read may be served from the cache. Strong reads
+// must see writes made since the result was cached.
+func readCache(ctx context.Context) bool {
+	return GetConsistency(ctx) != ConsistencyStrong
+}
+
+// writeCache reports whether the result of a read may be cached. A replica
+// may lag the primary, and the cache also serves reads that must not see
+// that lag, so only results read from the primary are cached.
+func (c *Checker) writeCache(ctx context.Context) bool {
+	return !c.readsReplica(ctx)
+}
+
 // checkPermission calls the PostgreSQL check_permission function.
 // This is the low-level implementation that maps to the stored procedure.
 //
@@ -328,23 +413,33 @@ func (c *Checker) CheckWithContextualTuples(
 // PostgreSQL errors are mapped to sentinel errors (ErrNoTuplesTable)
 // for easier error handling in application code.
 func (c *Checker) checkPermission(ctx context.Context, subject Object, relation Relation, object Object) (bool, error) {
-	return c.checkPermissionWithQuerier(ctx, c.q, subject, relation, object)
+	return c.checkPermissionWithQuerier(ctx, c.querier(ctx), subject, relation, object)
 }
 
 func (c *Checker) checkPermissionWithQuerier(ctx context.Context, q Querier, subject Object, relation Relation, object Object) (bool, error) {
 	var result int
 
-	err := q.QueryRowContext(ctx,
-		fmt.Sprintf("SELECT %s($1, $2, $3, $4, $5)", prefixIdent("check_permission", c.databaseSchema)),
-		subject.Type, subject.ID, relation, object.Type, object.ID,
-	).Scan(&result)
+	var row *sql.Row
+	if c.expectedSchemaHash != "" {
+		row = q.QueryRowContext(ctx,
+			fmt.Sprintf("SELECT %s($1, $2, $3, $4, $5, $6)", prefixIdent("check_permission", c.databaseSchema)),
+			subject.Type, subject.ID, relation, object.Type, object.ID, c.expectedSchemaHash,
+		)
+	} else {
+		row = q.QueryRowContext(ctx,
+			fmt.Sprintf("SELECT %s($1, $2, $3, $4, $5)", prefixIdent("check_permission", c.databaseSchema)),
+			subject.Type, subject.ID, relation, object.Type, object.ID,
+		)
+	}
+	err := row.Scan(&result)
 	if err != nil {
 		return false, c.mapError("check_permission", err)
 	}
 	return result == 1, nil
 }
 
-// mapError maps PostgreSQL errors to sentinel errors.
+// mapError maps PostgreSQL errors to sentinel errors, deferring to
+// TranslateError for the codes the generated functions raise.
 // Uses interface-based detection to work with any PostgreSQL driver (pq, pgx).
 func (c *Checker) mapError(operation string, err error) error {
 	code := sqlState(err)
@@ -352,7 +447,7 @@ func (c *Checker) mapError(operation string, err error) error {
 	switch code {
 	case pgUndefinedTable:
 		errStr := err.Error()
-		if strings.Contains(errStr, "melange_tuples") {
+		if _, name := c.tuplesRelation(); strings.Contains(errStr, name) {
 			return fmt.Errorf("%w: %v", ErrNoTuplesTable, err)
 		}
 	case pgUndefinedFunction:
@@ -361,13 +456,11 @@ func (c *Checker) mapError(operation string, err error) error {
 			strings.Contains(err.Error(), "explain_permission") {
 			return fmt.Errorf("%w: %v", ErrMissingFunction, err)
 		}
-	case pgResolutionTooComplex:
-		return &ValidationError{
-			Code:    ErrorCodeResolutionTooComplex,
-			Message: "resolution too complex: depth limit exceeded",
-		}
 	}
 
+	if mapped, ok := translateSQLState(err); ok {
+		return mapped
+	}
 	return fmt.Errorf("%s: %w", operation, err)
 }
 
@@ -377,7 +470,7 @@ func (c *Checker) validateUsersetSubject(_ context.Context, _ Querier, subject O
 	}
 
 	id := subject.ID
-	idx := strings.Index(id, "#")
+	idx := strings.LastIndex(id, "#")
 	if idx == -1 {
 		return nil
 	}
@@ -466,13 +559,13 @@ func sqlState(err error) string {
 // providing 10-50x improvement over N+1 patterns on large datasets.
 func (c *Checker) ListObjects(ctx context.Context, subject SubjectLike, relation RelationLike, objectType ObjectType, page PageOptions) (ids []string, nextCursor *string, err error) {
 	if c.validateUserset {
-		if err := c.validateUsersetSubject(ctx, c.q, subject.FGASubject()); err != nil {
+		if err := c.validateUsersetSubject(ctx, c.querier(ctx), subject.FGASubject()); err != nil {
 			return nil, nil, err
 		}
 	}
 
 	if c.validateRequest {
-		if err := c.validateCheckRequest(ctx, c.q, subject.FGASubject(), relation.FGARelation(), Object{Type: objectType}); err != nil {
+		if err := c.validateCheckRequest(ctx, c.querier(ctx), subject.FGASubject(), relation.FGARelation(), Object{Type: objectType}); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -497,7 +590,7 @@ func (c *Checker) ListObjects(ctx context.Context, subject SubjectLike, relation
 		limit = page.Limit
 	}
 
-	rows, err := c.q.QueryContext(ctx,
+	rows, err := c.querier(ctx).QueryContext(ctx,
 		fmt.Sprintf("SELECT object_id, next_cursor FROM %s($1, $2, $3, $4, $5, $6)", prefixIdent("list_accessible_objects", c.databaseSchema)),
 		subject.FGASubject().Type, subject.FGASubject().ID, relation.FGARelation(), objectType, limit, page.After,
 	)
@@ -562,13 +655,13 @@ func (c *Checker) ListObjectsWithContextualTuples(
 	}
 
 	if c.validateUserset {
-		if err := c.validateUsersetSubject(ctx, c.q, subject.FGASubject()); err != nil {
+		if err := c.validateUsersetSubject(ctx, c.querier(ctx), subject.FGASubject()); err != nil {
 			return nil, nil, err
 		}
 	}
 
 	if c.validateRequest {
-		if err := c.validateCheckRequest(ctx, c.q, subject.FGASubject(), relation.FGARelation(), Object{Type: objectType}); err != nil {
+		if err := c.validateCheckRequest(ctx, c.querier(ctx), subject.FGASubject(), relation.FGARelation(), Object{Type: objectType}); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -644,7 +737,7 @@ func (c *Checker) ListObjectsWithContextualTuples(
 // providing 10-50x improvement over N+1 patterns on large datasets.
 func (c *Checker) ListSubjects(ctx context.Context, object ObjectLike, relation RelationLike, subjectType ObjectType, page PageOptions) (ids []string, nextCursor *string, err error) {
 	if c.validateRequest {
-		if err := c.validateListUsersRequest(ctx, c.q, relation.FGARelation(), object.FGAObject(), subjectType); err != nil {
+		if err := c.validateListUsersRequest(ctx, c.querier(ctx), relation.FGARelation(), object.FGAObject(), subjectType); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -669,7 +762,7 @@ func (c *Checker) ListSubjects(ctx context.Context, object ObjectLike, relation
 		limit = page.Limit
 	}
 
-	rows, err := c.q.QueryContext(ctx,
+	rows, err := c.querier(ctx).QueryContext(ctx,
 		fmt.Sprintf("SELECT subject_id, next_cursor FROM %s($1, $2, $3, $4, $5, $6)", prefixIdent("list_accessible_subjects", c.databaseSchema)),
 		object.FGAObject().Type, object.FGAObject().ID, relation.FGARelation(), subjectType, limit, page.After,
 	)
@@ -734,7 +827,7 @@ func (c *Checker) ListSubjectsWithContextualTuples(
 	}
 
 	if c.validateRequest {
-		if err := c.validateListUsersRequest(ctx, c.q, relation.FGARelation(), object.FGAObject(), subjectType); err != nil {
+		if err := c.validateListUsersRequest(ctx, c.querier(ctx), relation.FGARelation(), object.FGAObject(), subjectType); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -790,6 +883,93 @@ func (c *Checker) ListSubjectsWithContextualTuples(
 	return ids, nextCursor, rows.Err()
 }
 
+// ListSubjectsMulti returns the subjects that have relation on object for each
+// of several subject type filters, as OpenFGA's ListUsers does. A filter is a
+// type ("user") or a userset filter ("group#member"), and each returned
+// Object carries the filter that matched it as its Type, so usersets can be
+// told apart from plain subjects of the same type.
+//
+// The filters are listed in one query through list_accessible_subjects_multi,
+// which unions list_accessible_subjects over them. The result is not
+// paginated and subjects come back in no particular order.
+//
+// Example:
+//
+//	subjects, _ := checker.ListSubjectsMulti(ctx, authz.Document("1"), authz.RelViewer,
+//		[]melange.ObjectType{"user", "group#member"})
+func (c *Checker) ListSubjectsMulti(ctx context.Context, object ObjectLike, relation RelationLike, subjectTypes []ObjectType) ([]Object, error) {
+	return c.ListSubjectsMultiWithContextualTuples(ctx, object, relation, subjectTypes, nil)
+}
+
+// ListSubjectsMultiWithContextualTuples is ListSubjectsMulti evaluated with
+// contextual tuples. Contextual tuples are validated against the loaded model
+// before evaluation.
+func (c *Checker) ListSubjectsMultiWithContextualTuples(
+	ctx context.Context,
+	object ObjectLike,
+	relation RelationLike,
+	subjectTypes []ObjectType,
+	tuples []ContextualTuple,
+) ([]Object, error) {
+	if c.validateRequest {
+		for _, subjectType := range subjectTypes {
+			if err := c.validateListUsersRequest(ctx, c.querier(ctx), relation.FGARelation(), object.FGAObject(), subjectType); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Check context decision if enabled
+	if c.useContextDecision {
+		if d := GetDecisionContext(ctx); d == DecisionDeny {
+			return nil, nil
+		}
+	}
+
+	// DecisionDeny means no subjects have access
+	if c.decision == DecisionDeny || len(subjectTypes) == 0 {
+		return nil, nil
+	}
+
+	var q Querier = c.querier(ctx)
+	if len(tuples) > 0 {
+		if err := c.validateContextualTuples(ctx, tuples); err != nil {
+			return nil, err
+		}
+		execer, cleanup, err := c.prepareContextualTuples(ctx, tuples)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		q = execer
+	}
+
+	types := make(textArray, len(subjectTypes))
+	for i, subjectType := range subjectTypes {
+		types[i] = string(subjectType)
+	}
+
+	rows, err := q.QueryContext(ctx,
+		fmt.Sprintf("SELECT subject_type, subject_id FROM %s($1, $2, $3, $4)", prefixIdent("list_accessible_subjects_multi", c.databaseSchema)),
+		object.FGAObject().Type, object.FGAObject().ID, relation.FGARelation(), types,
+	)
+	if err != nil {
+		return nil, c.mapError("list_accessible_subjects_multi", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	subjects := make([]Object, 0, 16)
+	for rows.Next() {
+		var s Object
+		if err := rows.Scan(&s.Type, &s.ID); err != nil {
+			return nil, err
+		}
+		subjects = append(subjects, s)
+	}
+
+	return subjects, rows.Err()
+}
+
 // validateContextualTuples validates all contextual tuples for basic shape errors.
 // With generated-only SQL entrypoints, model-backed validation is unavailable.
 func (c *Checker) validateContextualTuples(ctx context.Context, tuples []ContextualTuple) error {
@@ -810,7 +990,7 @@ func (c *Checker) validateContextualTuple(_ context.Context, tuple ContextualTup
 
 	subjectID := tuple.Subject.ID
 	usersetRelation := ""
-	if idx := strings.Index(subjectID, "#"); idx != -1 {
+	if idx := strings.LastIndex(subjectID, "#"); idx != -1 {
 		usersetRelation = subjectID[idx+1:]
 	}
 	if strings.Contains(subjectID, "#") && usersetRelation == "" {
@@ -827,16 +1007,28 @@ func (c *Checker) validateContextualTuple(_ context.Context, tuple ContextualTup
 // - *sql.Tx: Uses the transaction, cleanup drops temp objects (table persists until tx ends)
 // - *sql.Conn: Uses the connection, cleanup drops temp objects
 //
+// With WithPoolerSafe, *sql.DB and *sql.Conn instead open a transaction whose
+// rollback is the cleanup (see prepareContextualTuplesInTx).
+//
 // The temporary table (melange_contextual_tuples) is session-scoped, not transaction-scoped.
 // This allows it to persist across transaction boundaries within the same connection,
 // which is critical for contextual tuple support.
 //
-// The temp view (melange_tuples) shadows the permanent view and UNIONs base tuples with
+// The temp view (melange_tuples, or the WithTuplesTable name) shadows the permanent view and UNIONs base tuples with
 // contextual tuples. All permission checks within this connection see both sets.
 //
 // Returns an Execer for running permission checks, a cleanup function that MUST be
 // called when done, and an error if setup fails.
 func (c *Checker) prepareContextualTuples(ctx context.Context, tuples []ContextualTuple) (Execer, func(), error) {
+	if c.poolerSafe {
+		switch q := c.q.(type) {
+		case *sql.DB:
+			return c.prepareContextualTuplesInTx(ctx, q.BeginTx, tuples)
+		case *sql.Conn:
+			return c.prepareContextualTuplesInTx(ctx, q.BeginTx, tuples)
+		}
+	}
+
 	switch q := c.q.(type) {
 	case *sql.DB:
 		conn, err := q.Conn(ctx)
@@ -871,11 +1063,28 @@ func (c *Checker) prepareContextualTuples(ctx context.Context, tuples []Contextu
 	}
 }
 
+// prepareContextualTuplesInTx is the WithPoolerSafe lifecycle: the temp view
+// and every check that reads it share one transaction, so a transaction-mode
+// pooler keeps them on the same server backend. The cleanup rolls the
+// transaction back, which also drops the view.
+func (c *Checker) prepareContextualTuplesInTx(ctx context.Context, begin func(context.Context, *sql.TxOptions) (*sql.Tx, error), tuples []ContextualTuple) (Execer, func(), error) {
+	tx, err := begin(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { _ = tx.Rollback() }
+	if err := c.setupContextualTuples(ctx, tx, tuples); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return tx, cleanup, nil
+}
+
 // setupContextualTuples creates the temporary infrastructure for contextual tuples.
 // This is called by prepareContextualTuples after acquiring the right connection/transaction.
 //
 // The setup creates a single session-scoped temp view shadowing the base
-// melange_tuples view. The view's body unions the base relation with the
+// tuples relation. The view's body unions the base relation with the
 // supplied tuples inlined as a VALUES list, so all setup happens in one
 // statement (one round trip) regardless of how many tuples were passed.
 //
@@ -892,17 +1101,22 @@ func (c *Checker) prepareContextualTuples(ctx context.Context, tuples []Contextu
 // Caller invariant: tuples is non-empty (the *WithContextualTuples entry
 // points short-circuit to the non-contextual path on len == 0).
 func (c *Checker) setupContextualTuples(ctx context.Context, q Execer, tuples []ContextualTuple) error {
+	schema, name := c.tuplesRelation()
+	if schema != "" {
+		return fmt.Errorf("%w: tuples table %s.%s is schema-qualified and cannot be shadowed",
+			ErrContextualTuplesUnsupported, schema, name)
+	}
 	baseSchema, err := c.baseTuplesSchema(ctx, q)
 	if err != nil {
 		return err
 	}
 
 	var sb strings.Builder
-	fmt.Fprintf(&sb, `CREATE TEMP VIEW melange_tuples AS
+	fmt.Fprintf(&sb, `CREATE TEMP VIEW %s AS
 SELECT subject_type, subject_id, relation, object_type, object_id
-FROM %s.melange_tuples
+FROM %s.%s
 UNION ALL
-SELECT subject_type, subject_id, relation, object_type, object_id FROM (VALUES `, quoteIdent(baseSchema))
+SELECT subject_type, subject_id, relation, object_type, object_id FROM (VALUES `, quoteIdent(name), quoteIdent(baseSchema), quoteIdent(name))
 	for i, tuple := range tuples {
 		if i > 0 {
 			sb.WriteString(", ")
@@ -928,10 +1142,23 @@ SELECT subject_type, subject_id, relation, object_type, object_id FROM (VALUES `
 // The cleanup is best-effort (ignores errors) since temp objects are automatically
 // cleaned up when the session ends anyway.
 func (c *Checker) cleanupContextualTuples(ctx context.Context, q Execer) {
-	_, _ = q.ExecContext(ctx, "DROP VIEW IF EXISTS pg_temp.melange_tuples")
+	_, name := c.tuplesRelation()
+	_, _ = q.ExecContext(ctx, "DROP VIEW IF EXISTS pg_temp."+quoteIdent(name))
+}
+
+// tuplesRelation splits the WithTuplesTable name into its schema, empty when
+// unqualified, and relation name. The relation defaults to melange_tuples.
+func (c *Checker) tuplesRelation() (schema, name string) {
+	if c.tuplesTable == "" {
+		return "", "melange_tuples"
+	}
+	if schema, name, ok := strings.Cut(c.tuplesTable, "."); ok {
+		return schema, name
+	}
+	return "", c.tuplesTable
 }
 
-// baseTuplesSchema returns the schema containing the melange_tuples relation,
+// baseTuplesSchema returns the schema containing the tuples relation,
 // caching the result on the Checker so subsequent contextual-tuple calls do
 // not re-query pg_class. The lookup itself is implemented by lookupTuplesSchema.
 func (c *Checker) baseTuplesSchema(ctx context.Context, q Querier) (string, error) {
@@ -948,20 +1175,21 @@ func (c *Checker) baseTuplesSchema(ctx context.Context, q Querier) (string, erro
 	return schema, nil
 }
 
-// lookupTuplesSchema finds the schema containing the melange_tuples relation.
+// lookupTuplesSchema finds the schema containing the tuples relation.
 // This is needed when creating the temp view to properly qualify the base relation
 // in the UNION query. Returns the schema name or ErrNoTuplesTable if not found.
 func (c *Checker) lookupTuplesSchema(ctx context.Context, q Querier) (string, error) {
 	var schema string
+	_, name := c.tuplesRelation()
 	err := q.QueryRowContext(ctx, `
 		SELECT n.nspname
 		FROM pg_catalog.pg_class c
 		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
-		WHERE c.relname = 'melange_tuples'
+		WHERE c.relname = $1
 		  AND c.relkind IN ('r', 'v', 'm')
 		ORDER BY n.nspname
 		LIMIT 1
-	`).Scan(&schema)
+	`, name).Scan(&schema)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", ErrNoTuplesTable
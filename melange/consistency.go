@@ -0,0 +1,73 @@
+package melange
+
+import "context"
+
+// Consistency states how fresh the tuples behind a read must be, mirroring
+// OpenFGA's consistency preference.
+//
+// The generated SQL functions read whatever database they run on, so
+// consistency is decided by which connection a call uses. The Checker routes
+// each read by the consistency on its context:
+//
+//   - ConsistencyStrong reads the primary (the Querier passed to NewChecker)
+//     and skips cache lookups, so a check right after writing a tuple sees the
+//     write. Fresh results are still cached.
+//   - ConsistencyEventual reads the replica configured with WithReplica, and
+//     may be served from the cache. Results read from the replica are not
+//     cached, since the cache also serves reads that expect the primary.
+//   - ConsistencyUnspecified (the default) reads the primary and may be served
+//     from the cache.
+//
+// Contextual tuples always use the primary: they are staged in temp objects,
+// which a read-only replica cannot create.
+type Consistency int
+
+// consistencyContextKey is a custom type for context keys to avoid collisions.
+type consistencyContextKey struct{}
+
+var consistencyKey = consistencyContextKey{}
+
+const (
+	// ConsistencyUnspecified reads the primary, allowing cached results.
+	ConsistencyUnspecified Consistency = iota
+
+	// ConsistencyEventual allows stale results: reads go to the replica
+	// when one is configured.
+	ConsistencyEventual
+
+	// ConsistencyStrong requires read-your-writes: reads go to the primary
+	// and bypass the cache.
+	ConsistencyStrong
+)
+
+// String returns "unspecified", "eventual", or "strong".
+func (c Consistency) String() string {
+	switch c {
+	case ConsistencyEventual:
+		return "eventual"
+	case ConsistencyStrong:
+		return "strong"
+	default:
+		return "unspecified"
+	}
+}
+
+// WithConsistency returns a new context carrying the given consistency for
+// Checker reads made with it.
+//
+// Example:
+//
+//	// Immediately after writing a tuple:
+//	ok, err := checker.Check(melange.WithConsistency(ctx, melange.ConsistencyStrong), user, rel, doc)
+func WithConsistency(ctx context.Context, c Consistency) context.Context {
+	return context.WithValue(ctx, consistencyKey, c)
+}
+
+// GetConsistency retrieves the consistency from context.
+// Returns ConsistencyUnspecified if none is set.
+func GetConsistency(ctx context.Context) Consistency {
+	if c, ok := ctx.Value(consistencyKey).(Consistency); ok {
+		return c
+	}
+	return ConsistencyUnspecified
+}
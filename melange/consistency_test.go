@@ -0,0 +1,67 @@
+package melange
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestConsistencyContext(t *testing.T) {
+	ctx := context.Background()
+	if got := GetConsistency(ctx); got != ConsistencyUnspecified {
+		t.Errorf("GetConsistency() = %v, want unspecified", got)
+	}
+	for _, c := range []Consistency{ConsistencyEventual, ConsistencyStrong} {
+		if got := GetConsistency(WithConsistency(ctx, c)); got != c {
+			t.Errorf("GetConsistency() = %v, want %v", got, c)
+		}
+	}
+}
+
+func TestCheckerQuerier_RoutesByConsistency(t *testing.T) {
+	primary, replica := &sql.DB{}, &sql.DB{}
+	ctx := context.Background()
+
+	c := &Checker{q: primary, replica: replica}
+	tests := []struct {
+		consistency Consistency
+		want        Querier
+	}{
+		{ConsistencyUnspecified, primary},
+		{ConsistencyEventual, replica},
+		{ConsistencyStrong, primary},
+	}
+	for _, tt := range tests {
+		if got := c.querier(WithConsistency(ctx, tt.consistency)); got != tt.want {
+			t.Errorf("%v read used the wrong handle", tt.consistency)
+		}
+	}
+
+	// Without a replica, eventual reads stay on the primary.
+	c = &Checker{q: primary}
+	if got := c.querier(WithConsistency(ctx, ConsistencyEventual)); got != primary {
+		t.Error("eventual read without a replica must use the primary")
+	}
+}
+
+func TestCheckerWriteCache_SkipsReplicaReads(t *testing.T) {
+	primary, replica := &sql.DB{}, &sql.DB{}
+	ctx := context.Background()
+
+	c := &Checker{q: primary, replica: replica}
+	for consistency, want := range map[Consistency]bool{
+		ConsistencyUnspecified: true,
+		ConsistencyEventual:    false,
+		ConsistencyStrong:      true,
+	} {
+		if got := c.writeCache(WithConsistency(ctx, consistency)); got != want {
+			t.Errorf("writeCache(%v) = %v, want %v", consistency, got, want)
+		}
+	}
+
+	// Without a replica, eventual reads come from the primary and are cached.
+	c = &Checker{q: primary}
+	if !c.writeCache(WithConsistency(ctx, ConsistencyEventual)) {
+		t.Error("eventual read without a replica must be cached")
+	}
+}
@@ -13,7 +13,8 @@ import (
 // Use the Is*Err helper functions to check for specific errors and provide
 // helpful setup messages to users.
 var (
-	// ErrNoTuplesTable is returned when the melange_tuples relation doesn't exist.
+	// ErrNoTuplesTable is returned when the melange_tuples relation (or the one
+	// named by WithTuplesTable) doesn't exist.
 	// This typically means the application hasn't created the view (or table/materialized view)
 	// over its domain tables. See the melange documentation for view creation examples.
 	ErrNoTuplesTable = errors.New("melange: melange_tuples view/table not found")
@@ -43,6 +44,28 @@ var (
 	// IsBulkCheckDeniedErr to detect this. The concrete type is *BulkCheckDeniedError
 	// which carries the first denied check's details.
 	ErrBulkCheckDenied = errors.New("melange: bulk check denied")
+
+	// ErrRelationNotListable is returned by the list methods when the relation
+	// exists in the model but melange generated no list function for it (see
+	// the relation's ListReason in the compiler analysis). Checks on the
+	// relation still work; only listing is unavailable.
+	ErrRelationNotListable = errors.New("melange: relation not listable")
+
+	// ErrRelationNotExpandable is returned by Expand when the relation exists
+	// in the model but melange generated no expand function for it. Checks on
+	// the relation still work; only expanding it is unavailable.
+	ErrRelationNotExpandable = errors.New("melange: relation not expandable")
+
+	// ErrSchemaHashMismatch is returned when check_permission was passed an
+	// expected schema hash and the installed functions were generated from a
+	// different schema, typically because the application was deployed with a
+	// schema change before `melange migrate` ran.
+	ErrSchemaHashMismatch = errors.New("melange: schema hash mismatch")
+
+	// ErrInvalidArgument is returned when a generated function rejects an
+	// argument: a NULL where a value is required, or a malformed "type:id"
+	// string passed to one of the string overloads.
+	ErrInvalidArgument = errors.New("melange: invalid argument")
 )
 
 // IsNoTuplesTableErr returns true if err is or wraps ErrNoTuplesTable.
@@ -60,6 +83,26 @@ func IsMissingFunctionErr(err error) bool {
 	return errors.Is(err, ErrMissingFunction)
 }
 
+// IsRelationNotListableErr returns true if err is or wraps ErrRelationNotListable.
+func IsRelationNotListableErr(err error) bool {
+	return errors.Is(err, ErrRelationNotListable)
+}
+
+// IsRelationNotExpandableErr returns true if err is or wraps ErrRelationNotExpandable.
+func IsRelationNotExpandableErr(err error) bool {
+	return errors.Is(err, ErrRelationNotExpandable)
+}
+
+// IsSchemaHashMismatchErr returns true if err is or wraps ErrSchemaHashMismatch.
+func IsSchemaHashMismatchErr(err error) bool {
+	return errors.Is(err, ErrSchemaHashMismatch)
+}
+
+// IsInvalidArgumentErr returns true if err is or wraps ErrInvalidArgument.
+func IsInvalidArgumentErr(err error) bool {
+	return errors.Is(err, ErrInvalidArgument)
+}
+
 // IsCyclicSchemaErr returns true if err is or wraps ErrCyclicSchema.
 func IsCyclicSchemaErr(err error) bool {
 	return errors.Is(err, ErrCyclicSchema)
@@ -74,9 +117,57 @@ const (
 
 	// Custom Melange error codes (must not conflict with PostgreSQL codes)
 	// These are prefixed with 'M' to distinguish them from PG error codes.
-	pgResolutionTooComplex = "M2002" // resolution depth exceeded
+	pgResolutionTooComplex  = "M2002" // resolution depth exceeded
+	pgRelationNotListable   = "M2003" // list dispatcher reached a relation without a list function
+	pgRelationNotExpandable = "M2004" // expand dispatcher reached a relation without an expand function
+	pgSchemaHashMismatch    = "M2005" // check_permission's expected schema hash differs from the installed one
+
+	// Standard codes the generated functions raise for bad arguments.
+	pgNullValueNotAllowed   = "22004" // null_value_not_allowed
+	pgInvalidParameterValue = "22023" // invalid_parameter_value
 )
 
+// TranslateError maps an error raised by a generated function to the typed
+// melange error for its SQLSTATE: M2002 to a ValidationError with
+// ErrorCodeResolutionTooComplex, M2003 to ErrRelationNotListable, M2004 to
+// ErrRelationNotExpandable, M2005 to ErrSchemaHashMismatch, and 22004 or
+// 22023 to ErrInvalidArgument. The
+// sentinels wrap the driver error, so its message stays available. Any other
+// error, including nil, is returned unchanged.
+//
+// Checker applies it to every call. Code that calls the generated functions
+// directly, such as the generated clients, uses it to get the same errors.
+func TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if mapped, ok := translateSQLState(err); ok {
+		return mapped
+	}
+	return err
+}
+
+// translateSQLState reports the typed error for err's SQLSTATE, and false if
+// the code is not one the generated functions raise.
+func translateSQLState(err error) (error, bool) {
+	switch sqlState(err) {
+	case pgResolutionTooComplex:
+		return &ValidationError{
+			Code:    ErrorCodeResolutionTooComplex,
+			Message: "resolution too complex: depth limit exceeded",
+		}, true
+	case pgRelationNotListable:
+		return fmt.Errorf("%w: %v", ErrRelationNotListable, err), true
+	case pgRelationNotExpandable:
+		return fmt.Errorf("%w: %v", ErrRelationNotExpandable, err), true
+	case pgSchemaHashMismatch:
+		return fmt.Errorf("%w: %v", ErrSchemaHashMismatch, err), true
+	case pgNullValueNotAllowed, pgInvalidParameterValue:
+		return fmt.Errorf("%w: %v", ErrInvalidArgument, err), true
+	}
+	return nil, false
+}
+
 // OpenFGA error codes for compatibility with the OpenFGA API.
 // These are used in ValidationError to provide OpenFGA-compatible error responses.
 const (
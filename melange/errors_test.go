@@ -38,6 +38,16 @@ func TestErrorHelpers(t *testing.T) {
 			t.Error("IsMissingFunctionErr should return false for other errors")
 		}
 	})
+
+	t.Run("IsRelationNotListableErr", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", melange.ErrRelationNotListable)
+		if !melange.IsRelationNotListableErr(err) {
+			t.Error("IsRelationNotListableErr should return true for wrapped ErrRelationNotListable")
+		}
+		if melange.IsRelationNotListableErr(errors.New("other error")) {
+			t.Error("IsRelationNotListableErr should return false for other errors")
+		}
+	})
 }
 
 func TestSentinelErrors(t *testing.T) {
@@ -49,6 +59,8 @@ func TestSentinelErrors(t *testing.T) {
 		{melange.ErrNoTuplesTable, "melange_tuples view/table not found"},
 		{melange.ErrInvalidSchema, "invalid schema"},
 		{melange.ErrMissingFunction, "authorization function missing"},
+		{melange.ErrRelationNotListable, "relation not listable"},
+		{melange.ErrRelationNotExpandable, "relation not expandable"},
 	}
 
 	for _, tt := range tests {
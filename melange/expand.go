@@ -174,7 +174,7 @@ func (c *Checker) Expand(ctx context.Context, object ObjectLike, relation Relati
 		// type filter, when set, narrows which user types end up in
 		// Leaf.Users; pass it through so the validator can flag
 		// schema-invalid filters.
-		if err := c.validateListUsersRequest(ctx, c.q, rel, obj, resolved.subjectType); err != nil {
+		if err := c.validateListUsersRequest(ctx, c.querier(ctx), rel, obj, resolved.subjectType); err != nil {
 			return nil, err
 		}
 	}
@@ -182,7 +182,7 @@ func (c *Checker) Expand(ctx context.Context, object ObjectLike, relation Relati
 	// Cache lookup — same shape as Explain. subjectType filter and
 	// maxLeaf cap are part of the key because both change the tree.
 	expandCache, cacheOK := c.cache.(ExpandCache)
-	if cacheOK {
+	if cacheOK && readCache(ctx) {
 		if tree, cachedErr, found := expandCache.GetExpand(obj, rel, resolved.subjectType, resolved.maxLeaf); found {
 			return tree, cachedErr
 		}
@@ -198,7 +198,7 @@ func (c *Checker) Expand(ctx context.Context, object ObjectLike, relation Relati
 	}
 
 	var raw []byte
-	err := c.q.QueryRowContext(ctx,
+	err := c.querier(ctx).QueryRowContext(ctx,
 		fmt.Sprintf("SELECT %s($1, $2, $3, $4, $5)::text", prefixIdent("expand_permission", c.databaseSchema)),
 		obj.Type, obj.ID, rel, subjectType, maxLeaf,
 	).Scan(&raw)
@@ -210,7 +210,7 @@ func (c *Checker) Expand(ctx context.Context, object ObjectLike, relation Relati
 	if err := json.Unmarshal(raw, &tree); err != nil {
 		return nil, fmt.Errorf("expand_permission: decoding tree: %w", err)
 	}
-	if cacheOK {
+	if cacheOK && c.writeCache(ctx) {
 		expandCache.SetExpand(obj, rel, resolved.subjectType, resolved.maxLeaf, &tree, nil)
 	}
 	return &tree, nil
@@ -316,13 +316,15 @@ func collectExpandPointers(n *UsersetTreeNode, push func(Object, Relation)) {
 }
 
 // parseUsersetPointer splits a "<type>:<id>#<relation>" string into
-// the Object + Relation pair an Expand follow-up call needs. Returns
+// the Object + Relation pair an Expand follow-up call needs. The type
+// ends at the first ':' and the relation starts after the last '#', so
+// IDs may contain either ("group:team:eng#member"). Returns
 // ok=false on malformed input so a degenerate tree response stops
 // the walker rather than crashing — defensive against future
 // renderer bugs that might emit an empty or malformed Computed
 // userset string.
 func parseUsersetPointer(s string) (Object, Relation, bool) {
-	hash := strings.IndexByte(s, '#')
+	hash := strings.LastIndexByte(s, '#')
 	if hash < 1 || hash == len(s)-1 {
 		return Object{}, "", false
 	}
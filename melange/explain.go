@@ -52,12 +52,12 @@ func (c *Checker) Explain(ctx context.Context, subject SubjectLike, relation Rel
 	obj := object.FGAObject()
 
 	if c.validateUserset {
-		if err := c.validateUsersetSubject(ctx, c.q, subj); err != nil {
+		if err := c.validateUsersetSubject(ctx, c.querier(ctx), subj); err != nil {
 			return nil, err
 		}
 	}
 	if c.validateRequest {
-		if err := c.validateCheckRequest(ctx, c.q, subj, rel, obj); err != nil {
+		if err := c.validateCheckRequest(ctx, c.querier(ctx), subj, rel, obj); err != nil {
 			return nil, err
 		}
 	}
@@ -68,7 +68,7 @@ func (c *Checker) Explain(ctx context.Context, subject SubjectLike, relation Rel
 	// different traces (truncation flips). Miss on the type assertion
 	// (Check-only Cache impl) → fall through to the DB.
 	explainCache, cacheOK := c.cache.(ExplainCache)
-	if cacheOK {
+	if cacheOK && readCache(ctx) {
 		if trace, cachedErr, found := explainCache.GetExplain(subj, rel, obj, resolved.maxNodes); found {
 			return trace, cachedErr
 		}
@@ -80,7 +80,7 @@ func (c *Checker) Explain(ctx context.Context, subject SubjectLike, relation Rel
 	}
 
 	var raw []byte
-	err := c.q.QueryRowContext(ctx,
+	err := c.querier(ctx).QueryRowContext(ctx,
 		fmt.Sprintf("SELECT %s($1, $2, $3, $4, $5, $6)::text", prefixIdent("explain_permission", c.databaseSchema)),
 		subj.Type, subj.ID, rel, obj.Type, obj.ID, maxNodes,
 	).Scan(&raw)
@@ -92,7 +92,7 @@ func (c *Checker) Explain(ctx context.Context, subject SubjectLike, relation Rel
 	if err := json.Unmarshal(raw, &trace); err != nil {
 		return nil, fmt.Errorf("explain_permission: decoding trace: %w", err)
 	}
-	if cacheOK {
+	if cacheOK && c.writeCache(ctx) {
 		explainCache.SetExplain(subj, rel, obj, resolved.maxNodes, &trace, nil)
 	}
 	return &trace, nil
@@ -0,0 +1,97 @@
+package melange
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// sqlStateError is a driver error carrying a SQLSTATE, as pgx and pq report it.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "ERROR (SQLSTATE " + string(e) + ")" }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestMapError_CustomCodes(t *testing.T) {
+	c := &Checker{}
+
+	err := c.mapError("list_accessible_objects", sqlStateError(pgRelationNotListable))
+	if !IsRelationNotListableErr(err) {
+		t.Errorf("M2003: want ErrRelationNotListable, got %v", err)
+	}
+
+	err = c.mapError("expand_permission", sqlStateError(pgRelationNotExpandable))
+	if !IsRelationNotExpandableErr(err) {
+		t.Errorf("M2004: want ErrRelationNotExpandable, got %v", err)
+	}
+
+	err = c.mapError("check_permission", sqlStateError(pgResolutionTooComplex))
+	if GetValidationErrorCode(err) != ErrorCodeResolutionTooComplex {
+		t.Errorf("M2002: want ValidationError %d, got %v", ErrorCodeResolutionTooComplex, err)
+	}
+
+	for _, code := range []string{pgNullValueNotAllowed, pgInvalidParameterValue} {
+		err = c.mapError("check_permission", sqlStateError(code))
+		if !IsInvalidArgumentErr(err) {
+			t.Errorf("%s: want ErrInvalidArgument, got %v", code, err)
+		}
+	}
+
+	other := sqlStateError("22P02")
+	if err := c.mapError("check_permission", other); !errors.Is(err, other) || IsRelationNotListableErr(err) {
+		t.Errorf("unmapped code: want wrapped driver error, got %v", err)
+	}
+}
+
+func TestTranslateError(t *testing.T) {
+	if err := TranslateError(nil); err != nil {
+		t.Errorf("nil: want nil, got %v", err)
+	}
+
+	err := TranslateError(sqlStateError(pgRelationNotListable))
+	if !IsRelationNotListableErr(err) {
+		t.Errorf("M2003: want ErrRelationNotListable, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "SQLSTATE M2003") {
+		t.Errorf("M2003: want driver message kept, got %q", err)
+	}
+
+	err = TranslateError(sqlStateError(pgSchemaHashMismatch))
+	if !IsSchemaHashMismatchErr(err) {
+		t.Errorf("M2005: want ErrSchemaHashMismatch, got %v", err)
+	}
+
+	err = TranslateError(sqlStateError(pgNullValueNotAllowed))
+	if !IsInvalidArgumentErr(err) {
+		t.Errorf("22004: want ErrInvalidArgument, got %v", err)
+	}
+
+	// Unmapped codes pass through unwrapped, with no operation prefix.
+	other := sqlStateError("22P02")
+	if err := TranslateError(other); err != other {
+		t.Errorf("unmapped code: want driver error unchanged, got %v", err)
+	}
+}
+
+// undefinedTableError is a driver error for a missing relation.
+type undefinedTableError string
+
+func (e undefinedTableError) Error() string {
+	return `relation "` + string(e) + `" does not exist`
+}
+func (e undefinedTableError) SQLState() string { return pgUndefinedTable }
+
+func TestMapError_TuplesTable(t *testing.T) {
+	err := (&Checker{}).mapError("check_permission", undefinedTableError("melange_tuples"))
+	if !IsNoTuplesTableErr(err) {
+		t.Errorf("default table: want ErrNoTuplesTable, got %v", err)
+	}
+
+	c := NewChecker(nil, WithTuplesTable("tenant_42.tuples"))
+	if err := c.mapError("check_permission", undefinedTableError("tenant_42.tuples")); !IsNoTuplesTableErr(err) {
+		t.Errorf("custom table: want ErrNoTuplesTable, got %v", err)
+	}
+	if err := c.mapError("check_permission", undefinedTableError("documents")); IsNoTuplesTableErr(err) {
+		t.Errorf("other table: want driver error, got %v", err)
+	}
+}
@@ -68,12 +68,14 @@
 //
 // Or use the CLI for migrations:
 //
-//	melange migrate --db postgres://localhost/mydb --schemas-dir schemas
+//	melange migrate --db postgres://localhost/mydb --schema schemas
 package melange
 
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 )
 
 // ObjectType represents the type of an object.
@@ -97,7 +99,47 @@ type Object struct {
 
 // String returns the canonical representation "type:id".
 func (o Object) String() string {
-	return o.Type.String() + ":" + o.ID
+	return o.Type.String() + DefaultObjectDelimiter + o.ID
+}
+
+// DefaultObjectDelimiter separates type from id in Object.String and in the
+// "type:id" arguments of the generated string overloads, unless the schema
+// was migrated with a different ObjectDelimiter.
+const DefaultObjectDelimiter = ":"
+
+// Format returns "type<delimiter>id", the form the "type:id" overloads of
+// check_permission and the list functions expect from a schema migrated with
+// ObjectDelimiter set to delimiter. An empty delimiter means
+// DefaultObjectDelimiter. A userset's "#relation" suffix stays part of the
+// ID, so Object{Type: "team", ID: "eng#member"}.Format("/") is
+// "team/eng#member".
+//
+// The delimiter must match the one the schema was migrated with; the
+// functions cannot tell a wrong delimiter from a malformed string.
+func (o Object) Format(delimiter string) string {
+	if delimiter == "" {
+		delimiter = DefaultObjectDelimiter
+	}
+	return o.Type.String() + delimiter + o.ID
+}
+
+// ParseObject parses "type<delimiter>id" as produced by Format. The type ends
+// at the first delimiter and the ID is everything after it, so IDs may
+// contain the delimiter and usersets keep their "#relation" suffix in ID.
+// An empty delimiter means DefaultObjectDelimiter. It returns an error when
+// either part is empty or delimiter contains "#".
+func ParseObject(s, delimiter string) (Object, error) {
+	if delimiter == "" {
+		delimiter = DefaultObjectDelimiter
+	}
+	if strings.Contains(delimiter, "#") {
+		return Object{}, fmt.Errorf("melange: object delimiter %q must not contain %q", delimiter, "#")
+	}
+	typ, id, ok := strings.Cut(s, delimiter)
+	if !ok || typ == "" || id == "" {
+		return Object{}, fmt.Errorf("melange: malformed object %q: expected type%sid", s, delimiter)
+	}
+	return Object{Type: ObjectType(typ), ID: id}, nil
 }
 
 // FGAObject returns the object itself, implementing ObjectLike.
@@ -0,0 +1,60 @@
+package melange
+
+import "testing"
+
+func TestObjectFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		obj       Object
+		delimiter string
+		want      string
+	}{
+		{"empty delimiter uses the default", Object{Type: "document", ID: "1"}, "", "document:1"},
+		{"custom delimiter", Object{Type: "document", ID: "1"}, "/", "document/1"},
+		{"multi-character delimiter", Object{Type: "document", ID: "1"}, "::", "document::1"},
+		{"userset keeps its relation", Object{Type: "team", ID: "eng#member"}, "/", "team/eng#member"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.obj.Format(tt.delimiter); got != tt.want {
+				t.Errorf("Format(%q) = %q, want %q", tt.delimiter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseObject(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         string
+		delimiter string
+		want      Object
+		wantErr   bool
+	}{
+		{"default delimiter", "document:1", "", Object{Type: "document", ID: "1"}, false},
+		{"id containing the delimiter", "document:urn:doc:2", ":", Object{Type: "document", ID: "urn:doc:2"}, false},
+		{"custom delimiter", "document/a:b", "/", Object{Type: "document", ID: "a:b"}, false},
+		{"userset", "team/eng#member", "/", Object{Type: "team", ID: "eng#member"}, false},
+		{"missing delimiter", "document:1", "/", Object{}, true},
+		{"empty type", ":1", "", Object{}, true},
+		{"empty id", "document:", "", Object{}, true},
+		{"delimiter containing #", "team#eng", "#", Object{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseObject(tt.s, tt.delimiter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseObject(%q, %q) error = %v, wantErr %v", tt.s, tt.delimiter, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseObject(%q, %q) = %+v, want %+v", tt.s, tt.delimiter, got, tt.want)
+			}
+		})
+	}
+
+	// Format and ParseObject round-trip.
+	obj := Object{Type: "document", ID: "a/b"}
+	if got, err := ParseObject(obj.Format("/"), "/"); err != nil || got != obj {
+		t.Errorf("round trip = %+v, %v, want %+v", got, err, obj)
+	}
+}
@@ -0,0 +1,110 @@
+package melange
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PlanFormat selects the output format of ExplainPlan.
+type PlanFormat string
+
+const (
+	// PlanFormatText returns PostgreSQL's text plan, one line per node.
+	PlanFormatText PlanFormat = "text"
+	// PlanFormatJSON returns the plan as a JSON document.
+	PlanFormatJSON PlanFormat = "json"
+)
+
+// PlanOptions configures ExplainPlan. Each flag adds the EXPLAIN option of the
+// same name; ANALYZE and COSTS are always on. The zero value returns a text
+// plan with neither buffers nor timing.
+type PlanOptions struct {
+	Format   PlanFormat // PlanFormatText when empty
+	Buffers  bool       // shared/local buffer hits and reads
+	Timing   bool       // actual time per node
+	Verbose  bool       // output columns and schema-qualified names
+	Settings bool       // planner settings changed from their defaults
+	WAL      bool       // WAL records generated
+}
+
+// String renders the options as the parenthesized list of an EXPLAIN
+// statement, without the parentheses: "ANALYZE, BUFFERS, COSTS".
+func (o PlanOptions) String() string {
+	parts := []string{"ANALYZE"}
+	if o.Buffers {
+		parts = append(parts, "BUFFERS")
+	}
+	if o.Timing {
+		parts = append(parts, "TIMING")
+	}
+	if o.Verbose {
+		parts = append(parts, "VERBOSE")
+	}
+	if o.Settings {
+		parts = append(parts, "SETTINGS")
+	}
+	if o.WAL {
+		parts = append(parts, "WAL")
+	}
+	parts = append(parts, "COSTS")
+	if o.Format != "" && o.Format != PlanFormatText {
+		parts = append(parts, "FORMAT "+strings.ToUpper(string(o.Format)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ExplainPlan runs EXPLAIN ANALYZE on the check_permission call Check would
+// make for subject, relation and object, and returns the plan PostgreSQL
+// reports for it: the text plan's lines joined with newlines, or the JSON
+// document with PlanFormatJSON.
+//
+// Unlike Explain, which returns melange's resolution trace, ExplainPlan is
+// about the query itself and is meant for performance work such as plan
+// regression tests. ANALYZE executes the check, which only reads. The cache,
+// decision overrides and contextual tuples are not consulted; request
+// validation applies as for Check.
+func (c *Checker) ExplainPlan(ctx context.Context, subject SubjectLike, relation RelationLike, object ObjectLike, opts PlanOptions) (string, error) {
+	switch opts.Format {
+	case "", PlanFormatText, PlanFormatJSON:
+	default:
+		return "", fmt.Errorf("melange: unknown plan format %q", opts.Format)
+	}
+
+	subj := subject.FGASubject()
+	rel := relation.FGARelation()
+	obj := object.FGAObject()
+
+	if c.validateUserset {
+		if err := c.validateUsersetSubject(ctx, c.querier(ctx), subj); err != nil {
+			return "", err
+		}
+	}
+	if c.validateRequest {
+		if err := c.validateCheckRequest(ctx, c.querier(ctx), subj, rel, obj); err != nil {
+			return "", err
+		}
+	}
+
+	rows, err := c.querier(ctx).QueryContext(ctx,
+		fmt.Sprintf("EXPLAIN (%s) SELECT %s($1::TEXT, $2::TEXT, $3::TEXT, $4::TEXT, $5::TEXT)", opts, prefixIdent("check_permission", c.databaseSchema)),
+		subj.Type, subj.ID, rel, obj.Type, obj.ID,
+	)
+	if err != nil {
+		return "", c.mapError("check_permission", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,37 @@
+package melange_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pthm/melange/melange"
+)
+
+func TestPlanOptionsString(t *testing.T) {
+	tests := []struct {
+		opts melange.PlanOptions
+		want string
+	}{
+		{melange.PlanOptions{}, "ANALYZE, COSTS"},
+		{melange.PlanOptions{Format: melange.PlanFormatText, Buffers: true}, "ANALYZE, BUFFERS, COSTS"},
+		{
+			melange.PlanOptions{Format: melange.PlanFormatJSON, Buffers: true, Timing: true, Verbose: true, Settings: true, WAL: true},
+			"ANALYZE, BUFFERS, TIMING, VERBOSE, SETTINGS, WAL, COSTS, FORMAT JSON",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestExplainPlanRejectsUnknownFormat(t *testing.T) {
+	checker := melange.NewChecker(nil)
+	_, err := checker.ExplainPlan(context.Background(),
+		melange.Object{Type: "user", ID: "1"}, melange.Relation("viewer"), melange.Object{Type: "document", ID: "1"},
+		melange.PlanOptions{Format: "yaml"})
+	if err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
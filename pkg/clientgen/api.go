@@ -9,6 +9,8 @@
 //
 // Currently supported:
 //   - "go" - Type-safe Go code with constants and constructors
+//   - "graphql" - GraphQL SDL with check and list query fields per relation
+//   - "rust" - Rust module with enums, constructors and sqlx check helpers
 //
 // Registered but not yet implemented:
 //   - "typescript" - TypeScript types and factory functions (stub)
@@ -31,6 +33,8 @@ import (
 
 	"github.com/pthm/melange/lib/clientgen"
 	_ "github.com/pthm/melange/lib/clientgen/go"         // Register Go generator
+	_ "github.com/pthm/melange/lib/clientgen/graphql"    // Register GraphQL generator
+	_ "github.com/pthm/melange/lib/clientgen/rust"       // Register Rust generator
 	_ "github.com/pthm/melange/lib/clientgen/typescript" // Register TypeScript generator (stub)
 	"github.com/pthm/melange/pkg/schema"
 )
@@ -55,7 +59,7 @@ func DefaultConfig() *Config {
 
 // Generate produces client code for the specified runtime.
 //
-// Supported runtimes: "go"
+// Supported runtimes: "go", "graphql", "rust"
 //
 // Returns a map of filename -> content. For single-file outputs (like Go),
 // the map contains one entry. Multi-file outputs (like TypeScript) will
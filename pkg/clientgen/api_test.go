@@ -44,6 +44,12 @@ func TestRegistered(t *testing.T) {
 	if !Registered("typescript") {
 		t.Error("'typescript' should be registered")
 	}
+	if !Registered("graphql") {
+		t.Error("'graphql' should be registered")
+	}
+	if !Registered("rust") {
+		t.Error("'rust' should be registered")
+	}
 	if Registered("python") {
 		t.Error("'python' should not be registered")
 	}
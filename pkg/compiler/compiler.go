@@ -1,12 +1,19 @@
 // Package compiler provides public APIs for compiling OpenFGA schemas to SQL.
 //
 // It is a thin wrapper around lib/sqlgen that exposes only the types and
-// functions needed by external consumers. The package covers two concerns:
+// functions needed by external consumers. The package covers these concerns:
 //
+//   - One-call generation: GenerateFromSchema and
+//     GenerateFromSchemaWithOptions, which take schema source to the full set
+//     of check and list functions and dispatchers.
 //   - Check and list SQL generation: AnalyzeRelations, GenerateSQL,
 //     GenerateListSQL, CollectFunctionNames, CollectNamedFunctions.
 //   - Migration file generation: GenerateMigrationSQL and MigrationOptions,
 //     which assemble versioned UP/DOWN SQL files from already-compiled output.
+//   - Standalone scripts: GenerateScriptSQL and ScriptOptions, which assemble
+//     one transaction-wrapped script, optionally limited to some relations.
+//   - Relation description: DescribeRelation and DescribeRelations, which
+//     report the subject types, wildcards, and usersets each relation accepts.
 //
 // For applying generated SQL directly to a database, use pkg/migrator instead.
 package compiler
@@ -30,23 +37,71 @@ type InlineSQLData = sqlgen.InlineSQLData
 // GenerateSQL generates specialized check_permission functions from relation analyses.
 var GenerateSQL = sqlgen.GenerateSQL
 
+// GenerateSQLOptions tunes codegen, e.g. to emit the opt-in effective_access function.
+type GenerateSQLOptions = sqlgen.GenerateSQLOptions
+
+// GenerateSQLWithOptions is the option-aware variant of GenerateSQL.
+var GenerateSQLWithOptions = sqlgen.GenerateSQLWithOptions
+
 // GenerateListSQL generates specialized list functions from relation analyses.
 var GenerateListSQL = sqlgen.GenerateListSQL
 
+// GenerateListSQLWithOptions is the option-aware variant of GenerateListSQL.
+var GenerateListSQLWithOptions = sqlgen.GenerateListSQLWithOptions
+
 // AnalyzeRelations classifies all relations and gathers data needed for SQL generation.
 var AnalyzeRelations = sqlgen.AnalyzeRelations
 
 // ComputeCanGenerate computes which relations can have functions generated.
 var ComputeCanGenerate = sqlgen.ComputeCanGenerate
 
+// ComputeCanGenerateWithMaxDepth is ComputeCanGenerate for a depth limit other
+// than DefaultMaxDepth; pass the GenerateSQLOptions.MaxDepth used to generate.
+var ComputeCanGenerateWithMaxDepth = sqlgen.ComputeCanGenerateWithMaxDepth
+
+// DependentRelations returns the "type.relation" keys of every relation that
+// depends on objectType.relation, directly or transitively.
+var DependentRelations = sqlgen.DependentRelations
+
 // CollectFunctionNames returns all generated function names for tracking.
 var CollectFunctionNames = sqlgen.CollectFunctionNames
 
+// ContextualTuplesFunctionName is check_permission_with_contextual_tuples,
+// which CollectFunctionNames omits: it is generated only when
+// GeneratedSQL.ContextualTuplesFunction is set.
+const ContextualTuplesFunctionName = sqlgen.ContextualTuplesFunctionName
+
+// HealthcheckFunctionName is melange_healthcheck, which CollectFunctionNames
+// includes but GenerateSQL leaves empty; see GenerateHealthcheckFunction.
+const HealthcheckFunctionName = sqlgen.HealthcheckFunctionName
+
+// GenerateHealthcheckFunction renders melange_healthcheck for a schema hash
+// and the function names it should find installed. Store the result in
+// GeneratedSQL.HealthcheckFunction before calling GenerateMigrationSQL.
+var GenerateHealthcheckFunction = sqlgen.GenerateHealthcheckFunction
+
 // NamedFunction pairs a function name with its generated SQL body.
 type NamedFunction = sqlgen.NamedFunction
 
 // CollectNamedFunctions returns specialized functions paired with their SQL.
 var CollectNamedFunctions = sqlgen.CollectNamedFunctions
 
+// RelationFunctionNames returns every specialized function name a relation
+// can be generated as.
+var RelationFunctionNames = sqlgen.RelationFunctionNames
+
+// CheckFunctionName returns the name of a relation's specialized check
+// function, which clients can call directly instead of check_permission.
+var CheckFunctionName = sqlgen.CheckFunctionName
+
+// NameCollision is a set of relations whose function names fold to the same
+// identifier, such as "document.Viewer" and "document.viewer".
+type NameCollision = sqlgen.NameCollision
+
+// FindNameCollisions returns the relations whose function names would
+// collide. Generation hashes their names apart; callers report them as a
+// warning.
+var FindNameCollisions = sqlgen.FindNameCollisions
+
 // BuildInlineSQLData builds inline SQL data from closure and analyses.
 var BuildInlineSQLData = sqlgen.BuildInlineSQLData
@@ -0,0 +1,114 @@
+package compiler
+
+import (
+	"slices"
+
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+// RelationSurface describes what can be granted on one relation, for tooling
+// such as permission catalogs. It is derived from the same analysis that
+// drives SQL generation, so it matches what the generated functions accept.
+//
+// The subject lists cover tuples written for this relation. A relation
+// reached only through others (viewer: editor) has empty lists here;
+// AllowedSubjectTypes still reports who can end up holding it.
+type RelationSurface struct {
+	ObjectType string
+	Relation   string
+
+	// DirectSubjectTypes are the subject types a tuple may name by id, in
+	// schema order: [user, team] gives ["user", "team"].
+	DirectSubjectTypes []string
+
+	// WildcardSubjectTypes are the subject types a tuple may name as "*":
+	// [user:*] gives ["user"].
+	WildcardSubjectTypes []string
+
+	// Usersets are the usersets a tuple may name, as "type#relation":
+	// [group#member] gives ["group#member"].
+	Usersets []string
+
+	// AllowedSubjectTypes are the subject types that can hold the relation
+	// through any path: direct tuples, implied relations, usersets, and
+	// parents. Sorted.
+	AllowedSubjectTypes []string
+
+	// Feature flags from the analysis.
+	HasDirect       bool // [user]
+	HasImplied      bool // viewer: editor
+	HasWildcard     bool // [user:*], here or on a relation that grants this one
+	HasUserset      bool // [group#member]
+	HasRecursive    bool // viewer from parent
+	HasExclusion    bool // but not blocked
+	HasIntersection bool // writer and editor
+}
+
+// DescribeRelations returns the surface of every relation in types, in the
+// order of types and their relations.
+func DescribeRelations(types []schema.TypeDefinition) []RelationSurface {
+	closure := schema.ComputeRelationClosure(types)
+	analyses := sqlgen.ComputeCanGenerate(sqlgen.AnalyzeRelations(types, closure))
+
+	byKey := make(map[string]sqlgen.RelationAnalysis, len(analyses))
+	for _, a := range analyses {
+		byKey[a.ObjectType+"#"+a.Relation] = a
+	}
+
+	// Analyses come back in dependency order; walk types instead.
+	var surfaces []RelationSurface
+	for _, t := range types {
+		for _, r := range t.Relations {
+			surfaces = append(surfaces, describeAnalysis(byKey[t.Name+"#"+r.Name], r.SubjectTypeRefs))
+		}
+	}
+	return surfaces
+}
+
+// DescribeRelation returns the surface of objectType's relation. ok is false
+// when the relation is not defined. Describing many relations of one schema
+// is cheaper with DescribeRelations, which analyzes it once.
+func DescribeRelation(types []schema.TypeDefinition, objectType, relation string) (surface RelationSurface, ok bool) {
+	for _, s := range DescribeRelations(types) {
+		if s.ObjectType == objectType && s.Relation == relation {
+			return s, true
+		}
+	}
+	return RelationSurface{}, false
+}
+
+func describeAnalysis(a sqlgen.RelationAnalysis, refs []schema.SubjectTypeRef) RelationSurface {
+	s := RelationSurface{
+		ObjectType:          a.ObjectType,
+		Relation:            a.Relation,
+		AllowedSubjectTypes: slices.Sorted(slices.Values(a.AllowedSubjectTypes)),
+		HasDirect:           a.Features.HasDirect,
+		HasImplied:          a.Features.HasImplied,
+		HasWildcard:         a.Features.HasWildcard,
+		HasUserset:          a.Features.HasUserset,
+		HasRecursive:        a.Features.HasRecursive,
+		HasExclusion:        a.Features.HasExclusion,
+		HasIntersection:     a.Features.HasIntersection,
+	}
+	// Read from the definition rather than the analysis: its
+	// DirectSubjectTypes also lists types that only accept a wildcard.
+	for _, ref := range refs {
+		switch {
+		case ref.Relation != "":
+			s.Usersets = appendUnique(s.Usersets, ref.Type+"#"+ref.Relation)
+		case ref.Wildcard:
+			s.WildcardSubjectTypes = appendUnique(s.WildcardSubjectTypes, ref.Type)
+		default:
+			s.DirectSubjectTypes = appendUnique(s.DirectSubjectTypes, ref.Type)
+		}
+	}
+	return s
+}
+
+func appendUnique(list []string, v string) []string {
+	if slices.Contains(list, v) {
+		return list
+	}
+	return append(list, v)
+}
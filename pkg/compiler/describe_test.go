@@ -0,0 +1,86 @@
+package compiler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pthm/melange/pkg/parser"
+)
+
+const describeSchema = `
+model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type folder
+  relations
+    define viewer: [user]
+
+type document
+  relations
+    define parent: [folder]
+    define owner: [user]
+    define editor: [user, group#member] or owner
+    define viewer: [user:*] or editor or viewer from parent
+`
+
+func TestDescribeRelation(t *testing.T) {
+	types, err := parser.ParseSchemaString(describeSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	editor, ok := DescribeRelation(types, "document", "editor")
+	if !ok {
+		t.Fatal("document.editor not described")
+	}
+	want := RelationSurface{
+		ObjectType:          "document",
+		Relation:            "editor",
+		DirectSubjectTypes:  []string{"user"},
+		Usersets:            []string{"group#member"},
+		AllowedSubjectTypes: []string{"user"},
+		HasDirect:           true,
+		HasImplied:          true,
+		HasUserset:          true,
+	}
+	if !reflect.DeepEqual(editor, want) {
+		t.Errorf("DescribeRelation(document, editor) =\n%+v\nwant\n%+v", editor, want)
+	}
+
+	viewer, _ := DescribeRelation(types, "document", "viewer")
+	if viewer.DirectSubjectTypes != nil {
+		t.Errorf("[user:*] alone must not make user grantable by id, got %v", viewer.DirectSubjectTypes)
+	}
+	if !reflect.DeepEqual(viewer.WildcardSubjectTypes, []string{"user"}) {
+		t.Errorf("WildcardSubjectTypes = %v, want [user]", viewer.WildcardSubjectTypes)
+	}
+	if !viewer.HasWildcard || !viewer.HasImplied || !viewer.HasRecursive {
+		t.Errorf("viewer flags = %+v, want wildcard, implied and recursive", viewer)
+	}
+
+	if _, ok := DescribeRelation(types, "document", "nope"); ok {
+		t.Error("undefined relation reported as described")
+	}
+}
+
+func TestDescribeRelations_CoversEverySchemaRelation(t *testing.T) {
+	types, err := parser.ParseSchemaString(describeSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, s := range DescribeRelations(types) {
+		got = append(got, s.ObjectType+"#"+s.Relation)
+	}
+	want := []string{"group#member", "folder#viewer", "document#editor", "document#owner", "document#parent", "document#viewer"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DescribeRelations() relations = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+// RelationError reports a relation whose functions could not be generated.
+type RelationError = sqlgen.RelationError
+
+// RelationErrors lists the relations GenerateFromSchema left out of its
+// output because their functions could not be generated.
+type RelationErrors []*RelationError
+
+func (e RelationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d relation(s) could not be generated: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the individual relation errors, for errors.Is and errors.As.
+func (e RelationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// GenerateFromSchema compiles an OpenFGA schema in DSL form to every check
+// and list function and dispatcher the migrator would install, in the
+// public schema with default options. See GenerateFromSchemaWithOptions.
+func GenerateFromSchema(schemaBytes []byte) (GeneratedSQL, ListGeneratedSQL, error) {
+	return GenerateFromSchemaWithOptions(schemaBytes, "", GenerateSQLOptions{})
+}
+
+// GenerateFromSchemaWithOptions runs the whole generation pipeline: parse,
+// validate, compute the closure, analyze relations, and generate check and
+// list SQL into databaseSchema (empty for unqualified names).
+//
+// A schema that does not parse or validate returns only an error. A relation
+// whose functions fail to generate does not abort the model: it is left out,
+// together with every relation whose SQL depends on it, and the dispatchers
+// treat it as they treat a relation with no access paths. The SQL for the
+// rest is returned along with a RelationErrors naming each failure, so a
+// caller can decide whether partial output is acceptable:
+//
+//	check, list, err := compiler.GenerateFromSchema(src)
+//	var relErrs compiler.RelationErrors
+//	if errors.As(err, &relErrs) {
+//	    // check and list are complete except for the relations in relErrs.
+//	} else if err != nil {
+//	    return err
+//	}
+func GenerateFromSchemaWithOptions(schemaBytes []byte, databaseSchema string, opts GenerateSQLOptions) (GeneratedSQL, ListGeneratedSQL, error) {
+	types, err := parser.ParseSchemaString(string(schemaBytes))
+	if err != nil {
+		return GeneratedSQL{}, ListGeneratedSQL{}, err
+	}
+	if err := schema.DetectCycles(types); err != nil {
+		return GeneratedSQL{}, ListGeneratedSQL{}, err
+	}
+	if err := schema.ValidateTupleToUsersets(types); err != nil {
+		return GeneratedSQL{}, ListGeneratedSQL{}, err
+	}
+
+	closure := schema.ComputeRelationClosure(types)
+	analyses := ComputeCanGenerateWithMaxDepth(AnalyzeRelations(types, closure), opts.MaxDepth)
+	inline := BuildInlineSQLData(closure, analyses)
+
+	return generateExcludingFailures(analyses, func(analyses []RelationAnalysis) (GeneratedSQL, ListGeneratedSQL, error) {
+		check, err := GenerateSQLWithOptions(analyses, inline, databaseSchema, opts)
+		if err != nil {
+			return GeneratedSQL{}, ListGeneratedSQL{}, err
+		}
+		list, err := GenerateListSQLWithOptions(analyses, inline, databaseSchema, opts)
+		if err != nil {
+			return GeneratedSQL{}, ListGeneratedSQL{}, err
+		}
+		return check, list, nil
+	})
+}
+
+// generateExcludingFailures calls generate until it succeeds, each time
+// dropping the relation named by its RelationError, and that relation's
+// dependents, from analyses. Any other error ends generation.
+func generateExcludingFailures(
+	analyses []RelationAnalysis,
+	generate func([]RelationAnalysis) (GeneratedSQL, ListGeneratedSQL, error),
+) (GeneratedSQL, ListGeneratedSQL, error) {
+	var failed RelationErrors
+	for {
+		check, list, err := generate(analyses)
+		if err == nil {
+			if len(failed) > 0 {
+				return check, list, failed
+			}
+			return check, list, nil
+		}
+
+		var relErr *RelationError
+		if !errors.As(err, &relErr) || !excludeRelation(analyses, relErr) {
+			return GeneratedSQL{}, ListGeneratedSQL{}, err
+		}
+		failed = append(failed, relErr)
+	}
+}
+
+// excludeRelation turns off generation for the relation relErr names and
+// every relation depending on it: list functions for a list failure, check
+// and list functions for any other. It reports false if nothing was left to
+// turn off, so a repeated failure cannot loop.
+func excludeRelation(analyses []RelationAnalysis, relErr *RelationError) bool {
+	listOnly := relErr.Kind == sqlgen.FunctionKindListObjects || relErr.Kind == sqlgen.FunctionKindListSubjects
+	reason := relErr.Error()
+
+	excluded := map[string]bool{relErr.ObjectType + "." + relErr.Relation: true}
+	for _, key := range DependentRelations(analyses, relErr.ObjectType, relErr.Relation) {
+		excluded[key] = true
+	}
+
+	changed := false
+	for i := range analyses {
+		a := &analyses[i]
+		if !excluded[a.ObjectType+"."+a.Relation] {
+			continue
+		}
+		if a.Capabilities.ListAllowed {
+			a.Capabilities.ListAllowed = false
+			a.Capabilities.ListReason = reason
+			changed = true
+		}
+		if !listOnly && a.Capabilities.CheckAllowed {
+			a.Capabilities.CheckAllowed = false
+			a.Capabilities.CheckReason = reason
+			changed = true
+		}
+	}
+	return changed
+}
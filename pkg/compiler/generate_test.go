@@ -0,0 +1,128 @@
+package compiler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+func TestGenerateFromSchema(t *testing.T) {
+	check, list, err := GenerateFromSchema([]byte(describeSchema))
+	if err != nil {
+		t.Fatalf("GenerateFromSchema: %v", err)
+	}
+	if !strings.Contains(check.Dispatcher, "check_permission") {
+		t.Errorf("check dispatcher missing")
+	}
+	if !strings.Contains(list.ListObjectsDispatcher, "list_accessible_objects") {
+		t.Errorf("list_objects dispatcher missing")
+	}
+	if !strings.Contains(strings.Join(check.Functions, "\n"), "check_document_viewer") {
+		t.Errorf("check_document_viewer not generated")
+	}
+	if !strings.Contains(strings.Join(list.ListObjectsFunctions, "\n"), "list_document_viewer_obj") {
+		t.Errorf("list_document_viewer_obj not generated")
+	}
+
+	check, _, err = GenerateFromSchemaWithOptions([]byte(describeSchema), "authz", GenerateSQLOptions{})
+	if err != nil {
+		t.Fatalf("GenerateFromSchemaWithOptions: %v", err)
+	}
+	if !strings.Contains(check.Dispatcher, `"authz"."check_permission"`) {
+		t.Errorf("dispatcher not generated into the authz schema")
+	}
+}
+
+func TestGenerateFromSchema_InvalidSchema(t *testing.T) {
+	_, _, err := GenerateFromSchema([]byte(`model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define admin: [user] or owner
+    define owner: [user] or admin
+`))
+	if err == nil {
+		t.Fatal("expected an error for a cyclic schema")
+	}
+	if !schema.IsCyclicSchemaErr(err) {
+		t.Errorf("err = %v, want a cyclic schema error", err)
+	}
+	var relErrs RelationErrors
+	if errors.As(err, &relErrs) {
+		t.Errorf("schema errors must not be reported as relation errors: %v", err)
+	}
+}
+
+// A relation that fails to generate is dropped with its dependents and
+// reported, and the rest of the model is still generated.
+func TestGenerateExcludingFailures(t *testing.T) {
+	types, err := parser.ParseSchemaString(describeSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closure := schema.ComputeRelationClosure(types)
+	analyses := ComputeCanGenerate(AnalyzeRelations(types, closure))
+
+	failure := errors.New("boom")
+	var generated []string
+	check, _, err := generateExcludingFailures(analyses, func(analyses []RelationAnalysis) (GeneratedSQL, ListGeneratedSQL, error) {
+		generated = generated[:0]
+		for _, a := range analyses {
+			if !a.Capabilities.CheckAllowed {
+				continue
+			}
+			if a.ObjectType == "document" && a.Relation == "owner" {
+				return GeneratedSQL{}, ListGeneratedSQL{}, &RelationError{
+					ObjectType: a.ObjectType, Relation: a.Relation, Kind: sqlgen.FunctionKindCheck, Err: failure,
+				}
+			}
+			generated = append(generated, a.ObjectType+"."+a.Relation)
+		}
+		return GeneratedSQL{Dispatcher: "ok"}, ListGeneratedSQL{}, nil
+	})
+
+	var relErrs RelationErrors
+	if !errors.As(err, &relErrs) || len(relErrs) != 1 {
+		t.Fatalf("err = %v, want one RelationErrors entry", err)
+	}
+	if !errors.Is(err, failure) {
+		t.Errorf("errors.Is(err, failure) = false")
+	}
+	if relErrs[0].Relation != "owner" {
+		t.Errorf("failed relation = %s, want owner", relErrs[0].Relation)
+	}
+	if check.Dispatcher != "ok" {
+		t.Errorf("partial output not returned")
+	}
+	// editor and viewer are implied by owner, so they go with it.
+	for _, key := range generated {
+		switch key {
+		case "document.owner", "document.editor", "document.viewer":
+			t.Errorf("%s generated despite depending on a failed relation", key)
+		}
+	}
+	if !strings.Contains(strings.Join(generated, " "), "document.parent") {
+		t.Errorf("unrelated relations dropped: %v", generated)
+	}
+}
+
+func TestGenerateExcludingFailures_OtherError(t *testing.T) {
+	failure := errors.New("dispatcher failed")
+	_, _, err := generateExcludingFailures(nil, func([]RelationAnalysis) (GeneratedSQL, ListGeneratedSQL, error) {
+		return GeneratedSQL{}, ListGeneratedSQL{}, failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("err = %v, want %v", err, failure)
+	}
+	var relErrs RelationErrors
+	if errors.As(err, &relErrs) {
+		t.Errorf("non-relation error reported as RelationErrors")
+	}
+}
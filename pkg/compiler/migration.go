@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/pthm/melange/lib/sqlgen"
 	"github.com/pthm/melange/lib/sqlgen/sqldsl"
 )
 
@@ -27,7 +28,8 @@ type MigrationOptions struct {
 	DatabaseSchema string
 	// Version is the melange CLI/library version (e.g., "v0.7.3").
 	Version string
-	// SchemaChecksum is the SHA256 of the current schema content.
+	// SchemaChecksum is the schema.SchemaHash of the current schema types, as
+	// recorded by the migrator.
 	SchemaChecksum string
 	// CodegenVersion is the codegen version string.
 	CodegenVersion string
@@ -123,6 +125,7 @@ func generateUpSQL(
 	if changed != nil {
 		fmt.Fprintf(&b, "-- Changed functions: %d of %d\n", len(changed), len(opts.NamedFunctions))
 	}
+	b.WriteString(sqldsl.TuplesColumnTypeNote)
 	b.WriteString("\n")
 
 	// Orphan drops (only in comparison mode)
@@ -137,9 +140,13 @@ func generateUpSQL(
 		}
 	}
 
+	// Model functions are always included and come before the functions
+	// reading them (LANGUAGE sql bodies are validated at CREATE)
+	writeModelFunctions(&b, generatedSQL)
+
 	// When doing change detection, use named functions to filter
 	if changed != nil {
-		writeChangedFunctions(&b, opts.NamedFunctions, changed)
+		writeSelectedFunctions(&b, "Changed Functions", opts.NamedFunctions, changed)
 	} else {
 		writeAllFunctions(&b, generatedSQL, listSQL)
 	}
@@ -165,27 +172,32 @@ func writeFunctionSection(b *strings.Builder, label string, functions []string)
 func writeAllFunctions(b *strings.Builder, generatedSQL GeneratedSQL, listSQL ListGeneratedSQL) {
 	writeFunctionSection(b, "Check Functions", generatedSQL.Functions)
 	writeFunctionSection(b, "No-Wildcard Check Functions", generatedSQL.NoWildcardFunctions)
+	writeFunctionSection(b, "Context Check Functions", generatedSQL.ContextFunctions)
+	writeFunctionSection(b, "Strict Check Functions", generatedSQL.StrictFunctions)
 	writeFunctionSection(b, "Explain Functions", generatedSQL.ExplainFunctions)
 	writeFunctionSection(b, "Expand Functions", generatedSQL.ExpandFunctions)
+	writeFunctionSection(b, "Check Evidence Functions", generatedSQL.EvidenceFunctions)
 	writeFunctionSection(b, "List Objects Functions", listSQL.ListObjectsFunctions)
 	writeFunctionSection(b, "List Subjects Functions", listSQL.ListSubjectsFunctions)
+	writeFunctionSection(b, "List Objects Cursor Functions", listSQL.ListObjectsCursorFunctions)
 }
 
-// writeChangedFunctions writes only the functions that have changed.
-func writeChangedFunctions(b *strings.Builder, namedFunctions []NamedFunction, changed map[string]bool) {
-	var changedFns []NamedFunction
+// writeSelectedFunctions writes, under label, only the named functions in
+// selected: the changed ones in a migration, the filtered ones in a script.
+func writeSelectedFunctions(b *strings.Builder, label string, namedFunctions []NamedFunction, selected map[string]bool) {
+	var selectedFns []NamedFunction
 	for _, nf := range namedFunctions {
-		if changed[nf.Name] {
-			changedFns = append(changedFns, nf)
+		if selected[nf.Name] {
+			selectedFns = append(selectedFns, nf)
 		}
 	}
 
-	if len(changedFns) == 0 {
+	if len(selectedFns) == 0 {
 		return
 	}
 
-	writeSectionHeader(b, fmt.Sprintf("Changed Functions (%d functions)", len(changedFns)))
-	for _, nf := range changedFns {
+	writeSectionHeader(b, fmt.Sprintf("%s (%d functions)", label, len(selectedFns)))
+	for _, nf := range selectedFns {
 		fmt.Fprintf(b, "%s\n\n", nf.SQL)
 	}
 }
@@ -197,9 +209,31 @@ func writeSectionHeader(b *strings.Builder, label string) {
 	b.WriteString("-- ============================================================\n\n")
 }
 
+// writeModelFunctions writes the opt-in melange_closure_rows and
+// melange_userset_rows lookups.
+func writeModelFunctions(b *strings.Builder, generatedSQL GeneratedSQL) {
+	lookups := collectNonEmpty(generatedSQL.ClosureFunction, generatedSQL.UsersetFunction)
+	if len(lookups) > 0 {
+		writeSectionHeader(b, "Closure and Userset Functions")
+		for _, fn := range lookups {
+			fmt.Fprintf(b, "%s\n\n", fn)
+		}
+	}
+}
+
 // writeDispatchers writes all dispatcher functions (always included).
 func writeDispatchers(b *strings.Builder, generatedSQL GeneratedSQL, listSQL ListGeneratedSQL) {
-	checkDispatchers := collectNonEmpty(generatedSQL.Dispatcher, generatedSQL.DispatcherNoWildcard, generatedSQL.BulkDispatcher)
+	checkDispatchers := collectNonEmpty(
+		generatedSQL.Dispatcher,
+		generatedSQL.DispatcherNoWildcard,
+		generatedSQL.DispatcherStrict,
+		generatedSQL.ContextDispatcher,
+		generatedSQL.ContextualTuplesFunction,
+		generatedSQL.BulkDispatcher,
+		generatedSQL.BatchDispatcher,
+		generatedSQL.CheckAnyDispatcher,
+		generatedSQL.CheckAllDispatcher,
+	)
 	if len(checkDispatchers) > 0 {
 		writeSectionHeader(b, "Check Dispatchers")
 		for _, d := range checkDispatchers {
@@ -217,13 +251,33 @@ func writeDispatchers(b *strings.Builder, generatedSQL GeneratedSQL, listSQL Lis
 		fmt.Fprintf(b, "%s\n\n", generatedSQL.ExpandDispatcher)
 	}
 
-	listDispatchers := collectNonEmpty(listSQL.ListObjectsDispatcher, listSQL.ListSubjectsDispatcher)
+	if generatedSQL.ModelRelationsFunction != "" {
+		writeSectionHeader(b, "Model Relations Function")
+		fmt.Fprintf(b, "%s\n\n", generatedSQL.ModelRelationsFunction)
+	}
+
+	if generatedSQL.EffectiveAccessFunction != "" {
+		writeSectionHeader(b, "Effective Access Function")
+		fmt.Fprintf(b, "%s\n\n", generatedSQL.EffectiveAccessFunction)
+	}
+
+	if listSQL.DepthExceededFunction != "" {
+		writeSectionHeader(b, "Depth Limit Function")
+		fmt.Fprintf(b, "%s\n\n", listSQL.DepthExceededFunction)
+	}
+
+	listDispatchers := collectNonEmpty(listSQL.ListObjectsDispatcher, listSQL.ListSubjectsDispatcher, listSQL.ListObjectsAnyDispatcher, listSQL.ListSubjectsMultiDispatcher, listSQL.ListObjectsCursorDispatcher)
 	if len(listDispatchers) > 0 {
 		writeSectionHeader(b, "List Dispatchers")
 		for _, d := range listDispatchers {
 			fmt.Fprintf(b, "%s\n\n", d)
 		}
 	}
+
+	if generatedSQL.HealthcheckFunction != "" {
+		writeSectionHeader(b, "Healthcheck Function")
+		fmt.Fprintf(b, "%s\n\n", generatedSQL.HealthcheckFunction)
+	}
 }
 
 // collectNonEmpty returns only the non-empty strings from the input.
@@ -243,13 +297,26 @@ func collectNonEmpty(values ...string) []string {
 var dispatcherFunctionNames = []string{
 	"check_permission",
 	"check_permission_internal",
+	"check_permission_route",
 	"check_permission_nw",
 	"check_permission_nw_internal",
+	"check_permission_with_context",
 	"check_permission_bulk",
+	"check_permission_batch",
+	"check_any",
+	"check_all",
 	"explain_permission",
 	"explain_permission_internal",
 	"expand_permission",
 	"expand_permission_internal",
+	"effective_access",
+	"melange_closure_rows",
+	"melange_userset_rows",
+	"melange_model_relations",
+	"melange_depth_exceeded",
+	"melange_healthcheck",
+	"list_accessible_objects_any",
+	"list_accessible_subjects_multi",
 	"list_accessible_objects",
 	"list_accessible_subjects",
 }
@@ -300,6 +367,13 @@ func generateDownSQL(expectedFunctions []string, databaseSchema string) string {
 	if len(sortedDispatchers) > 0 {
 		b.WriteString("-- Drop dispatchers\n")
 		for _, fn := range sortedDispatchers {
+			// An overloaded name cannot be dropped by name alone.
+			if args, ok := sqlgen.ObjectStringOverloads[fn]; ok {
+				fmt.Fprintf(&b, "DROP FUNCTION IF EXISTS %s(%s) CASCADE;\n", sqldsl.PrefixIdent(fn, databaseSchema), args)
+			}
+			if args, ok := sqlgen.SchemaHashOverloads[fn]; ok {
+				fmt.Fprintf(&b, "DROP FUNCTION IF EXISTS %s(%s) CASCADE;\n", sqldsl.PrefixIdent(fn, databaseSchema), args)
+			}
 			fmt.Fprintf(&b, "DROP FUNCTION IF EXISTS %s CASCADE;\n", sqldsl.PrefixIdent(fn, databaseSchema))
 		}
 		b.WriteString("\n")
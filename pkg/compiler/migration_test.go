@@ -53,6 +53,9 @@ func TestGenerateMigrationSQL_FullMode(t *testing.T) {
 	if !strings.Contains(result.Up, "Codegen version: 1") {
 		t.Error("UP missing codegen version")
 	}
+	if !strings.Contains(result.Up, "melange_tuples columns are compared as TEXT") {
+		t.Error("UP missing column type note")
+	}
 	if !strings.Contains(result.Up, "check_doc_viewer()") {
 		t.Error("UP missing check function")
 	}
@@ -84,6 +87,11 @@ func TestGenerateMigrationSQL_FullMode(t *testing.T) {
 	if !strings.Contains(result.Down, "DROP FUNCTION IF EXISTS check_permission CASCADE") {
 		t.Error("DOWN missing dispatcher drop")
 	}
+	if !strings.Contains(result.Down, "DROP FUNCTION IF EXISTS check_permission(TEXT, TEXT, TEXT) CASCADE;\n"+
+		"DROP FUNCTION IF EXISTS check_permission(TEXT, TEXT, TEXT, TEXT, TEXT, TEXT, TEXT) CASCADE;\n"+
+		"DROP FUNCTION IF EXISTS check_permission CASCADE") {
+		t.Error("DOWN should drop the check_permission string and schema hash overloads by signature first")
+	}
 
 	// DOWN: specialized before dispatchers
 	specIdx := strings.Index(result.Down, "Drop specialized functions")
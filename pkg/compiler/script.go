@@ -0,0 +1,117 @@
+package compiler
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+)
+
+// ScriptOptions controls GenerateScriptSQL.
+type ScriptOptions struct {
+	// Version, SchemaChecksum and CodegenVersion are written to the header
+	// when set.
+	Version        string
+	SchemaChecksum string
+	CodegenVersion string
+
+	// Relations limits the script to the specialized functions of the
+	// matching relations, given as "type.relation" patterns in path.Match
+	// syntax ("document.viewer", "document.*"). The dispatchers route every
+	// relation, so a filtered script leaves them out and is meant for review
+	// rather than installation. Empty means every function and dispatcher.
+	Relations []string
+}
+
+// GenerateScriptSQL assembles functions already compiled by GenerateSQL and
+// GenerateListSQL into one SQL script wrapped in a transaction, in the order
+// GenerateMigrationSQL writes them: specialized functions, then dispatchers.
+//
+// Unlike GenerateMigrationSQL it neither drops orphaned functions nor has a
+// DOWN counterpart, so it suits reviewing the generated SQL or feeding a
+// migration tool that tracks state itself. analyses must be the ones the SQL
+// was generated from. It returns an error for a malformed pattern in
+// opts.Relations or one that matches no relation.
+func GenerateScriptSQL(generatedSQL GeneratedSQL, listSQL ListGeneratedSQL, analyses []RelationAnalysis, opts ScriptOptions) (string, error) {
+	var keep map[string]bool
+	var matched []string
+	if len(opts.Relations) > 0 {
+		var err error
+		if matched, err = matchRelations(analyses, opts.Relations); err != nil {
+			return "", err
+		}
+		selected := make(map[string]bool, len(matched))
+		for _, key := range matched {
+			selected[key] = true
+		}
+		keep = make(map[string]bool)
+		for _, a := range analyses {
+			if !selected[a.ObjectType+"."+a.Relation] {
+				continue
+			}
+			for _, name := range RelationFunctionNames(a) {
+				keep[name] = true
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("-- Melange SQL\n")
+	if opts.Version != "" {
+		fmt.Fprintf(&b, "-- Melange version: %s\n", opts.Version)
+	}
+	if opts.SchemaChecksum != "" {
+		fmt.Fprintf(&b, "-- Schema checksum: %s\n", opts.SchemaChecksum)
+	}
+	if opts.CodegenVersion != "" {
+		fmt.Fprintf(&b, "-- Codegen version: %s\n", opts.CodegenVersion)
+	}
+	if keep != nil {
+		fmt.Fprintf(&b, "-- Relations: %s (dispatchers omitted)\n", strings.Join(matched, ", "))
+	}
+	b.WriteString(sqldsl.TuplesColumnTypeNote)
+	b.WriteString("\n")
+	b.WriteString("BEGIN;\n\n")
+
+	if keep != nil {
+		writeSelectedFunctions(&b, "Functions", CollectNamedFunctions(generatedSQL, listSQL, analyses), keep)
+	} else {
+		writeModelFunctions(&b, generatedSQL)
+		writeAllFunctions(&b, generatedSQL, listSQL)
+		writeDispatchers(&b, generatedSQL, listSQL)
+	}
+
+	b.WriteString("COMMIT;\n")
+	return b.String(), nil
+}
+
+// matchRelations returns the "type.relation" keys of analyses matching any
+// of patterns, in analyses order.
+func matchRelations(analyses []RelationAnalysis, patterns []string) ([]string, error) {
+	used := make([]bool, len(patterns))
+	var keys []string
+	for _, a := range analyses {
+		key := a.ObjectType + "." + a.Relation
+		hit := false
+		for i, pattern := range patterns {
+			ok, err := path.Match(pattern, key)
+			if err != nil {
+				return nil, fmt.Errorf("relation filter %q: %w", pattern, err)
+			}
+			if ok {
+				used[i] = true
+				hit = true
+			}
+		}
+		if hit {
+			keys = append(keys, key)
+		}
+	}
+	for i, pattern := range patterns {
+		if !used[i] {
+			return nil, fmt.Errorf("relation filter %q matches no relation", pattern)
+		}
+	}
+	return keys, nil
+}
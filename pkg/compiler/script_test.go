@@ -0,0 +1,125 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+func generateScriptForTest(t *testing.T, opts ScriptOptions) (string, error) {
+	t.Helper()
+	types, err := parser.ParseSchemaString(describeSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closure := schema.ComputeRelationClosure(types)
+	analyses := ComputeCanGenerate(AnalyzeRelations(types, closure))
+	inline := BuildInlineSQLData(closure, analyses)
+	check, err := GenerateSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := GenerateListSQL(analyses, inline, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return GenerateScriptSQL(check, list, analyses, opts)
+}
+
+func TestGenerateScriptSQL(t *testing.T) {
+	script, err := generateScriptForTest(t, ScriptOptions{Version: "v0.9.0", SchemaChecksum: "abc123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"-- Melange SQL\n",
+		"Melange version: v0.9.0",
+		"Schema checksum: abc123",
+		"melange_tuples columns are compared as TEXT",
+		"BEGIN;\n",
+		"FUNCTION check_document_viewer(",
+		"FUNCTION list_folder_viewer_obj(",
+		"FUNCTION check_permission(",
+		"FUNCTION list_accessible_subjects(",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q", want)
+		}
+	}
+	if !strings.HasSuffix(script, "COMMIT;\n") {
+		t.Errorf("script does not end with COMMIT")
+	}
+	if strings.Contains(script, "-- Relations:") {
+		t.Errorf("unfiltered script lists relations")
+	}
+}
+
+func TestGenerateScriptSQL_Relations(t *testing.T) {
+	script, err := generateScriptForTest(t, ScriptOptions{Relations: []string{"document.viewer"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(script, "-- Relations: document.viewer (dispatchers omitted)") {
+		t.Errorf("script missing relations header")
+	}
+	for _, want := range []string{"FUNCTION check_document_viewer(", "FUNCTION list_document_viewer_obj("} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"FUNCTION check_document_editor(", "FUNCTION check_folder_viewer(", "FUNCTION check_permission("} {
+		if strings.Contains(script, unwanted) {
+			t.Errorf("filtered script contains %q", unwanted)
+		}
+	}
+
+	script, err = generateScriptForTest(t, ScriptOptions{Relations: []string{"document.*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"FUNCTION check_document_viewer(", "FUNCTION check_document_editor("} {
+		if !strings.Contains(script, want) {
+			t.Errorf("glob script missing %q", want)
+		}
+	}
+	if strings.Contains(script, "FUNCTION check_folder_viewer(") {
+		t.Errorf("glob script contains folder.viewer")
+	}
+}
+
+func TestGenerateScriptSQL_RelationsErrors(t *testing.T) {
+	for _, pattern := range []string{"document.nope", "document.["} {
+		if _, err := generateScriptForTest(t, ScriptOptions{Relations: []string{pattern}}); err == nil {
+			t.Errorf("pattern %q: want error", pattern)
+		}
+	}
+}
+
+func TestGenerateScriptSQL_ClosureFunction(t *testing.T) {
+	types, err := parser.ParseSchemaString(describeSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closure := schema.ComputeRelationClosure(types)
+	analyses := ComputeCanGenerate(AnalyzeRelations(types, closure))
+	inline := BuildInlineSQLData(closure, analyses)
+	check, err := GenerateSQLWithOptions(analyses, inline, "", GenerateSQLOptions{ClosureFunction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := GenerateScriptSQL(check, ListGeneratedSQL{}, analyses, ScriptOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lookups come before the check functions that call them.
+	lookup := strings.Index(script, "FUNCTION melange_closure_rows(")
+	if lookup < 0 || lookup > strings.Index(script, "FUNCTION check_document_viewer(") {
+		t.Errorf("melange_closure_rows missing or after the check functions")
+	}
+	if !strings.Contains(script, "FUNCTION melange_userset_rows(") {
+		t.Errorf("script missing melange_userset_rows")
+	}
+}
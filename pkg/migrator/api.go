@@ -2,6 +2,7 @@ package migrator
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/pthm/melange/pkg/parser"
@@ -88,6 +89,11 @@ func MigrateFromString(ctx context.Context, db Execer, content string) error {
 //   - skipped=true if migration was skipped due to unchanged schema (only when Force=false and DryRun=nil)
 //   - error is non-nil if migration failed (parse error, validation error, DB error)
 //
+// Every statement runs with ctx. Cancelling it aborts the statement in flight
+// and rolls the transaction back, which also releases the advisory lock, so a
+// deploy timeout leaves the previous functions installed. Set
+// MigrateOptions.StatementTimeout to bound each statement on its own.
+//
 // Example: Generate migration script without applying
 //
 //	var buf bytes.Buffer
@@ -96,6 +102,12 @@ func MigrateFromString(ctx context.Context, db Execer, content string) error {
 //	})
 //	os.WriteFile("migrations/001_authz.sql", buf.Bytes(), 0644)
 //
+// Example: Roll back the functions a deploy installed
+//
+//	_, err := migrator.MigrateWithOptions(ctx, db, "", migrator.MigrateOptions{
+//	    Down: true,
+//	})
+//
 // Example: Force re-migration (e.g., after manual schema corruption)
 //
 //	skipped, err := migrator.MigrateWithOptions(ctx, db, "schemas/schema.fga", migrator.MigrateOptions{
@@ -104,6 +116,12 @@ func MigrateFromString(ctx context.Context, db Execer, content string) error {
 func MigrateWithOptions(ctx context.Context, db Execer, schemaPath string, opts MigrateOptions) (skipped bool, err error) {
 	m := NewMigrator(db, schemaPath)
 	m.SetDatabaseSchema(opts.DatabaseSchema)
+	m.SetTuplesTable(opts.TuplesTable)
+
+	if opts.Down {
+		_, err := m.Rollback(ctx, opts.DryRun)
+		return false, err
+	}
 
 	if !m.HasSchema() {
 		return false, fmt.Errorf("no schema found at %s", m.SchemaPath())
@@ -121,16 +139,150 @@ func MigrateWithOptions(ctx context.Context, db Execer, schemaPath string, opts
 		return false, fmt.Errorf("parsing schema: %w", err)
 	}
 
-	// Convert to internal MigrateOptions
-	internalOpts := InternalMigrateOptions{
-		DryRun:        opts.DryRun,
-		Force:         opts.Force,
-		Version:       opts.Version,
-		SchemaContent: string(schemaContent),
-	}
+	internalOpts := opts.internal(string(schemaContent))
 
 	// Skip detection (both phases) happens inside migrateWithTypesAndOptions;
 	// its skipped result covers the phase 1 fast path and the phase 2 no-op
 	// that dev builds rely on (see shouldSkipMigration).
 	return m.migrateWithTypesAndOptions(ctx, types, internalOpts)
 }
+
+// internal converts opts to InternalMigrateOptions, hashing schemaContent
+// for skip detection.
+func (opts MigrateOptions) internal(schemaContent string) InternalMigrateOptions {
+	return InternalMigrateOptions{
+		DryRun:        opts.DryRun,
+		NoTransaction: opts.NoTransaction,
+		Force:         opts.Force,
+		Version:       opts.Version,
+		SchemaContent: schemaContent,
+
+		EnableEffectiveAccess:   opts.EnableEffectiveAccess,
+		EnableCheckEvidence:     opts.EnableCheckEvidence,
+		EnableStrictCheck:       opts.EnableStrictCheck,
+		PoolerSafe:              opts.PoolerSafe,
+		EnableCheckMemo:         opts.EnableCheckMemo,
+		TableRoutedDispatcher:   opts.TableRoutedDispatcher,
+		AnytimeListObjects:      opts.AnytimeListObjects,
+		ClosureFunction:         opts.ClosureFunction,
+		EnableListObjectsCursor: opts.EnableListObjectsCursor,
+		ExpandWildcardSubjects:  opts.ExpandWildcardSubjects,
+		ObjectDelimiter:         opts.ObjectDelimiter,
+		TuplesTable:             opts.TuplesTable,
+		MaxDepth:                opts.MaxDepth,
+		DisableNullGuards:       opts.DisableNullGuards,
+		Dialect:                 opts.Dialect,
+		MaxFunctions:            opts.MaxFunctions,
+		StatementTimeout:        opts.StatementTimeout,
+	}
+}
+
+// AdvisoryLockKey is the pg_advisory_xact_lock key ("melange" in ASCII) that
+// MigrateWithOptions and Migrator.Rollback take inside their transaction, and
+// ApplyTx takes when ApplyTxOptions.AdvisoryLock is set. Callers that
+// serialize migrations themselves can take the same key to exclude melange.
+const AdvisoryLockKey int64 = 0x6d656c616e6765
+
+// ApplyTxOptions controls ApplyTx.
+type ApplyTxOptions struct {
+	// SchemaContent is the raw schema text. When set, ApplyTx skips unchanged
+	// schemas and records the migration in melange_migrations, exactly like
+	// MigrateWithOptions. When empty, the functions are always applied and no
+	// record is written.
+	SchemaContent string
+
+	// Force re-applies even if schema/codegen unchanged.
+	Force bool
+
+	// Version is the melange CLI/library version recorded in melange_migrations.
+	Version string
+
+	// DatabaseSchema is the Postgres schema where the objects will be created.
+	DatabaseSchema string
+
+	// AdvisoryLock takes pg_advisory_xact_lock(AdvisoryLockKey) on tx before
+	// reading migration state, so concurrent ApplyTx calls queue instead of
+	// racing. The lock is released when the caller commits or rolls back.
+	// Leave it off when the caller's migration framework already serializes
+	// migrations.
+	AdvisoryLock bool
+
+	// EnableEffectiveAccess, EnableCheckEvidence, EnableStrictCheck,
+	// PoolerSafe, EnableCheckMemo, TableRoutedDispatcher, AnytimeListObjects,
+	// ClosureFunction, EnableListObjectsCursor,
+	// ExpandWildcardSubjects, ObjectDelimiter, TuplesTable, MaxDepth,
+	// DisableNullGuards, Dialect and MaxFunctions match the MigrateOptions
+	// fields of the same name.
+	EnableEffectiveAccess   bool
+	EnableCheckEvidence     bool
+	EnableStrictCheck       bool
+	PoolerSafe              bool
+	EnableCheckMemo         bool
+	TableRoutedDispatcher   bool
+	AnytimeListObjects      bool
+	ClosureFunction         bool
+	EnableListObjectsCursor bool
+	ExpandWildcardSubjects  bool
+	ObjectDelimiter         string
+	TuplesTable             string
+	MaxDepth                int
+	DisableNullGuards       bool
+	Dialect                 string
+	MaxFunctions            int
+}
+
+// ApplyTx applies the generated SQL for types on a caller-managed
+// transaction, so melange can be one step of a composite migration. It runs
+// the same validation, skip detection, orphan cleanup and record keeping as
+// MigrateWithOptions, but never begins, commits or rolls back: the caller owns
+// tx, and on error must roll it back.
+//
+// Example inside a framework-managed migration:
+//
+//	types, err := parser.ParseSchemaString(embeddedSchema)
+//	if err != nil {
+//	    return err
+//	}
+//	_, err = migrator.ApplyTx(ctx, tx, types, migrator.ApplyTxOptions{
+//	    SchemaContent: embeddedSchema,
+//	})
+//	return err // the framework commits tx with its other steps
+//
+// Returns skipped=true when the schema is unchanged since the last recorded
+// migration (only when SchemaContent is set and Force is false).
+func ApplyTx(ctx context.Context, tx *sql.Tx, types []TypeDefinition, opts ApplyTxOptions) (skipped bool, err error) {
+	m := NewMigrator(tx, "")
+	m.SetDatabaseSchema(opts.DatabaseSchema)
+	m.SetTuplesTable(opts.TuplesTable)
+
+	if opts.AdvisoryLock {
+		if err := lockMigrations(ctx, tx); err != nil {
+			return false, err
+		}
+	}
+
+	// *sql.Tx has no BeginTx, so migrateWithTypesAndOptions runs every
+	// statement directly on tx and leaves the commit to the caller.
+	return m.migrateWithTypesAndOptions(ctx, types, InternalMigrateOptions{
+		Force:         opts.Force,
+		Version:       opts.Version,
+		SchemaContent: opts.SchemaContent,
+
+		EnableEffectiveAccess:   opts.EnableEffectiveAccess,
+		EnableCheckEvidence:     opts.EnableCheckEvidence,
+		EnableStrictCheck:       opts.EnableStrictCheck,
+		PoolerSafe:              opts.PoolerSafe,
+		EnableCheckMemo:         opts.EnableCheckMemo,
+		TableRoutedDispatcher:   opts.TableRoutedDispatcher,
+		AnytimeListObjects:      opts.AnytimeListObjects,
+		ClosureFunction:         opts.ClosureFunction,
+		EnableListObjectsCursor: opts.EnableListObjectsCursor,
+		ExpandWildcardSubjects:  opts.ExpandWildcardSubjects,
+		ObjectDelimiter:         opts.ObjectDelimiter,
+		TuplesTable:             opts.TuplesTable,
+		MaxDepth:                opts.MaxDepth,
+		DisableNullGuards:       opts.DisableNullGuards,
+		Dialect:                 opts.Dialect,
+		MaxFunctions:            opts.MaxFunctions,
+	})
+}
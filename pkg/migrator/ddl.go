@@ -19,9 +19,16 @@ func migrationsDDL(databaseSchema string) string {
 -- - schema_checksum: SHA256 of the schema.fga content
 -- - codegen_version: Version of the SQL generation logic
 -- - function_names: All generated function names (for orphan detection)
+-- - schema_path: Schema file the migration was applied from ('' when the
+--   schema was passed as a string)
+-- - rolled_back_at: When a rollback dropped the migration's functions (NULL
+--   while they are installed)
 --
--- The migrator checks the most recent record to determine if re-migration
--- is needed. If both checksum and codegen_version match, migration is skipped
+-- The rows form the history 'melange status' prints, so environments can be
+-- compared by what was applied to them and when.
+--
+-- The migrator checks the most recent record not rolled back to determine if
+-- re-migration is needed. If both checksum and codegen_version match, migration is skipped
 -- unless --force is specified.
 
 CREATE TABLE IF NOT EXISTS %[1]s (
@@ -103,10 +110,52 @@ func migrationsTableDDL(databaseSchema string) []string {
 		migrationsDDL(databaseSchema),
 		addMelangeVersionColumn(databaseSchema),
 		addFunctionChecksumsColumn(databaseSchema),
+		addSchemaPathColumn(databaseSchema),
+		addGenerationOptionsColumn(databaseSchema),
+		addRolledBackAtColumn(databaseSchema),
 		widenVersionColumnsDDL(databaseSchema),
 	}
 }
 
+// addSchemaPathColumn returns a query to add schema_path to existing
+// melange_migrations tables, recording which schema file each migration was
+// applied from.
+func addSchemaPathColumn(databaseSchema string) string {
+	table := sqldsl.PrefixIdent("melange_migrations", databaseSchema)
+
+	return fmt.Sprintf(`
+ALTER TABLE %s
+ADD COLUMN IF NOT EXISTS schema_path TEXT NOT NULL DEFAULT '';
+`, table)
+}
+
+// addGenerationOptionsColumn returns a query to add generation_options to
+// existing melange_migrations tables. The column holds the GenerationOptions a
+// migration generated with, so 'melange status' can rebuild the same SQL. It
+// has no default: rows written before it existed are NULL, and status falls
+// back to the default options for them.
+func addGenerationOptionsColumn(databaseSchema string) string {
+	table := sqldsl.PrefixIdent("melange_migrations", databaseSchema)
+
+	return fmt.Sprintf(`
+ALTER TABLE %s
+ADD COLUMN IF NOT EXISTS generation_options JSONB;
+`, table)
+}
+
+// addRolledBackAtColumn returns a query to add rolled_back_at to existing
+// melange_migrations tables. Rollback sets it on the rows whose functions it
+// drops instead of deleting them, so the history keeps them, and the skip
+// logic ignores rows where it is set.
+func addRolledBackAtColumn(databaseSchema string) string {
+	table := sqldsl.PrefixIdent("melange_migrations", databaseSchema)
+
+	return fmt.Sprintf(`
+ALTER TABLE %s
+ADD COLUMN IF NOT EXISTS rolled_back_at TIMESTAMPTZ;
+`, table)
+}
+
 // addFunctionChecksumsColumn return a query to add function_checksums to existing melange_migrations
 // tables. The column was not present in the original DDL, so it is applied
 // separately via ADD COLUMN IF NOT EXISTS to preserve compatibility with databases
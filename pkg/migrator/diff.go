@@ -0,0 +1,157 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/pthm/melange/pkg/parser"
+)
+
+// SchemaDiff is what Migrate would change in the database: the functions the
+// schema generates compared with the ones deployed. See Migrator.Diff.
+type SchemaDiff struct {
+	// Unchanged is set when the last recorded migration has the same schema
+	// checksum and codegen version, the case in which Migrate skips without
+	// generating anything. No functions are compared and the lists are
+	// empty. DiffOptions.Force compares the functions anyway.
+	Unchanged bool `json:"unchanged"`
+
+	// Added lists functions the schema generates that are not deployed,
+	// sorted by name.
+	Added []string `json:"added"`
+
+	// Removed lists deployed melange functions the schema no longer
+	// generates, which Migrate drops, sorted by name.
+	Removed []string `json:"removed"`
+
+	// Changed lists deployed functions whose body differs from the generated
+	// one, sorted by name.
+	Changed []FunctionChange `json:"changed"`
+}
+
+// FunctionChange is a deployed function whose body differs from the body the
+// schema generates for it.
+type FunctionChange struct {
+	Name string `json:"name"`
+
+	// Deployed is the body from pg_proc.prosrc. When the function has
+	// several overloads, it is the first one.
+	Deployed string `json:"deployed"`
+
+	// Generated is the body Migrate would install.
+	Generated string `json:"generated"`
+}
+
+// HasChanges reports whether migrating would change any function.
+func (d *SchemaDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// DiffOptions controls Migrator.Diff.
+type DiffOptions struct {
+	// Force compares the deployed functions even when the last recorded
+	// migration matches the schema, catching functions edited or dropped
+	// outside melange.
+	Force bool
+}
+
+// Diff parses the schema file and reports how the deployed functions differ
+// from the ones it generates. Like GetStatus, it generates with the options
+// the last migration recorded.
+//
+// The check mirrors Migrate's skip detection: when the last recorded
+// migration has the same schema checksum and codegen version, Diff returns
+// Unchanged without generating SQL or reading pg_proc (unless opts.Force is
+// set). Otherwise each generated function body is compared with pg_proc,
+// ignoring whitespace differences as GetStatus does.
+func (m *Migrator) Diff(ctx context.Context, opts DiffOptions) (*SchemaDiff, error) {
+	if !m.HasSchema() {
+		return nil, fmt.Errorf("no schema found at %s", m.SchemaPath())
+	}
+	types, err := parser.ParseSchema(m.SchemaPath())
+	if err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	return m.DiffWithTypes(ctx, types, opts)
+}
+
+// DiffWithTypes is Diff for pre-parsed type definitions.
+func (m *Migrator) DiffWithTypes(ctx context.Context, types []TypeDefinition, opts DiffOptions) (*SchemaDiff, error) {
+	if err := DetectCycles(types); err != nil {
+		return nil, err
+	}
+	if err := ValidateTupleToUsersets(types); err != nil {
+		return nil, err
+	}
+
+	lastMigration, err := m.GetLastMigration(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checking last migration: %w", err)
+	}
+	genOptions := m.recordedGenerationOptions(lastMigration)
+	if !opts.Force {
+		migrateOpts := genOptions.migrateOptions()
+		schemaChecksum := migrationSchemaChecksum(SchemaHash(types), migrateOpts)
+		if shouldSkipMigration(lastMigration, schemaChecksum) && optionalFunctionsMatch(lastMigration, migrateOpts) {
+			return &SchemaDiff{Unchanged: true}, nil
+		}
+	}
+
+	names, functions, err := m.generateFunctions(types, genOptions)
+	if err != nil {
+		return nil, err
+	}
+	generated := make(map[string]string, len(functions))
+	for _, nf := range functions {
+		for name, body := range functionSources(nf.SQL) {
+			generated[name] = body
+		}
+	}
+	deployed, err := m.getInstalledFunctionSources(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checking functions: %w", err)
+	}
+	current, err := m.getCurrentFunctions(ctx, m.db)
+	if err != nil {
+		return nil, fmt.Errorf("checking functions: %w", err)
+	}
+
+	d := &SchemaDiff{}
+	d.compareFunctions(names, generated, deployed, current)
+	return d, nil
+}
+
+// compareFunctions fills d from the expected function names and their
+// generated bodies, the deployed bodies by name, and the deployed functions
+// Migrate treats as its own when dropping orphans. A function without a
+// generated body is only checked for presence.
+func (d *SchemaDiff) compareFunctions(expected []string, generated map[string]string, deployed map[string][]string, current []string) {
+	d.Added, d.Removed, d.Changed = nil, nil, nil
+
+	for _, name := range expected {
+		have, ok := deployed[name]
+		if !ok {
+			d.Added = append(d.Added, name)
+			continue
+		}
+		want, known := generated[name]
+		if !known {
+			continue
+		}
+		normalized := normalizeFunctionBody(want)
+		if !slices.ContainsFunc(have, func(body string) bool { return normalizeFunctionBody(body) == normalized }) {
+			d.Changed = append(d.Changed, FunctionChange{Name: name, Deployed: have[0], Generated: want})
+		}
+	}
+	for _, name := range current {
+		if !slices.Contains(expected, name) && !slices.Contains(d.Removed, name) {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].Name < d.Changed[j].Name })
+}
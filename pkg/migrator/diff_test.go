@@ -0,0 +1,43 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaDiffCompareFunctions(t *testing.T) {
+	expected := []string{"check_doc_viewer", "list_doc_viewer_obj", "check_permission", "check_doc_owner"}
+	generated := map[string]string{
+		"check_doc_viewer":    "\nBEGIN\n    RETURN 1;\nEND;\n",
+		"list_doc_viewer_obj": "BEGIN RETURN; END;",
+		"check_permission":    "SELECT 1;",
+		"check_doc_owner":     "BEGIN RETURN 0; END;",
+	}
+	deployed := map[string][]string{
+		"check_doc_viewer":    {"BEGIN\r\n  RETURN 1;\r\nEND;"},      // current, reformatted
+		"check_permission":    {"SELECT 0;"},                         // changed
+		"check_doc_owner":     {"SELECT 2;", "BEGIN RETURN 0; END;"}, // overload matches
+		"check_removed_thing": {"SELECT 1;"},
+	}
+	current := []string{"check_doc_viewer", "check_permission", "check_doc_owner", "check_removed_thing", "check_removed_thing"}
+
+	var d SchemaDiff
+	d.compareFunctions(expected, generated, deployed, current)
+
+	if want := []string{"list_doc_viewer_obj"}; !reflect.DeepEqual(d.Added, want) {
+		t.Errorf("Added = %v, want %v", d.Added, want)
+	}
+	if want := []string{"check_removed_thing"}; !reflect.DeepEqual(d.Removed, want) {
+		t.Errorf("Removed = %v, want %v", d.Removed, want)
+	}
+	want := []FunctionChange{{Name: "check_permission", Deployed: "SELECT 0;", Generated: "SELECT 1;"}}
+	if !reflect.DeepEqual(d.Changed, want) {
+		t.Errorf("Changed = %#v, want %#v", d.Changed, want)
+	}
+	if !d.HasChanges() {
+		t.Error("HasChanges() = false, want true")
+	}
+	if (&SchemaDiff{Unchanged: true}).HasChanges() {
+		t.Error("HasChanges() = true for an unchanged diff")
+	}
+}
@@ -0,0 +1,75 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pthm/melange/lib/sqlgen"
+)
+
+// functionKinds groups generated function names for the MaxFunctions error.
+// Prefixes are matched in order, so the longer evidence prefix comes before
+// check_.
+var functionKinds = []struct{ prefix, label string }{
+	{sqlgen.EvidenceFunctionPrefix, "check_with_evidence"},
+	{"check_", "check"},
+	{"list_", "list"},
+	{"explain_", "explain"},
+	{"expand_", "expand"},
+}
+
+// checkFunctionLimit returns an error when a migration would install more
+// than limit functions. A limit of 0 disables the guard.
+//
+// The error breaks the count down by kind and lists what brings it down, so
+// an oversized catalog is caught before it reaches pg_proc rather than after
+// migrations start to crawl.
+func checkFunctionLimit(expected []string, limit int, opts InternalMigrateOptions) error {
+	if limit <= 0 || len(expected) <= limit {
+		return nil
+	}
+
+	counts := make([]int, len(functionKinds)+1)
+	for _, name := range expected {
+		kind := len(functionKinds)
+		for i, k := range functionKinds {
+			if strings.HasPrefix(name, k.prefix) {
+				kind = i
+				break
+			}
+		}
+		counts[kind]++
+	}
+	var breakdown []string
+	for i, k := range functionKinds {
+		if counts[i] > 0 {
+			breakdown = append(breakdown, fmt.Sprintf("%d %s", counts[i], k.label))
+		}
+	}
+	if other := counts[len(functionKinds)]; other > 0 {
+		breakdown = append(breakdown, fmt.Sprintf("%d other", other))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "schema compiles to %d functions, over the MaxFunctions limit of %d (%s)\nto proceed:",
+		len(expected), limit, strings.Join(breakdown, ", "))
+	if opts.EnableCheckEvidence {
+		b.WriteString("\n  - disable check evidence (--check-evidence): it adds one check_with_evidence_* function per relation")
+	}
+	if opts.EnableStrictCheck {
+		b.WriteString("\n  - disable strict checks (--strict-check): it adds one check_*_strict function per relation with userset grants")
+	}
+	if opts.EnableListObjectsCursor {
+		b.WriteString("\n  - disable list_objects cursors (--list-objects-cursor): it adds one list_*_objects_cursor function per listable relation")
+	}
+	if opts.EnableEffectiveAccess {
+		b.WriteString("\n  - disable effective access (--effective-access) if the audit function is unused")
+	}
+	b.WriteString("\n  - remove relations no caller checks or lists: each adds check, list and explain functions")
+	if !opts.TableRoutedDispatcher {
+		b.WriteString("\n  - use the table-routed dispatcher (--table-routed-dispatcher): it keeps check_permission a fixed size however many relations route through it, though the count is unchanged")
+	}
+	b.WriteString("\n  - raise the limit (--max-functions) if the catalog is expected to be this large")
+	return errors.New(b.String())
+}
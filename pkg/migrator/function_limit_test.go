@@ -0,0 +1,45 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckFunctionLimit(t *testing.T) {
+	expected := []string{
+		"check_doc_viewer", "check_doc_editor", "check_with_evidence_doc_viewer",
+		"list_doc_viewer_obj", "explain_doc_viewer", "check_permission", "melange_closure_rows",
+	}
+
+	if err := checkFunctionLimit(expected, 0, InternalMigrateOptions{}); err != nil {
+		t.Errorf("zero limit must disable the guard: %v", err)
+	}
+	if err := checkFunctionLimit(expected, len(expected), InternalMigrateOptions{}); err != nil {
+		t.Errorf("a count at the limit must pass: %v", err)
+	}
+
+	err := checkFunctionLimit(expected, 5, InternalMigrateOptions{EnableCheckEvidence: true})
+	if err == nil {
+		t.Fatal("expected an error over the limit")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"schema compiles to 7 functions, over the MaxFunctions limit of 5",
+		"(1 check_with_evidence, 3 check, 1 list, 1 explain, 1 other)",
+		"--check-evidence",
+		"--table-routed-dispatcher",
+		"--max-functions",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error missing %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "--effective-access") {
+		t.Errorf("suggested disabling an option that is off:\n%s", msg)
+	}
+
+	err = checkFunctionLimit(expected, 5, InternalMigrateOptions{TableRoutedDispatcher: true})
+	if err == nil || strings.Contains(err.Error(), "--table-routed-dispatcher") {
+		t.Errorf("suggested the table-routed dispatcher while already enabled: %v", err)
+	}
+}
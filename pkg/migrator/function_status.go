@@ -0,0 +1,164 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// createFunctionRe matches one CREATE OR REPLACE FUNCTION statement in
+// generated SQL, capturing the (optionally schema-qualified, quoted) function
+// name, the argument list, and the body between the $$ delimiters. Generated
+// argument lists never contain parentheses. A single generated SQL string
+// may hold several functions (e.g. check_permission and
+// check_permission_internal), so callers iterate all matches.
+var createFunctionRe = regexp.MustCompile(`(?s)CREATE OR REPLACE FUNCTION\s+(?:"[^"]*"\.)?"?([A-Za-z0-9_]+)"?\s*\(([^)]*)\)\s*RETURNS.*?AS \$\$(.*?)\$\$ LANGUAGE`)
+
+// functionManifest compiles types with g and returns the expected function
+// names together with each function's normalized body, keyed by name. It runs
+// the same pipeline as migration so the manifest matches what a Migrate with
+// the same options installs.
+func (m *Migrator) functionManifest(types []TypeDefinition, g GenerationOptions) (names []string, bodies map[string]string, err error) {
+	names, namedFunctions, err := m.generateFunctions(types, g)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bodies = make(map[string]string, len(namedFunctions))
+	for _, nf := range namedFunctions {
+		for name, body := range functionBodies(nf.SQL) {
+			bodies[name] = body
+		}
+	}
+	return names, bodies, nil
+}
+
+// generateFunctions compiles types with g and returns the expected function
+// names and the SQL of every generated function, including dispatchers.
+func (m *Migrator) generateFunctions(types []TypeDefinition, g GenerationOptions) (names []string, functions []NamedFunction, err error) {
+	analyses, generatedSQL, listSQL, names, err := generateWithOptions(types, m.databaseSchema, g)
+	if err != nil {
+		return nil, nil, err
+	}
+	functions = collectNamedFunctions(generatedSQL, listSQL, analyses)
+	functions = append(functions, collectDispatcherFunctions(generatedSQL, listSQL)...)
+	return names, functions, nil
+}
+
+// recordedGenerationOptions returns the options rec was generated with, or
+// the defaults apart from the migrator's tuples table when rec is nil or
+// predates the generation_options column.
+func (m *Migrator) recordedGenerationOptions(rec *MigrationRecord) GenerationOptions {
+	if rec != nil && rec.GenerationOptions != nil {
+		return *rec.GenerationOptions
+	}
+	return GenerationOptions{TuplesTable: m.tuplesTable}
+}
+
+// functionBodies extracts every function defined in sql, keyed by name, with
+// normalized bodies.
+func functionBodies(sql string) map[string]string {
+	bodies := functionSources(sql)
+	for name, body := range bodies {
+		bodies[name] = normalizeFunctionBody(body)
+	}
+	return bodies
+}
+
+// functionSources extracts every function defined in sql, keyed by name,
+// with bodies exactly as generated, which is what pg_proc.prosrc stores.
+func functionSources(sql string) map[string]string {
+	sources := make(map[string]string)
+	for _, match := range createFunctionRe.FindAllStringSubmatch(sql, -1) {
+		sources[match[1]] = match[3]
+	}
+	return sources
+}
+
+// normalizeFunctionBody collapses all whitespace runs to a single space so a
+// body compares equal to its pg_proc.prosrc regardless of line endings or
+// indentation introduced by tooling between generation and install.
+func normalizeFunctionBody(body string) string {
+	return strings.Join(strings.Fields(body), " ")
+}
+
+// getInstalledFunctionBodies returns the normalized body of every
+// melange-generated function in the database schema, keyed by name. A name
+// maps to several bodies only when overloads exist.
+func (m *Migrator) getInstalledFunctionBodies(ctx context.Context) (map[string][]string, error) {
+	installed, err := m.getInstalledFunctionSources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, bodies := range installed {
+		for i, body := range bodies {
+			bodies[i] = normalizeFunctionBody(body)
+		}
+	}
+	return installed, nil
+}
+
+// getInstalledFunctionSources is getInstalledFunctionBodies with each body
+// as stored in pg_proc.prosrc.
+func (m *Migrator) getInstalledFunctionSources(ctx context.Context) (map[string][]string, error) {
+	rows, err := m.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT p.proname, p.prosrc
+		FROM pg_proc p
+		JOIN pg_namespace n ON p.pronamespace = n.oid
+		WHERE n.nspname = %s
+		AND (
+			p.proname LIKE 'check_%%'
+			OR p.proname LIKE 'list_%%'
+			OR p.proname LIKE 'explain_%%'
+			OR p.proname LIKE 'expand_%%'
+			OR p.proname = 'melange_model_relations'
+			OR p.proname = 'melange_depth_exceeded'
+			OR p.proname = 'melange_healthcheck'
+		)
+	`, m.postgresSchema()))
+	if err != nil {
+		return nil, fmt.Errorf("querying pg_proc: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	installed := make(map[string][]string)
+	for rows.Next() {
+		var name, src string
+		if err := rows.Scan(&name, &src); err != nil {
+			return nil, fmt.Errorf("scanning function: %w", err)
+		}
+		installed[name] = append(installed[name], src)
+	}
+	return installed, rows.Err()
+}
+
+// compareFunctions fills the function fields of s by comparing the expected
+// manifest against the installed bodies. An expected function is stale when
+// none of its installed overloads matches the generated body; functions with
+// no extracted body are only checked for presence.
+func (s *Status) compareFunctions(expected []string, bodies map[string]string, installed map[string][]string) {
+	s.FunctionsExpected = len(expected)
+	s.FunctionsInstalled = 0
+	s.FunctionsMissing = nil
+	s.FunctionsStale = nil
+
+	for _, name := range expected {
+		have, ok := installed[name]
+		if !ok {
+			s.FunctionsMissing = append(s.FunctionsMissing, name)
+			continue
+		}
+		s.FunctionsInstalled++
+
+		want, known := bodies[name]
+		if known && !slices.Contains(have, want) {
+			s.FunctionsStale = append(s.FunctionsStale, name)
+		}
+	}
+
+	sort.Strings(s.FunctionsMissing)
+	sort.Strings(s.FunctionsStale)
+}
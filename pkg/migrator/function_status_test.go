@@ -0,0 +1,204 @@
+package migrator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pthm/melange/pkg/parser"
+)
+
+func TestFunctionManifest_BodyForEveryExpectedFunction(t *testing.T) {
+	types, err := parser.ParseSchemaString(`
+model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type document
+  relations
+    define owner: [user]
+    define viewer: [user, group#member] or owner
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, schema := range []string{"public", "authz"} {
+		m := NewMigrator(nil, "")
+		m.SetDatabaseSchema(schema)
+		names, bodies, err := m.functionManifest(types, GenerationOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if bodies[name] == "" {
+				t.Errorf("schema %q: no body extracted for expected function %s", schema, name)
+			}
+		}
+	}
+}
+
+func TestFunctionManifest_RecordedGenerationOptions(t *testing.T) {
+	types, err := parser.ParseSchemaString(`
+model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define owner: [user]
+    define viewer: [user] or owner
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMigrator(nil, "")
+	m.SetTuplesTable("authz.tuples")
+	if got := m.recordedGenerationOptions(nil); got != (GenerationOptions{TuplesTable: "authz.tuples"}) {
+		t.Errorf("options without a record = %+v, want the migrator's tuples table only", got)
+	}
+	recorded := GenerationOptions{PoolerSafe: true, EnableStrictCheck: true, MaxDepth: 10}
+	g := m.recordedGenerationOptions(&MigrationRecord{GenerationOptions: &recorded})
+	if g != recorded {
+		t.Fatalf("options from record = %+v, want %+v", g, recorded)
+	}
+
+	// Status must rebuild the bodies the migration installed: opt-in
+	// options change both the bodies and the expected functions.
+	defNames, defBodies, err := m.functionManifest(types, GenerationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, bodies, err := m.functionManifest(types, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) <= len(defNames) {
+		t.Errorf("strict check functions missing from expected names: %v", names)
+	}
+	changed := false
+	for name, body := range defBodies {
+		if bodies[name] != body {
+			changed = true
+		}
+	}
+	if !changed {
+		t.Error("recorded options did not change any function body")
+	}
+}
+
+func TestFunctionBodies(t *testing.T) {
+	sql := `CREATE OR REPLACE FUNCTION "authz"."check_permission"(
+    p_subject_type TEXT
+) RETURNS INTEGER AS $$
+    SELECT check_permission_internal(p_subject_type);
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION check_permission_internal(p_subject_type TEXT) RETURNS INTEGER AS $$
+BEGIN
+    RETURN 1;
+END;
+$$ LANGUAGE plpgsql STABLE;`
+
+	got := functionBodies(sql)
+	want := map[string]string{
+		"check_permission":          "SELECT check_permission_internal(p_subject_type);",
+		"check_permission_internal": "BEGIN RETURN 1; END;",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("functionBodies() = %#v, want %#v", got, want)
+	}
+}
+
+func TestStatusCompareFunctions(t *testing.T) {
+	expected := []string{"check_doc_viewer", "list_doc_viewer_obj", "check_permission", "check_doc_owner"}
+	bodies := map[string]string{
+		"check_doc_viewer":    "BEGIN RETURN 1; END;",
+		"list_doc_viewer_obj": "BEGIN RETURN; END;",
+		"check_permission":    "SELECT 1;",
+		"check_doc_owner":     "BEGIN RETURN 0; END;",
+	}
+	installed := map[string][]string{
+		"check_doc_viewer":    {normalizeFunctionBody("BEGIN\n    RETURN 1;\r\nEND;")}, // current, reformatted
+		"check_permission":    {"SELECT 0;"},                                           // stale
+		"check_doc_owner":     {"SELECT 2;", "BEGIN RETURN 0; END;"},                   // overload matches
+		"check_removed_thing": {"SELECT 1;"},                                           // orphan, not expected
+	}
+
+	var s Status
+	s.compareFunctions(expected, bodies, installed)
+
+	if s.FunctionsInstalled != 3 {
+		t.Errorf("FunctionsInstalled = %d, want 3", s.FunctionsInstalled)
+	}
+	if want := []string{"list_doc_viewer_obj"}; !reflect.DeepEqual(s.FunctionsMissing, want) {
+		t.Errorf("FunctionsMissing = %v, want %v", s.FunctionsMissing, want)
+	}
+	if want := []string{"check_permission"}; !reflect.DeepEqual(s.FunctionsStale, want) {
+		t.Errorf("FunctionsStale = %v, want %v", s.FunctionsStale, want)
+	}
+}
+
+func TestGenerateWithOptions_MatchesDryRun(t *testing.T) {
+	types, err := parser.ParseSchemaString(`
+model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type document
+  relations
+    define viewer: [user, group#member]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := MigrateOptions{
+		DatabaseSchema:  "authz",
+		TuplesTable:     "authz.tuples",
+		PoolerSafe:      true,
+		ClosureFunction: true,
+		ObjectDelimiter: "/",
+		MaxDepth:        40,
+	}
+	generatedSQL, listSQL, analyses, err := GenerateWithOptions(types, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dryRun strings.Builder
+	m := NewMigrator(nil, "")
+	m.SetDatabaseSchema(opts.DatabaseSchema)
+	m.SetTuplesTable(opts.TuplesTable)
+	internal := opts.internal("")
+	internal.DryRun = &dryRun
+	if _, err := m.migrateWithTypesAndOptions(context.Background(), types, internal); err != nil {
+		t.Fatal(err)
+	}
+
+	functions := collectNamedFunctions(generatedSQL, listSQL, analyses)
+	functions = append(functions, collectDispatcherFunctions(generatedSQL, listSQL)...)
+	for _, fn := range functions {
+		if !strings.Contains(dryRun.String(), fn.SQL) {
+			t.Errorf("%s differs from the function migrate generates", fn.Name)
+		}
+	}
+	for _, fn := range []string{generatedSQL.ClosureFunction, generatedSQL.HealthcheckFunction} {
+		if fn == "" || !strings.Contains(dryRun.String(), fn) {
+			t.Errorf("dry run is missing:\n%s", fn)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package migrator
+
+import (
+	"fmt"
+
+	"github.com/pthm/melange/lib/sqlgen"
+)
+
+// GenerationOptions are the migrate options that change the generated SQL.
+// Every migration records them in melange_migrations.generation_options, so
+// GetStatus and Diff compare the deployed functions against the SQL the last
+// migration generated rather than against the default-option SQL. The
+// fields mirror the MigrateOptions fields of the same name.
+type GenerationOptions struct {
+	EnableEffectiveAccess   bool   `json:"enable_effective_access,omitempty"`
+	EnableCheckEvidence     bool   `json:"enable_check_evidence,omitempty"`
+	EnableStrictCheck       bool   `json:"enable_strict_check,omitempty"`
+	PoolerSafe              bool   `json:"pooler_safe,omitempty"`
+	EnableCheckMemo         bool   `json:"enable_check_memo,omitempty"`
+	TableRoutedDispatcher   bool   `json:"table_routed_dispatcher,omitempty"`
+	AnytimeListObjects      bool   `json:"anytime_list_objects,omitempty"`
+	ClosureFunction         bool   `json:"closure_function,omitempty"`
+	EnableListObjectsCursor bool   `json:"enable_list_objects_cursor,omitempty"`
+	ExpandWildcardSubjects  bool   `json:"expand_wildcard_subjects,omitempty"`
+	ObjectDelimiter         string `json:"object_delimiter,omitempty"`
+	TuplesTable             string `json:"tuples_table,omitempty"`
+	MaxDepth                int    `json:"max_depth,omitempty"`
+	DisableNullGuards       bool   `json:"disable_null_guards,omitempty"`
+	Dialect                 string `json:"dialect,omitempty"`
+}
+
+// generationOptions returns the fields of opts that change the generated SQL.
+func (opts InternalMigrateOptions) generationOptions() GenerationOptions {
+	return GenerationOptions{
+		EnableEffectiveAccess:   opts.EnableEffectiveAccess,
+		EnableCheckEvidence:     opts.EnableCheckEvidence,
+		EnableStrictCheck:       opts.EnableStrictCheck,
+		PoolerSafe:              opts.PoolerSafe,
+		EnableCheckMemo:         opts.EnableCheckMemo,
+		TableRoutedDispatcher:   opts.TableRoutedDispatcher,
+		AnytimeListObjects:      opts.AnytimeListObjects,
+		ClosureFunction:         opts.ClosureFunction,
+		EnableListObjectsCursor: opts.EnableListObjectsCursor,
+		ExpandWildcardSubjects:  opts.ExpandWildcardSubjects,
+		ObjectDelimiter:         opts.ObjectDelimiter,
+		TuplesTable:             opts.TuplesTable,
+		MaxDepth:                opts.MaxDepth,
+		DisableNullGuards:       opts.DisableNullGuards,
+		Dialect:                 opts.Dialect,
+	}
+}
+
+// migrateOptions returns the InternalMigrateOptions a migration recording g
+// ran with, as far as they affect the generated SQL.
+func (g GenerationOptions) migrateOptions() InternalMigrateOptions {
+	return InternalMigrateOptions{
+		EnableEffectiveAccess:   g.EnableEffectiveAccess,
+		EnableCheckEvidence:     g.EnableCheckEvidence,
+		EnableStrictCheck:       g.EnableStrictCheck,
+		PoolerSafe:              g.PoolerSafe,
+		EnableCheckMemo:         g.EnableCheckMemo,
+		TableRoutedDispatcher:   g.TableRoutedDispatcher,
+		AnytimeListObjects:      g.AnytimeListObjects,
+		ClosureFunction:         g.ClosureFunction,
+		EnableListObjectsCursor: g.EnableListObjectsCursor,
+		ExpandWildcardSubjects:  g.ExpandWildcardSubjects,
+		ObjectDelimiter:         g.ObjectDelimiter,
+		TuplesTable:             g.TuplesTable,
+		MaxDepth:                g.MaxDepth,
+		DisableNullGuards:       g.DisableNullGuards,
+		Dialect:                 g.Dialect,
+	}
+}
+
+// sqlOptions returns the sqlgen options g generates with, for a schema with
+// the given SchemaHash.
+func (g GenerationOptions) sqlOptions(schemaHash string) sqlgen.GenerateSQLOptions {
+	return sqlgen.GenerateSQLOptions{
+		EnableEffectiveAccess:   g.EnableEffectiveAccess,
+		EnableCheckEvidence:     g.EnableCheckEvidence,
+		EnableStrictCheck:       g.EnableStrictCheck,
+		PoolerSafe:              g.PoolerSafe,
+		EnableCheckMemo:         g.EnableCheckMemo,
+		TableRoutedDispatcher:   g.TableRoutedDispatcher,
+		AnytimeListObjects:      g.AnytimeListObjects,
+		ClosureFunction:         g.ClosureFunction,
+		EnableListObjectsCursor: g.EnableListObjectsCursor,
+		ExpandWildcardSubjects:  g.ExpandWildcardSubjects,
+		ObjectDelimiter:         g.ObjectDelimiter,
+		TuplesTable:             g.TuplesTable,
+		MaxDepth:                g.MaxDepth,
+		SchemaHash:              schemaHash,
+		DisableNullGuards:       g.DisableNullGuards,
+		Dialect:                 sqlgen.Dialect(g.Dialect),
+	}
+}
+
+// expectedFunctionNames returns the functions a migration generating
+// generatedSQL and listSQL from analyses with g installs: those every schema
+// compiles to, plus the opt-in functions g enables.
+func expectedFunctionNames(analyses []sqlgen.RelationAnalysis, generatedSQL GeneratedSQL, listSQL ListGeneratedSQL, g GenerationOptions) []string {
+	names := CollectFunctionNames(analyses)
+	if generatedSQL.ContextualTuplesFunction != "" {
+		names = append(names, sqlgen.ContextualTuplesFunctionName)
+	}
+	if generatedSQL.EffectiveAccessFunction != "" {
+		names = append(names, "effective_access")
+	}
+	if g.EnableCheckEvidence {
+		names = append(names, sqlgen.CollectEvidenceFunctionNames(analyses)...)
+	}
+	if g.EnableStrictCheck {
+		names = append(names, sqlgen.CollectStrictFunctionNames(analyses)...)
+	}
+	if g.EnableCheckMemo {
+		names = append(names, sqlgen.CheckMemoRouteFunction)
+	}
+	if generatedSQL.ClosureFunction != "" {
+		names = append(names, sqlgen.ClosureFunctionName, sqlgen.UsersetFunctionName)
+	}
+	if g.EnableListObjectsCursor {
+		names = append(names, sqlgen.CollectListObjectsCursorFunctionNames(analyses)...)
+	}
+	return names
+}
+
+// GenerateWithOptions compiles types into the check and list SQL
+// MigrateWithOptions would install with opts, healthcheck included, and
+// returns it with the analyses it was generated from. Only the options that
+// change the generated SQL and DatabaseSchema are read. types must already
+// have passed DetectCycles and ValidateTupleToUsersets.
+//
+// It lets tools that write the SQL out without a database, such as
+// 'melange generate sql', produce exactly what a migration applies.
+func GenerateWithOptions(types []TypeDefinition, opts MigrateOptions) (GeneratedSQL, ListGeneratedSQL, []sqlgen.RelationAnalysis, error) {
+	analyses, generatedSQL, listSQL, _, err := generateWithOptions(types, opts.DatabaseSchema, opts.internal("").generationOptions())
+	return generatedSQL, listSQL, analyses, err
+}
+
+// generateWithOptions compiles types with g and returns the analyses, the
+// check and list SQL with the healthcheck filled in, and the expected
+// function names the healthcheck lists.
+func generateWithOptions(types []TypeDefinition, databaseSchema string, g GenerationOptions) (analyses []sqlgen.RelationAnalysis, generatedSQL GeneratedSQL, listSQL ListGeneratedSQL, names []string, err error) {
+	closureRows := ComputeRelationClosure(types)
+	analyses = AnalyzeRelations(types, closureRows)
+	analyses = computeCanGenerateDepth(analyses, g.MaxDepth)
+	inline := buildInlineSQLData(closureRows, analyses)
+	genOpts := g.sqlOptions(SchemaHash(types))
+	generatedSQL, err = GenerateSQLWithOptions(analyses, inline, databaseSchema, genOpts)
+	if err != nil {
+		return nil, GeneratedSQL{}, ListGeneratedSQL{}, nil, fmt.Errorf("generating check SQL: %w", err)
+	}
+	listSQL, err = generateListSQLWithOptions(analyses, inline, databaseSchema, genOpts)
+	if err != nil {
+		return nil, GeneratedSQL{}, ListGeneratedSQL{}, nil, fmt.Errorf("generating list SQL: %w", err)
+	}
+
+	names = expectedFunctionNames(analyses, generatedSQL, listSQL, g)
+	generatedSQL.HealthcheckFunction = sqlgen.GenerateHealthcheckFunction(databaseSchema, g.TuplesTable, SchemaHash(types), names, genOpts.Dialect)
+	return analyses, generatedSQL, listSQL, names, nil
+}
@@ -0,0 +1,226 @@
+package migrator
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Manifest records the functions a schema generates: their names, argument
+// types, and normalized bodies. Committed alongside the schema, it lets
+// `melange doctor --baseline` check that a database holds exactly the
+// functions that commit would install.
+type Manifest struct {
+	// Functions are sorted by name, then arguments.
+	Functions []ManifestFunction `json:"functions"`
+}
+
+// ManifestFunction describes one installed or generated function.
+type ManifestFunction struct {
+	Name string `json:"name"`
+
+	// Arguments lists the input argument types as PostgreSQL reports them,
+	// e.g. "text, text, integer, text[]".
+	Arguments string `json:"arguments"`
+
+	// BodySHA256 is the hex SHA256 of the body with whitespace runs collapsed,
+	// so reformatting between generation and install does not count as a change.
+	BodySHA256 string `json:"body_sha256"`
+}
+
+// Signature returns the function as "name(arguments)".
+func (f ManifestFunction) Signature() string {
+	return f.Name + "(" + f.Arguments + ")"
+}
+
+// GenerateManifest compiles types and returns the manifest of the functions
+// Migrate would install, generated with default options apart from the
+// migrator's tuples table.
+func (m *Migrator) GenerateManifest(types []TypeDefinition) (*Manifest, error) {
+	_, functions, err := m.generateFunctions(types, GenerationOptions{TuplesTable: m.tuplesTable})
+	if err != nil {
+		return nil, err
+	}
+
+	mf := &Manifest{}
+	seen := make(map[string]bool)
+	for _, nf := range functions {
+		for _, match := range createFunctionRe.FindAllStringSubmatch(nf.SQL, -1) {
+			fn := ManifestFunction{
+				Name:       match[1],
+				Arguments:  canonicalArguments(match[2]),
+				BodySHA256: bodyChecksum(match[3]),
+			}
+			if seen[fn.Signature()] {
+				continue
+			}
+			seen[fn.Signature()] = true
+			mf.Functions = append(mf.Functions, fn)
+		}
+	}
+	mf.sort()
+	return mf, nil
+}
+
+// InstalledManifest returns the manifest of the melange-generated functions
+// installed in the database schema.
+func (m *Migrator) InstalledManifest(ctx context.Context) (*Manifest, error) {
+	rows, err := m.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT p.proname, oidvectortypes(p.proargtypes), p.prosrc
+		FROM pg_proc p
+		JOIN pg_namespace n ON p.pronamespace = n.oid
+		WHERE n.nspname = %s
+		AND (
+			p.proname LIKE 'check_%%'
+			OR p.proname LIKE 'list_%%'
+			OR p.proname LIKE 'explain_%%'
+			OR p.proname LIKE 'expand_%%'
+			OR p.proname = 'melange_model_relations'
+			OR p.proname = 'melange_depth_exceeded'
+			OR p.proname = 'melange_healthcheck'
+		)
+	`, m.postgresSchema()))
+	if err != nil {
+		return nil, fmt.Errorf("querying pg_proc: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	mf := &Manifest{}
+	for rows.Next() {
+		var fn ManifestFunction
+		var src string
+		if err := rows.Scan(&fn.Name, &fn.Arguments, &src); err != nil {
+			return nil, fmt.Errorf("scanning function: %w", err)
+		}
+		fn.BodySHA256 = bodyChecksum(src)
+		mf.Functions = append(mf.Functions, fn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	mf.sort()
+	return mf, nil
+}
+
+// ReadManifest decodes a manifest written by Manifest.Write.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	var mf Manifest
+	if err := json.NewDecoder(r).Decode(&mf); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+	mf.sort()
+	return &mf, nil
+}
+
+// Write encodes the manifest as indented JSON so committed manifests diff
+// cleanly.
+func (mf *Manifest) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(mf)
+}
+
+func (mf *Manifest) sort() {
+	slices.SortFunc(mf.Functions, func(a, b ManifestFunction) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Arguments, b.Arguments))
+	})
+}
+
+// ManifestDrift lists the differences between a baseline manifest and an
+// installed one. Functions are identified by signature, so a function whose
+// arguments changed appears as both removed and added.
+type ManifestDrift struct {
+	// Added are installed functions missing from the baseline.
+	Added []string
+	// Removed are baseline functions that are not installed.
+	Removed []string
+	// Changed are functions installed with a different body.
+	Changed []string
+}
+
+// Clean reports whether the installed functions match the baseline exactly.
+func (d ManifestDrift) Clean() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// CompareManifests reports how installed differs from baseline. Results are
+// sorted signatures ("name(arguments)").
+func CompareManifests(baseline, installed *Manifest) ManifestDrift {
+	want := make(map[string]string, len(baseline.Functions))
+	for _, fn := range baseline.Functions {
+		want[fn.Signature()] = fn.BodySHA256
+	}
+	have := make(map[string]string, len(installed.Functions))
+	for _, fn := range installed.Functions {
+		have[fn.Signature()] = fn.BodySHA256
+	}
+
+	var d ManifestDrift
+	for sig, body := range have {
+		wantBody, ok := want[sig]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, sig)
+		case wantBody != body:
+			d.Changed = append(d.Changed, sig)
+		}
+	}
+	for sig := range want {
+		if _, ok := have[sig]; !ok {
+			d.Removed = append(d.Removed, sig)
+		}
+	}
+	slices.Sort(d.Added)
+	slices.Sort(d.Removed)
+	slices.Sort(d.Changed)
+	return d
+}
+
+func bodyChecksum(body string) string {
+	h := sha256.Sum256([]byte(normalizeFunctionBody(body)))
+	return hex.EncodeToString(h[:])
+}
+
+// canonicalArguments renders a generated argument list the way
+// oidvectortypes reports installed ones: input types only, lower case,
+// with PostgreSQL's names for type aliases.
+func canonicalArguments(args string) string {
+	var types []string
+	for _, arg := range strings.Split(args, ",") {
+		fields := strings.Fields(arg)
+		if len(fields) < 2 {
+			continue
+		}
+		// fields[0] is the parameter name; a DEFAULT clause ends the type.
+		typeFields := fields[1:]
+		if i := slices.IndexFunc(typeFields, func(f string) bool { return strings.EqualFold(f, "DEFAULT") }); i >= 0 {
+			typeFields = typeFields[:i]
+		}
+		typ := strings.ToLower(strings.Join(typeFields, " "))
+		base, array := typ, ""
+		if i := strings.Index(typ, "["); i >= 0 {
+			base, array = strings.TrimSpace(typ[:i]), strings.ReplaceAll(typ[i:], " ", "")
+		}
+		if alias, ok := postgresTypeAliases[base]; ok {
+			base = alias
+		}
+		types = append(types, base+array)
+	}
+	return strings.Join(types, ", ")
+}
+
+// postgresTypeAliases maps type names accepted in generated SQL to the names
+// PostgreSQL reports for them.
+var postgresTypeAliases = map[string]string{
+	"int":     "integer",
+	"int4":    "integer",
+	"int8":    "bigint",
+	"bool":    "boolean",
+	"varchar": "character varying",
+}
@@ -0,0 +1,139 @@
+package migrator
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/pthm/melange/pkg/parser"
+)
+
+func TestGenerateManifest(t *testing.T) {
+	types, err := parser.ParseSchemaString(`
+model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type document
+  relations
+    define owner: [user]
+    define viewer: [user, group#member] or owner
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMigrator(nil, "")
+	names, _, err := m.functionManifest(types, GenerationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mf, err := m.GenerateManifest(types)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, fn := range mf.Functions {
+		got = append(got, fn.Name)
+		if fn.BodySHA256 == "" {
+			t.Errorf("%s: empty body checksum", fn.Signature())
+		}
+	}
+	for _, name := range names {
+		if !slices.Contains(got, name) {
+			t.Errorf("manifest is missing expected function %s", name)
+		}
+	}
+	if !slices.IsSorted(got) {
+		t.Errorf("manifest functions are not sorted: %v", got)
+	}
+
+	// Overloads are listed separately, by signature.
+	for _, want := range []string{
+		"check_permission(text, text, text, text, text)",
+		"check_permission(text, text, text)",
+	} {
+		if !slices.ContainsFunc(mf.Functions, func(fn ManifestFunction) bool { return fn.Signature() == want }) {
+			t.Errorf("manifest is missing %s", want)
+		}
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	mf := &Manifest{Functions: []ManifestFunction{
+		{Name: "check_permission", Arguments: "text, text, text, text, text", BodySHA256: "aa"},
+		{Name: "check_doc_viewer", Arguments: "text, text, text, text[]", BodySHA256: "bb"},
+	}}
+
+	var buf bytes.Buffer
+	if err := mf.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadManifest(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ManifestFunction{mf.Functions[1], mf.Functions[0]}
+	if !reflect.DeepEqual(got.Functions, want) {
+		t.Errorf("ReadManifest() = %#v, want %#v", got.Functions, want)
+	}
+}
+
+func TestCanonicalArguments(t *testing.T) {
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"p_subject_type TEXT, p_subject_id TEXT", "text, text"},
+		{"\n    p_visited TEXT [] DEFAULT ARRAY[]::TEXT[]\n", "text[]"},
+		{"p_limit INT DEFAULT NULL, p_after TEXT DEFAULT NULL", "integer, text"},
+		{"p_max_nodes INTEGER DEFAULT NULL, p_ids TEXT[]", "integer, text[]"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := canonicalArguments(tt.args); got != tt.want {
+			t.Errorf("canonicalArguments(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCompareManifests(t *testing.T) {
+	baseline := &Manifest{Functions: []ManifestFunction{
+		{Name: "check_doc_viewer", Arguments: "text, text, text, text[]", BodySHA256: "viewer"},
+		{Name: "check_doc_owner", Arguments: "text, text, text, text[]", BodySHA256: "owner"},
+		{Name: "list_doc_viewer_obj", Arguments: "text, text, integer, text", BodySHA256: "list"},
+		{Name: "check_permission", Arguments: "text, text, text, text, text", BodySHA256: "dispatch"},
+	}}
+	installed := &Manifest{Functions: []ManifestFunction{
+		{Name: "check_doc_viewer", Arguments: "text, text, text, text[]", BodySHA256: "viewer"},       // current
+		{Name: "check_doc_owner", Arguments: "text, text, text, text[]", BodySHA256: "edited"},        // changed
+		{Name: "list_doc_viewer_obj", Arguments: "text, text", BodySHA256: "list"},                    // signature changed
+		{Name: "check_permission", Arguments: "text, text, text, text, text", BodySHA256: "dispatch"}, // current
+		{Name: "check_doc_legacy", Arguments: "text, text, text, text[]", BodySHA256: "legacy"},       // added
+	}}
+
+	d := CompareManifests(baseline, installed)
+	if d.Clean() {
+		t.Fatal("expected drift")
+	}
+	if want := []string{"check_doc_legacy(text, text, text, text[])", "list_doc_viewer_obj(text, text)"}; !reflect.DeepEqual(d.Added, want) {
+		t.Errorf("Added = %v, want %v", d.Added, want)
+	}
+	if want := []string{"list_doc_viewer_obj(text, text, integer, text)"}; !reflect.DeepEqual(d.Removed, want) {
+		t.Errorf("Removed = %v, want %v", d.Removed, want)
+	}
+	if want := []string{"check_doc_owner(text, text, text, text[])"}; !reflect.DeepEqual(d.Changed, want) {
+		t.Errorf("Changed = %v, want %v", d.Changed, want)
+	}
+
+	if d := CompareManifests(baseline, baseline); !d.Clean() {
+		t.Errorf("CompareManifests(baseline, baseline) = %+v, want clean", d)
+	}
+}
@@ -9,14 +9,18 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
 
 	"github.com/pthm/melange/lib/sqlgen"
 	"github.com/pthm/melange/lib/sqlgen/sqldsl"
 	"github.com/pthm/melange/lib/version"
+	"github.com/pthm/melange/pkg/parser"
 	"github.com/pthm/melange/pkg/schema"
 )
 
@@ -32,15 +36,21 @@ type NamedFunction = sqlgen.NamedFunction
 
 // Function aliases from schema and sqlgen packages.
 var (
-	DetectCycles           = schema.DetectCycles
-	ComputeRelationClosure = schema.ComputeRelationClosure
-	AnalyzeRelations       = sqlgen.AnalyzeRelations
-	ComputeCanGenerate     = sqlgen.ComputeCanGenerate
-	buildInlineSQLData     = sqlgen.BuildInlineSQLData
-	GenerateSQL            = sqlgen.GenerateSQL
-	GenerateListSQL        = sqlgen.GenerateListSQL
-	CollectFunctionNames   = sqlgen.CollectFunctionNames
-	collectNamedFunctions  = sqlgen.CollectNamedFunctions
+	DetectCycles            = schema.DetectCycles
+	ValidateTupleToUsersets = schema.ValidateTupleToUsersets
+	ComputeRelationClosure  = schema.ComputeRelationClosure
+	SchemaHash              = schema.SchemaHash
+	AnalyzeRelations        = sqlgen.AnalyzeRelations
+	ComputeCanGenerate      = sqlgen.ComputeCanGenerate
+	computeCanGenerateDepth = sqlgen.ComputeCanGenerateWithMaxDepth
+	buildInlineSQLData      = sqlgen.BuildInlineSQLData
+	GenerateSQL             = sqlgen.GenerateSQL
+	GenerateSQLWithOptions  = sqlgen.GenerateSQLWithOptions
+	GenerateListSQL         = sqlgen.GenerateListSQL
+	CollectFunctionNames    = sqlgen.CollectFunctionNames
+	collectNamedFunctions   = sqlgen.CollectNamedFunctions
+
+	generateListSQLWithOptions = sqlgen.GenerateListSQLWithOptions
 
 	collectDispatcherFunctions = sqlgen.CollectDispatcherFunctions
 )
@@ -58,15 +68,122 @@ type MigrateOptions struct {
 	// If nil, migration proceeds normally. Use for previewing migrations or generating migration scripts.
 	DryRun io.Writer
 
+	// NoTransaction leaves BEGIN and COMMIT out of the DryRun script, for
+	// migration tools that wrap each file in their own transaction.
+	NoTransaction bool
+
 	// Force re-runs migration even if schema/codegen unchanged. Use when manually fixing corrupted state or testing.
 	Force bool
 
+	// Down rolls back instead of migrating: it drops the functions recorded
+	// in melange_migrations and marks their records rolled back, leaving
+	// melange_tuples untouched. The schema file is not read. See
+	// Migrator.Rollback.
+	Down bool
+
 	// Version is the melange CLI/library version (e.g., "v0.4.3").
 	// Recorded in melange_migrations for traceability.
 	Version string
 
 	// DatabaseSchema is the Postgres schema where the objects will be created.
 	DatabaseSchema string
+
+	// EnableEffectiveAccess also installs the effective_access audit function.
+	// It composes many list functions per call, so it is opt-in.
+	EnableEffectiveAccess bool
+
+	// EnableCheckEvidence also installs check_with_evidence_{type}_{relation},
+	// which returns the tuple that granted access alongside the check result.
+	// It is meant for audit logging, not request paths, so it is opt-in.
+	// See sqlgen.GenerateSQLOptions.EnableCheckEvidence.
+	EnableCheckEvidence bool
+
+	// EnableStrictCheck also installs check_permission_strict, which resolves
+	// every userset grant through check_permission_internal instead of
+	// joining membership tuples directly. It is slower than check_permission
+	// and adds a function per relation with userset grants, so it is opt-in.
+	// See sqlgen.GenerateSQLOptions.EnableStrictCheck.
+	EnableStrictCheck bool
+
+	// PoolerSafe generates functions that read no session-level settings,
+	// for databases reached through a transaction-mode pooler (PgBouncer).
+	// See sqlgen.GenerateSQLOptions.PoolerSafe.
+	PoolerSafe bool
+
+	// EnableCheckMemo memoizes repeated sub-checks within each check_permission
+	// call. It makes check_permission PARALLEL UNSAFE, so it is opt-in.
+	// See sqlgen.GenerateSQLOptions.EnableCheckMemo.
+	EnableCheckMemo bool
+
+	// TableRoutedDispatcher routes check_permission through the melange_routes
+	// table instead of a per-relation IF-chain. It trades per-call speed for a
+	// first-call cost that does not grow with the schema, so it is opt-in.
+	// See sqlgen.GenerateSQLOptions.TableRoutedDispatcher.
+	TableRoutedDispatcher bool
+
+	// AnytimeListObjects makes unpaged recursive list_objects calls return
+	// base-level grants before objects found through the recursion. The set
+	// is unchanged, only its order.
+	// See sqlgen.GenerateSQLOptions.AnytimeListObjects.
+	AnytimeListObjects bool
+
+	// ClosureFunction installs melange_closure_rows and melange_userset_rows
+	// and has check and list functions call them instead of each inlining the
+	// relation closure and userset rows.
+	// See sqlgen.GenerateSQLOptions.ClosureFunction.
+	ClosureFunction bool
+
+	// EnableListObjectsCursor also installs list_accessible_objects_cursor
+	// and list_{type}_{relation}_objects_cursor, which return a refcursor a
+	// client can FETCH in batches. It adds a function per listable relation,
+	// so it is opt-in.
+	// See sqlgen.GenerateSQLOptions.EnableListObjectsCursor.
+	EnableListObjectsCursor bool
+
+	// ExpandWildcardSubjects makes list_subjects calls with p_expand_wildcard
+	// read the subjects a wildcard covers from the melange_subjects view,
+	// which the user creates in the database schema, instead of from tuples.
+	// See sqlgen.GenerateSQLOptions.ExpandWildcardSubjects.
+	ExpandWildcardSubjects bool
+
+	// ObjectDelimiter separates type from id in the "type:id" strings taken
+	// by the string overloads of check_permission and the list dispatchers.
+	// Empty means ":". Callers must build their strings with the same
+	// delimiter. See sqlgen.GenerateSQLOptions.ObjectDelimiter.
+	ObjectDelimiter string
+
+	// TuplesTable is the relation generated functions read tuples from, e.g.
+	// "tenant_42.tuples". Empty means melange_tuples. Status and doctor
+	// checks look for the same relation.
+	// See sqlgen.GenerateSQLOptions.TuplesTable.
+	TuplesTable string
+
+	// MaxDepth bounds how deep generated functions follow recursive relations
+	// before raising M2002. Zero means sqlgen.DefaultMaxDepth (25).
+	// See sqlgen.GenerateSQLOptions.MaxDepth.
+	MaxDepth int
+
+	// DisableNullGuards drops the checks that make generated check and list
+	// functions raise 22004 when a required argument is NULL, saving a
+	// branch per call. See sqlgen.GenerateSQLOptions.DisableNullGuards.
+	DisableNullGuards bool
+
+	// Dialect is the database the generated functions target. Empty means
+	// sqlgen.DialectPostgres. See sqlgen.GenerateSQLOptions.Dialect.
+	Dialect string
+
+	// MaxFunctions fails the migration, before anything is applied, when the
+	// schema compiles to more functions than this. The error breaks the count
+	// down and suggests how to reduce it. Zero means no limit.
+	MaxFunctions int
+
+	// StatementTimeout bounds every statement the migration runs after taking
+	// the advisory lock, with SET LOCAL statement_timeout, so a function
+	// creation stuck behind a lock fails the migration instead of hanging the
+	// deploy. The transaction is rolled back as on any other error. Zero
+	// leaves the session's setting alone. SET LOCAL only lasts until the
+	// transaction ends, so it has no effect when db is a *sql.Conn.
+	StatementTimeout time.Duration
 }
 
 // InternalMigrateOptions extends MigrateOptions with internal fields.
@@ -74,13 +191,69 @@ type InternalMigrateOptions struct {
 	DryRun io.Writer
 	Force  bool
 
+	// NoTransaction leaves BEGIN and COMMIT out of the DryRun script.
+	NoTransaction bool
+
 	// Version is the melange CLI/library version (e.g., "v0.4.3").
 	// Recorded in melange_migrations for traceability.
 	Version string
 
-	// SchemaContent is the raw schema text used for checksum calculation to detect schema changes.
-	// If empty, skip-if-unchanged optimization is disabled.
+	// SchemaContent is the raw schema text. When set, skip-if-unchanged is
+	// enabled and the run is recorded in melange_migrations; if empty, both are
+	// disabled. The recorded checksum is the SchemaHash of the parsed types, so
+	// formatting-only edits to the text do not count as a change.
 	SchemaContent string
+
+	// EnableEffectiveAccess also installs the effective_access audit function.
+	EnableEffectiveAccess bool
+
+	// EnableCheckEvidence also installs the check_with_evidence_* audit functions.
+	EnableCheckEvidence bool
+
+	// EnableStrictCheck also installs check_permission_strict and its variants.
+	EnableStrictCheck bool
+
+	// PoolerSafe generates functions that read no session-level settings.
+	PoolerSafe bool
+
+	// EnableCheckMemo memoizes repeated sub-checks within each check_permission call.
+	EnableCheckMemo bool
+
+	// TableRoutedDispatcher routes check_permission through the melange_routes table.
+	TableRoutedDispatcher bool
+
+	// AnytimeListObjects returns base-level grants first from unpaged recursive list_objects calls.
+	AnytimeListObjects bool
+
+	// ClosureFunction has check and list functions look the closure and userset rows up through functions.
+	ClosureFunction bool
+
+	// EnableListObjectsCursor also installs the refcursor list_objects functions.
+	EnableListObjectsCursor bool
+
+	// ExpandWildcardSubjects expands wildcards from the melange_subjects view.
+	ExpandWildcardSubjects bool
+
+	// ObjectDelimiter separates type from id in "type:id" strings. Empty means ":".
+	ObjectDelimiter string
+
+	// TuplesTable is the relation generated functions read tuples from. Empty means melange_tuples.
+	TuplesTable string
+
+	// MaxDepth bounds recursion in generated functions. Zero means sqlgen.DefaultMaxDepth.
+	MaxDepth int
+
+	// DisableNullGuards drops the NULL argument checks from generated functions.
+	DisableNullGuards bool
+
+	// Dialect is the database the generated functions target. Empty means postgres.
+	Dialect string
+
+	// MaxFunctions fails the migration when the schema compiles to more functions. Zero means no limit.
+	MaxFunctions int
+
+	// StatementTimeout bounds each statement after the advisory lock. Zero means no bound.
+	StatementTimeout time.Duration
 }
 
 // MigrationRecord represents a row in the melange_migrations table.
@@ -95,6 +268,9 @@ type MigrationRecord struct {
 	// older versions; callers should treat nil as "no checksum data available" and
 	// fall back to full-mode generation.
 	FunctionChecksums map[string]string
+	// GenerationOptions are the options the migration generated its SQL with.
+	// Nil on records written before the generation_options column existed.
+	GenerationOptions *GenerationOptions
 }
 
 // Migrator handles loading authorization schemas into PostgreSQL.
@@ -125,10 +301,12 @@ type Migrator struct {
 	db             Execer
 	schemaPath     string
 	databaseSchema string
+	tuplesTable    string
 }
 
 // NewMigrator creates a new schema migrator.
-// The schemaPath should point to an OpenFGA DSL schema file (e.g., "schemas/schema.fga").
+// The schemaPath should point to an OpenFGA DSL schema file (e.g., "schemas/schema.fga"),
+// an fga.mod manifest, or a directory of .fga files (see parser.ParseSchema).
 // The Execer is typically *sql.DB but can be *sql.Tx for testing.
 func NewMigrator(db Execer, schemaPath string) *Migrator {
 	return &Migrator{db: db, schemaPath: schemaPath, databaseSchema: "public"}
@@ -149,6 +327,19 @@ func (m *Migrator) DatabaseSchema() string {
 	return m.databaseSchema
 }
 
+// SetTuplesTable sets the tuples relation MigrateWithTypes generates
+// functions against and GetStatus looks for and compares functions against.
+// Empty means melange_tuples. MigrateWithTypesAndOptions takes it from
+// InternalMigrateOptions.TuplesTable instead.
+func (m *Migrator) SetTuplesTable(tuplesTable string) {
+	m.tuplesTable = tuplesTable
+}
+
+// TuplesTable returns the tuples relation, melange_tuples unless set.
+func (m *Migrator) TuplesTable() string {
+	return sqlgen.TuplesTableName(m.tuplesTable)
+}
+
 // HasSchema returns true if the schema file exists.
 // Use this to conditionally run migration or skip if not configured.
 func (m *Migrator) HasSchema() bool {
@@ -163,7 +354,29 @@ func (m *Migrator) ApplyDDL(ctx context.Context) error {
 }
 
 // applyGeneratedSQL applies generated specialized functions and dispatcher.
+//
+// Every statement is CREATE OR REPLACE, so re-applying is idempotent. The
+// order keeps callees ahead of callers: each per-relation slice is already
+// in call order (callees first), and each group below is applied after the
+// groups it calls. LANGUAGE sql bodies are validated at CREATE and only call
+// earlier groups; the context functions call check functions, and the
+// wrappers and combinators call their dispatchers. Mutual recursion, such as
+// check functions reaching each other through check_permission_internal,
+// only runs through PL/pgSQL bodies, which resolve names at first call, so
+// a cycle needs no forward declaration.
 func (m *Migrator) applyGeneratedSQL(ctx context.Context, db Execer, gen GeneratedSQL) error {
+	// Apply the opt-in model lookups before anything calling them
+	if gen.ClosureFunction != "" {
+		if _, err := db.ExecContext(ctx, gen.ClosureFunction); err != nil {
+			return fmt.Errorf("applying closure function: %w", err)
+		}
+	}
+	if gen.UsersetFunction != "" {
+		if _, err := db.ExecContext(ctx, gen.UsersetFunction); err != nil {
+			return fmt.Errorf("applying userset function: %w", err)
+		}
+	}
+
 	// Apply specialized check functions first (dispatcher depends on them)
 	for i, fn := range gen.Functions {
 		if _, err := db.ExecContext(ctx, fn); err != nil {
@@ -175,6 +388,16 @@ func (m *Migrator) applyGeneratedSQL(ctx context.Context, db Execer, gen Generat
 			return fmt.Errorf("applying generated no-wildcard function %d: %w", i, err)
 		}
 	}
+	for i, fn := range gen.ContextFunctions {
+		if _, err := db.ExecContext(ctx, fn); err != nil {
+			return fmt.Errorf("applying generated context function %d: %w", i, err)
+		}
+	}
+	for i, fn := range gen.StrictFunctions {
+		if _, err := db.ExecContext(ctx, fn); err != nil {
+			return fmt.Errorf("applying generated strict function %d: %w", i, err)
+		}
+	}
 
 	// Apply dispatcher (replaces default check_permission)
 	if gen.Dispatcher != "" {
@@ -190,6 +413,25 @@ func (m *Migrator) applyGeneratedSQL(ctx context.Context, db Execer, gen Generat
 		}
 	}
 
+	// Apply the opt-in strict dispatcher
+	if gen.DispatcherStrict != "" {
+		if _, err := db.ExecContext(ctx, gen.DispatcherStrict); err != nil {
+			return fmt.Errorf("applying strict dispatcher: %w", err)
+		}
+	}
+
+	// Apply context dispatcher (falls back to check_permission)
+	if gen.ContextDispatcher != "" {
+		if _, err := db.ExecContext(ctx, gen.ContextDispatcher); err != nil {
+			return fmt.Errorf("applying context dispatcher: %w", err)
+		}
+	}
+	if gen.ContextualTuplesFunction != "" {
+		if _, err := db.ExecContext(ctx, gen.ContextualTuplesFunction); err != nil {
+			return fmt.Errorf("applying contextual tuples function: %w", err)
+		}
+	}
+
 	// Apply bulk dispatcher
 	if gen.BulkDispatcher != "" {
 		if _, err := db.ExecContext(ctx, gen.BulkDispatcher); err != nil {
@@ -197,6 +439,25 @@ func (m *Migrator) applyGeneratedSQL(ctx context.Context, db Execer, gen Generat
 		}
 	}
 
+	// Apply batch dispatcher (calls check_permission)
+	if gen.BatchDispatcher != "" {
+		if _, err := db.ExecContext(ctx, gen.BatchDispatcher); err != nil {
+			return fmt.Errorf("applying batch dispatcher: %w", err)
+		}
+	}
+
+	// Apply check_any / check_all combinators (both call check_permission)
+	if gen.CheckAnyDispatcher != "" {
+		if _, err := db.ExecContext(ctx, gen.CheckAnyDispatcher); err != nil {
+			return fmt.Errorf("applying check_any dispatcher: %w", err)
+		}
+	}
+	if gen.CheckAllDispatcher != "" {
+		if _, err := db.ExecContext(ctx, gen.CheckAllDispatcher); err != nil {
+			return fmt.Errorf("applying check_all dispatcher: %w", err)
+		}
+	}
+
 	// Apply per-relation explain functions before the explain dispatcher
 	// (dispatcher CASE expressions name the per-relation functions).
 	for i, fn := range gen.ExplainFunctions {
@@ -223,11 +484,43 @@ func (m *Migrator) applyGeneratedSQL(ctx context.Context, db Execer, gen Generat
 		}
 	}
 
+	if gen.ModelRelationsFunction != "" {
+		if _, err := db.ExecContext(ctx, gen.ModelRelationsFunction); err != nil {
+			return fmt.Errorf("applying model relations function: %w", err)
+		}
+	}
+
+	// Apply the opt-in effective_access audit function
+	if gen.EffectiveAccessFunction != "" {
+		if _, err := db.ExecContext(ctx, gen.EffectiveAccessFunction); err != nil {
+			return fmt.Errorf("applying effective_access function: %w", err)
+		}
+	}
+
+	// Apply the opt-in check_with_evidence audit functions
+	for i, fn := range gen.EvidenceFunctions {
+		if _, err := db.ExecContext(ctx, fn); err != nil {
+			return fmt.Errorf("applying evidence function %d: %w", i, err)
+		}
+	}
+
+	if gen.HealthcheckFunction != "" {
+		if _, err := db.ExecContext(ctx, gen.HealthcheckFunction); err != nil {
+			return fmt.Errorf("applying healthcheck function: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // applyGeneratedListSQL applies generated specialized list functions and dispatchers.
 func (m *Migrator) applyGeneratedListSQL(ctx context.Context, db Execer, gen ListGeneratedSQL) error {
+	if gen.DepthExceededFunction != "" {
+		if _, err := db.ExecContext(ctx, gen.DepthExceededFunction); err != nil {
+			return fmt.Errorf("applying depth limit function: %w", err)
+		}
+	}
+
 	// Apply specialized list_objects functions
 	for i, fn := range gen.ListObjectsFunctions {
 		if _, err := db.ExecContext(ctx, fn); err != nil {
@@ -256,6 +549,33 @@ func (m *Migrator) applyGeneratedListSQL(ctx context.Context, db Execer, gen Lis
 		}
 	}
 
+	// Apply the multi-relation combinator, which calls the list_objects dispatcher
+	if gen.ListObjectsAnyDispatcher != "" {
+		if _, err := db.ExecContext(ctx, gen.ListObjectsAnyDispatcher); err != nil {
+			return fmt.Errorf("applying list_objects any combinator: %w", err)
+		}
+	}
+
+	// Apply the multi-filter combinator, which calls the list_subjects dispatcher
+	if gen.ListSubjectsMultiDispatcher != "" {
+		if _, err := db.ExecContext(ctx, gen.ListSubjectsMultiDispatcher); err != nil {
+			return fmt.Errorf("applying list_subjects multi combinator: %w", err)
+		}
+	}
+
+	// Apply the opt-in cursor functions, which call the list functions and
+	// the list_objects dispatcher
+	for i, fn := range gen.ListObjectsCursorFunctions {
+		if _, err := db.ExecContext(ctx, fn); err != nil {
+			return fmt.Errorf("applying list_objects cursor function %d: %w", i, err)
+		}
+	}
+	if gen.ListObjectsCursorDispatcher != "" {
+		if _, err := db.ExecContext(ctx, gen.ListObjectsCursorDispatcher); err != nil {
+			return fmt.Errorf("applying list_objects cursor dispatcher: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -278,6 +598,9 @@ func (m *Migrator) MigrateWithTypes(ctx context.Context, types []TypeDefinition)
 	if err := DetectCycles(types); err != nil {
 		return err
 	}
+	if err := ValidateTupleToUsersets(types); err != nil {
+		return err
+	}
 
 	// 2. Compute derived data (pure computation, no DB)
 	closureRows := ComputeRelationClosure(types)
@@ -286,16 +609,22 @@ func (m *Migrator) MigrateWithTypes(ctx context.Context, types []TypeDefinition)
 	analyses := AnalyzeRelations(types, closureRows)
 	analyses = ComputeCanGenerate(analyses) // Walk dependency graph to set CanGenerate
 	inline := buildInlineSQLData(closureRows, analyses)
-	generatedSQL, err := GenerateSQL(analyses, inline, m.databaseSchema)
+	genOpts := sqlgen.GenerateSQLOptions{TuplesTable: m.tuplesTable, SchemaHash: SchemaHash(types)}
+	generatedSQL, err := GenerateSQLWithOptions(analyses, inline, m.databaseSchema, genOpts)
 	if err != nil {
 		return fmt.Errorf("generating check SQL: %w", err)
 	}
 
 	// 4. Generate list functions
-	listSQL, err := GenerateListSQL(analyses, inline, m.databaseSchema)
+	listSQL, err := generateListSQLWithOptions(analyses, inline, m.databaseSchema, genOpts)
 	if err != nil {
 		return fmt.Errorf("generating list SQL: %w", err)
 	}
+	names := CollectFunctionNames(analyses)
+	if generatedSQL.ContextualTuplesFunction != "" {
+		names = append(names, sqlgen.ContextualTuplesFunctionName)
+	}
+	generatedSQL.HealthcheckFunction = sqlgen.GenerateHealthcheckFunction(m.databaseSchema, m.tuplesTable, SchemaHash(types), names, genOpts.Dialect)
 
 	// 5. Apply everything atomically
 	if txer, ok := m.db.(interface {
@@ -334,42 +663,228 @@ func (m *Migrator) MigrateWithTypes(ctx context.Context, types []TypeDefinition)
 // Use GetStatus to check if the authorization system is properly configured.
 type Status struct {
 	// SchemaExists indicates if the schema.fga file exists on disk.
-	SchemaExists bool
+	SchemaExists bool `json:"schema_exists"`
 
-	// TuplesExists indicates if the melange_tuples relation exists (view, table, or materialized view).
+	// TuplesExists indicates if the tuples relation (melange_tuples unless
+	// set with SetTuplesTable) exists (view, table, or materialized view).
 	// This must be created by the user to map their domain tables.
-	TuplesExists bool
+	TuplesExists bool `json:"tuples_exists"`
+
+	// FunctionsExpected is the number of functions the schema compiles to
+	// with default options; MigrateOptions.MaxFunctions limits the same count
+	// plus any opt-in functions. Zero when the schema file is missing.
+	FunctionsExpected int `json:"functions_expected"`
+
+	// FunctionsInstalled is the number of functions the schema compiles to
+	// that exist in the database. Zero when the schema file is missing.
+	FunctionsInstalled int `json:"functions_installed"`
+
+	// FunctionsMissing lists functions the schema compiles to that are not
+	// installed, sorted by name.
+	FunctionsMissing []string `json:"functions_missing"`
+
+	// FunctionsStale lists installed functions whose body differs from the
+	// SQL the schema currently generates (e.g. the schema changed or melange
+	// was upgraded without re-running migrate), sorted by name.
+	FunctionsStale []string `json:"functions_stale"`
+
+	// History lists up to StatusHistoryLimit recorded migrations, most
+	// recent first. Empty when nothing has been migrated.
+	History []MigrationHistoryEntry `json:"history"`
+}
+
+// StatusHistoryLimit is how many recorded migrations GetStatus returns in
+// Status.History.
+const StatusHistoryLimit = 10
+
+// MigrationHistoryEntry is one applied migration recorded in
+// melange_migrations.
+type MigrationHistoryEntry struct {
+	MigratedAt     time.Time `json:"migrated_at"`
+	MelangeVersion string    `json:"melange_version"`
+	SchemaChecksum string    `json:"schema_checksum"`
+	CodegenVersion string    `json:"codegen_version"`
+	// SchemaPath is the schema file the migration was applied from. Empty
+	// for schemas passed as a string and for records written before the
+	// column was added.
+	SchemaPath string `json:"schema_path"`
+	// RolledBackAt is when Rollback dropped the migration's functions, or
+	// nil while they are installed.
+	RolledBackAt *time.Time `json:"rolled_back_at,omitempty"`
 }
 
 // GetStatus returns the current migration status.
 // Useful for health checks or migration diagnostics.
+//
+// When the schema file exists it is compiled with the generation options the
+// last migration recorded and every expected function is compared against
+// pg_proc, so the result also answers whether the deployed functions are
+// complete and current.
 func (m *Migrator) GetStatus(ctx context.Context) (*Status, error) {
 	status := &Status{
 		SchemaExists: m.HasSchema(),
 	}
 
-	// Check if melange_tuples relation exists (view, table, or materialized view)
+	// Check if the tuples relation exists (view, table, or materialized view)
+	tuplesSchema, tuplesName, _ := m.tuplesRelation()
 	var tuplesExists bool
 	err := m.db.QueryRowContext(ctx, fmt.Sprintf(
 		`
 			SELECT EXISTS (
 				SELECT 1 FROM pg_class c
 				JOIN pg_namespace n ON n.oid = c.relnamespace
-				WHERE c.relname = 'melange_tuples'
+				WHERE c.relname = $1
 				AND n.nspname = %s
 				AND c.relkind IN ('r', 'v', 'm')
 			)
 		`,
-		m.postgresSchema(),
-	)).Scan(&tuplesExists)
+		tuplesSchema,
+	), tuplesName).Scan(&tuplesExists)
 	if err != nil {
-		return nil, fmt.Errorf("checking melange_tuples: %w", err)
+		return nil, fmt.Errorf("checking %s: %w", m.TuplesTable(), err)
 	}
 	status.TuplesExists = tuplesExists
 
+	status.History, err = m.GetHistory(ctx, StatusHistoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if !status.SchemaExists {
+		return status, nil
+	}
+
+	types, err := parser.ParseSchema(m.SchemaPath())
+	if err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+	lastMigration, err := m.GetLastMigration(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checking last migration: %w", err)
+	}
+	expected, bodies, err := m.functionManifest(types, m.recordedGenerationOptions(lastMigration))
+	if err != nil {
+		return nil, err
+	}
+	installed, err := m.getInstalledFunctionBodies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checking functions: %w", err)
+	}
+	status.compareFunctions(expected, bodies, installed)
+
 	return status, nil
 }
 
+// GetHistory returns up to limit recorded migrations, most recent first, or
+// nil when melange_migrations does not exist.
+func (m *Migrator) GetHistory(ctx context.Context, limit int) ([]MigrationHistoryEntry, error) {
+	kind, err := m.relationKind(ctx, m.db, "melange_migrations")
+	if err != nil || kind == "" {
+		return nil, err
+	}
+
+	// schema_path and rolled_back_at are absent until the first migration
+	// by a version that adds them.
+	pathCol, rolledBackCol := "''", "NULL::TIMESTAMPTZ"
+	hasPathCol, err := m.migrationsColumnExists(ctx, m.db, "schema_path")
+	if err != nil {
+		return nil, err
+	}
+	if hasPathCol {
+		pathCol = "schema_path"
+	}
+	hasRolledBackCol, err := m.migrationsColumnExists(ctx, m.db, "rolled_back_at")
+	if err != nil {
+		return nil, err
+	}
+	if hasRolledBackCol {
+		rolledBackCol = "rolled_back_at"
+	}
+
+	rows, err := m.db.QueryContext(ctx, fmt.Sprintf(
+		`
+			SELECT migrated_at, melange_version, schema_checksum, codegen_version, %s, %s
+			FROM %s
+			ORDER BY id DESC
+			LIMIT $1
+		`,
+		pathCol, rolledBackCol, m.prefixIdent("melange_migrations"),
+	), limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying migration history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var history []MigrationHistoryEntry
+	for rows.Next() {
+		var e MigrationHistoryEntry
+		if err := rows.Scan(&e.MigratedAt, &e.MelangeVersion, &e.SchemaChecksum, &e.CodegenVersion, &e.SchemaPath, &e.RolledBackAt); err != nil {
+			return nil, fmt.Errorf("scanning migration history: %w", err)
+		}
+		history = append(history, e)
+	}
+	return history, rows.Err()
+}
+
+// migrationsColumnExists reports whether melange_migrations has column. Columns
+// added after the original DDL are missing until the first migration by a
+// version that adds them.
+func (m *Migrator) migrationsColumnExists(ctx context.Context, db Execer, column string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, fmt.Sprintf(
+		`
+			SELECT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_name = 'melange_migrations'
+				AND column_name = $1
+				AND table_schema = %s
+			)
+		`,
+		m.postgresSchema(),
+	), column).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("checking %s column: %w", column, err)
+	}
+	return exists, nil
+}
+
+// activeMigrationsFilter returns the WHERE clause that leaves out rolled-back
+// migrations, or "" when melange_migrations has no rolled_back_at column yet.
+func (m *Migrator) activeMigrationsFilter(ctx context.Context, db Execer) (string, error) {
+	exists, err := m.migrationsColumnExists(ctx, db, "rolled_back_at")
+	if err != nil || !exists {
+		return "", err
+	}
+	return "WHERE rolled_back_at IS NULL", nil
+}
+
+// lockMigrations takes the transaction-scoped advisory lock on
+// AdvisoryLockKey, waiting for any concurrent migration to finish.
+func lockMigrations(ctx context.Context, tx Execer) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", AdvisoryLockKey); err != nil {
+		return fmt.Errorf("acquiring advisory lock: %w", err)
+	}
+	return nil
+}
+
+// setStatementTimeout bounds every later statement in the current transaction
+// to timeout. It does nothing when timeout is zero.
+func setStatementTimeout(ctx context.Context, db Execer, timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, statementTimeoutSQL(timeout)); err != nil {
+		return fmt.Errorf("setting statement timeout: %w", err)
+	}
+	return nil
+}
+
+// statementTimeoutSQL renders SET LOCAL statement_timeout for timeout, in
+// whole milliseconds and never below one (zero would disable the timeout).
+func statementTimeoutSQL(timeout time.Duration) string {
+	return fmt.Sprintf("SET LOCAL statement_timeout = %d", max(timeout.Milliseconds(), 1))
+}
+
 // ComputeSchemaChecksum returns a SHA256 hash of the schema content.
 // Used to detect schema changes for skip-if-unchanged optimization.
 func ComputeSchemaChecksum(content string) string {
@@ -389,8 +904,8 @@ func ComputeFunctionChecksums(namedFunctions []NamedFunction) map[string]string
 	return checksums
 }
 
-// GetLastMigration returns the most recent migration record, or nil if none
-// exists. It queries against the migrator's own database connection, making it
+// GetLastMigration returns the most recent migration record not rolled back,
+// or nil if none exists. It queries against the migrator's own database connection, making it
 // suitable for external callers such as the generate migration command.
 //
 // Internal migration code uses the private getLastMigration with an explicit
@@ -399,7 +914,8 @@ func (m *Migrator) GetLastMigration(ctx context.Context) (*MigrationRecord, erro
 	return m.getLastMigration(ctx, m.db)
 }
 
-// getLastMigration returns the most recent migration record, or nil if none exists.
+// getLastMigration returns the most recent migration record not rolled back,
+// or nil if none exists.
 func (m *Migrator) getLastMigration(ctx context.Context, db Execer) (*MigrationRecord, error) {
 	// First check if the migrations table exists
 	var tableExists bool
@@ -421,6 +937,13 @@ func (m *Migrator) getLastMigration(ctx context.Context, db Execer) (*MigrationR
 		return nil, nil // No migrations table yet
 	}
 
+	// Rows Rollback marked stay in the history but no longer describe the
+	// installed functions.
+	active, err := m.activeMigrationsFilter(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if function_checksums column exists (may be absent on older installations)
 	var hasChecksumsCol bool
 	err = db.QueryRowContext(ctx, fmt.Sprintf(
@@ -445,10 +968,11 @@ func (m *Migrator) getLastMigration(ctx context.Context, db Execer) (*MigrationR
 			`
 				SELECT melange_version, schema_checksum, codegen_version, function_names, function_checksums::TEXT
 				FROM %s
+				%s
 				ORDER BY id DESC
 				LIMIT 1
 			`,
-			m.prefixIdent("melange_migrations"),
+			m.prefixIdent("melange_migrations"), active,
 		)).Scan(&rec.MelangeVersion, &rec.SchemaChecksum, &rec.CodegenVersion, pq.Array(&rec.FunctionNames), &checksumsJSON)
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -467,10 +991,11 @@ func (m *Migrator) getLastMigration(ctx context.Context, db Execer) (*MigrationR
 			`
 				SELECT melange_version, schema_checksum, codegen_version, function_names
 				FROM %s
+				%s
 				ORDER BY id DESC
 				LIMIT 1
 			`,
-			m.prefixIdent("melange_migrations"),
+			m.prefixIdent("melange_migrations"), active,
 		)).Scan(&rec.MelangeVersion, &rec.SchemaChecksum, &rec.CodegenVersion, pq.Array(&rec.FunctionNames))
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -479,9 +1004,111 @@ func (m *Migrator) getLastMigration(ctx context.Context, db Execer) (*MigrationR
 			return nil, fmt.Errorf("querying last migration: %w", err)
 		}
 	}
+
+	// generation_options is absent on tables no migration has touched since it
+	// was added; the record then keeps nil options.
+	var hasOptionsCol bool
+	err = db.QueryRowContext(ctx, fmt.Sprintf(
+		`
+			SELECT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_name = 'melange_migrations'
+				AND column_name = 'generation_options'
+				AND table_schema = %s
+			)
+		`,
+		m.postgresSchema(),
+	)).Scan(&hasOptionsCol)
+	if err != nil {
+		return nil, fmt.Errorf("checking generation_options column: %w", err)
+	}
+	if hasOptionsCol {
+		var optionsJSON sql.NullString
+		err = db.QueryRowContext(ctx, fmt.Sprintf(
+			`
+				SELECT generation_options::TEXT
+				FROM %s
+				%s
+				ORDER BY id DESC
+				LIMIT 1
+			`,
+			m.prefixIdent("melange_migrations"), active,
+		)).Scan(&optionsJSON)
+		if err != nil {
+			return nil, fmt.Errorf("querying last migration options: %w", err)
+		}
+		if optionsJSON.Valid {
+			rec.GenerationOptions = &GenerationOptions{}
+			if err := json.Unmarshal([]byte(optionsJSON.String), rec.GenerationOptions); err != nil {
+				return nil, fmt.Errorf("unmarshaling generation options: %w", err)
+			}
+		}
+	}
 	return &rec, nil
 }
 
+// Suffixes appended to the schema hash before rehashing when the matching
+// option is set. See migrationSchemaChecksum.
+const (
+	poolerSafeChecksumSuffix   = "\n# melange:pooler-safe\n"
+	tableRoutedChecksumSuffix  = "\n# melange:table-routed\n"
+	anytimeChecksumSuffix      = "\n# melange:anytime-list-objects\n"
+	subjectsViewChecksumSuffix = "\n# melange:expand-wildcard-subjects\n"
+	delimiterChecksumPrefix    = "\n# melange:object-delimiter "
+	tuplesTableChecksumPrefix  = "\n# melange:tuples-table "
+	maxDepthChecksumPrefix     = "\n# melange:max-depth "
+	dialectChecksumPrefix      = "\n# melange:dialect "
+	nullGuardsChecksumSuffix   = "\n# melange:no-null-guards\n"
+)
+
+// migrationSchemaChecksum returns the schema checksum recorded for a run,
+// given the SchemaHash of its types. PoolerSafe, TableRoutedDispatcher,
+// AnytimeListObjects, ExpandWildcardSubjects, ObjectDelimiter, TuplesTable,
+// MaxDepth, DisableNullGuards and Dialect change function bodies without
+// changing the schema or codegen version, so they are folded into the
+// checksum: changing any of them in either direction defeats the phase 1
+// skip and lets the phase 2 function checksums decide. Default runs record
+// the schema hash alone, matching the record written by generated
+// migrations.
+func migrationSchemaChecksum(schemaHash string, opts InternalMigrateOptions) string {
+	customDelimiter := opts.ObjectDelimiter != "" && opts.ObjectDelimiter != sqlgen.DefaultObjectDelimiter
+	customTuplesTable := sqlgen.TuplesTableName(opts.TuplesTable) != sqlgen.DefaultTuplesTable
+	customMaxDepth := opts.MaxDepth != 0 && opts.MaxDepth != sqlgen.DefaultMaxDepth
+	customDialect := opts.Dialect != "" && sqlgen.Dialect(opts.Dialect) != sqlgen.DialectPostgres
+	if !opts.PoolerSafe && !opts.TableRoutedDispatcher && !opts.AnytimeListObjects && !opts.ExpandWildcardSubjects && !customDelimiter && !customTuplesTable && !customMaxDepth && !opts.DisableNullGuards && !customDialect {
+		return schemaHash
+	}
+	content := schemaHash
+	if opts.PoolerSafe {
+		content += poolerSafeChecksumSuffix
+	}
+	if opts.TableRoutedDispatcher {
+		content += tableRoutedChecksumSuffix
+	}
+	if opts.AnytimeListObjects {
+		content += anytimeChecksumSuffix
+	}
+	if opts.ExpandWildcardSubjects {
+		content += subjectsViewChecksumSuffix
+	}
+	if customDelimiter {
+		content += delimiterChecksumPrefix + strconv.Quote(opts.ObjectDelimiter) + "\n"
+	}
+	if customTuplesTable {
+		content += tuplesTableChecksumPrefix + strconv.Quote(opts.TuplesTable) + "\n"
+	}
+	if customMaxDepth {
+		content += maxDepthChecksumPrefix + strconv.Itoa(opts.MaxDepth) + "\n"
+	}
+	if opts.DisableNullGuards {
+		content += nullGuardsChecksumSuffix
+	}
+	if customDialect {
+		content += dialectChecksumPrefix + opts.Dialect + "\n"
+	}
+	return ComputeSchemaChecksum(content)
+}
+
 // migrationRecordMatches reports whether the last migration was recorded with
 // the same schema checksum and codegen version as the current run.
 func migrationRecordMatches(lastMigration *MigrationRecord, schemaChecksum string) bool {
@@ -508,6 +1135,25 @@ func shouldSkipMigration(lastMigration *MigrationRecord, schemaChecksum string)
 	return migrationRecordMatches(lastMigration, schemaChecksum)
 }
 
+// optionalFunctionsMatch reports whether the opt-in functions recorded by a
+// migration (effective_access, the evidence functions, the strict dispatcher,
+// the check memo route, the closure function and the cursor dispatcher) are
+// exactly those opts would install.
+func optionalFunctionsMatch(rec *MigrationRecord, opts InternalMigrateOptions) bool {
+	return slices.Contains(rec.FunctionNames, "effective_access") == opts.EnableEffectiveAccess &&
+		slices.ContainsFunc(rec.FunctionNames, isEvidenceFunction) == opts.EnableCheckEvidence &&
+		slices.Contains(rec.FunctionNames, sqlgen.StrictDispatcherFunctionName) == opts.EnableStrictCheck &&
+		slices.Contains(rec.FunctionNames, sqlgen.CheckMemoRouteFunction) == opts.EnableCheckMemo &&
+		slices.Contains(rec.FunctionNames, sqlgen.ClosureFunctionName) == opts.ClosureFunction &&
+		slices.Contains(rec.FunctionNames, sqlgen.ListObjectsCursorDispatcherName) == opts.EnableListObjectsCursor
+}
+
+// isEvidenceFunction reports whether name is one of the opt-in
+// check_with_evidence_* audit functions.
+func isEvidenceFunction(name string) bool {
+	return strings.HasPrefix(name, sqlgen.EvidenceFunctionPrefix)
+}
+
 // shouldSkipApply returns true if the generated SQL is identical to what was
 // last applied. This is the phase 2 skip: SQL was generated (because the schema
 // or melange version changed) but the output is byte-for-byte identical, so
@@ -550,8 +1196,16 @@ func (m *Migrator) getCurrentFunctions(ctx context.Context, db Execer) ([]string
 		AND (
 			p.proname LIKE 'check_%%'
 			OR p.proname LIKE 'list_%%'
+			OR p.proname = 'effective_access'
+			OR p.proname = %s
+			OR p.proname = %s
+			OR p.proname = %s
+			OR p.proname = %s
+			OR p.proname = %s
 		)
-	`, m.postgresSchema()))
+	`, m.postgresSchema(), sqldsl.QuoteLiteral(sqlgen.ClosureFunctionName), sqldsl.QuoteLiteral(sqlgen.ModelRelationsFunctionName),
+		sqldsl.QuoteLiteral(sqlgen.DepthExceededFunctionName), sqldsl.QuoteLiteral(sqlgen.HealthcheckFunctionName),
+		sqldsl.QuoteLiteral(sqlgen.UsersetFunctionName)))
 	if err != nil {
 		return nil, fmt.Errorf("querying pg_proc: %w", err)
 	}
@@ -603,7 +1257,7 @@ func (m *Migrator) applyMigrationsDDL(ctx context.Context, db Execer) error {
 // recordMigrationOnly inserts a migration record without re-applying functions.
 // Used when phase 2 skip determines the generated SQL is identical to what's
 // already installed — only the melange version or schema checksum changed.
-func (m *Migrator) recordMigrationOnly(ctx context.Context, melangeVersion, schemaChecksum string, functionNames []string, functionChecksums map[string]string) error {
+func (m *Migrator) recordMigrationOnly(ctx context.Context, melangeVersion, schemaChecksum string, functionNames []string, functionChecksums map[string]string, genOptions GenerationOptions) error {
 	if txer, ok := m.db.(interface {
 		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 	}); ok {
@@ -613,10 +1267,13 @@ func (m *Migrator) recordMigrationOnly(ctx context.Context, melangeVersion, sche
 		}
 		defer func() { _ = tx.Rollback() }()
 
+		if err := lockMigrations(ctx, tx); err != nil {
+			return err
+		}
 		if err := m.applyMigrationsDDL(ctx, tx); err != nil {
 			return err
 		}
-		if err := m.insertMigrationRecord(ctx, tx, melangeVersion, schemaChecksum, functionNames, functionChecksums); err != nil {
+		if err := m.insertMigrationRecord(ctx, tx, melangeVersion, schemaChecksum, functionNames, functionChecksums, genOptions); err != nil {
 			return err
 		}
 		return tx.Commit()
@@ -625,22 +1282,26 @@ func (m *Migrator) recordMigrationOnly(ctx context.Context, melangeVersion, sche
 	if err := m.applyMigrationsDDL(ctx, m.db); err != nil {
 		return err
 	}
-	return m.insertMigrationRecord(ctx, m.db, melangeVersion, schemaChecksum, functionNames, functionChecksums)
+	return m.insertMigrationRecord(ctx, m.db, melangeVersion, schemaChecksum, functionNames, functionChecksums, genOptions)
 }
 
 // insertMigrationRecord records the migration in melange_migrations.
-func (m *Migrator) insertMigrationRecord(ctx context.Context, db Execer, melangeVersion, schemaChecksum string, functionNames []string, functionChecksums map[string]string) error {
+func (m *Migrator) insertMigrationRecord(ctx context.Context, db Execer, melangeVersion, schemaChecksum string, functionNames []string, functionChecksums map[string]string, genOptions GenerationOptions) error {
 	checksumsJSON, err := json.Marshal(functionChecksums)
 	if err != nil {
 		return fmt.Errorf("marshaling function checksums: %w", err)
 	}
+	optionsJSON, err := json.Marshal(genOptions)
+	if err != nil {
+		return fmt.Errorf("marshaling generation options: %w", err)
+	}
 	_, err = db.ExecContext(ctx, fmt.Sprintf(
 		`
-			INSERT INTO %s (melange_version, schema_checksum, codegen_version, function_names, function_checksums)
-			VALUES ($1, $2, $3, $4, $5)
+			INSERT INTO %s (melange_version, schema_checksum, codegen_version, function_names, function_checksums, schema_path, generation_options)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
 		`,
 		m.prefixIdent("melange_migrations"),
-	), melangeVersion, schemaChecksum, CodegenVersion(), pq.Array(functionNames), string(checksumsJSON))
+	), melangeVersion, schemaChecksum, CodegenVersion(), pq.Array(functionNames), string(checksumsJSON), m.schemaPath, string(optionsJSON))
 	if err != nil {
 		return fmt.Errorf("inserting migration record: %w", err)
 	}
@@ -673,11 +1334,14 @@ func (m *Migrator) migrateWithTypesAndOptions(ctx context.Context, types []TypeD
 	if err := DetectCycles(types); err != nil {
 		return false, err
 	}
+	if err := ValidateTupleToUsersets(types); err != nil {
+		return false, err
+	}
 
 	// 2. Compute schema checksum if content provided
 	var schemaChecksum string
 	if opts.SchemaContent != "" {
-		schemaChecksum = ComputeSchemaChecksum(opts.SchemaContent)
+		schemaChecksum = migrationSchemaChecksum(SchemaHash(types), opts)
 	}
 
 	// 3. Fetch last migration record (needed for both skip phases)
@@ -687,46 +1351,38 @@ func (m *Migrator) migrateWithTypesAndOptions(ctx context.Context, types []TypeD
 		if err != nil {
 			return false, fmt.Errorf("checking last migration: %w", err)
 		}
-		// Phase 1 skip: schema + codegen version unchanged → skip entirely
-		if shouldSkipMigration(lastMigration, schemaChecksum) {
+		// Phase 1 skip: schema + codegen version unchanged → skip entirely.
+		// Toggling effective_access, the evidence functions, strict checks,
+		// the check memo or the closure function changes the output without touching
+		// either, so each must also match what the last migration installed.
+		if shouldSkipMigration(lastMigration, schemaChecksum) && optionalFunctionsMatch(lastMigration, opts) {
 			return true, nil
 		}
 	}
 
-	// 4. Compute derived data (pure computation, no DB)
-	closureRows := ComputeRelationClosure(types)
-
-	// 5. Analyze relations and generate SQL
-	analyses := AnalyzeRelations(types, closureRows)
-	analyses = ComputeCanGenerate(analyses)
-	inline := buildInlineSQLData(closureRows, analyses)
-	generatedSQL, err := GenerateSQL(analyses, inline, m.databaseSchema)
+	// 4. Analyze relations and generate the check and list SQL. Dispatchers
+	// are checksummed alongside specialized functions so that a codegen
+	// change altering only dispatcher SQL still defeats the phase 2 skip
+	// below.
+	genOptions := opts.generationOptions()
+	analyses, generatedSQL, listSQL, expectedFunctions, err := generateWithOptions(types, m.databaseSchema, genOptions)
 	if err != nil {
-		return false, fmt.Errorf("generating check SQL: %w", err)
+		return false, err
 	}
-
-	// 6. Generate list functions
-	listSQL, err := GenerateListSQL(analyses, inline, m.databaseSchema)
-	if err != nil {
-		return false, fmt.Errorf("generating list SQL: %w", err)
+	if err := checkFunctionLimit(expectedFunctions, opts.MaxFunctions, opts); err != nil {
+		return false, err
 	}
-
-	// 7. Collect expected function names and checksums for tracking.
-	// Dispatchers are checksummed alongside specialized functions so that a
-	// codegen change altering only dispatcher SQL still defeats the phase 2
-	// skip below.
-	expectedFunctions := CollectFunctionNames(analyses)
 	namedFunctions := collectNamedFunctions(generatedSQL, listSQL, analyses)
 	namedFunctions = append(namedFunctions, collectDispatcherFunctions(generatedSQL, listSQL)...)
 	functionChecksums := ComputeFunctionChecksums(namedFunctions)
 
-	// 8. Handle dry-run mode
+	// 5. Handle dry-run mode
 	if opts.DryRun != nil {
-		m.outputDryRun(opts.DryRun, opts.Version, schemaChecksum, generatedSQL, listSQL, expectedFunctions)
+		m.outputDryRun(opts.DryRun, opts, schemaChecksum, generatedSQL, listSQL, expectedFunctions)
 		return false, nil
 	}
 
-	// 9. Phase 2 skip: generated SQL is identical to what's already applied.
+	// 6. Phase 2 skip: generated SQL is identical to what's already applied.
 	// The schema or melange version changed (phase 1 didn't skip), but the
 	// generated functions are byte-for-byte identical. Record the new version
 	// but skip re-applying the functions.
@@ -736,10 +1392,10 @@ func (m *Migrator) migrateWithTypesAndOptions(ctx context.Context, types []TypeD
 		if migrationRecordMatches(lastMigration, schemaChecksum) {
 			return true, nil
 		}
-		return false, m.recordMigrationOnly(ctx, opts.Version, schemaChecksum, expectedFunctions, functionChecksums)
+		return false, m.recordMigrationOnly(ctx, opts.Version, schemaChecksum, expectedFunctions, functionChecksums, genOptions)
 	}
 
-	// 10. Apply everything atomically
+	// 7. Apply everything atomically
 	if txer, ok := m.db.(interface {
 		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 	}); ok {
@@ -749,6 +1405,15 @@ func (m *Migrator) migrateWithTypesAndOptions(ctx context.Context, types []TypeD
 		}
 		defer func() { _ = tx.Rollback() }()
 
+		// Serialize with concurrent deploys before reading what is installed,
+		// so two runs cannot interleave their function sets.
+		if err := lockMigrations(ctx, tx); err != nil {
+			return false, err
+		}
+		if err := setStatementTimeout(ctx, tx, opts.StatementTimeout); err != nil {
+			return false, err
+		}
+
 		// Apply migrations DDL (creates tracking table)
 		if err := m.applyMigrationsDDL(ctx, tx); err != nil {
 			return false, err
@@ -777,7 +1442,7 @@ func (m *Migrator) migrateWithTypesAndOptions(ctx context.Context, types []TypeD
 
 		// Record migration
 		if schemaChecksum != "" {
-			if err := m.insertMigrationRecord(ctx, tx, opts.Version, schemaChecksum, expectedFunctions, functionChecksums); err != nil {
+			if err := m.insertMigrationRecord(ctx, tx, opts.Version, schemaChecksum, expectedFunctions, functionChecksums, genOptions); err != nil {
 				return false, err
 			}
 		}
@@ -785,7 +1450,11 @@ func (m *Migrator) migrateWithTypesAndOptions(ctx context.Context, types []TypeD
 		return false, tx.Commit()
 	}
 
-	// Fall back to non-transactional (for *sql.Conn)
+	// Fall back to non-transactional (for *sql.Conn). A caller-managed
+	// *sql.Tx (ApplyTx) also lands here and keeps every statement in its tx.
+	if err := setStatementTimeout(ctx, m.db, opts.StatementTimeout); err != nil {
+		return false, err
+	}
 	if err := m.applyMigrationsDDL(ctx, m.db); err != nil {
 		return false, err
 	}
@@ -803,15 +1472,23 @@ func (m *Migrator) migrateWithTypesAndOptions(ctx context.Context, types []TypeD
 		return false, err
 	}
 	if schemaChecksum != "" {
-		if err := m.insertMigrationRecord(ctx, m.db, opts.Version, schemaChecksum, expectedFunctions, functionChecksums); err != nil {
+		if err := m.insertMigrationRecord(ctx, m.db, opts.Version, schemaChecksum, expectedFunctions, functionChecksums, genOptions); err != nil {
 			return false, err
 		}
 	}
 	return false, nil
 }
 
-// outputDryRun writes the migration SQL to the provided writer.
-func (m *Migrator) outputDryRun(w io.Writer, melangeVersion, schemaChecksum string, generatedSQL GeneratedSQL, listSQL ListGeneratedSQL, expectedFunctions []string) {
+// outputDryRun writes the migration as a script that can be piped into psql.
+// Statements come in the order the migration applies them, so each function
+// is created after the ones it calls. Unless opts.NoTransaction is set, the
+// script runs in one transaction. It takes the advisory lock a migration
+// takes, fails early when the database schema or tuples relation is missing,
+// and ends by checking that every expected function exists. Orphaned
+// functions are not dropped, since finding them needs the database.
+func (m *Migrator) outputDryRun(w io.Writer, opts InternalMigrateOptions, schemaChecksum string, generatedSQL GeneratedSQL, listSQL ListGeneratedSQL, expectedFunctions []string) {
+	melangeVersion := opts.Version
+
 	// Header
 	_, _ = fmt.Fprintf(w, "-- Melange Migration (dry-run)\n")
 	if melangeVersion != "" {
@@ -819,8 +1496,18 @@ func (m *Migrator) outputDryRun(w io.Writer, melangeVersion, schemaChecksum stri
 	}
 	_, _ = fmt.Fprintf(w, "-- Schema checksum: %s\n", schemaChecksum)
 	_, _ = fmt.Fprintf(w, "-- Codegen version: %s\n", CodegenVersion())
+	_, _ = fmt.Fprint(w, sqldsl.TuplesColumnTypeNote)
+	_, _ = fmt.Fprintf(w, "-- Run with: psql -v ON_ERROR_STOP=1 -f <file>\n")
 	_, _ = fmt.Fprintf(w, "\n")
 
+	if !opts.NoTransaction {
+		_, _ = fmt.Fprintf(w, "BEGIN;\n\n")
+	}
+	_, _ = fmt.Fprintf(w, "SELECT pg_advisory_xact_lock(%d);\n\n", AdvisoryLockKey)
+	if opts.StatementTimeout > 0 {
+		_, _ = fmt.Fprintf(w, "%s;\n\n", statementTimeoutSQL(opts.StatementTimeout))
+	}
+
 	// Database schema
 	if m.databaseSchema != "" {
 		_, _ = fmt.Fprintf(w, "-- ============================================================\n")
@@ -830,6 +1517,12 @@ func (m *Migrator) outputDryRun(w io.Writer, melangeVersion, schemaChecksum stri
 		_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
 	}
 
+	// Guards
+	_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+	_, _ = fmt.Fprintf(w, "-- Guards: Database Schema and Tuples Relation\n")
+	_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+	_, _ = fmt.Fprintf(w, "%s\n\n", m.dryRunGuards(opts.TuplesTable))
+
 	// Migrations DDL (including column migrations for legacy tables)
 	_, _ = fmt.Fprintf(w, "-- ============================================================\n")
 	_, _ = fmt.Fprintf(w, "-- DDL: Migration Tracking Table\n")
@@ -838,6 +1531,15 @@ func (m *Migrator) outputDryRun(w io.Writer, melangeVersion, schemaChecksum stri
 		_, _ = fmt.Fprintf(w, "%s\n\n", stmt)
 	}
 
+	// Opt-in model lookups called by the check and list functions
+	if generatedSQL.ClosureFunction != "" {
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+		_, _ = fmt.Fprintf(w, "-- Closure and Userset Functions\n")
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.ClosureFunction)
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.UsersetFunction)
+	}
+
 	// Check functions
 	_, _ = fmt.Fprintf(w, "-- ============================================================\n")
 	_, _ = fmt.Fprintf(w, "-- Check Functions (%d functions)\n", len(generatedSQL.Functions))
@@ -854,6 +1556,24 @@ func (m *Migrator) outputDryRun(w io.Writer, melangeVersion, schemaChecksum stri
 		_, _ = fmt.Fprintf(w, "%s\n\n", fn)
 	}
 
+	// Context check functions
+	_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+	_, _ = fmt.Fprintf(w, "-- Context Check Functions (%d functions)\n", len(generatedSQL.ContextFunctions))
+	_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+	for _, fn := range generatedSQL.ContextFunctions {
+		_, _ = fmt.Fprintf(w, "%s\n\n", fn)
+	}
+
+	// Opt-in strict check functions
+	if len(generatedSQL.StrictFunctions) > 0 {
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+		_, _ = fmt.Fprintf(w, "-- Strict Check Functions (%d functions)\n", len(generatedSQL.StrictFunctions))
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+		for _, fn := range generatedSQL.StrictFunctions {
+			_, _ = fmt.Fprintf(w, "%s\n\n", fn)
+		}
+	}
+
 	// Check dispatchers
 	_, _ = fmt.Fprintf(w, "-- ============================================================\n")
 	_, _ = fmt.Fprintf(w, "-- Check Dispatchers\n")
@@ -864,9 +1584,27 @@ func (m *Migrator) outputDryRun(w io.Writer, melangeVersion, schemaChecksum stri
 	if generatedSQL.DispatcherNoWildcard != "" {
 		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.DispatcherNoWildcard)
 	}
+	if generatedSQL.DispatcherStrict != "" {
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.DispatcherStrict)
+	}
+	if generatedSQL.ContextDispatcher != "" {
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.ContextDispatcher)
+	}
+	if generatedSQL.ContextualTuplesFunction != "" {
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.ContextualTuplesFunction)
+	}
 	if generatedSQL.BulkDispatcher != "" {
 		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.BulkDispatcher)
 	}
+	if generatedSQL.BatchDispatcher != "" {
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.BatchDispatcher)
+	}
+	if generatedSQL.CheckAnyDispatcher != "" {
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.CheckAnyDispatcher)
+	}
+	if generatedSQL.CheckAllDispatcher != "" {
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.CheckAllDispatcher)
+	}
 
 	// Explain functions + dispatcher (Stage 1: slice 1 — direct-grant only,
 	// with cycle detection and a no-entry sentinel for unknown pairs).
@@ -897,6 +1635,40 @@ func (m *Migrator) outputDryRun(w io.Writer, melangeVersion, schemaChecksum stri
 		}
 	}
 
+	// Model inventory
+	if generatedSQL.ModelRelationsFunction != "" {
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+		_, _ = fmt.Fprintf(w, "-- Model Relations Function\n")
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.ModelRelationsFunction)
+	}
+
+	// Opt-in effective_access audit function
+	if generatedSQL.EffectiveAccessFunction != "" {
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+		_, _ = fmt.Fprintf(w, "-- Effective Access Function\n")
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.EffectiveAccessFunction)
+	}
+
+	// Opt-in check_with_evidence audit functions
+	if len(generatedSQL.EvidenceFunctions) > 0 {
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+		_, _ = fmt.Fprintf(w, "-- Check Evidence Functions (%d functions)\n", len(generatedSQL.EvidenceFunctions))
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+		for _, fn := range generatedSQL.EvidenceFunctions {
+			_, _ = fmt.Fprintf(w, "%s\n\n", fn)
+		}
+	}
+
+	// Depth limit helper called by the recursive list functions
+	if listSQL.DepthExceededFunction != "" {
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+		_, _ = fmt.Fprintf(w, "-- Depth Limit Function\n")
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+		_, _ = fmt.Fprintf(w, "%s\n\n", listSQL.DepthExceededFunction)
+	}
+
 	// List objects functions
 	_, _ = fmt.Fprintf(w, "-- ============================================================\n")
 	_, _ = fmt.Fprintf(w, "-- List Objects Functions (%d functions)\n", len(listSQL.ListObjectsFunctions))
@@ -923,6 +1695,31 @@ func (m *Migrator) outputDryRun(w io.Writer, melangeVersion, schemaChecksum stri
 	if listSQL.ListSubjectsDispatcher != "" {
 		_, _ = fmt.Fprintf(w, "%s\n\n", listSQL.ListSubjectsDispatcher)
 	}
+	if listSQL.ListObjectsAnyDispatcher != "" {
+		_, _ = fmt.Fprintf(w, "%s\n\n", listSQL.ListObjectsAnyDispatcher)
+	}
+	if listSQL.ListSubjectsMultiDispatcher != "" {
+		_, _ = fmt.Fprintf(w, "%s\n\n", listSQL.ListSubjectsMultiDispatcher)
+	}
+
+	// Opt-in list_objects cursor functions
+	if len(listSQL.ListObjectsCursorFunctions) > 0 {
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+		_, _ = fmt.Fprintf(w, "-- List Objects Cursor Functions (%d functions)\n", len(listSQL.ListObjectsCursorFunctions)+1)
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+		for _, fn := range listSQL.ListObjectsCursorFunctions {
+			_, _ = fmt.Fprintf(w, "%s\n\n", fn)
+		}
+		_, _ = fmt.Fprintf(w, "%s\n\n", listSQL.ListObjectsCursorDispatcher)
+	}
+
+	// Health check, last so that it reports the functions above
+	if generatedSQL.HealthcheckFunction != "" {
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+		_, _ = fmt.Fprintf(w, "-- Healthcheck Function\n")
+		_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+		_, _ = fmt.Fprintf(w, "%s\n\n", generatedSQL.HealthcheckFunction)
+	}
 
 	// Migration record
 	_, _ = fmt.Fprintf(w, "-- ============================================================\n")
@@ -939,8 +1736,63 @@ func (m *Migrator) outputDryRun(w io.Writer, melangeVersion, schemaChecksum stri
 	for i, fn := range sortedFunctions {
 		quotedFunctions[i] = fmt.Sprintf("'%s'", fn)
 	}
-	_, _ = fmt.Fprintf(w, "INSERT INTO %s (melange_version, schema_checksum, codegen_version, function_names)\n", m.prefixIdent("melange_migrations"))
-	_, _ = fmt.Fprintf(w, "VALUES ('%s', '%s', '%s', ARRAY[%s]);\n", melangeVersion, schemaChecksum, CodegenVersion(), strings.Join(quotedFunctions, ", "))
+	optionsJSON, _ := json.Marshal(opts.generationOptions())
+	_, _ = fmt.Fprintf(w, "INSERT INTO %s (melange_version, schema_checksum, codegen_version, function_names, generation_options)\n", m.prefixIdent("melange_migrations"))
+	_, _ = fmt.Fprintf(w, "VALUES ('%s', '%s', '%s', ARRAY[%s], %s);\n\n", melangeVersion, schemaChecksum, CodegenVersion(), strings.Join(quotedFunctions, ", "), sqldsl.QuoteLiteral(string(optionsJSON)))
+
+	// Verification
+	_, _ = fmt.Fprintf(w, "-- ============================================================\n")
+	_, _ = fmt.Fprintf(w, "-- Verification\n")
+	_, _ = fmt.Fprintf(w, "-- ============================================================\n\n")
+	_, _ = fmt.Fprintf(w, "%s\n", m.dryRunVerification(quotedFunctions))
+
+	if !opts.NoTransaction {
+		_, _ = fmt.Fprintf(w, "\nCOMMIT;\n")
+	}
+}
+
+// dryRunGuards returns a DO block that raises when the database schema or the
+// tuples relation does not exist, before any function is created against it.
+func (m *Migrator) dryRunGuards(tuplesTable string) string {
+	tuplesSchema, tuplesName, tuplesRef := m.tuplesRelationOf(tuplesTable)
+	return fmt.Sprintf(`DO $guard$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = %[1]s) THEN
+        RAISE EXCEPTION 'melange: database schema %% does not exist', %[1]s;
+    END IF;
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_class c
+        JOIN pg_namespace n ON n.oid = c.relnamespace
+        WHERE c.relname = %[2]s
+        AND n.nspname = %[3]s
+        AND c.relkind IN ('r', 'v', 'm')
+    ) THEN
+        RAISE EXCEPTION 'melange: tuples relation %% does not exist', %[4]s;
+    END IF;
+END
+$guard$;`, m.postgresSchema(), sqldsl.QuoteLiteral(tuplesName), tuplesSchema, sqldsl.QuoteLiteral(tuplesRef))
+}
+
+// dryRunVerification returns a DO block that raises, naming them, when any of
+// the quoted function names is missing from the database schema.
+func (m *Migrator) dryRunVerification(quotedFunctions []string) string {
+	return fmt.Sprintf(`DO $verify$
+DECLARE
+    missing TEXT;
+BEGIN
+    SELECT string_agg(f, ', ' ORDER BY f) INTO missing
+    FROM unnest(ARRAY[%s]::TEXT[]) AS f
+    WHERE NOT EXISTS (
+        SELECT 1 FROM pg_proc p
+        JOIN pg_namespace n ON n.oid = p.pronamespace
+        WHERE p.proname = f
+        AND n.nspname = %s
+    );
+    IF missing IS NOT NULL THEN
+        RAISE EXCEPTION 'melange: functions missing after migration: %%', missing;
+    END IF;
+END
+$verify$;`, strings.Join(quotedFunctions, ", "), m.postgresSchema())
 }
 
 func (m *Migrator) prefixIdent(identifier string) string {
@@ -950,3 +1802,19 @@ func (m *Migrator) prefixIdent(identifier string) string {
 func (m *Migrator) postgresSchema() string {
 	return sqldsl.PostgresSchemaExpr(m.databaseSchema)
 }
+
+// tuplesRelation returns the tuples relation as an SQL expression for the
+// schema it lives in, its name within that schema, and a reference to query
+// it by. An unqualified name lives in the database schema.
+func (m *Migrator) tuplesRelation() (schemaExpr, name, ref string) {
+	return m.tuplesRelationOf(m.tuplesTable)
+}
+
+// tuplesRelationOf is tuplesRelation for the given tuples table.
+func (m *Migrator) tuplesRelationOf(tuplesTable string) (schemaExpr, name, ref string) {
+	schema, name := sqlgen.SplitTuplesTable(tuplesTable)
+	if schema == "" {
+		return m.postgresSchema(), name, m.prefixIdent(name)
+	}
+	return sqldsl.QuoteLiteral(schema), name, sqldsl.PrefixIdent(name, schema)
+}
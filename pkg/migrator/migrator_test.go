@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pthm/melange/lib/version"
 )
@@ -159,6 +160,166 @@ func TestShouldSkipMigration(t *testing.T) {
 	})
 }
 
+// testSchemaHash stands in for a SchemaHash result in checksum tests.
+var testSchemaHash = ComputeSchemaChecksum("test schema")
+
+func TestMigrationSchemaChecksum_PoolerSafe(t *testing.T) {
+	plain := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema"})
+	if plain != testSchemaHash {
+		t.Error("default runs must record the schema hash alone so generated migration records still match")
+	}
+
+	withVersion(t, "v9.9.9")
+	rec := &MigrationRecord{SchemaChecksum: plain, CodegenVersion: CodegenVersion()}
+	pooled := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", PoolerSafe: true})
+	if shouldSkipMigration(rec, pooled) {
+		t.Error("enabling PoolerSafe must defeat the phase 1 skip")
+	}
+	rec.SchemaChecksum = pooled
+	if shouldSkipMigration(rec, plain) {
+		t.Error("disabling PoolerSafe must defeat the phase 1 skip")
+	}
+}
+
+func TestMigrationSchemaChecksum_TableRouted(t *testing.T) {
+	withVersion(t, "v9.9.9")
+	plain := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema"})
+	routed := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", TableRoutedDispatcher: true})
+	both := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", TableRoutedDispatcher: true, PoolerSafe: true})
+
+	rec := &MigrationRecord{SchemaChecksum: plain, CodegenVersion: CodegenVersion()}
+	if shouldSkipMigration(rec, routed) {
+		t.Error("enabling TableRoutedDispatcher must defeat the phase 1 skip")
+	}
+	rec.SchemaChecksum = routed
+	if shouldSkipMigration(rec, plain) {
+		t.Error("disabling TableRoutedDispatcher must defeat the phase 1 skip")
+	}
+	if shouldSkipMigration(rec, both) {
+		t.Error("enabling PoolerSafe alongside TableRoutedDispatcher must defeat the phase 1 skip")
+	}
+}
+
+func TestMigrationSchemaChecksum_Anytime(t *testing.T) {
+	withVersion(t, "v9.9.9")
+	plain := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema"})
+	anytime := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", AnytimeListObjects: true})
+
+	rec := &MigrationRecord{SchemaChecksum: plain, CodegenVersion: CodegenVersion()}
+	if shouldSkipMigration(rec, anytime) {
+		t.Error("enabling AnytimeListObjects must defeat the phase 1 skip")
+	}
+	rec.SchemaChecksum = anytime
+	if shouldSkipMigration(rec, plain) {
+		t.Error("disabling AnytimeListObjects must defeat the phase 1 skip")
+	}
+}
+
+func TestMigrationSchemaChecksum_ExpandWildcardSubjects(t *testing.T) {
+	withVersion(t, "v9.9.9")
+	plain := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema"})
+	view := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", ExpandWildcardSubjects: true})
+
+	rec := &MigrationRecord{SchemaChecksum: plain, CodegenVersion: CodegenVersion()}
+	if shouldSkipMigration(rec, view) {
+		t.Error("enabling ExpandWildcardSubjects must defeat the phase 1 skip")
+	}
+	rec.SchemaChecksum = view
+	if shouldSkipMigration(rec, plain) {
+		t.Error("disabling ExpandWildcardSubjects must defeat the phase 1 skip")
+	}
+}
+
+func TestMigrationSchemaChecksum_ObjectDelimiter(t *testing.T) {
+	withVersion(t, "v9.9.9")
+	plain := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema"})
+	colon := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", ObjectDelimiter: ":"})
+	slash := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", ObjectDelimiter: "/"})
+	pipe := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", ObjectDelimiter: "|"})
+
+	if colon != plain {
+		t.Error("spelling out the default delimiter must not change the checksum")
+	}
+	rec := &MigrationRecord{SchemaChecksum: plain, CodegenVersion: CodegenVersion()}
+	if shouldSkipMigration(rec, slash) {
+		t.Error("setting ObjectDelimiter must defeat the phase 1 skip")
+	}
+	rec.SchemaChecksum = slash
+	if shouldSkipMigration(rec, pipe) {
+		t.Error("changing ObjectDelimiter must defeat the phase 1 skip")
+	}
+}
+
+func TestMigrationSchemaChecksum_TuplesTable(t *testing.T) {
+	withVersion(t, "v9.9.9")
+	plain := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema"})
+	named := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", TuplesTable: "melange_tuples"})
+	custom := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", TuplesTable: "tuples"})
+	qualified := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", TuplesTable: "tenant_42.tuples"})
+
+	if named != plain {
+		t.Error("spelling out the default tuples table must not change the checksum")
+	}
+	rec := &MigrationRecord{SchemaChecksum: plain, CodegenVersion: CodegenVersion()}
+	if shouldSkipMigration(rec, custom) {
+		t.Error("setting TuplesTable must defeat the phase 1 skip")
+	}
+	rec.SchemaChecksum = custom
+	if shouldSkipMigration(rec, qualified) {
+		t.Error("changing TuplesTable must defeat the phase 1 skip")
+	}
+}
+
+func TestMigrationSchemaChecksum_MaxDepth(t *testing.T) {
+	withVersion(t, "v9.9.9")
+	plain := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema"})
+	dflt := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", MaxDepth: 25})
+	deeper := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", MaxDepth: 40})
+	shallower := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", MaxDepth: 10})
+
+	if dflt != plain {
+		t.Error("spelling out the default max depth must not change the checksum")
+	}
+	rec := &MigrationRecord{SchemaChecksum: plain, CodegenVersion: CodegenVersion()}
+	if shouldSkipMigration(rec, deeper) {
+		t.Error("setting MaxDepth must defeat the phase 1 skip")
+	}
+	rec.SchemaChecksum = deeper
+	if shouldSkipMigration(rec, shallower) {
+		t.Error("changing MaxDepth must defeat the phase 1 skip")
+	}
+}
+
+func TestMigrationSchemaChecksum_DisableNullGuards(t *testing.T) {
+	withVersion(t, "v9.9.9")
+	plain := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema"})
+	unguarded := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", DisableNullGuards: true})
+
+	rec := &MigrationRecord{SchemaChecksum: plain, CodegenVersion: CodegenVersion()}
+	if shouldSkipMigration(rec, unguarded) {
+		t.Error("enabling DisableNullGuards must defeat the phase 1 skip")
+	}
+	rec.SchemaChecksum = unguarded
+	if shouldSkipMigration(rec, plain) {
+		t.Error("disabling DisableNullGuards must defeat the phase 1 skip")
+	}
+}
+
+func TestMigrationSchemaChecksum_Dialect(t *testing.T) {
+	withVersion(t, "v9.9.9")
+	plain := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema"})
+	postgres := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", Dialect: "postgres"})
+	cockroach := migrationSchemaChecksum(testSchemaHash, InternalMigrateOptions{SchemaContent: "test schema", Dialect: "cockroach"})
+
+	if postgres != plain {
+		t.Error("spelling out the default dialect must not change the checksum")
+	}
+	rec := &MigrationRecord{SchemaChecksum: plain, CodegenVersion: CodegenVersion()}
+	if shouldSkipMigration(rec, cockroach) {
+		t.Error("switching dialect must defeat the phase 1 skip")
+	}
+}
+
 func TestShouldSkipApply(t *testing.T) {
 	checksums := map[string]string{
 		"check_doc_viewer": "hash_a",
@@ -274,7 +435,7 @@ func TestOutputDryRun(t *testing.T) {
 		}
 		expected := []string{"check_repo_viewer", "check_permission"}
 
-		m.outputDryRun(&buf, "v0.5.0", "abc123", gen, listSQL, expected)
+		m.outputDryRun(&buf, InternalMigrateOptions{Version: "v0.5.0"}, "abc123", gen, listSQL, expected)
 		output := buf.String()
 
 		// Header
@@ -322,9 +483,82 @@ func TestOutputDryRun(t *testing.T) {
 		}
 	})
 
+	t.Run("runnable script", func(t *testing.T) {
+		var buf bytes.Buffer
+		gen := GeneratedSQL{
+			Functions:  []string{"CREATE FUNCTION check_repo_viewer()"},
+			Dispatcher: "CREATE FUNCTION check_permission()",
+		}
+		listSQL := ListGeneratedSQL{ListObjectsDispatcher: "CREATE FUNCTION list_objects()"}
+		opts := InternalMigrateOptions{TuplesTable: "tenant.tuples"}
+		m.outputDryRun(&buf, opts, "abc", gen, listSQL, []string{"check_repo_viewer", "check_permission"})
+		output := buf.String()
+
+		// Statements appear in the order they must run.
+		var last int
+		for _, want := range []string{
+			"BEGIN;",
+			"pg_advisory_xact_lock",
+			"DO $guard$",
+			"CREATE TABLE IF NOT EXISTS",
+			"CREATE FUNCTION check_repo_viewer()",
+			"CREATE FUNCTION check_permission()",
+			"CREATE FUNCTION list_objects()",
+			"INSERT INTO",
+			"DO $verify$",
+			"COMMIT;",
+		} {
+			i := strings.Index(output[last:], want)
+			if i < 0 {
+				t.Fatalf("missing %q after offset %d:\n%s", want, last, output)
+			}
+			last += i
+		}
+		if !strings.HasSuffix(output, "COMMIT;\n") {
+			t.Error("script should end with COMMIT")
+		}
+		if !strings.Contains(output, "c.relname = 'tuples'") || !strings.Contains(output, "n.nspname = 'tenant'") {
+			t.Error("guard should look up the configured tuples relation")
+		}
+		if !strings.Contains(output, "unnest(ARRAY['check_permission', 'check_repo_viewer']::TEXT[])") {
+			t.Error("verification should check every expected function")
+		}
+		if !strings.Contains(output, `'{"tuples_table":"tenant.tuples"}');`) {
+			t.Error("migration record should store the generation options")
+		}
+	})
+
+	t.Run("statement timeout", func(t *testing.T) {
+		var buf bytes.Buffer
+		m.outputDryRun(&buf, InternalMigrateOptions{StatementTimeout: 1500 * time.Millisecond}, "abc", GeneratedSQL{}, ListGeneratedSQL{}, nil)
+		output := buf.String()
+
+		lock := strings.Index(output, "pg_advisory_xact_lock")
+		timeout := strings.Index(output, "SET LOCAL statement_timeout = 1500;")
+		if timeout < 0 || timeout < lock {
+			t.Errorf("should bound statements after taking the lock:\n%s", output)
+		}
+		if got := statementTimeoutSQL(time.Microsecond); got != "SET LOCAL statement_timeout = 1" {
+			t.Errorf("sub-millisecond timeout rendered as %q, want 1ms rather than no timeout", got)
+		}
+	})
+
+	t.Run("no transaction", func(t *testing.T) {
+		var buf bytes.Buffer
+		m.outputDryRun(&buf, InternalMigrateOptions{NoTransaction: true}, "abc", GeneratedSQL{}, ListGeneratedSQL{}, nil)
+		output := buf.String()
+
+		if strings.Contains(output, "BEGIN;") || strings.Contains(output, "COMMIT;") {
+			t.Error("should not wrap the script in a transaction")
+		}
+		if !strings.Contains(output, "DO $guard$") || !strings.Contains(output, "DO $verify$") {
+			t.Error("should keep the guard and verification blocks")
+		}
+	})
+
 	t.Run("omits version when empty", func(t *testing.T) {
 		var buf bytes.Buffer
-		m.outputDryRun(&buf, "", "abc", GeneratedSQL{}, ListGeneratedSQL{}, nil)
+		m.outputDryRun(&buf, InternalMigrateOptions{}, "abc", GeneratedSQL{}, ListGeneratedSQL{}, nil)
 		output := buf.String()
 
 		if strings.Contains(output, "-- Melange version:") {
@@ -335,7 +569,7 @@ func TestOutputDryRun(t *testing.T) {
 	t.Run("sorts function names in migration record", func(t *testing.T) {
 		var buf bytes.Buffer
 		expected := []string{"check_z_viewer", "check_a_owner", "check_m_editor"}
-		m.outputDryRun(&buf, "", "abc", GeneratedSQL{}, ListGeneratedSQL{}, expected)
+		m.outputDryRun(&buf, InternalMigrateOptions{}, "abc", GeneratedSQL{}, ListGeneratedSQL{}, expected)
 		output := buf.String()
 
 		// Should appear sorted in the INSERT
@@ -434,7 +668,7 @@ func TestOutputDryRun_DatabaseSchema(t *testing.T) {
 		m.SetDatabaseSchema("authz")
 
 		var buf bytes.Buffer
-		m.outputDryRun(&buf, "", "abc", GeneratedSQL{}, ListGeneratedSQL{}, nil)
+		m.outputDryRun(&buf, InternalMigrateOptions{}, "abc", GeneratedSQL{}, ListGeneratedSQL{}, nil)
 		output := buf.String()
 
 		if !strings.Contains(output, "Database schema: authz") {
@@ -452,7 +686,7 @@ func TestOutputDryRun_DatabaseSchema(t *testing.T) {
 		m := NewMigrator(nil, "")
 
 		var buf bytes.Buffer
-		m.outputDryRun(&buf, "", "abc", GeneratedSQL{}, ListGeneratedSQL{}, nil)
+		m.outputDryRun(&buf, InternalMigrateOptions{}, "abc", GeneratedSQL{}, ListGeneratedSQL{}, nil)
 		output := buf.String()
 
 		if !strings.Contains(output, "public") {
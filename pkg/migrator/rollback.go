@@ -0,0 +1,118 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/lib/pq"
+
+	"github.com/pthm/melange/lib/sqlgen"
+)
+
+// Rollback removes what previous migrations installed in the database
+// schema: every function recorded in melange_migrations by a migration not
+// yet rolled back, and the melange_routes table of a table-routed
+// dispatcher. It then sets rolled_back_at on those records rather than
+// deleting them, so the history keeps them while the next migration, which
+// ignores rolled-back records, applies its schema in full rather than
+// skipping it as unchanged.
+//
+// Unlike Uninstall, Rollback drops only recorded functions and never guesses
+// by naming convention, so functions installed outside the migrator, such as
+// by generated migration files (which carry their own DOWN migration), are
+// left alone. melange_tuples and melange_migrations itself are kept.
+//
+// Statements run without CASCADE in one transaction (when db supports
+// BeginTx), so Rollback fails rather than dropping an application object
+// that depends on a melange function. With dryRun set, the statements are
+// written to it and nothing is executed.
+//
+// Returns the statements executed, or that would be executed in a dry run.
+func (m *Migrator) Rollback(ctx context.Context, dryRun io.Writer) ([]string, error) {
+	stmts, err := m.rollbackStatements(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun != nil {
+		for _, stmt := range stmts {
+			if _, err := fmt.Fprintf(dryRun, "%s;\n", stmt); err != nil {
+				return nil, fmt.Errorf("writing dry run: %w", err)
+			}
+		}
+		return stmts, nil
+	}
+
+	err = inTx(ctx, m.db, func(tx Execer) error {
+		if err := lockMigrations(ctx, tx); err != nil {
+			return err
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("rolling back: %s: %w", stmt, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stmts, nil
+}
+
+// rollbackStatements lists the statements Rollback runs, in order. It is
+// empty when every recorded migration has been rolled back.
+func (m *Migrator) rollbackStatements(ctx context.Context, db Execer) ([]string, error) {
+	active, err := m.activeMigrationsFilter(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := m.recordedFunctionNames(ctx, db, active)
+	if err != nil || len(recorded) == 0 {
+		return nil, err
+	}
+
+	// Functions are dropped by signature, as in uninstallStatements. Names
+	// recorded by an older migration and since dropped as orphans are simply
+	// not found.
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT p.proname, oidvectortypes(p.proargtypes)
+		FROM pg_proc p
+		JOIN pg_namespace n ON p.pronamespace = n.oid
+		WHERE n.nspname = %s
+		AND p.proname = ANY($1)
+		ORDER BY 1, 2
+	`, m.postgresSchema()), pq.Array(recorded))
+	if err != nil {
+		return nil, fmt.Errorf("querying pg_proc: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var stmts []string
+	for rows.Next() {
+		var name, args string
+		if err := rows.Scan(&name, &args); err != nil {
+			return nil, fmt.Errorf("scanning function: %w", err)
+		}
+		stmts = append(stmts, fmt.Sprintf("DROP FUNCTION IF EXISTS %s(%s)", m.prefixIdent(name), args))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	kind, err := m.relationKind(ctx, db, sqlgen.RoutesTable)
+	if err != nil {
+		return nil, err
+	}
+	if kind != "" {
+		stmts = append(stmts, fmt.Sprintf("DROP %s IF EXISTS %s", kind, m.prefixIdent(sqlgen.RoutesTable)))
+	}
+
+	// Tables no migration has touched since rolled_back_at was added lack it.
+	table := m.prefixIdent("melange_migrations")
+	if active == "" {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN rolled_back_at TIMESTAMPTZ", table))
+	}
+	return append(stmts, fmt.Sprintf("UPDATE %s SET rolled_back_at = now() WHERE rolled_back_at IS NULL", table)), nil
+}
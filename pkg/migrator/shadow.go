@@ -0,0 +1,224 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+	"github.com/pthm/melange/pkg/parser"
+)
+
+// shadowCommentPrefix starts the COMMENT ON SCHEMA that MigrateShadow puts on
+// a shadow schema, followed by the name of the live schema it mirrors. Only
+// schemas carrying it are ever dropped, so a mistyped --shadow cannot drop a
+// schema melange did not create.
+const shadowCommentPrefix = "melange shadow of "
+
+// MigrateShadow installs the schema at schemaPath into shadowSchema, a
+// throwaway Postgres schema, without touching the functions in
+// opts.DatabaseSchema. The shadow gets its own check_permission and list
+// functions, and a view of the live schema's tuples relation (melange_tuples
+// unless opts.TuplesTable names another) under the same name, so the same
+// queries can be run against both and their results compared before the
+// change is promoted with PromoteShadow. A schema-qualified opts.TuplesTable
+// needs no view: both sets of functions read it directly.
+//
+// An existing shadow schema created by an earlier MigrateShadow is dropped
+// and rebuilt. Any other existing schema of that name is an error. The whole
+// install runs in one transaction when db supports BeginTx.
+//
+// opts.DryRun is not supported.
+func MigrateShadow(ctx context.Context, db Execer, schemaPath, shadowSchema string, opts MigrateOptions) error {
+	if err := checkShadowTarget(shadowSchema, opts); err != nil {
+		return err
+	}
+
+	schemaContent, err := parser.ReadSchemaContent(schemaPath)
+	if err != nil {
+		return fmt.Errorf("reading schema: %w", err)
+	}
+	types, err := parser.ParseSchema(schemaPath)
+	if err != nil {
+		return fmt.Errorf("parsing schema: %w", err)
+	}
+
+	return inTx(ctx, db, func(tx Execer) error {
+		mirrors, exists, err := shadowOf(ctx, tx, shadowSchema)
+		if err != nil {
+			return err
+		}
+		if exists && mirrors == nil {
+			return fmt.Errorf("schema %q exists and is not a melange shadow schema", shadowSchema)
+		}
+
+		shadow := sqldsl.QuoteIdent(shadowSchema)
+		stmts := []string{
+			"DROP SCHEMA IF EXISTS " + shadow + " CASCADE",
+			"CREATE SCHEMA " + shadow,
+			fmt.Sprintf("COMMENT ON SCHEMA %s IS %s", shadow, sqldsl.QuoteLiteral(shadowCommentPrefix+opts.DatabaseSchema)),
+		}
+		if tuplesSchema, tuplesName := sqlgen.SplitTuplesTable(opts.TuplesTable); tuplesSchema == "" {
+			stmts = append(stmts, fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM %s",
+				sqldsl.PrefixIdent(tuplesName, shadowSchema),
+				sqldsl.PrefixIdent(tuplesName, opts.DatabaseSchema)))
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("creating shadow schema: %w", err)
+			}
+		}
+
+		m := NewMigrator(tx, schemaPath)
+		m.SetDatabaseSchema(shadowSchema)
+		_, err = m.migrateWithTypesAndOptions(ctx, types, opts.internal(string(schemaContent)))
+		return err
+	})
+}
+
+// PromoteShadow makes the model installed in shadowSchema live and drops the
+// shadow schema, in one transaction when db supports BeginTx.
+//
+// Generated functions are bound to the schema they are installed in (their
+// search_path and the dispatcher's calls name it), so promotion cannot move
+// the shadow's functions. Instead it migrates schemaPath into
+// opts.DatabaseSchema, after checking that the schema content, codegen
+// version and generation options match what the shadow was built from. The
+// live functions are then the ones that were canaried, and callers see either
+// the old model or the new one, never a mix.
+//
+// Returns skipped=true when the live schema already had this model installed.
+func PromoteShadow(ctx context.Context, db Execer, schemaPath, shadowSchema string, opts MigrateOptions) (skipped bool, err error) {
+	if err := checkShadowTarget(shadowSchema, opts); err != nil {
+		return false, err
+	}
+
+	schemaContent, err := parser.ReadSchemaContent(schemaPath)
+	if err != nil {
+		return false, fmt.Errorf("reading schema: %w", err)
+	}
+	types, err := parser.ParseSchema(schemaPath)
+	if err != nil {
+		return false, fmt.Errorf("parsing schema: %w", err)
+	}
+	internalOpts := opts.internal(string(schemaContent))
+
+	err = inTx(ctx, db, func(tx Execer) error {
+		mirrors, _, err := shadowOf(ctx, tx, shadowSchema)
+		if err != nil {
+			return err
+		}
+		if mirrors == nil {
+			return fmt.Errorf("schema %q is not a melange shadow schema", shadowSchema)
+		}
+		if *mirrors != opts.DatabaseSchema {
+			return fmt.Errorf("shadow schema %q mirrors schema %q, not %q", shadowSchema, *mirrors, opts.DatabaseSchema)
+		}
+
+		shadow := NewMigrator(tx, schemaPath)
+		shadow.SetDatabaseSchema(shadowSchema)
+		rec, err := shadow.getLastMigration(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("reading shadow migration: %w", err)
+		}
+		if rec == nil ||
+			!migrationRecordMatches(rec, migrationSchemaChecksum(SchemaHash(types), internalOpts)) ||
+			!optionalFunctionsMatch(rec, internalOpts) {
+			return fmt.Errorf("schema or migrate options differ from those installed in shadow schema %q; re-run migrate --shadow first", shadowSchema)
+		}
+
+		live := NewMigrator(tx, schemaPath)
+		live.SetDatabaseSchema(opts.DatabaseSchema)
+		skipped, err = live.migrateWithTypesAndOptions(ctx, types, internalOpts)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DROP SCHEMA "+sqldsl.QuoteIdent(shadowSchema)+" CASCADE"); err != nil {
+			return fmt.Errorf("dropping shadow schema: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return skipped, nil
+}
+
+// DropShadow drops a shadow schema created by MigrateShadow, abandoning the
+// canary. It is a no-op when the schema does not exist and an error when the
+// schema exists but is not a melange shadow schema.
+func DropShadow(ctx context.Context, db Execer, shadowSchema string) error {
+	mirrors, exists, err := shadowOf(ctx, db, shadowSchema)
+	if err != nil || !exists {
+		return err
+	}
+	if mirrors == nil {
+		return fmt.Errorf("schema %q is not a melange shadow schema", shadowSchema)
+	}
+	if _, err := db.ExecContext(ctx, "DROP SCHEMA "+sqldsl.QuoteIdent(shadowSchema)+" CASCADE"); err != nil {
+		return fmt.Errorf("dropping shadow schema: %w", err)
+	}
+	return nil
+}
+
+// checkShadowTarget rejects shadow operations that would overwrite the live
+// schema or that cannot be previewed.
+func checkShadowTarget(shadowSchema string, opts MigrateOptions) error {
+	if shadowSchema == "" {
+		return errors.New("shadow schema name is required")
+	}
+	if shadowSchema == opts.DatabaseSchema {
+		return fmt.Errorf("shadow schema %q must differ from the database schema", shadowSchema)
+	}
+	if opts.DryRun != nil {
+		return errors.New("dry run is not supported for shadow schemas")
+	}
+	return nil
+}
+
+// shadowOf reports whether shadowSchema exists and, when it is a melange
+// shadow schema, the name of the live schema it mirrors. mirrors is nil for
+// a schema melange did not create.
+func shadowOf(ctx context.Context, db Execer, shadowSchema string) (mirrors *string, exists bool, err error) {
+	var comment sql.NullString
+	err = db.QueryRowContext(ctx,
+		"SELECT obj_description(oid, 'pg_namespace') FROM pg_namespace WHERE nspname = $1",
+		shadowSchema,
+	).Scan(&comment)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("checking shadow schema: %w", err)
+	}
+	if name, ok := strings.CutPrefix(comment.String, shadowCommentPrefix); ok {
+		return &name, true, nil
+	}
+	return nil, true, nil
+}
+
+// inTx runs fn in a transaction on db when db supports BeginTx, committing
+// when fn succeeds. Otherwise fn runs directly on db.
+func inTx(ctx context.Context, db Execer, fn func(Execer) error) error {
+	txer, ok := db.(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return fn(db)
+	}
+
+	tx, err := txer.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
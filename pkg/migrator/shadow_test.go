@@ -0,0 +1,29 @@
+package migrator
+
+import (
+	"io"
+	"testing"
+)
+
+func TestCheckShadowTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		shadow  string
+		opts    MigrateOptions
+		wantErr bool
+	}{
+		{"distinct schema", "melange_shadow", MigrateOptions{DatabaseSchema: "public"}, false},
+		{"unqualified live schema", "melange_shadow", MigrateOptions{}, false},
+		{"missing name", "", MigrateOptions{DatabaseSchema: "public"}, true},
+		{"same as live", "public", MigrateOptions{DatabaseSchema: "public"}, true},
+		{"dry run", "melange_shadow", MigrateOptions{DatabaseSchema: "public", DryRun: io.Discard}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkShadowTarget(tt.shadow, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkShadowTarget(%q) error = %v, wantErr %v", tt.shadow, err, tt.wantErr)
+			}
+		})
+	}
+}
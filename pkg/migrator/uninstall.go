@@ -0,0 +1,223 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lib/pq"
+
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+)
+
+// UninstallOptions controls Uninstall.
+type UninstallOptions struct {
+	// DatabaseSchema is the Postgres schema melange was installed in.
+	DatabaseSchema string
+
+	// DryRun writes the DROP statements to the provided writer instead of
+	// executing them. If nil, the objects are dropped.
+	DryRun io.Writer
+
+	// DropTuples also drops the tuples relation. It is the application's view
+	// (or table) over its own data, so it is kept unless asked for.
+	DropTuples bool
+
+	// TuplesTable names the tuples relation DropTuples drops. Empty means
+	// melange_tuples. See MigrateOptions.TuplesTable.
+	TuplesTable string
+}
+
+// Uninstall drops everything melange created in opts.DatabaseSchema: the
+// generated functions, the melange_routes table of a table-routed dispatcher,
+// and the melange_migrations history. Melange creates no types, views or
+// sequences of its own outside those tables. The tuples relation is dropped
+// only with opts.DropTuples.
+//
+// Functions are found by name: every function recorded in
+// melange_migrations, plus every function following melange's naming
+// convention (check_*, list_*, explain_*, expand_*, effective_access,
+// melange_closure_rows, melange_userset_rows,
+// melange_model_relations, melange_depth_exceeded and melange_healthcheck),
+// so functions installed by generated migrations or older versions are found
+// too. A function of the application's own that matches the convention is
+// dropped with them; use opts.DryRun to review the list first.
+//
+// Statements run without CASCADE, so Uninstall fails rather than silently
+// dropping an application object, such as a row-level security policy, that
+// still depends on a melange function. Functions go first, as nothing
+// melange creates depends on the tables. The whole uninstall runs in one
+// transaction when db supports BeginTx, and running it again finds nothing to
+// drop.
+//
+// Returns the statements executed, or that would be executed in a dry run.
+func Uninstall(ctx context.Context, db Execer, opts UninstallOptions) ([]string, error) {
+	m := NewMigrator(db, "")
+	m.SetDatabaseSchema(opts.DatabaseSchema)
+	m.SetTuplesTable(opts.TuplesTable)
+
+	stmts, err := m.uninstallStatements(ctx, db, opts.DropTuples)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun != nil {
+		for _, stmt := range stmts {
+			if _, err := fmt.Fprintf(opts.DryRun, "%s;\n", stmt); err != nil {
+				return nil, fmt.Errorf("writing dry run: %w", err)
+			}
+		}
+		return stmts, nil
+	}
+
+	err = inTx(ctx, db, func(tx Execer) error {
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("uninstalling: %s: %w", stmt, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stmts, nil
+}
+
+// uninstallStatements lists the DROP statements Uninstall runs, in order.
+func (m *Migrator) uninstallStatements(ctx context.Context, db Execer, dropTuples bool) ([]string, error) {
+	recorded, err := m.recordedFunctionNames(ctx, db, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Functions are dropped by signature: several names are overloaded, and
+	// DROP FUNCTION by bare name fails for those.
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT p.proname, oidvectortypes(p.proargtypes)
+		FROM pg_proc p
+		JOIN pg_namespace n ON p.pronamespace = n.oid
+		WHERE n.nspname = %s
+		AND (
+			p.proname LIKE 'check_%%'
+			OR p.proname LIKE 'list_%%'
+			OR p.proname LIKE 'explain_%%'
+			OR p.proname LIKE 'expand_%%'
+			OR p.proname = 'effective_access'
+			OR p.proname = %s
+			OR p.proname = %s
+			OR p.proname = %s
+			OR p.proname = %s
+			OR p.proname = %s
+			OR p.proname = ANY($1)
+		)
+		ORDER BY 1, 2
+	`, m.postgresSchema(), sqldsl.QuoteLiteral(sqlgen.ClosureFunctionName), sqldsl.QuoteLiteral(sqlgen.ModelRelationsFunctionName),
+		sqldsl.QuoteLiteral(sqlgen.DepthExceededFunctionName), sqldsl.QuoteLiteral(sqlgen.HealthcheckFunctionName),
+		sqldsl.QuoteLiteral(sqlgen.UsersetFunctionName)),
+		pq.Array(recorded))
+	if err != nil {
+		return nil, fmt.Errorf("querying pg_proc: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var stmts []string
+	for rows.Next() {
+		var name, args string
+		if err := rows.Scan(&name, &args); err != nil {
+			return nil, fmt.Errorf("scanning function: %w", err)
+		}
+		stmts = append(stmts, fmt.Sprintf("DROP FUNCTION IF EXISTS %s(%s)", m.prefixIdent(name), args))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, table := range []string{sqlgen.RoutesTable, "melange_migrations"} {
+		kind, err := m.relationKind(ctx, db, table)
+		if err != nil {
+			return nil, err
+		}
+		if kind != "" {
+			stmts = append(stmts, fmt.Sprintf("DROP %s IF EXISTS %s", kind, m.prefixIdent(table)))
+		}
+	}
+	if dropTuples {
+		schemaExpr, name, ref := m.tuplesRelation()
+		kind, err := relationKindIn(ctx, db, schemaExpr, name)
+		if err != nil {
+			return nil, err
+		}
+		if kind != "" {
+			stmts = append(stmts, fmt.Sprintf("DROP %s IF EXISTS %s", kind, ref))
+		}
+	}
+	return stmts, nil
+}
+
+// recordedFunctionNames returns every function name recorded in the
+// melange_migrations rows matching where (a WHERE clause, or "" for all rows),
+// or nil when the table does not exist.
+func (m *Migrator) recordedFunctionNames(ctx context.Context, db Execer, where string) ([]string, error) {
+	kind, err := m.relationKind(ctx, db, "melange_migrations")
+	if err != nil || kind == "" {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT DISTINCT unnest(function_names) FROM %s %s", m.prefixIdent("melange_migrations"), where))
+	if err != nil {
+		return nil, fmt.Errorf("querying recorded functions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning recorded function: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// relationKind returns the DROP keyword for relation in the database schema:
+// "TABLE", "VIEW" or "MATERIALIZED VIEW", or "" when it does not exist.
+func (m *Migrator) relationKind(ctx context.Context, db Execer, relation string) (string, error) {
+	return relationKindIn(ctx, db, m.postgresSchema(), relation)
+}
+
+// relationKindIn is relationKind for the schema schemaExpr evaluates to.
+func relationKindIn(ctx context.Context, db Execer, schemaExpr, relation string) (string, error) {
+	var relkind string
+	err := db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT c.relkind::text FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relname = $1
+		AND n.nspname = %s
+		AND c.relkind IN ('r', 'p', 'v', 'm')
+	`, schemaExpr), relation).Scan(&relkind)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("checking %s: %w", relation, err)
+	}
+	return dropKeyword(relkind), nil
+}
+
+// dropKeyword maps a pg_class relkind to the DROP statement keyword for it.
+func dropKeyword(relkind string) string {
+	switch relkind {
+	case "v":
+		return "VIEW"
+	case "m":
+		return "MATERIALIZED VIEW"
+	default:
+		return "TABLE"
+	}
+}
@@ -0,0 +1,17 @@
+package migrator
+
+import "testing"
+
+func TestDropKeyword(t *testing.T) {
+	tests := map[string]string{
+		"r": "TABLE",
+		"p": "TABLE",
+		"v": "VIEW",
+		"m": "MATERIALIZED VIEW",
+	}
+	for relkind, want := range tests {
+		if got := dropKeyword(relkind); got != want {
+			t.Errorf("dropKeyword(%q) = %q, want %q", relkind, got, want)
+		}
+	}
+}
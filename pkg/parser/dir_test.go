@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pthm/melange/melange"
+)
+
+func TestParseSchemaDir_Merge(t *testing.T) {
+	dir := t.TempDir()
+
+	// Written out of name order: the merge must follow names, not creation.
+	writeTestFile(t, dir, "b_docs.fga", `model
+  schema 1.1
+
+type document
+  relations
+    define owner: [user]
+    define viewer: [user, team#member] or owner
+`)
+	writeTestFile(t, dir, "a_core.fga", `model
+  schema 1.1
+
+type user
+
+type team
+  relations
+    define member: [user]
+`)
+	writeTestFile(t, dir, "README.md", "not a schema")
+	writeTestFile(t, dir, "nested/ignored.fga", "not parsed")
+
+	types, err := ParseSchema(dir)
+	if err != nil {
+		t.Fatalf("ParseSchema(dir): %v", err)
+	}
+
+	var order []string
+	for _, td := range types {
+		order = append(order, td.Name)
+	}
+	if want := []string{"user", "team", "document"}; !slices.Equal(order, want) {
+		t.Errorf("types = %v, want %v", order, want)
+	}
+	doc := findType(types, "document")
+	if doc == nil || !slices.Equal(relationNames(doc), []string{"owner", "viewer"}) {
+		t.Errorf("document relations = %v", doc)
+	}
+
+	content, err := ReadSchemaContent(dir)
+	if err != nil {
+		t.Fatalf("ReadSchemaContent(dir): %v", err)
+	}
+	a, b := bytes.Index(content, []byte("a_core.fga")), bytes.Index(content, []byte("b_docs.fga"))
+	if a < 0 || b < 0 || a > b {
+		t.Errorf("content should list a_core.fga before b_docs.fga:\n%s", content)
+	}
+	if bytes.Contains(content, []byte("not a schema")) || bytes.Contains(content, []byte("not parsed")) {
+		t.Error("content includes files that are not merged")
+	}
+}
+
+func TestParseSchemaDir_DuplicateType(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "one.fga", "model\n  schema 1.1\n\ntype user\n")
+	writeTestFile(t, dir, "two.fga", "model\n  schema 1.1\n\ntype user\n")
+
+	_, err := ParseSchemaDir(dir)
+	if !errors.Is(err, melange.ErrInvalidSchema) {
+		t.Fatalf("err = %v, want ErrInvalidSchema", err)
+	}
+	if !strings.Contains(err.Error(), `type "user" is defined in both one.fga and two.fga`) {
+		t.Errorf("err = %v, should name both files", err)
+	}
+}
+
+func TestParseSchemaDir_Errors(t *testing.T) {
+	t.Run("no schema files", func(t *testing.T) {
+		if _, err := ParseSchemaDir(t.TempDir()); !errors.Is(err, melange.ErrInvalidSchema) {
+			t.Errorf("err = %v, want ErrInvalidSchema", err)
+		}
+	})
+
+	t.Run("syntax error names the file", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "bad.fga", "model\n  schema 1.1\n\ntype doc\n  relations\n    define viewer [user]\n")
+		_, err := ParseSchemaDir(dir)
+		var parseErrs ParseErrors
+		if !errors.As(err, &parseErrs) || !strings.Contains(err.Error(), "bad.fga") {
+			t.Errorf("err = %v, want ParseErrors naming bad.fga", err)
+		}
+	})
+}
+
+func TestParseSchemaDir_Manifest(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "fga.mod", "schema: '1.2'\ncontents:\n  - core.fga\n")
+	writeTestFile(t, dir, "core.fga", "module core\n\ntype user\n")
+
+	types, err := ParseSchemaDir(dir)
+	if err != nil {
+		t.Fatalf("ParseSchemaDir: %v", err)
+	}
+	if names := typeNames(types); !slices.Equal(names, []string{"user"}) {
+		t.Errorf("types = %v, want [user]", names)
+	}
+}
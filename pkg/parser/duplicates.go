@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// definitionSite is where a type or relation name was first defined.
+type definitionSite struct {
+	line, column int // 1-based
+}
+
+// findDuplicateDefinitions scans single-file DSL content for a type defined
+// twice, or a relation defined twice within one type, and returns a
+// ParseError positioned at each repeat that names the line of the first
+// definition.
+//
+// The OpenFGA transformer accepts a repeated type block and returns both as
+// separate type definitions, leaving later stages to pick one; it reports a
+// repeated relation, but only at the second site. Reporting both locations
+// here makes a copy-paste mistake easy to find.
+//
+// Only lines starting with `type` or `define` are read, so the scan does not
+// depend on the rest of the content being valid.
+func findDuplicateDefinitions(content string) ParseErrors {
+	var errs ParseErrors
+	types := make(map[string]definitionSite)
+	var currentType string
+	var relations map[string]definitionSite
+
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		indent := len(line) - len(trimmed)
+		keyword, rest, _ := strings.Cut(trimmed, " ")
+
+		switch keyword {
+		case "type":
+			name := strings.Fields(rest)
+			if len(name) == 0 {
+				continue
+			}
+			site := definitionSite{line: i + 1, column: indent + strings.Index(trimmed, name[0]) + 1}
+			currentType = name[0]
+			relations = make(map[string]definitionSite)
+			if first, ok := types[currentType]; ok {
+				errs = append(errs, ParseError{
+					Line:    site.line,
+					Column:  site.column,
+					Message: fmt.Sprintf("type '%s' is already defined at line %d", currentType, first.line),
+					Token:   currentType,
+				})
+				continue
+			}
+			types[currentType] = site
+
+		case "define":
+			if relations == nil {
+				continue
+			}
+			name, _, _ := strings.Cut(strings.TrimLeft(rest, " \t"), ":")
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			site := definitionSite{line: i + 1, column: indent + len(keyword) + 1 + strings.Index(rest, name) + 1}
+			if first, ok := relations[name]; ok {
+				errs = append(errs, ParseError{
+					Line:    site.line,
+					Column:  site.column,
+					Message: fmt.Sprintf("relation '%s' in type '%s' is already defined at line %d", name, currentType, first.line),
+					Token:   name,
+				})
+				continue
+			}
+			relations[name] = site
+		}
+	}
+	return errs
+}
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pthm/melange/melange"
+)
+
+// ParseError is a single syntax error in OpenFGA DSL source, positioned for
+// editor tooling. Line and Column are 1-based.
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+	// Token is the source text at the error position, or empty when the error
+	// is at the end of a line or of the input.
+	Token string
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ParseErrors is every syntax error reported for one schema, in source order.
+// ParseSchemaString returns it for syntax errors; it matches
+// melange.ErrInvalidSchema under errors.Is.
+type ParseErrors []ParseError
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, pe := range e {
+		msgs[i] = pe.Error()
+	}
+	return fmt.Sprintf("%v: %s", melange.ErrInvalidSchema, strings.Join(msgs, "; "))
+}
+
+// Is reports whether target is melange.ErrInvalidSchema, so callers that only
+// check the sentinel keep working.
+func (e ParseErrors) Is(target error) bool {
+	return target == melange.ErrInvalidSchema
+}
+
+// Format renders each error compiler-style, as name:line:column followed by
+// the source line and a caret under the error column.
+func (e ParseErrors) Format(name, source string) string {
+	lines := strings.Split(source, "\n")
+	var b strings.Builder
+	for _, pe := range e {
+		fmt.Fprintf(&b, "%s:%d:%d: %s\n", name, pe.Line, pe.Column, pe.Message)
+		if pe.Line < 1 || pe.Line > len(lines) {
+			continue
+		}
+		text := strings.ReplaceAll(lines[pe.Line-1], "\t", " ")
+		fmt.Fprintf(&b, "    %s\n    %s^\n", text, strings.Repeat(" ", pe.Column-1))
+	}
+	return b.String()
+}
+
+// syntaxErrorRe matches the message of the OpenFGA transformer's syntax
+// errors. The transformer keeps positions in unexported fields, so the message
+// is the only way to recover them. Its line is 0-based, its column 0-based.
+var syntaxErrorRe = regexp.MustCompile(`(?s)^syntax error at line=(\d+), column=(\d+): (.*)$`)
+
+// toParseErrors converts an error from transformer.TransformDSLToProto into
+// ParseErrors, reading offending tokens from content. It reports false when
+// any underlying error carries no position, so the caller can fall back to
+// the unstructured error rather than drop information.
+func toParseErrors(err error, content string) (ParseErrors, bool) {
+	errs := []error{err}
+	var multi interface{ WrappedErrors() []error }
+	if errors.As(err, &multi) {
+		errs = multi.WrappedErrors()
+	}
+
+	lines := strings.Split(content, "\n")
+	result := make(ParseErrors, 0, len(errs))
+	for _, e := range errs {
+		m := syntaxErrorRe.FindStringSubmatch(e.Error())
+		if m == nil {
+			return nil, false
+		}
+		line, _ := strconv.Atoi(m[1])
+		column, _ := strconv.Atoi(m[2])
+		result = append(result, ParseError{
+			Line:    line + 1,
+			Column:  column + 1,
+			Message: m[3],
+			Token:   tokenAt(lines, line, column),
+		})
+	}
+	return result, len(result) > 0
+}
+
+// tokenAt returns the token starting at the 0-based line and column: a run of
+// identifier characters, or the single character there otherwise.
+func tokenAt(lines []string, line, column int) string {
+	if line < 0 || line >= len(lines) || column < 0 || column >= len(lines[line]) {
+		return ""
+	}
+	text := lines[line][column:]
+	end := strings.IndexFunc(text, func(r rune) bool { return !isTokenRune(r) })
+	switch end {
+	case -1:
+		return text
+	case 0:
+		return text[:1]
+	}
+	return text[:end]
+}
+
+func isTokenRune(r rune) bool {
+	return r == '_' || r == '-' || r == '.' ||
+		(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pthm/melange/melange"
+)
+
+const badSchema = `model
+  schema 1.1
+
+type user
+
+type doc
+  relations
+    define viewer: [user]
+    define editor [user]`
+
+func TestParseSchemaString_ReturnsPositionedErrors(t *testing.T) {
+	_, err := ParseSchemaString(badSchema)
+
+	var parseErrs ParseErrors
+	if !errors.As(err, &parseErrs) {
+		t.Fatalf("expected ParseErrors, got %T: %v", err, err)
+	}
+	if !errors.Is(err, melange.ErrInvalidSchema) {
+		t.Error("ParseErrors must still match melange.ErrInvalidSchema")
+	}
+	if len(parseErrs) != 1 {
+		t.Fatalf("expected 1 error, got %v", parseErrs)
+	}
+
+	got := parseErrs[0]
+	if got.Line != 9 || got.Column != 19 || got.Token != "[" {
+		t.Errorf("got line %d, column %d, token %q; want 9, 19, %q", got.Line, got.Column, got.Token, "[")
+	}
+	if !strings.Contains(got.Message, "missing ':'") {
+		t.Errorf("unexpected message %q", got.Message)
+	}
+}
+
+func TestParseErrors_FormatPlacesCaret(t *testing.T) {
+	errs := ParseErrors{{Line: 9, Column: 19, Message: "missing ':' at '['", Token: "["}}
+
+	want := "schema.fga:9:19: missing ':' at '['\n" +
+		"        define editor [user]\n" +
+		"                      ^\n"
+	if got := errs.Format("schema.fga", badSchema); got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseSchema_DuplicateDefinitions(t *testing.T) {
+	_, err := ParseSchema("testdata/duplicate_definitions.fga")
+
+	var parseErrs ParseErrors
+	if !errors.As(err, &parseErrs) {
+		t.Fatalf("expected ParseErrors, got %T: %v", err, err)
+	}
+	if !errors.Is(err, melange.ErrInvalidSchema) {
+		t.Error("duplicate definitions must match melange.ErrInvalidSchema")
+	}
+
+	want := ParseErrors{
+		{Line: 10, Column: 12, Message: "relation 'viewer' in type 'doc' is already defined at line 9", Token: "viewer"},
+		{Line: 16, Column: 6, Message: "type 'doc' is already defined at line 6", Token: "doc"},
+	}
+	if len(parseErrs) != len(want) {
+		t.Fatalf("got %v, want %v", parseErrs, want)
+	}
+	for i := range want {
+		if parseErrs[i] != want[i] {
+			t.Errorf("error %d = %+v, want %+v", i, parseErrs[i], want[i])
+		}
+	}
+}
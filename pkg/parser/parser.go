@@ -13,6 +13,10 @@
 //	    log.Fatal(err)
 //	}
 //
+// Parse every .fga file in a directory into one model:
+//
+//	types, err := parser.ParseSchema("schemas")
+//
 // Parse schema from a string:
 //
 //	types, err := parser.ParseSchemaString(schemaContent)
@@ -31,7 +35,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/language/pkg/go/transformer"
@@ -46,15 +52,21 @@ func IsModularSchema(path string) bool {
 }
 
 // ParseSchema reads an OpenFGA schema and returns type definitions.
-// Accepts either a single .fga file or an fga.mod manifest for modular schemas.
+// Accepts a single .fga file, an fga.mod manifest for modular schemas, or a
+// directory of .fga files.
 //
 // For single .fga files, uses the existing single-file parser.
 // For fga.mod manifests, reads all referenced module files and merges them
 // into a unified model using the upstream OpenFGA library.
+// For directories, merges the type definitions of every .fga file in it
+// (see ParseSchemaDir).
 func ParseSchema(path string) ([]schema.TypeDefinition, error) {
 	if IsModularSchema(path) {
 		return ParseModularSchema(path)
 	}
+	if isDir(path) {
+		return ParseSchemaDir(path)
+	}
 
 	content, err := os.ReadFile(path) //nolint:gosec // path is from trusted source
 	if err != nil {
@@ -68,10 +80,15 @@ func ParseSchema(path string) ([]schema.TypeDefinition, error) {
 // For single .fga files, returns the file bytes directly.
 // For fga.mod manifests, returns the manifest plus all referenced module files
 // concatenated in manifest order (deterministic).
+// For directories, returns the .fga files concatenated in name order (see
+// ReadSchemaDirContents).
 func ReadSchemaContent(path string) ([]byte, error) {
 	if IsModularSchema(path) {
 		return ReadManifestContents(path)
 	}
+	if isDir(path) {
+		return ReadSchemaDirContents(path)
+	}
 	content, err := os.ReadFile(path) //nolint:gosec // path is from trusted source
 	if err != nil {
 		return nil, fmt.Errorf("reading schema file: %w", err)
@@ -103,9 +120,21 @@ func ParseModularSchema(manifestPath string) ([]schema.TypeDefinition, error) {
 // ParseSchemaString parses OpenFGA DSL content and returns type definitions.
 // This is the core parser used by both file-based and string-based parsing.
 // Wraps the OpenFGA transformer to convert protobuf models to our format.
+//
+// Syntax errors are returned as ParseErrors carrying line, column and the
+// offending token for each issue. A type defined twice, or a relation defined
+// twice within one type, is reported the same way, naming the line of the
+// first definition.
 func ParseSchemaString(content string) ([]schema.TypeDefinition, error) {
+	if dups := findDuplicateDefinitions(content); len(dups) > 0 {
+		return nil, dups
+	}
+
 	model, err := transformer.TransformDSLToProto(content)
 	if err != nil {
+		if parseErrs, ok := toParseErrors(err, content); ok {
+			return nil, parseErrs
+		}
 		return nil, fmt.Errorf("%w: %v", melange.ErrInvalidSchema, err)
 	}
 
@@ -134,6 +163,7 @@ func ConvertProtoModel(model *openfgav1.AuthorizationModel) []schema.TypeDefinit
 func convertModel(model *openfgav1.AuthorizationModel) []schema.TypeDefinition {
 	typeDefs := model.GetTypeDefinitions()
 	types := make([]schema.TypeDefinition, 0, len(typeDefs))
+	conditions := convertConditions(model.GetConditions())
 
 	for _, td := range typeDefs {
 		typeDef := schema.TypeDefinition{
@@ -165,6 +195,9 @@ func convertModel(model *openfgav1.AuthorizationModel) []schema.TypeDefinition {
 						// This is a userset reference like [group#member]
 						ref.Relation = v.Relation
 					}
+					if name := t.GetCondition(); name != "" {
+						ref.Condition = conditionRef(conditions, name)
+					}
 
 					directTypeRefs[relName] = append(directTypeRefs[relName], ref)
 				}
@@ -191,6 +224,49 @@ func convertModel(model *openfgav1.AuthorizationModel) []schema.TypeDefinition {
 	return types
 }
 
+// convertConditions converts the model's condition definitions, keyed by name.
+// Parameter types are spelled as in the DSL: "string", "timestamp",
+// "list<string>" and so on.
+func convertConditions(defs map[string]*openfgav1.Condition) map[string]*schema.Condition {
+	conditions := make(map[string]*schema.Condition, len(defs))
+	for name, def := range defs {
+		params := make(map[string]string, len(def.GetParameters()))
+		for param, ref := range def.GetParameters() {
+			params[param] = conditionParamType(ref)
+		}
+		conditions[name] = &schema.Condition{
+			Name:       name,
+			Expression: def.GetExpression(),
+			Parameters: params,
+		}
+	}
+	return conditions
+}
+
+// conditionRef returns the named condition. The OpenFGA parser rejects
+// references to undefined conditions, so the fallback only keeps the name of
+// a condition missing from a hand-built model.
+func conditionRef(conditions map[string]*schema.Condition, name string) *schema.Condition {
+	if c, ok := conditions[name]; ok {
+		return c
+	}
+	return &schema.Condition{Name: name}
+}
+
+// conditionParamType spells a condition parameter type as in the DSL.
+// Examples: TYPE_NAME_STRING -> "string", list of TYPE_NAME_STRING -> "list<string>"
+func conditionParamType(ref *openfgav1.ConditionParamTypeRef) string {
+	name := strings.ToLower(strings.TrimPrefix(ref.GetTypeName().String(), "TYPE_NAME_"))
+	if generics := ref.GetGenericTypes(); len(generics) > 0 {
+		args := make([]string, len(generics))
+		for i, g := range generics {
+			args[i] = conditionParamType(g)
+		}
+		name += "<" + strings.Join(args, ", ") + ">"
+	}
+	return name
+}
+
 // convertRelation converts a protobuf Userset to our RelationDefinition format.
 // The Userset describes who has this relation and how it's computed:
 //   - Direct assignment: explicitly granted via tuples
@@ -249,6 +325,20 @@ func extractUserset(us *openfgav1.Userset, rel *schema.RelationDefinition) {
 	case *openfgav1.Userset_Union:
 		// Union: permission granted if ANY child grants it
 		for _, child := range v.Union.GetChild() {
+			// A difference under a union subtracts from its own base only:
+			// "a or (b but not c)" must not exclude c from a. Extracting it
+			// like a top-level difference would apply the exclusion to the
+			// whole relation, as if it read "(a or b) but not c", so a
+			// shape the groups cannot carry is marked unsupported instead.
+			if diff := child.GetDifference(); diff != nil {
+				if groups, ok := scopedDifferenceGroups(diff, rel.Name); ok {
+					rel.IntersectionGroups = append(rel.IntersectionGroups, groups...)
+					continue
+				}
+				if rel.CannotGenerateReason == "" {
+					rel.CannotGenerateReason = "exclusion nested in a union has a base or subtract other than computed relations"
+				}
+			}
 			extractUserset(child, rel)
 		}
 
@@ -281,6 +371,64 @@ func extractUserset(us *openfgav1.Userset, rel *schema.RelationDefinition) {
 	}
 }
 
+// scopedDifferenceGroups expresses a difference nested in a union as
+// single-part intersection groups that each carry the exclusion, so it stays
+// scoped to its base. "a or (b but not c)" yields [[b but not c]] alongside
+// the implied relation a, and "a or ((b or d) but not c)" yields
+// [[b but not c], [d but not c]].
+//
+// An intersection base is scoped the same way, since "(a and b) but not c"
+// is "a and (b but not c)": "d or ((a and b) but not c)" yields
+// [[a, b but not c]]. The exclusion goes on the first computed relation of
+// each group other than the relation's own direct part.
+//
+// It reports false for shapes a part cannot carry: a base other than computed
+// relations or an intersection with a computed relation, or a subtract other
+// than a single relation (an intersection part holds one excluded relation).
+// The caller then sets the relation's CannotGenerateReason, since applying
+// the exclusion to the whole relation would deny access the model grants.
+func scopedDifferenceGroups(diff *openfgav1.Difference, relationName string) ([]schema.IntersectionGroup, bool) {
+	subtract := diff.GetSubtract().GetComputedUserset()
+	if subtract == nil {
+		return nil, false
+	}
+
+	var bases []string
+	switch bv := diff.GetBase().GetUserset().(type) {
+	case *openfgav1.Userset_ComputedUserset:
+		bases = append(bases, bv.ComputedUserset.GetRelation())
+	case *openfgav1.Userset_Union:
+		for _, child := range bv.Union.GetChild() {
+			computed := child.GetComputedUserset()
+			if computed == nil {
+				return nil, false
+			}
+			bases = append(bases, computed.GetRelation())
+		}
+	case *openfgav1.Userset_Intersection:
+		groups := expandIntersection(bv.Intersection, relationName)
+		for i := range groups {
+			idx := slices.IndexFunc(groups[i].Relations, func(r string) bool { return r != relationName })
+			if idx < 0 {
+				return nil, false
+			}
+			mergeExclusions(&groups[i], map[string][]string{groups[i].Relations[idx]: {subtract.GetRelation()}})
+		}
+		return groups, true
+	default:
+		return nil, false
+	}
+
+	groups := make([]schema.IntersectionGroup, 0, len(bases))
+	for _, base := range bases {
+		groups = append(groups, schema.IntersectionGroup{
+			Relations:  []string{base},
+			Exclusions: map[string][]string{base: {subtract.GetRelation()}},
+		})
+	}
+	return groups, true
+}
+
 // expandIntersection expands an intersection node into one or more groups.
 // Returns multiple IntersectionGroups when union-in-intersection requires
 // distributive expansion: A ∧ (B ∨ C) = (A ∧ B) ∨ (A ∧ C)
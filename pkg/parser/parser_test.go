@@ -406,6 +406,103 @@ type doc
 	}
 }
 
+func TestParseSchemaString_ExclusionScope(t *testing.T) {
+	// OpenFGA rejects "a or b but not c" without parentheses, so the
+	// parentheses decide what the exclusion applies to.
+	schemaStr := `model
+  schema 1.1
+
+type user
+
+type doc
+  relations
+    define a: [user]
+    define b: [user]
+    define c: [user]
+    define d: [user]
+    define scoped: a or (b but not c)
+    define whole: (a or b) but not c
+    define scoped_union: a or ((b or d) but not c)
+    define scoped_intersection: a or ((b and d) but not c)
+    define parent: [doc]
+    define scoped_parent: a or (b from parent but not c)`
+
+	types, err := ParseSchemaString(schemaStr)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+
+	// a or (b but not c): c only excludes b
+	scoped := findRelation(t, types, "doc", "scoped")
+	if len(scoped.ImpliedBy) != 1 || scoped.ImpliedBy[0] != "a" {
+		t.Errorf("scoped: expected ImpliedBy [a], got %v", scoped.ImpliedBy)
+	}
+	if len(scoped.ExcludedRelations) != 0 {
+		t.Errorf("scoped: expected no relation-wide exclusions, got %v", scoped.ExcludedRelations)
+	}
+	if len(scoped.IntersectionGroups) != 1 {
+		t.Fatalf("scoped: expected 1 intersection group, got %d", len(scoped.IntersectionGroups))
+	}
+	g := scoped.IntersectionGroups[0]
+	if len(g.Relations) != 1 || g.Relations[0] != "b" || len(g.Exclusions["b"]) != 1 || g.Exclusions["b"][0] != "c" {
+		t.Errorf("scoped: expected group [b but not c], got %+v", g)
+	}
+
+	// (a or b) but not c: c excludes both
+	whole := findRelation(t, types, "doc", "whole")
+	if len(whole.ImpliedBy) != 2 {
+		t.Errorf("whole: expected ImpliedBy [a b], got %v", whole.ImpliedBy)
+	}
+	if len(whole.ExcludedRelations) != 1 || whole.ExcludedRelations[0] != "c" {
+		t.Errorf("whole: expected relation-wide exclusion [c], got %v", whole.ExcludedRelations)
+	}
+	if len(whole.IntersectionGroups) != 0 {
+		t.Errorf("whole: expected no intersection groups, got %+v", whole.IntersectionGroups)
+	}
+
+	// a or ((b or d) but not c): one group per base relation
+	scopedUnion := findRelation(t, types, "doc", "scoped_union")
+	if len(scopedUnion.ExcludedRelations) != 0 {
+		t.Errorf("scoped_union: expected no relation-wide exclusions, got %v", scopedUnion.ExcludedRelations)
+	}
+	if len(scopedUnion.IntersectionGroups) != 2 {
+		t.Fatalf("scoped_union: expected 2 intersection groups, got %d", len(scopedUnion.IntersectionGroups))
+	}
+	for i, base := range []string{"b", "d"} {
+		g := scopedUnion.IntersectionGroups[i]
+		if len(g.Relations) != 1 || g.Relations[0] != base || len(g.Exclusions[base]) != 1 || g.Exclusions[base][0] != "c" {
+			t.Errorf("scoped_union: expected group [%s but not c], got %+v", base, g)
+		}
+	}
+
+	// a or ((b and d) but not c): c excludes the intersection, carried by its
+	// first part as b and (d but not c) would be
+	scopedIntersection := findRelation(t, types, "doc", "scoped_intersection")
+	if len(scopedIntersection.ExcludedRelations) != 0 {
+		t.Errorf("scoped_intersection: expected no relation-wide exclusions, got %v", scopedIntersection.ExcludedRelations)
+	}
+	if len(scopedIntersection.IntersectionGroups) != 1 {
+		t.Fatalf("scoped_intersection: expected 1 intersection group, got %d", len(scopedIntersection.IntersectionGroups))
+	}
+	g = scopedIntersection.IntersectionGroups[0]
+	if len(g.Relations) != 2 || g.Relations[0] != "b" || g.Relations[1] != "d" || len(g.Exclusions) != 1 || len(g.Exclusions["b"]) != 1 || g.Exclusions["b"][0] != "c" {
+		t.Errorf("scoped_intersection: expected group [b but not c, d], got %+v", g)
+	}
+	for _, name := range []string{"scoped", "whole", "scoped_union", "scoped_intersection"} {
+		if reason := findRelation(t, types, "doc", name).CannotGenerateReason; reason != "" {
+			t.Errorf("%s: expected no CannotGenerateReason, got %q", name, reason)
+		}
+	}
+
+	// a or (b from parent but not c): no group carries an exclusion on a
+	// tuple-to-userset, and excluding c from a would deny access the model
+	// grants, so the relation is marked unsupported
+	scopedParent := findRelation(t, types, "doc", "scoped_parent")
+	if scopedParent.CannotGenerateReason == "" {
+		t.Errorf("scoped_parent: expected a CannotGenerateReason, got %+v", scopedParent)
+	}
+}
+
 func TestParseSchemaString_ErrorOnInvalid(t *testing.T) {
 	_, err := ParseSchemaString("not a valid schema")
 	if err == nil {
@@ -486,6 +583,46 @@ type doc
 	}
 }
 
+func TestParseSchemaString_ConditionalSubject(t *testing.T) {
+	schemaStr := `model
+  schema 1.1
+
+type user
+
+type doc
+  relations
+    define viewer: [user, user with in_region]
+
+condition in_region(region: string, allowed: list<string>) {
+  region in allowed
+}`
+
+	types, err := ParseSchemaString(schemaStr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rel := findRelation(t, types, "doc", "viewer")
+	if len(rel.SubjectTypeRefs) != 2 {
+		t.Fatalf("expected 2 subject type refs, got %v", rel.SubjectTypeRefs)
+	}
+	var cond *schema.Condition
+	for _, ref := range rel.SubjectTypeRefs {
+		if ref.Condition != nil {
+			cond = ref.Condition
+		}
+	}
+	if cond == nil {
+		t.Fatal("expected a conditional subject type ref for [user with in_region]")
+	}
+	if cond.Name != "in_region" || cond.Expression != "region in allowed" {
+		t.Errorf("condition = %q %q, want in_region with its expression", cond.Name, cond.Expression)
+	}
+	if cond.Parameters["region"] != "string" || cond.Parameters["allowed"] != "list<string>" {
+		t.Errorf("condition parameters = %v", cond.Parameters)
+	}
+}
+
 // findRelation is a test helper that locates a relation by type and name.
 func findRelation(t *testing.T, types []schema.TypeDefinition, typeName, relName string) schema.RelationDefinition {
 	t.Helper()
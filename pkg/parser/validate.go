@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/pthm/melange/melange"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+// ReferenceError is a reference to a type or relation the schema does not
+// define. ObjectType and Relation locate the relation definition that makes
+// the reference; Reference is the reference as written, such as "editor",
+// "group#member" or "viewer from parent".
+type ReferenceError struct {
+	ObjectType string
+	Relation   string
+	Reference  string
+	Message    string
+}
+
+func (e ReferenceError) Error() string {
+	return fmt.Sprintf("%s.%s: %s", e.ObjectType, e.Relation, e.Message)
+}
+
+// ReferenceErrors is every dangling reference Validate found, in the order of
+// the types and relations making them. It matches melange.ErrInvalidSchema
+// under errors.Is.
+type ReferenceErrors []ReferenceError
+
+func (e ReferenceErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, re := range e {
+		msgs[i] = re.Error()
+	}
+	return fmt.Sprintf("%v: %s", melange.ErrInvalidSchema, strings.Join(msgs, "; "))
+}
+
+// Is reports whether target is melange.ErrInvalidSchema, so callers that only
+// check the sentinel keep working.
+func (e ReferenceErrors) Is(target error) bool {
+	return target == melange.ErrInvalidSchema
+}
+
+// Format renders each error on its own line, prefixed with name.
+func (e ReferenceErrors) Format(name string) string {
+	var b strings.Builder
+	for _, re := range e {
+		fmt.Fprintf(&b, "%s: %s\n", name, re.Error())
+	}
+	return b.String()
+}
+
+// Validate checks that every type and relation a relation definition
+// references is defined:
+//   - type restrictions ([user], [group#member], [user:*]) name a defined
+//     type, and a userset restriction a relation defined on it;
+//   - computed relations (editor), exclusions (but not blocked) and the
+//     relations of intersections are defined on the same type;
+//   - the linking relation of a tuple-to-userset (viewer from parent) is
+//     defined on the same type.
+//
+// The OpenFGA transformer accepts all of these, and a dangling reference
+// later reads as a relation nobody has. Validate returns ReferenceErrors
+// listing each one, or nil. Whether a tuple-to-userset resolves on the
+// types it links to is checked by schema.ValidateTupleToUsersets.
+func Validate(types []schema.TypeDefinition) error {
+	relations := make(map[string]map[string]bool, len(types))
+	for _, t := range types {
+		rels := make(map[string]bool, len(t.Relations))
+		for _, r := range t.Relations {
+			rels[r.Name] = true
+		}
+		relations[t.Name] = rels
+	}
+
+	var errs ReferenceErrors
+	for _, t := range types {
+		for _, r := range t.Relations {
+			errs = append(errs, validateReferences(t.Name, r, relations)...)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateReferences returns the dangling references of one relation, each
+// reported once.
+func validateReferences(objectType string, r schema.RelationDefinition, relations map[string]map[string]bool) ReferenceErrors {
+	var errs ReferenceErrors
+	seen := make(map[string]bool)
+	report := func(reference, format string, args ...any) {
+		if seen[reference] {
+			return
+		}
+		seen[reference] = true
+		errs = append(errs, ReferenceError{
+			ObjectType: objectType,
+			Relation:   r.Name,
+			Reference:  reference,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+	relation := func(name string) {
+		if !relations[objectType][name] {
+			report(name, "relation '%s' is not defined on type '%s'", name, objectType)
+		}
+	}
+	ttu := func(p schema.ParentRelationCheck) {
+		if !relations[objectType][p.LinkingRelation] {
+			report(p.Relation+" from "+p.LinkingRelation, "linking relation '%s' of '%s from %s' is not defined on type '%s'",
+				p.LinkingRelation, p.Relation, p.LinkingRelation, objectType)
+		}
+	}
+	group := func(g schema.IntersectionGroup) {
+		for _, name := range g.Relations {
+			relation(name)
+		}
+		for _, p := range g.ParentRelations {
+			ttu(p)
+		}
+		for _, base := range slices.Sorted(maps.Keys(g.Exclusions)) {
+			for _, name := range g.Exclusions[base] {
+				relation(name)
+			}
+		}
+	}
+
+	for _, ref := range r.SubjectTypeRefs {
+		written := ref.Type
+		switch {
+		case ref.Wildcard:
+			written += ":*"
+		case ref.Relation != "":
+			written += "#" + ref.Relation
+		}
+		rels, ok := relations[ref.Type]
+		switch {
+		case !ok:
+			report(written, "type '%s' in '[%s]' is not defined", ref.Type, written)
+		case ref.Relation != "" && !rels[ref.Relation]:
+			report(written, "relation '%s' in '[%s]' is not defined on type '%s'", ref.Relation, written, ref.Type)
+		}
+	}
+	for _, name := range r.ImpliedBy {
+		relation(name)
+	}
+	for _, p := range r.ParentRelations {
+		ttu(p)
+	}
+	for _, name := range r.ExcludedRelations {
+		relation(name)
+	}
+	for _, p := range r.ExcludedParentRelations {
+		ttu(p)
+	}
+	for _, g := range r.IntersectionGroups {
+		group(g)
+	}
+	for _, g := range r.ExcludedIntersectionGroups {
+		group(g)
+	}
+	return errs
+}
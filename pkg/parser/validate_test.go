@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pthm/melange/melange"
+)
+
+func TestValidate_ReportsDanglingReferences(t *testing.T) {
+	types, err := ParseSchemaString(`model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type doc
+  relations
+    define parent: [doc]
+    define owner: [user, team, group#admin, robot:*]
+    define viewer: owner or editor or viewer from folder
+    define can_edit: (owner and writer) but not blocked
+    define can_share: owner but not (viewer from folder)`)
+	if err != nil {
+		t.Fatalf("ParseSchemaString: %v", err)
+	}
+
+	err = Validate(types)
+	var refErrs ReferenceErrors
+	if !errors.As(err, &refErrs) {
+		t.Fatalf("expected ReferenceErrors, got %T: %v", err, err)
+	}
+	if !errors.Is(err, melange.ErrInvalidSchema) {
+		t.Error("ReferenceErrors must match melange.ErrInvalidSchema")
+	}
+
+	// Parsed relations are sorted by name.
+	want := ReferenceErrors{
+		{"doc", "can_edit", "blocked", "relation 'blocked' is not defined on type 'doc'"},
+		{"doc", "can_edit", "writer", "relation 'writer' is not defined on type 'doc'"},
+		{"doc", "can_share", "viewer from folder", "linking relation 'folder' of 'viewer from folder' is not defined on type 'doc'"},
+		{"doc", "owner", "team", "type 'team' in '[team]' is not defined"},
+		{"doc", "owner", "group#admin", "relation 'admin' in '[group#admin]' is not defined on type 'group'"},
+		{"doc", "owner", "robot:*", "type 'robot' in '[robot:*]' is not defined"},
+		{"doc", "viewer", "editor", "relation 'editor' is not defined on type 'doc'"},
+		{"doc", "viewer", "viewer from folder", "linking relation 'folder' of 'viewer from folder' is not defined on type 'doc'"},
+	}
+	if !reflect.DeepEqual(refErrs, want) {
+		t.Errorf("Validate() =\n%v\nwant\n%v", refErrs, want)
+	}
+
+	if got, want := refErrs[:1].Format("schema.fga"), "schema.fga: doc.can_edit: relation 'blocked' is not defined on type 'doc'\n"; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+}
+
+func TestValidate_ValidSchema(t *testing.T) {
+	types, err := ParseSchemaString(`model
+  schema 1.1
+
+type user
+
+type folder
+  relations
+    define viewer: [user]
+
+type doc
+  relations
+    define parent: [folder]
+    define owner: [user, user:*]
+    define blocked: [user]
+    define editor: [user] and owner
+    define viewer: (owner or editor or viewer from parent) but not blocked`)
+	if err != nil {
+		t.Fatalf("ParseSchemaString: %v", err)
+	}
+	if err := Validate(types); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+}
@@ -57,27 +57,132 @@ func ComputeRelationClosure(types []TypeDefinition) []ClosureRow {
 		// For each relation, compute transitive closure via BFS
 		for _, r := range t.Relations {
 			satisfying := computeTransitiveSatisfiers(r.Name, impliedBy)
+			rows = appendClosureRows(rows, t.Name, r.Name, satisfying)
+		}
+	}
+
+	return rows
+}
+
+// RelationRef identifies a single relation on an object type.
+type RelationRef struct {
+	ObjectType string
+	Relation   string
+}
+
+// ComputeRelationClosureIncremental recomputes closure rows only for relations
+// affected by a change, reusing prev for everything else. The result is
+// identical (rows and order) to ComputeRelationClosure(types) provided prev was
+// computed from a schema that differs from types only in the changed relations.
+//
+// A relation is affected when it is listed in changed, or when its closure
+// reaches a changed relation through implied-by edges in either the previous
+// closure (prev) or the current schema (types). The previous closure matters
+// when a change removes an edge: dependents that used to reach the changed
+// relation must be recomputed even though the new graph no longer links them.
+//
+// Relations absent from prev are always computed fresh, and rows for types or
+// relations no longer present in types are dropped.
+func ComputeRelationClosureIncremental(prev []ClosureRow, changed []RelationRef, types []TypeDefinition) []ClosureRow {
+	changedByType := make(map[string]map[string]bool)
+	for _, ref := range changed {
+		if changedByType[ref.ObjectType] == nil {
+			changedByType[ref.ObjectType] = make(map[string]bool)
+		}
+		changedByType[ref.ObjectType][ref.Relation] = true
+	}
+
+	// prevByRelation groups previous rows per (type, relation), preserving
+	// their order so reused relations emit exactly what a full run would.
+	// prevDependents collects relations whose previous closure included a
+	// changed relation.
+	prevByRelation := make(map[RelationRef][]ClosureRow)
+	prevDependents := make(map[RelationRef]bool)
+	for _, row := range prev {
+		key := RelationRef{ObjectType: row.ObjectType, Relation: row.Relation}
+		prevByRelation[key] = append(prevByRelation[key], row)
+		if changedByType[row.ObjectType][row.SatisfyingRelation] {
+			prevDependents[key] = true
+		}
+	}
 
-			// Sort map keys for deterministic output order.
-			// This ensures consistent ordering of SatisfyingRelations in
-			// downstream processing (complexity analysis, code generation).
-			satisfyingRels := make([]string, 0, len(satisfying))
-			for rel := range satisfying {
-				satisfyingRels = append(satisfyingRels, rel)
+	rows := make([]ClosureRow, 0, len(prev))
+	for _, t := range types {
+		impliedBy := make(map[string][]string)
+		for _, r := range t.Relations {
+			impliedBy[r.Name] = append(impliedBy[r.Name], r.ImpliedBy...)
+		}
+		affected := closureDependents(changedByType[t.Name], t.Relations)
+
+		for _, r := range t.Relations {
+			key := RelationRef{ObjectType: t.Name, Relation: r.Name}
+			prevRows, hadPrev := prevByRelation[key]
+			if hadPrev && !affected[r.Name] && !prevDependents[key] {
+				rows = append(rows, prevRows...)
+				continue
 			}
-			sort.Strings(satisfyingRels)
-
-			for _, rel := range satisfyingRels {
-				rows = append(rows, ClosureRow{
-					ObjectType:         t.Name,
-					Relation:           r.Name,
-					SatisfyingRelation: rel,
-					ViaPath:            satisfying[rel],
-				})
+			satisfying := computeTransitiveSatisfiers(r.Name, impliedBy)
+			rows = appendClosureRows(rows, t.Name, r.Name, satisfying)
+		}
+	}
+
+	return rows
+}
+
+// closureDependents returns the changed relations plus every relation whose
+// closure transitively includes one of them in the current schema. It walks
+// implied-by edges in reverse: if B is in A's ImpliedBy, a change to B
+// affects A.
+func closureDependents(changed map[string]bool, relations []RelationDefinition) map[string]bool {
+	if len(changed) == 0 {
+		return nil
+	}
+	implies := make(map[string][]string)
+	for _, r := range relations {
+		for _, by := range r.ImpliedBy {
+			implies[by] = append(implies[by], r.Name)
+		}
+	}
+
+	affected := make(map[string]bool, len(changed))
+	queue := make([]string, 0, len(changed))
+	for rel := range changed {
+		affected[rel] = true
+		queue = append(queue, rel)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range implies[current] {
+			if affected[dependent] {
+				continue
 			}
+			affected[dependent] = true
+			queue = append(queue, dependent)
 		}
 	}
+	return affected
+}
 
+// appendClosureRows appends one row per satisfying relation. Satisfiers are
+// sorted for deterministic output order, which keeps SatisfyingRelations
+// ordering consistent in downstream processing (complexity analysis, code
+// generation).
+func appendClosureRows(rows []ClosureRow, objectType, relation string, satisfying map[string][]string) []ClosureRow {
+	satisfyingRels := make([]string, 0, len(satisfying))
+	for rel := range satisfying {
+		satisfyingRels = append(satisfyingRels, rel)
+	}
+	sort.Strings(satisfyingRels)
+
+	for _, rel := range satisfyingRels {
+		rows = append(rows, ClosureRow{
+			ObjectType:         objectType,
+			Relation:           relation,
+			SatisfyingRelation: rel,
+			ViaPath:            satisfying[rel],
+		})
+	}
 	return rows
 }
 
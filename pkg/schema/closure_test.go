@@ -1,6 +1,7 @@
 package schema_test
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/pthm/melange/pkg/schema"
@@ -304,3 +305,92 @@ func hasClosureRow(rows []schema.ClosureRow, objectType, relation, satisfying st
 	}
 	return false
 }
+
+func TestComputeRelationClosureIncremental_MatchesFullRecompute(t *testing.T) {
+	// repo: owner -> admin -> member; viewer implied by member.
+	before := []schema.TypeDefinition{
+		{
+			Name: "repo",
+			Relations: []schema.RelationDefinition{
+				{Name: "owner", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}},
+				{Name: "admin", ImpliedBy: []string{"owner"}},
+				{Name: "member", ImpliedBy: []string{"admin"}},
+				{Name: "viewer", ImpliedBy: []string{"member"}},
+				{Name: "auditor", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}},
+			},
+		},
+		{
+			Name: "org",
+			Relations: []schema.RelationDefinition{
+				{Name: "admin", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}},
+				{Name: "member", ImpliedBy: []string{"admin"}},
+			},
+		},
+	}
+	prev := schema.ComputeRelationClosure(before)
+
+	tests := []struct {
+		name    string
+		mutate  func(types []schema.TypeDefinition) []schema.TypeDefinition
+		changed []schema.RelationRef
+	}{
+		{
+			name: "edge added propagates to dependents",
+			mutate: func(types []schema.TypeDefinition) []schema.TypeDefinition {
+				types[0].Relations[1].ImpliedBy = []string{"owner", "auditor"}
+				return types
+			},
+			changed: []schema.RelationRef{{ObjectType: "repo", Relation: "admin"}},
+		},
+		{
+			name: "edge removed recomputes former dependents",
+			mutate: func(types []schema.TypeDefinition) []schema.TypeDefinition {
+				types[0].Relations[2].ImpliedBy = nil
+				return types
+			},
+			changed: []schema.RelationRef{{ObjectType: "repo", Relation: "member"}},
+		},
+		{
+			name: "new relation",
+			mutate: func(types []schema.TypeDefinition) []schema.TypeDefinition {
+				types[1].Relations = append(types[1].Relations, schema.RelationDefinition{Name: "guest", ImpliedBy: []string{"member"}})
+				return types
+			},
+			changed: []schema.RelationRef{{ObjectType: "org", Relation: "guest"}},
+		},
+		{
+			name: "relation removed",
+			mutate: func(types []schema.TypeDefinition) []schema.TypeDefinition {
+				types[0].Relations = types[0].Relations[:4]
+				return types
+			},
+			changed: []schema.RelationRef{{ObjectType: "repo", Relation: "auditor"}},
+		},
+		{
+			name:   "no changes reuses previous rows",
+			mutate: func(types []schema.TypeDefinition) []schema.TypeDefinition { return types },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := tt.mutate(cloneTypes(before))
+			want := schema.ComputeRelationClosure(after)
+			got := schema.ComputeRelationClosureIncremental(prev, tt.changed, after)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("incremental closure differs from full recompute\ngot:  %+v\nwant: %+v", got, want)
+			}
+		})
+	}
+}
+
+// cloneTypes deep-copies the relation slices the incremental tests mutate.
+func cloneTypes(types []schema.TypeDefinition) []schema.TypeDefinition {
+	out := make([]schema.TypeDefinition, len(types))
+	for i, td := range types {
+		out[i] = td
+		out[i].Relations = make([]schema.RelationDefinition, len(td.Relations))
+		copy(out[i].Relations, td.Relations)
+	}
+	return out
+}
@@ -9,3 +9,13 @@ var ErrCyclicSchema = errors.New("melange/schema: cyclic schema")
 func IsCyclicSchemaErr(err error) bool {
 	return errors.Is(err, ErrCyclicSchema)
 }
+
+// ErrInvalidTupleToUserset is returned when a tuple-to-userset ("viewer from
+// parent") cannot resolve: its linking relation is undefined on the type, cannot
+// hold direct object links, or points at types that do not define the relation.
+var ErrInvalidTupleToUserset = errors.New("melange/schema: invalid tuple-to-userset")
+
+// IsInvalidTupleToUsersetErr returns true if err is or wraps ErrInvalidTupleToUserset.
+func IsInvalidTupleToUsersetErr(err error) bool {
+	return errors.Is(err, ErrInvalidTupleToUserset)
+}
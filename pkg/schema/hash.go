@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SchemaHashVersion identifies the encoding SchemaHash digests. It is part of
+// the hashed content, so changing the encoding changes every hash exactly
+// once, and is bumped only when a change to the model would otherwise go
+// unnoticed.
+const SchemaHashVersion = 1
+
+// SchemaHash returns a SHA256 hex digest of the semantic model in types.
+//
+// The digest covers only what the parser extracts, so whitespace, comments
+// and other formatting in the source never change it; two schemas hash alike
+// exactly when they produce the same TypeDefinitions. Order is kept as
+// parsed: reordering types, relations or the operands of a rule changes the
+// hash, even though it may not change what the model allows.
+//
+// The hash is stable across melange versions for a given SchemaHashVersion.
+// Fields left empty are not encoded, so a field added to RelationDefinition
+// later does not change the hash of schemas that do not use it.
+func SchemaHash(types []TypeDefinition) string {
+	var b strings.Builder
+	b.WriteString("melange-schema-hash/v" + strconv.Itoa(SchemaHashVersion) + "\n")
+	for _, t := range types {
+		writeHashLine(&b, "type", t.Name)
+		for _, r := range t.Relations {
+			writeHashLine(&b, "relation", r.Name)
+			writeHashLine(&b, " subjects", subjectRefStrings(r.SubjectTypeRefs)...)
+			writeHashLine(&b, " conditions", conditionStrings(r.SubjectTypeRefs)...)
+			writeHashLine(&b, " implied", r.ImpliedBy...)
+			writeHashLine(&b, " parents", parentCheckStrings(r.ParentRelations)...)
+			writeHashLine(&b, " excluded", r.ExcludedRelations...)
+			writeHashLine(&b, " excluded_parents", parentCheckStrings(r.ExcludedParentRelations)...)
+			writeHashGroups(&b, " excluded_group", r.ExcludedIntersectionGroups)
+			writeHashGroups(&b, " group", r.IntersectionGroups)
+			if r.CannotGenerateReason != "" {
+				writeHashLine(&b, " unsupported", r.CannotGenerateReason)
+			}
+		}
+	}
+	h := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(h[:])
+}
+
+// writeHashGroups encodes each intersection group as a header line followed
+// by its members, with exclusions in relation order.
+func writeHashGroups(b *strings.Builder, key string, groups []IntersectionGroup) {
+	for _, g := range groups {
+		b.WriteString(key + "\n")
+		writeHashLine(b, "  relations", g.Relations...)
+		writeHashLine(b, "  parents", parentCheckStrings(g.ParentRelations)...)
+		for _, rel := range slices.Sorted(maps.Keys(g.Exclusions)) {
+			writeHashLine(b, "  exclusions", append([]string{rel}, g.Exclusions[rel]...)...)
+		}
+	}
+}
+
+// writeHashLine writes key followed by each value quoted, so no value can be
+// mistaken for a separator. Lines with no values are omitted, keeping empty
+// and nil lists (and fields that did not exist yet) equivalent.
+func writeHashLine(b *strings.Builder, key string, values ...string) {
+	if len(values) == 0 {
+		return
+	}
+	b.WriteString(key)
+	for _, v := range values {
+		b.WriteByte(' ')
+		b.WriteString(strconv.Quote(v))
+	}
+	b.WriteByte('\n')
+}
+
+func subjectRefStrings(refs []SubjectTypeRef) []string {
+	out := make([]string, len(refs))
+	for i, ref := range refs {
+		s := ref.Type
+		if ref.Relation != "" {
+			s += "#" + ref.Relation
+		}
+		if ref.Wildcard {
+			s += ":*"
+		}
+		if ref.Condition != nil {
+			s += " with " + ref.Condition.Name
+		}
+		out[i] = s
+	}
+	return out
+}
+
+// conditionStrings encodes the definition of each condition the refs use, so
+// editing a condition's expression or parameters changes the hash.
+func conditionStrings(refs []SubjectTypeRef) []string {
+	var out []string
+	for _, ref := range refs {
+		c := ref.Condition
+		if c == nil {
+			continue
+		}
+		s := c.Name + ": " + c.Expression
+		for _, name := range slices.Sorted(maps.Keys(c.Parameters)) {
+			s += "; " + name + " " + c.Parameters[name]
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func parentCheckStrings(checks []ParentRelationCheck) []string {
+	out := make([]string, len(checks))
+	for i, c := range checks {
+		out[i] = c.Relation + " from " + c.LinkingRelation
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/pkg/schema"
+)
+
+func mustParse(t *testing.T, content string) []schema.TypeDefinition {
+	t.Helper()
+	types, err := parser.ParseSchemaString(content)
+	if err != nil {
+		t.Fatalf("parsing schema: %v", err)
+	}
+	return types
+}
+
+func TestSchemaHash_IgnoresFormatting(t *testing.T) {
+	compact := `model
+  schema 1.1
+type user
+type group
+  relations
+    define member: [user]
+type doc
+  relations
+    define owner: [user]
+    define viewer: ([user, group#member, user:*] or owner) but not blocked
+    define blocked: [user]
+`
+	reformatted := `# Documents and who can see them.
+model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]   # direct members only
+
+type doc
+  relations
+    define owner: [user]
+    define viewer: ([user, group#member, user:*] or owner) but not blocked  
+
+    define blocked: [user]
+`
+	if a, b := schema.SchemaHash(mustParse(t, compact)), schema.SchemaHash(mustParse(t, reformatted)); a != b {
+		t.Errorf("reformatted schema hashes differ: %s != %s", a, b)
+	}
+}
+
+func TestSchemaHash_DetectsSemanticChanges(t *testing.T) {
+	base := `model
+  schema 1.1
+type user
+type folder
+  relations
+    define viewer: [user]
+type doc
+  relations
+    define parent: [folder]
+    define editor: [user]
+    define viewer: [user] or editor or viewer from parent
+`
+	changes := map[string]string{
+		"wildcard": `model
+  schema 1.1
+type user
+type folder
+  relations
+    define viewer: [user]
+type doc
+  relations
+    define parent: [folder]
+    define editor: [user]
+    define viewer: [user, user:*] or editor or viewer from parent
+`,
+		"intersection": `model
+  schema 1.1
+type user
+type folder
+  relations
+    define viewer: [user]
+type doc
+  relations
+    define parent: [folder]
+    define editor: [user]
+    define viewer: [user] and editor
+`,
+		"exclusion": `model
+  schema 1.1
+type user
+type folder
+  relations
+    define viewer: [user]
+type doc
+  relations
+    define parent: [folder]
+    define editor: [user]
+    define viewer: ([user] or viewer from parent) but not editor
+`,
+		"condition": `model
+  schema 1.1
+type user
+type folder
+  relations
+    define viewer: [user]
+type doc
+  relations
+    define parent: [folder]
+    define editor: [user with in_region]
+    define viewer: [user] or editor or viewer from parent
+condition in_region(region: string) {
+  region == "eu"
+}
+`,
+		"renamed type": `model
+  schema 1.1
+type user
+type directory
+  relations
+    define viewer: [user]
+type doc
+  relations
+    define parent: [directory]
+    define editor: [user]
+    define viewer: [user] or editor or viewer from parent
+`,
+	}
+
+	baseHash := schema.SchemaHash(mustParse(t, base))
+	for name, content := range changes {
+		t.Run(name, func(t *testing.T) {
+			if schema.SchemaHash(mustParse(t, content)) == baseHash {
+				t.Error("semantic change must change the hash")
+			}
+		})
+	}
+}
+
+// The hash is recorded in melange_migrations, so it must not drift between
+// releases. Update the expected value only together with SchemaHashVersion.
+func TestSchemaHash_Stable(t *testing.T) {
+	types := []schema.TypeDefinition{
+		{Name: "user"},
+		{Name: "doc", Relations: []schema.RelationDefinition{
+			{Name: "owner", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}},
+			{Name: "viewer", ImpliedBy: []string{"owner"}, SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user", Wildcard: true}}},
+		}},
+	}
+	const want = "9e9c04803ee5305afeaf543e5fc66fc4700b7e3ff40f9f047d36f8132b5c614e"
+	if got := schema.SchemaHash(types); got != want {
+		t.Errorf("SchemaHash = %s, want %s", got, want)
+	}
+
+	// Nil and empty lists are the same model.
+	types[0].Relations = []schema.RelationDefinition{}
+	types[1].Relations[0].ImpliedBy = []string{}
+	if got := schema.SchemaHash(types); got != want {
+		t.Errorf("empty lists changed the hash: %s", got)
+	}
+}
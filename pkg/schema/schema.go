@@ -89,9 +89,20 @@ type TypeDefinition struct {
 // For userset references like [group#member], Type is "group" and Relation is "member".
 // For direct references like [user], Type is "user" and Relation is empty.
 type SubjectTypeRef struct {
-	Type     string // Subject type: "user", "group", etc.
-	Relation string // For userset refs: the relation (e.g., "member" in [group#member])
-	Wildcard bool   // True if this is a wildcard reference (user:*)
+	Type      string     // Subject type: "user", "group", etc.
+	Relation  string     // For userset refs: the relation (e.g., "member" in [group#member])
+	Wildcard  bool       // True if this is a wildcard reference (user:*)
+	Condition *Condition // For conditional refs: the condition (e.g., [user with non_expired])
+}
+
+// Condition is an OpenFGA condition attached to a type restriction, as in
+// [user with non_expired]. Expression is the CEL source; Parameters maps each
+// parameter name to its type as written in the DSL ("string", "int",
+// "timestamp", "list<string>", ...).
+type Condition struct {
+	Name       string
+	Expression string
+	Parameters map[string]string
 }
 
 // IntersectionGroup represents a group of relations that must ALL be satisfied.
@@ -148,6 +159,12 @@ type RelationDefinition struct {
 	// For "viewer: writer and editor", IntersectionGroups = [["writer", "editor"]]
 	// For "viewer: (a and b) or (c and d)", IntersectionGroups = [["a","b"], ["c","d"]]
 	IntersectionGroups []IntersectionGroup
+	// CannotGenerateReason explains why the relation's rewrite has no faithful
+	// representation in the fields above, if it has none. For
+	// "viewer: owner or (viewer from parent but not blocked)" the exclusion
+	// could only be recorded against the whole relation, so melange generates
+	// no functions for it and check_permission denies it.
+	CannotGenerateReason string
 }
 
 // RuleGroupMode constants define how rules within a group are combined.
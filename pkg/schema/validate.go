@@ -1,7 +1,9 @@
 package schema
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -251,3 +253,121 @@ func formatCycle(cycle []relationNode) string {
 	}
 	return strings.Join(parts, " → ")
 }
+
+// ValidateTupleToUsersets checks every tuple-to-userset ("viewer from parent")
+// in the schema, including those inside intersections and exclusions. For each
+// it requires that:
+//   - the linking relation (parent) is defined on the object type;
+//   - the linking relation allows direct object types only, since a userset or
+//     wildcard restriction can never name a single parent object. A computed
+//     linking relation ("define parent: container") is checked through every
+//     relation it is implied by;
+//   - at least one of those types defines the relation checked on the parent.
+//
+// The OpenFGA transformer accepts all of these, and the generated SQL would
+// silently join on tuples that can never exist, so a misspelled linking
+// relation reads as "no access" rather than an error. Every problem found is
+// reported, each wrapping ErrInvalidTupleToUserset.
+func ValidateTupleToUsersets(types []TypeDefinition) error {
+	relations := make(map[string]map[string]*RelationDefinition, len(types))
+	for i := range types {
+		rels := make(map[string]*RelationDefinition, len(types[i].Relations))
+		for j := range types[i].Relations {
+			rels[types[i].Relations[j].Name] = &types[i].Relations[j]
+		}
+		relations[types[i].Name] = rels
+	}
+
+	var errs []error
+	for _, t := range types {
+		for _, r := range t.Relations {
+			for _, ttu := range tupleToUsersets(r) {
+				if err := validateTupleToUserset(t.Name, r.Name, ttu, relations); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tupleToUsersets returns the distinct TTUs a relation references anywhere in
+// its rewrite, in definition order.
+func tupleToUsersets(r RelationDefinition) []ParentRelationCheck {
+	all := append([]ParentRelationCheck{}, r.ParentRelations...)
+	all = append(all, r.ExcludedParentRelations...)
+	for _, g := range r.IntersectionGroups {
+		all = append(all, g.ParentRelations...)
+	}
+	for _, g := range r.ExcludedIntersectionGroups {
+		all = append(all, g.ParentRelations...)
+	}
+
+	seen := make(map[ParentRelationCheck]bool, len(all))
+	result := all[:0]
+	for _, ttu := range all {
+		if !seen[ttu] {
+			seen[ttu] = true
+			result = append(result, ttu)
+		}
+	}
+	return result
+}
+
+func validateTupleToUserset(objectType, relation string, ttu ParentRelationCheck, relations map[string]map[string]*RelationDefinition) error {
+	where := fmt.Sprintf("%s.%s: %q", objectType, relation, ttu.Relation+" from "+ttu.LinkingRelation)
+
+	link, ok := relations[objectType][ttu.LinkingRelation]
+	if !ok {
+		return fmt.Errorf("%w: %s: relation %q is not defined on type %q",
+			ErrInvalidTupleToUserset, where, ttu.LinkingRelation, objectType)
+	}
+
+	// A computed linking relation ("define parent: container") links through
+	// every relation it is implied by, so gather their type restrictions.
+	var linked []string
+	seen := map[string]bool{ttu.LinkingRelation: true}
+	queue := []*RelationDefinition{link}
+	for len(queue) > 0 {
+		rel := queue[0]
+		queue = queue[1:]
+		for _, ref := range rel.SubjectTypeRefs {
+			if ref.Relation != "" || ref.Wildcard {
+				return fmt.Errorf("%w: %s: linking relation %q must allow direct object types only, not %s",
+					ErrInvalidTupleToUserset, where, rel.Name, formatSubjectTypeRef(ref))
+			}
+			if !slices.Contains(linked, ref.Type) {
+				linked = append(linked, ref.Type)
+			}
+		}
+		for _, by := range rel.ImpliedBy {
+			if next, ok := relations[objectType][by]; ok && !seen[by] {
+				seen[by] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	if len(linked) == 0 {
+		return fmt.Errorf("%w: %s: linking relation %q has no direct type restrictions, so no tuple can link a parent object",
+			ErrInvalidTupleToUserset, where, ttu.LinkingRelation)
+	}
+
+	for _, parentType := range linked {
+		if _, ok := relations[parentType][ttu.Relation]; ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s: none of the types %q links to (%s) defines relation %q",
+		ErrInvalidTupleToUserset, where, ttu.LinkingRelation, strings.Join(linked, ", "), ttu.Relation)
+}
+
+// formatSubjectTypeRef renders a type restriction as written in the DSL.
+func formatSubjectTypeRef(ref SubjectTypeRef) string {
+	switch {
+	case ref.Wildcard:
+		return ref.Type + ":*"
+	case ref.Relation != "":
+		return ref.Type + "#" + ref.Relation
+	}
+	return ref.Type
+}
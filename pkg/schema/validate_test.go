@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/pthm/melange/pkg/clientgen"
+	"github.com/pthm/melange/pkg/parser"
 	"github.com/pthm/melange/pkg/schema"
 )
 
@@ -347,3 +348,137 @@ func TestDetectCycles_ComplexValidSchema(t *testing.T) {
 		t.Errorf("expected no error for complex valid schema, got: %v", err)
 	}
 }
+
+// misspelledLinkSchema inherits viewer through "parnet", a typo of the
+// "parent" linking relation the type actually defines.
+const misspelledLinkSchema = `model
+  schema 1.1
+
+type user
+
+type folder
+  relations
+    define viewer: [user]
+
+type document
+  relations
+    define parent: [folder]
+    define viewer: [user] or viewer from parnet
+`
+
+func TestValidateTupleToUsersets_MisspelledLinkingRelation(t *testing.T) {
+	types, err := parser.ParseSchemaString(misspelledLinkSchema)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	err = schema.ValidateTupleToUsersets(types)
+	if !schema.IsInvalidTupleToUsersetErr(err) {
+		t.Fatalf("expected ErrInvalidTupleToUserset, got %v", err)
+	}
+	want := `document.viewer: "viewer from parnet": relation "parnet" is not defined on type "document"`
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error should contain %q, got: %s", want, err)
+	}
+}
+
+func TestValidateTupleToUsersets(t *testing.T) {
+	folder := schema.TypeDefinition{
+		Name:      "folder",
+		Relations: []schema.RelationDefinition{{Name: "viewer", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "user"}}}},
+	}
+	document := func(parent schema.RelationDefinition, viewer schema.RelationDefinition) []schema.TypeDefinition {
+		return []schema.TypeDefinition{{Name: "user"}, folder, {
+			Name:      "document",
+			Relations: []schema.RelationDefinition{parent, viewer},
+		}}
+	}
+	parent := schema.RelationDefinition{Name: "parent", SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "folder"}}}
+	viewerFrom := func(rel string) schema.RelationDefinition {
+		return schema.RelationDefinition{
+			Name:            "viewer",
+			ParentRelations: []schema.ParentRelationCheck{{Relation: rel, LinkingRelation: "parent"}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		types   []schema.TypeDefinition
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			types: document(parent, viewerFrom("viewer")),
+		},
+		{
+			name: "userset linking relation",
+			types: document(schema.RelationDefinition{
+				Name:            "parent",
+				SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "folder", Relation: "viewer"}},
+			}, viewerFrom("viewer")),
+			wantErr: `linking relation "parent" must allow direct object types only, not folder#viewer`,
+		},
+		{
+			name:    "computed linking relation",
+			types:   document(schema.RelationDefinition{Name: "parent", ImpliedBy: []string{"viewer"}}, viewerFrom("viewer")),
+			wantErr: `linking relation "parent" has no direct type restrictions`,
+		},
+		{
+			name: "linking relation computed from a direct relation",
+			types: func() []schema.TypeDefinition {
+				types := document(schema.RelationDefinition{Name: "parent", ImpliedBy: []string{"container"}}, viewerFrom("viewer"))
+				types[2].Relations = append(types[2].Relations, schema.RelationDefinition{
+					Name:            "container",
+					SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "folder"}},
+				})
+				return types
+			}(),
+		},
+		{
+			name: "linking relation computed from a userset relation",
+			types: func() []schema.TypeDefinition {
+				types := document(schema.RelationDefinition{Name: "parent", ImpliedBy: []string{"container"}}, viewerFrom("viewer"))
+				types[2].Relations = append(types[2].Relations, schema.RelationDefinition{
+					Name:            "container",
+					SubjectTypeRefs: []schema.SubjectTypeRef{{Type: "folder", Relation: "viewer"}},
+				})
+				return types
+			}(),
+			wantErr: `linking relation "container" must allow direct object types only, not folder#viewer`,
+		},
+		{
+			name:    "relation missing on every linked type",
+			types:   document(parent, viewerFrom("editor")),
+			wantErr: `none of the types "parent" links to (folder) defines relation "editor"`,
+		},
+		{
+			name: "intersection part",
+			types: document(parent, schema.RelationDefinition{
+				Name: "viewer",
+				IntersectionGroups: []schema.IntersectionGroup{{
+					Relations:       []string{"parent"},
+					ParentRelations: []schema.ParentRelationCheck{{Relation: "viewer", LinkingRelation: "folder"}},
+				}},
+			}),
+			wantErr: `relation "folder" is not defined on type "document"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.ValidateTupleToUsersets(tt.types)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !schema.IsInvalidTupleToUsersetErr(err) {
+				t.Fatalf("expected ErrInvalidTupleToUserset, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error should contain %q, got: %s", tt.wantErr, err)
+			}
+		})
+	}
+}
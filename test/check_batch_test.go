@@ -0,0 +1,96 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestCheckPermissionBatch checks that check_permission_batch returns one row
+// per element in input order, including duplicates, and agrees with
+// check_permission for every element.
+func TestCheckPermissionBatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	db := testutil.EmptyDB(t)
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		);
+		INSERT INTO melange_tuples VALUES
+			('user', 'anne', 'viewer', 'folder', 'f1'),
+			('folder', 'f1', 'parent', 'document', 'd1'),
+			('user', 'bob', 'owner', 'document', 'd2')`)
+	require.NoError(t, err)
+	require.NoError(t, migrator.MigrateFromString(ctx, db, `model
+  schema 1.1
+
+type user
+
+type folder
+  relations
+    define viewer: [user]
+
+type document
+  relations
+    define parent: [folder]
+    define owner: [user]
+    define viewer: [user] or owner or viewer from parent
+`))
+
+	type check struct {
+		SubjectType string `json:"subject_type"`
+		SubjectID   string `json:"subject_id"`
+		Relation    string `json:"relation"`
+		ObjectType  string `json:"object_type"`
+		ObjectID    string `json:"object_id"`
+	}
+	checks := []check{
+		{"user", "bob", "viewer", "document", "d2"},
+		{"user", "anne", "viewer", "document", "d1"},
+		{"user", "anne", "owner", "document", "d1"},
+		{"user", "bob", "viewer", "document", "d2"},
+		{"user", "anne", "unknown", "document", "d1"},
+	}
+	payload, err := json.Marshal(checks)
+	require.NoError(t, err)
+
+	rows, err := db.QueryContext(ctx, `SELECT index, allowed FROM check_permission_batch($1::jsonb)`, string(payload))
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+
+	var got []bool
+	for rows.Next() {
+		var index int
+		var allowed bool
+		require.NoError(t, rows.Scan(&index, &allowed))
+		assert.Equal(t, len(got), index, "rows are returned in input order")
+		got = append(got, allowed)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []bool{true, true, false, true, false}, got)
+
+	for i, c := range checks {
+		var single int
+		require.NoError(t, db.QueryRowContext(ctx, `SELECT check_permission($1, $2, $3, $4, $5)`,
+			c.SubjectType, c.SubjectID, c.Relation, c.ObjectType, c.ObjectID).Scan(&single))
+		assert.Equal(t, single == 1, got[i], "element %d matches check_permission", i)
+	}
+
+	var empty int
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT count(*) FROM check_permission_batch('[]')`).Scan(&empty))
+	assert.Zero(t, empty)
+}
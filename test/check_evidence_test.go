@@ -0,0 +1,141 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// evidenceSchema grants document viewer directly, through a wildcard, a
+// group userset, a folder parent, a complex implied relation, and an
+// intersection with no single granting tuple.
+const evidenceSchema = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type folder
+  relations
+    define viewer: [user]
+
+type document
+  relations
+    define parent: [folder]
+    define blocked: [user]
+    define owner: [user]
+    define editor: [user] but not blocked
+    define member: [user]
+    define viewer: [user, user:*, group#member] or owner or editor or viewer from parent
+    define auditor: editor and member
+`
+
+// evidenceRow is one row returned by a check_with_evidence_* function.
+type evidenceRow struct {
+	Allowed                                                bool
+	ObjectType, ObjectID, Relation, SubjectType, SubjectID sql.NullString
+}
+
+func checkWithEvidence(t *testing.T, ctx context.Context, db *sql.DB, relation, subjectID, objectID string) evidenceRow {
+	t.Helper()
+	var r evidenceRow
+	err := db.QueryRowContext(ctx,
+		"SELECT * FROM check_with_evidence_document_"+relation+"('user', $1, $2)",
+		subjectID, objectID,
+	).Scan(&r.Allowed, &r.ObjectType, &r.ObjectID, &r.Relation, &r.SubjectType, &r.SubjectID)
+	require.NoError(t, err)
+	return r
+}
+
+// tuple renders the reported tuple as object#relation@subject, or "" when
+// none was reported.
+func (r evidenceRow) tuple() string {
+	if !r.ObjectID.Valid {
+		return ""
+	}
+	return r.ObjectType.String + ":" + r.ObjectID.String + "#" + r.Relation.String + "@" + r.SubjectType.String + ":" + r.SubjectID.String
+}
+
+func TestCheckEvidence(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err, "creating melange_tuples table")
+
+	types, err := parser.ParseSchemaString(evidenceSchema)
+	require.NoError(t, err)
+	m := migrator.NewMigrator(db, "")
+	require.NoError(t, m.MigrateWithTypesAndOptions(ctx, types, migrator.InternalMigrateOptions{
+		SchemaContent:       evidenceSchema,
+		EnableCheckEvidence: true,
+	}))
+
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "direct")
+	insertTuple(t, ctx, db, "user", "*", "viewer", "document", "public")
+	insertTuple(t, ctx, db, "user", "bob", "member", "group", "eng")
+	insertTuple(t, ctx, db, "group", "eng#member", "viewer", "document", "shared")
+	insertTuple(t, ctx, db, "user", "carol", "viewer", "folder", "f1")
+	insertTuple(t, ctx, db, "folder", "f1", "parent", "document", "nested")
+	insertTuple(t, ctx, db, "user", "dave", "editor", "document", "edited")
+	insertTuple(t, ctx, db, "user", "erin", "editor", "document", "edited")
+	insertTuple(t, ctx, db, "user", "erin", "blocked", "document", "edited")
+	insertTuple(t, ctx, db, "user", "dave", "member", "document", "edited")
+
+	tests := []struct {
+		name     string
+		relation string
+		subject  string
+		object   string
+		allowed  bool
+		tuple    string
+	}{
+		{"direct tuple", "viewer", "alice", "direct", true, "document:direct#viewer@user:alice"},
+		{"wildcard tuple", "viewer", "zoe", "public", true, "document:public#viewer@user:*"},
+		{"userset grant tuple", "viewer", "bob", "shared", true, "document:shared#viewer@group:eng#member"},
+		{"parent linking tuple", "viewer", "carol", "nested", true, "document:nested#parent@folder:f1"},
+		{"implied relation tuple", "viewer", "dave", "edited", true, "document:edited#editor@user:dave"},
+		{"intersection reports closest tuple", "auditor", "dave", "edited", true, "document:edited#editor@user:dave"},
+		{"excluded subject denied", "viewer", "erin", "edited", false, ""},
+		{"no access denied", "viewer", "alice", "shared", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkWithEvidence(t, ctx, db, tt.relation, tt.subject, tt.object)
+			assert.Equal(t, tt.allowed, got.Allowed)
+			assert.Equal(t, tt.tuple, got.tuple())
+			assert.Equal(t, tt.allowed, checkPermission(t, ctx, db, tt.subject, tt.relation, tt.object) == 1,
+				"evidence must agree with check_permission")
+		})
+	}
+
+	// Disabling the option drops the audit functions.
+	require.NoError(t, m.MigrateWithTypesAndOptions(ctx, types, migrator.InternalMigrateOptions{
+		SchemaContent: evidenceSchema,
+	}))
+	var n int
+	require.NoError(t, db.QueryRowContext(ctx,
+		"SELECT count(*) FROM pg_proc WHERE proname LIKE 'check\\_with\\_evidence\\_%'").Scan(&n))
+	assert.Zero(t, n)
+}
@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// repeatedSubcheckSchema reaches the editor and member sub-checks of one
+// document from several branches of a single viewer check.
+const repeatedSubcheckSchema = `model
+  schema 1.1
+
+type user
+
+type team
+  relations
+    define member: [user]
+
+type document
+  relations
+    define blocked: [user]
+    define member: [user, team#member]
+    define editor: [user] and member
+    define commenter: editor and member
+    define viewer: (editor and member) or (commenter but not blocked)
+`
+
+// installCheckMemoSchema installs repeatedSubcheckSchema over a plain
+// melange_tuples table through the builtin migrator, which is the only path
+// that accepts EnableCheckMemo.
+func installCheckMemoSchema(t *testing.T, ctx context.Context, memo bool) (*sql.DB, *migrator.Migrator) {
+	t.Helper()
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err, "creating melange_tuples table")
+
+	m := migrator.NewMigrator(db, "")
+	migrateCheckMemo(t, ctx, m, memo)
+	return db, m
+}
+
+func migrateCheckMemo(t *testing.T, ctx context.Context, m *migrator.Migrator, memo bool) {
+	t.Helper()
+	types, err := parser.ParseSchemaString(repeatedSubcheckSchema)
+	require.NoError(t, err)
+	require.NoError(t, m.MigrateWithTypesAndOptions(ctx, types, migrator.InternalMigrateOptions{
+		SchemaContent:   repeatedSubcheckSchema,
+		EnableCheckMemo: memo,
+	}))
+}
+
+func checkPermission(t *testing.T, ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, subjectID, relation, objectID string) int {
+	t.Helper()
+	var result int
+	err := q.QueryRowContext(ctx,
+		"SELECT check_permission('user', $1, $2, 'document', $3)",
+		subjectID, relation, objectID).Scan(&result)
+	require.NoError(t, err)
+	return result
+}
+
+// TestCheckMemo_MatchesUnmemoized runs the same checks with and without the
+// memo and requires identical answers.
+func TestCheckMemo_MatchesUnmemoized(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	cases := []struct {
+		subject, relation, object string
+	}{
+		{"alice", "viewer", "doc1"},
+		{"alice", "commenter", "doc1"},
+		{"bob", "viewer", "doc1"},
+		{"carol", "viewer", "doc2"},
+		{"carol", "commenter", "doc2"},
+		{"dave", "viewer", "doc1"},
+	}
+
+	results := make(map[bool][]int)
+	for _, memo := range []bool{false, true} {
+		db, _ := installCheckMemoSchema(t, ctx, memo)
+		insertTuple(t, ctx, db, "user", "alice", "member", "team", "eng")
+		insertTuple(t, ctx, db, "team", "eng#member", "member", "document", "doc1")
+		insertTuple(t, ctx, db, "user", "alice", "editor", "document", "doc1")
+		insertTuple(t, ctx, db, "user", "bob", "editor", "document", "doc1")
+		insertTuple(t, ctx, db, "user", "carol", "member", "document", "doc2")
+		insertTuple(t, ctx, db, "user", "carol", "editor", "document", "doc2")
+		insertTuple(t, ctx, db, "user", "carol", "blocked", "document", "doc2")
+
+		for _, c := range cases {
+			results[memo] = append(results[memo], checkPermission(t, ctx, db, c.subject, c.relation, c.object))
+		}
+	}
+
+	assert.Equal(t, []int{1, 1, 0, 1, 1, 0}, results[false])
+	assert.Equal(t, results[false], results[true], "memoized answers must match")
+}
+
+// TestCheckMemo_ScopedToOneCall verifies that a memo entry never answers a
+// later check_permission call in the same transaction: a tuple written
+// between two calls is visible to the second.
+func TestCheckMemo_ScopedToOneCall(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	db, _ := installCheckMemoSchema(t, ctx, true)
+	insertTuple(t, ctx, db, "user", "bob", "editor", "document", "doc1")
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+
+	assert.Equal(t, 0, checkPermission(t, ctx, tx, "bob", "viewer", "doc1"))
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO melange_tuples VALUES ('user', 'bob', 'member', 'document', 'doc1')`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, checkPermission(t, ctx, tx, "bob", "viewer", "doc1"),
+		"second call must not reuse the first call's deny")
+
+	var scope string
+	require.NoError(t, tx.QueryRowContext(ctx,
+		"SELECT COALESCE(current_setting('melange.memo_scope', true), '')").Scan(&scope))
+	assert.Empty(t, scope, "check_permission must close its memo scope")
+}
+
+// TestCheckMemo_ToggleReinstalls verifies that turning the memo off for an
+// unchanged schema is not skipped, and drops the routing function.
+func TestCheckMemo_ToggleReinstalls(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	db, m := installCheckMemoSchema(t, ctx, true)
+
+	assert.True(t, functionExists(t, ctx, db, sqlgen.CheckMemoRouteFunction))
+	var parallel string
+	require.NoError(t, db.QueryRowContext(ctx,
+		"SELECT proparallel FROM pg_proc WHERE proname = 'check_permission'").Scan(&parallel))
+	assert.Equal(t, "u", parallel, "memoized check_permission must be PARALLEL UNSAFE")
+
+	migrateCheckMemo(t, ctx, m, false)
+
+	assert.False(t, functionExists(t, ctx, db, sqlgen.CheckMemoRouteFunction),
+		"disabling the memo must drop %s", sqlgen.CheckMemoRouteFunction)
+	require.NoError(t, db.QueryRowContext(ctx,
+		"SELECT proparallel FROM pg_proc WHERE proname = 'check_permission'").Scan(&parallel))
+	assert.Equal(t, "r", parallel)
+}
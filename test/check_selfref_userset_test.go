@@ -0,0 +1,83 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestCheckSelfRefUserset verifies that check_group_member resolves nested
+// [group#member] grants through its recursive CTE: a chain of nested groups
+// grants, a cycle among them terminates, userset subjects still match, and a
+// chain longer than MaxDepth raises M2002 instead of denying.
+func TestCheckSelfRefUserset(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		);
+		INSERT INTO melange_tuples VALUES
+			('user', 'x', 'member', 'group', 'c'),
+			('group', 'c#member', 'member', 'group', 'b'),
+			('group', 'b#member', 'member', 'group', 'a'),
+			('group', 'a#member', 'member', 'group', 'c'),
+			('user', 'z', 'member', 'group', 'd6'),
+			('group', 'd6#member', 'member', 'group', 'd5'),
+			('group', 'd5#member', 'member', 'group', 'd4'),
+			('group', 'd4#member', 'member', 'group', 'd3'),
+			('group', 'd3#member', 'member', 'group', 'd2'),
+			('group', 'd2#member', 'member', 'group', 'd1')`)
+	require.NoError(t, err)
+	migrateSchema(t, ctx, migrator.NewMigrator(db, ""), `model
+  schema 1.1
+type user
+type group
+  relations
+    define member: [user, group#member]
+`, migrator.InternalMigrateOptions{MaxDepth: 4})
+
+	check := func(subjectType, subjectID, object string) (int, error) {
+		var allowed int
+		err := db.QueryRowContext(ctx,
+			"SELECT check_group_member($1, $2, $3)", subjectType, subjectID, object,
+		).Scan(&allowed)
+		return allowed, err
+	}
+
+	for _, tc := range []struct {
+		subjectType, subjectID, object string
+		want                           int
+	}{
+		{"user", "x", "a", 1},
+		{"user", "x", "b", 1},
+		{"user", "x", "c", 1},
+		{"user", "y", "a", 0},
+		{"group", "c#member", "a", 1},
+		{"group", "d1#member", "a", 0},
+		{"user", "z", "d3", 1},
+	} {
+		allowed, err := check(tc.subjectType, tc.subjectID, tc.object)
+		require.NoError(t, err, "%s:%s member group:%s", tc.subjectType, tc.subjectID, tc.object)
+		assert.Equal(t, tc.want, allowed, "%s:%s member group:%s", tc.subjectType, tc.subjectID, tc.object)
+	}
+
+	_, err = check("user", "z", "d1")
+	require.Error(t, err, "d1 -> d6 nests deeper than MaxDepth")
+	assert.Equal(t, melange.ErrorCodeResolutionTooComplex,
+		melange.GetValidationErrorCode(melange.TranslateError(err)), "want M2002, got %v", err)
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestCheckStrict migrates with EnableStrictCheck and checks that
+// check_permission_strict answers every question check_permission does, the
+// same way, across userset grants, implied relations, wildcards and
+// relations that have no strict variant.
+func TestCheckStrict(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte(`model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, user:*, group#member]
+
+type document
+  relations
+    define owner: [user]
+    define editor: [group#member] or owner
+    define viewer: [user] or editor
+`), 0o644))
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		);
+		INSERT INTO melange_tuples VALUES
+			('user', 'alice', 'member', 'group', 'eng'),
+			('group', 'eng#member', 'member', 'group', 'all'),
+			('user', '*', 'member', 'group', 'public'),
+			('group', 'all#member', 'editor', 'document', '1'),
+			('group', 'public#member', 'editor', 'document', '2'),
+			('user', 'bob', 'owner', 'document', '1'),
+			('user', 'carol', 'viewer', 'document', '1')`)
+	require.NoError(t, err)
+
+	_, err = migrator.MigrateWithOptions(ctx, db, path, migrator.MigrateOptions{EnableStrictCheck: true})
+	require.NoError(t, err)
+
+	for _, subject := range []string{"alice", "bob", "carol", "dave"} {
+		for _, relation := range []string{"owner", "editor", "viewer"} {
+			for _, object := range []string{"1", "2"} {
+				var want, got int
+				require.NoError(t, db.QueryRowContext(ctx,
+					"SELECT check_permission('user', $1, $2, 'document', $3)",
+					subject, relation, object).Scan(&want))
+				require.NoError(t, db.QueryRowContext(ctx,
+					"SELECT check_permission_strict('user', $1, $2, 'document', $3)",
+					subject, relation, object).Scan(&got))
+				assert.Equal(t, want, got, "user:%s %s document:%s", subject, relation, object)
+			}
+		}
+	}
+
+	var allowed int
+	require.NoError(t, db.QueryRowContext(ctx,
+		"SELECT check_permission_strict('user', 'alice', 'viewer', 'document', '1')").Scan(&allowed))
+	assert.Equal(t, 1, allowed, "alice views document:1 through nested group membership")
+
+	skipped, err := migrator.MigrateWithOptions(ctx, db, path, migrator.MigrateOptions{EnableStrictCheck: true})
+	require.NoError(t, err)
+	assert.True(t, skipped, "strict functions present, migration skipped")
+}
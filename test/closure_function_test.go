@@ -0,0 +1,125 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// closureFunctionSchema exercises the closure lookups list functions make:
+// userset filters on a closure-implied relation, through a direct userset, a
+// self-referential userset, and a TTU parent.
+const closureFunctionSchema = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define owner: [user]
+    define admin: [user] or owner
+    define member: [user, group#member] or admin
+
+type folder
+  relations
+    define viewer: [user, group#member, group#admin]
+
+type document
+  relations
+    define parent: [folder]
+    define viewer: [user, group#member, group#admin] or viewer from parent
+`
+
+// installClosureFunctionSchema installs closureFunctionSchema with opts and
+// seeds the same tuples whatever they are.
+func installClosureFunctionSchema(t *testing.T, ctx context.Context, opts migrator.InternalMigrateOptions) (*sql.DB, []migrator.TypeDefinition) {
+	t.Helper()
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err, "creating melange_tuples table")
+
+	types, err := parser.ParseSchemaString(closureFunctionSchema)
+	require.NoError(t, err)
+	opts.SchemaContent = closureFunctionSchema
+	require.NoError(t, migrator.NewMigrator(db, "").MigrateWithTypesAndOptions(ctx, types, opts))
+
+	insertTuple(t, ctx, db, "user", "alice", "owner", "group", "eng")
+	insertTuple(t, ctx, db, "user", "bob", "member", "group", "eng")
+	insertTuple(t, ctx, db, "group", "eng#member", "member", "group", "all")
+	insertTuple(t, ctx, db, "group", "eng#member", "viewer", "document", "spec")
+	insertTuple(t, ctx, db, "group", "eng#admin", "viewer", "folder", "drafts")
+	insertTuple(t, ctx, db, "folder", "drafts", "parent", "document", "draft")
+	insertTuple(t, ctx, db, "group", "all#member", "viewer", "document", "wiki")
+	return db, types
+}
+
+// TestClosureFunction_MatchesInlineClosure verifies that check and list
+// functions calling melange_closure_rows and melange_userset_rows answer as
+// the inline rows do.
+func TestClosureFunction_MatchesInlineClosure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	inline, _ := installClosureFunctionSchema(t, ctx, migrator.InternalMigrateOptions{})
+	shared, types := installClosureFunctionSchema(t, ctx, migrator.InternalMigrateOptions{ClosureFunction: true})
+
+	var n int
+	require.NoError(t, shared.QueryRowContext(ctx,
+		"SELECT count(*) FROM melange_closure_rows('group', 'member') AS c WHERE c.satisfying_relation = 'owner'").Scan(&n))
+	assert.Equal(t, 1, n, "owner satisfies group member")
+	require.NoError(t, shared.QueryRowContext(ctx,
+		"SELECT count(*) FROM melange_userset_rows('document', 'viewer')").Scan(&n))
+	assert.Equal(t, 2, n, "document viewer accepts group#member and group#admin")
+
+	subjects := []struct{ typ, id string }{
+		{"user", "alice"}, {"user", "bob"}, {"group", "eng#admin"}, {"group", "eng#member"}, {"group", "all#member"},
+	}
+	for _, object := range []string{"spec", "draft", "wiki"} {
+		for _, s := range subjects {
+			assert.Equal(t, checkPerm(t, inline, s.typ, s.id, "viewer", "document", object),
+				checkPerm(t, shared, s.typ, s.id, "viewer", "document", object),
+				"check %s:%s viewer document:%s", s.typ, s.id, object)
+		}
+		for _, subjectType := range []string{"user", "group#member", "group#admin", "group#owner"} {
+			want := listSubjects(t, inline, "document", object, "viewer", subjectType)
+			got := listSubjects(t, shared, "document", object, "viewer", subjectType)
+			assert.ElementsMatch(t, want, got, "list_subjects document:%s viewer %s", object, subjectType)
+		}
+	}
+	assert.Contains(t, listSubjects(t, shared, "document", "spec", "viewer", "group#admin"), "eng#admin",
+		"admins of eng are members of eng, so eng#admin views spec")
+
+	assert.Equal(t, 1, checkPerm(t, shared, "group", "eng#admin", "viewer", "document", "spec"),
+		"check resolves eng#admin through melange_closure_rows")
+
+	for _, subject := range subjects {
+		want := listObjects(t, inline, subject.typ, subject.id, "viewer", "document")
+		got := listObjects(t, shared, subject.typ, subject.id, "viewer", "document")
+		assert.ElementsMatch(t, want, got, "list_objects %s:%s viewer document", subject.typ, subject.id)
+	}
+
+	// Disabling the option drops the functions again.
+	require.NoError(t, migrator.NewMigrator(shared, "").MigrateWithTypesAndOptions(ctx, types, migrator.InternalMigrateOptions{
+		SchemaContent: closureFunctionSchema,
+	}))
+	require.NoError(t, shared.QueryRowContext(ctx,
+		"SELECT count(*) FROM pg_proc WHERE proname IN ('melange_closure_rows', 'melange_userset_rows')").Scan(&n))
+	assert.Zero(t, n)
+}
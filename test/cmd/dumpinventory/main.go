@@ -380,8 +380,8 @@ func dumpTestInventory(tc TestCase, showCheck, showList bool) {
 			fmt.Printf("**Cannot Generate (%d):**\n", len(listCannotGenerate))
 			for _, a := range listCannotGenerate {
 				fmt.Printf("  ✗ %s.%s [%s]\n", a.ObjectType, a.Relation, a.Features.String())
-				if a.Capabilities.ListReason != "" {
-					fmt.Printf("    Reason: %s\n", a.Capabilities.ListReason)
+				for _, reason := range a.ExplainListEligibility() {
+					fmt.Printf("    Reason: %s\n", reason)
 				}
 			}
 		}
@@ -5,6 +5,7 @@
 // Usage:
 //
 //	dumpsql <name>              # Dump SQL for a specific test by exact name
+//	dumpsql -trace <name>       # Tag list function branches with their feature
 //
 // Output Sections:
 //
@@ -54,6 +55,7 @@ type Stage struct {
 
 func main() {
 	analysisOnly := flag.Bool("analysis", false, "Only show relation analysis, not generated SQL")
+	trace := flag.Bool("trace", false, "Tag list function branch comments with their relation, feature and pattern")
 	databaseSchema := flag.String("db-schema", "public", "Database schema")
 	flag.Parse()
 
@@ -70,6 +72,7 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		fmt.Fprintf(os.Stderr, "  -analysis    Only show relation analysis (features, patterns)\n\n")
 		fmt.Fprintf(os.Stderr, "  -db-schema   Database schema\n\n")
+		fmt.Fprintf(os.Stderr, "  -trace       Tag list function branches with [relation=... feature=... pattern=...]\n\n")
 		fmt.Fprintf(os.Stderr, "Use 'dumptest' to list available test names.\n")
 		os.Exit(1)
 	}
@@ -77,6 +80,7 @@ func main() {
 	opts := dumpOptions{
 		analysisOnly:   *analysisOnly,
 		databaseSchema: *databaseSchema,
+		trace:          *trace,
 	}
 
 	// Dump specific test by name
@@ -177,6 +181,7 @@ func loadLocalTests() ([]TestCase, error) {
 type dumpOptions struct {
 	analysisOnly   bool
 	databaseSchema string
+	trace          bool
 }
 
 func dumpSQL(tc TestCase, opts dumpOptions) {
@@ -271,6 +276,13 @@ func dumpSQL(tc TestCase, opts dumpOptions) {
 			fmt.Println(generatedSQL.DispatcherNoWildcard)
 		}
 
+		// Show context dispatcher
+		if generatedSQL.ContextDispatcher != "" {
+			fmt.Println("\n## CONTEXT DISPATCHER (check_permission_with_context)")
+			fmt.Println()
+			fmt.Println(generatedSQL.ContextDispatcher)
+		}
+
 		// Show bulk dispatcher
 		if generatedSQL.BulkDispatcher != "" {
 			fmt.Println("\n## BULK DISPATCHER (check_permission_bulk)")
@@ -278,8 +290,23 @@ func dumpSQL(tc TestCase, opts dumpOptions) {
 			fmt.Println(generatedSQL.BulkDispatcher)
 		}
 
+		// Show batch dispatcher
+		if generatedSQL.BatchDispatcher != "" {
+			fmt.Println("\n## BATCH DISPATCHER (check_permission_batch)")
+			fmt.Println()
+			fmt.Println(generatedSQL.BatchDispatcher)
+		}
+
+		// Show check_any / check_all combinators
+		if generatedSQL.CheckAnyDispatcher != "" {
+			fmt.Println("\n## COMBINATORS (check_any, check_all)")
+			fmt.Println()
+			fmt.Println(generatedSQL.CheckAnyDispatcher)
+			fmt.Println(generatedSQL.CheckAllDispatcher)
+		}
+
 		// Generate list functions
-		listSQL, err := compiler.GenerateListSQL(analyses, inline, opts.databaseSchema)
+		listSQL, err := compiler.GenerateListSQLWithOptions(analyses, inline, opts.databaseSchema, compiler.GenerateSQLOptions{TraceBlocks: opts.trace})
 		if err != nil {
 			fmt.Printf("\n⚠️  List SQL generation error: %v\n", err)
 			continue
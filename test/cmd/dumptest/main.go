@@ -1,4 +1,5 @@
-// Command dumptest dumps OpenFGA test cases in a human-readable format.
+// Command dumptest dumps OpenFGA test cases in a human-readable format, or as
+// a pgTAP script with -emit-tests.
 //
 // Usage:
 //
@@ -6,17 +7,22 @@
 //	dumptest <name>              # Dump a specific test by exact name
 //	dumptest -pattern <regex>    # Dump tests matching a regex pattern
 //	dumptest -all                # Dump all tests (warning: very long output)
+//	dumptest -file <yaml> ...    # Read tests from a YAML file instead of the suites
+//	dumptest -emit-tests ...     # Emit pgTAP assertions for the selected tests
 //
 // Examples:
 //
 //	dumptest wildcard_direct
 //	dumptest -pattern "^userset"
 //	dumptest -pattern "computed_userset|ttu_"
+//	dumptest -emit-tests -o wildcard_direct.sql wildcard_direct
+//	dumptest -emit-tests -file authz_tests.yaml -all > authz_test.sql
 package main
 
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -69,8 +75,9 @@ type ListObjectsAssertion struct {
 		Type     string `json:"type"`
 		Relation string `json:"relation"`
 	} `json:"request"`
-	Expectation []string `json:"expectation"`
-	ErrorCode   int      `json:"errorCode"`
+	ContextualTuples []Tuple  `json:"contextualTuples"`
+	Expectation      []string `json:"expectation"`
+	ErrorCode        int      `json:"errorCode"`
 }
 
 // ListUsersAssertion represents an expected result for ListUsers.
@@ -87,9 +94,21 @@ type ListUsersAssertion struct {
 func main() {
 	pattern := flag.String("pattern", "", "Regex pattern to match test names")
 	all := flag.Bool("all", false, "Dump all tests (warning: very long output)")
+	file := flag.String("file", "", "Read tests from this YAML file instead of the OpenFGA suites")
+	emitTests := flag.Bool("emit-tests", false, "Emit pgTAP assertions runnable with pg_prove instead of a readable dump")
+	output := flag.String("o", "", "Write output to this file instead of stdout")
+	databaseSchema := flag.String("db-schema", "", "Database schema of the melange functions (with -emit-tests)")
 	flag.Parse()
 
-	tests, err := loadTests()
+	var (
+		tests []TestCase
+		err   error
+	)
+	if *file != "" {
+		tests, err = loadTestFile(*file)
+	} else {
+		tests, err = loadTests()
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading tests: %v\n", err)
 		os.Exit(1)
@@ -106,52 +125,70 @@ func main() {
 		fmt.Println("  dumptest <name>            # Dump a specific test by name")
 		fmt.Println("  dumptest -pattern <regex>  # Dump tests matching a pattern")
 		fmt.Println("  dumptest -all              # Dump all tests")
+		fmt.Println("  dumptest -emit-tests ...   # Emit pgTAP assertions for the selected tests")
 		return
 	}
 
-	// Dump all tests
-	if *all {
-		for i, tc := range tests {
-			if i > 0 {
-				fmt.Println("\n" + strings.Repeat("=", 80) + "\n")
-			}
-			dumpTest(tc)
+	selected := selectTests(tests, *pattern, *all, flag.Arg(0))
+
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+
+	if *emitTests {
+		if err := emitPgTAP(out, selected, *databaseSchema); err != nil {
+			fmt.Fprintf(os.Stderr, "Error emitting tests: %v\n", err)
+			os.Exit(1)
 		}
 		return
 	}
 
-	// Dump tests matching pattern
-	if *pattern != "" {
-		re, err := regexp.Compile(*pattern)
+	for i, tc := range selected {
+		if i > 0 {
+			fmt.Fprintln(out, "\n"+strings.Repeat("=", 80)+"\n")
+		}
+		dumpTest(out, tc)
+	}
+}
+
+// selectTests returns the tests chosen by -all, -pattern, or an exact name,
+// exiting with a message when nothing matches.
+func selectTests(tests []TestCase, pattern string, all bool, name string) []TestCase {
+	if all {
+		return tests
+	}
+
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Invalid pattern: %v\n", err)
 			os.Exit(1)
 		}
 
-		var matched int
+		var matched []TestCase
 		for _, tc := range tests {
 			if re.MatchString(tc.Name) {
-				if matched > 0 {
-					fmt.Println("\n" + strings.Repeat("=", 80) + "\n")
-				}
-				dumpTest(tc)
-				matched++
+				matched = append(matched, tc)
 			}
 		}
 
-		if matched == 0 {
-			fmt.Fprintf(os.Stderr, "No tests matched pattern %q\n", *pattern)
+		if len(matched) == 0 {
+			fmt.Fprintf(os.Stderr, "No tests matched pattern %q\n", pattern)
 			os.Exit(1)
 		}
-		return
+		return matched
 	}
 
-	// Dump specific test by name
-	name := flag.Arg(0)
 	for _, tc := range tests {
 		if tc.Name == name {
-			dumpTest(tc)
-			return
+			return []TestCase{tc}
 		}
 	}
 
@@ -173,6 +210,7 @@ func main() {
 
 	fmt.Fprintf(os.Stderr, "Test %q not found\n", name)
 	os.Exit(1)
+	return nil
 }
 
 func loadTests() ([]TestCase, error) {
@@ -206,6 +244,21 @@ func loadTests() ([]TestCase, error) {
 	return allTests, nil
 }
 
+// loadTestFile reads test cases from a single YAML file in the OpenFGA test
+// format, such as a project's own assertions file.
+func loadTestFile(path string) ([]TestCase, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var tf TestFile
+	if err := yaml.Unmarshal(b, &tf); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return tf.Tests, nil
+}
+
 // loadLocalTests reads YAML test files from the openfgatests/testdata directory.
 func loadLocalTests() ([]TestCase, error) {
 	// Find testdata dir relative to this source file
@@ -243,35 +296,35 @@ func loadLocalTests() ([]TestCase, error) {
 	return allTests, nil
 }
 
-func dumpTest(tc TestCase) {
-	fmt.Printf("Test: %s\n", tc.Name)
-	fmt.Println(strings.Repeat("-", len(tc.Name)+6))
+func dumpTest(w io.Writer, tc TestCase) {
+	fmt.Fprintf(w, "Test: %s\n", tc.Name)
+	fmt.Fprintln(w, strings.Repeat("-", len(tc.Name)+6))
 
 	for i, stage := range tc.Stages {
-		fmt.Printf("\n=== Stage %d ===\n", i+1)
+		fmt.Fprintf(w, "\n=== Stage %d ===\n", i+1)
 
 		// Model
-		fmt.Println("\nModel:")
-		fmt.Println("```fga")
-		fmt.Print(stage.Model)
+		fmt.Fprintln(w, "\nModel:")
+		fmt.Fprintln(w, "```fga")
+		fmt.Fprint(w, stage.Model)
 		if !strings.HasSuffix(stage.Model, "\n") {
-			fmt.Println()
+			fmt.Fprintln(w)
 		}
-		fmt.Println("```")
+		fmt.Fprintln(w, "```")
 
 		// Tuples
 		if len(stage.Tuples) > 0 {
-			fmt.Println("\nTuples:")
+			fmt.Fprintln(w, "\nTuples:")
 			for _, t := range stage.Tuples {
-				fmt.Printf("  %s | %s | %s\n", t.User, t.Relation, t.Object)
+				fmt.Fprintf(w, "  %s | %s | %s\n", t.User, t.Relation, t.Object)
 			}
 		} else {
-			fmt.Println("\nTuples: (none)")
+			fmt.Fprintln(w, "\nTuples: (none)")
 		}
 
 		// Check Assertions
 		if len(stage.CheckAssertions) > 0 {
-			fmt.Println("\nCheck Assertions:")
+			fmt.Fprintln(w, "\nCheck Assertions:")
 			for j, c := range stage.CheckAssertions {
 				expectStr := "ALLOW"
 				if !c.Expectation {
@@ -281,13 +334,13 @@ func dumpTest(tc TestCase) {
 					expectStr = fmt.Sprintf("ERROR(%d)", c.ErrorCode)
 				}
 
-				fmt.Printf("  [%d] %s: %s | %s | %s\n",
+				fmt.Fprintf(w, "  [%d] %s: %s | %s | %s\n",
 					j+1, expectStr, c.Tuple.User, c.Tuple.Relation, c.Tuple.Object)
 
 				if len(c.ContextualTuples) > 0 {
-					fmt.Println("      with contextual tuples:")
+					fmt.Fprintln(w, "      with contextual tuples:")
 					for _, ct := range c.ContextualTuples {
-						fmt.Printf("        %s | %s | %s\n", ct.User, ct.Relation, ct.Object)
+						fmt.Fprintf(w, "        %s | %s | %s\n", ct.User, ct.Relation, ct.Object)
 					}
 				}
 			}
@@ -295,34 +348,34 @@ func dumpTest(tc TestCase) {
 
 		// ListObjects Assertions
 		if len(stage.ListObjectsAssertions) > 0 {
-			fmt.Println("\nListObjects Assertions:")
+			fmt.Fprintln(w, "\nListObjects Assertions:")
 			for j, l := range stage.ListObjectsAssertions {
-				fmt.Printf("  [%d] user=%s relation=%s type=%s\n",
+				fmt.Fprintf(w, "  [%d] user=%s relation=%s type=%s\n",
 					j+1, l.Request.User, l.Request.Relation, l.Request.Type)
 				switch {
 				case l.ErrorCode != 0:
-					fmt.Printf("      => ERROR(%d)\n", l.ErrorCode)
+					fmt.Fprintf(w, "      => ERROR(%d)\n", l.ErrorCode)
 				case len(l.Expectation) > 0:
-					fmt.Printf("      => %v\n", l.Expectation)
+					fmt.Fprintf(w, "      => %v\n", l.Expectation)
 				default:
-					fmt.Println("      => (empty)")
+					fmt.Fprintln(w, "      => (empty)")
 				}
 			}
 		}
 
 		// ListUsers Assertions
 		if len(stage.ListUsersAssertions) > 0 {
-			fmt.Println("\nListUsers Assertions:")
+			fmt.Fprintln(w, "\nListUsers Assertions:")
 			for j, l := range stage.ListUsersAssertions {
-				fmt.Printf("  [%d] object=%s relation=%s filters=%v\n",
+				fmt.Fprintf(w, "  [%d] object=%s relation=%s filters=%v\n",
 					j+1, l.Request.Object, l.Request.Relation, l.Request.Filters)
 				switch {
 				case l.ErrorCode != 0:
-					fmt.Printf("      => ERROR(%d)\n", l.ErrorCode)
+					fmt.Fprintf(w, "      => ERROR(%d)\n", l.ErrorCode)
 				case len(l.Expectation) > 0:
-					fmt.Printf("      => %v\n", l.Expectation)
+					fmt.Fprintf(w, "      => %v\n", l.Expectation)
 				default:
-					fmt.Println("      => (empty)")
+					fmt.Fprintln(w, "      => (empty)")
 				}
 			}
 		}
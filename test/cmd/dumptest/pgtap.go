@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Temp relations backing the emitted script. Generated functions read
+// melange_tuples unqualified, and pg_temp is searched first, so a temp view
+// of that name shadows the real tuples for the script's transaction (the
+// same mechanism Checker uses for contextual tuples).
+const (
+	pgtapStoredTable     = "melange_pgtap_tuples"
+	pgtapContextualTable = "melange_pgtap_contextual"
+)
+
+// emitPgTAP writes a pgTAP script asserting every Check and ListObjects
+// expectation of the given tests against an already-migrated database.
+//
+// The script runs in one transaction and rolls back: test tuples go into temp
+// tables shadowing melange_tuples, so it neither sees nor touches stored
+// tuples. Tuples accumulate across stages, as in the OpenFGA runner.
+// Contextual tuples are written to a separate temp table and cleared after
+// their assertion.
+//
+// A script runs against a single migrated model, so every selected stage must
+// use the same model. ListUsers assertions are not emitted.
+func emitPgTAP(w io.Writer, tests []TestCase, databaseSchema string) error {
+	model, err := singleModel(tests)
+	if err != nil {
+		return err
+	}
+
+	fn := func(name string) string {
+		if databaseSchema == "" {
+			return name
+		}
+		return quoteIdent(databaseSchema) + "." + name
+	}
+
+	var body strings.Builder
+	planned := 0
+	for _, tc := range tests {
+		for i, stage := range tc.Stages {
+			prefix := fmt.Sprintf("%s stage %d", tc.Name, i+1)
+			fmt.Fprintf(&body, "\n-- %s\n", prefix)
+			writeTupleInsert(&body, pgtapStoredTable, stage.Tuples)
+
+			for _, c := range stage.CheckAssertions {
+				subjectType, subjectID := splitRef(c.Tuple.User)
+				objectType, objectID := splitRef(c.Tuple.Object)
+				call := fmt.Sprintf("%s(%s, %s, %s, %s, %s)", fn("check_permission"),
+					quoteLiteral(subjectType), quoteLiteral(subjectID), quoteLiteral(c.Tuple.Relation),
+					quoteLiteral(objectType), quoteLiteral(objectID))
+				desc := fmt.Sprintf("%s: check %s %s %s", prefix, c.Tuple.User, c.Tuple.Relation, c.Tuple.Object)
+
+				writeTupleInsert(&body, pgtapContextualTable, c.ContextualTuples)
+				switch {
+				case c.ErrorCode != 0:
+					fmt.Fprintf(&body, "SELECT dies_ok(%s, %s);\n", quoteLiteral("SELECT "+call), quoteLiteral(desc+" errors"))
+				default:
+					want := 0
+					if c.Expectation {
+						want = 1
+					}
+					fmt.Fprintf(&body, "SELECT is(%s, %d, %s);\n", call, want, quoteLiteral(desc))
+				}
+				if len(c.ContextualTuples) > 0 {
+					fmt.Fprintf(&body, "DELETE FROM pg_temp.%s;\n", pgtapContextualTable)
+				}
+				planned++
+			}
+
+			for _, l := range stage.ListObjectsAssertions {
+				subjectType, subjectID := splitRef(l.Request.User)
+				query := fmt.Sprintf("SELECT object_id FROM %s(%s, %s, %s, %s)", fn("list_accessible_objects"),
+					quoteLiteral(subjectType), quoteLiteral(subjectID), quoteLiteral(l.Request.Relation),
+					quoteLiteral(l.Request.Type))
+				desc := fmt.Sprintf("%s: list %s %s %s", prefix, l.Request.User, l.Request.Relation, l.Request.Type)
+
+				writeTupleInsert(&body, pgtapContextualTable, l.ContextualTuples)
+				switch {
+				case l.ErrorCode != 0:
+					fmt.Fprintf(&body, "SELECT dies_ok(%s, %s);\n", quoteLiteral(query), quoteLiteral(desc+" errors"))
+				case len(l.Expectation) == 0:
+					fmt.Fprintf(&body, "SELECT is_empty(%s, %s);\n", quoteLiteral(query), quoteLiteral(desc))
+				default:
+					ids := make([]string, len(l.Expectation))
+					for j, obj := range l.Expectation {
+						_, id := splitRef(obj)
+						ids[j] = quoteLiteral(id)
+					}
+					fmt.Fprintf(&body, "SELECT set_eq(%s, ARRAY[%s]::TEXT[], %s);\n",
+						quoteLiteral(query), strings.Join(ids, ", "), quoteLiteral(desc))
+				}
+				if len(l.ContextualTuples) > 0 {
+					fmt.Fprintf(&body, "DELETE FROM pg_temp.%s;\n", pgtapContextualTable)
+				}
+				planned++
+			}
+		}
+	}
+
+	fmt.Fprintln(w, "-- Generated by dumptest -emit-tests. Run with pg_prove against a database")
+	fmt.Fprintln(w, "-- with the pgtap extension, migrated with this model:")
+	fmt.Fprintln(w, "--")
+	for _, line := range strings.Split(strings.TrimRight(model, "\n"), "\n") {
+		fmt.Fprintln(w, strings.TrimRight("--   "+line, " "))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "BEGIN;")
+	fmt.Fprintf(w, "SELECT plan(%d);\n\n", planned)
+	fmt.Fprintln(w, "-- Setup: shadow melange_tuples with the test tuples for this transaction.")
+	for _, table := range []string{pgtapStoredTable, pgtapContextualTable} {
+		fmt.Fprintf(w, "CREATE TEMP TABLE %s (\n", table)
+		fmt.Fprintln(w, "    subject_type TEXT NOT NULL,")
+		fmt.Fprintln(w, "    subject_id TEXT NOT NULL,")
+		fmt.Fprintln(w, "    relation TEXT NOT NULL,")
+		fmt.Fprintln(w, "    object_type TEXT NOT NULL,")
+		fmt.Fprintln(w, "    object_id TEXT NOT NULL")
+		fmt.Fprintln(w, ");")
+	}
+	fmt.Fprintf(w, "CREATE TEMP VIEW melange_tuples AS\nSELECT * FROM pg_temp.%s\nUNION ALL\nSELECT * FROM pg_temp.%s;\n",
+		pgtapStoredTable, pgtapContextualTable)
+	fmt.Fprint(w, body.String())
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "-- Teardown: rolling back drops the temp tables and test tuples.")
+	fmt.Fprintln(w, "SELECT * FROM finish();")
+	fmt.Fprintln(w, "ROLLBACK;")
+	return nil
+}
+
+// singleModel returns the model shared by every stage of tests, or an error
+// naming the first stage whose model differs.
+func singleModel(tests []TestCase) (string, error) {
+	var model string
+	for _, tc := range tests {
+		for i, stage := range tc.Stages {
+			switch {
+			case model == "":
+				model = stage.Model
+			case normalizeModel(stage.Model) != normalizeModel(model):
+				return "", fmt.Errorf("%s stage %d uses a different model; a pgTAP script runs against one migrated model, so select tests that share it", tc.Name, i+1)
+			}
+		}
+	}
+	if model == "" {
+		return "", fmt.Errorf("no stages with a model selected")
+	}
+	return model, nil
+}
+
+// normalizeModel collapses whitespace so indentation differences between
+// stages do not count as a model change.
+func normalizeModel(model string) string {
+	return strings.Join(strings.Fields(model), " ")
+}
+
+// writeTupleInsert writes one INSERT for tuples into the named temp table.
+// Usersets ("group:eng#member") keep the relation in subject_id, matching
+// how melange stores them.
+func writeTupleInsert(w io.Writer, table string, tuples []Tuple) {
+	if len(tuples) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "INSERT INTO pg_temp.%s (subject_type, subject_id, relation, object_type, object_id) VALUES\n", table)
+	for i, t := range tuples {
+		subjectType, subjectID := splitRef(t.User)
+		objectType, objectID := splitRef(t.Object)
+		sep := ","
+		if i == len(tuples)-1 {
+			sep = ";"
+		}
+		fmt.Fprintf(w, "    (%s, %s, %s, %s, %s)%s\n",
+			quoteLiteral(subjectType), quoteLiteral(subjectID), quoteLiteral(t.Relation),
+			quoteLiteral(objectType), quoteLiteral(objectID), sep)
+	}
+}
+
+// splitRef splits "type:id" at the first colon. The id keeps any "#relation"
+// suffix.
+func splitRef(ref string) (typ, id string) {
+	typ, id, _ = strings.Cut(ref, ":")
+	return typ, id
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
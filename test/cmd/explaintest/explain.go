@@ -42,7 +42,7 @@ type openfgaClient interface {
 // the underlying tuple set. We mirror the openfgatests runner's behavior
 // here so EXPLAIN sees the same data layout the integration tests do.
 func runTest(tc TestCase, opts Options) error {
-	db, client, cleanup, err := setupTest(tc)
+	db, client, cleanup, err := setupTest(tc, opts.Memo)
 	if err != nil {
 		return fmt.Errorf("setup test: %w", err)
 	}
@@ -183,32 +183,59 @@ func runStage(ctx context.Context, db *sql.DB, stageNum int, stage Stage, opts O
 
 // runExplain executes an EXPLAIN query, joining the plan output and extracting
 // metrics. The query template should contain a single %s for the EXPLAIN options.
+//
+// The query runs in its own transaction, rolled back afterwards. With
+// opts.Calls, function tracking is enabled for that transaction only and the
+// per-transaction statistics then count exactly the calls this query made.
 func runExplain(ctx context.Context, db *sql.DB, opts Options, queryTemplate string, args ...any) (string, Metrics, error) {
 	query := fmt.Sprintf(queryTemplate, buildExplainOptions(opts))
 
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query, args...)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", Metrics{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if opts.Calls {
+		if _, err := tx.ExecContext(ctx, "SET LOCAL track_functions = 'all'"); err != nil {
+			return "", Metrics{}, fmt.Errorf("enable function tracking: %w", err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return "", Metrics{}, fmt.Errorf("execute EXPLAIN: %w", err)
 	}
-	defer func() { _ = rows.Close() }()
 
 	var planLines []string
 	for rows.Next() {
 		var line string
 		if err := rows.Scan(&line); err != nil {
+			_ = rows.Close()
 			return "", Metrics{}, fmt.Errorf("scan plan line: %w", err)
 		}
 		planLines = append(planLines, line)
 	}
+	_ = rows.Close()
 	if err := rows.Err(); err != nil {
 		return "", Metrics{}, fmt.Errorf("iterate plan lines: %w", err)
 	}
 
 	plan := strings.Join(planLines, "\n")
-	return plan, extractMetrics(plan), nil
+	metrics := extractMetrics(plan)
+
+	if opts.Calls {
+		if err := tx.QueryRowContext(ctx,
+			"SELECT COALESCE(sum(calls), 0) FROM pg_stat_xact_user_functions",
+		).Scan(&metrics.FunctionCalls); err != nil {
+			return "", Metrics{}, fmt.Errorf("read function call count: %w", err)
+		}
+	}
+
+	return plan, metrics, nil
 }
 
 // explainCheckAssertion runs EXPLAIN ANALYZE on a Check assertion.
@@ -304,7 +331,9 @@ func explainListUsersAssertion(ctx context.Context, db *sql.DB, index int, asser
 	}, nil
 }
 
-// buildExplainOptions constructs the EXPLAIN options string.
+// buildExplainOptions constructs the EXPLAIN options string. It mirrors
+// melange.PlanOptions, which the root module cannot use until a melange
+// release includes it.
 func buildExplainOptions(opts Options) string {
 	var parts []string
 	parts = append(parts, "ANALYZE")
@@ -17,6 +17,8 @@
 //	explaintest --summary "^userset"        # Summary mode for pattern
 //	explaintest --verbose=false <name>      # Disable verbose output
 //	explaintest --settings=false <name>     # Disable settings output
+//	explaintest --calls <name>              # Count function calls per query
+//	explaintest --calls --memo <name>       # Same, with the check memo installed
 //
 // Examples:
 //
@@ -103,6 +105,8 @@ type Options struct {
 	Verbose   bool
 	Settings  bool
 	WAL       bool
+	Memo      bool // install with GenerateSQLOptions.EnableCheckMemo
+	Calls     bool // count function calls made by each query
 }
 
 func main() {
@@ -117,6 +121,8 @@ func main() {
 	verbose := flag.Bool("verbose", true, "Include verbose output (column lists, schema-qualified names)")
 	settings := flag.Bool("settings", true, "Include configuration parameters that affect query planning")
 	wal := flag.Bool("wal", true, "Include information about WAL record generation")
+	memo := flag.Bool("memo", false, "Install the schema with check memoization enabled")
+	calls := flag.Bool("calls", false, "Count function calls per query (track_functions; requires superuser)")
 	flag.Parse()
 
 	opts := Options{
@@ -130,6 +136,8 @@ func main() {
 		Verbose:   *verbose,
 		Settings:  *settings,
 		WAL:       *wal,
+		Memo:      *memo,
+		Calls:     *calls,
 	}
 
 	// Summary mode
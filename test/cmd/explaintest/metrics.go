@@ -12,6 +12,9 @@ type Metrics struct {
 	BufferHits      int     `json:"buffer_hits"`
 	BufferReads     int     `json:"buffer_reads"`
 	Rows            int     `json:"rows"`
+	// FunctionCalls is the number of function calls the query made, from
+	// pg_stat_xact_user_functions. Collected only with --calls.
+	FunctionCalls int `json:"function_calls,omitempty"`
 }
 
 var (
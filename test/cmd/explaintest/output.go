@@ -37,6 +37,9 @@ func formatTextOutput(testName string, results []*ExplainResult) string {
 			sb.WriteString(fmt.Sprintf("  Buffer Reads:   %d\n", r.Metrics.BufferReads))
 		}
 		sb.WriteString(fmt.Sprintf("  Rows:           %d\n", r.Metrics.Rows))
+		if r.Metrics.FunctionCalls > 0 {
+			sb.WriteString(fmt.Sprintf("  Function Calls: %d\n", r.Metrics.FunctionCalls))
+		}
 		sb.WriteString("\n")
 
 		sb.WriteString("Query Plan:\n")
@@ -15,6 +15,7 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 
 	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
 	"github.com/pthm/melange/test/openfgatests"
 )
 
@@ -26,9 +27,10 @@ var (
 	containerErr error
 )
 
-// setupTest creates a database and client for a test case.
+// setupTest creates a database and client for a test case. memo installs the
+// generated functions with check memoization enabled.
 // Returns the database, client, and a cleanup function.
-func setupTest(tc TestCase) (*sql.DB, *openfgatests.Client, func(), error) {
+func setupTest(tc TestCase, memo bool) (*sql.DB, *openfgatests.Client, func(), error) {
 	// Get database connection (container or external)
 	adminDSN, err := getDatabaseDSN()
 	if err != nil {
@@ -67,7 +69,7 @@ func setupTest(tc TestCase) (*sql.DB, *openfgatests.Client, func(), error) {
 	}
 
 	// Initialize melange schema with the test's model
-	if err := initializeMelangeSchema(db, tc.Stages[0].Model); err != nil {
+	if err := initializeMelangeSchema(db, tc.Stages[0].Model, memo); err != nil {
 		_ = db.Close()
 		_ = dropDatabase(context.Background(), adminDSN, dbName)
 		return nil, nil, nil, fmt.Errorf("initialize schema: %w", err)
@@ -132,16 +134,23 @@ func getDatabaseDSN() (string, error) {
 }
 
 // initializeMelangeSchema sets up the melange schema in the database.
-func initializeMelangeSchema(db *sql.DB, modelDSL string) error {
+func initializeMelangeSchema(db *sql.DB, modelDSL string, memo bool) error {
 	ctx := context.Background()
 
 	// Migrate the actual test model to generate the correct SQL functions
-	if err := migrator.MigrateFromString(ctx, db, modelDSL); err != nil {
+	types, err := parser.ParseSchemaString(modelDSL)
+	if err != nil {
+		return fmt.Errorf("parse model: %w", err)
+	}
+	m := migrator.NewMigrator(db, "")
+	if err := m.MigrateWithTypesAndOptions(ctx, types, migrator.InternalMigrateOptions{
+		EnableCheckMemo: memo,
+	}); err != nil {
 		return fmt.Errorf("apply melange migration: %w", err)
 	}
 
 	// Create test tuples table and view
-	_, err := db.ExecContext(ctx, `
+	_, err = db.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS melange_test_tuples (
 			subject_type TEXT NOT NULL,
 			subject_id TEXT NOT NULL,
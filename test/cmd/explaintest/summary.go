@@ -86,7 +86,7 @@ func getOverallAvgTime(s *TestSummary) float64 {
 // runTestSummary executes EXPLAIN ANALYZE on a single test and returns aggregated metrics.
 func runTestSummary(tc TestCase) (*TestSummary, error) {
 	// Setup database and client
-	db, client, cleanup, err := setupTest(tc)
+	db, client, cleanup, err := setupTest(tc, false)
 	if err != nil {
 		return nil, fmt.Errorf("setup test: %w", err)
 	}
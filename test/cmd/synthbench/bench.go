@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pthm/melange/pkg/compiler"
+)
+
+// operation is one timed SQL entry point. query takes the subject type and
+// id, the relation, and the object type and id, and returns one integer:
+// the check result or the number of rows listed.
+type operation struct {
+	query string
+	args  func(subjectType, subjectID, relation, objectType, objectID string) []any
+	// list marks operations that need a list function rather than a check
+	// function.
+	list bool
+}
+
+var operations = map[string]operation{
+	"check": {
+		query: "SELECT check_permission($1, $2, $3, $4, $5)",
+		args: func(st, sid, rel, ot, oid string) []any {
+			return []any{st, sid, rel, ot, oid}
+		},
+	},
+	"list_objects": {
+		query: "SELECT count(*) FROM list_accessible_objects($1, $2, $3, $4)",
+		args: func(st, sid, rel, ot, _ string) []any {
+			return []any{st, sid, rel, ot}
+		},
+		list: true,
+	},
+	"list_subjects": {
+		query: "SELECT count(*) FROM list_accessible_subjects($1, $2, $3, $4)",
+		args: func(st, _, rel, ot, oid string) []any {
+			return []any{ot, oid, rel, st}
+		},
+		list: true,
+	},
+}
+
+// operationNames returns the keys of operations in sorted order.
+func operationNames() []string {
+	names := make([]string, 0, len(operations))
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// result summarises the timed calls of one operation on one relation.
+type result struct {
+	Op       string
+	Relation string // object_type#relation
+	Calls    int
+	Errors   int
+	MeanRows float64 // check results count as 0 or 1
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+type bench struct {
+	db  *sql.DB
+	cfg config
+}
+
+// run times every configured operation on every relation whose subject can
+// hold it and that has the function the operation calls.
+func (b bench) run(ctx context.Context, analyses []compiler.RelationAnalysis) ([]result, error) {
+	rng := rand.New(rand.NewPCG(b.cfg.scale.Seed, b.cfg.scale.Seed+1))
+	n := max(b.cfg.scale.ObjectsPerType, 1)
+
+	reach := reachable(analyses, b.cfg.subjectType)
+	var results []result
+	for _, opName := range b.cfg.ops {
+		op := operations[opName]
+		for _, a := range analyses {
+			if !b.timed(a, op, reach) {
+				continue
+			}
+			r := result{Op: opName, Relation: a.ObjectType + "#" + a.Relation}
+			durations := make([]time.Duration, 0, b.cfg.samples)
+			var rows int
+			for range b.cfg.samples {
+				args := op.args(b.cfg.subjectType, strconv.Itoa(rng.IntN(n)), a.Relation, a.ObjectType, strconv.Itoa(rng.IntN(n)))
+				var got int
+				start := time.Now()
+				err := b.db.QueryRowContext(ctx, op.query, args...).Scan(&got)
+				elapsed := time.Since(start)
+				r.Calls++
+				if err != nil {
+					// Errors such as M2002 on a deep hierarchy are counted
+					// rather than fatal.
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					r.Errors++
+					continue
+				}
+				durations = append(durations, elapsed)
+				rows += got
+			}
+			if len(durations) > 0 {
+				r.MeanRows = float64(rows) / float64(len(durations))
+				slices.Sort(durations)
+				r.P50 = percentile(durations, 0.50)
+				r.P90 = percentile(durations, 0.90)
+				r.P99 = percentile(durations, 0.99)
+				r.Max = durations[len(durations)-1]
+			}
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// timed reports whether op is timed on the relation of a: the relation must
+// have the specialized function op calls, and the benchmark subject type must
+// be able to hold it.
+func (b bench) timed(a compiler.RelationAnalysis, op operation, reach map[string]bool) bool {
+	if op.list && !a.Capabilities.ListAllowed || !op.list && !a.Capabilities.CheckAllowed {
+		return false
+	}
+	return reach[a.ObjectType+"#"+a.Relation]
+}
+
+// reachable returns the object_type#relation keys subjectType can hold:
+// those granting it directly, and, until nothing changes, those satisfied by,
+// granting a userset of, inheriting from a parent through, or intersecting
+// only relations it can hold. Timing the others would only measure denials.
+func reachable(analyses []compiler.RelationAnalysis, subjectType string) map[string]bool {
+	reach := make(map[string]bool)
+	for _, a := range analyses {
+		if slices.Contains(a.DirectSubjectTypes, subjectType) {
+			reach[a.ObjectType+"#"+a.Relation] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, a := range analyses {
+			key := a.ObjectType + "#" + a.Relation
+			if reach[key] || !reachesVia(a, reach) {
+				continue
+			}
+			reach[key] = true
+			changed = true
+		}
+	}
+	return reach
+}
+
+// reachesVia reports whether a holds through a relation already in reach.
+func reachesVia(a compiler.RelationAnalysis, reach map[string]bool) bool {
+	for _, rel := range a.SatisfyingRelations {
+		if reach[a.ObjectType+"#"+rel] {
+			return true
+		}
+	}
+	for _, p := range a.UsersetPatterns {
+		if reach[p.SubjectType+"#"+p.SubjectRelation] {
+			return true
+		}
+	}
+	for _, p := range a.ParentRelations {
+		for _, t := range p.AllowedLinkingTypes {
+			if reach[t+"#"+p.Relation] {
+				return true
+			}
+		}
+	}
+	for _, g := range a.IntersectionGroups {
+		all := len(g.Parts) > 0
+		for _, part := range g.Parts {
+			switch {
+			case part.IsThis:
+				// A direct grant would have put a in reach already.
+				all = false
+			case part.ParentRelation != nil:
+				all = all && slices.ContainsFunc(part.ParentRelation.AllowedLinkingTypes, func(t string) bool { return reach[t+"#"+part.ParentRelation.Relation] })
+			default:
+				all = all && reach[a.ObjectType+"#"+part.Relation]
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(float64(len(sorted))*p)) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
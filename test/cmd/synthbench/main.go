@@ -0,0 +1,197 @@
+// Command synthbench loads synthetic tuples for a model into melange_tuples
+// and times check and list operations across them, for reproducible load
+// when tuning indexes.
+//
+// It migrates the model, generates tuples with
+// testutil.GenerateSyntheticTuples (the same seed always gives the same
+// tuples), loads them with COPY, and then times each relation the analysis
+// can generate specialized functions for: check_permission for relations
+// with a check function, list_accessible_objects and list_accessible_subjects
+// for those with list functions. Subjects and objects are drawn at random
+// from the generated ids.
+//
+// Usage:
+//
+//	synthbench -schema <file.fga> [flags]
+//
+// The database comes from -dsn or DATABASE_URL. melange_tuples is created as
+// a table if it does not exist and truncated before loading; a view is
+// rejected. Pass -skip-load to time the tuples already loaded, for example
+// after adding an index.
+//
+// Examples:
+//
+//	synthbench -schema schema.fga -objects 10000 -fanout 5
+//	synthbench -schema schema.fga -userset-density 0.5 -ops check
+//	synthbench -schema schema.fga -skip-load -samples 1000
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/pthm/melange/pkg/compiler"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/pkg/schema"
+	"github.com/pthm/melange/test/testutil"
+)
+
+func main() {
+	schemaPath := flag.String("schema", "", "Path to the .fga model (required)")
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "PostgreSQL connection string (default $DATABASE_URL)")
+	objects := flag.Int("objects", 1000, "Objects per type; subjects are drawn from the same ids")
+	fanOut := flag.Int("fanout", 3, "Tuples per object for each relation that accepts direct grants")
+	usersetDensity := flag.Float64("userset-density", 0.2, "Share of grants written as usersets where a relation accepts them")
+	wildcardDensity := flag.Float64("wildcard-density", 0, "Share of grants written as type:* where a relation accepts both")
+	seed := flag.Uint64("seed", 1, "Seed for tuple generation and sampling")
+	samples := flag.Int("samples", 200, "Timed calls per operation and relation")
+	subjectType := flag.String("subject-type", "", "Subject type to time (default: first type with no relations)")
+	ops := flag.String("ops", "check,list_objects,list_subjects", "Comma-separated operations to time")
+	skipLoad := flag.Bool("skip-load", false, "Time the tuples already in melange_tuples instead of regenerating them")
+	flag.Parse()
+
+	if *schemaPath == "" || *dsn == "" {
+		fmt.Fprintln(os.Stderr, "usage: synthbench -schema <file.fga> [-dsn <url>] [flags]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	cfg := config{
+		scale: testutil.SyntheticScale{
+			ObjectsPerType:  *objects,
+			FanOut:          *fanOut,
+			UsersetDensity:  *usersetDensity,
+			WildcardDensity: *wildcardDensity,
+			Seed:            *seed,
+		},
+		samples:     *samples,
+		subjectType: *subjectType,
+		ops:         strings.Split(*ops, ","),
+		skipLoad:    *skipLoad,
+	}
+	if err := run(context.Background(), *schemaPath, *dsn, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "synthbench: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// config holds the parsed flags run needs.
+type config struct {
+	scale       testutil.SyntheticScale
+	samples     int
+	subjectType string
+	ops         []string
+	skipLoad    bool
+}
+
+func run(ctx context.Context, schemaPath, dsn string, cfg config) error {
+	for _, op := range cfg.ops {
+		if _, ok := operations[op]; !ok {
+			return fmt.Errorf("unknown operation %q (want %s)", op, strings.Join(operationNames(), ", "))
+		}
+	}
+
+	src, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return fmt.Errorf("read schema: %w", err)
+	}
+	types, err := parser.ParseSchemaString(string(src))
+	if err != nil {
+		return fmt.Errorf("parse schema: %w", err)
+	}
+	if cfg.subjectType == "" {
+		cfg.subjectType = leafType(types)
+		if cfg.subjectType == "" {
+			return fmt.Errorf("schema has no type without relations; pass -subject-type")
+		}
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return fmt.Errorf("connect to database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping database: %w", err)
+	}
+
+	if err := migrator.NewMigrator(db, "").MigrateWithTypes(ctx, types); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	if !cfg.skipLoad {
+		start := time.Now()
+		n, err := loadTuples(ctx, db, types, cfg.scale)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("loaded %d tuples in %s\n\n", n, time.Since(start).Round(time.Millisecond))
+	}
+
+	closure := schema.ComputeRelationClosure(types)
+	analyses := compiler.ComputeCanGenerate(compiler.AnalyzeRelations(types, closure))
+
+	b := bench{db: db, cfg: cfg}
+	results, err := b.run(ctx, analyses)
+	if err != nil {
+		return err
+	}
+	printResults(os.Stdout, results)
+	return nil
+}
+
+// leafType returns the first type with no relations, the usual subject of a
+// model (user), or "" when every type has relations.
+func leafType(types []schema.TypeDefinition) string {
+	for _, td := range types {
+		if len(td.Relations) == 0 {
+			return td.Name
+		}
+	}
+	return ""
+}
+
+// loadTuples replaces the contents of melange_tuples with the tuples
+// generated for scale and returns how many it loaded.
+func loadTuples(ctx context.Context, db *sql.DB, types []schema.TypeDefinition, scale testutil.SyntheticScale) (int, error) {
+	var kind string
+	err := db.QueryRowContext(ctx, "SELECT relkind FROM pg_class WHERE oid = to_regclass('melange_tuples')").Scan(&kind)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := db.ExecContext(ctx, `
+			CREATE TABLE melange_tuples (
+				subject_type TEXT NOT NULL,
+				subject_id TEXT NOT NULL,
+				relation TEXT NOT NULL,
+				object_type TEXT NOT NULL,
+				object_id TEXT NOT NULL
+			)
+		`); err != nil {
+			return 0, fmt.Errorf("create melange_tuples: %w", err)
+		}
+	case err != nil:
+		return 0, fmt.Errorf("inspect melange_tuples: %w", err)
+	case kind != "r":
+		return 0, fmt.Errorf("melange_tuples is not a table; load into its source tables and pass -skip-load")
+	}
+
+	tuples := testutil.GenerateSyntheticTuples(types, scale)
+	if _, err := db.ExecContext(ctx, "TRUNCATE melange_tuples"); err != nil {
+		return 0, fmt.Errorf("truncate melange_tuples: %w", err)
+	}
+	if err := testutil.NewBulkFixtures(ctx, db).CopyTuples("melange_tuples", tuples); err != nil {
+		return 0, fmt.Errorf("load tuples: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "ANALYZE melange_tuples"); err != nil {
+		return 0, fmt.Errorf("analyze melange_tuples: %w", err)
+	}
+	return len(tuples), nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// printResults writes one row per timed operation and relation.
+func printResults(w io.Writer, results []result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(tw, "OP\tRELATION\tCALLS\tERRORS\tROWS\tP50\tP90\tP99\tMAX\t")
+	for _, r := range results {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n",
+			r.Op, r.Relation, r.Calls, r.Errors, r.MeanRows,
+			formatDuration(r.P50), formatDuration(r.P90), formatDuration(r.P99), formatDuration(r.Max))
+	}
+	_ = tw.Flush()
+}
+
+// formatDuration renders d in microseconds, the scale generated functions
+// answer in, or "-" when no call succeeded.
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0fµs", float64(d)/float64(time.Microsecond))
+}
@@ -114,6 +114,7 @@ func TestCodegen_DispatcherRouting(t *testing.T) {
 		FROM pg_proc p
 		JOIN pg_namespace n ON p.pronamespace = n.oid
 		WHERE p.proname = 'check_permission'
+		  AND p.pronargs = 5
 		  AND n.nspname = current_schema()
 		LIMIT 1
 	`).Scan(&publicDispatcherDef)
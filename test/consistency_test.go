@@ -0,0 +1,148 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestConsistency_ReadYourWrites verifies that strong reads skip the cache
+// and the replica, and that eventual reads go to the replica. The "replica"
+// here is an empty database, so any read routed to it fails.
+func TestConsistency_ReadYourWrites(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	primary := installAdHocSchema(t, ctx, `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define viewer: [user]
+`, "consistency")
+	replica := testutil.EmptyDB(t)
+
+	checker := melange.NewChecker(primary, melange.WithCache(melange.NewCache()), melange.WithReplica(replica))
+	alice := melange.Object{Type: "user", ID: "alice"}
+	doc := melange.Object{Type: "document", ID: "doc1"}
+	strong := melange.WithConsistency(ctx, melange.ConsistencyStrong)
+	eventual := melange.WithConsistency(ctx, melange.ConsistencyEventual)
+
+	// Unspecified reads the primary and caches the denial.
+	ok, err := checker.Check(ctx, alice, melange.Relation("viewer"), doc)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	insertTuple(t, ctx, primary, "user", "alice", "viewer", "document", "doc1")
+
+	ok, err = checker.Check(ctx, alice, melange.Relation("viewer"), doc)
+	require.NoError(t, err)
+	assert.False(t, ok, "unspecified reads may be served from the cache")
+
+	ok, err = checker.Check(strong, alice, melange.Relation("viewer"), doc)
+	require.NoError(t, err)
+	assert.True(t, ok, "strong reads must see the write")
+
+	ok, err = checker.Check(ctx, alice, melange.Relation("viewer"), doc)
+	require.NoError(t, err)
+	assert.True(t, ok, "strong reads refresh the cache")
+
+	_, err = checker.ListObjectsAll(eventual, alice, melange.Relation("viewer"), "document")
+	require.Error(t, err, "eventual reads must go to the replica")
+
+	ids, err := checker.ListObjectsAll(strong, alice, melange.Relation("viewer"), "document")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"doc1"}, ids)
+}
+
+// TestConsistency_ReplicaResultsNotCached verifies that a result read from
+// the replica does not answer later unspecified reads, which expect the
+// primary.
+func TestConsistency_ReplicaResultsNotCached(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	const model = `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define viewer: [user]
+`
+	primary := installAdHocSchema(t, ctx, model, "consistency-primary")
+	replica := installAdHocSchema(t, ctx, model, "consistency-replica")
+	insertTuple(t, ctx, primary, "user", "alice", "viewer", "document", "doc1")
+
+	checker := melange.NewChecker(primary, melange.WithCache(melange.NewCache()), melange.WithReplica(replica))
+	alice := melange.Object{Type: "user", ID: "alice"}
+	doc := melange.Object{Type: "document", ID: "doc1"}
+
+	// The replica has not seen the write yet.
+	ok, err := checker.Check(melange.WithConsistency(ctx, melange.ConsistencyEventual), alice, melange.Relation("viewer"), doc)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = checker.Check(ctx, alice, melange.Relation("viewer"), doc)
+	require.NoError(t, err)
+	assert.True(t, ok, "unspecified reads must not be served the replica's result")
+}
+
+// TestConsistency_SQLParameter verifies that the functions taking
+// p_consistency accept it and answer as without it.
+func TestConsistency_SQLParameter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	// Migrated rather than installed ad hoc, so that the schema hash
+	// overload of check_permission exists.
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err, "creating melange_tuples table")
+	_, err = db.ExecContext(ctx, `INSERT INTO melange_tuples VALUES ('user', 'alice', 'owner', 'document', '1')`)
+	require.NoError(t, err)
+	migrateSchema(t, ctx, migrator.NewMigrator(db, ""), evidenceSchema, migrator.InternalMigrateOptions{})
+
+	for _, consistency := range []any{"strong", "eventual", nil} {
+		var allowed int
+		require.NoError(t, db.QueryRowContext(ctx,
+			"SELECT check_permission('user', 'alice', 'viewer', 'document', '1', NULL, p_consistency => $1)", consistency,
+		).Scan(&allowed))
+		assert.Equal(t, 1, allowed, "check_permission with p_consistency %v", consistency)
+
+		var objectID string
+		require.NoError(t, db.QueryRowContext(ctx,
+			"SELECT object_id FROM list_accessible_objects('user', 'alice', 'viewer', 'document', p_consistency => $1)", consistency,
+		).Scan(&objectID))
+		assert.Equal(t, "1", objectID, "list_accessible_objects with p_consistency %v", consistency)
+
+		var subjectID string
+		require.NoError(t, db.QueryRowContext(ctx,
+			"SELECT subject_id FROM list_accessible_subjects('document', '1', 'owner', 'user', p_consistency => $1)", consistency,
+		).Scan(&subjectID))
+		assert.Equal(t, "alice", subjectID, "list_accessible_subjects with p_consistency %v", consistency)
+	}
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/lib/doctor"
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/lib/sqlgen/sqldsl"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestDoctor_FunctionMetadata verifies that migrated check and list functions
+// carry their generation metadata in pg_description, and that doctor flags a
+// function whose metadata no longer matches the schema analysis.
+func TestDoctor_FunctionMetadata(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := testutil.DB(t)
+	ctx := context.Background()
+
+	var name, comment string
+	require.NoError(t, db.QueryRowContext(ctx, `
+		SELECT p.proname, obj_description(p.oid, 'pg_proc')
+		FROM pg_proc p
+		JOIN pg_namespace n ON p.pronamespace = n.oid
+		WHERE n.nspname = current_schema()
+		AND p.proname LIKE 'list_%_obj'
+		AND obj_description(p.oid, 'pg_proc') LIKE 'melange: %'
+		ORDER BY 1
+		LIMIT 1`).Scan(&name, &comment))
+	md, ok := sqlgen.ParseFunctionMetadata(comment)
+	require.True(t, ok, "comment %q", comment)
+	assert.Equal(t, sqlgen.FunctionKindListObjects, md.Kind)
+	assert.NotEmpty(t, md.ListStrategy)
+
+	metadataCheck := func() *doctor.CheckResult {
+		report, err := doctor.New(db, "testutil/testdata/schema.fga").Run(ctx)
+		require.NoError(t, err)
+		check := findCheck(filterCategory(report, "Generated Functions"), "metadata")
+		require.NotNil(t, check, "expected a metadata check")
+		return check
+	}
+	assert.Equal(t, doctor.StatusPass, metadataCheck().Status)
+
+	// Simulate a deployment generated before the relation gained a feature.
+	md.Features = "None"
+	_, err := db.ExecContext(ctx, "COMMENT ON FUNCTION "+name+" IS "+sqldsl.QuoteLiteral(md.Comment()))
+	require.NoError(t, err)
+
+	check := metadataCheck()
+	assert.Equal(t, doctor.StatusWarn, check.Status)
+	assert.True(t, strings.HasPrefix(check.Details, name+": installed None"), check.Details)
+}
@@ -2,6 +2,8 @@ package test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -190,3 +192,74 @@ func TestDoctor_TableIndexes_PartialIndexRecognized(t *testing.T) {
 		}
 	}
 }
+
+// TestDoctor_TableIndexes_UsersetPartialRecognized verifies the userset
+// partial recommendation is emitted for a schema with [type#relation] grants
+// and is matched against the definition pg_indexes renders for it.
+func TestDoctor_TableIndexes_UsersetPartialRecognized(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := testutil.DB(t)
+	ctx := context.Background()
+
+	schemaPath := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(`model
+  schema 1.1
+type user
+type group
+  relations
+    define member: [user, group#member]
+type document
+  relations
+    define viewer: [user, group#member]
+`), 0o644))
+
+	t.Cleanup(func() { restoreView(t, db) })
+	_, err := db.ExecContext(ctx, `DROP VIEW IF EXISTS melange_tuples`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id   TEXT NOT NULL,
+			relation     TEXT NOT NULL,
+			object_type  TEXT NOT NULL,
+			object_id    TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(ctx, `DROP TABLE IF EXISTS melange_tuples`)
+	})
+
+	usersetMissing := func() []doctor.CheckResult {
+		t.Helper()
+		report, err := doctor.New(db, schemaPath).Run(ctx)
+		require.NoError(t, err)
+		var missing []doctor.CheckResult
+		for _, c := range filterCategory(report, "Performance") {
+			if c.Name == "table_indexes" && strings.Contains(c.FixHint, "WHERE position('#' in subject_id) > 0") {
+				missing = append(missing, c)
+			}
+		}
+		return missing
+	}
+
+	// One partial for the object-keyed userset lookups, one for the
+	// list_*_obj membership joins.
+	missing := usersetMissing()
+	require.Len(t, missing, 2, "expected both userset partial recommendations on an unindexed table")
+	details := missing[0].Details + "\n" + missing[1].Details
+	assert.Contains(t, details, "check_document_viewer")
+	assert.Contains(t, details, "list_group_member_sub")
+	assert.Contains(t, details, "list_document_viewer_obj")
+
+	// Apply the recommended DDL; pg_indexes renders the predicate as
+	// (POSITION(('#'::text) IN (subject_id)) > 0), which must still match.
+	for _, c := range missing {
+		_, err = db.ExecContext(ctx, c.FixHint)
+		require.NoError(t, err)
+	}
+	assert.Empty(t, usersetMissing(), "userset partial recommendations should be covered once applied")
+}
@@ -3,6 +3,8 @@ package test
 import (
 	"context"
 	"database/sql"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -11,6 +13,7 @@ import (
 
 	"github.com/pthm/melange/lib/doctor"
 	"github.com/pthm/melange/pkg/compiler"
+	"github.com/pthm/melange/pkg/migrator"
 	"github.com/pthm/melange/pkg/parser"
 	"github.com/pthm/melange/pkg/schema"
 	"github.com/pthm/melange/test/testutil"
@@ -578,6 +581,53 @@ func TestDoctor_OrphanedTuples_InvalidSubjectType(t *testing.T) {
 	assert.True(t, found, "should report invalid subject type assignments")
 }
 
+// TestDoctor_OrphanedTuples_UnmatchedSubject verifies that tuples whose
+// subject type is allowed, but not in the tuple's form, produce a warning
+// with an example tuple.
+func TestDoctor_OrphanedTuples_UnmatchedSubject(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := testutil.EmptyDB(t)
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type text, subject_id text, relation text,
+			object_type text, object_id text
+		)
+	`)
+	require.NoError(t, err)
+
+	// owner is [user], so user:* never matches it; banned is [user:*], so a
+	// concrete user never matches it.
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO melange_tuples VALUES
+		('user', '1', 'owner', 'organization', 'org1'),
+		('user', '*', 'owner', 'organization', 'org1'),
+		('user', '*', 'banned', 'repository', 'repo1'),
+		('user', '2', 'banned', 'repository', 'repo1')
+	`)
+	require.NoError(t, err)
+
+	d := doctor.New(db, "testutil/testdata/schema.fga")
+	report, err := d.Run(ctx)
+	require.NoError(t, err)
+
+	checks := filterCategory(report, "Data Health")
+	found := false
+	for _, c := range checks {
+		if c.Name == "unmatched_subjects" {
+			found = true
+			assert.Equal(t, doctor.StatusWarn, c.Status)
+			assert.Contains(t, c.Details, "organization:owner subject=user:* (1 tuples, e.g. organization:org1#owner@user:*)")
+			assert.Contains(t, c.Details, "repository:banned subject=user (1 tuples, e.g. repository:repo1#banned@user:2)")
+		}
+	}
+	assert.True(t, found, "should report subjects in a form their relation does not accept")
+}
+
 // TestDoctor_OrphanedTuples_Clean verifies that the fully migrated test database
 // with valid tuples passes all data health checks.
 func TestDoctor_OrphanedTuples_Clean(t *testing.T) {
@@ -669,6 +719,57 @@ func TestDoctor_CustomSchema(t *testing.T) {
 	assertCheck(t, perfChecks, "source_tables", doctor.StatusPass)
 }
 
+// TestDoctor_Baseline verifies that a manifest generated from the migrated
+// schema matches the installed functions exactly, and that an extra function
+// is reported as drift.
+func TestDoctor_Baseline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	databaseSchema := "melange"
+	db := testutil.DBWithDatabaseSchema(t, databaseSchema)
+	ctx := context.Background()
+
+	types, err := parser.ParseSchema("testutil/testdata/schema.fga")
+	require.NoError(t, err)
+	m := migrator.NewMigrator(db, "testutil/testdata/schema.fga")
+	m.SetDatabaseSchema(databaseSchema)
+	manifest, err := m.GenerateManifest(types)
+	require.NoError(t, err)
+
+	baselinePath := filepath.Join(t.TempDir(), "manifest.json")
+	f, err := os.Create(baselinePath)
+	require.NoError(t, err)
+	require.NoError(t, manifest.Write(f))
+	require.NoError(t, f.Close())
+
+	runBaseline := func() *doctor.CheckResult {
+		d := doctor.New(db, "testutil/testdata/schema.fga", doctor.Options{SkipPerformance: true, BaselinePath: baselinePath})
+		d.SetDatabaseSchema(databaseSchema)
+		report, err := d.Run(ctx)
+		require.NoError(t, err)
+		check := findCheck(filterCategory(report, "Baseline"), "drift")
+		require.NotNil(t, check, "should have a baseline drift check")
+		return check
+	}
+
+	check := runBaseline()
+	assert.Equal(t, doctor.StatusPass, check.Status, check.Details)
+
+	// Clean up for shared remote DB in CI.
+	t.Cleanup(func() {
+		_, _ = db.ExecContext(ctx, `DROP FUNCTION IF EXISTS melange.check_baseline_extra(TEXT)`)
+	})
+	_, err = db.ExecContext(ctx, `CREATE FUNCTION melange.check_baseline_extra(p_id TEXT) RETURNS INTEGER AS $$ SELECT 1 $$ LANGUAGE sql`)
+	require.NoError(t, err)
+
+	check = runBaseline()
+	assert.Equal(t, doctor.StatusFail, check.Status)
+	assert.Contains(t, check.Message, "1 added, 0 removed, 0 changed")
+	assert.Contains(t, check.Details, "check_baseline_extra(text)")
+}
+
 // restoreIndexes re-creates expression indexes that doctor tests may have dropped.
 func restoreIndexes(t *testing.T, db *sql.DB) {
 	t.Helper()
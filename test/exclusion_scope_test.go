@@ -0,0 +1,114 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// TestExclusionScope checks both parenthesizations of a union with an
+// exclusion against their OpenFGA truth tables. OpenFGA rejects the
+// unparenthesized "a or b but not c", so the parentheses alone decide whether
+// c excludes only b or the whole union.
+func TestExclusionScope(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name     string
+		relation string
+		truth    func(a, b, c bool) bool
+	}{
+		{"a or (b but not c)", "scoped", func(a, b, c bool) bool { return a || (b && !c) }},
+		{"(a or b) but not c", "whole", func(a, b, c bool) bool { return (a || b) && !c }},
+		{"[user] or (b but not c)", "direct", func(a, b, c bool) bool { return a || (b && !c) }},
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type doc
+  relations
+    define a: [user]
+    define b: [user]
+    define c: [user]
+    define scoped: a or (b but not c)
+    define whole: (a or b) but not c
+    define direct: [user] or (b but not c)
+    define parent: [doc]
+    define scoped_parent: a or (b from parent but not c)
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "exclusion-scope")
+
+	// One user per row of the truth table, named after the relations it
+	// holds on doc:1. For "direct", holding a means a direct tuple.
+	type row struct {
+		id      string
+		a, b, c bool
+	}
+	var rows []row
+	for i := range 8 {
+		r := row{id: "u", a: i&4 != 0, b: i&2 != 0, c: i&1 != 0}
+		var held []string
+		for _, rel := range []struct {
+			on   bool
+			name string
+		}{{r.a, "a"}, {r.b, "b"}, {r.c, "c"}} {
+			if rel.on {
+				r.id += "_" + rel.name
+				held = append(held, rel.name)
+			}
+		}
+		if r.a {
+			held = append(held, "direct")
+		}
+		for _, rel := range held {
+			insertTuple(t, ctx, db, "user", r.id, rel, "doc", "1")
+		}
+		rows = append(rows, r)
+	}
+
+	checker := melange.NewChecker(db)
+	doc := melange.Object{Type: "doc", ID: "1"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []string
+			for _, r := range rows {
+				expected := tt.truth(r.a, r.b, r.c)
+				if expected {
+					want = append(want, r.id)
+				}
+
+				ok, err := checker.Check(ctx, melange.Object{Type: "user", ID: r.id}, melange.Relation(tt.relation), doc)
+				require.NoError(t, err)
+				assert.Equal(t, expected, ok, "check %s for %s", tt.relation, r.id)
+
+				objects := listObjects(t, db, "user", r.id, tt.relation, "doc")
+				if expected {
+					assert.Equal(t, []string{"1"}, objects, "list_objects %s for %s", tt.relation, r.id)
+				} else {
+					assert.Empty(t, objects, "list_objects %s for %s", tt.relation, r.id)
+				}
+			}
+			assert.ElementsMatch(t, want, listSubjects(t, db, "doc", "1", tt.relation, "user"), "list_subjects %s", tt.relation)
+		})
+	}
+
+	// An exclusion on a tuple-to-userset base cannot be scoped to it. The
+	// relation is left ungenerated rather than generated as
+	// "(a or b from parent) but not c", which would deny u_a_c.
+	t.Run("a or (b from parent but not c)", func(t *testing.T) {
+		var n int
+		require.NoError(t, db.QueryRowContext(ctx,
+			"SELECT count(*) FROM pg_proc WHERE proname = 'check_doc_scoped_parent'").Scan(&n))
+		assert.Zero(t, n, "check_doc_scoped_parent generated for an exclusion it cannot scope")
+	})
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// TestExclusionOfTTU checks that check, list_objects and list_subjects agree
+// when "but not" excludes a tuple-to-userset, written inline or through a
+// relation that is itself one.
+func TestExclusionOfTTU(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type org
+  relations
+    define banned: [user]
+
+type doc
+  relations
+    define org: [org]
+    define editor: [user]
+    define banned_from_parent: banned from org
+    define can_view: editor but not banned_from_parent
+    define can_edit: editor but not (banned from org)
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "exclusion-ttu")
+
+	insertTuple(t, ctx, db, "org", "acme", "org", "doc", "1")
+	for _, id := range []string{"alice", "bob"} {
+		insertTuple(t, ctx, db, "user", id, "editor", "doc", "1")
+	}
+	insertTuple(t, ctx, db, "user", "bob", "banned", "org", "acme")
+	insertTuple(t, ctx, db, "user", "carol", "banned", "org", "acme")
+
+	checker := melange.NewChecker(db)
+	doc := melange.Object{Type: "doc", ID: "1"}
+	for _, relation := range []string{"can_view", "can_edit"} {
+		t.Run(relation, func(t *testing.T) {
+			for id, want := range map[string]bool{"alice": true, "bob": false, "carol": false} {
+				ok, err := checker.Check(ctx, melange.Object{Type: "user", ID: id}, melange.Relation(relation), doc)
+				require.NoError(t, err)
+				assert.Equal(t, want, ok, "check %s for %s", relation, id)
+
+				objects := listObjects(t, db, "user", id, relation, "doc")
+				if want {
+					assert.Equal(t, []string{"1"}, objects, "list_objects %s for %s", relation, id)
+				} else {
+					assert.Empty(t, objects, "list_objects %s for %s", relation, id)
+				}
+			}
+			assert.Equal(t, []string{"alice"}, listSubjects(t, db, "doc", "1", relation, "user"), "list_subjects %s", relation)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// TestExclusionWildcardBase checks exclusions whose base is a user:* grant. The
+// wildcard tuple matches every user, so the exclusion must be evaluated for
+// the querying user, not for the tuple's "*": a banned user who would
+// otherwise match user:* is denied, and everyone else is allowed.
+func TestExclusionWildcardBase(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type doc
+  relations
+    define banned: [user, group#member]
+    define public: [user:*]
+    define direct: [user:*] but not banned
+    define implied: public but not banned
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "exclusion-wildcard")
+
+	insertTuple(t, ctx, db, "user", "*", "direct", "doc", "1")
+	insertTuple(t, ctx, db, "user", "*", "public", "doc", "1")
+	insertTuple(t, ctx, db, "user", "bob", "banned", "doc", "1")
+	insertTuple(t, ctx, db, "user", "carol", "member", "group", "eng")
+	insertTuple(t, ctx, db, "group", "eng#member", "banned", "doc", "1")
+	// Expanding user:* lists the users tuples name, so give alice one.
+	insertTuple(t, ctx, db, "user", "alice", "member", "group", "ops")
+
+	checker := melange.NewChecker(db)
+	doc := melange.Object{Type: "doc", ID: "1"}
+	for _, rel := range []string{"direct", "implied"} {
+		t.Run(rel, func(t *testing.T) {
+			for user, want := range map[string]bool{
+				"alice": true,  // matches user:* only
+				"bob":   false, // banned directly
+				"carol": false, // banned through group:eng#member
+			} {
+				ok, err := checker.Check(ctx, melange.Object{Type: "user", ID: user}, melange.Relation(rel), doc)
+				require.NoError(t, err)
+				assert.Equal(t, want, ok, "check %s for %s", rel, user)
+
+				objects := listObjects(t, db, "user", user, rel, "doc")
+				if want {
+					assert.Equal(t, []string{"1"}, objects, "list_objects %s for %s", rel, user)
+				} else {
+					assert.Empty(t, objects, "list_objects %s for %s", rel, user)
+				}
+			}
+		})
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT subject_id FROM list_accessible_subjects('doc', '1', 'direct', 'user', p_expand_wildcard => TRUE)`)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	var expanded []string
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		expanded = append(expanded, id)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"alice"}, expanded, "expanded list_subjects leaves out banned users")
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// TestExplainPlan_CheckPermission captures the plan of a check in both
+// formats and checks the toggles reach EXPLAIN.
+func TestExplainPlan_CheckPermission(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define viewer: [user]
+`, "explain-plan")
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "1")
+
+	checker := melange.NewChecker(db)
+	alice := melange.Object{Type: "user", ID: "alice"}
+	doc := melange.Object{Type: "document", ID: "1"}
+
+	plan, err := checker.ExplainPlan(ctx, alice, melange.Relation("viewer"), doc, melange.PlanOptions{Buffers: true, Timing: true})
+	require.NoError(t, err)
+	assert.Contains(t, plan, "actual time=")
+	assert.Contains(t, plan, "Execution Time:")
+
+	plan, err = checker.ExplainPlan(ctx, alice, melange.Relation("viewer"), doc, melange.PlanOptions{Format: melange.PlanFormatJSON, Settings: true, WAL: true})
+	require.NoError(t, err)
+	var result []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(plan), &result), "JSON plan must decode")
+	require.Len(t, result, 1)
+	assert.Contains(t, result[0], "Plan")
+	assert.Contains(t, result[0], "Execution Time")
+}
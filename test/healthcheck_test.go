@@ -0,0 +1,68 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// healthcheck is the JSONB object melange_healthcheck returns.
+type healthcheck struct {
+	TuplesTableExists     bool   `json:"tuples_table_exists"`
+	FunctionCount         int    `json:"function_count"`
+	ExpectedFunctionCount int    `json:"expected_function_count"`
+	SchemaHash            string `json:"schema_hash"`
+}
+
+// TestHealthcheck_ReportsMigratedState verifies that melange_healthcheck
+// reports the migrated schema hash, finds every function the migration
+// installed, and notices when the tuples relation or a function goes missing.
+func TestHealthcheck_ReportsMigratedState(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err, "creating melange_tuples table")
+	migrateSchema(t, ctx, migrator.NewMigrator(db, ""), evidenceSchema, migrator.InternalMigrateOptions{})
+
+	read := func() healthcheck {
+		t.Helper()
+		var raw []byte
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT melange_healthcheck()").Scan(&raw))
+		var hc healthcheck
+		require.NoError(t, json.Unmarshal(raw, &hc))
+		return hc
+	}
+
+	hc := read()
+	assert.True(t, hc.TuplesTableExists)
+	assert.Equal(t, schemaHash(t, evidenceSchema), hc.SchemaHash)
+	assert.Positive(t, hc.ExpectedFunctionCount)
+	assert.Equal(t, hc.ExpectedFunctionCount, hc.FunctionCount, "every generated function is installed")
+
+	_, err = db.ExecContext(ctx, "DROP FUNCTION melange_model_relations")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "DROP TABLE melange_tuples")
+	require.NoError(t, err)
+
+	hc = read()
+	assert.False(t, hc.TuplesTableExists)
+	assert.Equal(t, hc.ExpectedFunctionCount-1, hc.FunctionCount)
+}
@@ -0,0 +1,166 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestMigrationHistory checks that each applied migration is recorded with
+// its schema path and returned most recent first, by GetHistory and in
+// Status.History.
+func TestMigrationHistory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	db := testutil.EmptyDB(t)
+
+	dir := t.TempDir()
+	v1 := filepath.Join(dir, "v1.fga")
+	v2 := filepath.Join(dir, "v2.fga")
+	require.NoError(t, os.WriteFile(v1, []byte(schemaV1), 0o644))
+	require.NoError(t, os.WriteFile(v2, []byte(schemaV2), 0o644))
+
+	m := migrator.NewMigrator(db, v2)
+	history, err := m.GetHistory(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, history, "no history before the first migration")
+
+	_, err = migrator.MigrateWithOptions(ctx, db, v1, migrator.MigrateOptions{})
+	require.NoError(t, err)
+	_, err = migrator.MigrateWithOptions(ctx, db, v2, migrator.MigrateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, migrator.MigrateFromString(ctx, db, schemaV3))
+
+	history, err = m.GetHistory(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "", history[0].SchemaPath, "string schemas have no path")
+	assert.Equal(t, v2, history[1].SchemaPath)
+	assert.Equal(t, v1, history[2].SchemaPath)
+	assert.NotEqual(t, history[1].SchemaChecksum, history[2].SchemaChecksum)
+	assert.False(t, history[0].MigratedAt.Before(history[2].MigratedAt))
+
+	limited, err := m.GetHistory(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, history[:1], limited)
+
+	status, err := m.GetStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, history, status.History)
+}
+
+// TestMigrationAdvisoryLock checks that MigrateWithOptions waits for a
+// concurrent holder of AdvisoryLockKey before applying.
+func TestMigrationAdvisoryLock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	db := testutil.EmptyDB(t)
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte(schemaV1), 0o644))
+
+	holder, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = holder.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrator.AdvisoryLockKey)
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := migrator.MigrateWithOptions(ctx, db, path, migrator.MigrateOptions{})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("migration finished while the lock was held: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	require.NoError(t, holder.Rollback())
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(30 * time.Second):
+		t.Fatal("migration did not finish after the lock was released")
+	}
+}
+
+// TestMigrationStatementTimeout checks that a migration blocked behind a lock
+// fails once StatementTimeout or the context runs out, and rolls back without
+// keeping the advisory lock.
+func TestMigrationStatementTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	db := testutil.EmptyDB(t)
+
+	dir := t.TempDir()
+	v1 := filepath.Join(dir, "v1.fga")
+	v2 := filepath.Join(dir, "v2.fga")
+	require.NoError(t, os.WriteFile(v1, []byte(schemaV1), 0o644))
+	require.NoError(t, os.WriteFile(v2, []byte(schemaV2), 0o644))
+	_, err := migrator.MigrateWithOptions(ctx, db, v1, migrator.MigrateOptions{})
+	require.NoError(t, err)
+
+	// Reading the last migration passes this lock, but recording the new one
+	// waits for it until the holder ends.
+	block := func(t *testing.T) {
+		t.Helper()
+		holder, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		_, err = holder.ExecContext(ctx, "LOCK TABLE melange_migrations IN SHARE MODE")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = holder.Rollback() })
+	}
+
+	assertRolledBack := func(t *testing.T) {
+		t.Helper()
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		var locked bool
+		require.NoError(t, conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", migrator.AdvisoryLockKey).Scan(&locked))
+		assert.True(t, locked, "advisory lock should be released")
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrator.AdvisoryLockKey)
+		require.NoError(t, err)
+	}
+
+	t.Run("statement timeout", func(t *testing.T) {
+		block(t)
+		start := time.Now()
+		_, err := migrator.MigrateWithOptions(ctx, db, v2, migrator.MigrateOptions{
+			StatementTimeout: 200 * time.Millisecond,
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "statement timeout")
+		assert.Less(t, time.Since(start), 10*time.Second)
+		assertRolledBack(t)
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		block(t)
+		cctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		_, err := migrator.MigrateWithOptions(cctx, db, v2, migrator.MigrateOptions{})
+		require.Error(t, err)
+		assertRolledBack(t)
+	})
+
+	history, err := migrator.NewMigrator(db, v1).GetHistory(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1, "neither blocked migration should be recorded")
+	assert.Equal(t, v1, history[0].SchemaPath)
+}
@@ -146,10 +146,13 @@ type document
 				if rec.WhereClause == "" {
 					continue
 				}
-				wantSnippet := "WHERE (subject_id = '*'::text)"
+				wantSnippet := "where (subject_id = '*'::text)"
+				if strings.Contains(rec.WhereClause, "#") {
+					wantSnippet = "where (position("
+				}
 				found := false
 				for _, g := range got {
-					if strings.Contains(g, wantSnippet) {
+					if strings.Contains(strings.ToLower(g), wantSnippet) {
 						found = true
 						break
 					}
@@ -199,8 +202,9 @@ type document
 
 // TestRecommendIndexes_PlannerPicksThem creates each recommended index against
 // a populated test table, runs the access patterns generated SQL actually uses
-// (object-keyed check, subject-keyed list_objects, wildcard partial lookup),
-// and asserts via EXPLAIN that PG picks the index rather than seq-scanning.
+// (object-keyed check, subject-keyed list_objects, wildcard and userset partial
+// lookups), and asserts via EXPLAIN that PG picks the index rather than
+// seq-scanning.
 //
 // This is the strongest evidence that the recommendations are right:
 // syntactically valid + planner-recognized + chosen over a sequential scan.
@@ -209,8 +213,8 @@ func TestRecommendIndexes_PlannerPicksThem(t *testing.T) {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	// A schema covering the three recommendation families: direct, wildcard,
-	// userset (drives list_*_obj subject-keyed access).
+	// A schema covering the recommendation families: direct, wildcard,
+	// userset (drives list_*_obj subject-keyed access and the userset partial).
 	model := `model
   schema 1.1
 type user
@@ -255,7 +259,7 @@ type document
 		FROM generate_series(1, 10000) AS n
 	`)
 	require.NoError(t, err)
-	_, err = db.ExecContext(ctx, `INSERT INTO `+tableName+` (subject_type, subject_id, relation, object_type, object_id) VALUES ('user', '*', 'public', 'document', 'd1')`)
+	_, err = db.ExecContext(ctx, `INSERT INTO `+tableName+` (subject_type, subject_id, relation, object_type, object_id) VALUES ('user', '*', 'public', 'document', 'd1'), ('group', 'g1#member', 'viewer', 'document', 'd2')`)
 	require.NoError(t, err)
 	_, err = db.ExecContext(ctx, "ANALYZE "+tableName)
 	require.NoError(t, err)
@@ -290,11 +294,15 @@ type document
 
 	// Look up recommendations by shape so the test stays decoupled from
 	// recommendation ordering.
-	var objKeyedRec, subjKeyedRec, wildcardRec *sqlgen.IndexRecommendation
+	var objKeyedRec, subjKeyedRec, wildcardRec, usersetRec, usersetListRec *sqlgen.IndexRecommendation
 	for i := range recs {
 		switch {
-		case recs[i].WhereClause != "":
+		case recs[i].WhereClause == "subject_id = '*'":
 			wildcardRec = &recs[i]
+		case recs[i].WhereClause != "" && recs[i].Columns[1] == "relation":
+			usersetListRec = &recs[i]
+		case recs[i].WhereClause != "":
+			usersetRec = &recs[i]
 		case len(recs[i].Columns) > 0 && recs[i].Columns[0] == "object_type":
 			objKeyedRec = &recs[i]
 		case len(recs[i].Columns) > 0 && recs[i].Columns[0] == "subject_type":
@@ -304,6 +312,8 @@ type document
 	require.NotNil(t, objKeyedRec, "missing object-keyed recommendation")
 	require.NotNil(t, subjKeyedRec, "missing subject-keyed recommendation")
 	require.NotNil(t, wildcardRec, "missing wildcard recommendation")
+	require.NotNil(t, usersetRec, "missing userset recommendation")
+	require.NotNil(t, usersetListRec, "missing list_objects userset recommendation")
 
 	rewriteDDL := func(rec *sqlgen.IndexRecommendation) string {
 		ddl := strings.ReplaceAll(rec.DDL, "ON melange_tuples ", "ON "+tableName+" ")
@@ -326,6 +336,16 @@ type document
 			query:    `SELECT 1 FROM ` + tableName + ` WHERE object_type='document' AND object_id='d1' AND relation='public' AND subject_id='*' LIMIT 1`,
 			minIndex: "idx_" + short + "_by_object_type_object_id_wildcard",
 		},
+		{
+			name:     "userset_partial_alone",
+			query:    `SELECT split_part(subject_id, '#', 1) FROM ` + tableName + ` WHERE object_type='document' AND object_id='d2' AND relation='viewer' AND subject_type='group' AND position('#' in subject_id) > 0`,
+			minIndex: "idx_" + short + "_by_object_type_object_id_userset",
+		},
+		{
+			name:     "userset_list_objects_partial_alone",
+			query:    `SELECT DISTINCT object_id FROM ` + tableName + ` WHERE object_type='document' AND relation='viewer' AND subject_type='group' AND position('#' in subject_id) > 0 AND split_part(subject_id, '#', 2) = 'member'`,
+			minIndex: "idx_" + short + "_by_object_type_relation_userset",
+		},
 	}
 
 	for _, tc := range cases {
@@ -343,6 +363,10 @@ type document
 				rec = subjKeyedRec
 			case "wildcard_partial_alone":
 				rec = wildcardRec
+			case "userset_partial_alone":
+				rec = usersetRec
+			case "userset_list_objects_partial_alone":
+				rec = usersetListRec
 			}
 			_, err := db.ExecContext(ctx, rewriteDDL(rec))
 			require.NoError(t, err)
@@ -3,6 +3,7 @@ package test
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -79,13 +80,19 @@ func TestMigrator_GetStatus(t *testing.T) {
 		db := testutil.DBWithDatabaseSchema(t, databaseSchema)
 		ctx := context.Background()
 
-		m := migrator.NewMigrator(db, "testdata")
+		m := migrator.NewMigrator(db, "testutil/testdata/schema.fga")
 		m.SetDatabaseSchema(databaseSchema)
 		status, err := m.GetStatus(ctx)
 		require.NoError(t, err)
 
 		// Template database has tuples relation
 		assert.True(t, status.TuplesExists, "melange_tuples should exist")
+
+		// Template database was migrated from the same schema, so every
+		// expected function is installed and current.
+		assert.Positive(t, status.FunctionsInstalled)
+		assert.Empty(t, status.FunctionsMissing, "no generated function should be missing")
+		assert.Empty(t, status.FunctionsStale, "no generated function should be stale")
 	})
 }
 
@@ -441,6 +448,20 @@ func TestListSubjects(t *testing.T) {
 			assert.Len(t, ids, 1, "should find 1 user with can_delete (owner only)")
 			assert.Contains(t, ids, idStr(user1ID))
 		})
+
+		t.Run("type-only filter with trailing hash", func(t *testing.T) {
+			// "user#" names no relation, so it must behave like the bare
+			// type rather than emitting malformed "id#" usersets.
+			ids, err := checker.ListSubjectsAll(ctx, org, authz.RelCanRead, melange.ObjectType("user#"))
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{idStr(user1ID), idStr(user2ID), idStr(user3ID)}, ids)
+
+			ids, err = checker.ListSubjectsAll(ctx, org, authz.RelCanRead, melange.ObjectType("organization#"))
+			require.NoError(t, err)
+			for _, id := range ids {
+				assert.False(t, strings.HasSuffix(id, "#"), "malformed subject %q", id)
+			}
+		})
 	})
 }
 
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestListConditions_AgreeWithCheck lists a relation with a conditional
+// grant under several contexts and checks each result against
+// check_permission_with_context for the same context.
+func TestListConditions_AgreeWithCheck(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define owner: [user]
+    define viewer: [user with in_region] or owner
+
+condition in_region(region: string) {
+  region == "eu"
+}
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "list-conditions")
+
+	insertTuple(t, ctx, db, "user", "alice", "owner", "document", "owned")
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "conditional")
+	insertTuple(t, ctx, db, "user", "bob", "viewer", "document", "other")
+
+	query := func(q string, args ...any) []string {
+		t.Helper()
+		rows, err := db.QueryContext(ctx, q, args...)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		var ids []string
+		for rows.Next() {
+			var id string
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		return ids
+	}
+
+	for _, tc := range []struct {
+		name     string
+		context  sql.NullString
+		objects  []string
+		subjects []string
+	}{
+		{name: "no context", objects: []string{"owned"}, subjects: nil},
+		{name: "condition holds", context: sql.NullString{String: `{"region": "eu"}`, Valid: true}, objects: []string{"conditional", "owned"}, subjects: []string{"alice"}},
+		{name: "condition fails", context: sql.NullString{String: `{"region": "us"}`, Valid: true}, objects: []string{"owned"}, subjects: nil},
+		{name: "parameter missing", context: sql.NullString{String: `{}`, Valid: true}, objects: []string{"owned"}, subjects: nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := query(`SELECT object_id FROM list_accessible_objects('user', 'alice', 'viewer', 'document', p_context => $1::JSONB) ORDER BY object_id`, tc.context)
+			assert.Equal(t, tc.objects, objects)
+
+			subjects := query(`SELECT subject_id FROM list_accessible_subjects('document', 'conditional', 'viewer', 'user', p_context => $1::JSONB) ORDER BY subject_id`, tc.context)
+			assert.Equal(t, tc.subjects, subjects)
+
+			// Every document alice could see under some context is listed
+			// exactly when check_permission_with_context allows it.
+			for _, id := range []string{"owned", "conditional", "other"} {
+				var allowed int
+				require.NoError(t, db.QueryRowContext(ctx,
+					`SELECT check_permission_with_context('user', 'alice', 'viewer', 'document', $1, COALESCE($2::JSONB, '{}'))`,
+					id, tc.context).Scan(&allowed))
+				assert.Equal(t, allowed == 1, slices.Contains(objects, id), "document %s", id)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestListObjectsAny_ViewerOrEditor lists the documents a user can view or
+// edit, through a direct grant, an implied relation and a team, and pages
+// through the combined set.
+func TestListObjectsAny_ViewerOrEditor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type team
+  relations
+    define member: [user]
+
+type document
+  relations
+    define owner: [user]
+    define editor: [user] or owner
+    define viewer: [user, team#member]
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "list-objects-any")
+
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "a_viewed")
+	insertTuple(t, ctx, db, "user", "alice", "editor", "document", "b_edited")
+	insertTuple(t, ctx, db, "user", "alice", "owner", "document", "c_owned")
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "d_both")
+	insertTuple(t, ctx, db, "user", "alice", "editor", "document", "d_both")
+	insertTuple(t, ctx, db, "user", "alice", "member", "team", "eng")
+	insertTuple(t, ctx, db, "team", "eng#member", "viewer", "document", "e_team")
+	insertTuple(t, ctx, db, "user", "bob", "viewer", "document", "f_other")
+
+	listAny := func(relations string, limit, after any) (ids, cursors []string) {
+		t.Helper()
+		rows, err := db.QueryContext(ctx,
+			`SELECT object_id, coalesce(next_cursor, '') FROM list_accessible_objects_any('user', 'alice', $1::text[], 'document', $2, $3)`,
+			relations, limit, after)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var id, cursor string
+			require.NoError(t, rows.Scan(&id, &cursor))
+			ids = append(ids, id)
+			cursors = append(cursors, cursor)
+		}
+		require.NoError(t, rows.Err())
+		return ids, cursors
+	}
+
+	all := []string{"a_viewed", "b_edited", "c_owned", "d_both", "e_team"}
+	ids, _ := listAny("{viewer,editor}", nil, nil)
+	assert.Equal(t, all, ids, "an object granted by both relations is listed once")
+
+	ids, _ = listAny("{editor}", nil, nil)
+	assert.Equal(t, []string{"b_edited", "c_owned", "d_both"}, ids)
+
+	ids, _ = listAny("{}", nil, nil)
+	assert.Empty(t, ids)
+
+	// Pages walk the combined set in object_id order.
+	var paged []string
+	var after any
+	for range len(all) {
+		ids, cursors := listAny("{viewer,editor}", 2, after)
+		paged = append(paged, ids...)
+		if cursors[len(cursors)-1] == "" {
+			break
+		}
+		after = cursors[len(cursors)-1]
+	}
+	assert.Equal(t, all, paged)
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/test/testutil"
+)
+
+const anytimeSchema = `
+model
+  schema 1.1
+
+type user
+
+type folder
+  relations
+    define parent: [folder]
+    define owner: [user]
+    define viewer: [user] or viewer from parent
+`
+
+// installAnytimeSchema installs anytimeSchema over a plain melange_tuples
+// table, with or without AnytimeListObjects, and seeds a folder tree where
+// alice is granted two folders directly and reaches four more through parent
+// links. Ids are chosen so that sorting by id would interleave the two groups.
+func installAnytimeSchema(t *testing.T, ctx context.Context, anytime bool) *sql.DB {
+	t.Helper()
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err, "creating melange_tuples table")
+
+	types, err := parser.ParseSchemaString(anytimeSchema)
+	require.NoError(t, err)
+	require.NoError(t, migrator.NewMigrator(db, "").MigrateWithTypesAndOptions(ctx, types, migrator.InternalMigrateOptions{
+		SchemaContent:      anytimeSchema,
+		AnytimeListObjects: anytime,
+	}))
+
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "folder", "m-root")
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "folder", "z-shared")
+	insertTuple(t, ctx, db, "folder", "m-root", "parent", "folder", "a-child")
+	insertTuple(t, ctx, db, "folder", "a-child", "parent", "folder", "b-grandchild")
+	insertTuple(t, ctx, db, "folder", "z-shared", "parent", "folder", "c-child")
+	insertTuple(t, ctx, db, "folder", "c-child", "parent", "folder", "y-grandchild")
+	// Reached both directly and through m-root: returned once, early.
+	insertTuple(t, ctx, db, "folder", "m-root", "parent", "folder", "z-shared")
+	return db
+}
+
+func listFolderViewerObjects(t *testing.T, ctx context.Context, db *sql.DB, limit any) []string {
+	t.Helper()
+	rows, err := db.QueryContext(ctx,
+		`SELECT object_id FROM list_folder_viewer_obj('user', 'alice', $1::INT)`, limit)
+	require.NoError(t, err)
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	return ids
+}
+
+// TestAnytimeListObjects_SameSetDirectFirst verifies that the option changes
+// only the order of an unpaged list_objects result: the same ids, without
+// repeats, with the direct grants first.
+func TestAnytimeListObjects_SameSetDirectFirst(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	plain := listFolderViewerObjects(t, ctx, installAnytimeSchema(t, ctx, false), nil)
+	db := installAnytimeSchema(t, ctx, true)
+	anytime := listFolderViewerObjects(t, ctx, db, nil)
+
+	assert.ElementsMatch(t, []string{"m-root", "z-shared", "a-child", "b-grandchild", "c-child", "y-grandchild"}, plain)
+	assert.ElementsMatch(t, plain, anytime, "the complete set must be unchanged")
+	require.Len(t, anytime, 6, "no id may repeat")
+	assert.ElementsMatch(t, []string{"m-root", "z-shared"}, anytime[:2], "direct grants must come first")
+
+	// Paged calls keep ordering by object_id.
+	assert.Equal(t, []string{"a-child", "b-grandchild", "c-child"}, listFolderViewerObjects(t, ctx, db, 3))
+}
+
+// TestAnytimeListObjects_ExcludeRelation verifies that an unpaged call, which
+// takes the direct-grants-first branch, drops the p_exclude_relation objects
+// as paged calls do.
+func TestAnytimeListObjects_ExcludeRelation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	db := installAnytimeSchema(t, ctx, true)
+	// One directly granted folder and one reached through recursion.
+	insertTuple(t, ctx, db, "user", "alice", "owner", "folder", "z-shared")
+	insertTuple(t, ctx, db, "user", "alice", "owner", "folder", "b-grandchild")
+
+	listExcluding := func(limit any) []string {
+		t.Helper()
+		rows, err := db.QueryContext(ctx,
+			`SELECT object_id FROM list_folder_viewer_obj('user', 'alice', $1::INT, p_exclude_relation => 'owner')`, limit)
+		require.NoError(t, err)
+		defer rows.Close()
+		var ids []string
+		for rows.Next() {
+			var id string
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		return ids
+	}
+
+	unpaged := listExcluding(nil)
+	assert.ElementsMatch(t, []string{"m-root", "a-child", "c-child", "y-grandchild"}, unpaged)
+	require.NotEmpty(t, unpaged)
+	assert.Equal(t, "m-root", unpaged[0], "the remaining direct grant must come first")
+	assert.Equal(t, []string{"a-child", "c-child", "m-root", "y-grandchild"}, listExcluding(10))
+}
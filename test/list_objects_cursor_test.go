@@ -0,0 +1,113 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestListObjectsCursor migrates with EnableListObjectsCursor and fetches the
+// cursor functions in batches, checking that the batches together hold the
+// rows of an unpaged list_accessible_objects call: distinct and ordered.
+func TestListObjectsCursor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte(`model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type document
+  relations
+    define editor: [user, group#member]
+    define viewer: [user] or editor
+`), 0o644))
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		);
+		INSERT INTO melange_tuples VALUES
+			('user', 'alice', 'member', 'group', 'eng'),
+			-- alice reaches d3 directly, as an editor and through eng.
+			('user', 'alice', 'viewer', 'document', 'd3'),
+			('user', 'alice', 'editor', 'document', 'd3'),
+			('group', 'eng#member', 'editor', 'document', 'd3'),
+			('group', 'eng#member', 'editor', 'document', 'd1'),
+			('user', 'alice', 'viewer', 'document', 'd5'),
+			('user', 'alice', 'editor', 'document', 'd2'),
+			('user', 'bob', 'viewer', 'document', 'd4')`)
+	require.NoError(t, err)
+
+	_, err = migrator.MigrateWithOptions(ctx, db, path, migrator.MigrateOptions{EnableListObjectsCursor: true})
+	require.NoError(t, err)
+
+	// fetchAll opens the cursor with call and fetches it two rows at a time.
+	fetchAll := func(call string) [][]string {
+		t.Helper()
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback() }()
+
+		var cursor string
+		require.NoError(t, tx.QueryRowContext(ctx, call).Scan(&cursor))
+		fetch := `FETCH 2 FROM "` + strings.ReplaceAll(cursor, `"`, `""`) + `"`
+		var batches [][]string
+		for {
+			batch := queryIDs(t, ctx, tx, fetch)
+			if len(batch) == 0 {
+				return batches
+			}
+			batches = append(batches, batch)
+		}
+	}
+
+	want := [][]string{{"d1", "d2"}, {"d3", "d5"}}
+	assert.Equal(t, want, fetchAll(`SELECT list_document_viewer_objects_cursor('user', 'alice')`))
+	assert.Equal(t, want, fetchAll(`SELECT list_accessible_objects_cursor('user', 'alice', 'viewer', 'document')`))
+	assert.Empty(t, fetchAll(`SELECT list_accessible_objects_cursor('user', 'carol', 'viewer', 'document')`))
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+	assert.Equal(t, []string{"d1", "d2", "d3", "d5"},
+		queryIDs(t, ctx, tx, `SELECT object_id FROM list_accessible_objects('user', 'alice', 'viewer', 'document')`))
+}
+
+// queryIDs returns the single text column of every row query returns.
+func queryIDs(t *testing.T, ctx context.Context, tx *sql.Tx, query string) []string {
+	t.Helper()
+	rows, err := tx.QueryContext(ctx, query)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	var ids []string
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	return ids
+}
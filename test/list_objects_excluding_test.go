@@ -0,0 +1,110 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// TestListObjectsExcluding_SharedButNotOwned lists, with p_exclude_relation,
+// the documents shared with a user that the user does not own, including one
+// reached through a team and one owned through a folder.
+func TestListObjectsExcluding_SharedButNotOwned(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type team
+  relations
+    define member: [user]
+
+type folder
+  relations
+    define owner: [user]
+
+type document
+  relations
+    define parent: [folder]
+    define owner: [user] or owner from parent
+    define viewer: [user, team#member] or owner
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "list-objects-excluding")
+
+	insertTuple(t, ctx, db, "user", "alice", "owner", "document", "owned")
+	insertTuple(t, ctx, db, "user", "alice", "owner", "folder", "home")
+	insertTuple(t, ctx, db, "folder", "home", "parent", "document", "in_folder")
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "shared")
+	insertTuple(t, ctx, db, "user", "alice", "member", "team", "eng")
+	insertTuple(t, ctx, db, "team", "eng#member", "viewer", "document", "team_shared")
+	insertTuple(t, ctx, db, "user", "bob", "viewer", "document", "not_shared")
+
+	listExcluding := func(exclude any, limit any, after any) (ids, cursors []string) {
+		t.Helper()
+		rows, err := db.QueryContext(ctx,
+			`SELECT object_id, coalesce(next_cursor, '') FROM list_accessible_objects('user', 'alice', 'viewer', 'document', $2, $3, p_exclude_relation => $1)`,
+			exclude, limit, after)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var id, cursor string
+			require.NoError(t, rows.Scan(&id, &cursor))
+			ids = append(ids, id)
+			cursors = append(cursors, cursor)
+		}
+		require.NoError(t, rows.Err())
+		return ids, cursors
+	}
+
+	require.ElementsMatch(t, []string{"in_folder", "owned", "shared", "team_shared"},
+		listObjects(t, db, "user", "alice", "viewer", "document"))
+
+	ids, _ := listExcluding("owner", nil, nil)
+	assert.Equal(t, []string{"shared", "team_shared"}, ids)
+
+	ids, _ = listExcluding(nil, nil, nil)
+	assert.Equal(t, []string{"in_folder", "owned", "shared", "team_shared"}, ids, "NULL excludes nothing")
+
+	ids, _ = listExcluding("editor", nil, nil)
+	assert.Equal(t, []string{"in_folder", "owned", "shared", "team_shared"}, ids, "an unknown relation excludes nothing")
+
+	// The specialized function takes the parameter directly.
+	var direct []string
+	rows, err := db.QueryContext(ctx, `SELECT object_id FROM list_document_viewer_obj('user', 'alice', p_exclude_relation => 'owner') ORDER BY object_id`)
+	require.NoError(t, err)
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		direct = append(direct, id)
+	}
+	require.NoError(t, rows.Err())
+	_ = rows.Close()
+	assert.Equal(t, []string{"shared", "team_shared"}, direct)
+
+	// Pages are cut after the exclusion, so the first page is full.
+	ids, cursors := listExcluding("owner", 1, nil)
+	assert.Equal(t, []string{"shared"}, ids)
+	assert.Equal(t, []string{"shared"}, cursors)
+	ids, cursors = listExcluding("owner", 1, "shared")
+	assert.Equal(t, []string{"team_shared"}, ids)
+	assert.Equal(t, []string{""}, cursors)
+
+	// Results agree with check_permission for every listed document.
+	checker := melange.NewChecker(db)
+	alice := melange.Object{Type: "user", ID: "alice"}
+	for _, id := range []string{"shared", "team_shared"} {
+		doc := melange.Object{Type: "document", ID: id}
+		ok, err := checker.Check(ctx, alice, melange.Relation("owner"), doc)
+		require.NoError(t, err)
+		assert.False(t, ok, "alice must not own %s", id)
+	}
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestListObjectsSubjectTypes lists the objects of a relation granted to
+// both users and group members with and without p_subject_types, over a
+// database that still holds the list_objects signatures from before the
+// parameter existed.
+func TestListObjectsSubjectTypes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte(`model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type document
+  relations
+    define owner: [user]
+    define viewer: [user, group#member] or owner
+`), 0o644))
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		);
+		INSERT INTO melange_tuples VALUES
+			('user', 'alice', 'viewer', 'document', '1'),
+			('user', 'alice', 'owner', 'document', '2'),
+			('group', 'eng#member', 'viewer', 'document', '3'),
+			('user', 'alice', 'member', 'group', 'eng');
+
+		-- Functions as an older melange installed them.
+		CREATE FUNCTION list_accessible_objects(TEXT, TEXT, TEXT, TEXT, INT DEFAULT NULL, TEXT DEFAULT NULL)
+		RETURNS TABLE(object_id TEXT, next_cursor TEXT) LANGUAGE sql AS 'SELECT NULL::TEXT, NULL::TEXT WHERE FALSE';
+		CREATE FUNCTION list_document_viewer_obj(TEXT, TEXT, INT DEFAULT NULL, TEXT DEFAULT NULL)
+		RETURNS TABLE(object_id TEXT, next_cursor TEXT) LANGUAGE sql AS 'SELECT NULL::TEXT, NULL::TEXT WHERE FALSE';`)
+	require.NoError(t, err)
+
+	_, err = migrator.MigrateWithOptions(ctx, db, path, migrator.MigrateOptions{})
+	require.NoError(t, err)
+
+	listObjects := func(query string) []string {
+		t.Helper()
+		rows, err := db.QueryContext(ctx, query)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		var ids []string
+		for rows.Next() {
+			var id string
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		return ids
+	}
+
+	assert.Equal(t, []string{"1", "2", "3"},
+		listObjects(`SELECT object_id FROM list_accessible_objects('user', 'alice', 'viewer', 'document')`))
+	assert.Equal(t, []string{"1", "2", "3"},
+		listObjects(`SELECT object_id FROM list_document_viewer_obj('user', 'alice')`))
+
+	// Document 3 is granted to a group alice is a member of.
+	assert.Equal(t, []string{"1", "2"},
+		listObjects(`SELECT object_id FROM list_accessible_objects('user', 'alice', 'viewer', 'document', p_subject_types => ARRAY['user'])`))
+	assert.Equal(t, []string{"3"},
+		listObjects(`SELECT object_id FROM list_accessible_objects('user', 'alice', 'viewer', 'document', p_subject_types => ARRAY['group'])`))
+	assert.Equal(t, []string{"1"},
+		listObjects(`SELECT object_id FROM list_accessible_objects('user', 'alice', 'viewer', 'document', 1, NULL, ARRAY['user'])`))
+}
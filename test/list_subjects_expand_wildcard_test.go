@@ -0,0 +1,175 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestListSubjectsExpandWildcard lists the subjects of a wildcard-only
+// relation with and without p_expand_wildcard, over a database that still
+// holds the list_subjects signatures from before the parameter existed.
+func TestListSubjectsExpandWildcard(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte(`model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type document
+  relations
+    define blocked: [user]
+    define viewer: [user:*] but not blocked
+`), 0o644))
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		);
+		INSERT INTO melange_tuples VALUES
+			('user', '*', 'viewer', 'document', '1'),
+			('user', 'alice', 'member', 'group', 'eng'),
+			('user', 'bob', 'member', 'group', 'eng'),
+			('user', 'bob', 'blocked', 'document', '1'),
+			('user', 'carol', 'member', 'group', 'ops'),
+			('group', 'eng#member', 'member', 'group', 'ops');
+
+		-- Functions as an older melange installed them.
+		CREATE FUNCTION list_accessible_subjects(TEXT, TEXT, TEXT, TEXT, INT DEFAULT NULL, TEXT DEFAULT NULL)
+		RETURNS TABLE(subject_id TEXT, next_cursor TEXT) LANGUAGE sql AS 'SELECT NULL::TEXT, NULL::TEXT WHERE FALSE';
+		CREATE FUNCTION list_document_viewer_sub(TEXT, TEXT, INT DEFAULT NULL, TEXT DEFAULT NULL)
+		RETURNS TABLE(subject_id TEXT, next_cursor TEXT) LANGUAGE sql AS 'SELECT NULL::TEXT, NULL::TEXT WHERE FALSE';`)
+	require.NoError(t, err)
+
+	_, err = migrator.MigrateWithOptions(ctx, db, path, migrator.MigrateOptions{})
+	require.NoError(t, err)
+
+	listSubjects := func(query string) []string {
+		t.Helper()
+		rows, err := db.QueryContext(ctx, query)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		var ids []string
+		for rows.Next() {
+			var id string
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		return ids
+	}
+
+	assert.Equal(t, []string{"*"},
+		listSubjects(`SELECT subject_id FROM list_accessible_subjects('document', '1', 'viewer', 'user')`))
+	assert.Equal(t, []string{"*"},
+		listSubjects(`SELECT subject_id FROM list_document_viewer_sub('1', 'user')`))
+
+	// bob is blocked; the eng#member userset is not a user.
+	assert.Equal(t, []string{"alice", "carol"},
+		listSubjects(`SELECT subject_id FROM list_accessible_subjects('document', '1', 'viewer', 'user', p_expand_wildcard => TRUE)`))
+	assert.Equal(t, []string{"alice"},
+		listSubjects(`SELECT subject_id FROM list_accessible_subjects('document', '1', 'viewer', 'user', 1, NULL, TRUE)`))
+	assert.Empty(t,
+		listSubjects(`SELECT subject_id FROM list_accessible_subjects('document', '2', 'viewer', 'user', p_expand_wildcard => TRUE)`))
+}
+
+// TestListSubjectsExpandWildcardSubjectsView expands wildcard grants from a
+// melange_subjects view, which lists subjects that have no tuples, on both
+// the direct and the userset filter paths.
+func TestListSubjectsExpandWildcardSubjectsView(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte(`model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type document
+  relations
+    define blocked: [user]
+    define viewer: [user:*] but not blocked
+    define editor: [group#member, group:*]
+`), 0o644))
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		);
+		INSERT INTO melange_tuples VALUES
+			('user', '*', 'viewer', 'document', '1'),
+			('user', 'bob', 'blocked', 'document', '1'),
+			('group', '*', 'editor', 'document', '1'),
+			('user', 'alice', 'member', 'group', 'eng');
+
+		CREATE TABLE users (id TEXT PRIMARY KEY);
+		INSERT INTO users VALUES ('alice'), ('bob'), ('dave');
+		CREATE TABLE groups (id TEXT PRIMARY KEY);
+		INSERT INTO groups VALUES ('eng'), ('empty');
+
+		CREATE VIEW melange_subjects AS
+			SELECT 'user' AS subject_type, id AS subject_id FROM users
+			UNION ALL
+			SELECT 'group', id FROM groups;`)
+	require.NoError(t, err)
+
+	_, err = migrator.MigrateWithOptions(ctx, db, path, migrator.MigrateOptions{ExpandWildcardSubjects: true})
+	require.NoError(t, err)
+
+	listSubjects := func(query string) []string {
+		t.Helper()
+		rows, err := db.QueryContext(ctx, query)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		var ids []string
+		for rows.Next() {
+			var id string
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		return ids
+	}
+
+	assert.Equal(t, []string{"*"},
+		listSubjects(`SELECT subject_id FROM list_accessible_subjects('document', '1', 'viewer', 'user')`))
+	// dave has no tuples but is in the view; bob is blocked.
+	assert.Equal(t, []string{"alice", "dave"},
+		listSubjects(`SELECT subject_id FROM list_accessible_subjects('document', '1', 'viewer', 'user', p_expand_wildcard => TRUE)`))
+	// group:empty has no members but group:* covers its usersets too.
+	assert.Equal(t, []string{"empty#member", "eng#member"},
+		listSubjects(`SELECT subject_id FROM list_accessible_subjects('document', '1', 'editor', 'group#member', p_expand_wildcard => TRUE)`))
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// directAndEditorSchema wraps a direct [user] assignment in an intersection,
+// so the only tuple granting viewer is on viewer itself.
+const directAndEditorSchema = `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define editor: [user]
+    define viewer: [user] and editor
+`
+
+// TestListSubjects_IntersectionDirectPart verifies that list_subjects finds
+// subjects whose viewer tuple comes from the [user] part of the intersection,
+// and that it agrees with Check and ListObjects.
+func TestListSubjects_IntersectionDirectPart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, directAndEditorSchema, "intersection-direct-part")
+
+	// alice: viewer and editor. bob: viewer only. carol: editor only.
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "doc1")
+	insertTuple(t, ctx, db, "user", "alice", "editor", "document", "doc1")
+	insertTuple(t, ctx, db, "user", "bob", "viewer", "document", "doc1")
+	insertTuple(t, ctx, db, "user", "carol", "editor", "document", "doc1")
+
+	checker := melange.NewChecker(db)
+	doc := melange.Object{Type: "document", ID: "doc1"}
+
+	subjects, err := checker.ListSubjectsAll(ctx, doc, melange.Relation("viewer"), "user")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alice"}, subjects)
+
+	for _, id := range []string{"alice", "bob", "carol"} {
+		ok, err := checker.Check(ctx, melange.Object{Type: "user", ID: id}, melange.Relation("viewer"), doc)
+		require.NoError(t, err)
+		assert.Equal(t, id == "alice", ok, "check viewer for %s", id)
+
+		objects, err := checker.ListObjectsAll(ctx, melange.Object{Type: "user", ID: id}, melange.Relation("viewer"), "document")
+		require.NoError(t, err)
+		assert.Equal(t, id == "alice", len(objects) == 1, "list_objects viewer for %s", id)
+	}
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// TestListSubjectsMulti_UnionsFilters lists the viewers of a document for a
+// plain type filter and a userset filter at once, and checks each matches
+// the single-filter list_accessible_subjects.
+func TestListSubjectsMulti_UnionsFilters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user]
+
+type document
+  relations
+    define owner: [user]
+    define viewer: [user, group#member] or owner
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "list-subjects-multi")
+
+	insertTuple(t, ctx, db, "user", "alice", "owner", "document", "1")
+	insertTuple(t, ctx, db, "user", "bob", "viewer", "document", "1")
+	insertTuple(t, ctx, db, "user", "carol", "member", "group", "eng")
+	insertTuple(t, ctx, db, "group", "eng#member", "viewer", "document", "1")
+	insertTuple(t, ctx, db, "user", "dave", "viewer", "document", "2")
+
+	checker := melange.NewChecker(db)
+	doc := melange.Object{Type: "document", ID: "1"}
+	filters := []melange.ObjectType{"user", "group#member"}
+
+	subjects, err := checker.ListSubjectsMulti(ctx, doc, melange.Relation("viewer"), filters)
+	require.NoError(t, err)
+
+	var want []melange.Object
+	for _, filter := range filters {
+		ids, err := checker.ListSubjectsAll(ctx, doc, melange.Relation("viewer"), filter)
+		require.NoError(t, err)
+		for _, id := range ids {
+			want = append(want, melange.Object{Type: filter, ID: id})
+		}
+	}
+	assert.ElementsMatch(t, want, subjects)
+	assert.Subset(t, subjects, []melange.Object{
+		{Type: "user", ID: "alice"},
+		{Type: "user", ID: "bob"},
+		{Type: "user", ID: "carol"},
+	})
+	assert.NotContains(t, subjects, melange.Object{Type: "user", ID: "dave"})
+
+	subjects, err = checker.ListSubjectsMulti(ctx, doc, melange.Relation("viewer"), nil)
+	require.NoError(t, err)
+	assert.Empty(t, subjects, "no filters lists nothing")
+
+	// Contextual tuples apply to every filter.
+	subjects, err = checker.ListSubjectsMultiWithContextualTuples(ctx, doc, melange.Relation("viewer"),
+		[]melange.ObjectType{"user", "group#member"},
+		[]melange.ContextualTuple{{
+			Subject:  melange.Object{Type: "group", ID: "ops#member"},
+			Relation: melange.Relation("viewer"),
+			Object:   doc,
+		}})
+	require.NoError(t, err)
+	want = append(want, melange.Object{Type: "group#member", ID: "ops#member"})
+	assert.ElementsMatch(t, want, subjects)
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// TestListSubjects_UsersetExclusion is the regression test for suspended
+// members of a granted group still appearing in list_subjects. Each schema
+// routes the exclusion differently: a simple suspended relation goes through
+// the excluded-subjects CTE, a complex one through per-member checks on the
+// simple (JOIN) or complex (LATERAL) userset block.
+func TestListSubjects_UsersetExclusion(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name   string
+		schema string
+		want   []string
+	}{
+		{
+			name: "simple exclusion",
+			schema: `model
+  schema 1.1
+
+type user
+
+type team
+  relations
+    define member: [user]
+
+type group
+  relations
+    define member: [user]
+
+type document
+  relations
+    define suspended: [user]
+    define viewer: [group#member] but not suspended
+`,
+			want: []string{"alice"},
+		},
+		{
+			name: "complex exclusion",
+			schema: `model
+  schema 1.1
+
+type user
+
+type team
+  relations
+    define member: [user]
+
+type group
+  relations
+    define member: [user]
+
+type document
+  relations
+    define suspended: [user, team#member]
+    define viewer: [group#member] but not suspended
+`,
+			want: []string{"alice"},
+		},
+		{
+			name: "complex exclusion and complex userset",
+			schema: `model
+  schema 1.1
+
+type user
+
+type team
+  relations
+    define member: [user]
+
+type group
+  relations
+    define member: [user, team#member]
+
+type document
+  relations
+    define suspended: [user, team#member]
+    define viewer: [group#member] but not suspended
+`,
+			want: []string{"alice", "carol"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := installAdHocSchema(t, ctx, tt.schema, "userset-exclusion")
+
+			// alice, bob, and (through team core, where the schema allows
+			// it) carol are members of eng, which may view doc1. bob is
+			// suspended on doc1.
+			insertTuple(t, ctx, db, "user", "alice", "member", "group", "eng")
+			insertTuple(t, ctx, db, "user", "bob", "member", "group", "eng")
+			insertTuple(t, ctx, db, "user", "carol", "member", "team", "core")
+			insertTuple(t, ctx, db, "team", "core#member", "member", "group", "eng")
+			insertTuple(t, ctx, db, "group", "eng#member", "viewer", "document", "doc1")
+			insertTuple(t, ctx, db, "user", "bob", "suspended", "document", "doc1")
+
+			checker := melange.NewChecker(db)
+			doc := melange.Object{Type: "document", ID: "doc1"}
+
+			subjects, err := checker.ListSubjectsAll(ctx, doc, melange.Relation("viewer"), "user")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, subjects)
+
+			for _, id := range []string{"alice", "bob", "carol"} {
+				ok, err := checker.Check(ctx, melange.Object{Type: "user", ID: id}, melange.Relation("viewer"), doc)
+				require.NoError(t, err)
+				assert.Equal(t, slices.Contains(tt.want, id), ok, "check viewer for %s", id)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestLongRelationNames migrates a model whose function names exceed
+// PostgreSQL's 63-byte identifier limit. The functions are created under
+// hashed names, the dispatchers route to them, and each one's comment maps
+// the hashed name back to its type and relation.
+func TestLongRelationNames(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	db := testutil.EmptyDB(t)
+
+	objectType := "organization_" + strings.Repeat("x", 40)
+	relation := "can_view_" + strings.Repeat("y", 40)
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO melange_tuples VALUES ('user', 'anne', $1, $2, 'o1')`, relation, objectType)
+	require.NoError(t, err)
+
+	require.NoError(t, migrator.MigrateFromString(ctx, db, `model
+  schema 1.1
+
+type user
+
+type `+objectType+`
+  relations
+    define `+relation+`: [user]
+`))
+
+	var allowed int
+	require.NoError(t, db.QueryRowContext(ctx,
+		`SELECT check_permission('user', 'anne', $1, $2, 'o1')`, relation, objectType).Scan(&allowed))
+	assert.Equal(t, 1, allowed)
+
+	var objects []string
+	rows, err := db.QueryContext(ctx,
+		`SELECT object_id FROM list_accessible_objects('user', 'anne', $1, $2)`, relation, objectType)
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		objects = append(objects, id)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"o1"}, objects)
+
+	// Every generated name fits, and the comments recover the original names.
+	comments, err := db.QueryContext(ctx, `
+		SELECT p.proname, COALESCE(obj_description(p.oid, 'pg_proc'), '')
+		FROM pg_proc p
+		JOIN pg_namespace n ON p.pronamespace = n.oid
+		WHERE n.nspname = 'public' AND p.proname LIKE '%organization%'`)
+	require.NoError(t, err)
+	defer func() { _ = comments.Close() }()
+	var kinds []string
+	for comments.Next() {
+		var name, comment string
+		require.NoError(t, comments.Scan(&name, &comment))
+		assert.LessOrEqual(t, len(name), 63, name)
+		md, ok := sqlgen.ParseFunctionMetadata(comment)
+		require.True(t, ok, "%s has no metadata comment", name)
+		assert.Equal(t, objectType, md.ObjectType)
+		assert.Equal(t, relation, md.Relation)
+		kinds = append(kinds, md.Kind)
+	}
+	require.NoError(t, comments.Err())
+	assert.Contains(t, kinds, sqlgen.FunctionKindCheck)
+	assert.Contains(t, kinds, sqlgen.FunctionKindListObjects)
+}
@@ -27,6 +27,14 @@ type compiledSchema struct {
 	namedFunctions []compiler.NamedFunction
 }
 
+// schemaHash returns the checksum the migrator records for schemaContent.
+func schemaHash(t *testing.T, schemaContent string) string {
+	t.Helper()
+	types, err := parser.ParseSchemaString(schemaContent)
+	require.NoError(t, err, "parsing schema")
+	return migrator.SchemaHash(types)
+}
+
 // compilePipeline runs the full compilation pipeline for a schema string.
 func compilePipeline(t *testing.T, schemaContent string) compiledSchema {
 	t.Helper()
@@ -67,7 +75,7 @@ func compilePipelineFromTypes(t *testing.T, types []schema.TypeDefinition) compi
 func fullMigration(t *testing.T, schemaContent, version string) (compiledSchema, compiler.MigrationSQL) {
 	t.Helper()
 	cs := compilePipeline(t, schemaContent)
-	return cs, generateMigration(cs, migrator.ComputeSchemaChecksum(schemaContent), version, nil)
+	return cs, generateMigration(cs, schemaHash(t, schemaContent), version, nil)
 }
 
 // fullMigrationFromTypes generates a full migration from pre-parsed type definitions.
@@ -82,7 +90,7 @@ func fullMigrationFromTypes(t *testing.T, types []schema.TypeDefinition, checksu
 func incrementalMigration(t *testing.T, schemaContent, version string, prev compiledSchema) (compiledSchema, compiler.MigrationSQL) {
 	t.Helper()
 	cs := compilePipeline(t, schemaContent)
-	return cs, generateMigration(cs, migrator.ComputeSchemaChecksum(schemaContent), version, &prev)
+	return cs, generateMigration(cs, schemaHash(t, schemaContent), version, &prev)
 }
 
 // incrementalMigrationFromTypes generates an incremental migration from pre-parsed
@@ -578,7 +586,7 @@ func TestMigration_BuiltinMigrateSchemaEvolution(t *testing.T) {
 	rec, err := m.GetLastMigration(ctx)
 	require.NoError(t, err)
 	require.NotNil(t, rec)
-	assert.Equal(t, migrator.ComputeSchemaChecksum(schemaV2), rec.SchemaChecksum,
+	assert.Equal(t, schemaHash(t, schemaV2), rec.SchemaChecksum,
 		"last migration should reflect V2 schema checksum")
 }
 
@@ -679,7 +687,7 @@ func TestMigration_Phase2SkipOnVersionBump(t *testing.T) {
 	rec, err := m.GetLastMigration(ctx)
 	require.NoError(t, err)
 	require.NotNil(t, rec)
-	assert.Equal(t, migrator.ComputeSchemaChecksum(schemaV1), rec.SchemaChecksum)
+	assert.Equal(t, schemaHash(t, schemaV1), rec.SchemaChecksum)
 }
 
 // TestMigration_DryRun verifies that dry-run mode outputs SQL without applying changes.
@@ -714,6 +722,86 @@ func TestMigration_DryRun(t *testing.T) {
 	assert.Empty(t, functions, "dry-run should not create any functions")
 }
 
+// TestMigration_ApplyTx verifies that ApplyTx runs entirely on the caller's
+// transaction: nothing is visible outside it until the caller commits, and a
+// rollback leaves no functions or migration record behind.
+func TestMigration_ApplyTx(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := testutil.EmptyDB(t)
+	ctx := context.Background()
+
+	types, err := parser.ParseSchemaString(schemaV1)
+	require.NoError(t, err)
+	opts := migrator.ApplyTxOptions{SchemaContent: schemaV1, Version: "v0.7.3"}
+
+	// Rolled back: nothing persists.
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	skipped, err := migrator.ApplyTx(ctx, tx, types, opts)
+	require.NoError(t, err)
+	assert.False(t, skipped)
+
+	var inTx bool
+	require.NoError(t, tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_proc WHERE proname = 'check_document_viewer')").Scan(&inTx))
+	assert.True(t, inTx, "functions should be visible inside the caller's transaction")
+	assert.False(t, functionExists(t, ctx, db, "check_document_viewer"),
+		"ApplyTx must not commit the caller's transaction")
+	require.NoError(t, tx.Rollback())
+	assert.Empty(t, getFunctionNames(t, ctx, db), "rollback should discard every function")
+
+	// Committed: functions and the migration record persist.
+	tx, err = db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = migrator.ApplyTx(ctx, tx, types, opts)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	assert.True(t, functionExists(t, ctx, db, "check_document_viewer"))
+	assert.Equal(t, 1, migrationRecordCount(t, ctx, db))
+
+	// Unchanged schema: skipped on a fresh transaction.
+	tx, err = db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer func() { _ = tx.Rollback() }()
+	skipped, err = migrator.ApplyTx(ctx, tx, types, opts)
+	require.NoError(t, err)
+	assert.True(t, skipped, "unchanged schema should be skipped")
+}
+
+// TestMigration_ApplyTxAdvisoryLock verifies that ApplyTx takes the
+// transaction-scoped advisory lock only when asked to.
+func TestMigration_ApplyTxAdvisoryLock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	db := testutil.EmptyDB(t)
+	ctx := context.Background()
+
+	types, err := parser.ParseSchemaString(schemaV1)
+	require.NoError(t, err)
+
+	for _, lock := range []bool{false, true} {
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		_, err = migrator.ApplyTx(ctx, tx, types, migrator.ApplyTxOptions{AdvisoryLock: lock})
+		require.NoError(t, err)
+
+		var held bool
+		require.NoError(t, tx.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM pg_locks
+				WHERE locktype = 'advisory' AND pid = pg_backend_pid()
+				AND ((classid::BIGINT << 32) | objid::BIGINT) = $1
+			)`, migrator.AdvisoryLockKey).Scan(&held))
+		assert.Equal(t, lock, held, "advisory lock held (AdvisoryLock=%v)", lock)
+		require.NoError(t, tx.Rollback())
+	}
+}
+
 // --- Modular schema definitions ---
 //
 // These mirror the single-file schemaV1/V2/V3 definitions above but use
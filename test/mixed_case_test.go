@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/pthm/melange/melange"
 	"github.com/pthm/melange/pkg/migrator"
 	"github.com/pthm/melange/pkg/parser"
 	"github.com/pthm/melange/test/testutil"
@@ -78,3 +79,52 @@ type organization
 		assert.True(t, functionExists(t, ctx, db, fn), "function %s should exist after re-migration", fn)
 	}
 }
+
+// TestMixedCaseRelations_DifferOnlyByCase verifies that relations whose names
+// differ only by case get their own functions, and that check_permission and
+// the list dispatchers route each to its own.
+func TestMixedCaseRelations_DifferOnlyByCase(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define viewer: [user]
+    define Viewer: [user]
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "mixed-case-collision")
+
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "1")
+	insertTuple(t, ctx, db, "user", "bob", "Viewer", "document", "1")
+
+	checker := melange.NewChecker(db)
+	doc := melange.Object{Type: "document", ID: "1"}
+	for _, tc := range []struct {
+		subject  string
+		relation melange.Relation
+		want     bool
+	}{
+		{"alice", "viewer", true},
+		{"alice", "Viewer", false},
+		{"bob", "viewer", false},
+		{"bob", "Viewer", true},
+	} {
+		ok, err := checker.Check(ctx, melange.Object{Type: "user", ID: tc.subject}, tc.relation, doc)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, ok, "%s %s", tc.subject, tc.relation)
+	}
+
+	viewers, err := checker.ListSubjectsAll(ctx, doc, melange.Relation("viewer"), "user")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alice"}, viewers)
+	viewers, err = checker.ListSubjectsAll(ctx, doc, melange.Relation("Viewer"), "user")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bob"}, viewers)
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestModelRelations_MatchesInstalledFunctions verifies that
+// melange_model_relations reports a relation as checkable or listable exactly
+// when its check or list function is installed.
+func TestModelRelations_MatchesInstalledFunctions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err, "creating melange_tuples table")
+	migrateSchema(t, ctx, migrator.NewMigrator(db, ""), evidenceSchema, migrator.InternalMigrateOptions{})
+
+	rows, err := db.QueryContext(ctx, "SELECT object_type, relation, features, can_check, can_list FROM melange_model_relations()")
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+
+	features := make(map[string]string)
+	for rows.Next() {
+		var objectType, relation, feature string
+		var canCheck, canList bool
+		require.NoError(t, rows.Scan(&objectType, &relation, &feature, &canCheck, &canList))
+		features[objectType+"#"+relation] = feature
+
+		assert.Equal(t, canCheck, functionExists(t, ctx, db, "check_"+objectType+"_"+relation),
+			"can_check for %s#%s", objectType, relation)
+		assert.Equal(t, canList, functionExists(t, ctx, db, "list_"+objectType+"_"+relation+"_obj"),
+			"can_list for %s#%s", objectType, relation)
+	}
+	require.NoError(t, rows.Err())
+
+	assert.Len(t, features, 9, "one row per relation")
+	assert.Equal(t, "Direct+Exclusion", features["document#editor"])
+	assert.Equal(t, "Intersection", features["document#auditor"])
+}
@@ -0,0 +1,58 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNullGuards passes NULL for required arguments of check_permission and
+// the list dispatchers and expects an error naming the parameter instead of
+// a silent deny or empty list.
+func TestNullGuards(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define viewer: [user]
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "null-guards")
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "1")
+
+	var allowed int
+	err := db.QueryRowContext(ctx,
+		`SELECT check_permission('user', NULL, 'viewer', 'document', '1')`).Scan(&allowed)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "p_subject_id must not be NULL")
+
+	err = db.QueryRowContext(ctx,
+		`SELECT check_permission('user', 'alice', 'viewer', 'document', NULL)`).Scan(&allowed)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "p_object_id must not be NULL")
+
+	var id string
+	err = db.QueryRowContext(ctx,
+		`SELECT object_id FROM list_accessible_objects('user', NULL, 'viewer', 'document')`).Scan(&id)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "p_subject_id must not be NULL")
+
+	err = db.QueryRowContext(ctx,
+		`SELECT subject_id FROM list_accessible_subjects('document', '1', 'viewer', NULL)`).Scan(&id)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "p_subject_type must not be NULL")
+
+	// Optional arguments stay nullable.
+	objects := distinct(t, db,
+		`SELECT object_id FROM list_accessible_objects('user', 'alice', 'viewer', 'document', NULL, NULL)`)
+	assert.Equal(t, []string{"1"}, objects)
+}
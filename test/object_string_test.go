@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestObjectStringOverloads calls check_permission and the list dispatchers
+// with type:id strings and compares them with the tuple-column overloads,
+// including ids that contain colons and userset subjects.
+func TestObjectStringOverloads(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type team
+  relations
+    define member: [user]
+
+type document
+  relations
+    define viewer: [user, team#member]
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "object-string-overloads")
+
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "1")
+	insertTuple(t, ctx, db, "user", "alice", "viewer", "document", "urn:doc:2")
+	insertTuple(t, ctx, db, "user", "bob", "member", "team", "eng")
+	insertTuple(t, ctx, db, "team", "eng#member", "viewer", "document", "1")
+
+	check := func(subject, relation, object string) (int, error) {
+		t.Helper()
+		var allowed int
+		err := db.QueryRowContext(ctx, `SELECT check_permission($1, $2, $3)`, subject, relation, object).Scan(&allowed)
+		return allowed, err
+	}
+
+	for _, tc := range []struct {
+		subject, object string
+		want            int
+	}{
+		{"user:alice", "document:1", 1},
+		{"user:alice", "document:urn:doc:2", 1},
+		{"user:bob", "document:1", 1},
+		{"team:eng#member", "document:1", 1},
+		{"user:bob", "document:urn:doc:2", 0},
+	} {
+		got, err := check(tc.subject, "viewer", tc.object)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got, "check_permission(%q, viewer, %q)", tc.subject, tc.object)
+	}
+
+	for _, malformed := range []string{"document", "document:", ":1", ""} {
+		_, err := check("user:alice", "viewer", malformed)
+		require.Error(t, err, "object %q must be rejected", malformed)
+		assert.Contains(t, err.Error(), "expected type:id")
+
+		_, err = check(malformed, "viewer", "document:1")
+		require.Error(t, err, "subject %q must be rejected", malformed)
+	}
+
+	objects := distinct(t, db, `SELECT object_id FROM list_accessible_objects($1, $2, $3)`, "user:alice", "viewer", "document")
+	assert.ElementsMatch(t, listObjects(t, db, "user", "alice", "viewer", "document"), objects)
+	assert.ElementsMatch(t, []string{"1", "urn:doc:2"}, objects)
+
+	subjects := distinct(t, db, `SELECT subject_id FROM list_accessible_subjects($1, $2, $3)`, "document:1", "viewer", "user")
+	assert.ElementsMatch(t, listSubjects(t, db, "document", "1", "viewer", "user"), subjects)
+	assert.ElementsMatch(t, []string{"alice", "bob"}, subjects)
+
+	// Pagination arguments pass through.
+	page := distinct(t, db, `SELECT object_id FROM list_accessible_objects($1, $2, $3, 1)`, "user:alice", "viewer", "document")
+	assert.Len(t, page, 1)
+
+	_, err := db.ExecContext(ctx, `SELECT * FROM list_accessible_subjects('document', 'viewer', 'user')`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "malformed object")
+}
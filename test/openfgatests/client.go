@@ -18,6 +18,7 @@ package openfgatests
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -31,6 +32,7 @@ import (
 	"github.com/openfga/openfga/pkg/storage/memory"
 	"google.golang.org/grpc"
 
+	"github.com/pthm/melange/lib/sqlgen"
 	"github.com/pthm/melange/lib/sqlgen/sqldsl"
 	"github.com/pthm/melange/melange"
 	"github.com/pthm/melange/pkg/migrator"
@@ -600,7 +602,9 @@ func (c *Client) ListUsers(ctx context.Context, req *openfgav1.ListUsersRequest,
 		return nil, fmt.Errorf("parsing object: %w", err)
 	}
 
-	// Get all user filters and list subjects for each
+	// Get all user filters and list subjects for each. The harness is part of
+	// the root module, which builds against the released melange module, so
+	// it stays off ListSubjectsMulti until a release includes it.
 	var users []*openfgav1.User
 	for _, filter := range req.GetUserFilters() {
 		// The returned object type is just the principal type, without any
@@ -880,6 +884,54 @@ func (c *Client) CheckBulk(ctx context.Context, storeID string, assertions []*Ch
 	return results, nil
 }
 
+// CheckWithContextualTuplesSQL runs a single check through the generated
+// check_permission_with_contextual_tuples function, passing the assertion's
+// contextual tuples as JSONB rather than through the Checker's temp view.
+// Used by the parity sweep in contextual_parity.go.
+func (c *Client) CheckWithContextualTuplesSQL(ctx context.Context, storeID string, a *CheckAssertion) (bool, error) {
+	store, ok := c.storeByID(storeID)
+	if !ok {
+		return false, fmt.Errorf("store not found: %s", storeID)
+	}
+	subject, err := parseSubject(a.Tuple.GetUser())
+	if err != nil {
+		return false, fmt.Errorf("parsing subject: %w", err)
+	}
+	object, err := parseObject(a.Tuple.GetObject())
+	if err != nil {
+		return false, fmt.Errorf("parsing object: %w", err)
+	}
+	contextualTuples, err := contextualTuplesFromKeys(a.ContextualTuples)
+	if err != nil {
+		return false, fmt.Errorf("parsing contextual tuples: %w", err)
+	}
+	rows := make([]map[string]string, 0, len(contextualTuples))
+	for _, ct := range contextualTuples {
+		rows = append(rows, map[string]string{
+			"subject_type": string(ct.Subject.Type),
+			"subject_id":   ct.Subject.ID,
+			"relation":     string(ct.Relation),
+			"object_type":  string(ct.Object.Type),
+			"object_id":    ct.Object.ID,
+		})
+	}
+	payload, err := json.Marshal(rows)
+	if err != nil {
+		return false, fmt.Errorf("encoding contextual tuples: %w", err)
+	}
+
+	funcName := sqldsl.PrefixIdent(sqlgen.ContextualTuplesFunctionName, c.databaseSchema)
+	var allowed int
+	err = store.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT %s($1, $2, $3, $4, $5, $6::JSONB)", funcName),
+		string(subject.Type), subject.ID, a.Tuple.GetRelation(), string(object.Type), object.ID, string(payload),
+	).Scan(&allowed)
+	if err != nil {
+		return false, fmt.Errorf("%s query: %w", sqlgen.ContextualTuplesFunctionName, err)
+	}
+	return allowed == 1, nil
+}
+
 // Ensure Client implements the interface at compile time.
 var _ interface {
 	CreateStore(context.Context, *openfgav1.CreateStoreRequest, ...grpc.CallOption) (*openfgav1.CreateStoreResponse, error)
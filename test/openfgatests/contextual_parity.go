@@ -0,0 +1,76 @@
+package openfgatests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runContextualTuplesParityAssertions replays every eligible check assertion
+// that carries contextual tuples through the generated
+// check_permission_with_contextual_tuples function, which receives the
+// tuples as JSONB instead of through the Checker's temp view setup. The
+// result must equal the assertion's Expectation, so the SQL entry point is
+// held to the same YAML cases as CheckWithContextualTuples.
+//
+// Eligibility:
+//   - assertion.ErrorCode == 0 (the SQL function does not validate the
+//     request against the model the way the Checker does)
+//   - len(assertion.ContextualTuples) > 0
+//   - no condition context and no conditional contextual tuples (the
+//     function answers check_permission, which ignores conditions)
+func runContextualTuplesParityAssertions(t *testing.T, ctx context.Context, client *Client, storeID string, checks []*CheckAssertion) {
+	t.Helper()
+
+	if client.openfgaBackend != nil {
+		return // check_permission_with_contextual_tuples is a melange-only API.
+	}
+
+	for i, a := range contextualParityEligible(checks) {
+		name := a.Name
+		if name == "" {
+			name = fmt.Sprintf("contextual_parity_%d", i)
+		} else {
+			name = "contextual_parity_" + name
+		}
+
+		t.Run(name, func(t *testing.T) {
+			tk := a.Tuple
+			allowed, err := client.CheckWithContextualTuplesSQL(ctx, storeID, a)
+			require.NoError(t, err,
+				"check_permission_with_contextual_tuples failed for %s#%s on %s",
+				tk.GetUser(), tk.GetRelation(), tk.GetObject())
+			require.Equal(t, a.Expectation, allowed,
+				"check_permission_with_contextual_tuples disagrees for %s#%s on %s",
+				tk.GetUser(), tk.GetRelation(), tk.GetObject())
+		})
+	}
+}
+
+// contextualParityEligible filters a stage's check assertions down to those
+// check_permission_with_contextual_tuples can answer. See
+// runContextualTuplesParityAssertions for the criteria.
+func contextualParityEligible(checks []*CheckAssertion) []*CheckAssertion {
+	var out []*CheckAssertion
+	for _, a := range checks {
+		if a == nil || a.Tuple == nil || a.ErrorCode != 0 || len(a.ContextualTuples) == 0 {
+			continue
+		}
+		if a.Context != nil || a.Tuple.GetCondition() != nil {
+			continue
+		}
+		conditional := false
+		for _, ct := range a.ContextualTuples {
+			if ct.GetCondition() != nil {
+				conditional = true
+				break
+			}
+		}
+		if !conditional {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -707,6 +707,11 @@ func RunTest(t *testing.T, parent *Client, tc TestCase) {
 				// explain_parity.go for the eligibility / skip rules.
 				runExplainParityAssertions(t, ctx, client, storeID, modelID, stage.CheckAssertions)
 
+				// Replay check assertions that carry contextual tuples through
+				// check_permission_with_contextual_tuples, which takes them as
+				// JSONB. See contextual_parity.go.
+				runContextualTuplesParityAssertions(t, ctx, client, storeID, stage.CheckAssertions)
+
 				// Cross-reference Expand against list_objects for every
 				// eligible list_objects assertion. For each expected
 				// object, ExpandRecursive(object, relation) must
@@ -71,7 +71,7 @@ func (v *modelValidator) relationDef(objectType, relation string) (schema.Relati
 
 func (v *modelValidator) ValidateUsersetSubject(subject melange.Object) error {
 	id := subject.ID
-	idx := strings.Index(id, "#")
+	idx := strings.LastIndex(id, "#")
 	if idx == -1 {
 		return nil
 	}
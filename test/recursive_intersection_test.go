@@ -0,0 +1,111 @@
+package test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// recursiveIntersectionSchema intersects a relation with a TTU back to
+// itself through parent. can_edit has no base case, so under OpenFGA's
+// semantics nobody holds it: the root folder has no parent to inherit from.
+// can_manage and can_write add a standalone base case, one reached through
+// another type and one assigned directly.
+const recursiveIntersectionSchema = `model
+  schema 1.1
+
+type user
+
+type org
+  relations
+    define admin: [user]
+
+type folder
+  relations
+    define org: [org]
+    define parent: [folder]
+    define owner: [user]
+    define editor: [user]
+    define can_edit: editor and can_edit from parent
+    define can_manage: admin from org or (editor and can_manage from parent)
+    define can_write: owner or (editor and can_write from parent)
+`
+
+// TestRecursiveIntersection checks every subject, relation and folder of the
+// recursive-intersection model against its truth table, through Check,
+// ListObjects and ListSubjects.
+//
+// Folders form the chain root <- mid <- leaf. acme owns root and alice
+// administers acme. alice, bob and dave edit mid; bob and carol edit leaf.
+// dave owns root and bob owns mid.
+func TestRecursiveIntersection(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, recursiveIntersectionSchema, "recursive-intersection")
+
+	insertTuple(t, ctx, db, "folder", "root", "parent", "folder", "mid")
+	insertTuple(t, ctx, db, "folder", "mid", "parent", "folder", "leaf")
+	insertTuple(t, ctx, db, "org", "acme", "org", "folder", "root")
+	insertTuple(t, ctx, db, "user", "alice", "admin", "org", "acme")
+	for _, editor := range []string{"alice", "bob", "dave"} {
+		insertTuple(t, ctx, db, "user", editor, "editor", "folder", "mid")
+	}
+	for _, editor := range []string{"bob", "carol"} {
+		insertTuple(t, ctx, db, "user", editor, "editor", "folder", "leaf")
+	}
+	insertTuple(t, ctx, db, "user", "dave", "owner", "folder", "root")
+	insertTuple(t, ctx, db, "user", "bob", "owner", "folder", "mid")
+
+	// truth[relation][user] lists the folders the user holds relation on.
+	truth := map[string]map[string][]string{
+		"can_edit": {},
+		"can_manage": {
+			"alice": {"mid", "root"},
+		},
+		"can_write": {
+			"bob":  {"leaf", "mid"},
+			"dave": {"mid", "root"},
+		},
+	}
+	users := []string{"alice", "bob", "carol", "dave"}
+	folders := []string{"leaf", "mid", "root"}
+
+	checker := melange.NewChecker(db)
+	for relation, byUser := range truth {
+		rel := melange.Relation(relation)
+		for _, user := range users {
+			subject := melange.Object{Type: "user", ID: user}
+			want := byUser[user]
+
+			for _, folder := range folders {
+				ok, err := checker.Check(ctx, subject, rel, melange.Object{Type: "folder", ID: folder})
+				require.NoError(t, err)
+				assert.Equal(t, slices.Contains(want, folder), ok, "check %s %s folder:%s", user, relation, folder)
+			}
+
+			objects, err := checker.ListObjectsAll(ctx, subject, rel, "folder")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, want, objects, "list_objects %s %s", user, relation)
+		}
+
+		for _, folder := range folders {
+			var want []string
+			for _, user := range users {
+				if slices.Contains(byUser[user], folder) {
+					want = append(want, user)
+				}
+			}
+			subjects, err := checker.ListSubjectsAll(ctx, melange.Object{Type: "folder", ID: folder}, rel, "user")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, want, subjects, "list_subjects folder:%s %s", folder, relation)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestRollback migrates a schema, then checks that a Down migration drops
+// exactly the recorded functions, keeps melange_tuples and unrecorded
+// functions, keeps the history with the migration marked rolled back, and
+// lets the next migration re-apply instead of skipping.
+func TestRollback(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte(`model
+  schema 1.1
+
+type user
+
+type folder
+  relations
+    define viewer: [user]
+
+type document
+  relations
+    define parent: [folder]
+    define viewer: [user] or viewer from parent
+`), 0o644))
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		);
+		CREATE FUNCTION check_app_owned() RETURNS INTEGER AS 'SELECT 1' LANGUAGE sql`)
+	require.NoError(t, err)
+
+	opts := migrator.MigrateOptions{DatabaseSchema: "public", TableRoutedDispatcher: true}
+	_, err = migrator.MigrateWithOptions(ctx, db, path, opts)
+	require.NoError(t, err)
+
+	countFunctions := func() int {
+		var n int
+		require.NoError(t, db.QueryRowContext(ctx, `
+			SELECT count(*) FROM pg_proc p
+			JOIN pg_namespace n ON p.pronamespace = n.oid
+			WHERE n.nspname = 'public'`).Scan(&n))
+		return n
+	}
+	tableExists := func(name string) bool {
+		var exists bool
+		require.NoError(t, db.QueryRowContext(ctx,
+			`SELECT to_regclass($1) IS NOT NULL`, "public."+name).Scan(&exists))
+		return exists
+	}
+	installed := countFunctions()
+
+	// A dry run lists the statements without running them.
+	var buf bytes.Buffer
+	down := opts
+	down.Down = true
+	down.DryRun = &buf
+	_, err = migrator.MigrateWithOptions(ctx, db, "", down)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `DROP FUNCTION IF EXISTS "public"."check_permission"(text, text, text);`)
+	assert.Contains(t, buf.String(), `DROP TABLE IF EXISTS "public"."melange_routes";`)
+	assert.Contains(t, buf.String(), `UPDATE "public"."melange_migrations" SET rolled_back_at = now() WHERE rolled_back_at IS NULL;`)
+	assert.NotContains(t, buf.String(), "DELETE")
+	assert.NotContains(t, buf.String(), "check_app_owned")
+	assert.NotContains(t, buf.String(), "melange_tuples")
+	assert.Equal(t, installed, countFunctions())
+
+	down.DryRun = nil
+	_, err = migrator.MigrateWithOptions(ctx, db, "", down)
+	require.NoError(t, err)
+	assert.Equal(t, 1, countFunctions(), "only the unrecorded application function is left")
+	assert.False(t, tableExists("melange_routes"))
+	assert.True(t, tableExists("melange_migrations"))
+	assert.True(t, tableExists("melange_tuples"))
+
+	// The record stays in the history, marked rolled back, so nothing is
+	// left to roll back and the same schema is applied again rather than
+	// skipped.
+	m := migrator.NewMigrator(db, "")
+	history, err := m.GetHistory(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.NotNil(t, history[0].RolledBackAt)
+
+	stmts, err := m.Rollback(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, stmts)
+
+	skipped, err := migrator.MigrateWithOptions(ctx, db, path, opts)
+	require.NoError(t, err)
+	assert.False(t, skipped)
+	assert.Equal(t, installed, countFunctions())
+
+	history, err = m.GetHistory(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Nil(t, history[0].RolledBackAt, "the re-applied migration is in effect")
+	assert.NotNil(t, history[1].RolledBackAt)
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestSchemaHashGuard verifies that check_permission's p_expected_schema_hash
+// overload answers like the five-argument dispatcher when the hash matches or
+// is NULL, and raises M2005 on a mismatch, which Checker surfaces as
+// ErrSchemaHashMismatch.
+func TestSchemaHashGuard(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err, "creating melange_tuples table")
+	_, err = db.ExecContext(ctx, `INSERT INTO melange_tuples VALUES ('user', 'alice', 'owner', 'document', '1')`)
+	require.NoError(t, err)
+	migrateSchema(t, ctx, migrator.NewMigrator(db, ""), evidenceSchema, migrator.InternalMigrateOptions{})
+
+	hash := schemaHash(t, evidenceSchema)
+	check := func(expected any) (int, error) {
+		var allowed int
+		err := db.QueryRowContext(ctx,
+			"SELECT check_permission('user', 'alice', 'viewer', 'document', '1', $1)", expected,
+		).Scan(&allowed)
+		return allowed, err
+	}
+
+	allowed, err := check(hash)
+	require.NoError(t, err)
+	assert.Equal(t, 1, allowed)
+
+	allowed, err = check(nil)
+	require.NoError(t, err, "a NULL hash skips the comparison")
+	assert.Equal(t, 1, allowed)
+
+	_, err = check("stale")
+	require.Error(t, err)
+	assert.True(t, melange.IsSchemaHashMismatchErr(melange.TranslateError(err)), "want M2005, got %v", err)
+
+	alice := melange.Object{Type: "user", ID: "alice"}
+	doc := melange.Object{Type: "document", ID: "1"}
+
+	ok, err := melange.NewChecker(db, melange.WithExpectedSchemaHash(hash)).Check(ctx, alice, melange.Relation("viewer"), doc)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = melange.NewChecker(db, melange.WithExpectedSchemaHash("stale")).Check(ctx, alice, melange.Relation("viewer"), doc)
+	assert.True(t, melange.IsSchemaHashMismatchErr(err), "want ErrSchemaHashMismatch, got %v", err)
+}
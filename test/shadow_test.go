@@ -0,0 +1,109 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestShadow_MigrateAndPromote canaries a change to document#viewer in a
+// shadow schema, checks that only the shadow's dispatcher sees it, then
+// promotes it.
+func TestShadow_MigrateAndPromote(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	writeSchema := func(name, viewer string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(`model
+  schema 1.1
+
+type user
+
+type document
+  relations
+    define editor: [user]
+    define viewer: `+viewer+`
+`), 0o644))
+		return path
+	}
+	current := writeSchema("current.fga", "[user]")
+	next := writeSchema("next.fga", "[user] or editor")
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)`)
+	require.NoError(t, err)
+	insertTuple(t, ctx, db, "user", "alice", "editor", "document", "1")
+
+	opts := migrator.MigrateOptions{DatabaseSchema: "public"}
+	_, err = migrator.MigrateWithOptions(ctx, db, current, opts)
+	require.NoError(t, err)
+
+	checkIn := func(schema string) int {
+		var result int
+		require.NoError(t, db.QueryRowContext(ctx,
+			`SELECT "`+schema+`".check_permission('user', 'alice', 'viewer', 'document', '1')`).Scan(&result))
+		return result
+	}
+
+	require.NoError(t, migrator.MigrateShadow(ctx, db, next, "melange_shadow", opts))
+	assert.Equal(t, 0, checkIn("public"), "live functions are untouched")
+	assert.Equal(t, 1, checkIn("melange_shadow"), "shadow reads the live tuples")
+
+	// Re-running rebuilds the shadow in place.
+	require.NoError(t, migrator.MigrateShadow(ctx, db, next, "melange_shadow", opts))
+
+	_, err = migrator.PromoteShadow(ctx, db, current, "melange_shadow", opts)
+	require.Error(t, err, "promoting a schema other than the canaried one must fail")
+
+	_, err = migrator.PromoteShadow(ctx, db, next, "melange_shadow", opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, checkIn("public"))
+
+	var shadowExists bool
+	require.NoError(t, db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = 'melange_shadow')`).Scan(&shadowExists))
+	assert.False(t, shadowExists, "promotion drops the shadow schema")
+}
+
+// TestShadow_RefusesForeignSchema checks that a schema melange did not create
+// is never dropped to make room for a shadow.
+func TestShadow_RefusesForeignSchema(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte("model\n  schema 1.1\n\ntype user\n"), 0o644))
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `CREATE SCHEMA app_data; CREATE TABLE app_data.keep (id INT)`)
+	require.NoError(t, err)
+
+	opts := migrator.MigrateOptions{DatabaseSchema: "public"}
+	require.Error(t, migrator.MigrateShadow(ctx, db, path, "app_data", opts))
+	require.Error(t, migrator.DropShadow(ctx, db, "app_data"))
+
+	var kept bool
+	require.NoError(t, db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pg_tables WHERE schemaname = 'app_data' AND tablename = 'keep')`).Scan(&kept))
+	assert.True(t, kept)
+}
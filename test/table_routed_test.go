@@ -0,0 +1,119 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/lib/sqlgen"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/pkg/parser"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// installTableRoutedSchema installs repeatedSubcheckSchema over a plain
+// melange_tuples table with the chosen check_permission dispatcher.
+func installTableRoutedSchema(t *testing.T, ctx context.Context, routed bool) (*sql.DB, *migrator.Migrator) {
+	t.Helper()
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		)
+	`)
+	require.NoError(t, err, "creating melange_tuples table")
+
+	m := migrator.NewMigrator(db, "")
+	migrateTableRouted(t, ctx, m, routed)
+	return db, m
+}
+
+func migrateTableRouted(t *testing.T, ctx context.Context, m *migrator.Migrator, routed bool) {
+	t.Helper()
+	types, err := parser.ParseSchemaString(repeatedSubcheckSchema)
+	require.NoError(t, err)
+	require.NoError(t, m.MigrateWithTypesAndOptions(ctx, types, migrator.InternalMigrateOptions{
+		SchemaContent:         repeatedSubcheckSchema,
+		TableRoutedDispatcher: routed,
+	}))
+}
+
+// TestTableRouted_MatchesStatic runs the same checks through both dispatchers
+// and requires identical answers.
+func TestTableRouted_MatchesStatic(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	cases := []struct {
+		subject, relation, object string
+	}{
+		{"alice", "viewer", "doc1"},
+		{"alice", "commenter", "doc1"},
+		{"bob", "viewer", "doc1"},
+		{"carol", "viewer", "doc2"},
+		{"carol", "blocked", "doc2"},
+		{"dave", "viewer", "doc1"},
+		{"alice", "no_such_relation", "doc1"},
+	}
+
+	results := make(map[bool][]int)
+	for _, routed := range []bool{false, true} {
+		db, _ := installTableRoutedSchema(t, ctx, routed)
+		insertTuple(t, ctx, db, "user", "alice", "member", "team", "eng")
+		insertTuple(t, ctx, db, "team", "eng#member", "member", "document", "doc1")
+		insertTuple(t, ctx, db, "user", "alice", "editor", "document", "doc1")
+		insertTuple(t, ctx, db, "user", "bob", "editor", "document", "doc1")
+		insertTuple(t, ctx, db, "user", "carol", "member", "document", "doc2")
+		insertTuple(t, ctx, db, "user", "carol", "editor", "document", "doc2")
+		insertTuple(t, ctx, db, "user", "carol", "blocked", "document", "doc2")
+
+		for _, c := range cases {
+			results[routed] = append(results[routed], checkPermission(t, ctx, db, c.subject, c.relation, c.object))
+		}
+	}
+
+	assert.Equal(t, []int{1, 1, 0, 1, 1, 0, 0}, results[false])
+	assert.Equal(t, results[false], results[true], "table-routed answers must match")
+}
+
+// TestTableRouted_ToggleReroutes verifies that toggling the option for an
+// unchanged schema is not skipped in either direction.
+func TestTableRouted_ToggleReroutes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	db, m := installTableRoutedSchema(t, ctx, false)
+
+	var exists bool
+	require.NoError(t, db.QueryRowContext(ctx,
+		"SELECT to_regclass($1) IS NOT NULL", sqlgen.RoutesTable).Scan(&exists))
+	assert.False(t, exists, "the static dispatcher must not create %s", sqlgen.RoutesTable)
+
+	migrateTableRouted(t, ctx, m, true)
+
+	var routes int
+	require.NoError(t, db.QueryRowContext(ctx,
+		"SELECT count(*) FROM "+sqlgen.RoutesTable+" WHERE object_type = 'document'").Scan(&routes))
+	assert.Equal(t, 5, routes, "every checkable document relation must be routed")
+
+	var body string
+	require.NoError(t, db.QueryRowContext(ctx,
+		"SELECT prosrc FROM pg_proc WHERE proname = 'check_permission_internal'").Scan(&body))
+	assert.Contains(t, body, "EXECUTE")
+
+	migrateTableRouted(t, ctx, m, false)
+
+	require.NoError(t, db.QueryRowContext(ctx,
+		"SELECT prosrc FROM pg_proc WHERE proname = 'check_permission_internal'").Scan(&body))
+	assert.NotContains(t, body, sqlgen.RoutesTable, "disabling the option must restore the static dispatcher")
+}
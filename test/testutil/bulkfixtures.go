@@ -10,6 +10,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/stdlib"
@@ -545,6 +546,65 @@ func (bf *BulkFixtures) fetchPullRequestIDs(n int) ([]int64, error) {
 	return ids, nil
 }
 
+// Tuple is one authorization tuple, in the column order of melange_tuples.
+type Tuple struct {
+	SubjectType string
+	SubjectID   string
+	Relation    string
+	ObjectType  string
+	ObjectID    string
+}
+
+// CopyTuples loads tuples into table, which must have the columns of
+// melange_tuples, using COPY FROM.
+// Falls back to batch INSERT if COPY fails.
+func (bf *BulkFixtures) CopyTuples(table string, tuples []Tuple) error {
+	var buf bytes.Buffer
+	for _, tp := range tuples {
+		buf.WriteString(tp.SubjectType)
+		buf.WriteByte('\t')
+		buf.WriteString(tp.SubjectID)
+		buf.WriteByte('\t')
+		buf.WriteString(tp.Relation)
+		buf.WriteByte('\t')
+		buf.WriteString(tp.ObjectType)
+		buf.WriteByte('\t')
+		buf.WriteString(tp.ObjectID)
+		buf.WriteByte('\n')
+	}
+	err := bf.copyFrom(table, tupleColumns, &buf)
+	if err == nil {
+		return nil
+	}
+
+	// Fallback
+	log.Printf("COPY FROM failed for %s (%v), falling back to batch INSERT", table, err)
+	return bf.insertTuplesBatched(table, tuples)
+}
+
+// tupleColumns are the columns CopyTuples loads, in Tuple's field order.
+var tupleColumns = []string{"subject_type", "subject_id", "relation", "object_type", "object_id"}
+
+// insertTuplesBatched inserts tuples into table 1000 rows per statement.
+func (bf *BulkFixtures) insertTuplesBatched(table string, tuples []Tuple) error {
+	const batch = 1000
+	for i := 0; i < len(tuples); i += batch {
+		end := min(i+batch, len(tuples))
+		vals := make([]string, 0, end-i)
+		args := make([]any, 0, 5*(end-i))
+		for j, tp := range tuples[i:end] {
+			b := j * 5
+			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d)", b+1, b+2, b+3, b+4, b+5))
+			args = append(args, tp.SubjectType, tp.SubjectID, tp.Relation, tp.ObjectType, tp.ObjectID)
+		}
+		q := "INSERT INTO " + table + " (" + joinColumns(tupleColumns) + ") VALUES " + strings.Join(vals, ",")
+		if _, err := bf.db.ExecContext(bf.ctx, q, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // TupleCount returns the current count of tuples in the melange_tuples view.
 func (bf *BulkFixtures) TupleCount() (int, error) {
 	var count int
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
 	"testing"
 	"time"
 
@@ -15,13 +14,7 @@ import (
 )
 
 // KitchenSinkTuple is one authorization tuple in the kitchen-sink dataset.
-type KitchenSinkTuple struct {
-	SubjectType string
-	SubjectID   string
-	Relation    string
-	ObjectType  string
-	ObjectID    string
-}
+type KitchenSinkTuple = Tuple
 
 // KitchenSinkScale parameterises the deterministic kitchenSink-tuple generator. The same
 // scale always produces the same tuples (no randomness), so results are
@@ -187,6 +180,9 @@ func GenerateKitchenSinkTuples(s KitchenSinkScale) []KitchenSinkTuple {
 				if (level+idx)%5 == 0 {
 					add("user", umod(o*17+level), "banned", "folder", f)
 				}
+				if (level+idx)%3 == 0 {
+					add("user", umod(o*23+idx), "admin", "folder", f)
+				}
 
 				// Documents under this folder.
 				for d := 0; d < s.DocsPerFolder; d++ {
@@ -275,45 +271,7 @@ func SetupKitchenSinkDB(tb testing.TB) *sql.DB {
 func LoadKitchenSinkTuples(tb testing.TB, db *sql.DB, tuples []KitchenSinkTuple) {
 	tb.Helper()
 	ctx := context.Background()
-	bf := NewBulkFixtures(ctx, db)
-
-	var buf strings.Builder
-	for _, tp := range tuples {
-		buf.WriteString(tp.SubjectType)
-		buf.WriteByte('\t')
-		buf.WriteString(tp.SubjectID)
-		buf.WriteByte('\t')
-		buf.WriteString(tp.Relation)
-		buf.WriteByte('\t')
-		buf.WriteString(tp.ObjectType)
-		buf.WriteByte('\t')
-		buf.WriteString(tp.ObjectID)
-		buf.WriteByte('\n')
-	}
-	cols := []string{"subject_type", "subject_id", "relation", "object_type", "object_id"}
-	if err := bf.copyFrom("kitchen_sink_tuples", cols, strings.NewReader(buf.String())); err != nil {
-		// Fallback: batched INSERT.
-		require.NoError(tb, insertKitchenSinkTuplesBatched(ctx, db, tuples), "load kitchen-sink tuples")
-	}
+	require.NoError(tb, NewBulkFixtures(ctx, db).CopyTuples("kitchen_sink_tuples", tuples), "load kitchen-sink tuples")
 	_, err := db.ExecContext(ctx, "ANALYZE kitchen_sink_tuples")
 	require.NoError(tb, err)
 }
-
-func insertKitchenSinkTuplesBatched(ctx context.Context, db *sql.DB, tuples []KitchenSinkTuple) error {
-	const batch = 1000
-	for i := 0; i < len(tuples); i += batch {
-		end := min(i+batch, len(tuples))
-		vals := make([]string, 0, end-i)
-		args := make([]any, 0, 5*(end-i))
-		for j, tp := range tuples[i:end] {
-			b := j * 5
-			vals = append(vals, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d)", b+1, b+2, b+3, b+4, b+5))
-			args = append(args, tp.SubjectType, tp.SubjectID, tp.Relation, tp.ObjectType, tp.ObjectID)
-		}
-		q := "INSERT INTO kitchen_sink_tuples (subject_type,subject_id,relation,object_type,object_id) VALUES " + strings.Join(vals, ",")
-		if _, err := db.ExecContext(ctx, q, args...); err != nil {
-			return err
-		}
-	}
-	return nil
-}
@@ -0,0 +1,118 @@
+package testutil
+
+import (
+	"math/rand/v2"
+	"strconv"
+
+	"github.com/pthm/melange/pkg/schema"
+)
+
+// SyntheticScale parameterises GenerateSyntheticTuples. The same scale and
+// model always produce the same tuples.
+type SyntheticScale struct {
+	// ObjectsPerType is the number of objects of every type, ids "0" to
+	// ObjectsPerType-1. Types with no relations, such as user, only appear
+	// as subjects.
+	ObjectsPerType int
+
+	// FanOut is the number of tuples written for each object and each
+	// relation that accepts direct grants.
+	FanOut int
+
+	// UsersetDensity is the share of a relation's tuples that grant a
+	// userset ([group#member]) when the relation accepts both usersets and
+	// plain subjects. A relation that accepts only usersets always grants one.
+	UsersetDensity float64
+
+	// WildcardDensity is the share of grants to a type the relation accepts
+	// both as [user] and [user:*] that are written as user:*. A type accepted
+	// only as [user:*] always is.
+	WildcardDensity float64
+
+	// Seed seeds the generator.
+	Seed uint64
+}
+
+// GenerateSyntheticTuples produces tuples for an arbitrary model: for every
+// object of every type and every relation with a type restriction, FanOut
+// grants to subjects the restriction allows. Grants whose subject has the
+// object's own type, as parent: [folder] or member: [group#member], point
+// only at lower ids, so hierarchies never cycle and nest about ln(n) deep.
+// Conditional grants are skipped, as melange_tuples carries no condition.
+// Duplicate draws are dropped, so a relation may get fewer than FanOut tuples
+// per object.
+func GenerateSyntheticTuples(types []schema.TypeDefinition, s SyntheticScale) []Tuple {
+	rng := rand.New(rand.NewPCG(s.Seed, s.Seed))
+	seen := make(map[Tuple]bool)
+	var tuples []Tuple
+
+	for _, td := range types {
+		for _, rel := range td.Relations {
+			var plain, wildcard, usersets []schema.SubjectTypeRef
+			for _, ref := range rel.SubjectTypeRefs {
+				switch {
+				case ref.Condition != nil:
+				case ref.Relation != "":
+					usersets = append(usersets, ref)
+				case ref.Wildcard:
+					wildcard = append(wildcard, ref)
+				default:
+					plain = append(plain, ref)
+				}
+			}
+			// A type accepted only as [user:*] is always granted as user:*;
+			// one accepted as both [user] and [user:*] is, at WildcardDensity.
+			plainTypes := make(map[string]bool, len(plain))
+			for _, ref := range plain {
+				plainTypes[ref.Type] = true
+			}
+			wildcardTypes := make(map[string]bool, len(wildcard))
+			for _, ref := range wildcard {
+				if plainTypes[ref.Type] {
+					wildcardTypes[ref.Type] = true
+				} else {
+					plain = append(plain, ref)
+				}
+			}
+			if len(plain) == 0 && len(usersets) == 0 {
+				continue
+			}
+
+			for i := 0; i < s.ObjectsPerType; i++ {
+				for k := 0; k < s.FanOut; k++ {
+					var ref schema.SubjectTypeRef
+					if len(usersets) > 0 && (len(plain) == 0 || rng.Float64() < s.UsersetDensity) {
+						ref = usersets[rng.IntN(len(usersets))]
+					} else {
+						ref = plain[rng.IntN(len(plain))]
+					}
+
+					var id string
+					switch {
+					case ref.Wildcard:
+						id = "*"
+					case ref.Relation == "" && wildcardTypes[ref.Type] && rng.Float64() < s.WildcardDensity:
+						id = "*"
+					case ref.Type == td.Name:
+						if i == 0 {
+							continue
+						}
+						id = strconv.Itoa(rng.IntN(i))
+					default:
+						id = strconv.Itoa(rng.IntN(max(s.ObjectsPerType, 1)))
+					}
+					if ref.Relation != "" {
+						id += "#" + ref.Relation
+					}
+
+					tp := Tuple{ref.Type, id, rel.Name, td.Name, strconv.Itoa(i)}
+					if !seen[tp] {
+						seen[tp] = true
+						tuples = append(tuples, tp)
+					}
+				}
+			}
+		}
+	}
+	return tuples
+}
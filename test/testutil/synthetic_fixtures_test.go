@@ -0,0 +1,76 @@
+package testutil
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/parser"
+)
+
+const syntheticTestSchema = `model
+  schema 1.1
+type user
+type group
+  relations
+    define member: [user, user:*, group#member]
+type folder
+  relations
+    define parent: [folder]
+    define public: [user:*]
+    define editor: [user with allowed]
+    define viewer: [group#member] or editor or viewer from parent
+condition allowed(ok: bool) {
+  ok
+}
+`
+
+func TestGenerateSyntheticTuples(t *testing.T) {
+	types, err := parser.ParseSchemaString(syntheticTestSchema)
+	require.NoError(t, err)
+
+	scale := SyntheticScale{ObjectsPerType: 50, FanOut: 3, UsersetDensity: 0.3, WildcardDensity: 0.1, Seed: 7}
+	tuples := GenerateSyntheticTuples(types, scale)
+	require.NotEmpty(t, tuples)
+	assert.Equal(t, tuples, GenerateSyntheticTuples(types, scale), "same scale, same tuples")
+
+	allowed := map[string][]string{
+		"group#member":  {"user", "user:*", "group#member"},
+		"folder#parent": {"folder"},
+		"folder#public": {"user:*"},
+		"folder#viewer": {"group#member"},
+	}
+	byRelation := make(map[string]int)
+	seen := make(map[Tuple]bool)
+	for _, tp := range tuples {
+		key := tp.ObjectType + "#" + tp.Relation
+		byRelation[key]++
+		assert.False(t, seen[tp], "duplicate %v", tp)
+		seen[tp] = true
+
+		subject := tp.SubjectType
+		id, rel, isUserset := strings.Cut(tp.SubjectID, "#")
+		switch {
+		case isUserset:
+			subject += "#" + rel
+		case id == "*":
+			subject += ":*"
+		}
+		assert.Contains(t, allowed[key], subject, "%v", tp)
+
+		if tp.SubjectType == tp.ObjectType {
+			sub, err := strconv.Atoi(id)
+			require.NoError(t, err)
+			obj, err := strconv.Atoi(tp.ObjectID)
+			require.NoError(t, err)
+			assert.Less(t, sub, obj, "self-typed grants point at lower ids: %v", tp)
+		}
+	}
+	assert.Zero(t, byRelation["folder#editor"], "conditional grants are skipped")
+	for key := range allowed {
+		assert.Positive(t, byRelation[key], key)
+	}
+}
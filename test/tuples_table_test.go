@@ -0,0 +1,98 @@
+package test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestTuplesTable migrates with a tuples relation other than melange_tuples,
+// unqualified and schema-qualified, and checks permissions read from it.
+// Contextual tuples shadow an unqualified relation and are refused for a
+// qualified one.
+func TestTuplesTable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte(`model
+  schema 1.1
+
+type user
+
+type folder
+  relations
+    define viewer: [user]
+
+type document
+  relations
+    define parent: [folder]
+    define viewer: [user] or viewer from parent
+`), 0o644))
+
+	alice := melange.Object{Type: "user", ID: "alice"}
+	bob := melange.Object{Type: "user", ID: "bob"}
+	doc := melange.Object{Type: "document", ID: "1"}
+	const viewer = melange.Relation("viewer")
+
+	for _, tuplesTable := range []string{"app_tuples", "tenant_42.tuples"} {
+		t.Run(tuplesTable, func(t *testing.T) {
+			db := testutil.EmptyDB(t)
+			_, err := db.ExecContext(ctx, `
+				CREATE SCHEMA tenant_42;
+				CREATE TABLE `+tuplesTable+` (
+					subject_type TEXT NOT NULL,
+					subject_id TEXT NOT NULL,
+					relation TEXT NOT NULL,
+					object_type TEXT NOT NULL,
+					object_id TEXT NOT NULL
+				);
+				INSERT INTO `+tuplesTable+` VALUES
+					('user', 'alice', 'viewer', 'folder', 'f'),
+					('folder', 'f', 'parent', 'document', '1')`)
+			require.NoError(t, err)
+
+			opts := migrator.MigrateOptions{TuplesTable: tuplesTable}
+			_, err = migrator.MigrateWithOptions(ctx, db, path, opts)
+			require.NoError(t, err)
+
+			m := migrator.NewMigrator(db, path)
+			m.SetTuplesTable(tuplesTable)
+			status, err := m.GetStatus(ctx)
+			require.NoError(t, err)
+			assert.True(t, status.TuplesExists)
+			assert.Empty(t, status.FunctionsStale)
+
+			skipped, err := migrator.MigrateWithOptions(ctx, db, path, opts)
+			require.NoError(t, err)
+			assert.True(t, skipped, "same tuples table, same checksum")
+
+			checker := melange.NewChecker(db, melange.WithTuplesTable(tuplesTable))
+			ok, err := checker.Check(ctx, alice, viewer, doc)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			ok, err = checker.Check(ctx, bob, viewer, doc)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			extra := []melange.ContextualTuple{{Subject: bob, Relation: viewer, Object: doc}}
+			ok, err = checker.CheckWithContextualTuples(ctx, bob, viewer, doc, extra)
+			if tuplesTable == "tenant_42.tuples" {
+				assert.ErrorIs(t, err, melange.ErrContextualTuplesUnsupported)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, ok)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/pkg/migrator"
+	"github.com/pthm/melange/test/testutil"
+)
+
+// TestUninstall installs a schema with every optional function and the
+// routing table, then checks that Uninstall removes all of it, keeps the
+// application's tables and melange_tuples, and is idempotent.
+func TestUninstall(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte(`model
+  schema 1.1
+
+type user
+
+type folder
+  relations
+    define viewer: [user]
+
+type document
+  relations
+    define parent: [folder]
+    define viewer: [user] or viewer from parent
+`), 0o644))
+
+	db := testutil.EmptyDB(t)
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE melange_tuples (
+			subject_type TEXT NOT NULL,
+			subject_id TEXT NOT NULL,
+			relation TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_id TEXT NOT NULL
+		);
+		CREATE TABLE check_app_data (id INT)`)
+	require.NoError(t, err)
+
+	_, err = migrator.MigrateWithOptions(ctx, db, path, migrator.MigrateOptions{
+		DatabaseSchema:        "public",
+		EnableEffectiveAccess: true,
+		EnableCheckEvidence:   true,
+		TableRoutedDispatcher: true,
+		ClosureFunction:       true,
+	})
+	require.NoError(t, err)
+
+	countFunctions := func() int {
+		var n int
+		require.NoError(t, db.QueryRowContext(ctx, `
+			SELECT count(*) FROM pg_proc p
+			JOIN pg_namespace n ON p.pronamespace = n.oid
+			WHERE n.nspname = 'public'`).Scan(&n))
+		return n
+	}
+	tableExists := func(name string) bool {
+		var exists bool
+		require.NoError(t, db.QueryRowContext(ctx,
+			`SELECT to_regclass($1) IS NOT NULL`, "public."+name).Scan(&exists))
+		return exists
+	}
+	installed := countFunctions()
+	require.NotZero(t, installed)
+
+	// A dry run lists the statements without running them.
+	var buf bytes.Buffer
+	planned, err := migrator.Uninstall(ctx, db, migrator.UninstallOptions{DatabaseSchema: "public", DryRun: &buf})
+	require.NoError(t, err)
+	assert.Len(t, planned, installed+2, "every function plus melange_routes and melange_migrations")
+	assert.Contains(t, buf.String(), `DROP FUNCTION IF EXISTS "public"."check_permission"(text, text, text);`)
+	assert.Contains(t, buf.String(), `DROP TABLE IF EXISTS "public"."melange_routes";`)
+	assert.NotContains(t, buf.String(), "melange_tuples")
+	assert.Equal(t, installed, countFunctions())
+
+	dropped, err := migrator.Uninstall(ctx, db, migrator.UninstallOptions{DatabaseSchema: "public"})
+	require.NoError(t, err)
+	assert.Equal(t, planned, dropped)
+	assert.Zero(t, countFunctions())
+	assert.False(t, tableExists("melange_routes"))
+	assert.False(t, tableExists("melange_migrations"))
+	assert.True(t, tableExists("melange_tuples"), "tuples are kept unless asked for")
+	assert.True(t, tableExists("check_app_data"), "only functions match the naming convention")
+
+	// Nothing is left, so a second run drops nothing.
+	dropped, err = migrator.Uninstall(ctx, db, migrator.UninstallOptions{DatabaseSchema: "public"})
+	require.NoError(t, err)
+	assert.Empty(t, dropped)
+
+	dropped, err = migrator.Uninstall(ctx, db, migrator.UninstallOptions{DatabaseSchema: "public", DropTuples: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`DROP TABLE IF EXISTS "public"."melange_tuples"`}, dropped)
+	assert.False(t, tableExists("melange_tuples"))
+}
+
+// TestUninstall_KeepsDependents checks that Uninstall fails, and drops
+// nothing, while an application object still depends on a melange function.
+func TestUninstall_KeepsDependents(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "schema.fga")
+	require.NoError(t, os.WriteFile(path, []byte("model\n  schema 1.1\n\ntype user\n\ntype document\n  relations\n    define viewer: [user]\n"), 0o644))
+
+	db := testutil.EmptyDB(t)
+	_, err := migrator.MigrateWithOptions(ctx, db, path, migrator.MigrateOptions{DatabaseSchema: "public"})
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE documents (id TEXT PRIMARY KEY);
+		CREATE VIEW visible_documents AS
+			SELECT id FROM documents WHERE check_permission('user', 'alice', 'viewer', 'document', id) = 1`)
+	require.NoError(t, err)
+
+	_, err = migrator.Uninstall(ctx, db, migrator.UninstallOptions{DatabaseSchema: "public"})
+	require.Error(t, err)
+
+	var n int
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT count(*) FROM pg_proc WHERE proname = 'check_permission'`).Scan(&n))
+	// The dispatcher, its type:id overload and its schema hash overload.
+	assert.Equal(t, 3, n, "the failed uninstall rolls back")
+}
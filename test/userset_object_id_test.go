@@ -0,0 +1,85 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// TestUsersetObjectIDsWithSeparators verifies that userset subjects whose
+// object IDs contain ':' or '#' are split on their last '#', in check and
+// both list directions, through direct and nested usersets.
+func TestUsersetObjectIDsWithSeparators(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, `model
+  schema 1.1
+
+type user
+
+type group
+  relations
+    define member: [user, group#member]
+
+type document
+  relations
+    define viewer: [group#member]
+`, "userset-object-ids")
+
+	// alice is in team:eng and, through it, in team:all. bob is in eng#2024,
+	// whose ID holds a '#'.
+	insertTuple(t, ctx, db, "user", "alice", "member", "group", "team:eng")
+	insertTuple(t, ctx, db, "group", "team:eng#member", "member", "group", "team:all")
+	insertTuple(t, ctx, db, "user", "bob", "member", "group", "eng#2024")
+	insertTuple(t, ctx, db, "group", "team:all#member", "viewer", "document", "proj:123")
+	insertTuple(t, ctx, db, "group", "eng#2024#member", "viewer", "document", "release#1")
+
+	checker := melange.NewChecker(db)
+	alice := melange.Object{Type: "user", ID: "alice"}
+	bob := melange.Object{Type: "user", ID: "bob"}
+	proj := melange.Object{Type: "document", ID: "proj:123"}
+	release := melange.Object{Type: "document", ID: "release#1"}
+
+	for _, tc := range []struct {
+		subject melange.Object
+		object  melange.Object
+		want    bool
+	}{
+		{alice, proj, true},
+		{alice, release, false},
+		{bob, release, true},
+		{bob, proj, false},
+		{melange.Object{Type: "group", ID: "team:eng#member"}, proj, true},
+		{melange.Object{Type: "group", ID: "eng#2024#member"}, release, true},
+	} {
+		ok, err := checker.Check(ctx, tc.subject, melange.Relation("viewer"), tc.object)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, ok, "check %s viewer %s", tc.subject, tc.object)
+	}
+
+	objects, err := checker.ListObjectsAll(ctx, alice, melange.Relation("viewer"), "document")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"proj:123"}, objects)
+
+	objects, err = checker.ListObjectsAll(ctx, bob, melange.Relation("viewer"), "document")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"release#1"}, objects)
+
+	groups, err := checker.ListObjectsAll(ctx, alice, melange.Relation("member"), "group")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"team:eng", "team:all"}, groups)
+
+	subjects, err := checker.ListSubjectsAll(ctx, proj, melange.Relation("viewer"), "user")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alice"}, subjects)
+
+	subjects, err = checker.ListSubjectsAll(ctx, release, melange.Relation("viewer"), "user")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bob"}, subjects)
+}
@@ -0,0 +1,72 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pthm/melange/melange"
+)
+
+// TestWildcardSubjectTypes_PerType checks that a wildcard grant only counts
+// for the subject types whose restriction allows one: in [user:*, service] a
+// service:* tuple must not grant every service.
+func TestWildcardSubjectTypes_PerType(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	const schema = `model
+  schema 1.1
+
+type user
+
+type service
+
+type document
+  relations
+    define viewer: [user:*, service]
+`
+	ctx := context.Background()
+	db := installAdHocSchema(t, ctx, schema, "wildcard-subject-types")
+
+	insertTuple(t, ctx, db, "user", "*", "viewer", "document", "1")
+	insertTuple(t, ctx, db, "service", "api", "viewer", "document", "1")
+	// Not writable under the model, but the tuples relation is a view the
+	// model cannot police.
+	insertTuple(t, ctx, db, "service", "*", "viewer", "document", "2")
+
+	checker := melange.NewChecker(db)
+	viewer := melange.Relation("viewer")
+	doc1 := melange.Object{Type: "document", ID: "1"}
+	doc2 := melange.Object{Type: "document", ID: "2"}
+
+	for _, tc := range []struct {
+		subject melange.Object
+		object  melange.Object
+		want    bool
+	}{
+		{melange.Object{Type: "user", ID: "alice"}, doc1, true},
+		{melange.Object{Type: "service", ID: "api"}, doc1, true},
+		{melange.Object{Type: "service", ID: "worker"}, doc1, false},
+		{melange.Object{Type: "service", ID: "worker"}, doc2, false},
+	} {
+		ok, err := checker.Check(ctx, tc.subject, viewer, tc.object)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, ok, "%s viewer %s", tc.subject, tc.object)
+	}
+
+	objects, err := checker.ListObjectsAll(ctx, melange.Object{Type: "service", ID: "worker"}, viewer, "document")
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+
+	subjects, err := checker.ListSubjectsAll(ctx, doc2, viewer, "service")
+	require.NoError(t, err)
+	assert.Empty(t, subjects)
+
+	subjects, err = checker.ListSubjectsAll(ctx, doc1, viewer, "user")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, subjects)
+}